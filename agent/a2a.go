@@ -0,0 +1,313 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/internal/a2a"
+)
+
+// a2aRemote delegates a candidate turn to a remote A2A agent.
+type a2aRemote struct {
+	name     string
+	url      string
+	skill    string
+	protocol *AnswerProtocol
+	hc       *http.Client
+
+	mu     sync.Mutex
+	card   *a2a.AgentCard
+	client *a2a.Client
+}
+
+// NewA2ARemoteAgent creates a candidate agent backed by the remote A2A agent at url.
+//
+// url is the agent's origin or the URL of its agent card. When skill is non-empty it must name a skill advertised
+// by the card, and is passed to the remote agent in the message metadata. The remote agent receives the question
+// together with the previous round's answers, and its task updates are streamed back as partial events so only the
+// final answer takes part in the vote. The remote agent is asked for the final answer in protocol p.
+func NewA2ARemoteAgent(url, skill string, p *AnswerProtocol) (agent.Agent, error) {
+	return newA2ARemoteAgent(url, skill, p, http.DefaultClient)
+}
+
+func newA2ARemoteAgent(rawURL, skill string, p *AnswerProtocol, hc *http.Client) (agent.Agent, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid A2A agent URL %q", rawURL)
+	}
+
+	r := &a2aRemote{
+		name:     a2aAgentName(u.Host, skill),
+		url:      rawURL,
+		skill:    skill,
+		protocol: p,
+		hc:       hc,
+	}
+	a, err := agent.New(agent.Config{
+		Name:        r.name,
+		Description: "Delegates the question to the remote A2A agent at " + rawURL + ".",
+		Run:         r.run,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build A2A remote agent: %w", err)
+	}
+
+	return a, nil
+}
+
+// a2aAgentName derives a stable agent name from the remote host and skill.
+func a2aAgentName(host, skill string) string {
+	name := "a2a_" + host
+	if skill != "" {
+		name += "_" + skill
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// resolve fetches and caches the agent card on first use.
+func (r *a2aRemote) resolve(ctx context.Context) (*a2a.AgentCard, *a2a.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.card != nil {
+		return r.card, r.client, nil
+	}
+	card, err := a2a.ResolveCard(ctx, r.hc, r.url)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.skill != "" {
+		if _, ok := card.FindSkill(r.skill); !ok {
+			return nil, nil, fmt.Errorf("A2A agent %q has no skill %q", card.Name, r.skill)
+		}
+	}
+	r.card, r.client = card, a2a.NewClient(r.hc, card.URL)
+	return r.card, r.client, nil
+}
+
+func (r *a2aRemote) contextKey() string {
+	return "a2a:" + r.name + ":context_id"
+}
+
+func (r *a2aRemote) run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		card, client, err := r.resolve(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		params := &a2a.MessageSendParams{
+			Message: a2a.Message{
+				Kind:      a2a.KindMessage,
+				MessageID: rand.Text(),
+				Role:      "user",
+				Parts:     []a2a.Part{a2a.TextPart(r.prompt(ctx))},
+			},
+		}
+		// Keep one remote conversation per session so later rounds can build on earlier ones.
+		if contextID, err := getState(ctx, r.contextKey()); err == nil {
+			params.Message.ContextID, _ = contextID.(string)
+		}
+		if r.skill != "" {
+			params.Message.Metadata = map[string]any{"skill": r.skill}
+		}
+
+		var result a2aResult
+		if card.Capabilities.Streaming {
+			for ev, err := range client.StreamMessage(ctx, params) {
+				if err != nil {
+					err = fmt.Errorf("stream A2A agent %q: %w", card.Name, err)
+					yield(nil, errors.Join(err, r.cancelTask(ctx, client, &result)))
+					return
+				}
+				if text := result.add(ev); text != "" {
+					if !yield(r.event(ctx, text, true), nil) {
+						return
+					}
+				}
+			}
+		} else {
+			ev, err := client.SendMessage(ctx, params)
+			if err != nil {
+				yield(nil, fmt.Errorf("send to A2A agent %q: %w", card.Name, err))
+				return
+			}
+			result.add(ev)
+		}
+
+		if result.contextID != "" {
+			if err := setState(ctx, r.contextKey(), result.contextID); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+		answer, err := result.answer()
+		if err != nil {
+			yield(nil, fmt.Errorf("A2A agent %q: %w", card.Name, err))
+			return
+		}
+		yield(r.event(ctx, answer, false), nil)
+	}
+}
+
+// a2aCancelTimeout bounds the tasks/cancel call made once the invocation is already canceled.
+const a2aCancelTimeout = 5 * time.Second
+
+// cancelTask cancels the remote task of result when the invocation was canceled before the task finished, e.g. by a
+// round deadline or an interrupted run, so the remote agent stops working on an answer nobody waits for.
+func (r *a2aRemote) cancelTask(ctx context.Context, client *a2a.Client, result *a2aResult) error {
+	if ctx.Err() == nil || result.taskID == "" || result.state.Terminal() {
+		return nil
+	}
+	cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a2aCancelTimeout)
+	defer cancel()
+	if _, err := client.CancelTask(cctx, result.taskID); err != nil {
+		return fmt.Errorf("cancel A2A task %s: %w", result.taskID, err)
+	}
+	return nil
+}
+
+// prompt builds the message sent to the remote agent from the shared round context.
+func (r *a2aRemote) prompt(ctx agent.InvocationContext) string {
+	question := firstContentText(ctx.UserContent())
+	if v, err := getState(ctx, stateKeyQuestion); err == nil {
+		if s, ok := v.(string); ok && s != "" {
+			question = s
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(question)
+	if v, err := getState(ctx, stateKeyJoined); err == nil {
+		if joined, ok := v.(string); ok && strings.TrimSpace(joined) != "" {
+			b.WriteString("\n\nPrevious answers from other agents (may contain errors):\n")
+			b.WriteString(joined)
+		}
+	}
+	b.WriteString("\n\nRespond with the final answer " + r.protocol.Enclosure() + ".")
+	return b.String()
+}
+
+func (r *a2aRemote) event(ctx agent.InvocationContext, text string, partial bool) *session.Event {
+	ev := session.NewEvent(ctx.InvocationID())
+	ev.Author = r.name
+	ev.LLMResponse = model.LLMResponse{
+		Content: genai.NewContentFromText(text, genai.RoleModel),
+		Partial: partial,
+	}
+	return ev
+}
+
+// a2aResult accumulates the events of one remote task.
+type a2aResult struct {
+	contextID string
+	taskID    string
+	state     a2a.TaskState
+	status    string
+	message   string
+	artifacts []string
+}
+
+// add records ev and returns the progress text worth streaming to the round loop, if any.
+func (r *a2aResult) add(ev *a2a.Event) string {
+	if ev.ContextID != "" {
+		r.contextID = ev.ContextID
+	}
+	if taskID := cmp.Or(ev.TaskID, ev.ID); taskID != "" && ev.Kind != a2a.KindMessage {
+		r.taskID = taskID
+	}
+
+	switch ev.Kind {
+	case a2a.KindMessage:
+		r.message = a2a.PartsText(ev.Parts)
+		return r.message
+	case a2a.KindTask:
+		r.artifacts = r.artifacts[:0]
+		for _, art := range ev.Artifacts {
+			r.artifacts = append(r.artifacts, a2a.PartsText(art.Parts))
+		}
+		return r.setStatus(ev.Status)
+	case a2a.KindStatusUpdate:
+		return r.setStatus(ev.Status)
+	case a2a.KindArtifactUpdate:
+		text := a2a.PartsText(ev.Artifact.Parts)
+		if ev.Append && len(r.artifacts) > 0 {
+			r.artifacts[len(r.artifacts)-1] += text
+		} else {
+			r.artifacts = append(r.artifacts, text)
+		}
+		return text
+	default:
+		return ""
+	}
+}
+
+func (r *a2aResult) setStatus(status a2a.TaskStatus) string {
+	if status.State != "" {
+		r.state = status.State
+	}
+	if status.Message == nil {
+		return ""
+	}
+	r.status = a2a.PartsText(status.Message.Parts)
+	return r.status
+}
+
+// answer returns the final answer text, preferring artifacts over messages.
+func (r *a2aResult) answer() (string, error) {
+	switch r.state {
+	case a2a.TaskStateFailed, a2a.TaskStateRejected, a2a.TaskStateCanceled:
+		return "", fmt.Errorf("task %s: %s", r.state, r.status)
+	case a2a.TaskStateInputRequired, a2a.TaskStateAuthRequired:
+		return "", fmt.Errorf("task requires interaction (%s): %s", r.state, r.status)
+	}
+
+	if text := strings.TrimSpace(strings.Join(r.artifacts, "\n")); text != "" {
+		return text, nil
+	}
+	if text := strings.TrimSpace(r.message); text != "" {
+		return text, nil
+	}
+	if text := strings.TrimSpace(r.status); text != "" {
+		return text, nil
+	}
+	return "", errors.New("no answer in response")
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/zchee/tumix/agent/agenttest"
+)
+
+// fakeA2AServer serves an agent card and answers message/send and message/stream with canned results, and
+// tasks/cancel with a canceled task.
+type fakeA2AServer struct {
+	streaming bool
+	results   []string
+	// hang keeps the stream open after the results until the client goes away.
+	hang bool
+
+	mu       sync.Mutex
+	requests []map[string]any
+}
+
+func (s *fakeA2AServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if r.URL.Path != "/.well-known/agent-card.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"remote","url":"http://%s/rpc","capabilities":{"streaming":%t},"skills":[{"id":"math","name":"Math"}]}`,
+			r.Host, s.streaming)
+		return
+	}
+
+	var req map[string]any
+	if err := json.UnmarshalRead(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	switch req["method"] {
+	case "message/stream":
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, res := range s.results {
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%v,\"result\":%s}\n\n", req["id"], res)
+		}
+		if s.hang {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+		return
+	case "tasks/cancel":
+		id := req["params"].(map[string]any)["id"]
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":{"kind":"task","id":%q,"status":{"state":"canceled"}}}`, req["id"], id)
+		return
+	}
+	fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":%s}`, req["id"], s.results[len(s.results)-1])
+}
+
+func TestA2ARemoteAgent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		streaming   bool
+		skill       string
+		results     []string
+		wantPartial []string
+		wantFinal   string
+		wantErr     string
+	}{
+		"streamed task": {
+			streaming: true,
+			skill:     "math",
+			results: []string{
+				`{"kind":"task","id":"t1","contextId":"c1","status":{"state":"submitted"}}`,
+				`{"kind":"status-update","taskId":"t1","contextId":"c1","status":{"state":"working","message":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"thinking"}]}}}`,
+				`{"kind":"artifact-update","taskId":"t1","contextId":"c1","artifact":{"artifactId":"a1","parts":[{"kind":"text","text":"<<<4"}]}}`,
+				`{"kind":"artifact-update","taskId":"t1","contextId":"c1","append":true,"artifact":{"artifactId":"a1","parts":[{"kind":"text","text":"2>>>"}]}}`,
+				`{"kind":"status-update","taskId":"t1","contextId":"c1","final":true,"status":{"state":"completed"}}`,
+			},
+			wantPartial: []string{"thinking", "<<<4", "2>>>"},
+			wantFinal:   "<<<42>>>",
+		},
+		"sent message": {
+			results:   []string{`{"kind":"message","messageId":"m1","role":"agent","contextId":"c1","parts":[{"kind":"text","text":"<<<7>>>"}]}`},
+			wantFinal: "<<<7>>>",
+		},
+		"failed task": {
+			results: []string{`{"kind":"task","id":"t1","contextId":"c1","status":{"state":"failed","message":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"boom"}]}}}`},
+			wantErr: "task failed: boom",
+		},
+		"unknown skill": {
+			skill:   "poetry",
+			wantErr: `A2A agent "remote" has no skill "poetry"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &fakeA2AServer{streaming: tt.streaming, results: tt.results}
+			srv := httptest.NewServer(fake)
+			t.Cleanup(srv.Close)
+
+			a, err := newA2ARemoteAgent(srv.URL, tt.skill, nil, srv.Client())
+			if err != nil {
+				t.Fatalf("newA2ARemoteAgent() error = %v", err)
+			}
+			if !strings.HasPrefix(a.Name(), "a2a_127_0_0_1_") {
+				t.Fatalf("Name() = %q, want sanitized host prefix", a.Name())
+			}
+
+			state := agenttest.NewInMemoryState(map[string]any{
+				stateKeyQuestion: "What is 6*7?",
+				stateKeyJoined:   "- base: <<<41>>>",
+			})
+			sess := agenttest.NewInMemorySession("s1", "app", "user", state, nil, time.Time{})
+
+			var (
+				partials []string
+				final    string
+				runErr   error
+			)
+			for ev, err := range a.Run(agenttest.NewSessionInvocationContext(t.Context(), sess)) {
+				if err != nil {
+					runErr = err
+					break
+				}
+				text := firstContentText(ev.Content)
+				if ev.Partial {
+					partials = append(partials, text)
+					continue
+				}
+				final = text
+			}
+
+			if tt.wantErr != "" {
+				if runErr == nil || !strings.Contains(runErr.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", runErr, tt.wantErr)
+				}
+				return
+			}
+			if runErr != nil {
+				t.Fatalf("Run() error = %v", runErr)
+			}
+			if diff := cmp.Diff(tt.wantPartial, partials); diff != "" {
+				t.Fatalf("partial events mismatch (-want +got):\n%s", diff)
+			}
+			if final != tt.wantFinal {
+				t.Fatalf("final answer = %q, want %q", final, tt.wantFinal)
+			}
+
+			contextID, err := state.Get("a2a:" + a.Name() + ":context_id")
+			if err != nil || contextID != "c1" {
+				t.Fatalf("context id state = (%v, %v), want c1", contextID, err)
+			}
+
+			params := fake.requests[0]["params"].(map[string]any)
+			msg := params["message"].(map[string]any)
+			text := msg["parts"].([]any)[0].(map[string]any)["text"].(string)
+			if !strings.Contains(text, "What is 6*7?") || !strings.Contains(text, "<<<41>>>") {
+				t.Fatalf("prompt = %q, want question and previous answers", text)
+			}
+			if tt.skill != "" {
+				if got := msg["metadata"].(map[string]any)["skill"]; got != tt.skill {
+					t.Fatalf("metadata skill = %v, want %q", got, tt.skill)
+				}
+			}
+		})
+	}
+}
+
+func TestNewA2ARemoteAgentInvalidURL(t *testing.T) {
+	t.Parallel()
+
+	for _, u := range []string{"", "localhost:8080", "ftp://example.com"} {
+		if _, err := NewA2ARemoteAgent(u, "", nil); err == nil {
+			t.Fatalf("NewA2ARemoteAgent(%q) error = nil, want error", u)
+		}
+	}
+}
+
+func TestA2ARemoteAgentCancel(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeA2AServer{
+		streaming: true,
+		hang:      true,
+		results: []string{
+			`{"kind":"task","id":"t1","contextId":"c1","status":{"state":"working","message":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"thinking"}]}}}`,
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	a, err := newA2ARemoteAgent(srv.URL, "", nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newA2ARemoteAgent() error = %v", err)
+	}
+	state := agenttest.NewInMemoryState(map[string]any{stateKeyQuestion: "What is 6*7?"})
+	sess := agenttest.NewInMemorySession("s1", "app", "user", state, nil, time.Time{})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	var runErr error
+	for ev, err := range a.Run(agenttest.NewSessionInvocationContext(ctx, sess)) {
+		if err != nil {
+			runErr = err
+			break
+		}
+		if ev.Partial {
+			// The round is cut short while the remote task is still working.
+			cancel()
+		}
+	}
+	if !errors.Is(runErr, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", runErr)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	last := fake.requests[len(fake.requests)-1]
+	if last["method"] != "tasks/cancel" || last["params"].(map[string]any)["id"] != "t1" {
+		t.Fatalf("last request = %v, want tasks/cancel of t1", last)
+	}
+}
@@ -35,12 +35,17 @@
 package agent
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"iter"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/dotprompt/go/dotprompt"
 	"github.com/invopop/jsonschema"
@@ -53,6 +58,8 @@ import (
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/internal/mathcheck"
 )
 
 func Prompt() *dotprompt.Dotprompt {
@@ -107,23 +114,59 @@ func getState(ctx agent.InvocationContext, key string) (any, error) {
 	return val, nil
 }
 
-var sharedContext = `**TUMIX shared context**
+// RequestScope returns the scope of the candidate model calls ctx belongs to: one TUMIX run, round, and sample.
+//
+// It is empty outside the candidate phase of a round, so the Judge and other agents never share model calls. Use it
+// as the scope of a request deduplicating [model.LLM].
+func RequestScope(ctx context.Context) string {
+	ictx, ok := ctx.(agent.InvocationContext)
+	if !ok || ictx.Session() == nil {
+		return ""
+	}
+	val, err := ictx.Session().State().Get(stateKeyRequestScope)
+	if err != nil {
+		return ""
+	}
+	scope, _ := val.(string)
+	return scope
+}
+
+// sharedContext returns the shared TUMIX context asking for the final answer in protocol p.
+func sharedContext(p *AnswerProtocol) string {
+	return `**TUMIX shared context**
 - Round: {round_num}
 - Question: {question}
 - Vote margin (0-1): {vote_margin?}; Unique answers: {unique_answers?}; Coverage: {coverage?}; Entropy: {answer_entropy?}
 - Previous answers (may be empty):
 {joined_answers?}
+- Sources cited by previous answers (may be empty):
+{citations?}
+- Verification report rejecting the last proposed final answer (may be empty):
+{verification_report?}
+
+{refine_instruction?}
+Continue producing an explicit answer ` + p.Enclosure() + `.
+{format_reminder?}`
+}
 
-Use the shared context to refine your reasoning. Continue producing an explicit answer enclosed in ` + code(`<<<`) + ` and ` + code(`>>>`) + `.`
-
-func applySharedContext(cfg *llmagent.Config) {
-	cfg.GlobalInstruction = sharedContext
+// applySharedContext sets the shared TUMIX context asking for the final answer in protocol p as the global instruction
+// of a candidate, preceded by the system prompt of its generation config, if any (see [WithSystemPrompt]).
+//
+// The context ends with the optional debate prompt of the agent, which is only set in [ModeDebate]. The tools of the
+// candidate are wrapped so that a panicking tool fails its call rather than the candidate.
+func applySharedContext(cfg *llmagent.Config, p *AnswerProtocol) {
+	cfg.GlobalInstruction = sharedContext(p) + "\n" + debatePlaceholder(cfg.Name)
+	if prompt := takeSystemPrompt(cfg); prompt != "" {
+		cfg.GlobalInstruction = prompt + "\n\n" + cfg.GlobalInstruction
+	}
+	cfg.Tools = recoverTools(cfg.Tools)
+	cfg.Toolsets = recoverToolsets(cfg.Toolsets)
 }
 
 // NewBaseAgent creates a Base Agent that uses direct prompting to solve problems.
 //
 // This agent is responsible for "1. w/o TTS (Base)".
-func NewBaseAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewBaseAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "base",
 		Description: `Direct prompt.
@@ -132,7 +175,7 @@ func NewBaseAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Age
 		GenerateContentConfig: cloneGenConfig(genCfg),
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -145,7 +188,7 @@ func NewBaseAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Age
 // NewCoTAgent creates a CoT Agent that uses chain-of-thought reasoning to solve problems.
 //
 // This agent is responsible for "2. CoT Agent (CoT)".
-func NewCoTAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewCoTAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "cot",
 		Description: `Chain-of-thought text-only reasoning.
@@ -159,7 +202,7 @@ func NewCoTAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agen
 **Do not output the code for execution.**`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -172,7 +215,7 @@ func NewCoTAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agen
 // NewCoTCodeAgent creates a CoT Code Agent that uses chain-of-thought reasoning and output code to solve problems.
 //
 // This agent is responsible for "3. CoT-Code Agent (CoT code)".
-func NewCoTCodeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewCoTCodeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "cot-code",
 		Description: `Chain-of-thought text-only reasoning and output code.
@@ -193,7 +236,7 @@ output your thinking steps with texts and then the final <language> code.
 Start the <language> block with ` + "```" + `<language>`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -206,7 +249,7 @@ Start the <language> block with ` + "```" + `<language>`,
 // NewSearchAgent creates a Search Agent that uses web search to solve problems.
 //
 // This agent is responsible for "4. Search Agent (S)".
-func NewSearchAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewSearchAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "search",
 		Description: `Uses WebSearch.
@@ -224,7 +267,7 @@ generation. Then, the search platform will return the searched results.
 **Do not output the code for execution.**`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -237,7 +280,7 @@ generation. Then, the search platform will return the searched results.
 // NewCodeAgent creates a Code Agent that uses code execution to solve problems.
 //
 // This agent is responsible for "5. Code Agent (C)".
-func NewCodeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewCodeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "code",
 		Description: `Code-execution strategy for precise computation.
@@ -251,12 +294,12 @@ During the thinking process, **you can generate <language> code** for efficient
 with ` + "```" + `<language>. **A code query must involve only a single script that uses ‘print’
 function for the output.**. Once the code script is complete, stop the generation. Then, the code
 interpreter platform will execute the code and return the execution output and error. Once you feel you are
-ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + ` at the end
+ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + ` at the end
 of your response. Otherwise, you can continue your reasoning process and possibly generate more code
 query to solve the problem.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -269,7 +312,7 @@ query to solve the problem.`,
 // NewCodePlusAgent creates a Code+ Agent that uses code execution with extra human-pre-designed priors to solve problems.
 //
 // This agent is responsible for "6. Code Agent+ (C+)".
-func NewCodePlusAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewCodePlusAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "code-plus",
 		Description: `Code-execution strategy for precise computation with a hinted version with extra human-pre-designed priors.
@@ -283,12 +326,12 @@ During the thinking process, **you can generate <language> code** for efficient
 with ` + "```" + `<language>. **A code query must involve only a single script that uses ‘print’
 function for the output.**. Once the code script is complete, stop the generation. Then, the code
 interpreter platform will execute the code and return the execution output and error. Once you feel you are
-ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + ` at the end
+ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + ` at the end
 of your response. Otherwise, you can continue your reasoning process and possibly generate more code
 query to solve the problem.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -301,7 +344,7 @@ query to solve the problem.`,
 // NewDualToolGSAgent creates a Dual-Tool Agent that uses both code execution and Google Search API to solve problems.
 //
 // This agent is responsible for "7. Dual-Tool Agent (CS gs)".
-func NewDualToolGSAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewDualToolGSAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "dual-tool-google-search",
 		Description: `Dual-tool strategy combining code execution and Google Search API search.
@@ -326,12 +369,12 @@ generation. Then, the search platform will return the searched results.
 If you need to search the web, **do not generate code in the same response. Vice versa**. You can also solve
 the question without code and searching, just by your textual reasoning.
 
-Once you feel you are ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + `
+Once you feel you are ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + `
 at the end of your response. Otherwise, you can continue your reasoning process and possibly
 generate more code or search queries to solve the problem.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -344,7 +387,7 @@ generate more code or search queries to solve the problem.`,
 // NewDualToolLLMAgent creates a Dual-Tool Agent that uses both code execution and LLM search function to solve problems.
 //
 // This agent is responsible for "8. Dual-Tool Agent (CS llm)".
-func NewDualToolLLMAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewDualToolLLMAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "dual-tool-llm-search",
 		Description: `Dual-tool strategy combining code execution and LLM search function.
@@ -369,12 +412,12 @@ generation. Then, the search platform will return the searched results.
 If you need to search the web, **do not generate code in the same response. Vice versa**. You can also solve
 the question without code and searching, just by your textual reasoning.
 
-Once you feel you are ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + `
+Once you feel you are ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + `
 at the end of your response. Otherwise, you can continue your reasoning process and possibly
 generate more code or search queries to solve the problem.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -387,7 +430,7 @@ generate more code or search queries to solve the problem.`,
 // NewDualToolComAgent creates a Dual-Tool Agent that uses both code execution and a combination of Google Search API and LLM search function to solve problems.
 //
 // This agent is responsible for "9. Dual-Tool Agent (CS com)".
-func NewDualToolComAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewDualToolComAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "dual-tool-combine-search",
 		Description: `Dual-tool strategy combining code execution and combination of Google Search API search and LLM search function.
@@ -412,12 +455,12 @@ generation. Then, the search platform will return the searched results.
 If you need to search the web, **do not generate code in the same response. Vice versa**. You can also solve
 the question without code and searching, just by your textual reasoning.
 
-Once you feel you are ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + `
+Once you feel you are ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + `
 at the end of your response. Otherwise, you can continue your reasoning process and possibly
 generate more code or search queries to solve the problem.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -430,7 +473,7 @@ generate more code or search queries to solve the problem.`,
 // NewGuidedGSAgent creates a Guided Agent that uses both code execution and Google Search API to solve problems.
 //
 // This agent is responsible for "10. Guided Agent (CSGgs)".
-func NewGuidedGSAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewGuidedGSAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "guided-google-search",
 		Description: `Dual-tool strategy combining code execution and Google Search API search.
@@ -457,7 +500,7 @@ generation. Then, the search platform will return the searched results.
 If you need to search the web, **do not generate code in the same response. Vice versa.** You can also solve
 the question without code and searching, just by your textual reasoning.
 
-Once you feel you are ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + `
+Once you feel you are ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + `
 at the end of your response. Otherwise, you can continue your reasoning process and possibly
 generate more code or search queries to solve the problem.
 
@@ -467,7 +510,7 @@ should be included between ` + code(`<<<`) + ` and ` + code(`>>>`) + `, such as
 Now, here is the task:`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -480,7 +523,7 @@ Now, here is the task:`,
 // NewGuidedLLMAgent creates a Guided Agent that uses both code execution and LLM search function to solve problems.
 //
 // This agent is responsible for "11. Guided Agent (CSGllm)".
-func NewGuidedLLMAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewGuidedLLMAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "guided-llm-search",
 		Description: `Dual-tool strategy combining code execution and LLM search function.
@@ -507,7 +550,7 @@ generation. Then, the search platform will return the searched results.
 If you need to search the web, **do not generate code in the same response. Vice versa.** You can also solve
 the question without code and searching, just by your textual reasoning.
 
-Once you feel you are ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + `
+Once you feel you are ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + `
 at the end of your response. Otherwise, you can continue your reasoning process and possibly
 generate more code or search queries to solve the problem.
 
@@ -517,7 +560,7 @@ should be included between ` + code(`<<<`) + ` and ` + code(`>>>`) + `, such as
 Now, here is the task:`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -530,7 +573,7 @@ Now, here is the task:`,
 // NewGuidedComAgent creates a Guided Agent that uses both code execution and a combination of Google Search API and LLM search function to solve problems.
 //
 // This agent is responsible for "12. Guided Agent (CSGcom)".
-func NewGuidedComAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewGuidedComAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "guided-combine-search",
 		Description: `Dual-tool strategy combining code execution and combination of Google Search API search and LLM search function.
@@ -557,7 +600,7 @@ generation. Then, the search platform will return the searched results.
 If you need to search the web, **do not generate code in the same response. Vice versa.** You can also solve
 the question without code and searching, just by your textual reasoning.
 
-Once you feel you are ready for the final answer, directly return the answer with the format ` + code(`<<<answer content>>>`) + `
+Once you feel you are ready for the final answer, directly return the answer with the format ` + p.Example("answer content") + `
 at the end of your response. Otherwise, you can continue your reasoning process and possibly
 generate more code or search queries to solve the problem.
 
@@ -567,7 +610,7 @@ should be included between ` + code(`<<<`) + ` and ` + code(`>>>`) + `, such as
 Now, here is the task:`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -580,7 +623,7 @@ Now, here is the task:`,
 // NewGuidedPlusGSAgent creates a Guided+ Agent that uses both code execution and Google Search API with extra priors.
 //
 // This agent is responsible for "13. Guided Agent+ (CSG+gs)".
-func NewGuidedPlusGSAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewGuidedPlusGSAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "guided-plus-google-search",
 		Description: `Guided dual-tool with stronger priors combining code execution and Google Search API search.
@@ -598,7 +641,7 @@ Priority rules:
 When ready, output the guidance between ` + code(`<<<`) + ` and ` + code(`>>>`) + `, e.g.,` + code(`<<<Run a short Python script to factor the polynomial, then verify with a quick search.>>>`) + `.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -611,7 +654,7 @@ When ready, output the guidance between ` + code(`<<<`) + ` and ` + code(`>>>`)
 // NewGuidedPlusLLMAgent creates a Guided+ Agent that uses both code execution and LLM search with extra priors.
 //
 // This agent is responsible for "14. Guided Agent+ (CSG+llm)".
-func NewGuidedPlusLLMAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewGuidedPlusLLMAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "guided-plus-llm-search",
 		Description: `Guided dual-tool with stronger priors combining code execution and LLM search function.
@@ -629,7 +672,7 @@ Priority rules:
 Return guidance between ` + code(`<<<`) + ` and ` + code(`>>>`) + `.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -642,7 +685,7 @@ Return guidance between ` + code(`<<<`) + ` and ` + code(`>>>`) + `.`,
 // NewGuidedPlusComAgent creates a Guided+ Agent that uses both code execution and combined search with extra priors.
 //
 // This agent is responsible for "15. Guided Agent+ (CSG+com)".
-func NewGuidedPlusComAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewGuidedPlusComAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	cfg := llmagent.Config{
 		Name: "guided-plus-combine-Search",
 		Description: `Guided dual-tool with stronger priors combining code execution and mixed Google/LLM search.
@@ -656,7 +699,7 @@ Priority rules mirror Guided+gs/llm: choose code for computation, search for fac
 Return guidance between ` + code(`<<<`) + ` and ` + code(`>>>`) + `.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -667,7 +710,7 @@ Return guidance between ` + code(`<<<`) + ` and ` + code(`>>>`) + `.`,
 }
 
 // NewRefinementAgent creates a Refinement Agent that gathers candidate answers from sub-agents and judges the final answer.
-func NewRefinementAgent(subAgents ...agent.Agent) (agent.Agent, error) {
+func NewRefinementAgent(p *AnswerProtocol, subAgents ...agent.Agent) (agent.Agent, error) {
 	parallel, err := parallelagent.New(parallelagent.Config{
 		AgentConfig: agent.Config{
 			Name:        "candidates",
@@ -691,11 +734,15 @@ func NewRefinementAgent(subAgents ...agent.Agent) (agent.Agent, error) {
 **Candidate answers from several methods**:
 {joined_answers}
 
-Based on the candidates above, analyze the question step by step and try to list all the careful points. In the
-end of your response, directly output the answer to the question with the format ` + code(`«<answer content»>`) + `.`,
+**Sources cited by the candidates**:
+{citations?}
+
+Based on the candidates above, analyze the question step by step and try to list all the careful points. Preserve
+the sources that support your conclusion. In the end of your response, directly output the answer to the question
+with the format ` + p.Example("answer content") + `.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -709,6 +756,7 @@ const (
 	defaultMinRounds           uint    = 2
 	defaultMaxRounds           uint    = 10
 	defaultConfidenceThreshold float64 = 0.6
+	defaultSamplesPerAgent     uint    = 1
 
 	stateKeyAnswer      = "tumix_final_answer"
 	stateKeyConfidence  = "tumix_final_confidence"
@@ -721,12 +769,29 @@ const (
 	stateKeyEntropy     = "answer_entropy"
 	stateKeyTopAnswer   = "top_answer"
 	stateKeyJudgeAnswer = "judge_recommended_answer"
+	stateKeyCitations   = "citations"
+
+	stateKeyOriginalQuestion   = "original_question"
+	stateKeyCompressedQuestion = "compressed_question"
+
+	stateKeyCandidateScores = "candidate_scores"
+	stateKeyWeightedMargin  = "weighted_vote_margin"
+
+	stateKeySubQuestions = "sub_questions"
+	stateKeySubAnswers   = "sub_answers"
+
+	stateKeyRequestScope = "request_scope"
+
+	stateKeyVerifyAnswer       = "verify_answer"
+	stateKeyVerificationReport = "verification_report"
 )
 
 type finalizeArgs struct {
 	Answer     string  `json:"answer,omitzero"`
 	Confidence float64 `json:"confidence,omitzero"`
 	Stop       bool    `json:"stop,omitzero"`
+	// Scores rates every candidate answer of the round.
+	Scores []CandidateScore `json:"scores,omitzero"`
 }
 
 type finalizeResult struct {
@@ -737,7 +802,7 @@ type finalizeResult struct {
 func newFinalizeTool() (tool.Tool, error) {
 	cfg := functiontool.Config{
 		Name:        "finalize",
-		Description: "Store the selected answer, confidence, and optionally stop further rounds.",
+		Description: "Store the selected answer, confidence, and per-candidate scores, and optionally stop further rounds.",
 	}
 
 	t, err := functiontool.New(cfg, func(ctx tool.Context, args finalizeArgs) (finalizeResult, error) {
@@ -748,6 +813,11 @@ func newFinalizeTool() (tool.Tool, error) {
 		case args.Confidence < 0 || args.Confidence > 1:
 			return finalizeResult{}, fmt.Errorf("confidence must be between 0 and 1")
 		}
+		for _, score := range args.Scores {
+			if err := score.validate(); err != nil {
+				return finalizeResult{}, err
+			}
+		}
 
 		if err := ctx.State().Set(stateKeyAnswer, answer); err != nil {
 			return finalizeResult{}, fmt.Errorf("set answer state: %w", err)
@@ -755,6 +825,11 @@ func newFinalizeTool() (tool.Tool, error) {
 		if err := ctx.State().Set(stateKeyConfidence, args.Confidence); err != nil {
 			return finalizeResult{}, fmt.Errorf("set confidence state: %w", err)
 		}
+		if len(args.Scores) > 0 {
+			if err := ctx.State().Set(stateKeyCandidateScores, args.Scores); err != nil {
+				return finalizeResult{}, fmt.Errorf("set candidate scores state: %w", err)
+			}
+		}
 
 		if args.Stop {
 			ctx.Actions().Escalate = true
@@ -771,22 +846,25 @@ func newFinalizeTool() (tool.Tool, error) {
 	return t, nil
 }
 
+// JudgeAgentName is the name of the Judge Agent, the author of its events.
+const JudgeAgentName = "LLM-as-Judge"
+
 // NewJudgeAgent creates a Judge Agent that evaluates candidate answers and decides whether to finalize or continue.
-func NewJudgeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig) (agent.Agent, error) {
+func NewJudgeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
 	finalizeTool, err := newFinalizeTool()
 	if err != nil {
 		return nil, fmt.Errorf("build finalize tool: %w", err)
 	}
 
 	cfg := llmagent.Config{
-		Name:                  "LLM-as-Judge",
+		Name:                  JudgeAgentName,
 		Description:           `Ranks candidate agent outputs and signals when to stop.`,
 		Model:                 llm,
 		GenerateContentConfig: cloneGenConfig(genCfg),
 		Tools:                 []tool.Tool{finalizeTool},
 		Instruction: `Task: Decide STOP or CONTINUE; do not solve the problem yourself.
 
-Round {round_num}; vote margin {vote_margin?}; unique answers {unique_answers?}; coverage {coverage?}; entropy {answer_entropy?}.
+Round {round_num}; vote margin {vote_margin?}; score-weighted vote margin of the previous round {weighted_vote_margin?}; unique answers {unique_answers?}; coverage {coverage?}; entropy {answer_entropy?}.
 
 Stop only when:
 - vote margin >= ` + fmt.Sprintf("%.2f", defaultConfidenceThreshold) + ` AND round >= 2; and
@@ -800,15 +878,20 @@ Question:
 Candidate answers:
 {joined_answers}
 
+Sources cited by the candidates:
+{citations?}
+
 Instructions:
-1. Briefly compare answers; highlight disagreements or uncertainties.
-2. Choose the best current answer (copy verbatim); call finalize exactly once with answer, confidence 0-1, stop=true only when conditions met.
-3. If not safe to stop, call finalize with stop=false.
+1. Briefly compare answers; highlight disagreements or uncertainties; keep the sources that support the chosen answer.
+2. Score every candidate by the label it is listed under: correctness (0-1 likelihood its final answer is correct) and
+   reasoning (0-1 quality and rigor of its reasoning).
+3. Choose the best current answer (copy verbatim); call finalize exactly once with answer, confidence 0-1, the scores, stop=true only when conditions met.
+4. If not safe to stop, call finalize with stop=false.
 
 End with ` + code(`<<<YES>>>`) + ` when you set stop=true, else ` + code(`<<<NO>>>`) + `.`,
 	}
 
-	applySharedContext(&cfg)
+	applySharedContext(&cfg, p)
 
 	a, err := llmagent.New(cfg)
 	if err != nil {
@@ -840,6 +923,60 @@ type TumixConfig struct {
 	Judge      agent.Agent
 	MaxRounds  uint
 	MinRounds  uint
+
+	// JudgeModel is the model the Judge Agent runs on when Judge is nil, independent of the candidate models.
+	JudgeModel model.LLM
+	// JudgeGenerateContentConfig is the generation config for the Judge Agent built from JudgeModel.
+	JudgeGenerateContentConfig *genai.GenerateContentConfig
+
+	// SamplesPerAgent is the number of completions sampled from each candidate per round (self-consistency).
+	// All samples are fed into the vote statistics. The samples of a round run at the same time, each on a branch of
+	// its own, so a candidate does not see the answers of its other samples in its history. Zero means one sample.
+	SamplesPerAgent uint
+
+	// PromptCompression, when set, compresses long questions before the first round.
+	PromptCompression *PromptCompression
+
+	// AnswerSummary, when set, shrinks the previous answers the candidates read in the shared context.
+	AnswerSummary *AnswerSummary
+
+	// AnswerProtocol is the final answer format the candidates were built with and the answers are parsed in, also
+	// that of the Judge built from JudgeModel; nil means [DelimitedAnswers].
+	AnswerProtocol *AnswerProtocol
+
+	// ReformatAnswers asks a candidate whose answer has no recognizable final answer (see [AnswerProtocol]) for it
+	// once more, with a reminder of the format. How every candidate followed the format is recorded on the final
+	// event either way (see [FormatComplianceFromEvent]).
+	ReformatAnswers bool
+
+	// StateMerge decides which session state writes of the candidates of a round, each made in its own namespace,
+	// are merged back into the state shared by the run; empty means [StateMergeUnique] (see [CandidateStateKey]).
+	StateMerge StateMergePolicy
+
+	// Mode selects the orchestration of the rounds; empty means [ModeTumix].
+	Mode Mode
+
+	// Verifier, when set, checks every answer about to be finalized before the last round (see [NewVerifierAgent]).
+	// A rejected answer is not finalized; the next round runs with the verifier's report in the shared context.
+	Verifier agent.Agent
+
+	// RoundTimeout, when positive, bounds each round. The candidates that have not answered by then are cut short and
+	// the round ends with the answers collected so far, without consulting the Judge.
+	RoundTimeout time.Duration
+	// RunTimeout, when positive, bounds the whole run. When it fires the run finalizes with the score-weighted
+	// majority vote over the answers collected so far. Timeouts are recorded on the final event (see
+	// [TimeoutsFromEvent]).
+	RunTimeout time.Duration
+
+	// Triage, when set, rates the difficulty of the question before the first round and runs easy questions with the
+	// first Triage.EasyCandidates candidates only.
+	Triage *Triage
+
+	// Seed, when nonzero, makes the orchestration deterministic: the candidate answers of every round and sample, and
+	// their events, are taken in an order of the candidates derived from the seed instead of the order they finished
+	// in, and vote ties are broken by the seed. Together with seeded models (see [SeedScope]) and replayed responses,
+	// two runs with the same seed then produce the same output.
+	Seed int64
 }
 
 // NewTumixAgent creates the TUMIX Agent that performs multi-agent test-time scaling with tool-use mixture.
@@ -865,7 +1002,14 @@ func NewTumixAgentWithConfig(cfg TumixConfig) (agent.Loader, error) {
 		return nil, errors.New("at least one candidate agent is required")
 	}
 	if cfg.Judge == nil {
-		return nil, errors.New("judge agent is required")
+		if cfg.JudgeModel == nil {
+			return nil, errors.New("judge agent or judge model is required")
+		}
+		judge, err := NewJudgeAgent(cfg.JudgeModel, cfg.JudgeGenerateContentConfig, cfg.AnswerProtocol)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Judge = judge
 	}
 	if cfg.MaxRounds == 0 {
 		cfg.MaxRounds = defaultMaxRounds
@@ -876,6 +1020,23 @@ func NewTumixAgentWithConfig(cfg TumixConfig) (agent.Loader, error) {
 	if cfg.MinRounds > cfg.MaxRounds {
 		cfg.MinRounds = cfg.MaxRounds
 	}
+	if cfg.SamplesPerAgent == 0 {
+		cfg.SamplesPerAgent = defaultSamplesPerAgent
+	}
+	switch cfg.StateMerge {
+	case "":
+		cfg.StateMerge = StateMergeUnique
+	case StateMergeUnique, StateMergeLast, StateMergeNone:
+	default:
+		return nil, fmt.Errorf("unknown state merge policy %q", cfg.StateMerge)
+	}
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = ModeTumix
+	case ModeTumix, ModeDebate:
+	default:
+		return nil, fmt.Errorf("unknown orchestrator mode %q", cfg.Mode)
+	}
 
 	parallel, err := parallelagent.New(parallelagent.Config{
 		AgentConfig: agent.Config{
@@ -888,17 +1049,54 @@ func NewTumixAgentWithConfig(cfg TumixConfig) (agent.Loader, error) {
 		return nil, fmt.Errorf("build candidates workflow: %w", err)
 	}
 
+	// The easy subset shares the candidates, and the name and branches, of the full mixture; it is not part of the
+	// agent tree.
+	var easy agent.Agent
+	if cfg.Triage != nil && cfg.Triage.easyCandidates() < len(cfg.Candidates) {
+		easy, err = parallelagent.New(parallelagent.Config{
+			AgentConfig: agent.Config{
+				Name:        "candidates",
+				Description: "Runs the candidates for easy questions in parallel.",
+				SubAgents:   cfg.Candidates[:cfg.Triage.easyCandidates()],
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("build easy candidates workflow: %w", err)
+		}
+	}
+
+	subAgents := []agent.Agent{parallel, cfg.Judge}
+	if cfg.Verifier != nil {
+		if cfg.Mode != ModeTumix {
+			return nil, fmt.Errorf("verifier requires mode %q", ModeTumix)
+		}
+		subAgents = append(subAgents, cfg.Verifier)
+	}
+
 	orchestrator := &tumixOrchestrator{
-		candidateAgent: parallel,
-		judge:          cfg.Judge,
-		maxRounds:      cfg.MaxRounds,
-		minRounds:      cfg.MinRounds,
+		candidateAgent:  parallel,
+		easyAgent:       easy,
+		triage:          cfg.Triage,
+		judge:           cfg.Judge,
+		maxRounds:       cfg.MaxRounds,
+		minRounds:       cfg.MinRounds,
+		samplesPerAgent: cfg.SamplesPerAgent,
+		compression:     cfg.PromptCompression,
+		answerSummary:   cfg.AnswerSummary,
+		protocol:        cfg.AnswerProtocol.orDefault(),
+		reformatAnswers: cfg.ReformatAnswers,
+		stateMerge:      cfg.StateMerge,
+		verifier:        cfg.Verifier,
+		mode:            cfg.Mode,
+		roundTimeout:    cfg.RoundTimeout,
+		runTimeout:      cfg.RunTimeout,
+		seed:            cfg.Seed,
 	}
 
 	tumix, err := agent.New(agent.Config{
 		Name:        "tumix",
 		Description: "TUMIX: Multi-Agent Test-Time Scaling with Tool-Use Mixture.",
-		SubAgents:   append([]agent.Agent{parallel}, cfg.Judge),
+		SubAgents:   subAgents,
 		Run:         orchestrator.run,
 	})
 	if err != nil {
@@ -910,48 +1108,152 @@ func NewTumixAgentWithConfig(cfg TumixConfig) (agent.Loader, error) {
 
 type tumixOrchestrator struct {
 	candidateAgent agent.Agent
-	judge          agent.Agent
-	maxRounds      uint
-	minRounds      uint
-	prevTopAnswer  string
-	prevVoteMargin float64
+	// easyAgent runs the candidates of questions the triage rated easy; nil without triage.
+	easyAgent       agent.Agent
+	triage          *Triage
+	judge           agent.Agent
+	maxRounds       uint
+	minRounds       uint
+	samplesPerAgent uint
+	compression     *PromptCompression
+	answerSummary   *AnswerSummary
+	protocol        *AnswerProtocol
+	reformatAnswers bool
+	stateMerge      StateMergePolicy
+	verifier        agent.Agent
+	mode            Mode
+	roundTimeout    time.Duration
+	runTimeout      time.Duration
+	// seed, when nonzero, makes the run deterministic: see [TumixConfig.Seed].
+	seed int64
 }
 
 type candidateAnswer struct {
 	Agent string
 	Text  string
+	// Sample is the 1-based sample index when self-consistency sampling is enabled, otherwise zero.
+	Sample int
+	// Citations is the provenance gathered from the candidate's events leading to this answer.
+	Citations []Citation
+	// Confidence is the logprob confidence of the answer (see [eventConfidence]), zero when the backend reported none.
+	Confidence float64
+}
+
+// samples returns the number of candidate samples drawn per round.
+func (t *tumixOrchestrator) samples() uint {
+	return max(t.samplesPerAgent, defaultSamplesPerAgent)
 }
 
 func (t *tumixOrchestrator) run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
 	return func(yield func(*session.Event, error) bool) {
-		question := firstContentText(ctx.UserContent())
+		ctx, cancel := withTimeout(ctx, t.runTimeout, ErrRunTimeout)
+		defer cancel()
+		ctx, cancelInterrupt := withInterrupt(ctx)
+		defer cancelInterrupt()
+
+		ctx, question, stop := t.prepareQuestion(ctx, firstContentText(ctx.UserContent()), yield)
+		if stop {
+			return
+		}
 		if err := setState(ctx, stateKeyQuestion, question); err != nil {
 			yield(nil, err)
 			return
 		}
+		if err := setState(ctx, stateKeyTimeouts, nil); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := setState(ctx, stateKeyRoundStats, nil); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := setState(ctx, stateKeyFormatCompliance, nil); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := clearJudgeRationales(ctx, t.maxRounds); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := t.triageQuestion(ctx, question); err != nil {
+			yield(nil, err)
+			return
+		}
+		if t.mode == ModeDebate {
+			t.debate(ctx, yield)
+			return
+		}
 
-		var lastAnswers []candidateAnswer
+		// The state of the run lives in the invocation, not in the orchestrator, which concurrent runs share.
+		var (
+			lastAnswers    []candidateAnswer
+			citations      []Citation
+			timeouts       []string
+			prevTopAnswer  string
+			prevVoteMargin float64
+			cancelRound    context.CancelFunc = func() {}
+		)
+		defer func() { cancelRound() }()
 		for round := uint(1); round <= t.maxRounds; round++ {
+			cancelRound()
+			var (
+				rctx   agent.InvocationContext
+				ryield func(*session.Event, error) bool
+			)
+			rctx, ryield, cancelRound = t.roundContext(ctx, yield)
+
+			if round > 1 {
+				roundEnded(ctx, round-1, t.maxRounds)
+			}
 			if err := setState(ctx, stateKeyRound, round); err != nil {
 				yield(nil, err)
 				return
 			}
-			if err := setState(ctx, stateKeyJoined, joinAnswers(lastAnswers)); err != nil {
+			// Scores rate the answers of one round; drop those of the previous round.
+			if err := setState(ctx, stateKeyCandidateScores, nil); err != nil {
+				yield(nil, err)
+				return
+			}
+			joined, shared := t.shareAnswers(ctx, lastAnswers)
+			if err := setState(ctx, stateKeyJoined, joined); err != nil {
+				yield(nil, err)
+				return
+			}
+			if err := setRefineInstruction(ctx, t.protocol, lastAnswers); err != nil {
 				yield(nil, err)
 				return
 			}
 
-			answers, stop := t.runCandidates(ctx, yield)
+			answers, stop := t.runCandidates(rctx, round, ryield)
 			if stop {
 				return
 			}
+			timeout, stop := t.recordTimeout(rctx, round, &timeouts, yield)
+			if stop {
+				return
+			}
+			if timeout != nil && len(answers) == 0 {
+				// Nothing arrived in time; keep the answers of the previous round.
+				answers = lastAnswers
+			}
+			prevAnswers := lastAnswers
 			lastAnswers = answers
+			citations = mergeCitations(citations, answers)
+			if err := setState(ctx, stateKeyCitations, joinCitations(citations)); err != nil {
+				yield(nil, err)
+				return
+			}
 			if len(lastAnswers) == 0 {
+				if timeout != nil {
+					if endsRun(timeout) {
+						break
+					}
+					continue
+				}
 				if round < t.minRounds {
 					continue
 				}
-				if t.runJudge(ctx, yield) {
-					t.emitFinalFromState(ctx, yield)
+				if t.runJudge(rctx, ryield) && t.finalize(rctx, round, citations, ryield) {
 					return
 				}
 				continue
@@ -961,29 +1263,24 @@ func (t *tumixOrchestrator) run(ctx agent.InvocationContext) iter.Seq2[*session.
 				yield(nil, err)
 				return
 			}
-			stats := computeStats(lastAnswers, len(t.candidateAgent.SubAgents()))
-			if err := setState(ctx, stateKeyVoteMargin, stats.voteMargin); err != nil {
-				yield(nil, err)
-				return
-			}
-			if err := setState(ctx, stateKeyUnique, stats.unique); err != nil {
-				yield(nil, err)
-				return
+			stats := computeStats(t.protocol, lastAnswers, len(t.candidates(ctx).SubAgents())*int(t.samples()), tieBreaker(t.seed)) //nolint:gosec // samples is small
+			if round > 1 {
+				stats.changes = answerChanges(t.protocol, prevAnswers, lastAnswers)
 			}
-			if err := setState(ctx, stateKeyCoverage, stats.coverage); err != nil {
+			stats.sharedTokens, stats.savedTokens = shared.tokens, shared.saved
+			if err := setRoundStats(ctx, stats); err != nil {
 				yield(nil, err)
 				return
 			}
-			if err := setState(ctx, stateKeyEntropy, stats.answerEntropy); err != nil {
-				yield(nil, err)
-				return
-			}
-			if err := setState(ctx, stateKeyTopAnswer, stats.topAnswer); err != nil {
-				yield(nil, err)
-				return
+			if timeout != nil {
+				// The round is over before the Judge could run; the run finalizes on the answers collected so far.
+				if endsRun(timeout) {
+					break
+				}
+				continue
 			}
 
-			if round >= t.minRounds && stats.topAnswer != "" && stats.topAnswer == t.prevTopAnswer && stats.voteMargin >= defaultConfidenceThreshold && t.prevVoteMargin >= defaultConfidenceThreshold {
+			if round >= t.minRounds && stats.topAnswer != "" && stats.topAnswer == prevTopAnswer && stats.voteMargin >= defaultConfidenceThreshold && prevVoteMargin >= defaultConfidenceThreshold {
 				if err := setState(ctx, stateKeyAnswer, stats.topAnswer); err != nil {
 					yield(nil, err)
 					return
@@ -992,27 +1289,47 @@ func (t *tumixOrchestrator) run(ctx agent.InvocationContext) iter.Seq2[*session.
 					yield(nil, err)
 					return
 				}
-				t.emitFinalFromState(ctx, yield)
-				return
+				if t.finalize(rctx, round, citations, ryield) {
+					return
+				}
+				// The verifier rejected the consensus; it has to form again.
+				prevTopAnswer, prevVoteMargin = "", 0
+				continue
 			}
 
 			if round >= t.minRounds && stats.topAnswer != "" {
-				t.prevTopAnswer = stats.topAnswer
-				t.prevVoteMargin = stats.voteMargin
+				prevTopAnswer = stats.topAnswer
+				prevVoteMargin = stats.voteMargin
 			}
 
 			if round < t.minRounds {
 				continue
 			}
 
-			if t.runJudge(ctx, yield) {
-				t.emitFinalFromState(ctx, yield)
+			stop = t.runJudge(rctx, ryield)
+			if err := recordWeightedMargin(ctx, t.protocol, lastAnswers); err != nil {
+				yield(nil, err)
 				return
 			}
+			if stop && t.finalize(rctx, round, citations, ryield) {
+				return
+			}
+			timeout, stop = t.recordTimeout(rctx, round, &timeouts, yield)
+			if stop {
+				return
+			}
+			if endsRun(timeout) {
+				break
+			}
 		}
 
 		if len(lastAnswers) > 0 {
-			answer, conf := majorityVote(lastAnswers)
+			scores, err := stateCandidateScores(ctx)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			answer, conf := weightedVote(t.protocol, lastAnswers, scores, tieBreaker(t.seed))
 			if err := setState(ctx, stateKeyAnswer, answer); err != nil {
 				yield(nil, err)
 				return
@@ -1026,51 +1343,225 @@ func (t *tumixOrchestrator) run(ctx agent.InvocationContext) iter.Seq2[*session.
 			yield(nil, err)
 			return
 		}
-		t.emitFinalFromState(ctx, yield)
+		t.emitFinalFromState(ctx, citations, yield)
+	}
+}
+
+// runCandidates runs every candidate agent once per sample and collects their answers.
+//
+// The samples run at the same time, each on a branch and in a state layer of its own (see [sampleContext]), and their
+// answers are collected in sample order. With self-consistency sampling enabled, each answer is tagged with its 1-based
+// sample index.
+func (t *tumixOrchestrator) runCandidates(ctx agent.InvocationContext, round uint, yield func(*session.Event, error) bool) ([]candidateAnswer, bool) {
+	samples := int(t.samples()) //nolint:gosec // samples is small
+	candidates := t.candidates(ctx)
+
+	gctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	group := &deadlineContext{InvocationContext: ctx, ctx: gctx}
+
+	var (
+		wg      sync.WaitGroup
+		events  = make(chan scopedResult)
+		done    = make(chan struct{})
+		results = make([]sampleResult, samples)
+		held    = make([][]scopedResult, samples)
+		answers = make([]candidateAnswer, 0, len(candidates.SubAgents())*samples)
+	)
+	for i := range samples {
+		wg.Go(func() {
+			// A seeded run holds the complete events back and yields them sample by sample once all samples are done,
+			// so they do not interleave in the order the samples finished in.
+			emit := func(event *session.Event, err error) bool {
+				if t.seed != 0 && err == nil && event != nil && !event.Partial {
+					held[i] = append(held[i], scopedResult{event: event})
+					return true
+				}
+				select {
+				case <-done:
+					return false
+				case events <- scopedResult{event: event, err: err}:
+					return true
+				}
+			}
+			results[i] = t.runSample(sampleContext(group, round, i, samples), round, i, candidates, emit)
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	stopped := func() ([]candidateAnswer, bool) {
+		close(done)
+		return answers, true
+	}
+	for res := range events {
+		if !yield(res.event, res.err) {
+			return stopped()
+		}
+	}
+	var formats formatTally
+	scopes := newStateScopes()
+	for i, res := range results {
+		for _, r := range held[i] {
+			if !yield(r.event, r.err) {
+				return stopped()
+			}
+		}
+		if res.stop {
+			return stopped()
+		}
+		answers = append(answers, res.answers...)
+		formats.merge(res.formats)
+		scopes.add(ctx, res.scopes)
 	}
+	close(done)
+
+	if err := addFormatCompliance(ctx, formats); err != nil {
+		yield(nil, err)
+		return answers, true
+	}
+	if !scopes.merge(ctx, t.stateMerge, candidates.SubAgents(), yield) {
+		return answers, true
+	}
+	return answers, false
+}
+
+// sampleResult is the outcome of one sample of a round run by [tumixOrchestrator.runSample].
+type sampleResult struct {
+	answers []candidateAnswer
+	formats formatTally
+	scopes  *stateScopes
+	// stop reports that the run must stop.
+	stop bool
 }
 
-func (t *tumixOrchestrator) runCandidates(ctx agent.InvocationContext, yield func(*session.Event, error) bool) ([]candidateAnswer, bool) {
-	answers := make([]candidateAnswer, 0, len(t.candidateAgent.SubAgents()))
-	for event, err := range t.candidateAgent.Run(ctx) {
+// sampleContext returns the context sample i of samples of a round runs in. With several samples, every sample runs
+// on a branch of its own, so the candidates do not see the answers of the other samples in their history and the
+// samples stay independent. Every sample also has a state layer of its own, over the shared state, holding its request
+// scope and the format reminder of its reprompts.
+func sampleContext(ctx agent.InvocationContext, round uint, i, samples int) agent.InvocationContext {
+	// Identical candidate requests may only share a model call within one sample of a round.
+	layer := &scopedState{shared: ctx.Session().State()}
+	_ = layer.Set(stateKeyRequestScope, fmt.Sprintf("%s/%d/%d", ctx.InvocationID(), round, i))
+	branch := ctx.Branch()
+	if samples > 1 {
+		branch = joinBranch(branch, fmt.Sprintf("sample%d", i+1))
+	}
+	return &scopedContext{
+		InvocationContext: ctx,
+		session:           &scopedSession{Session: ctx.Session(), state: layer},
+		branch:            branch,
+	}
+}
+
+// runSample runs every candidate agent once for sample i of a round in ctx, a [sampleContext], and collects their
+// answers, yielding their events.
+func (t *tumixOrchestrator) runSample(ctx agent.InvocationContext, round uint, i int, candidates agent.Agent, yield func(*session.Event, error) bool) sampleResult {
+	res := sampleResult{scopes: newStateScopes()}
+	sample := 0
+	if t.samples() > 1 {
+		sample = i + 1
+	}
+	pending := make(map[string][]Citation)
+	handle := func(event *session.Event, err error) bool {
 		if !yield(event, err) {
-			return answers, true
+			return false
 		}
-		if err != nil || event == nil || event.Content == nil {
-			continue
+		// Partial events carry streamed deltas; the aggregated event that follows holds the full answer.
+		if err != nil || event == nil || event.Partial {
+			return true
+		}
+		if cites := eventCitations(event); len(cites) > 0 {
+			pending[event.Author] = append(pending[event.Author], cites...)
+		}
+		if event.Content == nil {
+			return true
 		}
-		text := firstTextFromContent(event.Content)
+		text := candidateText(event.Content)
 		if text == "" {
+			return true
+		}
+		res.answers = append(res.answers, candidateAnswer{
+			Agent:      event.Author,
+			Text:       strings.TrimSpace(text),
+			Sample:     sample,
+			Citations:  pending[event.Author],
+			Confidence: eventConfidence(event),
+		})
+		delete(pending, event.Author)
+		return true
+	}
+
+	// Every candidate runs in its own state namespace; a candidates agent without sub-agents runs as is.
+	events := recoverRun(ctx, candidates.Name(), candidates.Run(ctx))
+	if subs := candidates.SubAgents(); len(subs) > 0 {
+		events = res.scopes.run(ctx, candidates, subs...)
+	}
+
+	// A seeded run holds the complete events of the sample back and handles them in the seeded order of the
+	// candidates rather than in the order they finished, so its answers and events do not depend on timing.
+	var held []*session.Event
+	for event, err := range events {
+		if t.seed != 0 && err == nil && event != nil && !event.Partial {
+			held = append(held, event)
 			continue
 		}
-		answers = append(answers, candidateAnswer{Agent: event.Author, Text: strings.TrimSpace(text)})
+		if !handle(event, err) {
+			res.stop = true
+			return res
+		}
 	}
-	return answers, false
+	if len(held) > 0 {
+		sortBySeededOrder(held, seededOrder(t.seed, round, sample, candidates.SubAgents()))
+		for _, event := range held {
+			if !handle(event, nil) {
+				res.stop = true
+				return res
+			}
+		}
+	}
+	res.stop = !t.reformat(ctx, candidates, res.scopes, res.answers, &res.formats, yield)
+	return res
 }
 
 func (t *tumixOrchestrator) runJudge(ctx agent.InvocationContext, yield func(*session.Event, error) bool) bool {
 	stop := false
-	for event, err := range t.judge.Run(ctx) {
+	var transcript judgeTranscript
+	// A panicking Judge ends its turn without stopping the run.
+	for event, err := range recoverRun(ctx, t.judge.Name(), t.judge.Run(ctx)) {
 		if !yield(event, err) {
 			return true
 		}
 		if err != nil {
 			continue
 		}
+		transcript.add(event)
 		if event != nil && event.Actions.Escalate {
 			stop = true
 			if text := firstTextFromContent(event.Content); text != "" {
-				if err := setState(ctx, stateKeyJudgeAnswer, normalizeAnswer(text)); err != nil {
+				if err := setState(ctx, stateKeyJudgeAnswer, t.protocol.Strip(text)); err != nil {
 					yield(nil, err)
 					return true
 				}
 			}
 		}
 	}
+	if err := transcript.save(ctx); err != nil {
+		yield(nil, err)
+		return true
+	}
 	return stop
 }
 
-func (t *tumixOrchestrator) emitFinalFromState(ctx agent.InvocationContext, yield func(*session.Event, error) bool) {
+func (t *tumixOrchestrator) emitFinalFromState(ctx agent.InvocationContext, citations []Citation, yield func(*session.Event, error) bool) {
+	emitFinalFromState(ctx, citations, yield)
+}
+
+// emitFinalFromState yields the final answer event built from the answer and confidence in the session state,
+// persisting the final state keys through its state delta. The answer of an interrupted run is marked provisional.
+func emitFinalFromState(ctx agent.InvocationContext, citations []Citation, yield func(*session.Event, error) bool) {
 	answerVal, err := getState(ctx, stateKeyAnswer)
 	if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
 		yield(nil, err)
@@ -1097,7 +1588,11 @@ func (t *tumixOrchestrator) emitFinalFromState(ctx agent.InvocationContext, yiel
 		return
 	}
 
-	content := genai.NewContentFromText(fmt.Sprintf("Final answer (conf %s): %s", conf, answer), genai.RoleModel)
+	label := "Final answer"
+	if interrupted(ctx) {
+		label = "Provisional answer (interrupted)"
+	}
+	content := genai.NewContentFromText(fmt.Sprintf("%s (conf %s): %s", label, conf, answer), genai.RoleModel)
 	event := session.NewEvent(ctx.InvocationID())
 	event.Author = "tumix"
 	event.Content = content
@@ -1116,21 +1611,156 @@ func (t *tumixOrchestrator) emitFinalFromState(ctx agent.InvocationContext, yiel
 	if joinedVal != nil {
 		event.Actions.StateDelta[stateKeyJoined] = joinedVal
 	}
+	for _, key := range []string{stateKeyOriginalQuestion, stateKeyCompressedQuestion} {
+		val, err := getState(ctx, key)
+		if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+			yield(nil, err)
+			return
+		}
+		if val != nil {
+			event.Actions.StateDelta[key] = val
+		}
+	}
+	if len(citations) > 0 {
+		event.CustomMetadata = map[string]any{MetadataKeyCitations: citations}
+		event.Actions.StateDelta[MetadataKeyCitations] = citations
+	}
+	scores, err := stateCandidateScores(ctx)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	if len(scores) > 0 {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyCandidateScores] = scores
+		event.Actions.StateDelta[stateKeyCandidateScores] = scores
+	}
+	timeouts, err := getState(ctx, stateKeyTimeouts)
+	if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+		yield(nil, err)
+		return
+	}
+	if timeouts != nil {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyTimeouts] = timeouts
+		event.Actions.StateDelta[stateKeyTimeouts] = timeouts
+	}
+	roundStatsVal, err := getState(ctx, stateKeyRoundStats)
+	if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+		yield(nil, err)
+		return
+	}
+	roundStats, err := roundStatsList(roundStatsVal)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	if len(roundStats) > 0 {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyRoundStats] = roundStats
+		event.Actions.StateDelta[stateKeyRoundStats] = roundStats
+	}
+	triage, err := stateTriage(ctx)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	if triage != nil {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyTriage] = triage
+		event.Actions.StateDelta[stateKeyTriage] = triage
+	}
+	compliance, err := stateFormatCompliance(ctx)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	if len(compliance) > 0 {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyFormatCompliance] = compliance
+		event.Actions.StateDelta[stateKeyFormatCompliance] = compliance
+	}
+	rationales, err := stateJudgeRationales(ctx)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	for _, rationale := range rationales {
+		event.Actions.StateDelta[judgeRationaleKey(rationale.Round)] = rationale.Text
+	}
+	if len(rationales) > 0 {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyJudgeRationale] = &rationales[len(rationales)-1]
+	}
+	if interrupted(ctx) {
+		if event.CustomMetadata == nil {
+			event.CustomMetadata = make(map[string]any)
+		}
+		event.CustomMetadata[MetadataKeyProvisional] = true
+	}
 	yield(event, nil)
 }
 
+// maxPooledAnswerBuffer is the capacity above which a buffer of [answerBuffers] is dropped rather than pooled, so one
+// run with very long answers does not pin its buffers for the life of the process.
+const maxPooledAnswerBuffer = 64 << 10
+
+// answerBuffers pools the buffers the answers of a round are joined in, several times a round.
+var answerBuffers = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getAnswerBuffer() *bytes.Buffer {
+	return answerBuffers.Get().(*bytes.Buffer)
+}
+
+func putAnswerBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledAnswerBuffer {
+		return
+	}
+	buf.Reset()
+	answerBuffers.Put(buf)
+}
+
 func joinAnswers(ans []candidateAnswer) string {
 	if len(ans) == 0 {
 		return ""
 	}
-	sb := strings.Builder{}
+	buf := getAnswerBuffer()
+	defer putAnswerBuffer(buf)
 	for i, a := range ans {
 		if i > 0 {
-			sb.WriteString("\n")
+			buf.WriteByte('\n')
 		}
-		sb.WriteString(fmt.Sprintf("- %s: %s", a.Agent, strings.TrimSpace(a.Text)))
+		buf.WriteString("- ")
+		writeAnswerLabel(buf, a)
+		buf.WriteString(": ")
+		buf.WriteString(strings.TrimSpace(a.Text))
+	}
+	return buf.String()
+}
+
+// writeAnswerLabel writes the name of the candidate sample giving a to buf, "agent" or "agent#sample".
+func writeAnswerLabel(buf *bytes.Buffer, a candidateAnswer) {
+	buf.WriteString(a.Agent)
+	if a.Sample > 0 {
+		buf.WriteByte('#')
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(a.Sample), 10))
 	}
-	return sb.String()
 }
 
 func firstTextFromContent(c *genai.Content) string {
@@ -1160,26 +1790,94 @@ func firstContentText(c *genai.Content) string {
 	return ""
 }
 
-func majorityVote(ans []candidateAnswer) (answer string, confidence float64) {
-	if len(ans) == 0 {
-		return "", 0
+// answerTally is a distinct candidate answer and the number of candidates giving it.
+type answerTally struct {
+	Answer string
+	Count  int
+}
+
+// tallyAnswers counts candidate answers, merging equivalent ones.
+//
+// Answers are compared by normalized text. Numeric answers are also evaluated exactly with [mathcheck] so forms such
+// as "1/3", "0.333…", and "0.33" count as one answer instead of splitting the vote; the group is represented by its
+// most exact form. The result is in order of first appearance.
+func tallyAnswers(p *AnswerProtocol, ans []candidateAnswer) []answerTally {
+	tallies, _ := groupAnswers(p, ans)
+	return tallies
+}
+
+// groupAnswers is [tallyAnswers] that also returns, for each answer, the index of its tally.
+func groupAnswers(p *AnswerProtocol, ans []candidateAnswer) (tallies []answerTally, groupOf []int) {
+	return groupFinalAnswers(finalAnswers(p, ans))
+}
+
+// finalAnswers returns the final answer of every answer of ans (see [finalAnswerText]), so the statistics of a round
+// extract each one once.
+func finalAnswers(p *AnswerProtocol, ans []candidateAnswer) []string {
+	finals := make([]string, len(ans))
+	for i, a := range ans {
+		finals[i] = finalAnswerText(p, a.Text)
 	}
-	cnts := make(map[string]int)
-	for _, a := range ans {
-		key := normalizeAnswer(a.Text)
-		cnts[key]++
+	return finals
+}
+
+// groupFinalAnswers is [groupAnswers] over the final answers of the answers.
+func groupFinalAnswers(finals []string) (tallies []answerTally, groupOf []int) {
+	// Each distinct final answer is parsed once, and the numeric ones are grouped together so the groups do not
+	// depend on the order the answers arrived in.
+	var nums []mathcheck.Number
+	numOf := make(map[string]int, len(finals))
+	for _, key := range finals {
+		if _, seen := numOf[key]; seen {
+			continue
+		}
+		numOf[key] = -1
+		if num, ok := mathcheck.Parse(key); ok {
+			numOf[key] = len(nums)
+			nums = append(nums, num)
+		}
 	}
-	type kv struct {
-		Answer string
-		Count  int
+	classes := mathcheck.Group(nums)
+
+	tallies = make([]answerTally, 0, len(finals))
+	groupOf = make([]int, len(finals))
+	rounded := make([]bool, 0, len(finals)) // whether the tally is represented by a decimal literal
+	byText := make(map[string]int, len(finals))
+	byClass := make(map[int]int, len(nums))
+	for i, key := range finals {
+		g, ok := byText[key]
+		if !ok {
+			n := numOf[key]
+			if n >= 0 {
+				g, ok = byClass[classes[n]]
+			}
+			switch {
+			case !ok:
+				g = len(tallies)
+				tallies = append(tallies, answerTally{Answer: key})
+				rounded = append(rounded, n >= 0 && nums[n].Decimals >= 0)
+				if n >= 0 {
+					byClass[classes[n]] = g
+				}
+			case rounded[g] && nums[n].Decimals < 0:
+				tallies[g].Answer, rounded[g] = key, false
+			}
+			byText[key] = g
+		}
+		tallies[g].Count++
+		groupOf[i] = g
 	}
-	pairs := make([]kv, 0, len(cnts))
-	for k, v := range cnts {
-		pairs = append(pairs, kv{Answer: k, Count: v})
+	return tallies, groupOf
+}
+
+func majorityVote(p *AnswerProtocol, ans []candidateAnswer, ties tieBreaker) (answer string, confidence float64) {
+	if len(ans) == 0 {
+		return "", 0
 	}
+	pairs := tallyAnswers(p, ans)
 	sort.Slice(pairs, func(i, j int) bool {
 		if pairs[i].Count == pairs[j].Count {
-			return pairs[i].Answer < pairs[j].Answer
+			return ties.less(pairs[i].Answer, pairs[j].Answer)
 		}
 		return pairs[i].Count > pairs[j].Count
 	})
@@ -1194,29 +1892,57 @@ type roundStats struct {
 	coverage      float64
 	answerEntropy float64
 	topAnswer     string
+	// changes compare the answers with those of the previous round, nil in the first round.
+	changes []AnswerChange
+	// conflicts are the specific disagreements between the answers.
+	conflicts []Conflict
+	// sharedTokens and savedTokens are what the summary of the previous answers the candidates read saved (see
+	// [sharedAnswers]).
+	sharedTokens int
+	savedTokens  int
 }
 
-func computeStats(ans []candidateAnswer, candidateCount int) roundStats {
+// setRoundStats stores the vote statistics of a round in the session state, where the candidates and the Judge read
+// them, and records them for the final event.
+func setRoundStats(ctx agent.InvocationContext, stats roundStats) error {
+	if err := appendRoundStats(ctx, stats); err != nil {
+		return err
+	}
+	for _, kv := range [...]struct {
+		key string
+		val any
+	}{
+		{stateKeyVoteMargin, stats.voteMargin},
+		{stateKeyUnique, stats.unique},
+		{stateKeyCoverage, stats.coverage},
+		{stateKeyEntropy, stats.answerEntropy},
+		{stateKeyTopAnswer, stats.topAnswer},
+	} {
+		if err := setState(ctx, kv.key, kv.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func computeStats(p *AnswerProtocol, ans []candidateAnswer, candidateCount int, ties tieBreaker) roundStats {
 	if len(ans) == 0 || candidateCount <= 0 {
 		return roundStats{}
 	}
 
-	cnts := make(map[string]int)
-	for _, a := range ans {
-		key := normalizeAnswer(a.Text)
-		cnts[key]++
-	}
+	finals := finalAnswers(p, ans)
+	tallies, _ := groupFinalAnswers(finals)
 
-	unique := len(cnts)
+	unique := len(tallies)
 	total := len(ans)
 	topCount := 0
 	topAnswer := ""
 	entropy := 0.0
-	for k, v := range cnts {
-		if v > topCount || (v == topCount && k < topAnswer) {
-			topCount, topAnswer = v, k
+	for _, t := range tallies {
+		if t.Count > topCount || (t.Count == topCount && ties.less(t.Answer, topAnswer)) {
+			topCount, topAnswer = t.Count, t.Answer
 		}
-		p := float64(v) / float64(total)
+		p := float64(t.Count) / float64(total)
 		if p > 0 {
 			entropy -= p * math.Log2(p)
 		}
@@ -1228,12 +1954,6 @@ func computeStats(ans []candidateAnswer, candidateCount int) roundStats {
 		coverage:      float64(total) / float64(candidateCount),
 		answerEntropy: entropy,
 		topAnswer:     topAnswer,
+		conflicts:     finalAnswerConflicts(ans, finals),
 	}
 }
-
-func normalizeAnswer(text string) string {
-	trimmed := strings.TrimSpace(text)
-	trimmed = strings.TrimPrefix(trimmed, `<<<`)
-	trimmed = strings.TrimSuffix(trimmed, `>>>`)
-	return strings.TrimSpace(trimmed)
-}
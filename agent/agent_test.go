@@ -19,6 +19,7 @@ package agent
 import (
 	"errors"
 	"iter"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -274,67 +275,71 @@ func TestAgentConstructors(t *testing.T) {
 		wantName string
 	}{
 		"NewBaseAgent": {
-			build:    func() (adkagent.Agent, error) { return NewBaseAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewBaseAgent(llm, genCfg, nil) },
 			wantName: "base",
 		},
 		"NewCoTAgent": {
-			build:    func() (adkagent.Agent, error) { return NewCoTAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewCoTAgent(llm, genCfg, nil) },
 			wantName: "cot",
 		},
 		"NewCoTCodeAgent": {
-			build:    func() (adkagent.Agent, error) { return NewCoTCodeAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewCoTCodeAgent(llm, genCfg, nil) },
 			wantName: "cot-code",
 		},
 		"NewSearchAgent": {
-			build:    func() (adkagent.Agent, error) { return NewSearchAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewSearchAgent(llm, genCfg, nil) },
 			wantName: "search",
 		},
 		"NewCodeAgent": {
-			build:    func() (adkagent.Agent, error) { return NewCodeAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewCodeAgent(llm, genCfg, nil) },
 			wantName: "code",
 		},
 		"NewCodePlusAgent": {
-			build:    func() (adkagent.Agent, error) { return NewCodePlusAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewCodePlusAgent(llm, genCfg, nil) },
 			wantName: "code-plus",
 		},
 		"NewDualToolGSAgent": {
-			build:    func() (adkagent.Agent, error) { return NewDualToolGSAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewDualToolGSAgent(llm, genCfg, nil) },
 			wantName: "dual-tool-google-search",
 		},
 		"NewDualToolLLMAgent": {
-			build:    func() (adkagent.Agent, error) { return NewDualToolLLMAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewDualToolLLMAgent(llm, genCfg, nil) },
 			wantName: "dual-tool-llm-search",
 		},
 		"NewDualToolComAgent": {
-			build:    func() (adkagent.Agent, error) { return NewDualToolComAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewDualToolComAgent(llm, genCfg, nil) },
 			wantName: "dual-tool-combine-search",
 		},
 		"NewGuidedGSAgent": {
-			build:    func() (adkagent.Agent, error) { return NewGuidedGSAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewGuidedGSAgent(llm, genCfg, nil) },
 			wantName: "guided-google-search",
 		},
 		"NewGuidedLLMAgent": {
-			build:    func() (adkagent.Agent, error) { return NewGuidedLLMAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewGuidedLLMAgent(llm, genCfg, nil) },
 			wantName: "guided-llm-search",
 		},
 		"NewGuidedComAgent": {
-			build:    func() (adkagent.Agent, error) { return NewGuidedComAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewGuidedComAgent(llm, genCfg, nil) },
 			wantName: "guided-combine-search",
 		},
 		"NewGuidedPlusGSAgent": {
-			build:    func() (adkagent.Agent, error) { return NewGuidedPlusGSAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewGuidedPlusGSAgent(llm, genCfg, nil) },
 			wantName: "guided-plus-google-search",
 		},
 		"NewGuidedPlusLLMAgent": {
-			build:    func() (adkagent.Agent, error) { return NewGuidedPlusLLMAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewGuidedPlusLLMAgent(llm, genCfg, nil) },
 			wantName: "guided-plus-llm-search",
 		},
 		"NewGuidedPlusComAgent": {
-			build:    func() (adkagent.Agent, error) { return NewGuidedPlusComAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewGuidedPlusComAgent(llm, genCfg, nil) },
 			wantName: "guided-plus-combine-Search",
 		},
+		"NewVisionAgent": {
+			build:    func() (adkagent.Agent, error) { return NewVisionAgent(llm, genCfg, nil) },
+			wantName: "vision",
+		},
 		"NewJudgeAgent": {
-			build:    func() (adkagent.Agent, error) { return NewJudgeAgent(llm, genCfg) },
+			build:    func() (adkagent.Agent, error) { return NewJudgeAgent(llm, genCfg, nil) },
 			wantName: "LLM-as-Judge",
 		},
 	}
@@ -394,7 +399,7 @@ func TestNewRefinementAgent(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			a, err := NewRefinementAgent(tt.subAgents...)
+			a, err := NewRefinementAgent(nil, tt.subAgents...)
 			if err != nil {
 				t.Fatalf("NewRefinementAgent() err = %v, want nil", err)
 			}
@@ -490,6 +495,15 @@ func TestNewTumixAgentWrappers(t *testing.T) {
 				})
 			},
 		},
+		"success: judge built from JudgeModel": {
+			build: func() (adkagent.Loader, error) {
+				return NewTumixAgentWithConfig(TumixConfig{
+					Candidates: []adkagent.Agent{stubCandidate("A")},
+					JudgeModel: &stubLLM{},
+				})
+			},
+			wantAgents: []string{"tumix", "candidates", JudgeAgentName},
+		},
 		"success: MinRounds clamps to MaxRounds": {
 			build: func() (adkagent.Loader, error) {
 				return NewTumixAgentWithConfig(TumixConfig{
@@ -629,13 +643,23 @@ func TestMajorityVoteTieBreak(t *testing.T) {
 			wantAnswer:     "a",
 			wantConfidence: 0.5,
 		},
+		"numeric: equivalent forms share one vote represented by the exact form": {
+			answers: []candidateAnswer{
+				{Agent: "a", Text: "0.33"},
+				{Agent: "b", Text: "<<<1/3>>>"},
+				{Agent: "c", Text: "The result is <<<0.333…>>>"},
+				{Agent: "d", Text: "1/4"},
+			},
+			wantAnswer:     "1/3",
+			wantConfidence: 0.75,
+		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			gotAnswer, gotConfidence := majorityVote(tt.answers)
+			gotAnswer, gotConfidence := majorityVote(DelimitedAnswers, tt.answers, 0)
 			if diff := cmp.Diff(tt.wantAnswer, gotAnswer); diff != "" {
 				t.Fatalf("majorityVote answer mismatch (-want +got):\n%s", diff)
 			}
@@ -648,6 +672,58 @@ func TestMajorityVoteTieBreak(t *testing.T) {
 	}
 }
 
+func TestGroupFinalAnswersOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		finals []string
+		want   []answerTally
+	}{
+		"rounded decimals are different answers": {
+			finals: []string{"0.325", "0.334", "0.33"},
+			want:   []answerTally{{Answer: "0.325", Count: 1}, {Answer: "0.33", Count: 1}, {Answer: "0.334", Count: 1}},
+		},
+		"rounded decimal joins the exact answer": {
+			finals: []string{"0.33", "0.334", "1/3", "0.33"},
+			want:   []answerTally{{Answer: "0.334", Count: 1}, {Answer: "1/3", Count: 3}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, finals := range permutations(tt.finals) {
+				got, groupOf := groupFinalAnswers(finals)
+				for i, g := range groupOf {
+					if !sameVote(DelimitedAnswers, finals[i], got[g].Answer) {
+						t.Fatalf("groupFinalAnswers(%q) put %q in the group of %q", finals, finals[i], got[g].Answer)
+					}
+				}
+				slices.SortFunc(got, func(a, b answerTally) int { return strings.Compare(a.Answer, b.Answer) })
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Fatalf("groupFinalAnswers(%q) mismatch (-want +got):\n%s", finals, diff)
+				}
+			}
+		})
+	}
+}
+
+// permutations returns every ordering of s.
+func permutations(s []string) [][]string {
+	if len(s) <= 1 {
+		return [][]string{slices.Clone(s)}
+	}
+	var perms [][]string
+	for i := range s {
+		rest := slices.Concat(s[:i], s[i+1:])
+		for _, p := range permutations(rest) {
+			perms = append(perms, append([]string{s[i]}, p...))
+		}
+	}
+	return perms
+}
+
 func TestComputeStatsEmptyInputs(t *testing.T) {
 	t.Parallel()
 
@@ -677,7 +753,7 @@ func TestComputeStatsEmptyInputs(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			got := computeStats(tt.answers, tt.candidateCount)
+			got := computeStats(DelimitedAnswers, tt.answers, tt.candidateCount, 0)
 			if diff := cmp.Diff(tt.want, got,
 				cmp.AllowUnexported(roundStats{}),
 				cmpopts.EquateApprox(0, 1e-12),
@@ -692,10 +768,11 @@ func TestRunCandidates(t *testing.T) {
 	t.Parallel()
 
 	tests := map[string]struct {
-		candidateAgent adkagent.Agent
-		yield          func(*session.Event, error) bool
-		wantAnswers    []candidateAnswer
-		wantStop       bool
+		candidateAgent  adkagent.Agent
+		samplesPerAgent uint
+		yield           func(*session.Event, error) bool
+		wantAnswers     []candidateAnswer
+		wantStop        bool
 	}{
 		"stop: yield aborts iteration": {
 			candidateAgent: mustAgent(adkagent.New(adkagent.Config{
@@ -761,6 +838,28 @@ func TestRunCandidates(t *testing.T) {
 			},
 			wantStop: false,
 		},
+		"success: self-consistency collects every sample": {
+			candidateAgent: mustAgent(adkagent.New(adkagent.Config{
+				Name:        "candidates",
+				Description: "candidate agent",
+				Run: func(ctx adkagent.InvocationContext) iter.Seq2[*session.Event, error] {
+					return func(yield func(*session.Event, error) bool) {
+						ev := session.NewEvent(ctx.InvocationID())
+						ev.Author = "A"
+						ev.Content = genai.NewContentFromText("foo", genai.RoleModel)
+						yield(ev, nil)
+					}
+				},
+			})),
+			samplesPerAgent: 3,
+			yield:           func(*session.Event, error) bool { return true },
+			wantAnswers: []candidateAnswer{
+				{Agent: "A", Text: "foo", Sample: 1},
+				{Agent: "A", Text: "foo", Sample: 2},
+				{Agent: "A", Text: "foo", Sample: 3},
+			},
+			wantStop: false,
+		},
 	}
 
 	for name, tt := range tests {
@@ -768,12 +867,13 @@ func TestRunCandidates(t *testing.T) {
 			t.Parallel()
 
 			orchestrator := &tumixOrchestrator{
-				candidateAgent: tt.candidateAgent,
+				candidateAgent:  tt.candidateAgent,
+				samplesPerAgent: tt.samplesPerAgent,
 			}
 			sess := agenttest.NewInMemorySession("s", "app", "u", agenttest.NewInMemoryState(map[string]any{}), &agenttest.InMemoryEvents{}, time.Time{})
 			ctx := agenttest.NewSessionInvocationContext(t.Context(), sess)
 
-			answers, stop := orchestrator.runCandidates(ctx, tt.yield)
+			answers, stop := orchestrator.runCandidates(ctx, 1, tt.yield)
 			if diff := cmp.Diff(tt.wantStop, stop); diff != "" {
 				t.Fatalf("stop mismatch (-want +got):\n%s", diff)
 			}
@@ -989,7 +1089,7 @@ func TestEmitFinalFromState(t *testing.T) {
 				return true
 			}
 
-			orchestrator.emitFinalFromState(ctx, yield)
+			orchestrator.emitFinalFromState(ctx, nil, yield)
 
 			if tt.wantErrMsg != "" {
 				if gotErr == nil {
@@ -31,7 +31,7 @@ import (
 // They emulate the paper's LLM-designed variants by varying tool emphasis.
 //
 // Each agent is given a specific focus: textual reasoning, code execution, or web search.
-func NewAutoAgents(llm model.LLM, genCfg *genai.GenerateContentConfig, n int) ([]agent.Agent, error) {
+func NewAutoAgents(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol, n int) ([]agent.Agent, error) {
 	if n <= 0 {
 		return nil, nil
 	}
@@ -84,10 +84,10 @@ func NewAutoAgents(llm model.LLM, genCfg *genai.GenerateContentConfig, n int) ([
 			GenerateContentConfig: genConfig,
 			Instruction: fmt.Sprintf(`You are an auto-designed agent. Primary focus: %s.
 Use chain-of-thought, then pick a single best action: plain answer, one <language> block, or one `+code(`<search>…</search>`)+` query.
-Do not mix code and search in the same turn. Respond with final answer inside `+code(`<<<`)+` and `+code(`>>>`)+`.`, emphasis),
+Do not mix code and search in the same turn. Respond with final answer `+p.Enclosure()+`.`, emphasis),
 		}
 
-		applySharedContext(&cfg)
+		applySharedContext(&cfg, p)
 
 		a, err := llmagent.New(cfg)
 		if err != nil {
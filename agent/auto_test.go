@@ -21,7 +21,9 @@ import (
 	"iter"
 	"testing"
 
+	"google.golang.org/adk/agent"
 	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
 )
 
@@ -41,7 +43,7 @@ func TestNewAutoAgents(t *testing.T) {
 	llm := &stubLLM{}
 	genCfg := &genai.GenerateContentConfig{}
 
-	agents, err := NewAutoAgents(llm, genCfg, 3)
+	agents, err := NewAutoAgents(llm, genCfg, nil, 3)
 	if err != nil {
 		t.Fatalf("NewAutoAgents error: %v", err)
 	}
@@ -60,7 +62,7 @@ func TestNewAutoAgents(t *testing.T) {
 
 func TestNewAutoAgentsZero(t *testing.T) {
 	llm := &stubLLM{}
-	agents, err := NewAutoAgents(llm, nil, 0)
+	agents, err := NewAutoAgents(llm, nil, nil, 0)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
@@ -70,7 +72,7 @@ func TestNewAutoAgentsZero(t *testing.T) {
 }
 
 func TestNewAutoAgentsRealConfig(t *testing.T) {
-	agents, err := NewAutoAgents(&stubLLM{}, nil, 1)
+	agents, err := NewAutoAgents(&stubLLM{}, nil, nil, 1)
 	if err != nil {
 		t.Fatalf("NewAutoAgents real config: %v", err)
 	}
@@ -78,3 +80,25 @@ func TestNewAutoAgentsRealConfig(t *testing.T) {
 		t.Fatalf("expected 1 agent, got %d", len(agents))
 	}
 }
+
+func TestNewToolsetAgent(t *testing.T) {
+	llm := &stubLLM{}
+
+	if _, err := NewToolsetAgent(llm, nil, nil); err == nil {
+		t.Fatalf("expected error without toolsets")
+	}
+
+	a, err := NewToolsetAgent(llm, nil, nil, stubToolset{})
+	if err != nil {
+		t.Fatalf("NewToolsetAgent error: %v", err)
+	}
+	if a.Name() != "external-tools" {
+		t.Fatalf("agent name = %q, want external-tools", a.Name())
+	}
+}
+
+type stubToolset struct{}
+
+func (stubToolset) Name() string { return "stub" }
+
+func (stubToolset) Tools(agent.ReadonlyContext) ([]tool.Tool, error) { return nil, nil }
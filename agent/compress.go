@@ -0,0 +1,268 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/log"
+)
+
+// DefaultCompressionThresholdTokens is the question length above which [PromptCompression] applies by default.
+const DefaultCompressionThresholdTokens = 4000
+
+// compressionAuthor is the author of the event reporting the usage of the compression call.
+const compressionAuthor = "prompt-compression"
+
+const compressionInstruction = `Summarize the following background material for someone who must answer a question about it.
+Keep every fact, number, name, constraint, code identifier, and formula that could matter; drop repetition,
+pleasantries, and formatting. Do not answer any question and do not add information. Output only the summary.`
+
+// PromptCompression shrinks long questions once before the first round, so every candidate in every round reads a
+// shorter question.
+//
+// The background part of the question is summarized by Model while the final paragraph, or the final sentence of a
+// single-paragraph question, is kept verbatim. The compressed question replaces the text of the user message the
+// agents of the run build their model requests from, as well as {question}; the session keeps the original message.
+// The original and compressed texts are recorded in the "original_question" and "compressed_question" state keys, and
+// the usage of the summary call is reported in an event of its own.
+type PromptCompression struct {
+	// Model summarizes the background of the question.
+	Model model.LLM
+	// GenerateContentConfig is the generation config of the summary request.
+	GenerateContentConfig *genai.GenerateContentConfig
+	// ThresholdTokens is the question length above which it is compressed; zero uses
+	// [DefaultCompressionThresholdTokens].
+	ThresholdTokens int
+	// CountTokens counts the tokens of text. Nil estimates four characters per token.
+	CountTokens func(ctx context.Context, text string) (int, error)
+}
+
+// compress returns the compressed question, or question unchanged when it is short enough, has no separable
+// background, or the summary would not be shorter, with the usage of the summary call, if one was made.
+func (c *PromptCompression) compress(ctx context.Context, question string) (string, *genai.GenerateContentResponseUsageMetadata, error) {
+	if c.Model == nil {
+		return "", nil, errors.New("prompt compression: model is required")
+	}
+
+	n, err := c.countTokens(ctx, question)
+	if err != nil {
+		return "", nil, fmt.Errorf("prompt compression: count tokens: %w", err)
+	}
+	if n <= c.threshold() {
+		return question, nil, nil
+	}
+
+	background, ask := splitQuestion(question)
+	if background == "" {
+		return question, nil, nil
+	}
+
+	summary, usage, err := c.summarize(ctx, background)
+	if err != nil {
+		return "", usage, err
+	}
+	compressed := strings.TrimSpace(summary) + "\n\n" + ask
+	if summary == "" || len(compressed) >= len(question) {
+		return question, usage, nil
+	}
+	return compressed, usage, nil
+}
+
+func (c *PromptCompression) threshold() int {
+	if c.ThresholdTokens > 0 {
+		return c.ThresholdTokens
+	}
+	return DefaultCompressionThresholdTokens
+}
+
+func (c *PromptCompression) countTokens(ctx context.Context, text string) (int, error) {
+	if c.CountTokens != nil {
+		return c.CountTokens(ctx, text)
+	}
+	return (len(text) + 3) / 4, nil
+}
+
+func (c *PromptCompression) summarize(ctx context.Context, background string) (string, *genai.GenerateContentResponseUsageMetadata, error) {
+	cfg := cloneGenConfig(c.GenerateContentConfig)
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	cfg.SystemInstruction = genai.NewContentFromText(compressionInstruction, genai.RoleUser)
+
+	req := &model.LLMRequest{
+		Model:    c.Model.Name(),
+		Contents: []*genai.Content{genai.NewContentFromText(background, genai.RoleUser)},
+		Config:   cfg,
+	}
+	var (
+		sb    strings.Builder
+		usage *genai.GenerateContentResponseUsageMetadata
+	)
+	for resp, err := range c.Model.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", usage, fmt.Errorf("prompt compression: summarize: %w", err)
+		}
+		if resp == nil {
+			continue
+		}
+		if resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
+		}
+		if resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if part != nil && !part.Thought {
+				sb.WriteString(part.Text)
+			}
+		}
+	}
+	return sb.String(), usage, nil
+}
+
+// prepareQuestion applies prompt compression to question. When the question was compressed, both versions are
+// recorded in the session state and the returned context carries the compressed question in place of the user message
+// (see [withQuestion]). The usage of the summary call is reported in an event; a failed compression is logged and
+// falls back to the original question. It reports whether the run must stop.
+func (t *tumixOrchestrator) prepareQuestion(ctx agent.InvocationContext, question string, yield func(*session.Event, error) bool) (agent.InvocationContext, string, bool) {
+	if t.compression == nil {
+		return ctx, question, false
+	}
+	compressed, usage, err := t.compression.compress(ctx, question)
+	if usage != nil {
+		ev := session.NewEvent(ctx.InvocationID())
+		ev.Author = compressionAuthor
+		ev.UsageMetadata = usage
+		if !yield(ev, nil) {
+			return ctx, "", true
+		}
+	}
+	if err != nil {
+		log.Warn(ctx, "prompt compression failed; keeping the original question", "error", err)
+		compressed = question
+	}
+	if compressed == question {
+		return ctx, question, false
+	}
+	if err := setState(ctx, stateKeyOriginalQuestion, question); err != nil {
+		yield(nil, err)
+		return ctx, "", true
+	}
+	if err := setState(ctx, stateKeyCompressedQuestion, compressed); err != nil {
+		yield(nil, err)
+		return ctx, "", true
+	}
+	return withQuestion(ctx, compressed), compressed, false
+}
+
+// withQuestion returns ctx in which the first text of the user message is question, both in
+// [agent.InvocationContext.UserContent] and in the session history the agents build their model requests from.
+func withQuestion(ctx agent.InvocationContext, question string) agent.InvocationContext {
+	user := ctx.UserContent()
+	content := &genai.Content{Role: genai.RoleUser}
+	replaced := false
+	if user != nil {
+		content.Role = user.Role
+		for _, part := range user.Parts {
+			if !replaced && part != nil && part.Text != "" {
+				part = genai.NewPartFromText(question)
+				replaced = true
+			}
+			content.Parts = append(content.Parts, part)
+		}
+	}
+	if !replaced {
+		content.Parts = append([]*genai.Part{genai.NewPartFromText(question)}, content.Parts...)
+	}
+	return &questionContext{
+		InvocationContext: ctx,
+		session:           &questionSession{Session: ctx.Session(), invocationID: ctx.InvocationID(), content: content},
+		content:           content,
+	}
+}
+
+// questionContext is an invocation context whose user message carries the compressed question.
+type questionContext struct {
+	agent.InvocationContext
+	session *questionSession
+	content *genai.Content
+}
+
+func (c *questionContext) Session() session.Session    { return c.session }
+func (c *questionContext) UserContent() *genai.Content { return c.content }
+
+// questionSession is a session whose events show content in place of the user message of the invocation.
+type questionSession struct {
+	session.Session
+	invocationID string
+	content      *genai.Content
+}
+
+func (s *questionSession) Events() session.Events {
+	return &questionEvents{Events: s.Session.Events(), session: s}
+}
+
+type questionEvents struct {
+	session.Events
+	session *questionSession
+}
+
+func (e *questionEvents) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for event := range e.Events.All() {
+			if !yield(e.rewrite(event)) {
+				return
+			}
+		}
+	}
+}
+
+func (e *questionEvents) At(i int) *session.Event { return e.rewrite(e.Events.At(i)) }
+
+// rewrite returns a copy of event with the compressed question when it is the user message of the invocation.
+func (e *questionEvents) rewrite(event *session.Event) *session.Event {
+	if event == nil || event.Author != "user" || event.InvocationID != e.session.invocationID {
+		return event
+	}
+	rewritten := *event
+	rewritten.Content = e.session.content
+	return &rewritten
+}
+
+// splitQuestion separates the background of text from the question to keep verbatim: the last paragraph, or the
+// last sentence when text is a single paragraph.
+func splitQuestion(text string) (background, question string) {
+	text = strings.TrimSpace(text)
+	if i := strings.LastIndex(text, "\n\n"); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:])
+	}
+	// Skip the terminal punctuation of the question itself.
+	if i := strings.LastIndexAny(strings.TrimRight(text, ".!?"), ".!?\n"); i >= 0 {
+		return strings.TrimSpace(text[:i+1]), strings.TrimSpace(text[i+1:])
+	}
+	return "", text
+}
@@ -0,0 +1,289 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// summaryLLM answers every request with a fixed summary.
+type summaryLLM struct {
+	summary string
+	err     error
+}
+
+// summaryUsage is the usage every summaryLLM response reports.
+var summaryUsage = &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 200, CandidatesTokenCount: 5, TotalTokenCount: 205}
+
+var _ model.LLM = (*summaryLLM)(nil)
+
+// Name implements [model.LLM].
+func (s *summaryLLM) Name() string { return "summary" }
+
+// GenerateContent implements [model.LLM].
+func (s *summaryLLM) GenerateContent(_ context.Context, _ *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if s.err != nil {
+			yield(nil, s.err)
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(s.summary, genai.RoleModel), UsageMetadata: summaryUsage}, nil)
+	}
+}
+
+// userTextLLM answers every request with "<<<ok>>>" and records the first user text of each request.
+type userTextLLM struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+var _ model.LLM = (*userTextLLM)(nil)
+
+// Name implements [model.LLM].
+func (u *userTextLLM) Name() string { return "user-text" }
+
+// GenerateContent implements [model.LLM].
+func (u *userTextLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var text string
+		for _, c := range req.Contents {
+			if c.Role == genai.RoleUser {
+				text = firstTextFromContent(c)
+				break
+			}
+		}
+		u.mu.Lock()
+		u.texts = append(u.texts, text)
+		u.mu.Unlock()
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("<<<ok>>>", genai.RoleModel)}, nil)
+	}
+}
+
+func TestSplitQuestion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text           string
+		wantBackground string
+		wantQuestion   string
+	}{
+		"paragraphs": {
+			text:           "Some context.\n\nMore context.\n\nWhat is x?",
+			wantBackground: "Some context.\n\nMore context.",
+			wantQuestion:   "What is x?",
+		},
+		"single paragraph": {
+			text:           "A train leaves at noon. It travels 60 km/h. How far does it go by 3pm?",
+			wantBackground: "A train leaves at noon. It travels 60 km/h.",
+			wantQuestion:   "How far does it go by 3pm?",
+		},
+		"single sentence": {
+			text:         "What is 2+2?",
+			wantQuestion: "What is 2+2?",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			background, question := splitQuestion(tt.text)
+			if diff := cmp.Diff(tt.wantBackground, background); diff != "" {
+				t.Fatalf("background mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantQuestion, question); diff != "" {
+				t.Fatalf("question mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPromptCompressionCompress(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("The ledger lists many transactions. ", 20) + "\n\nWhat is the final balance?"
+	tests := map[string]struct {
+		llm       *summaryLLM
+		threshold int
+		question  string
+		want      string
+		wantUsage *genai.GenerateContentResponseUsageMetadata
+		wantErr   bool
+	}{
+		"compressed": {
+			llm:       &summaryLLM{summary: "Ledger of transactions."},
+			threshold: 10,
+			question:  long,
+			want:      "Ledger of transactions.\n\nWhat is the final balance?",
+			wantUsage: summaryUsage,
+		},
+		"under threshold": {
+			llm:       &summaryLLM{summary: "unused"},
+			threshold: 10000,
+			question:  long,
+			want:      long,
+		},
+		"no background": {
+			llm:       &summaryLLM{summary: "unused"},
+			threshold: 1,
+			question:  "What is the final balance?",
+			want:      "What is the final balance?",
+		},
+		"summary not shorter": {
+			llm:       &summaryLLM{summary: strings.Repeat("x", len(long))},
+			threshold: 10,
+			question:  long,
+			want:      long,
+			wantUsage: summaryUsage,
+		},
+		"model error": {
+			llm:       &summaryLLM{err: errors.New("boom")},
+			threshold: 10,
+			question:  long,
+			wantErr:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &PromptCompression{Model: tt.llm, ThresholdTokens: tt.threshold}
+			got, usage, err := c.compress(t.Context(), tt.question)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compress() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("compress() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantUsage, usage); diff != "" {
+				t.Fatalf("compress() usage mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTumixCompressesQuestion(t *testing.T) {
+	t.Parallel()
+	if err := initLLMFlow(); err != nil {
+		t.Fatalf("init llm flow: %v", err)
+	}
+
+	question := strings.Repeat("Background fact. ", 50) + "\n\nWhat follows?"
+	tests := map[string]struct {
+		summary   *summaryLLM
+		wantText  string
+		wantState map[string]any
+		wantUsage bool
+	}{
+		"compressed": {
+			summary:  &summaryLLM{summary: "Facts."},
+			wantText: "Facts.\n\nWhat follows?",
+			wantState: map[string]any{
+				stateKeyOriginalQuestion:   question,
+				stateKeyCompressedQuestion: "Facts.\n\nWhat follows?",
+			},
+			wantUsage: true,
+		},
+		"failed": {
+			summary:   &summaryLLM{err: errors.New("boom")},
+			wantText:  question,
+			wantState: map[string]any{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// The candidates run on branches of their own, which the user message is not on; the Judge reads it.
+			backend := &userTextLLM{}
+			judge := mustAgent(llmagent.New(llmagent.Config{
+				Name:        "judge",
+				Description: "recording judge",
+				Model:       backend,
+				Instruction: "Judge the answers.",
+			}))
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{staticCandidate("A", "<<<1>>>")},
+				Judge:      judge,
+				MaxRounds:  1,
+				PromptCompression: &PromptCompression{
+					Model:           tt.summary,
+					ThresholdTokens: 10,
+				},
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+			var usage *genai.GenerateContentResponseUsageMetadata
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText(question, genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == compressionAuthor {
+					usage = event.UsageMetadata
+				}
+			}
+
+			// The Judge reads the compressed question in place of the user message.
+			if diff := cmp.Diff([]string{tt.wantText}, backend.texts); diff != "" {
+				t.Fatalf("judge user texts mismatch (-want +got):\n%s", diff)
+			}
+			if got := usage != nil; got != tt.wantUsage {
+				t.Fatalf("compression usage reported = %t, want %t", got, tt.wantUsage)
+			}
+
+			res, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+			if err != nil {
+				t.Fatalf("get session: %v", err)
+			}
+			got := make(map[string]any)
+			for _, key := range []string{stateKeyOriginalQuestion, stateKeyCompressedQuestion} {
+				if v, err := res.Session.State().Get(key); err == nil {
+					got[key] = v
+				}
+			}
+			if diff := cmp.Diff(tt.wantState, got); diff != "" {
+				t.Fatalf("state mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+
+	"github.com/zchee/tumix/internal/mathcheck"
+)
+
+// stateKeyRefineInstruction is the state key of the instruction ending the shared context of the next round: the
+// generic one, or a targeted one naming the disagreements detected between the previous answers.
+const stateKeyRefineInstruction = "refine_instruction"
+
+// genericRefineInstruction is the refine instruction of a round whose previous answers show no specific disagreement.
+const genericRefineInstruction = "Use the shared context to refine your reasoning."
+
+// ConflictKind classifies a disagreement between the candidate answers of a round.
+type ConflictKind string
+
+const (
+	// ConflictNumericSpread is numeric answers with different values.
+	ConflictNumericSpread ConflictKind = "numeric_spread"
+	// ConflictYesNo is answers concluding both yes and no.
+	ConflictYesNo ConflictKind = "yes_no"
+	// ConflictUnitMismatch is numeric answers given in different units.
+	ConflictUnitMismatch ConflictKind = "unit_mismatch"
+)
+
+// Conflict is a specific disagreement detected between the candidate answers of a round, beyond a split vote.
+type Conflict struct {
+	Kind ConflictKind `json:"kind"`
+	// Detail names the disagreeing answers and the candidates giving them, e.g. "12 (base) vs 15 (cot, sc)".
+	Detail string `json:"detail"`
+}
+
+// quantityRe splits a numeric answer with a trailing unit, e.g. "12.5 km/h", into its number and unit.
+var quantityRe = regexp.MustCompile(`^(.*[0-9)}])\s*([\p{L}°µ][\p{L}°µ/·²³]*)$`)
+
+// detectConflicts returns the specific disagreements between the final answers of ans: numeric answers with
+// different values or units, and contradictory yes/no conclusions.
+//
+// Numeric values given in different units are not compared, so a unit mismatch is not also reported as a spread. The
+// answers are considered in agent and sample order, not in the order the parallel candidates answered in, so the agents
+// in the details are sorted.
+func detectConflicts(p *AnswerProtocol, ans []candidateAnswer) []Conflict {
+	return finalAnswerConflicts(ans, finalAnswers(p, ans))
+}
+
+// finalAnswerConflicts is [detectConflicts] given the final answers of the answers (see [finalAnswers]).
+func finalAnswerConflicts(ans []candidateAnswer, finals []string) []Conflict {
+	type quantity struct {
+		num   mathcheck.Number
+		text  string
+		agent string
+	}
+	type parsed struct {
+		num  mathcheck.Number
+		unit string
+		ok   bool
+	}
+
+	var (
+		quantities []quantity
+		units      = make(map[string][]string)
+		unitOrder  []string
+		yes, no    []string
+		// Candidates mostly agree, so each distinct final answer is parsed once.
+		parsedFinals = make(map[string]parsed, len(ans))
+	)
+	order := make([]int, len(ans))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		return cmp.Or(strings.Compare(ans[i].Agent, ans[j].Agent), cmp.Compare(ans[i].Sample, ans[j].Sample), cmp.Compare(i, j))
+	})
+	for _, i := range order {
+		a, final := ans[i], finals[i]
+		switch yesNo(final) {
+		case "yes":
+			yes = appendAgent(yes, a.Agent)
+			continue
+		case "no":
+			no = appendAgent(no, a.Agent)
+			continue
+		}
+
+		p, seen := parsedFinals[final]
+		if !seen {
+			p.num, p.unit, p.ok = parseQuantity(final)
+			parsedFinals[final] = p
+		}
+		if !p.ok {
+			continue
+		}
+		quantities = append(quantities, quantity{num: p.num, text: final, agent: a.Agent})
+		if p.unit == "" {
+			continue
+		}
+		if _, seen := units[p.unit]; !seen {
+			unitOrder = append(unitOrder, p.unit)
+		}
+		units[p.unit] = appendAgent(units[p.unit], a.Agent)
+	}
+
+	var conflicts []Conflict
+	if len(yes) > 0 && len(no) > 0 {
+		conflicts = append(conflicts, Conflict{
+			Kind:   ConflictYesNo,
+			Detail: fmt.Sprintf("yes (%s) vs no (%s)", strings.Join(yes, ", "), strings.Join(no, ", ")),
+		})
+	}
+
+	if len(unitOrder) > 1 {
+		parts := make([]string, len(unitOrder))
+		for i, unit := range unitOrder {
+			parts[i] = fmt.Sprintf("%s (%s)", unit, strings.Join(units[unit], ", "))
+		}
+		return append(conflicts, Conflict{Kind: ConflictUnitMismatch, Detail: strings.Join(parts, " vs ")})
+	}
+
+	// Group equivalent values, then report the smallest and the largest when they differ.
+	type value struct {
+		quantity
+		agents []string
+	}
+	nums := make([]mathcheck.Number, len(quantities))
+	for i, q := range quantities {
+		nums[i] = q.num
+	}
+	var values []*value
+	for i, class := range mathcheck.Group(nums) {
+		if class == len(values) {
+			values = append(values, &value{quantity: quantities[i]})
+		}
+		values[class].agents = appendAgent(values[class].agents, quantities[i].agent)
+	}
+	if len(values) > 1 {
+		lo := slices.MinFunc(values, func(a, b *value) int { return a.num.Rat.Cmp(b.num.Rat) })
+		hi := slices.MaxFunc(values, func(a, b *value) int { return a.num.Rat.Cmp(b.num.Rat) })
+		detail := fmt.Sprintf("%s (%s) vs %s (%s)", lo.text, strings.Join(lo.agents, ", "), hi.text, strings.Join(hi.agents, ", "))
+		if n := len(values) - 2; n > 0 {
+			detail += fmt.Sprintf(", and %d value(s) between", n)
+		}
+		conflicts = append(conflicts, Conflict{Kind: ConflictNumericSpread, Detail: detail})
+	}
+	return conflicts
+}
+
+// refineInstruction returns the instruction ending the shared context of a round following answers with conflicts:
+// a targeted one asking the candidates to resolve them, or the generic one when there are none.
+func refineInstruction(conflicts []Conflict) string {
+	if len(conflicts) == 0 {
+		return genericRefineInstruction
+	}
+	var sb strings.Builder
+	sb.WriteString("The previous answers disagree. Before anything else, resolve this specific disagreement:\n")
+	for _, c := range conflicts {
+		switch c.Kind {
+		case ConflictYesNo:
+			fmt.Fprintf(&sb, "- Contradictory conclusions: %s.\n", c.Detail)
+		case ConflictUnitMismatch:
+			fmt.Fprintf(&sb, "- Answers in different units: %s. State the answer in the unit the question asks for.\n", c.Detail)
+		case ConflictNumericSpread:
+			fmt.Fprintf(&sb, "- Different numeric values: %s.\n", c.Detail)
+		}
+	}
+	sb.WriteString("Find the step where the reasoning behind these answers diverges, check it, and commit to the conclusion it supports.")
+	return sb.String()
+}
+
+// setRefineInstruction sets the instruction ending the shared context of the next round from the conflicts between
+// the previous answers.
+func setRefineInstruction(ctx agent.InvocationContext, p *AnswerProtocol, prev []candidateAnswer) error {
+	return setState(ctx, stateKeyRefineInstruction, refineInstruction(detectConflicts(p, prev)))
+}
+
+// yesNo returns "yes" or "no" when the final answer concludes so, e.g. "Yes, because…" or "false", and "" otherwise.
+func yesNo(final string) string {
+	word, _, _ := strings.Cut(strings.ToLower(final), " ")
+	switch strings.TrimRight(word, ".,;:!") {
+	case "yes", "true":
+		return "yes"
+	case "no", "false":
+		return "no"
+	}
+	return ""
+}
+
+// parseQuantity evaluates a numeric final answer with an optional trailing unit, which is returned in lower case.
+func parseQuantity(final string) (num mathcheck.Number, unit string, ok bool) {
+	if num, ok := mathcheck.Parse(final); ok {
+		return num, "", true
+	}
+	m := quantityRe.FindStringSubmatch(final)
+	if m == nil {
+		return mathcheck.Number{}, "", false
+	}
+	num, ok = mathcheck.Parse(m[1])
+	if !ok {
+		return mathcheck.Number{}, "", false
+	}
+	return num, strings.ToLower(m[2]), true
+}
+
+func appendAgent(agents []string, name string) []string {
+	if slices.Contains(agents, name) {
+		return agents
+	}
+	return append(agents, name)
+}
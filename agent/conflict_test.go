@@ -0,0 +1,208 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"iter"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestDetectConflicts(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		ans  []candidateAnswer
+		want []Conflict
+	}{
+		"consensus": {
+			ans: []candidateAnswer{{Agent: "a", Text: "<<<1/3>>>"}, {Agent: "b", Text: "so <<<0.33>>>"}},
+		},
+		"non-numeric disagreement": {
+			ans: []candidateAnswer{{Agent: "a", Text: "<<<Paris>>>"}, {Agent: "b", Text: "<<<Lyon>>>"}},
+		},
+		"numeric spread": {
+			ans: []candidateAnswer{
+				{Agent: "a", Text: "<<<15>>>"},
+				{Agent: "b", Text: "<<<12>>>"},
+				{Agent: "c", Text: "<<<13.5>>>"},
+				{Agent: "d", Text: "<<<12>>>"},
+			},
+			want: []Conflict{{Kind: ConflictNumericSpread, Detail: "12 (b, d) vs 15 (a), and 1 value(s) between"}},
+		},
+		"yes no": {
+			ans: []candidateAnswer{
+				{Agent: "a", Text: "<<<Yes, it halts.>>>"},
+				{Agent: "b", Text: "<<<no>>>"},
+				{Agent: "c", Text: "<<<true>>>"},
+			},
+			want: []Conflict{{Kind: ConflictYesNo, Detail: "yes (a, c) vs no (b)"}},
+		},
+		"unit mismatch": {
+			ans: []candidateAnswer{
+				{Agent: "a", Text: "<<<5 km>>>"},
+				{Agent: "b", Text: "<<<5000 m>>>"},
+				{Agent: "c", Text: "<<<5 KM>>>"},
+			},
+			want: []Conflict{{Kind: ConflictUnitMismatch, Detail: "km (a, c) vs m (b)"}},
+		},
+		"same unit spread": {
+			ans:  []candidateAnswer{{Agent: "a", Text: "<<<60 km/h>>>"}, {Agent: "b", Text: "<<<50 km/h>>>"}},
+			want: []Conflict{{Kind: ConflictNumericSpread, Detail: "50 km/h (b) vs 60 km/h (a)"}},
+		},
+		"arrival order": {
+			ans: []candidateAnswer{
+				{Agent: "d", Text: "<<<5000 m>>>"},
+				{Agent: "c", Text: "<<<5 km>>>"},
+				{Agent: "b", Text: "<<<no>>>"},
+				{Agent: "a", Text: "<<<5 km>>>"},
+				{Agent: "e", Text: "<<<yes>>>"},
+			},
+			want: []Conflict{
+				{Kind: ConflictYesNo, Detail: "yes (e) vs no (b)"},
+				{Kind: ConflictUnitMismatch, Detail: "km (a, c) vs m (d)"},
+			},
+		},
+		"numeric spread in arrival order": {
+			ans: []candidateAnswer{
+				{Agent: "C", Text: "<<<41>>>"},
+				{Agent: "B", Text: "<<<42.0>>>"},
+				{Agent: "A", Text: "<<<42>>>"},
+			},
+			want: []Conflict{{Kind: ConflictNumericSpread, Detail: "41 (C) vs 42 (A, B)"}},
+		},
+		"samples of one agent": {
+			ans:  []candidateAnswer{{Agent: "a", Sample: 1, Text: "<<<1>>>"}, {Agent: "a", Sample: 2, Text: "<<<2>>>"}},
+			want: []Conflict{{Kind: ConflictNumericSpread, Detail: "1 (a) vs 2 (a)"}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, detectConflicts(DelimitedAnswers, tt.ans)); diff != "" {
+				t.Fatalf("detectConflicts() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRefineInstruction(t *testing.T) {
+	t.Parallel()
+
+	if got := refineInstruction(nil); got != genericRefineInstruction {
+		t.Fatalf("refineInstruction(nil) = %q, want %q", got, genericRefineInstruction)
+	}
+
+	got := refineInstruction([]Conflict{
+		{Kind: ConflictYesNo, Detail: "yes (a) vs no (b)"},
+		{Kind: ConflictUnitMismatch, Detail: "km (a) vs m (b)"},
+	})
+	for _, want := range []string{
+		"resolve this specific disagreement",
+		"- Contradictory conclusions: yes (a) vs no (b).",
+		"- Answers in different units: km (a) vs m (b).",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("refineInstruction() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, genericRefineInstruction) {
+		t.Errorf("refineInstruction() = %q, want no generic instruction", got)
+	}
+}
+
+func TestTumixTargetsConflicts(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu           sync.Mutex
+		instructions []string
+	)
+	recorder := mustAgent(agent.New(agent.Config{
+		Name:        "Y",
+		Description: "recording candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				val, _ := ctx.Session().State().Get(stateKeyRefineInstruction)
+				text, _ := val.(string)
+				mu.Lock()
+				instructions = append(instructions, text)
+				mu.Unlock()
+
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("<<<15>>>", genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: []agent.Agent{staticCandidate("X", "<<<12>>>"), recorder},
+		Judge:      noOpJudge(),
+		MaxRounds:  2,
+		MinRounds:  1,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	var final *session.Event
+	for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+		if event.Author == "tumix" {
+			final = event
+		}
+	}
+
+	conflicts := []Conflict{{Kind: ConflictNumericSpread, Detail: "12 (X) vs 15 (Y)"}}
+	want := []string{genericRefineInstruction, refineInstruction(conflicts)}
+	if diff := cmp.Diff(want, instructions); diff != "" {
+		t.Fatalf("refine instructions mismatch (-want +got):\n%s", diff)
+	}
+
+	stats := RoundStatsFromEvent(final)
+	if len(stats) != 2 {
+		t.Fatalf("RoundStatsFromEvent() = %+v, want 2 rounds", stats)
+	}
+	for _, s := range stats {
+		if diff := cmp.Diff(conflicts, s.Conflicts); diff != "" {
+			t.Errorf("round %d conflicts mismatch (-want +got):\n%s", s.Round, diff)
+		}
+	}
+}
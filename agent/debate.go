@@ -0,0 +1,233 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// Mode selects how the TUMIX Agent orchestrates its candidates across rounds.
+type Mode string
+
+const (
+	// ModeTumix shows every candidate the joined answers of the previous round and consults the Judge after every
+	// round from MinRounds on, stopping early once it is confident.
+	ModeTumix Mode = "tumix"
+
+	// ModeDebate has the candidates answer independently in the first round, then shows each candidate the answers of
+	// the others and asks it to critique them and revise its own. The Judge aggregates once, after MaxRounds rounds or
+	// from MinRounds on as soon as every candidate agrees.
+	ModeDebate Mode = "debate"
+)
+
+const stateKeyDebatePrefix = "debate_"
+
+// debateStateKey returns the state key of the debate prompt of the candidate agent name, with every rune that is not
+// valid in a state placeholder replaced by an underscore.
+func debateStateKey(name string) string {
+	return stateKeyDebatePrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r == '_', r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// debatePlaceholder returns the optional state placeholder of the debate prompt of the candidate agent name.
+func debatePlaceholder(name string) string {
+	return "{" + debateStateKey(name) + "?}"
+}
+
+// debatePrompt returns the prompt asking the candidate self to critique the answers of the others and revise its own
+// in protocol p, or "" before the first round.
+func debatePrompt(p *AnswerProtocol, self string, answers []candidateAnswer) string {
+	var own, others []candidateAnswer
+	for _, a := range answers {
+		if a.Agent == self {
+			own = append(own, a)
+		} else {
+			others = append(others, a)
+		}
+	}
+	if len(others) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("**Debate**\n- Answers of the other candidates:\n")
+	sb.WriteString(joinAnswers(others))
+	if len(own) > 0 {
+		sb.WriteString("\n- Your previous answer:\n")
+		sb.WriteString(joinAnswers(own))
+	}
+	sb.WriteString(`
+
+Critique each answer of the other candidates: point out errors, gaps, and unsupported steps, and concede where they
+are right. Then revise your own answer and end with it ` + p.Enclosure() + `.`)
+	return sb.String()
+}
+
+// debate runs the rounds of [ModeDebate] and yields the final answer.
+func (t *tumixOrchestrator) debate(ctx agent.InvocationContext, yield func(*session.Event, error) bool) {
+	candidates := t.candidates(ctx).SubAgents()
+	candidateCount := len(candidates) * int(t.samples()) //nolint:gosec // samples is small
+
+	var (
+		lastAnswers []candidateAnswer
+		citations   []Citation
+		timeouts    []string
+		runOver     bool
+		lastRound   uint
+	)
+	for round := uint(1); round <= t.maxRounds; round++ {
+		lastRound = round
+		if round > 1 {
+			roundEnded(ctx, round-1, t.maxRounds)
+		}
+		if err := setState(ctx, stateKeyRound, round); err != nil {
+			yield(nil, err)
+			return
+		}
+		// Candidates see the others' answers through their debate prompts rather than the joined answers.
+		if err := setState(ctx, stateKeyJoined, ""); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := setDebatePrompts(ctx, t.protocol, candidates, lastAnswers); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := setRefineInstruction(ctx, t.protocol, lastAnswers); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		rctx, ryield, cancel := t.roundContext(ctx, yield)
+		answers, stop := t.runCandidates(rctx, round, ryield)
+		cancel()
+		if stop {
+			return
+		}
+		timeout, stop := t.recordTimeout(rctx, round, &timeouts, yield)
+		if stop {
+			return
+		}
+		runOver = endsRun(timeout)
+		if len(answers) == 0 {
+			if runOver {
+				break
+			}
+			continue
+		}
+		prevAnswers := lastAnswers
+		lastAnswers = answers
+		citations = mergeCitations(citations, answers)
+		if err := setState(ctx, stateKeyCitations, joinCitations(citations)); err != nil {
+			yield(nil, err)
+			return
+		}
+		stats := computeStats(t.protocol, lastAnswers, candidateCount, tieBreaker(t.seed))
+		if round > 1 {
+			stats.changes = answerChanges(t.protocol, prevAnswers, lastAnswers)
+		}
+		if err := setRoundStats(ctx, stats); err != nil {
+			yield(nil, err)
+			return
+		}
+		if runOver || round >= t.minRounds && stats.unique == 1 {
+			break
+		}
+	}
+
+	if err := setDebatePrompts(ctx, t.protocol, candidates, nil); err != nil {
+		yield(nil, err)
+		return
+	}
+	if err := setState(ctx, stateKeyJoined, joinAnswers(lastAnswers)); err != nil {
+		yield(nil, err)
+		return
+	}
+	if err := setState(ctx, stateKeyAnswer, nil); err != nil {
+		yield(nil, err)
+		return
+	}
+	// Past the run deadline, or once interrupted, there is no time left for the Judge.
+	if len(lastAnswers) > 0 && !runOver {
+		// The Judge aggregates once, storing the answer with finalize; whether it asks to stop does not matter as
+		// no round follows.
+		jyield := dropTimeoutErrors(ctx, yield)
+		var transcript judgeTranscript
+		for event, err := range t.judge.Run(ctx) {
+			if !jyield(event, err) {
+				return
+			}
+			if err == nil {
+				transcript.add(event)
+			}
+		}
+		if err := transcript.save(ctx); err != nil {
+			yield(nil, err)
+			return
+		}
+		if _, stop := t.recordTimeout(ctx, lastRound, &timeouts, yield); stop {
+			return
+		}
+		if err := recordWeightedMargin(ctx, t.protocol, lastAnswers); err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+
+	answer, err := getState(ctx, stateKeyAnswer)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	if answer == nil && len(lastAnswers) > 0 {
+		scores, err := stateCandidateScores(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		voted, conf := weightedVote(t.protocol, lastAnswers, scores, tieBreaker(t.seed))
+		if err := setState(ctx, stateKeyAnswer, voted); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := setState(ctx, stateKeyConfidence, conf); err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+	t.emitFinalFromState(ctx, citations, yield)
+}
+
+// setDebatePrompts stores the debate prompt of every candidate built from answers, asking for the answer in protocol p.
+func setDebatePrompts(ctx agent.InvocationContext, p *AnswerProtocol, candidates []agent.Agent, answers []candidateAnswer) error {
+	for _, c := range candidates {
+		if err := setState(ctx, debateStateKey(c.Name()), debatePrompt(p, c.Name(), answers)); err != nil {
+			return fmt.Errorf("debate prompt of %s: %w", c.Name(), err)
+		}
+	}
+	return nil
+}
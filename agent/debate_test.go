@@ -0,0 +1,248 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestDebateStateKey(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"cot":          "debate_cot",
+		"cot-code":     "debate_cot_code",
+		"LLM-as-Judge": "debate_LLM_as_Judge",
+		"a.b c":        "debate_a_b_c",
+	}
+	for name, want := range tests {
+		if got := debateStateKey(name); got != want {
+			t.Fatalf("debateStateKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDebatePrompt(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		answers  []candidateAnswer
+		want     []string
+		wantNone bool
+	}{
+		"first round": {
+			wantNone: true,
+		},
+		"no other candidate": {
+			answers:  []candidateAnswer{{Agent: "A", Text: "1"}},
+			wantNone: true,
+		},
+		"others and own samples": {
+			answers: []candidateAnswer{
+				{Agent: "A", Text: "1", Sample: 1},
+				{Agent: "B", Text: "2"},
+				{Agent: "A", Text: "3", Sample: 2},
+			},
+			want: []string{
+				"Answers of the other candidates:\n- B: 2\n",
+				"Your previous answer:\n- A#1: 1\n- A#2: 3",
+				"Critique each answer of the other candidates",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := debatePrompt(DelimitedAnswers, "A", tt.answers)
+			if tt.wantNone {
+				if got != "" {
+					t.Fatalf("debatePrompt() = %q, want empty", got)
+				}
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("debatePrompt() = %q, want containing %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+// debater answers with its name and round, and records the debate prompt it was shown in each round.
+type debater struct {
+	name string
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (d *debater) agent(answer func(round any) string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        d.name,
+		Description: "debating candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				round, err := ctx.Session().State().Get(stateKeyRound)
+				if err != nil {
+					yield(nil, fmt.Errorf("state round: %w", err))
+					return
+				}
+				prompt, err := ctx.Session().State().Get(debateStateKey(d.name))
+				if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+					yield(nil, fmt.Errorf("state debate: %w", err))
+					return
+				}
+				d.mu.Lock()
+				d.prompts = append(d.prompts, fmt.Sprint(prompt))
+				d.mu.Unlock()
+
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(answer(round), genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+func countingJudge(calls *atomic.Int64, judge agent.Agent) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        "judge",
+		Description: "counting judge",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			calls.Add(1)
+			return judge.Run(ctx)
+		},
+	}))
+}
+
+func TestTumixDebateMode(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		answers    map[string]func(round any) string
+		judge      agent.Agent
+		minRounds  uint
+		wantRounds int
+		wantAnswer string
+	}{
+		"judge aggregates after the last round": {
+			answers: map[string]func(any) string{
+				"A": func(round any) string { return fmt.Sprintf("A%v", round) },
+				"B": func(round any) string { return fmt.Sprintf("B%v", round) },
+			},
+			judge:      stubJudge("A3"),
+			minRounds:  2,
+			wantRounds: 3,
+			wantAnswer: "A3",
+		},
+		"consensus ends the debate early": {
+			answers: map[string]func(any) string{
+				"A": func(any) string { return "42" },
+				"B": func(any) string { return "42" },
+			},
+			judge:      noOpJudge(),
+			minRounds:  2,
+			wantRounds: 2,
+			wantAnswer: "42",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			a, b := &debater{name: "A"}, &debater{name: "B"}
+			var judgeCalls atomic.Int64
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{a.agent(tt.answers["A"]), b.agent(tt.answers["B"])},
+				Judge:      countingJudge(&judgeCalls, tt.judge),
+				MaxRounds:  3,
+				MinRounds:  tt.minRounds,
+				Mode:       ModeDebate,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+			var final string
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == "tumix" {
+					final = firstTextFromContent(event.Content)
+				}
+			}
+
+			if got := judgeCalls.Load(); got != 1 {
+				t.Fatalf("judge calls = %d, want 1", got)
+			}
+			if len(a.prompts) != tt.wantRounds || len(b.prompts) != tt.wantRounds {
+				t.Fatalf("candidate rounds = %d/%d, want %d", len(a.prompts), len(b.prompts), tt.wantRounds)
+			}
+			if a.prompts[0] != "" {
+				t.Fatalf("first round debate prompt = %q, want empty", a.prompts[0])
+			}
+			// From the second round on, each candidate sees the other's previous answer and its own.
+			wantOther, wantOwn := "- B: "+tt.answers["B"](uint(1)), "- A: "+tt.answers["A"](uint(1))
+			if got := a.prompts[1]; !strings.Contains(got, "other candidates:\n"+wantOther) || !strings.Contains(got, "previous answer:\n"+wantOwn) {
+				t.Fatalf("second round debate prompt of A = %q, want the answers of B and A", got)
+			}
+			if !strings.Contains(final, tt.wantAnswer) {
+				t.Fatalf("final answer = %q, want containing %q", final, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestNewTumixAgentUnknownMode(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: []agent.Agent{stubCandidate("A")},
+		Judge:      noOpJudge(),
+		Mode:       "swarm",
+	})
+	if diff := cmp.Diff(`unknown orchestrator mode "swarm"`, fmt.Sprint(err)); diff != "" {
+		t.Fatalf("error mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/zchee/tumix/internal/mathcheck"
+)
+
+// AnswerChange records how the answer of one candidate sample evolved from the previous round.
+type AnswerChange struct {
+	Agent string `json:"agent"`
+	// Sample is the 1-based sample index when self-consistency sampling is enabled, otherwise zero.
+	Sample int `json:"sample,omitzero"`
+	// Changed reports that the answer counted by the vote differs from the previous round, or that the sample did not
+	// answer then.
+	Changed bool `json:"changed"`
+	// Diff is the line diff from the previous answer text to this one, lines prefixed with "-" when removed and "+"
+	// when added; it is empty when the text is unchanged.
+	Diff string `json:"diff,omitzero"`
+}
+
+// answerChanges compares every answer of cur with the answer of the same candidate sample in prev, ordered by agent
+// and sample rather than by the order the parallel candidates answered in.
+func answerChanges(p *AnswerProtocol, prev, cur []candidateAnswer) []AnswerChange {
+	type sampleKey struct {
+		agent  string
+		sample int
+	}
+	before := make(map[sampleKey]string, len(prev))
+	for _, a := range prev {
+		before[sampleKey{a.Agent, a.Sample}] = a.Text
+	}
+
+	changes := make([]AnswerChange, 0, len(cur))
+	for _, a := range cur {
+		old, ok := before[sampleKey{a.Agent, a.Sample}]
+		change := AnswerChange{
+			Agent:   a.Agent,
+			Sample:  a.Sample,
+			Changed: !ok || !sameVote(p, old, a.Text),
+		}
+		if old != a.Text {
+			change.Diff = diffLines(old, a.Text)
+		}
+		changes = append(changes, change)
+	}
+	slices.SortFunc(changes, func(a, b AnswerChange) int {
+		return cmp.Or(strings.Compare(a.Agent, b.Agent), cmp.Compare(a.Sample, b.Sample))
+	})
+	return changes
+}
+
+// sameVote reports whether the vote counts the answer texts a and b as the same answer.
+func sameVote(p *AnswerProtocol, a, b string) bool {
+	a, b = finalAnswerText(p, a), finalAnswerText(p, b)
+	if a == b {
+		return true
+	}
+	x, ok := mathcheck.Parse(a)
+	if !ok {
+		return false
+	}
+	y, ok := mathcheck.Parse(b)
+	return ok && mathcheck.Equivalent(x, y)
+}
+
+// diffLines returns the line diff from a to b over their longest common subsequence of lines, unchanged lines
+// prefixed with " ", removed ones with "-", and added ones with "+".
+func diffLines(a, b string) string {
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			sb.WriteString(" " + x[i] + "\n")
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb.WriteString("+" + y[j] + "\n")
+			j++
+		default:
+			sb.WriteString("-" + x[i] + "\n")
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines, none for empty text.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
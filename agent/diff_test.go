@@ -0,0 +1,95 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		a, b string
+		want string
+	}{
+		"equal": {
+			a:    "x\ny",
+			b:    "x\ny",
+			want: " x\n y\n",
+		},
+		"both empty": {},
+		"added": {
+			b:    "x\n",
+			want: "+x\n",
+		},
+		"removed": {
+			a:    "x",
+			want: "-x\n",
+		},
+		"changed line": {
+			a:    "step 1\nso <<<41>>>",
+			b:    "step 1\nstep 2\nso <<<42>>>",
+			want: " step 1\n+step 2\n+so <<<42>>>\n-so <<<41>>>\n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, diffLines(tt.a, tt.b)); diff != "" {
+				t.Fatalf("diffLines(%q, %q) mismatch (-want +got):\n%s", tt.a, tt.b, diff)
+			}
+		})
+	}
+}
+
+func TestAnswerChanges(t *testing.T) {
+	t.Parallel()
+
+	prev := []candidateAnswer{
+		{Agent: "A", Text: "<<<42>>>"},
+		{Agent: "B", Text: "<<<41>>>"},
+		{Agent: "C", Text: "so\n<<<7>>>"},
+		{Agent: "D", Text: "<<<1>>>"},
+		{Agent: "E", Text: "<<<2>>>", Sample: 1},
+	}
+	// In arrival order, which the changes do not follow.
+	cur := []candidateAnswer{
+		{Agent: "E", Text: "<<<2>>>", Sample: 2},
+		{Agent: "C", Text: "hence\n<<<7>>>"},
+		{Agent: "B", Text: "<<<42>>>"},
+		{Agent: "E", Text: "<<<2>>>", Sample: 1},
+		{Agent: "D", Text: "<<<1.0>>>"},
+		{Agent: "A", Text: "<<<42>>>"},
+	}
+
+	want := []AnswerChange{
+		{Agent: "A"},
+		{Agent: "B", Changed: true, Diff: "+<<<42>>>\n-<<<41>>>\n"},
+		{Agent: "C", Diff: "+hence\n-so\n <<<7>>>\n"},
+		{Agent: "D", Diff: "+<<<1.0>>>\n-<<<1>>>\n"},
+		{Agent: "E", Sample: 1},
+		{Agent: "E", Sample: 2, Changed: true, Diff: "+<<<2>>>\n"},
+	}
+	if diff := cmp.Diff(want, answerChanges(DelimitedAnswers, prev, cur)); diff != "" {
+		t.Fatalf("answerChanges() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// MetadataKeyFormatCompliance is the [session.Event] custom metadata key carrying the []FormatCompliance of every
+// candidate agent on the final TUMIX event.
+const MetadataKeyFormatCompliance = "tumix_format_compliance"
+
+const (
+	stateKeyFormatCompliance = "format_compliance"
+	// stateKeyFormatReminder is the state key of the reminder of the answer format ending the shared context of a
+	// candidate asked for its answer once more.
+	stateKeyFormatReminder = "format_reminder"
+)
+
+// formatReminder returns the reminder of the answer format of p.
+func formatReminder(p *AnswerProtocol) string {
+	return `**Your previous reply had no final answer in the required format.** Reply again and end it with the final
+answer ` + p.Enclosure() + `, e.g. ` + p.Example("42") + `.`
+}
+
+// answerFormat is how well an answer followed the [AnswerProtocol].
+type answerFormat int
+
+const (
+	// formatMissing answers have no recognizable final answer; the vote counts their whole text.
+	formatMissing answerFormat = iota
+	// formatRepaired answers give their final answer in another known form, e.g. "«<42»>" or "\boxed{42}", or leave it
+	// unclosed.
+	formatRepaired
+	// formatConforming answers end with a final answer marked by the protocol, e.g. "<<<42>>>".
+	formatConforming
+)
+
+// answerMarkers are the forms, other than that of the configured [AnswerProtocol], final answers are recognized in, in
+// order of preference. The last match in the text wins.
+var answerMarkers = []*regexp.Regexp{
+	// The default protocol, when another one is configured.
+	regexp.MustCompile(`(?s)<<<\s*(.+?)\s*>>>`),
+	// The mismatched markers an earlier Refinement prompt asked for.
+	regexp.MustCompile(`(?s)«<(.+?)»>`),
+	regexp.MustCompile(`(?s)<<\s*([^<>]+?)\s*>>`),
+	regexp.MustCompile(`\\boxed\{((?:[^{}]|\{[^{}]*\})+)\}`),
+	regexp.MustCompile(`(?im)^[\s*#_]*final answer[\s*_]*[:：][\s*_]*(.+?)[\s*_.]*$`),
+}
+
+// extractAnswer returns the final answer of text and how well it followed protocol p. Without a recognizable final
+// answer, it returns the whole text.
+func extractAnswer(p *AnswerProtocol, text string) (string, answerFormat) {
+	text = strings.TrimSpace(text)
+	if answer, closed, ok := p.Extract(text); ok {
+		if closed {
+			return answer, formatConforming
+		}
+		return answer, formatRepaired
+	}
+	for _, re := range answerMarkers {
+		m := re.FindAllStringSubmatch(text, -1)
+		if len(m) == 0 {
+			continue
+		}
+		if answer := strings.TrimSpace(m[len(m)-1][1]); answer != "" {
+			return answer, formatRepaired
+		}
+	}
+	return text, formatMissing
+}
+
+// finalAnswerText returns the final answer of text in protocol p (see [extractAnswer]).
+func finalAnswerText(p *AnswerProtocol, text string) string {
+	answer, _ := extractAnswer(p, text)
+	return answer
+}
+
+// FormatCompliance counts how one candidate agent followed the answer format of the [AnswerProtocol] over a run.
+type FormatCompliance struct {
+	Agent string `json:"agent"`
+	// Answers is the number of answers of the agent, counting a reply to a reprompt in place of the answer it
+	// replaces.
+	Answers int `json:"answers"`
+	// Conforming answers end with a final answer marked by the protocol, Repaired answers give the final answer in
+	// another known form, e.g. "«<answer»>" or "\boxed{answer}", and Missing answers have none, so the vote counts
+	// their whole text.
+	Conforming int `json:"conforming"`
+	Repaired   int `json:"repaired"`
+	Missing    int `json:"missing"`
+	// Reprompted is the number of answers without a final answer the agent was asked once more for, with
+	// [TumixConfig.ReformatAnswers], and Fixed the number of replies that had one.
+	Reprompted int `json:"reprompted,omitzero"`
+	Fixed      int `json:"fixed,omitzero"`
+}
+
+// Rate returns the share of the answers of the agent that conform to the format.
+func (c FormatCompliance) Rate() float64 {
+	if c.Answers == 0 {
+		return 0
+	}
+	return float64(c.Conforming) / float64(c.Answers)
+}
+
+// FormatComplianceFromEvent returns how every candidate agent followed the answer format, as recorded on the final
+// TUMIX event.
+func FormatComplianceFromEvent(event *session.Event) []FormatCompliance {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	compliance, _ := event.CustomMetadata[MetadataKeyFormatCompliance].([]FormatCompliance)
+	return compliance
+}
+
+// formatTally accumulates the [FormatCompliance] of the agents answering in one round.
+type formatTally []FormatCompliance
+
+func (f *formatTally) agent(name string) *FormatCompliance {
+	i := slices.IndexFunc(*f, func(c FormatCompliance) bool { return c.Agent == name })
+	if i < 0 {
+		*f = append(*f, FormatCompliance{Agent: name})
+		i = len(*f) - 1
+	}
+	return &(*f)[i]
+}
+
+// add counts an answer of agent name in format.
+func (f *formatTally) add(name string, format answerFormat) {
+	c := f.agent(name)
+	c.Answers++
+	switch format {
+	case formatConforming:
+		c.Conforming++
+	case formatRepaired:
+		c.Repaired++
+	default:
+		c.Missing++
+	}
+}
+
+// merge adds the counts of tally to f.
+func (f *formatTally) merge(tally formatTally) {
+	for _, c := range tally {
+		m := f.agent(c.Agent)
+		m.Answers += c.Answers
+		m.Conforming += c.Conforming
+		m.Repaired += c.Repaired
+		m.Missing += c.Missing
+		m.Reprompted += c.Reprompted
+		m.Fixed += c.Fixed
+	}
+}
+
+// reformat counts the format of every answer of answers in tally. With [TumixConfig.ReformatAnswers], it first asks
+// every candidate whose answer has no final answer for its answer once more, with a reminder of the format in its
+// shared context, and replaces the answer with the reply. It returns false when the run must stop.
+func (t *tumixOrchestrator) reformat(ctx agent.InvocationContext, candidates agent.Agent, scopes *stateScopes, answers []candidateAnswer, tally *formatTally, yield func(*session.Event, error) bool) bool {
+	for i := range answers {
+		a := &answers[i]
+		_, format := extractAnswer(t.protocol, a.Text)
+		if !t.reformatAnswers || format != formatMissing {
+			tally.add(a.Agent, format)
+			continue
+		}
+		sub := slices.IndexFunc(candidates.SubAgents(), func(s agent.Agent) bool { return s.Name() == a.Agent })
+		if sub < 0 {
+			tally.add(a.Agent, format)
+			continue
+		}
+
+		if err := setState(ctx, stateKeyFormatReminder, formatReminder(t.protocol)); err != nil {
+			yield(nil, err)
+			return false
+		}
+		var reply string
+		for event, err := range scopes.run(ctx, candidates, candidates.SubAgents()[sub]) {
+			if !yield(event, err) {
+				return false
+			}
+			if err != nil || event == nil || event.Partial || event.Content == nil {
+				continue
+			}
+			if text := candidateText(event.Content); text != "" {
+				reply = strings.TrimSpace(text)
+			}
+		}
+		if err := setState(ctx, stateKeyFormatReminder, nil); err != nil {
+			yield(nil, err)
+			return false
+		}
+
+		c := tally.agent(a.Agent)
+		c.Reprompted++
+		if reply != "" {
+			a.Text = reply
+			_, format = extractAnswer(t.protocol, reply)
+		}
+		if format != formatMissing {
+			c.Fixed++
+		}
+		tally.add(a.Agent, format)
+	}
+	return true
+}
+
+// addFormatCompliance merges the compliance of a round into that of the run in the session state.
+func addFormatCompliance(ctx agent.InvocationContext, tally formatTally) error {
+	all, err := stateFormatCompliance(ctx)
+	if err != nil {
+		return err
+	}
+	merged := formatTally(all)
+	merged.merge(tally)
+	return setState(ctx, stateKeyFormatCompliance, []FormatCompliance(merged))
+}
+
+// stateFormatCompliance returns the format compliance of the run in the session state, which is []FormatCompliance
+// when set in this process and generic JSON values when the state was reloaded from a persistent session store.
+func stateFormatCompliance(ctx agent.InvocationContext) ([]FormatCompliance, error) {
+	val, err := ctx.Session().State().Get(stateKeyFormatCompliance)
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("state %s: %w", stateKeyFormatCompliance, err)
+	}
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case []FormatCompliance:
+		return slices.Clone(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal format compliance: %w", err)
+		}
+		var compliance []FormatCompliance
+		if err := json.Unmarshal(b, &compliance); err != nil {
+			return nil, fmt.Errorf("unmarshal format compliance: %w", err)
+		}
+		return compliance, nil
+	}
+}
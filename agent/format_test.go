@@ -0,0 +1,180 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"iter"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestExtractAnswer(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text       string
+		want       string
+		wantFormat answerFormat
+	}{
+		"conforming":          {text: "so <<<1>>> then <<< 42 >>>", want: "42", wantFormat: formatConforming},
+		"unclosed":            {text: "thus <<<42", want: "42", wantFormat: formatRepaired},
+		"empty markers":       {text: "<<<>>>\nFinal answer: 7", want: "7", wantFormat: formatRepaired},
+		"mismatched markers":  {text: "reasoning «<Paris»>", want: "Paris", wantFormat: formatRepaired},
+		"double angles":       {text: "<< 12 >>", want: "12", wantFormat: formatRepaired},
+		"boxed":               {text: `so \boxed{\frac{1}{3}} is it`, want: `\frac{1}{3}`, wantFormat: formatRepaired},
+		"final answer line":   {text: "steps\n**Final Answer:** 12 apples.", want: "12 apples", wantFormat: formatRepaired},
+		"missing":             {text: "  I think it is 12  ", want: "I think it is 12", wantFormat: formatMissing},
+		"last of each marker": {text: "«<a»> then «<b»>", want: "b", wantFormat: formatRepaired},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, format := extractAnswer(DelimitedAnswers, tt.text)
+			if got != tt.want || format != tt.wantFormat {
+				t.Fatalf("extractAnswer(%q) = %q, %v, want %q, %v", tt.text, got, format, tt.want, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestTallyAnswersByFinalAnswer(t *testing.T) {
+	t.Parallel()
+
+	got := tallyAnswers(DelimitedAnswers, []candidateAnswer{
+		{Agent: "a", Text: "first derivation <<<42>>>"},
+		{Agent: "b", Text: "another derivation «<42»>"},
+		{Agent: "c", Text: "no idea"},
+	})
+	want := []answerTally{{Answer: "42", Count: 2}, {Answer: "no idea", Count: 1}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("tallyAnswers() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// sloppyCandidate answers without the "<<<answer>>>" format unless its shared context reminds it of the format.
+func sloppyCandidate(name string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "sloppy candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				text := "it is probably foo"
+				if val, err := ctx.Session().State().Get(stateKeyFormatReminder); err == nil && val != nil {
+					text = "<<<foo>>>"
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.Author = name
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+func TestTumixFormatCompliance(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		reformat   bool
+		want       []FormatCompliance
+		wantUnique int
+	}{
+		"counted only": {
+			want: []FormatCompliance{
+				{Agent: "X", Answers: 2, Conforming: 2},
+				{Agent: "Y", Answers: 2, Missing: 2},
+			},
+			wantUnique: 2,
+		},
+		"reformat": {
+			reformat: true,
+			want: []FormatCompliance{
+				{Agent: "X", Answers: 2, Conforming: 2},
+				{Agent: "Y", Answers: 2, Conforming: 2, Reprompted: 2, Fixed: 2},
+			},
+			wantUnique: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates:      []agent.Agent{staticCandidate("X", "<<<foo>>>"), sloppyCandidate("Y")},
+				Judge:           noOpJudge(),
+				MaxRounds:       2,
+				MinRounds:       2,
+				ReformatAnswers: tt.reformat,
+				Seed:            1,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+
+			var final *session.Event
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == "tumix" {
+					final = event
+				}
+			}
+			if final == nil {
+				t.Fatal("no final event")
+			}
+
+			got := FormatComplianceFromEvent(final)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("FormatComplianceFromEvent() mismatch (-want +got):\n%s", diff)
+			}
+			if got := RoundStatsFromEvent(final); len(got) == 0 || got[len(got)-1].Unique != tt.wantUnique {
+				t.Fatalf("RoundStatsFromEvent() = %+v, want %d unique answers", got, tt.wantUnique)
+			}
+		})
+	}
+}
+
+func TestFormatComplianceRate(t *testing.T) {
+	t.Parallel()
+
+	if got := (FormatCompliance{}).Rate(); got != 0 {
+		t.Fatalf("Rate() of no answers = %v, want 0", got)
+	}
+	if got := (FormatCompliance{Answers: 4, Conforming: 3, Missing: 1}).Rate(); got != 0.75 {
+		t.Fatalf("Rate() = %v, want 0.75", got)
+	}
+}
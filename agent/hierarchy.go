@@ -0,0 +1,501 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+const (
+	// PlannerAgentName is the name of the Planner Agent, which splits a question into sub-questions.
+	PlannerAgentName = "Planner"
+	// SynthesisAgentName is the name of the Synthesis Agent, which composes the sub-answers into the final answer.
+	SynthesisAgentName = "Synthesizer"
+
+	defaultMaxSubTasks = 4
+)
+
+type planArgs struct {
+	SubQuestions []string `json:"sub_questions,omitzero"`
+}
+
+type planResult struct {
+	Stored int `json:"stored,omitzero"`
+}
+
+// newPlanTool returns the tool the Planner Agent stores its sub-questions with.
+func newPlanTool(maxSubTasks int) (tool.Tool, error) {
+	cfg := functiontool.Config{
+		Name:        "plan",
+		Description: "Store the sub-questions to answer independently. Pass an empty list when the question should not be split.",
+	}
+
+	t, err := functiontool.New(cfg, func(ctx tool.Context, args planArgs) (planResult, error) {
+		subs := make([]string, 0, len(args.SubQuestions))
+		for _, q := range args.SubQuestions {
+			// Sub-questions are stored one per line.
+			if q = strings.Join(strings.Fields(q), " "); q != "" {
+				subs = append(subs, q)
+			}
+		}
+		if len(subs) > maxSubTasks {
+			return planResult{}, fmt.Errorf("at most %d sub-questions are allowed, got %d", maxSubTasks, len(subs))
+		}
+
+		if err := ctx.State().Set(stateKeySubQuestions, strings.Join(subs, "\n")); err != nil {
+			return planResult{}, fmt.Errorf("set sub-questions state: %w", err)
+		}
+		return planResult{Stored: len(subs)}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build plan tool: %w", err)
+	}
+	return t, nil
+}
+
+// NewPlannerAgent creates a Planner Agent that decomposes a complex question into at most maxSubTasks
+// self-contained sub-questions.
+func NewPlannerAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, maxSubTasks int) (agent.Agent, error) {
+	if maxSubTasks <= 0 {
+		maxSubTasks = defaultMaxSubTasks
+	}
+	planTool, err := newPlanTool(maxSubTasks)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := llmagent.Config{
+		Name:                  PlannerAgentName,
+		Description:           `Splits a complex question into independent sub-questions.`,
+		Model:                 llm,
+		GenerateContentConfig: cloneGenConfig(genCfg),
+		Tools:                 []tool.Tool{planTool},
+		Instruction: fmt.Sprintf(`Task: Plan how to answer the question below; do not solve it yourself.
+
+Question:
+{question}
+
+Instructions:
+1. Decide whether the question combines several parts that can be answered independently.
+2. If so, write 2 to %d sub-questions. Each must be self-contained (repeat every fact, number, and definition it
+   needs) and answerable without the answers of the others; together they must cover everything the question asks.
+3. If the question is a single problem, or its parts depend on each other step by step, do not split it.
+4. Call plan exactly once with the sub-questions, or with an empty list when not splitting.`, maxSubTasks),
+	}
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build Planner agent: %w", err)
+	}
+
+	return a, nil
+}
+
+// NewSynthesisAgent creates a Synthesis Agent that composes the answers of the sub-questions into the final answer.
+func NewSynthesisAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
+	finalizeTool, err := newFinalizeTool()
+	if err != nil {
+		return nil, fmt.Errorf("build finalize tool: %w", err)
+	}
+
+	cfg := llmagent.Config{
+		Name:                  SynthesisAgentName,
+		Description:           `Composes sub-question answers into the final answer.`,
+		Model:                 llm,
+		GenerateContentConfig: cloneGenConfig(genCfg),
+		Tools:                 []tool.Tool{finalizeTool},
+		Instruction: `Task: Answer the question by composing the answers of its sub-questions.
+
+Question:
+{question}
+
+Sub-questions and their answers (each settled by a separate multi-agent run):
+{sub_answers}
+
+Instructions:
+1. Check the sub-answers for consistency with each other and with the question; resolve conflicts explicitly.
+2. Combine them into one complete answer to the original question; fill small gaps yourself only when needed.
+3. Call finalize exactly once with the answer and a confidence 0-1 that reflects the weakest sub-answer you relied on.
+
+End with the answer ` + p.Enclosure() + `.`,
+	}
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build Synthesis agent: %w", err)
+	}
+
+	return a, nil
+}
+
+// HierarchicalConfig configures hierarchical TUMIX.
+type HierarchicalConfig struct {
+	// Sub configures the TUMIX loop run for each sub-question, usually with fewer rounds than a flat run.
+	// Its PromptCompression is not applied to sub-questions.
+	Sub TumixConfig
+
+	// Planner splits the question into sub-questions. Nil builds one with [NewPlannerAgent] from Model.
+	Planner agent.Agent
+	// Synthesizer composes the sub-answers. Nil builds one with [NewSynthesisAgent] from Model, asking for the final
+	// answer in Sub.AnswerProtocol.
+	Synthesizer agent.Agent
+
+	// Model is the model of the Planner and Synthesizer agents built when they are nil.
+	Model model.LLM
+	// GenerateContentConfig is the generation config of the agents built from Model.
+	GenerateContentConfig *genai.GenerateContentConfig
+
+	// MaxSubTasks caps the number of sub-questions. Zero means 4.
+	MaxSubTasks int
+	// MaxParallel caps the sub-runs in flight. Zero runs every sub-question at once.
+	MaxParallel int
+}
+
+// NewHierarchicalTumixAgent creates a TUMIX loader that decomposes the question before running TUMIX.
+//
+// The Planner Agent splits the question into sub-questions, each answered by its own TUMIX loop built from
+// cfg.Sub in an isolated session, in parallel. The Synthesis Agent then composes the sub-answers into the final
+// answer. A question the planner does not split is answered by a single sub-run.
+func NewHierarchicalTumixAgent(cfg HierarchicalConfig) (agent.Loader, error) {
+	if cfg.MaxSubTasks <= 0 {
+		cfg.MaxSubTasks = defaultMaxSubTasks
+	}
+	cfg.Sub.PromptCompression = nil
+	// Build the sub-loop once up front so a bad configuration fails here rather than per sub-question.
+	if _, err := NewTumixAgentWithConfig(cfg.Sub); err != nil {
+		return nil, fmt.Errorf("build sub-task loop: %w", err)
+	}
+
+	if cfg.Planner == nil || cfg.Synthesizer == nil {
+		if cfg.Model == nil {
+			return nil, errors.New("planner and synthesizer agents or a model are required")
+		}
+	}
+	if cfg.Planner == nil {
+		planner, err := NewPlannerAgent(cfg.Model, cfg.GenerateContentConfig, cfg.MaxSubTasks)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Planner = planner
+	}
+	if cfg.Synthesizer == nil {
+		synthesizer, err := NewSynthesisAgent(cfg.Model, cfg.GenerateContentConfig, cfg.Sub.AnswerProtocol)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Synthesizer = synthesizer
+	}
+
+	orchestrator := &hierarchicalOrchestrator{
+		sub:         cfg.Sub,
+		planner:     cfg.Planner,
+		synthesizer: cfg.Synthesizer,
+		maxSubTasks: cfg.MaxSubTasks,
+		maxParallel: cfg.MaxParallel,
+	}
+
+	tumix, err := agent.New(agent.Config{
+		Name:        "tumix",
+		Description: "Hierarchical TUMIX: plans sub-questions, answers each with TUMIX, and synthesizes the result.",
+		SubAgents:   []agent.Agent{cfg.Planner, cfg.Synthesizer},
+		Run:         orchestrator.run,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build hierarchical tumix agent: %w", err)
+	}
+
+	return agent.NewSingleLoader(tumix), nil
+}
+
+type hierarchicalOrchestrator struct {
+	sub         TumixConfig
+	planner     agent.Agent
+	synthesizer agent.Agent
+	maxSubTasks int
+	maxParallel int
+}
+
+// subAnswer is the outcome of the TUMIX loop of one sub-question.
+type subAnswer struct {
+	Question   string
+	Answer     string
+	Confidence any
+	Usage      *genai.GenerateContentResponseUsageMetadata
+	Err        error
+}
+
+func (h *hierarchicalOrchestrator) run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		question := firstContentText(ctx.UserContent())
+		if err := setState(ctx, stateKeyQuestion, question); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		subQuestions, stop := h.plan(ctx, yield)
+		if stop {
+			return
+		}
+		if len(subQuestions) < 2 {
+			subQuestions = []string{question}
+		}
+
+		answers := h.runSubTasks(ctx, subQuestions)
+		var errs []error
+		for i, ans := range answers {
+			if ans.Err != nil {
+				errs = append(errs, fmt.Errorf("sub-task %d: %w", i+1, ans.Err))
+			}
+			if !yield(subTaskEvent(ctx, i, ans), nil) {
+				return
+			}
+		}
+		if len(errs) == len(answers) {
+			yield(nil, errors.Join(errs...))
+			return
+		}
+
+		if len(answers) == 1 {
+			// The question was not split, so its TUMIX answer is final.
+			if err := setState(ctx, stateKeyAnswer, answers[0].Answer); err != nil {
+				yield(nil, err)
+				return
+			}
+			if answers[0].Confidence != nil {
+				if err := setState(ctx, stateKeyConfidence, answers[0].Confidence); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+			emitFinalFromState(ctx, nil, yield)
+			return
+		}
+
+		joined := joinSubAnswers(answers)
+		if err := setState(ctx, stateKeySubAnswers, joined); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := setState(ctx, stateKeyJoined, joined); err != nil {
+			yield(nil, err)
+			return
+		}
+		if stop := h.synthesize(ctx, joined, yield); stop {
+			return
+		}
+		emitFinalFromState(ctx, nil, yield)
+	}
+}
+
+// plan runs the planner and returns the sub-questions it stored, capped at maxSubTasks.
+func (h *hierarchicalOrchestrator) plan(ctx agent.InvocationContext, yield func(*session.Event, error) bool) ([]string, bool) {
+	// Clear the plan of a previous turn of the session.
+	if err := setState(ctx, stateKeySubQuestions, ""); err != nil {
+		yield(nil, err)
+		return nil, true
+	}
+	for event, err := range h.planner.Run(ctx) {
+		if !yield(event, err) {
+			return nil, true
+		}
+	}
+
+	val, err := getState(ctx, stateKeySubQuestions)
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return nil, false
+	}
+	if err != nil {
+		yield(nil, err)
+		return nil, true
+	}
+	var subs []string
+	for line := range strings.Lines(fmt.Sprint(val)) {
+		if line = strings.TrimSpace(line); line != "" {
+			subs = append(subs, line)
+		}
+	}
+	return subs[:min(len(subs), h.maxSubTasks)], false
+}
+
+// runSubTasks answers every sub-question with its own TUMIX loop, at most maxParallel at a time.
+func (h *hierarchicalOrchestrator) runSubTasks(ctx context.Context, subQuestions []string) []subAnswer {
+	parallel := h.maxParallel
+	if parallel <= 0 {
+		parallel = len(subQuestions)
+	}
+	sem := make(chan struct{}, parallel)
+
+	answers := make([]subAnswer, len(subQuestions))
+	var wg sync.WaitGroup
+	for i, q := range subQuestions {
+		wg.Go(func() {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				answers[i] = subAnswer{Question: q, Err: ctx.Err()}
+				return
+			}
+			answers[i] = h.runSubTask(ctx, i, q)
+		})
+	}
+	wg.Wait()
+	return answers
+}
+
+// runSubTask runs a fresh TUMIX loop on question in a session of its own, so the sub-runs neither share round state
+// with each other nor with the parent session.
+func (h *hierarchicalOrchestrator) runSubTask(ctx context.Context, index int, question string) subAnswer {
+	res := subAnswer{Question: question, Usage: &genai.GenerateContentResponseUsageMetadata{}}
+
+	loader, err := NewTumixAgentWithConfig(h.sub)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	const appName, userID = "tumix-subtask", "tumix"
+	sessionID := fmt.Sprintf("subtask-%d", index+1)
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: appName, UserID: userID, SessionID: sessionID}); err != nil {
+		res.Err = fmt.Errorf("create session: %w", err)
+		return res
+	}
+	r, err := runner.New(runner.Config{AppName: appName, Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		res.Err = fmt.Errorf("build runner: %w", err)
+		return res
+	}
+
+	for event, err := range r.Run(ctx, userID, sessionID, genai.NewContentFromText(question, genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		if event != nil && !event.Partial {
+			addUsage(res.Usage, event.UsageMetadata)
+		}
+	}
+
+	got, err := svc.Get(ctx, &session.GetRequest{AppName: appName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		res.Err = fmt.Errorf("get session: %w", err)
+		return res
+	}
+	answer, err := got.Session.State().Get(stateKeyAnswer)
+	if err != nil {
+		res.Err = fmt.Errorf("no final answer: %w", err)
+		return res
+	}
+	res.Answer = fmt.Sprint(answer)
+	if conf, err := got.Session.State().Get(stateKeyConfidence); err == nil {
+		res.Confidence = conf
+	}
+	return res
+}
+
+// synthesize runs the synthesizer. When it does not call finalize, its last answer text, or else the joined
+// sub-answers, becomes the final answer.
+func (h *hierarchicalOrchestrator) synthesize(ctx agent.InvocationContext, joined string, yield func(*session.Event, error) bool) bool {
+	// Clear the answer of a previous turn of the session to tell whether the synthesizer finalized.
+	if err := setState(ctx, stateKeyAnswer, ""); err != nil {
+		yield(nil, err)
+		return true
+	}
+
+	var last string
+	for event, err := range h.synthesizer.Run(ctx) {
+		if !yield(event, err) {
+			return true
+		}
+		if err != nil || event == nil || event.Partial {
+			continue
+		}
+		if text := firstTextFromContent(event.Content); text != "" {
+			last = text
+		}
+	}
+
+	if val, err := getState(ctx, stateKeyAnswer); err == nil && fmt.Sprint(val) != "" {
+		return false
+	}
+	answer := h.sub.AnswerProtocol.Strip(last)
+	if answer == "" {
+		answer = joined
+	}
+	if err := setState(ctx, stateKeyAnswer, answer); err != nil {
+		yield(nil, err)
+		return true
+	}
+	return false
+}
+
+// subTaskEvent reports the answer of sub-task index, carrying the token usage of its whole TUMIX loop.
+func subTaskEvent(ctx agent.InvocationContext, index int, ans subAnswer) *session.Event {
+	text := fmt.Sprintf("Sub-question %d: %s\nAnswer: %s", index+1, ans.Question, ans.Answer)
+	if ans.Err != nil {
+		text = fmt.Sprintf("Sub-question %d: %s\nFailed: %v", index+1, ans.Question, ans.Err)
+	}
+	ev := session.NewEvent(ctx.InvocationID())
+	ev.Author = fmt.Sprintf("subtask-%d", index+1)
+	ev.LLMResponse = model.LLMResponse{
+		Content:       genai.NewContentFromText(text, genai.RoleModel),
+		UsageMetadata: ans.Usage,
+	}
+	return ev
+}
+
+func joinSubAnswers(answers []subAnswer) string {
+	var sb strings.Builder
+	for i, a := range answers {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		answer := a.Answer
+		if a.Err != nil {
+			answer = "(unanswered)"
+		}
+		fmt.Fprintf(&sb, "%d. %s\n   Answer: %s", i+1, a.Question, answer)
+	}
+	return sb.String()
+}
+
+// addUsage adds the token counts of u to total.
+func addUsage(total, u *genai.GenerateContentResponseUsageMetadata) {
+	if u == nil {
+		return
+	}
+	total.PromptTokenCount += u.PromptTokenCount
+	total.CachedContentTokenCount += u.CachedContentTokenCount
+	total.CandidatesTokenCount += u.CandidatesTokenCount
+	total.ThoughtsTokenCount += u.ThoughtsTokenCount
+	total.ToolUsePromptTokenCount += u.ToolUsePromptTokenCount
+	total.TotalTokenCount += u.TotalTokenCount
+}
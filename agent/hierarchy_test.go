@@ -0,0 +1,208 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestHierarchicalTumix(t *testing.T) {
+	tests := map[string]struct {
+		plan       string
+		wantAnswer string
+		wantEvents []string
+	}{
+		"split": {
+			plan:       "What is a?\nWhat is b?",
+			wantAnswer: "1. What is a?\n   Answer: echo: What is a?\n2. What is b?\n   Answer: echo: What is b?",
+			wantEvents: []string{PlannerAgentName, "subtask-1", "subtask-2", SynthesisAgentName, "tumix"},
+		},
+		"not split": {
+			plan:       "",
+			wantAnswer: "echo: q",
+			wantEvents: []string{PlannerAgentName, "subtask-1", "tumix"},
+		},
+		"capped": {
+			plan:       "one\ntwo\nthree",
+			wantAnswer: "1. one\n   Answer: echo: one\n2. two\n   Answer: echo: two",
+			wantEvents: []string{PlannerAgentName, "subtask-1", "subtask-2", SynthesisAgentName, "tumix"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			loader, err := NewHierarchicalTumixAgent(HierarchicalConfig{
+				Sub: TumixConfig{
+					Candidates: []agent.Agent{echoCandidate("E1"), echoCandidate("E2")},
+					Judge:      noOpJudge(),
+					MaxRounds:  1,
+				},
+				Planner:     stubPlanner(tt.plan),
+				Synthesizer: stubSynthesizer(),
+				MaxSubTasks: 2,
+				MaxParallel: 1,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+
+			var authors []string
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				authors = append(authors, event.Author)
+			}
+			if diff := cmp.Diff(tt.wantEvents, authors); diff != "" {
+				t.Fatalf("event authors mismatch (-want +got):\n%s", diff)
+			}
+
+			res, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+			if err != nil {
+				t.Fatalf("get session: %v", err)
+			}
+			answer, err := res.Session.State().Get(stateKeyAnswer)
+			if err != nil {
+				t.Fatalf("state answer: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantAnswer, answer); diff != "" {
+				t.Fatalf("final answer mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewHierarchicalTumixAgentErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]HierarchicalConfig{
+		"no candidates": {
+			Planner:     stubPlanner(""),
+			Synthesizer: stubSynthesizer(),
+		},
+		"no planner model": {
+			Sub: TumixConfig{Candidates: []agent.Agent{echoCandidate("E")}, Judge: noOpJudge()},
+		},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewHierarchicalTumixAgent(cfg); err == nil {
+				t.Fatal("NewHierarchicalTumixAgent() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	t.Parallel()
+
+	total := &genai.GenerateContentResponseUsageMetadata{}
+	addUsage(total, &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 5, TotalTokenCount: 15})
+	addUsage(total, nil)
+	addUsage(total, &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 1, ThoughtsTokenCount: 2, TotalTokenCount: 3})
+
+	want := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 11, CandidatesTokenCount: 5, ThoughtsTokenCount: 2, TotalTokenCount: 18}
+	if diff := cmp.Diff(want, total); diff != "" {
+		t.Fatalf("addUsage() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// echoCandidate answers with the question it was given, proving each sub-run sees its own sub-question.
+func echoCandidate(name string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "echo candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				q, err := ctx.Session().State().Get(stateKeyQuestion)
+				if err != nil {
+					yield(nil, fmt.Errorf("state question: %w", err))
+					return
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(fmt.Sprintf("echo: %v", q), genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+// stubPlanner stores plan, one sub-question per line, as the planner's sub-questions.
+func stubPlanner(plan string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        PlannerAgentName,
+		Description: "stub planner",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				if err := ctx.Session().State().Set(stateKeySubQuestions, plan); err != nil {
+					yield(nil, fmt.Errorf("set plan: %w", err))
+					return
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("planned", genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+// stubSynthesizer finalizes the joined sub-answers as the answer.
+func stubSynthesizer() agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        SynthesisAgentName,
+		Description: "stub synthesizer",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				subs, err := ctx.Session().State().Get(stateKeySubAnswers)
+				if err != nil {
+					yield(nil, fmt.Errorf("state sub answers: %w", err))
+					return
+				}
+				if err := ctx.Session().State().Set(stateKeyAnswer, strings.TrimSpace(fmt.Sprint(subs))); err != nil {
+					yield(nil, fmt.Errorf("set answer: %w", err))
+					return
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("synthesized", genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
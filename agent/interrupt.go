@@ -0,0 +1,72 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// ErrInterrupted is the cause of the cancellation of a run interrupted through [WithInterrupt].
+var ErrInterrupted = errors.New("interrupted")
+
+// MetadataKeyProvisional is the [session.Event] custom metadata key set to true on the final TUMIX event of an
+// interrupted run.
+const MetadataKeyProvisional = "tumix_provisional"
+
+type interruptKey struct{}
+
+// WithInterrupt returns a copy of ctx carrying interrupt. Once interrupt is done, a TUMIX Agent running under ctx cuts
+// its agents short and finalizes a provisional answer, the score-weighted majority vote over the answers collected so
+// far, instead of failing (see [IsProvisional]).
+//
+// ctx itself should outlive interrupt, so the runner can still store the final event.
+func WithInterrupt(ctx, interrupt context.Context) context.Context {
+	return context.WithValue(ctx, interruptKey{}, interrupt)
+}
+
+// IsProvisional reports whether event is the final TUMIX event of an interrupted run.
+func IsProvisional(event *session.Event) bool {
+	if event == nil || event.CustomMetadata == nil {
+		return false
+	}
+	provisional, _ := event.CustomMetadata[MetadataKeyProvisional].(bool)
+	return provisional
+}
+
+// withInterrupt returns ctx canceled with [ErrInterrupted] once the interrupt context attached by [WithInterrupt] is
+// done, or ctx itself when there is none.
+func withInterrupt(ctx agent.InvocationContext) (agent.InvocationContext, context.CancelFunc) {
+	interrupt, ok := ctx.Value(interruptKey{}).(context.Context)
+	if !ok {
+		return ctx, func() {}
+	}
+	ictx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(interrupt, func() { cancel(ErrInterrupted) })
+	return &deadlineContext{InvocationContext: ctx, ctx: ictx}, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// interrupted reports whether ctx was canceled by an interruption.
+func interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
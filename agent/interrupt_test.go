@@ -0,0 +1,125 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestTumixInterrupt(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mode Mode
+	}{
+		"tumix":  {mode: ModeTumix},
+		"debate": {mode: ModeDebate},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var judgeCalls atomic.Int64
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{staticCandidate("X", "foo"), blockingCandidate("slow")},
+				Judge:      countingJudge(&judgeCalls, noOpJudge()),
+				MaxRounds:  3,
+				MinRounds:  1,
+				Mode:       tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+
+			interrupt, cancel := context.WithCancel(ctx)
+			defer cancel()
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			var final *session.Event
+			for event, err := range r.Run(WithInterrupt(ctx, interrupt), "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == "tumix" {
+					final = event
+				}
+			}
+
+			if final == nil {
+				t.Fatal("no final event")
+			}
+			if got, want := firstTextFromContent(final.Content), "Provisional answer (interrupted) (conf 1): foo"; got != want {
+				t.Fatalf("final answer = %q, want %q", got, want)
+			}
+			if !IsProvisional(final) {
+				t.Fatal("final event is not provisional")
+			}
+			if diff := cmp.Diff([]string{"round 1: interrupted"}, TimeoutsFromEvent(final)); diff != "" {
+				t.Fatalf("timeouts mismatch (-want +got):\n%s", diff)
+			}
+			if got := judgeCalls.Load(); got != 0 {
+				t.Fatalf("judge calls = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestIsProvisional(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		event *session.Event
+		want  bool
+	}{
+		"nil":         {},
+		"no metadata": {event: &session.Event{}},
+		"provisional": {
+			event: &session.Event{LLMResponse: model.LLMResponse{CustomMetadata: map[string]any{MetadataKeyProvisional: true}}},
+			want:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := IsProvisional(tt.event); got != tt.want {
+				t.Fatalf("IsProvisional() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"math"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// WithResponseLogprobs returns a copy of genCfg requesting the token log probabilities of the responses, which
+// weight the fallback vote by the confidence of each candidate's answer on backends that report them.
+func WithResponseLogprobs(genCfg *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	cfg := cloneGenConfig(genCfg)
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	cfg.ResponseLogprobs = true
+	return cfg
+}
+
+// eventConfidence returns the length-normalized confidence, 0-1, of the answer in event: the geometric mean of its
+// token probabilities. It is zero when the backend reported no log probabilities.
+func eventConfidence(event *session.Event) float64 {
+	if res := event.LogprobsResult; res != nil {
+		var (
+			sum float64
+			n   int
+		)
+		for _, c := range res.ChosenCandidates {
+			if c == nil {
+				continue
+			}
+			sum += float64(c.LogProbability)
+			n++
+		}
+		if n > 0 {
+			return math.Exp(sum / float64(n))
+		}
+	}
+	if event.AvgLogprobs != 0 {
+		return math.Exp(event.AvgLogprobs)
+	}
+	return 0
+}
+
+// answerConfidences returns the logprob confidence of every answer, or nil when none has one.
+//
+// Answers without a confidence get the mean of the others, so candidates on backends without log probabilities are
+// neither favored nor penalized.
+func answerConfidences(ans []candidateAnswer) []float64 {
+	var (
+		sum   float64
+		known int
+	)
+	for _, a := range ans {
+		if a.Confidence > 0 {
+			sum += a.Confidence
+			known++
+		}
+	}
+	if known == 0 {
+		return nil
+	}
+
+	mean := sum / float64(known)
+	out := make([]float64, len(ans))
+	for i, a := range ans {
+		out[i] = a.Confidence
+		if out[i] <= 0 {
+			out[i] = mean
+		}
+	}
+	return out
+}
+
+// confidenceFactor scales a vote weight by a logprob confidence, discounting it by up to half for an unsure answer.
+func confidenceFactor(confidence float64) float64 {
+	return 0.5 + 0.5*confidence
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestWithResponseLogprobs(t *testing.T) {
+	t.Parallel()
+
+	temp := float32(0.3)
+	base := &genai.GenerateContentConfig{Temperature: &temp}
+	got := WithResponseLogprobs(base)
+	if !got.ResponseLogprobs || got.Temperature != &temp {
+		t.Fatalf("WithResponseLogprobs() = %+v, want logprobs with the base settings", got)
+	}
+	if base.ResponseLogprobs {
+		t.Fatal("WithResponseLogprobs() modified its input")
+	}
+	if got := WithResponseLogprobs(nil); !got.ResponseLogprobs {
+		t.Fatalf("WithResponseLogprobs(nil) = %+v, want logprobs", got)
+	}
+}
+
+func TestEventConfidence(t *testing.T) {
+	t.Parallel()
+
+	half := float32(math.Log(0.5))
+	tests := map[string]struct {
+		resp model.LLMResponse
+		want float64
+	}{
+		"none": {},
+		"chosen candidates": {
+			resp: model.LLMResponse{LogprobsResult: &genai.LogprobsResult{
+				ChosenCandidates: []*genai.LogprobsResultCandidate{{LogProbability: 0}, nil, {LogProbability: 2 * half}},
+			}},
+			want: 0.5,
+		},
+		"average logprob": {
+			resp: model.LLMResponse{AvgLogprobs: float64(half)},
+			want: 0.5,
+		},
+		"empty result uses average": {
+			resp: model.LLMResponse{LogprobsResult: &genai.LogprobsResult{}, AvgLogprobs: float64(half)},
+			want: 0.5,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			event := &session.Event{LLMResponse: tt.resp}
+			if got := eventConfidence(event); math.Abs(got-tt.want) > 1e-6 {
+				t.Fatalf("eventConfidence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnswerConfidences(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		ans  []candidateAnswer
+		want []float64
+	}{
+		"none":    {ans: []candidateAnswer{{Agent: "X"}, {Agent: "Y"}}},
+		"all":     {ans: []candidateAnswer{{Confidence: 0.2}, {Confidence: 0.4}}, want: []float64{0.2, 0.4}},
+		"partial": {ans: []candidateAnswer{{Confidence: 0.2}, {}, {Confidence: 0.6}}, want: []float64{0.2, 0.4, 0.6}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := answerConfidences(tt.ans)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("answerConfidences() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Tool names recorded as [Citation.Tool] for Gemini built-in tools.
+const (
+	nativeSearchToolName = "google_search"
+	nativeCodeToolName   = "code_execution"
+)
+
+// NewNativeSearchAgent creates the Search Agent backed by the model's built-in Google Search tool instead of
+// <search> text markers.
+//
+// It replaces [NewSearchAgent] when the backend runs the GoogleSearch tool itself, which is the case for Gemini.
+// The grounding sources of its answers are recorded as citations.
+func NewNativeSearchAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
+	cfg := llmagent.Config{
+		Name: "search",
+		Description: `Uses WebSearch.
+- Short name: {S}`,
+		Model:                 llm,
+		GenerateContentConfig: withNativeTools(genCfg, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}}),
+		Instruction: `You are a helpful AI assistant. Solve tasks using WebSearch tool.
+
+You have the Google Search tool; use it to look up facts you are not sure about or that may have changed, and
+ground your reasoning in the results it returns. Search as many times as you need.
+
+**Do not output the code for execution.** In the end of your response, output the final answer ` + p.Enclosure() + `.`,
+	}
+
+	applySharedContext(&cfg, p)
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build Search agent: %w", err)
+	}
+
+	return a, nil
+}
+
+// NewNativeCodeAgent creates the Code Agent backed by the model's built-in code execution tool instead of fenced
+// code blocks run by a separate interpreter.
+//
+// It replaces [NewCodeAgent] when the backend runs the CodeExecution tool itself, which is the case for Gemini.
+func NewNativeCodeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
+	a, err := newNativeCodeAgent(llm, genCfg, p, "code", `Code-execution strategy for precise computation.
+- Short name: {C}`)
+	if err != nil {
+		return nil, fmt.Errorf("build Code agent: %w", err)
+	}
+	return a, nil
+}
+
+// NewNativeCodePlusAgent creates the Code+ Agent backed by the model's built-in code execution tool.
+//
+// It replaces [NewCodePlusAgent] when the backend runs the CodeExecution tool itself, which is the case for Gemini.
+func NewNativeCodePlusAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
+	a, err := newNativeCodeAgent(llm, genCfg, p, "code-plus", `Code-execution strategy for precise computation with a hinted version with extra human-pre-designed priors.
+- Short name: {C+}`)
+	if err != nil {
+		return nil, fmt.Errorf("build Code+ agent: %w", err)
+	}
+	return a, nil
+}
+
+func newNativeCodeAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol, name, description string) (agent.Agent, error) {
+	cfg := llmagent.Config{
+		Name:                  name,
+		Description:           description,
+		Model:                 llm,
+		GenerateContentConfig: withNativeTools(genCfg, &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}}),
+		Instruction: `The User asks a question, and you solve it. You first generate the reasoning and thinking process and
+then provide the User with the final answer.
+
+During the thinking process, **use the code execution tool** for efficient searching, optimization, and computing.
+Each script must print its output. The tool runs the code and returns the execution output and error, and you can
+continue your reasoning process and run more code to solve the problem. Once you feel you are ready for the final
+answer, directly return the answer with the format ` + p.Example("answer content") + ` at the end of your response.`,
+	}
+
+	applySharedContext(&cfg, p)
+
+	return llmagent.New(cfg)
+}
+
+// withNativeTools returns a copy of genCfg with tools appended to its tool list.
+func withNativeTools(genCfg *genai.GenerateContentConfig, tools ...*genai.Tool) *genai.GenerateContentConfig {
+	cfg := cloneGenConfig(genCfg)
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	cfg.Tools = append(slices.Clip(cfg.Tools), tools...)
+	return cfg
+}
+
+// candidateText returns the answer text of a candidate response.
+//
+// Responses produced with the built-in code execution tool interleave text with executable code and its result, and
+// the final answer usually follows them; such responses are rendered as a whole, with code and output fenced, so the
+// answer and the computation behind it reach the Judge. Other responses yield their first text part.
+func candidateText(c *genai.Content) string {
+	if c == nil || !slices.ContainsFunc(c.Parts, isCodeExecutionPart) {
+		return firstTextFromContent(c)
+	}
+
+	var sb strings.Builder
+	for _, p := range c.Parts {
+		if p == nil || p.Thought {
+			continue
+		}
+		var chunk string
+		switch {
+		case p.ExecutableCode != nil:
+			lang := ""
+			if l := p.ExecutableCode.Language; l != "" && l != genai.LanguageUnspecified {
+				lang = strings.ToLower(string(l))
+			}
+			chunk = "```" + lang + "\n" + strings.TrimSpace(p.ExecutableCode.Code) + "\n```"
+		case p.CodeExecutionResult != nil:
+			chunk = "```output\n" + strings.TrimSpace(p.CodeExecutionResult.Output) + "\n```"
+			switch outcome := p.CodeExecutionResult.Outcome; outcome {
+			case "", genai.OutcomeUnspecified, genai.OutcomeOK:
+			default:
+				chunk = fmt.Sprintf("Execution failed (%s):\n%s", outcome, chunk)
+			}
+		default:
+			chunk = strings.TrimSpace(p.Text)
+		}
+		if chunk == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(chunk)
+	}
+	return sb.String()
+}
+
+func isCodeExecutionPart(p *genai.Part) bool {
+	return p != nil && (p.ExecutableCode != nil || p.CodeExecutionResult != nil)
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestWithNativeTools(t *testing.T) {
+	t.Parallel()
+
+	search := &genai.Tool{GoogleSearch: &genai.GoogleSearch{}}
+	fn := &genai.Tool{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "lookup"}}}
+
+	if got := withNativeTools(nil, search); len(got.Tools) != 1 || got.Tools[0] != search {
+		t.Fatalf("withNativeTools(nil) tools = %v, want [search]", got.Tools)
+	}
+
+	base := &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.2), Tools: make([]*genai.Tool, 1, 4)}
+	base.Tools[0] = fn
+	got := withNativeTools(base, search)
+	if diff := cmp.Diff([]*genai.Tool{fn, search}, got.Tools); diff != "" {
+		t.Fatalf("withNativeTools() tools mismatch (-want +got):\n%s", diff)
+	}
+	if got == base || *got.Temperature != 0.2 {
+		t.Fatalf("withNativeTools() = %+v, want a copy of the base config", got)
+	}
+	if len(base.Tools) != 1 || base.Tools[:2][1] != nil {
+		t.Fatalf("withNativeTools() modified the base tools: %v", base.Tools[:2])
+	}
+}
+
+func TestCandidateText(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		content *genai.Content
+		want    string
+	}{
+		"nil": {},
+		"text only keeps first part": {
+			content: &genai.Content{Parts: []*genai.Part{{Text: "first <<<1>>>"}, {Text: "second"}}},
+			want:    "first <<<1>>>",
+		},
+		"code execution": {
+			content: &genai.Content{Parts: []*genai.Part{
+				{Text: "Let me compute.", Thought: true},
+				{Text: "I will compute it. "},
+				{ExecutableCode: &genai.ExecutableCode{Code: "print(6*7)\n", Language: genai.LanguagePython}},
+				nil,
+				{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "42\n"}},
+				{Text: "The answer is <<<42>>>"},
+			}},
+			want: "I will compute it.\n\n```python\nprint(6*7)\n```\n\n```output\n42\n```\n\nThe answer is <<<42>>>",
+		},
+		"failed execution": {
+			content: &genai.Content{Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "1/0"}},
+				{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeFailed, Output: "ZeroDivisionError"}},
+			}},
+			want: "```\n1/0\n```\n\nExecution failed (OUTCOME_FAILED):\n```output\nZeroDivisionError\n```",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, candidateText(tt.content)); diff != "" {
+				t.Fatalf("candidateText() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNativeAgents(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]func(model.LLM, *genai.GenerateContentConfig, *AnswerProtocol) (agent.Agent, error){
+		"search":    NewNativeSearchAgent,
+		"code":      NewNativeCodeAgent,
+		"code-plus": NewNativeCodePlusAgent,
+	}
+
+	for name, build := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := build(nil, &genai.GenerateContentConfig{}, nil)
+			if err != nil {
+				t.Fatalf("build native %s agent: %v", name, err)
+			}
+			if got := a.Name(); got != name {
+				t.Fatalf("Name() = %q, want %q", got, name)
+			}
+		})
+	}
+}
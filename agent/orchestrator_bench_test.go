@@ -0,0 +1,100 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"testing"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+const (
+	benchCandidates = 12
+	benchRounds     = 10
+)
+
+// benchAnswer is the answer of the i-th of the synthetic candidates: a derivation ending in one of three values, so
+// the vote sees a majority, a minority, and a numeric conflict.
+func benchAnswer(i int) string {
+	return fmt.Sprintf("Candidate %d works through the problem step by step, checks each step, and concludes. <<<%d>>>", i, 40+i%3)
+}
+
+func benchAnswers() []candidateAnswer {
+	ans := make([]candidateAnswer, benchCandidates)
+	for i := range ans {
+		ans[i] = candidateAnswer{Agent: fmt.Sprintf("C%02d", i), Text: benchAnswer(i)}
+	}
+	return ans
+}
+
+func BenchmarkJoinAnswers(b *testing.B) {
+	ans := benchAnswers()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = joinAnswers(ans)
+	}
+}
+
+func BenchmarkComputeStats(b *testing.B) {
+	ans := benchAnswers()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = computeStats(DelimitedAnswers, ans, benchCandidates, 0)
+	}
+}
+
+// BenchmarkTumixRun runs 12 candidates for 10 rounds, the Judge never stopping early.
+func BenchmarkTumixRun(b *testing.B) {
+	candidates := make([]agent.Agent, benchCandidates)
+	for i := range candidates {
+		candidates[i] = staticCandidate(fmt.Sprintf("C%02d", i), benchAnswer(i))
+	}
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: candidates,
+		Judge:      noOpJudge(),
+		MaxRounds:  benchRounds,
+		MinRounds:  benchRounds,
+	})
+	if err != nil {
+		b.Fatalf("loader: %v", err)
+	}
+	msg := genai.NewContentFromText("q", genai.RoleUser)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		ctx := b.Context()
+		svc := session.InMemoryService()
+		if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+			b.Fatalf("create session: %v", err)
+		}
+		r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+		if err != nil {
+			b.Fatalf("runner: %v", err)
+		}
+		for _, err := range r.Run(ctx, "u", "s", msg, agent.RunConfig{}) {
+			if err != nil {
+				b.Fatalf("run err: %v", err)
+			}
+		}
+	}
+}
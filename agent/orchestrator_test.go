@@ -17,16 +17,25 @@
 package agent
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm/dedup"
 )
 
 func TestTumixStopsWhenJudgeEscalates(t *testing.T) {
@@ -301,3 +310,205 @@ func mustAgent(a agent.Agent, err error) agent.Agent {
 	}
 	return a
 }
+
+func TestTumixDedupsIdenticalCandidateRequests(t *testing.T) {
+	backend := &countingLLM{answer: "42"}
+	llm, err := dedup.New(backend, RequestScope)
+	if err != nil {
+		t.Fatalf("dedup: %v", err)
+	}
+	candidates := make([]agent.Agent, 0, 3)
+	for _, name := range []string{"A", "B", "C"} {
+		candidates = append(candidates, modelCandidate(name, llm))
+	}
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: candidates,
+		Judge:      noOpJudge(),
+		MaxRounds:  2,
+		MinRounds:  2,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	for _, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+	}
+
+	// One call per round serves all three candidates.
+	if got := backend.calls.Load(); got != 2 {
+		t.Fatalf("backend calls = %d, want 2", got)
+	}
+	if got := llm.Saved(); got != 4 {
+		t.Fatalf("saved calls = %d, want 4", got)
+	}
+}
+
+// initLLMFlow makes one llmagent model call before tests make several at the same time, since adk sets up its tracer
+// on the first call without synchronizing with concurrent ones.
+var initLLMFlow = sync.OnceValue(func() error {
+	a, err := llmagent.New(llmagent.Config{Name: "init", Model: &summaryLLM{summary: "ok"}})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		return err
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: a, SessionService: svc})
+	if err != nil {
+		return err
+	}
+	for _, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+func TestTumixSamplesAreIndependent(t *testing.T) {
+	t.Parallel()
+	if err := initLLMFlow(); err != nil {
+		t.Fatalf("init llm flow: %v", err)
+	}
+
+	backend := &historyLLM{batch: 3}
+	candidate := mustAgent(llmagent.New(llmagent.Config{
+		Name:        "A",
+		Description: "sampled candidate",
+		Model:       backend,
+		Instruction: "Answer the question.",
+	}))
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates:      []agent.Agent{candidate},
+		Judge:           noOpJudge(),
+		MaxRounds:       2,
+		MinRounds:       2,
+		SamplesPerAgent: 3,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	for _, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+	}
+
+	// The samples of a round run at the same time; each sees only its own answer of the previous round.
+	want := [][]string{nil, nil, nil, {"<<<1>>>"}, {"<<<2>>>"}, {"<<<3>>>"}}
+	got := backend.histories
+	slices.SortFunc(got[3:], func(a, b []string) int { return slices.Compare(a, b) })
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("answers in the candidate histories mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// historyLLM answers the n-th request with "<<<n>>>" and records the earlier answers each request holds.
+type historyLLM struct {
+	// batch, when positive, holds every request until batch requests have arrived together, failing it otherwise.
+	batch int
+
+	mu        sync.Mutex
+	histories [][]string
+	release   chan struct{}
+}
+
+func (h *historyLLM) Name() string { return "history" }
+
+func (h *historyLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var answers []string
+		for _, c := range req.Contents {
+			if c.Role == genai.RoleModel {
+				for _, p := range c.Parts {
+					answers = append(answers, p.Text)
+				}
+			}
+		}
+		h.mu.Lock()
+		h.histories = append(h.histories, answers)
+		n := len(h.histories)
+		if h.release == nil {
+			h.release = make(chan struct{})
+		}
+		release := h.release
+		if h.batch > 0 && n%h.batch == 0 {
+			close(release)
+			h.release = nil
+		}
+		h.mu.Unlock()
+		if h.batch > 0 {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+				yield(nil, fmt.Errorf("request %d: %d requests did not arrive together", n, h.batch))
+				return
+			}
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(fmt.Sprintf("<<<%d>>>", n), genai.RoleModel)}, nil)
+	}
+}
+
+// modelCandidate returns a candidate sending the same request to llm in every round. Unlike an llmagent, it neither
+// reads nor appends to the session shared by the parallel candidates, so it only exercises the model.
+func modelCandidate(name string, llm model.LLM) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "identical candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Answer the question.", genai.RoleUser)}}
+				for resp, err := range llm.GenerateContent(ctx, req, false) {
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					ev := session.NewEvent(ctx.InvocationID())
+					ev.LLMResponse = *resp
+					if !yield(ev, nil) {
+						return
+					}
+				}
+			}
+		},
+	}))
+}
+
+// countingLLM answers every request with a fixed text and counts the calls.
+type countingLLM struct {
+	answer string
+	calls  atomic.Int64
+}
+
+func (c *countingLLM) Name() string { return "counting" }
+
+func (c *countingLLM) GenerateContent(_ context.Context, _ *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		c.calls.Add(1)
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(c.answer, genai.RoleModel)}, nil)
+	}
+}
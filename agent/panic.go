@@ -0,0 +1,240 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"runtime/debug"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// ErrPanic is the error a [PanicError] wraps.
+var ErrPanic = errors.New("panic")
+
+// MetadataKeyPanic is the [session.Event] custom metadata key carrying, as a *[PanicError], the panic of the candidate
+// or Judge that authored the event.
+const MetadataKeyPanic = "tumix_panic"
+
+// panicResponseKey is the key of the *[PanicError] in the function response of a tool that panicked, next to its
+// "error".
+const panicResponseKey = "panic"
+
+// panicErrorCode is the [model.LLMResponse.ErrorCode] of the events reporting a panic.
+const panicErrorCode = "PANIC"
+
+// PanicError is a panic recovered from a candidate, the Judge, or a tool of one of them. The run goes on without the
+// agent, or with the tool call failed, instead of the panic taking down the process.
+type PanicError struct {
+	// Agent is the name of the agent that panicked, or whose tool panicked.
+	Agent string `json:"agent"`
+	// Tool is the name of the tool that panicked, empty if the agent itself did.
+	Tool string `json:"tool,omitempty"`
+	// Value is the value passed to panic, formatted.
+	Value string `json:"value"`
+	// Stack is the stack trace of the panicking goroutine. It is not persisted, nor sent to the model.
+	Stack []byte `json:"-"`
+}
+
+func newPanicError(agentName, toolName string, value any) *PanicError {
+	return &PanicError{Agent: agentName, Tool: toolName, Value: fmt.Sprint(value), Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	if e.Tool != "" {
+		return fmt.Sprintf("tool %q of agent %q panicked: %s", e.Tool, e.Agent, e.Value)
+	}
+	return fmt.Sprintf("agent %q panicked: %s", e.Agent, e.Value)
+}
+
+func (e *PanicError) Unwrap() error { return ErrPanic }
+
+// PanicsFromEvent returns the panics event reports: the panic of its author, recorded under [MetadataKeyPanic], and
+// those of the tools whose function responses it carries.
+func PanicsFromEvent(event *session.Event) []*PanicError {
+	if event == nil {
+		return nil
+	}
+	var panics []*PanicError
+	if p, ok := event.CustomMetadata[MetadataKeyPanic].(*PanicError); ok {
+		panics = append(panics, p)
+	}
+	if event.Content != nil {
+		for _, part := range event.Content.Parts {
+			if part == nil || part.FunctionResponse == nil {
+				continue
+			}
+			if p, ok := part.FunctionResponse.Response[panicResponseKey].(*PanicError); ok {
+				panics = append(panics, p)
+			}
+		}
+	}
+	return panics
+}
+
+// panicEvent returns the event reporting p, a panic of the agent running in ctx. It carries no content, so it adds
+// no answer.
+func panicEvent(ctx agent.InvocationContext, p *PanicError) *session.Event {
+	event := session.NewEvent(ctx.InvocationID())
+	event.Author = p.Agent
+	event.Branch = ctx.Branch()
+	event.ErrorCode = panicErrorCode
+	event.ErrorMessage = p.Error()
+	event.CustomMetadata = map[string]any{MetadataKeyPanic: p}
+	return event
+}
+
+// recoverRun returns the events of events, run by the agent named name, ending with a [panicEvent] instead of
+// panicking if the agent panics. Panics of the consumer of the events propagate as usual.
+func recoverRun(ctx agent.InvocationContext, name string, events iter.Seq2[*session.Event, error]) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		var p *PanicError
+		func() {
+			consuming := false
+			defer func() {
+				if r := recover(); r != nil {
+					if consuming {
+						panic(r)
+					}
+					p = newPanicError(name, "", r)
+				}
+			}()
+			for event, err := range events {
+				consuming = true
+				if !yield(event, err) {
+					return
+				}
+				consuming = false
+			}
+		}()
+		if p != nil {
+			yield(panicEvent(ctx, p), nil)
+		}
+	}
+}
+
+// functionTool is the interface of the tools the model calls, which ADK does not export.
+type functionTool interface {
+	tool.Tool
+	Declaration() *genai.FunctionDeclaration
+	Run(ctx tool.Context, args any) (map[string]any, error)
+}
+
+// requestProcessor is the interface of the tools that add themselves to the model requests, which ADK does not
+// export.
+type requestProcessor interface {
+	ProcessRequest(ctx tool.Context, req *model.LLMRequest) error
+}
+
+// recoveringTool is a function tool whose panics fail the call, reported to the model as its error, instead of the
+// agent calling it.
+type recoveringTool struct {
+	functionTool
+}
+
+// recoverTools returns tools with their function tools wrapped so that their panics fail the call; other tools are
+// kept as is.
+func recoverTools(tools []tool.Tool) []tool.Tool {
+	if len(tools) == 0 {
+		return tools
+	}
+	out := make([]tool.Tool, len(tools))
+	for i, t := range tools {
+		out[i] = t
+		ft, ok := t.(functionTool)
+		if _, wrapped := t.(*recoveringTool); !ok || wrapped {
+			continue
+		}
+		if _, ok := t.(requestProcessor); ok {
+			out[i] = &recoveringTool{functionTool: ft}
+		}
+	}
+	return out
+}
+
+// ProcessRequest adds the tool to req, registered as itself rather than the tool it wraps.
+func (t *recoveringTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	if err := t.functionTool.(requestProcessor).ProcessRequest(ctx, req); err != nil {
+		return err
+	}
+	if _, ok := req.Tools[t.Name()]; ok {
+		req.Tools[t.Name()] = t
+	}
+	return nil
+}
+
+// Run runs the tool. A panic is returned as a function response with the message of a [PanicError] as its "error",
+// and the PanicError itself.
+//
+// The function tools of ADK recover the panics of their handler themselves, into an error that also holds the stack;
+// such an error is turned into a PanicError too, which keeps the stack from the model.
+func (t *recoveringTool) Run(ctx tool.Context, args any) (result map[string]any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = panicResponse(newPanicError(ctx.AgentName(), t.Name(), r)), nil
+		}
+	}()
+	result, err = t.functionTool.Run(ctx, args)
+	if err == nil {
+		return result, nil
+	}
+	if msg, ok := strings.CutPrefix(err.Error(), fmt.Sprintf("panic in tool %q: ", t.Name())); ok {
+		value, stack, _ := strings.Cut(msg, "\nstack: ")
+		return panicResponse(&PanicError{Agent: ctx.AgentName(), Tool: t.Name(), Value: value, Stack: []byte(stack)}), nil
+	}
+	return result, err
+}
+
+// panicResponse returns the function response of a tool that panicked with p.
+func panicResponse(p *PanicError) map[string]any {
+	return map[string]any{"error": p.Error(), panicResponseKey: p}
+}
+
+// recoveringToolset is a toolset whose function tools are wrapped by [recoverTools].
+type recoveringToolset struct {
+	tool.Toolset
+}
+
+// recoverToolsets returns toolsets wrapped so that the panics of their function tools fail the call.
+func recoverToolsets(toolsets []tool.Toolset) []tool.Toolset {
+	if len(toolsets) == 0 {
+		return toolsets
+	}
+	out := make([]tool.Toolset, len(toolsets))
+	for i, ts := range toolsets {
+		out[i] = ts
+		if _, wrapped := ts.(*recoveringToolset); !wrapped {
+			out[i] = &recoveringToolset{Toolset: ts}
+		}
+	}
+	return out
+}
+
+func (t *recoveringToolset) Tools(ctx agent.ReadonlyContext) ([]tool.Tool, error) {
+	tools, err := t.Toolset.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return recoverTools(tools), nil
+}
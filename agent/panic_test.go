@@ -0,0 +1,223 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+// panickingAgent panics as soon as it runs.
+func panickingAgent(name string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "panicking agent",
+		Run: func(agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(func(*session.Event, error) bool) {
+				panic("boom")
+			}
+		},
+	}))
+}
+
+func TestTumixPanicIsolation(t *testing.T) {
+	t.Parallel()
+
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: []agent.Agent{scratchCandidate("X"), panickingAgent("P")},
+		Judge:      panickingAgent("judge"),
+		MaxRounds:  1,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	var panicked, answers []string
+	var final *session.Event
+	for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+		for _, p := range PanicsFromEvent(event) {
+			if event.ErrorCode != panicErrorCode || p.Agent != event.Author || p.Value != "boom" {
+				t.Fatalf("panic event = %+v, want an event of %s reporting boom", event, p.Agent)
+			}
+			panicked = append(panicked, p.Agent)
+		}
+		if event.Author == "X" {
+			answers = append(answers, candidateText(event.Content))
+		}
+		final = event
+	}
+
+	slices.Sort(panicked)
+	if diff := cmp.Diff([]string{"P", "judge"}, panicked); diff != "" {
+		t.Fatalf("panicked agents mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"<<<X>>>"}, answers); diff != "" {
+		t.Fatalf("answers of X mismatch (-want +got):\n%s", diff)
+	}
+	if final == nil || final.Author != "tumix" || !strings.Contains(firstTextFromContent(final.Content), "X") {
+		t.Fatalf("final event = %+v, want the answer of X", final)
+	}
+}
+
+// rawTool is a function tool that panics in Run itself, unlike the function tools of ADK.
+type rawTool struct{ name string }
+
+func (t rawTool) Name() string      { return t.name }
+func (rawTool) Description() string { return "panics" }
+func (rawTool) IsLongRunning() bool { return false }
+func (t rawTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{Name: t.name}
+}
+func (rawTool) Run(tool.Context, any) (map[string]any, error) { panic("raw boom") }
+
+func (t rawTool) ProcessRequest(_ tool.Context, req *model.LLMRequest) error {
+	if req.Tools == nil {
+		req.Tools = make(map[string]any)
+	}
+	req.Tools[t.name] = t
+	return nil
+}
+
+// agentToolContext is a [tool.Context] of the agent named name.
+type agentToolContext struct {
+	tool.Context
+	name string
+}
+
+func (c agentToolContext) AgentName() string { return c.name }
+
+func TestRecoverTools(t *testing.T) {
+	t.Parallel()
+
+	handlerPanic, err := functiontool.New(functiontool.Config{Name: "handler", Description: "panics"}, func(tool.Context, struct{}) (map[string]any, error) {
+		panic("handler boom")
+	})
+	if err != nil {
+		t.Fatalf("functiontool.New() error = %v", err)
+	}
+	fine, err := functiontool.New(functiontool.Config{Name: "fine", Description: "answers"}, func(tool.Context, struct{}) (map[string]any, error) {
+		return map[string]any{"answer": 42}, nil
+	})
+	if err != nil {
+		t.Fatalf("functiontool.New() error = %v", err)
+	}
+
+	tools := recoverTools([]tool.Tool{rawTool{name: "raw"}, handlerPanic, fine})
+	if again := recoverTools(tools); again[0] != tools[0] {
+		t.Fatal("recoverTools() wrapped a wrapped tool again")
+	}
+	ctx := agentToolContext{name: "candidate"}
+
+	tests := map[string]struct {
+		tool      tool.Tool
+		want      map[string]any
+		wantPanic *PanicError
+	}{
+		"raw": {
+			tool:      tools[0],
+			wantPanic: &PanicError{Agent: "candidate", Tool: "raw", Value: "raw boom"},
+		},
+		"handler": {
+			tool:      tools[1],
+			wantPanic: &PanicError{Agent: "candidate", Tool: "handler", Value: "handler boom"},
+		},
+		"fine": {
+			tool: tools[2],
+			want: map[string]any{"answer": float64(42)},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &model.LLMRequest{}
+			if err := tt.tool.(requestProcessor).ProcessRequest(ctx, req); err != nil {
+				t.Fatalf("ProcessRequest() error = %v", err)
+			}
+			if req.Tools[tt.tool.Name()] != tt.tool {
+				t.Fatalf("request tool = %T, want the wrapper", req.Tools[tt.tool.Name()])
+			}
+
+			got, err := tt.tool.(functionTool).Run(ctx, map[string]any{})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if tt.wantPanic == nil {
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Fatalf("Run() mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			p, _ := got[panicResponseKey].(*PanicError)
+			if p == nil || len(p.Stack) == 0 {
+				t.Fatalf("Run() = %v, want a panic with its stack", got)
+			}
+			if diff := cmp.Diff(tt.wantPanic, p, cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".Stack" }, cmp.Ignore())); diff != "" {
+				t.Fatalf("panic mismatch (-want +got):\n%s", diff)
+			}
+			if msg := got["error"].(string); msg != tt.wantPanic.Error() {
+				t.Fatalf("error = %q, want %q without the stack", msg, tt.wantPanic.Error())
+			}
+			event := session.NewEvent("inv")
+			event.Content = genai.NewContentFromFunctionResponse(tt.tool.Name(), got, genai.RoleUser)
+			if panics := PanicsFromEvent(event); len(panics) != 1 || panics[0] != p {
+				t.Fatalf("PanicsFromEvent() = %v, want the panic of the tool", panics)
+			}
+		})
+	}
+}
+
+func TestRecoverRunPropagatesConsumerPanics(t *testing.T) {
+	t.Parallel()
+
+	events := func(yield func(*session.Event, error) bool) {
+		yield(session.NewEvent("inv"), nil)
+	}
+	defer func() {
+		if r := recover(); r != "consumer" {
+			t.Fatalf("recover() = %v, want the panic of the consumer", r)
+		}
+	}()
+	for range recoverRun(nil, "agent", events) {
+		panic("consumer")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+)
+
+// RoundFunc is called by a TUMIX Agent at every round boundary with the number of the round that ended and the
+// maximum number of rounds of the run.
+type RoundFunc func(round, maxRounds uint)
+
+type roundFuncKey struct{}
+
+// WithRoundFunc returns a copy of ctx carrying fn, which a TUMIX Agent running under ctx calls whenever a round ends
+// and the next one starts. fn runs on the goroutine of the run and should return quickly.
+func WithRoundFunc(ctx context.Context, fn RoundFunc) context.Context {
+	return context.WithValue(ctx, roundFuncKey{}, fn)
+}
+
+// roundEnded calls the [RoundFunc] attached to ctx, if any, for the end of round.
+func roundEnded(ctx context.Context, round, maxRounds uint) {
+	if fn, ok := ctx.Value(roundFuncKey{}).(RoundFunc); ok && fn != nil {
+		fn(round, maxRounds)
+	}
+}
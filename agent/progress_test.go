@@ -0,0 +1,77 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestWithRoundFunc(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mode Mode
+	}{
+		"tumix":  {mode: ModeTumix},
+		"debate": {mode: ModeDebate},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{staticCandidate("X", "foo"), staticCandidate("Y", "bar")},
+				Judge:      noOpJudge(),
+				MaxRounds:  3,
+				MinRounds:  3,
+				Mode:       tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+
+			var got [][2]uint
+			ctx = WithRoundFunc(ctx, func(round, maxRounds uint) { got = append(got, [2]uint{round, maxRounds}) })
+			for _, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+			}
+
+			if diff := cmp.Diff([][2]uint{{1, 3}, {2, 3}}, got); diff != "" {
+				t.Fatalf("round ends mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	json "encoding/json/v2"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AnswerProtocol defines how agents mark their final answer: the instructions of every candidate, the Judge, and the
+// synthesis agents ask for it, and the vote, the Judge, and the final answer parse it.
+//
+// The agent constructors and [TumixConfig] take the protocol to use; a nil *AnswerProtocol is [DelimitedAnswers].
+//
+// The protocol only covers final answers. The control markers of the Judge, the Verifier, and the guided agents,
+// e.g. "<<<YES>>>", keep their own fixed format.
+type AnswerProtocol struct {
+	// Name identifies the protocol, e.g. in the -answer_protocol flag.
+	Name string
+	// Open and Close delimit the final answer.
+	Open, Close string
+	// Pattern matches a final answer, with its encoded answer as the first submatch.
+	Pattern *regexp.Regexp
+	// Encode encodes an answer for the text between Open and Close. Nil uses the answer as is.
+	Encode func(answer string) string
+	// Decode returns the answer encoded in the text between Open and Close. Nil uses the text as is.
+	Decode func(encoded string) (string, bool)
+}
+
+// DelimitedAnswers is the default protocol, the final answer enclosed in "<<<" and ">>>" as in the TUMIX paper.
+var DelimitedAnswers = &AnswerProtocol{
+	Name:    "delimited",
+	Open:    `<<<`,
+	Close:   `>>>`,
+	Pattern: regexp.MustCompile(`(?s)<<<(.*?)>>>`),
+}
+
+// JSONAnswers is a protocol for models that follow JSON better than delimiters: the final answer is a JSON object
+// such as {"final_answer": "42"}.
+var JSONAnswers = &AnswerProtocol{
+	Name:    "json",
+	Open:    `{"final_answer": `,
+	Close:   `}`,
+	Pattern: regexp.MustCompile(`\{\s*"final_answer"\s*:\s*("(?:[^"\\]|\\.)*"|[^{}"\s][^{}]*?)\s*\}`),
+	Encode: func(answer string) string {
+		b, err := json.Marshal(answer)
+		if err != nil {
+			return `"` + answer + `"`
+		}
+		return string(b)
+	},
+	Decode: func(encoded string) (string, bool) {
+		var v any
+		if err := json.Unmarshal([]byte(encoded), &v); err != nil {
+			return "", false
+		}
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		return fmt.Sprint(v), true
+	},
+}
+
+var answerProtocols = []*AnswerProtocol{DelimitedAnswers, JSONAnswers}
+
+// AnswerProtocolByName returns the built-in protocol named name. An empty name returns [DelimitedAnswers].
+func AnswerProtocolByName(name string) (*AnswerProtocol, bool) {
+	if name == "" {
+		return DelimitedAnswers, true
+	}
+	for _, p := range answerProtocols {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// orDefault returns p, or [DelimitedAnswers] when p is nil.
+func (p *AnswerProtocol) orDefault() *AnswerProtocol {
+	if p == nil {
+		return DelimitedAnswers
+	}
+	return p
+}
+
+// Format returns answer marked as a final answer.
+func (p *AnswerProtocol) Format(answer string) string {
+	p = p.orDefault()
+	if p.Encode != nil {
+		answer = p.Encode(answer)
+	}
+	return p.Open + answer + p.Close
+}
+
+// Example returns answer marked as a final answer, as inline code for instructions, e.g. "`<<<42>>>`".
+func (p *AnswerProtocol) Example(answer string) string {
+	return code(p.Format(answer))
+}
+
+// Enclosure returns how instructions ask for a final answer after "output the final answer", e.g. "inside `<<<` and
+// `>>>`".
+func (p *AnswerProtocol) Enclosure() string {
+	p = p.orDefault()
+	if p.Encode == nil {
+		return "inside " + code(p.Open) + " and " + code(p.Close)
+	}
+	return "in the format " + p.Example("answer content")
+}
+
+// Extract returns the last final answer of text. closed reports whether it was marked completely; an answer after
+// an Open that is never closed is returned with closed false. ok is false when text has no non-empty final answer.
+func (p *AnswerProtocol) Extract(text string) (answer string, closed, ok bool) {
+	p = p.orDefault()
+	var end int
+	if m := p.Pattern.FindAllStringSubmatchIndex(text, -1); len(m) > 0 {
+		last := m[len(m)-1]
+		end = last[1]
+		answer = strings.TrimSpace(p.decode(text[last[2]:last[3]]))
+		closed = true
+	}
+	if i := strings.LastIndex(text, p.Open); i >= end {
+		if rest := strings.TrimSpace(p.decode(strings.TrimSpace(text[i+len(p.Open):]))); rest != "" {
+			return rest, false, true
+		}
+	}
+	if answer == "" {
+		return "", false, false
+	}
+	return answer, closed, true
+}
+
+// Strip removes the markers of a final answer wrapping text, if any.
+func (p *AnswerProtocol) Strip(text string) string {
+	p = p.orDefault()
+	trimmed := strings.TrimSpace(text)
+	trimmed = strings.TrimPrefix(trimmed, p.Open)
+	trimmed = strings.TrimSuffix(trimmed, p.Close)
+	return strings.TrimSpace(p.decode(strings.TrimSpace(trimmed)))
+}
+
+func (p *AnswerProtocol) decode(encoded string) string {
+	if p.Decode == nil {
+		return encoded
+	}
+	if answer, ok := p.Decode(encoded); ok {
+		return answer
+	}
+	return encoded
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestAnswerProtocolExtract(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		protocol   *AnswerProtocol
+		text       string
+		want       string
+		wantClosed bool
+		wantOK     bool
+	}{
+		"delimited": {
+			protocol: DelimitedAnswers, text: "so <<<1>>> then <<< 42 >>>", want: "42", wantClosed: true, wantOK: true,
+		},
+		"delimited unclosed": {
+			protocol: DelimitedAnswers, text: "so <<<1>>> then <<<42", want: "42", wantOK: true,
+		},
+		"delimited empty": {
+			protocol: DelimitedAnswers, text: "<<<>>>",
+		},
+		"json string": {
+			protocol: JSONAnswers, text: `reasoning {"final_answer": "Paris"}`, want: "Paris", wantClosed: true, wantOK: true,
+		},
+		"json number": {
+			protocol: JSONAnswers, text: `{ "final_answer" : 12.5 }`, want: "12.5", wantClosed: true, wantOK: true,
+		},
+		"json escaped": {
+			protocol: JSONAnswers, text: `{"final_answer": "say \"hi\" {twice}"}`, want: `say "hi" {twice}`, wantClosed: true, wantOK: true,
+		},
+		"json unclosed": {
+			protocol: JSONAnswers, text: `{"final_answer": "42"`, want: "42", wantOK: true,
+		},
+		"json missing": {
+			protocol: JSONAnswers, text: "the answer is <<<42>>>",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, closed, ok := tt.protocol.Extract(tt.text)
+			if got != tt.want || closed != tt.wantClosed || ok != tt.wantOK {
+				t.Fatalf("Extract(%q) = %q, %t, %t, want %q, %t, %t", tt.text, got, closed, ok, tt.want, tt.wantClosed, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAnswerProtocolRender(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		protocol      *AnswerProtocol
+		wantFormat    string
+		wantEnclosure string
+	}{
+		"delimited": {
+			protocol:      DelimitedAnswers,
+			wantFormat:    `<<<say "hi">>>`,
+			wantEnclosure: "inside `<<<` and `>>>`",
+		},
+		"json": {
+			protocol:      JSONAnswers,
+			wantFormat:    `{"final_answer": "say \"hi\""}`,
+			wantEnclosure: "in the format `{\"final_answer\": \"answer content\"}`",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			format := tt.protocol.Format(`say "hi"`)
+			if format != tt.wantFormat {
+				t.Fatalf("Format() = %q, want %q", format, tt.wantFormat)
+			}
+			if got, _, _ := tt.protocol.Extract(format); got != `say "hi"` {
+				t.Fatalf("Extract(Format()) = %q, want the answer back", got)
+			}
+			if got := tt.protocol.Strip("  " + format + "\n"); got != `say "hi"` {
+				t.Fatalf("Strip(Format()) = %q, want the answer back", got)
+			}
+			if got := tt.protocol.Enclosure(); got != tt.wantEnclosure {
+				t.Fatalf("Enclosure() = %q, want %q", got, tt.wantEnclosure)
+			}
+		})
+	}
+}
+
+func TestAnswerProtocolByName(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]*AnswerProtocol{"": DelimitedAnswers, "delimited": DelimitedAnswers, "json": JSONAnswers} {
+		if got, ok := AnswerProtocolByName(name); !ok || got != want {
+			t.Fatalf("AnswerProtocolByName(%q) = %v, %t, want %v", name, got, ok, want.Name)
+		}
+	}
+	if _, ok := AnswerProtocolByName("xml"); ok {
+		t.Fatal("AnswerProtocolByName(xml) ok, want unknown")
+	}
+}
+
+func TestJSONAnswerProtocol(t *testing.T) {
+	t.Parallel()
+
+	if !strings.Contains(sharedContext(JSONAnswers), `{"final_answer": "answer content"}`) {
+		t.Fatalf("sharedContext(JSONAnswers) = %q, want the JSON answer format", sharedContext(JSONAnswers))
+	}
+	got, format := extractAnswer(JSONAnswers, `so {"final_answer": "42"}`)
+	if got != "42" || format != formatConforming {
+		t.Fatalf("extractAnswer(JSONAnswers, JSON) = %q, %v, want 42 conforming", got, format)
+	}
+	got, format = extractAnswer(JSONAnswers, "so <<<42>>>")
+	if got != "42" || format != formatRepaired {
+		t.Fatalf("extractAnswer(JSONAnswers, <<<42>>>) = %q, %v, want 42 repaired", got, format)
+	}
+	if !strings.Contains(sharedContext(nil), "inside `<<<` and `>>>`") {
+		t.Fatalf("sharedContext(nil) = %q, want the delimited answer format", sharedContext(nil))
+	}
+}
+
+// TestTumixAnswerProtocol runs loaders with different protocols side by side: each parses the answers in its own.
+func TestTumixAnswerProtocol(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		protocol *AnswerProtocol
+		answers  []string
+	}{
+		"delimited": {
+			answers: []string{"so <<<42>>>", "hence <<<42.0>>>", `{"final_answer": "7"}`},
+		},
+		"json": {
+			protocol: JSONAnswers,
+			answers:  []string{`so {"final_answer": "42"}`, `hence {"final_answer": 42}`, "<<<7>>>"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{
+					staticCandidate("X", tt.answers[0]),
+					staticCandidate("Y", tt.answers[1]),
+					staticCandidate("Z", tt.answers[2]),
+				},
+				Judge:          noOpJudge(),
+				MaxRounds:      1,
+				AnswerProtocol: tt.protocol,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+			for _, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+			}
+
+			res, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+			if err != nil {
+				t.Fatalf("get session: %v", err)
+			}
+			answer, err := res.Session.State().Get(stateKeyAnswer)
+			if err != nil {
+				t.Fatalf("state answer: %v", err)
+			}
+			if answer != "42" {
+				t.Fatalf("answer = %v, want 42", answer)
+			}
+		})
+	}
+}
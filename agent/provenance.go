@@ -0,0 +1,161 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// MetadataKeyCitations is the [session.Event] custom metadata key carrying the aggregated []Citation on the final TUMIX event.
+const MetadataKeyCitations = "tumix_citations"
+
+// Custom metadata keys populated by the gollm xAI adapter.
+const (
+	xaiCitationsKey       = "xai_citations"
+	xaiServerSideToolsKey = "xai_server_side_tools"
+)
+
+// Citation records a source or tool invocation backing a candidate answer.
+type Citation struct {
+	// Agent is the candidate agent that produced the citation.
+	Agent string `json:"agent"`
+	// URI is the cited source location, if any.
+	URI string `json:"uri,omitzero"`
+	// Title is the human readable title of the source, if known.
+	Title string `json:"title,omitzero"`
+	// Tool is the name of the invoked tool when the provenance is a tool call.
+	Tool string `json:"tool,omitzero"`
+}
+
+// CitationsFromEvent returns the aggregated citations attached to the final TUMIX event.
+func CitationsFromEvent(event *session.Event) []Citation {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	cites, _ := event.CustomMetadata[MetadataKeyCitations].([]Citation)
+	return cites
+}
+
+// eventCitations extracts citation and tool-invocation provenance from a candidate event.
+//
+// It understands Gemini citation and grounding metadata, the xAI adapter citations and server-side tools, function
+// calls, and code run by the Gemini built-in code execution tool.
+func eventCitations(event *session.Event) []Citation {
+	if event == nil {
+		return nil
+	}
+
+	var out []Citation
+	if cm := event.CitationMetadata; cm != nil {
+		for _, c := range cm.Citations {
+			if c == nil || c.URI == "" {
+				continue
+			}
+			out = append(out, Citation{Agent: event.Author, URI: c.URI, Title: c.Title})
+		}
+	}
+	if gm := event.GroundingMetadata; gm != nil {
+		for _, chunk := range gm.GroundingChunks {
+			if chunk == nil || chunk.Web == nil || chunk.Web.URI == "" {
+				continue
+			}
+			out = append(out, Citation{Agent: event.Author, URI: chunk.Web.URI, Title: chunk.Web.Title})
+		}
+		if len(gm.WebSearchQueries) > 0 {
+			out = append(out, Citation{Agent: event.Author, Tool: nativeSearchToolName})
+		}
+	}
+	switch uris := event.CustomMetadata[xaiCitationsKey].(type) {
+	case []string:
+		for _, uri := range uris {
+			if uri != "" {
+				out = append(out, Citation{Agent: event.Author, URI: uri})
+			}
+		}
+	case []any:
+		for _, v := range uris {
+			if uri, ok := v.(string); ok && uri != "" {
+				out = append(out, Citation{Agent: event.Author, URI: uri})
+			}
+		}
+	}
+	if tools, ok := event.CustomMetadata[xaiServerSideToolsKey].([]string); ok {
+		for _, name := range tools {
+			if name != "" {
+				out = append(out, Citation{Agent: event.Author, Tool: name})
+			}
+		}
+	}
+	if event.Content != nil {
+		for _, p := range event.Content.Parts {
+			if p == nil || p.FunctionCall == nil || p.FunctionCall.Name == "" {
+				continue
+			}
+			out = append(out, Citation{Agent: event.Author, Tool: p.FunctionCall.Name})
+		}
+		if slices.ContainsFunc(event.Content.Parts, func(p *genai.Part) bool { return p != nil && p.ExecutableCode != nil }) {
+			out = append(out, Citation{Agent: event.Author, Tool: nativeCodeToolName})
+		}
+	}
+
+	return out
+}
+
+// mergeCitations appends the citations of answers to base, dropping duplicates.
+func mergeCitations(base []Citation, answers []candidateAnswer) []Citation {
+	seen := make(map[Citation]struct{}, len(base))
+	for _, c := range base {
+		seen[c] = struct{}{}
+	}
+	for _, a := range answers {
+		for _, c := range a.Citations {
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+			base = append(base, c)
+		}
+	}
+	return base
+}
+
+// joinCitations renders citations for prompt injection.
+func joinCitations(cites []Citation) string {
+	if len(cites) == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	for i, c := range cites {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		switch {
+		case c.URI != "" && c.Title != "":
+			sb.WriteString(fmt.Sprintf("- %s: %s (%s)", c.Agent, c.Title, c.URI))
+		case c.URI != "":
+			sb.WriteString(fmt.Sprintf("- %s: %s", c.Agent, c.URI))
+		default:
+			sb.WriteString(fmt.Sprintf("- %s: tool %s", c.Agent, c.Tool))
+		}
+	}
+	return sb.String()
+}
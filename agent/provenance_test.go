@@ -0,0 +1,159 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestEventCitations(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		event *session.Event
+		want  []Citation
+	}{
+		"nil event": {
+			event: nil,
+			want:  nil,
+		},
+		"gemini citation and grounding metadata": {
+			event: &session.Event{
+				Author: "search",
+				LLMResponse: model.LLMResponse{
+					CitationMetadata: &genai.CitationMetadata{
+						Citations: []*genai.Citation{
+							nil,
+							{URI: "https://a.example", Title: "A"},
+							{Title: "no uri"},
+						},
+					},
+					GroundingMetadata: &genai.GroundingMetadata{
+						GroundingChunks: []*genai.GroundingChunk{
+							{Web: &genai.GroundingChunkWeb{URI: "https://b.example", Title: "B"}},
+							{},
+						},
+					},
+				},
+			},
+			want: []Citation{
+				{Agent: "search", URI: "https://a.example", Title: "A"},
+				{Agent: "search", URI: "https://b.example", Title: "B"},
+			},
+		},
+		"gemini built-in tools": {
+			event: &session.Event{
+				Author: "code",
+				LLMResponse: model.LLMResponse{
+					Content: &genai.Content{
+						Parts: []*genai.Part{
+							{ExecutableCode: &genai.ExecutableCode{Code: "print(1)", Language: genai.LanguagePython}},
+							{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "1"}},
+							{ExecutableCode: &genai.ExecutableCode{Code: "print(2)", Language: genai.LanguagePython}},
+						},
+					},
+					GroundingMetadata: &genai.GroundingMetadata{
+						WebSearchQueries: []string{"tumix"},
+						GroundingChunks: []*genai.GroundingChunk{
+							{Web: &genai.GroundingChunkWeb{URI: "https://d.example"}},
+						},
+					},
+				},
+			},
+			want: []Citation{
+				{Agent: "code", URI: "https://d.example"},
+				{Agent: "code", Tool: "google_search"},
+				{Agent: "code", Tool: "code_execution"},
+			},
+		},
+		"xai citations and tool calls": {
+			event: &session.Event{
+				Author: "code",
+				LLMResponse: model.LLMResponse{
+					Content: &genai.Content{
+						Parts: []*genai.Part{
+							{FunctionCall: &genai.FunctionCall{Name: "web_search"}},
+							{Text: "answer"},
+						},
+					},
+					CustomMetadata: map[string]any{
+						xaiCitationsKey:       []any{"https://c.example", 1, ""},
+						xaiServerSideToolsKey: []string{"code_execution"},
+					},
+				},
+			},
+			want: []Citation{
+				{Agent: "code", URI: "https://c.example"},
+				{Agent: "code", Tool: "code_execution"},
+				{Agent: "code", Tool: "web_search"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, eventCitations(tt.event)); diff != "" {
+				t.Fatalf("eventCitations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeCitationsDeduplicates(t *testing.T) {
+	t.Parallel()
+
+	base := []Citation{{Agent: "a", URI: "https://a.example"}}
+	answers := []candidateAnswer{
+		{Agent: "a", Citations: []Citation{{Agent: "a", URI: "https://a.example"}, {Agent: "a", Tool: "search"}}},
+		{Agent: "b", Citations: []Citation{{Agent: "b", URI: "https://a.example"}}},
+	}
+
+	want := []Citation{
+		{Agent: "a", URI: "https://a.example"},
+		{Agent: "a", Tool: "search"},
+		{Agent: "b", URI: "https://a.example"},
+	}
+	got := mergeCitations(base, answers)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mergeCitations mismatch (-want +got):\n%s", diff)
+	}
+
+	wantJoined := "- a: https://a.example\n- a: tool search\n- b: https://a.example"
+	if diff := cmp.Diff(wantJoined, joinCitations(got)); diff != "" {
+		t.Fatalf("joinCitations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCitationsFromEvent(t *testing.T) {
+	t.Parallel()
+
+	cites := []Citation{{Agent: "a", URI: "https://a.example"}}
+	ev := &session.Event{LLMResponse: model.LLMResponse{CustomMetadata: map[string]any{MetadataKeyCitations: cites}}}
+	if diff := cmp.Diff(cites, CitationsFromEvent(ev)); diff != "" {
+		t.Fatalf("CitationsFromEvent mismatch (-want +got):\n%s", diff)
+	}
+	if got := CitationsFromEvent(&session.Event{}); got != nil {
+		t.Fatalf("CitationsFromEvent(empty) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// MetadataKeyJudgeRationale is the [session.Event] custom metadata key carrying the [JudgeRationale] of the last round
+// the Judge ran on the final TUMIX event.
+const MetadataKeyJudgeRationale = "tumix_judge_rationale"
+
+// stateKeyJudgeRationalePrefix prefixes the state key of the Judge's analysis of each round, e.g.
+// "judge_rationale_round_2".
+const stateKeyJudgeRationalePrefix = "judge_rationale_round_"
+
+// JudgeRationale is the comparative analysis the Judge wrote in one round.
+type JudgeRationale struct {
+	Round uint   `json:"round"`
+	Text  string `json:"text"`
+}
+
+// JudgeRationaleFromEvent returns the Judge's analysis of the last round it ran attached to the final TUMIX event, or
+// nil when the Judge wrote none.
+func JudgeRationaleFromEvent(event *session.Event) *JudgeRationale {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	rationale, _ := event.CustomMetadata[MetadataKeyJudgeRationale].(*JudgeRationale)
+	return rationale
+}
+
+// judgeRationaleKey returns the state key of the Judge's analysis of round.
+func judgeRationaleKey(round uint) string {
+	return fmt.Sprintf("%s%d", stateKeyJudgeRationalePrefix, round)
+}
+
+// judgeTranscript collects the text the Judge writes in one run, leaving out its thoughts and streamed deltas.
+type judgeTranscript struct {
+	sb strings.Builder
+}
+
+func (j *judgeTranscript) add(event *session.Event) {
+	if event == nil || event.Partial || event.Content == nil {
+		return
+	}
+	for _, part := range event.Content.Parts {
+		if part == nil || part.Thought || strings.TrimSpace(part.Text) == "" {
+			continue
+		}
+		if j.sb.Len() > 0 {
+			j.sb.WriteString("\n")
+		}
+		j.sb.WriteString(strings.TrimSpace(part.Text))
+	}
+}
+
+// save stores the transcript as the Judge's analysis of the current round, if the Judge wrote any.
+func (j *judgeTranscript) save(ctx agent.InvocationContext) error {
+	if j.sb.Len() == 0 {
+		return nil
+	}
+	round, err := getState(ctx, stateKeyRound)
+	if err != nil {
+		return err
+	}
+	r, _ := round.(uint)
+	return setState(ctx, judgeRationaleKey(r), j.sb.String())
+}
+
+// clearJudgeRationales drops the Judge's analyses of a previous run in the session from the state.
+func clearJudgeRationales(ctx agent.InvocationContext, maxRounds uint) error {
+	for r := uint(1); r <= maxRounds; r++ {
+		if err := setState(ctx, judgeRationaleKey(r), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateJudgeRationales returns the Judge's analyses of every round it ran in the session state, in round order.
+func stateJudgeRationales(ctx agent.InvocationContext) ([]JudgeRationale, error) {
+	round, err := ctx.Session().State().Get(stateKeyRound)
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("state %s: %w", stateKeyRound, err)
+	}
+	last, _ := round.(uint)
+	var rationales []JudgeRationale
+	for r := uint(1); r <= last; r++ {
+		val, err := ctx.Session().State().Get(judgeRationaleKey(r))
+		if errors.Is(err, session.ErrStateKeyNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("state %s: %w", judgeRationaleKey(r), err)
+		}
+		if text, _ := val.(string); text != "" {
+			rationales = append(rationales, JudgeRationale{Round: r, Text: text})
+		}
+	}
+	return rationales, nil
+}
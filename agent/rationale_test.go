@@ -0,0 +1,127 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// analysisJudge writes an analysis naming the round, streamed as a partial delta with a thought before it, and
+// never stops the run.
+func analysisJudge() agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        "judge",
+		Description: "analysis judge",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				round, _ := ctx.Session().State().Get(stateKeyRound)
+				text := fmt.Sprintf("Round %v: foo is better supported than bar. <<<NO>>>", round)
+
+				partial := session.NewEvent(ctx.InvocationID())
+				partial.Author = "judge"
+				partial.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(text[:8], genai.RoleModel), Partial: true}
+				if !yield(partial, nil) {
+					return
+				}
+
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.Author = "judge"
+				content := genai.NewContentFromText(text, genai.RoleModel)
+				content.Parts = append([]*genai.Part{{Text: "secret thought", Thought: true}}, content.Parts...)
+				ev.LLMResponse = model.LLMResponse{Content: content}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+func TestTumixJudgeRationale(t *testing.T) {
+	t.Parallel()
+
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		// An even split never reaches a consensus, so the Judge runs in every round.
+		Candidates: []agent.Agent{staticCandidate("X", "foo"), staticCandidate("Z", "bar")},
+		Judge:      analysisJudge(),
+		MaxRounds:  2,
+		MinRounds:  1,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	var final *session.Event
+	for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+		if event.Author == "tumix" {
+			final = event
+		}
+	}
+	if final == nil {
+		t.Fatal("no final event")
+	}
+
+	want := &JudgeRationale{Round: 2, Text: "Round 2: foo is better supported than bar. <<<NO>>>"}
+	if diff := cmp.Diff(want, JudgeRationaleFromEvent(final)); diff != "" {
+		t.Fatalf("JudgeRationaleFromEvent() mismatch (-want +got):\n%s", diff)
+	}
+
+	res, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	for round := uint(1); round <= 2; round++ {
+		got, err := res.Session.State().Get(judgeRationaleKey(round))
+		if err != nil {
+			t.Fatalf("state %s: %v", judgeRationaleKey(round), err)
+		}
+		if want := fmt.Sprintf("Round %d: foo is better supported than bar. <<<NO>>>", round); got != want {
+			t.Fatalf("state %s = %q, want %q", judgeRationaleKey(round), got, want)
+		}
+	}
+}
+
+func TestJudgeRationaleFromEventWithoutJudge(t *testing.T) {
+	t.Parallel()
+
+	if got := JudgeRationaleFromEvent(session.NewEvent("inv")); got != nil {
+		t.Fatalf("JudgeRationaleFromEvent() = %+v, want nil", got)
+	}
+	if got := JudgeRationaleFromEvent(nil); got != nil {
+		t.Fatalf("JudgeRationaleFromEvent(nil) = %+v, want nil", got)
+	}
+}
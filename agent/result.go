@@ -0,0 +1,131 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// MetadataKeyRoundStats is the [session.Event] custom metadata key carrying the []RoundStats of every round on the
+// final TUMIX event.
+const MetadataKeyRoundStats = "tumix_round_stats"
+
+const stateKeyRoundStats = "round_stats"
+
+// RoundStats are the vote statistics of the candidate answers of one round.
+type RoundStats struct {
+	Round uint `json:"round"`
+	// Unique is the number of distinct answers.
+	Unique int `json:"unique_answers"`
+	// TopAnswer is the most frequent answer and VoteMargin its share of the answers.
+	TopAnswer  string  `json:"top_answer"`
+	VoteMargin float64 `json:"vote_margin"`
+	// Coverage is the share of the candidate samples that answered.
+	Coverage float64 `json:"coverage"`
+	// Entropy is the Shannon entropy, in bits, of the answer distribution.
+	Entropy float64 `json:"answer_entropy"`
+	// Changes record how the answer of every candidate sample evolved from the previous round; nil in the first round.
+	Changes []AnswerChange `json:"answer_changes,omitzero"`
+	// Conflicts are the specific disagreements detected between the answers, which the shared context of the next
+	// round asks the candidates to resolve.
+	Conflicts []Conflict `json:"conflicts,omitzero"`
+	// SharedAnswerTokens is the size, in tokens, of the previous answers in each candidate prompt of the round when
+	// an [AnswerSummary] is configured, and SavedAnswerTokens the tokens its summary saved over the verbatim answers,
+	// summed over the candidate prompts; both are zero otherwise.
+	SharedAnswerTokens int `json:"shared_answer_tokens,omitzero"`
+	SavedAnswerTokens  int `json:"saved_answer_tokens,omitzero"`
+}
+
+// RoundStatsFromEvent returns the vote statistics of every round attached to the final TUMIX event.
+func RoundStatsFromEvent(event *session.Event) []RoundStats {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	stats, _ := event.CustomMetadata[MetadataKeyRoundStats].([]RoundStats)
+	return stats
+}
+
+// FinalAnswerFromEvent returns the answer and confidence recorded by the final TUMIX event, and false for any other
+// event. The confidence is zero when the answer has none.
+func FinalAnswerFromEvent(event *session.Event) (answer string, confidence float64, ok bool) {
+	if event == nil || event.Actions.StateDelta == nil {
+		return "", 0, false
+	}
+	val, ok := event.Actions.StateDelta[stateKeyAnswer]
+	if !ok || val == nil {
+		return "", 0, false
+	}
+	confidence, _ = event.Actions.StateDelta[stateKeyConfidence].(float64)
+	return fmt.Sprint(val), confidence, true
+}
+
+// appendRoundStats appends the statistics of the current round to the round statistics in the session state.
+func appendRoundStats(ctx agent.InvocationContext, stats roundStats) error {
+	round, err := getState(ctx, stateKeyRound)
+	if err != nil {
+		return err
+	}
+	prev, err := ctx.Session().State().Get(stateKeyRoundStats)
+	if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+		return fmt.Errorf("state %s: %w", stateKeyRoundStats, err)
+	}
+	all, err := roundStatsList(prev)
+	if err != nil {
+		return err
+	}
+	r, _ := round.(uint)
+	all = append(all, RoundStats{
+		Round:      r,
+		Unique:     stats.unique,
+		TopAnswer:  stats.topAnswer,
+		VoteMargin: stats.voteMargin,
+		Coverage:   stats.coverage,
+		Entropy:    stats.answerEntropy,
+		Changes:    stats.changes,
+		Conflicts:  stats.conflicts,
+
+		SharedAnswerTokens: stats.sharedTokens,
+		SavedAnswerTokens:  stats.savedTokens,
+	})
+	return setState(ctx, stateKeyRoundStats, all)
+}
+
+// roundStatsList decodes the round statistics stored in the session state, which are []RoundStats when set in this
+// process and generic JSON values when the state was reloaded from a persistent session store.
+func roundStatsList(val any) ([]RoundStats, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case []RoundStats:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal round stats: %w", err)
+		}
+		var stats []RoundStats
+		if err := json.Unmarshal(b, &stats); err != nil {
+			return nil, fmt.Errorf("unmarshal round stats: %w", err)
+		}
+		return stats, nil
+	}
+}
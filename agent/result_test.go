@@ -0,0 +1,98 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+)
+
+func TestRoundStatsList(t *testing.T) {
+	t.Parallel()
+
+	want := []RoundStats{{Round: 2, Unique: 1, TopAnswer: "42", VoteMargin: 1, Coverage: 0.5}}
+	tests := map[string]struct {
+		val     any
+		want    []RoundStats
+		wantErr bool
+	}{
+		"nil":   {val: nil},
+		"typed": {val: want, want: want},
+		"reloaded json": {
+			val:  []any{map[string]any{"round": 2, "unique_answers": 1, "top_answer": "42", "vote_margin": 1, "coverage": 0.5, "answer_entropy": 0}},
+			want: want,
+		},
+		"invalid": {val: "not stats", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := roundStatsList(tt.val)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("roundStatsList() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("roundStatsList() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFinalAnswerFromEvent(t *testing.T) {
+	t.Parallel()
+
+	type final struct {
+		Answer     string
+		Confidence float64
+		OK         bool
+	}
+	tests := map[string]struct {
+		delta map[string]any
+		want  final
+	}{
+		"no state delta": {},
+		"other event": {
+			delta: map[string]any{stateKeyRound: uint(1)},
+		},
+		"answer and confidence": {
+			delta: map[string]any{stateKeyAnswer: "42", stateKeyConfidence: 0.8},
+			want:  final{Answer: "42", Confidence: 0.8, OK: true},
+		},
+		"answer without confidence": {
+			delta: map[string]any{stateKeyAnswer: "42"},
+			want:  final{Answer: "42", OK: true},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			event := session.NewEvent("inv")
+			event.Actions.StateDelta = tt.delta
+			var got final
+			got.Answer, got.Confidence, got.OK = FinalAnswerFromEvent(event)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("FinalAnswerFromEvent() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
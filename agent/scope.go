@@ -0,0 +1,313 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// candidateStatePrefix prefixes the session state keys the candidates write during a round (see [CandidateStateKey]).
+const candidateStatePrefix = "candidate_state:"
+
+// CandidateStateKey returns the session state key holding the value the candidate agent wrote to key during a round.
+//
+// Every candidate reads and writes the session state through its own namespace while the candidates of a round run
+// in parallel, so the tools of one cannot clobber the state of another. Its writes are recorded under this key, and
+// merged back under key itself according to the [StateMergePolicy] of the run.
+func CandidateStateKey(agent, key string) string {
+	return candidateStatePrefix + agent + ":" + key
+}
+
+// StateMergePolicy decides which state writes of the candidates of a round are merged back into the session state
+// shared by the run.
+type StateMergePolicy string
+
+const (
+	// StateMergeUnique merges the keys written by one candidate only, or to equal values by all that wrote them.
+	// Conflicting writes are kept under their [CandidateStateKey] only. It is the default.
+	StateMergeUnique StateMergePolicy = "unique"
+	// StateMergeLast merges every key, the value of the last candidate in the order of the candidates winning.
+	StateMergeLast StateMergePolicy = "last"
+	// StateMergeNone merges nothing: the writes are kept under their [CandidateStateKey] only.
+	StateMergeNone StateMergePolicy = "none"
+)
+
+// scopedState is the session state as one candidate sees it during a round: its own writes over the shared state,
+// which it never writes to.
+type scopedState struct {
+	shared session.State
+
+	mu      sync.Mutex
+	written map[string]any
+}
+
+var _ session.State = (*scopedState)(nil)
+
+func (s *scopedState) Get(key string) (any, error) {
+	s.mu.Lock()
+	val, ok := s.written[key]
+	s.mu.Unlock()
+	if ok {
+		return val, nil
+	}
+	return s.shared.Get(key)
+}
+
+func (s *scopedState) Set(key string, val any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.written == nil {
+		s.written = make(map[string]any)
+	}
+	s.written[key] = val
+	return nil
+}
+
+func (s *scopedState) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		s.mu.Lock()
+		written := maps.Clone(s.written)
+		s.mu.Unlock()
+		for key, val := range s.shared.All() {
+			if _, ok := written[key]; ok {
+				continue
+			}
+			if !yield(key, val) {
+				return
+			}
+		}
+		for key, val := range written {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot returns the writes of the candidate, without its temporary ones, which stay private.
+func (s *scopedState) snapshot() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]any, len(s.written))
+	for key, val := range s.written {
+		if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+			out[key] = val
+		}
+	}
+	return out
+}
+
+// scopedSession is the session of one candidate, with its [scopedState].
+type scopedSession struct {
+	session.Session
+	state *scopedState
+}
+
+func (s *scopedSession) State() session.State { return s.state }
+
+// scopedContext is the invocation context of one candidate run by [stateScopes.run], or of one sample of a round.
+type scopedContext struct {
+	agent.InvocationContext
+	session *scopedSession
+	branch  string
+}
+
+func (c *scopedContext) Session() session.Session { return c.session }
+func (c *scopedContext) Branch() string           { return c.branch }
+
+// joinBranch returns the branch named name nested in parent, which is empty at the root.
+func joinBranch(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// stateScopes runs the candidates of a round, each in its own state namespace, and merges their writes back.
+type stateScopes struct {
+	agents []string
+	states map[string]*scopedState
+}
+
+func newStateScopes() *stateScopes {
+	return &stateScopes{states: make(map[string]*scopedState)}
+}
+
+// state returns the scoped state of the candidate named name, reused when it runs again in the round.
+func (s *stateScopes) state(ctx agent.InvocationContext, name string) *scopedState {
+	st, ok := s.states[name]
+	if !ok {
+		st = &scopedState{shared: ctx.Session().State()}
+		s.states[name] = st
+		s.agents = append(s.agents, name)
+	}
+	return st
+}
+
+// add adds the writes of the candidates run by other to s, over their writes in s.
+func (s *stateScopes) add(ctx agent.InvocationContext, other *stateScopes) {
+	for _, name := range other.agents {
+		st := s.state(ctx, name)
+		src := other.states[name]
+		src.mu.Lock()
+		written := maps.Clone(src.written)
+		src.mu.Unlock()
+		for key, val := range written {
+			_ = st.Set(key, val)
+		}
+	}
+}
+
+type scopedResult struct {
+	event *session.Event
+	err   error
+}
+
+// run runs subs, sub-agents of parent, in parallel like parent itself would, each with a scoped session state. The
+// state deltas of their events are moved under the [CandidateStateKey] of their author, so no candidate overwrites
+// the shared state. The first error cancels the other sub-agents; a panic does not (see [PanicError]).
+func (s *stateScopes) run(ctx agent.InvocationContext, parent agent.Agent, subs ...agent.Agent) iter.Seq2[*session.Event, error] {
+	states := make([]*scopedState, len(subs))
+	for i, sub := range subs {
+		states[i] = s.state(ctx, sub.Name())
+	}
+
+	return func(yield func(*session.Event, error) bool) {
+		gctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		group := &deadlineContext{InvocationContext: ctx, ctx: gctx}
+
+		var (
+			wg      sync.WaitGroup
+			results = make(chan scopedResult)
+			done    = make(chan struct{})
+		)
+		for i, sub := range subs {
+			sctx := &scopedContext{
+				InvocationContext: group,
+				session:           &scopedSession{Session: ctx.Session(), state: states[i]},
+				branch:            joinBranch(ctx.Branch(), parent.Name()+"."+sub.Name()),
+			}
+			wg.Go(func() {
+				// A panicking candidate ends with an event reporting the panic, leaving the others running.
+				for event, err := range recoverRun(sctx, sub.Name(), sub.Run(sctx)) {
+					if err == nil && event != nil {
+						scopeStateDelta(event, sub.Name(), states[i])
+					}
+					select {
+					case <-done:
+						return
+					case results <- scopedResult{event: event, err: err}:
+					}
+					if err != nil {
+						cancel(fmt.Errorf("sub-agent %q: %w", sub.Name(), err))
+						return
+					}
+				}
+			})
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		defer close(done)
+		for res := range results {
+			if !yield(res.event, res.err) {
+				return
+			}
+		}
+	}
+}
+
+// scopeStateDelta records the state delta of event, authored by the candidate named name, in its scoped state and
+// moves it under its [CandidateStateKey]s.
+func scopeStateDelta(event *session.Event, name string, state *scopedState) {
+	if len(event.Actions.StateDelta) == 0 {
+		return
+	}
+	scoped := make(map[string]any, len(event.Actions.StateDelta))
+	for key, val := range event.Actions.StateDelta {
+		_ = state.Set(key, val)
+		if strings.HasPrefix(key, session.KeyPrefixTemp) {
+			continue
+		}
+		scoped[CandidateStateKey(name, key)] = val
+	}
+	event.Actions.StateDelta = scoped
+}
+
+// merge merges the writes of the candidates into the session state according to policy, in the order of the
+// candidates, and yields them as the state delta of a TUMIX event. It returns false when the run must stop.
+func (s *stateScopes) merge(ctx agent.InvocationContext, policy StateMergePolicy, order []agent.Agent, yield func(*session.Event, error) bool) bool {
+	if policy == StateMergeNone {
+		return true
+	}
+	names := slices.Clone(s.agents)
+	rank := make(map[string]int, len(order))
+	for i, a := range order {
+		rank[a.Name()] = i
+	}
+	slices.SortStableFunc(names, func(a, b string) int { return rank[a] - rank[b] })
+
+	type write struct {
+		val       any
+		conflicts bool
+	}
+	writes := make(map[string]*write)
+	for _, name := range names {
+		for key, val := range s.states[name].snapshot() {
+			w, ok := writes[key]
+			switch {
+			case !ok:
+				writes[key] = &write{val: val}
+			case policy == StateMergeLast:
+				w.val = val
+			case !reflect.DeepEqual(w.val, val):
+				w.conflicts = true
+			}
+		}
+	}
+
+	delta := make(map[string]any)
+	for _, key := range slices.Sorted(maps.Keys(writes)) {
+		if w := writes[key]; !w.conflicts {
+			if err := setState(ctx, key, w.val); err != nil {
+				yield(nil, err)
+				return false
+			}
+			delta[key] = w.val
+		}
+	}
+	if len(delta) == 0 {
+		return true
+	}
+	event := session.NewEvent(ctx.InvocationID())
+	event.Author = "tumix"
+	event.Actions.StateDelta = delta
+	return yield(event, nil)
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"iter"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// scratchCandidate writes its name to the "scratch" state key, like a tool would, reads it back, and answers with
+// what it read. It also records the key own, with the same value for every candidate, in the state delta of its event.
+func scratchCandidate(name string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "scratch candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				if err := ctx.Session().State().Set("scratch", name); err != nil {
+					yield(nil, err)
+					return
+				}
+				val, err := ctx.Session().State().Get("scratch")
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.Author = name
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("<<<"+val.(string)+">>>", genai.RoleModel)}
+				ev.Actions.StateDelta = map[string]any{"scratch": name, "shared": "same", "only_" + name: true}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+func TestTumixStateIsolation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy     StateMergePolicy
+		wantMerged map[string]any
+	}{
+		"unique": {
+			wantMerged: map[string]any{"shared": "same", "only_X": true, "only_Y": true},
+		},
+		"last": {
+			policy:     StateMergeLast,
+			wantMerged: map[string]any{"scratch": "Y", "shared": "same", "only_X": true, "only_Y": true},
+		},
+		"none": {
+			policy:     StateMergeNone,
+			wantMerged: map[string]any{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{scratchCandidate("X"), scratchCandidate("Y")},
+				Judge:      noOpJudge(),
+				MaxRounds:  1,
+				StateMerge: tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+
+			answers := make(map[string]string)
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == "X" || event.Author == "Y" {
+					answers[event.Author] = candidateText(event.Content)
+				}
+			}
+			// Every candidate reads its own write back, whatever the other wrote.
+			if want := map[string]string{"X": "<<<X>>>", "Y": "<<<Y>>>"}; !cmp.Equal(want, answers) {
+				t.Fatalf("answers = %v, want %v", answers, want)
+			}
+
+			resp, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+			if err != nil {
+				t.Fatalf("get session: %v", err)
+			}
+			state := resp.Session.State()
+			for _, cand := range []string{"X", "Y"} {
+				if got, err := state.Get(CandidateStateKey(cand, "scratch")); err != nil || got != cand {
+					t.Fatalf("state %s = %v, %v, want %s", CandidateStateKey(cand, "scratch"), got, err, cand)
+				}
+			}
+			for _, key := range []string{"scratch", "shared", "only_X", "only_Y"} {
+				got, err := state.Get(key)
+				want, merged := tt.wantMerged[key]
+				if !merged {
+					if !errors.Is(err, session.ErrStateKeyNotExist) {
+						t.Fatalf("state %s = %v, %v, want not merged", key, got, err)
+					}
+					continue
+				}
+				if err != nil || got != want {
+					t.Fatalf("state %s = %v, %v, want %v", key, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTumixStateMergeUnknown(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: []agent.Agent{scratchCandidate("X")},
+		Judge:      noOpJudge(),
+		StateMerge: "first",
+	})
+	if err == nil {
+		t.Fatal("NewTumixAgentWithConfig() with an unknown state merge policy succeeded, want error")
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// MetadataKeyCandidateScores is the [session.Event] custom metadata key carrying the Judge's []CandidateScore of the
+// last judged round on the final TUMIX event.
+const MetadataKeyCandidateScores = "tumix_candidate_scores"
+
+// unscoredWeight is the vote weight of a candidate the Judge did not score.
+const unscoredWeight = 0.5
+
+// CandidateScore is the Judge's rubric score of one candidate answer.
+type CandidateScore struct {
+	// Agent is the candidate label as listed to the Judge, e.g. "CoT" or "CoT#2" with self-consistency sampling.
+	Agent string `json:"agent"`
+	// Correctness is the likelihood, 0-1, that the candidate's final answer is correct.
+	Correctness float64 `json:"correctness"`
+	// Reasoning is the quality, 0-1, of the candidate's reasoning.
+	Reasoning float64 `json:"reasoning"`
+}
+
+// Weight is the vote weight of s, its correctness likelihood discounted by up to half for weak reasoning.
+func (s CandidateScore) Weight() float64 {
+	return s.Correctness * (0.5 + 0.5*s.Reasoning)
+}
+
+func (s CandidateScore) validate() error {
+	switch {
+	case s.Agent == "":
+		return fmt.Errorf("score agent is required")
+	case s.Correctness < 0 || s.Correctness > 1:
+		return fmt.Errorf("correctness of %s must be between 0 and 1", s.Agent)
+	case s.Reasoning < 0 || s.Reasoning > 1:
+		return fmt.Errorf("reasoning of %s must be between 0 and 1", s.Agent)
+	}
+	return nil
+}
+
+// CandidateScoresFromEvent returns the Judge's candidate scores attached to the final TUMIX event.
+func CandidateScoresFromEvent(event *session.Event) []CandidateScore {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	scores, _ := event.CustomMetadata[MetadataKeyCandidateScores].([]CandidateScore)
+	return scores
+}
+
+// candidateScores decodes the candidate scores stored in the session state, which are []CandidateScore when set in
+// this process and generic JSON values when the state was reloaded from a persistent session store.
+func candidateScores(val any) ([]CandidateScore, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case []CandidateScore:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encode candidate scores: %w", err)
+		}
+		var scores []CandidateScore
+		if err := json.Unmarshal(b, &scores); err != nil {
+			return nil, fmt.Errorf("decode candidate scores: %w", err)
+		}
+		return scores, nil
+	}
+}
+
+// stateCandidateScores returns the candidate scores of the current round from the session state.
+func stateCandidateScores(ctx agent.InvocationContext) ([]CandidateScore, error) {
+	val, err := getState(ctx, stateKeyCandidateScores)
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return candidateScores(val)
+}
+
+// recordWeightedMargin stores the score-weighted vote margin of ans once the Judge scored them, surfacing the
+// scores in the round statistics.
+func recordWeightedMargin(ctx agent.InvocationContext, p *AnswerProtocol, ans []candidateAnswer) error {
+	scores, err := stateCandidateScores(ctx)
+	if err != nil || len(scores) == 0 || len(ans) == 0 {
+		return err
+	}
+	// The margin does not depend on which of tied answers wins.
+	_, margin := weightedVote(p, ans, scores, 0)
+	return setState(ctx, stateKeyWeightedMargin, margin)
+}
+
+// label returns the name a is listed under in the joined answers.
+func (a candidateAnswer) label() string {
+	if a.Sample > 0 {
+		return fmt.Sprintf("%s#%d", a.Agent, a.Sample)
+	}
+	return a.Agent
+}
+
+// weightedVote is [majorityVote] with every answer weighted by the Judge's score of its candidate and by the
+// confidence of the answer's token log probabilities.
+//
+// Unscored candidates weigh [unscoredWeight], or one without any scores. A logprob confidence discounts the weight by
+// up to half; answers without one take the mean confidence of the others. The confidence of the vote is the weight
+// share of the winning answer. Without scores and log probabilities, or when every weight is zero, it falls back to
+// the unweighted majority vote.
+func weightedVote(p *AnswerProtocol, ans []candidateAnswer, scores []CandidateScore, ties tieBreaker) (answer string, confidence float64) {
+	if len(ans) == 0 {
+		return "", 0
+	}
+	confidences := answerConfidences(ans)
+	if len(scores) == 0 && confidences == nil {
+		return majorityVote(p, ans, ties)
+	}
+
+	weights := make(map[string]float64, len(scores))
+	for _, s := range scores {
+		weights[s.Agent] = s.Weight()
+	}
+
+	// Group equivalent answers exactly as the vote statistics do.
+	tallies, groupOf := groupAnswers(p, ans)
+	totals := make([]float64, len(tallies))
+	var total float64
+	for i, a := range ans {
+		w := 1.0
+		if len(scores) > 0 {
+			var ok bool
+			if w, ok = weights[a.label()]; !ok {
+				w = unscoredWeight
+			}
+		}
+		if confidences != nil {
+			w *= confidenceFactor(confidences[i])
+		}
+		totals[groupOf[i]] += w
+		total += w
+	}
+	if total == 0 {
+		return majorityVote(p, ans, ties)
+	}
+
+	best := 0
+	for i := range tallies {
+		if totals[i] > totals[best] || (totals[i] == totals[best] && ties.less(tallies[i].Answer, tallies[best].Answer)) {
+			best = i
+		}
+	}
+	return tallies[best].Answer, totals[best] / total
+}
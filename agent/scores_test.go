@@ -0,0 +1,264 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestWeightedVote(t *testing.T) {
+	t.Parallel()
+
+	ans := []candidateAnswer{
+		{Agent: "X", Text: "foo"},
+		{Agent: "Y", Text: "foo"},
+		{Agent: "Z", Text: "bar"},
+	}
+	tests := map[string]struct {
+		ans      []candidateAnswer
+		scores   []CandidateScore
+		wantAns  string
+		wantConf float64
+	}{
+		"no scores is majority": {
+			ans:      ans,
+			wantAns:  "foo",
+			wantConf: 2.0 / 3,
+		},
+		"scores outweigh majority": {
+			ans: ans,
+			scores: []CandidateScore{
+				{Agent: "X", Correctness: 0.2},
+				{Agent: "Y", Correctness: 0.2},
+				{Agent: "Z", Correctness: 1, Reasoning: 1},
+			},
+			// foo: 0.1 + 0.1, bar: 1.
+			wantAns:  "bar",
+			wantConf: 1 / 1.2,
+		},
+		"unscored candidates weigh half": {
+			ans:    ans,
+			scores: []CandidateScore{{Agent: "Z", Correctness: 0.8, Reasoning: 1}},
+			// foo: 0.5 + 0.5, bar: 0.8.
+			wantAns:  "foo",
+			wantConf: 1 / 1.8,
+		},
+		"all zero falls back to majority": {
+			ans: ans,
+			scores: []CandidateScore{
+				{Agent: "X"},
+				{Agent: "Y"},
+				{Agent: "Z"},
+			},
+			wantAns:  "foo",
+			wantConf: 2.0 / 3,
+		},
+		"samples are scored by label": {
+			ans: []candidateAnswer{
+				{Agent: "X", Sample: 1, Text: "1/2"},
+				{Agent: "X", Sample: 2, Text: "0.5"},
+				{Agent: "Y", Text: "2"},
+			},
+			scores: []CandidateScore{
+				{Agent: "X#1", Correctness: 0.4, Reasoning: 1},
+				{Agent: "X#2", Correctness: 0.4, Reasoning: 1},
+				{Agent: "Y", Correctness: 0.6, Reasoning: 1},
+			},
+			// Equivalent numeric answers are merged before weighing.
+			wantAns:  "1/2",
+			wantConf: 0.8 / 1.4,
+		},
+		"logprobs outweigh majority": {
+			ans: []candidateAnswer{
+				{Agent: "X", Text: "foo", Confidence: 0.1},
+				{Agent: "Y", Text: "foo", Confidence: 0.1},
+				{Agent: "Z", Text: "bar", Confidence: 1},
+				{Agent: "W", Text: "bar"},
+			},
+			// foo: 0.55 + 0.55, bar: 1 + 0.7 with W at the mean confidence 0.4.
+			wantAns:  "bar",
+			wantConf: 1.7 / 2.8,
+		},
+		"logprobs scale scores": {
+			ans: []candidateAnswer{
+				{Agent: "X", Text: "foo", Confidence: 1},
+				{Agent: "Z", Text: "bar", Confidence: 0.2},
+			},
+			scores: []CandidateScore{
+				{Agent: "X", Correctness: 0.5, Reasoning: 1},
+				{Agent: "Z", Correctness: 0.8, Reasoning: 1},
+			},
+			// foo: 0.5 * 1, bar: 0.8 * 0.6.
+			wantAns:  "foo",
+			wantConf: 0.5 / 0.98,
+		},
+		"empty": {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			gotAns, gotConf := weightedVote(DelimitedAnswers, tt.ans, tt.scores, 0)
+			if gotAns != tt.wantAns || math.Abs(gotConf-tt.wantConf) > 1e-9 {
+				t.Fatalf("weightedVote() = (%q, %v), want (%q, %v)", gotAns, gotConf, tt.wantAns, tt.wantConf)
+			}
+		})
+	}
+}
+
+func TestCandidateScores(t *testing.T) {
+	t.Parallel()
+
+	want := []CandidateScore{{Agent: "CoT", Correctness: 0.9, Reasoning: 0.7}}
+	tests := map[string]struct {
+		val     any
+		want    []CandidateScore
+		wantErr bool
+	}{
+		"nil":   {val: nil},
+		"typed": {val: want, want: want},
+		"reloaded json": {
+			val:  []any{map[string]any{"agent": "CoT", "correctness": 0.9, "reasoning": 0.7}},
+			want: want,
+		},
+		"invalid": {val: "not scores", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := candidateScores(tt.val)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("candidateScores() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("candidateScores() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCandidateScoreValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		score   CandidateScore
+		wantErr bool
+	}{
+		"valid":               {score: CandidateScore{Agent: "A", Correctness: 1, Reasoning: 0}},
+		"missing agent":       {score: CandidateScore{Correctness: 0.5}, wantErr: true},
+		"correctness too big": {score: CandidateScore{Agent: "A", Correctness: 1.5}, wantErr: true},
+		"negative reasoning":  {score: CandidateScore{Agent: "A", Reasoning: -0.1}, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.score.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTumixWeightsFallbackVoteByScores(t *testing.T) {
+	scores := []CandidateScore{
+		{Agent: "X", Correctness: 0.2},
+		{Agent: "Y", Correctness: 0.2},
+		{Agent: "Z", Correctness: 1, Reasoning: 1},
+	}
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: []agent.Agent{
+			staticCandidate("X", "foo"),
+			staticCandidate("Y", "foo"),
+			staticCandidate("Z", "bar"),
+		},
+		Judge:     scoringJudge(scores),
+		MaxRounds: 1,
+		MinRounds: 1,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	var final []CandidateScore
+	for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+		if s := CandidateScoresFromEvent(event); len(s) > 0 {
+			final = s
+		}
+	}
+	if diff := cmp.Diff(scores, final); diff != "" {
+		t.Fatalf("final event scores mismatch (-want +got):\n%s", diff)
+	}
+
+	res, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	answer, err := res.Session.State().Get(stateKeyAnswer)
+	if err != nil {
+		t.Fatalf("state answer: %v", err)
+	}
+	if answer != "bar" {
+		t.Fatalf("expected score-weighted answer bar, got %v", answer)
+	}
+}
+
+// scoringJudge stores scores like the finalize tool does and lets the rounds continue.
+func scoringJudge(scores []CandidateScore) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        "judge",
+		Description: "scoring judge",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				if err := ctx.Session().State().Set(stateKeyCandidateScores, scores); err != nil {
+					yield(nil, fmt.Errorf("set scores: %w", err))
+					return
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("continue", genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
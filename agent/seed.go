@@ -0,0 +1,112 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// SeedScope returns the scope the seed of the model call ctx belongs to is derived from: the calling agent, the TUMIX
+// round, and, in the candidate phase, the sample. It holds nothing specific to one run, so two runs of the same
+// question derive the same seeds. Use it as the scope of a seed deriving [model.LLM].
+func SeedScope(ctx context.Context) string {
+	ictx, ok := ctx.(agent.InvocationContext)
+	if !ok || ictx.Agent() == nil {
+		return ""
+	}
+	scope := ictx.Agent().Name()
+	if ictx.Session() == nil {
+		return scope
+	}
+	if round, err := ictx.Session().State().Get(stateKeyRound); err == nil && round != nil {
+		scope += fmt.Sprintf("/%v", round)
+	}
+	// The request scope ends with the sample index of the candidate phase.
+	if rs := RequestScope(ctx); rs != "" {
+		scope += rs[strings.LastIndexByte(rs, '/'):]
+	}
+	return scope
+}
+
+// seedHash hashes s under seed.
+func seedHash(seed int64, s string) uint64 {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, seed)
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// tieBreaker orders the answers tied in a vote. Unseeded, the lexically smallest answer wins; seeded, the one with the
+// smallest hash under the seed, so a seeded run breaks ties the same way every time without favoring lexically small
+// answers.
+type tieBreaker int64
+
+// less reports whether answer a wins a tie against b.
+func (s tieBreaker) less(a, b string) bool {
+	if s != 0 {
+		if ha, hb := seedHash(int64(s), a), seedHash(int64(s), b); ha != hb {
+			return ha < hb
+		}
+	}
+	return a < b
+}
+
+// seededOrder returns the rank of every candidate in the seeded order of round and sample: a permutation of the
+// candidates that is the same for every run with seed.
+func seededOrder(seed int64, round uint, sample int, candidates []agent.Agent) map[string]int {
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Name()
+	}
+	salt := fmt.Sprintf("/%d/%d", round, sample)
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(seedHash(seed, a+salt), seedHash(seed, b+salt)), strings.Compare(a, b))
+	})
+	rank := make(map[string]int, len(names))
+	for i, name := range names {
+		rank[name] = i
+	}
+	return rank
+}
+
+// sortBySeededOrder stably sorts the events of one sample by the rank of the candidates they come from, found by
+// author or, for the events of agents nested in a candidate, in the branch; events of no candidate go last.
+func sortBySeededOrder(events []*session.Event, rank map[string]int) {
+	rankOf := func(event *session.Event) int {
+		if r, ok := rank[event.Author]; ok {
+			return r
+		}
+		for name := range strings.SplitSeq(event.Branch, ".") {
+			if r, ok := rank[name]; ok {
+				return r
+			}
+		}
+		return len(rank)
+	}
+	slices.SortStableFunc(events, func(a, b *session.Event) int {
+		return cmp.Compare(rankOf(a), rankOf(b))
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// scopeCandidate answers its seed scope after a random delay, so the candidates finish in a random order.
+func scopeCandidate(name string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "seed scope candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				time.Sleep(time.Duration(rand.IntN(3)) * time.Millisecond) //nolint:gosec // test jitter
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("<<<"+SeedScope(ctx)+">>>", genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+func TestTumixSeededOrder(t *testing.T) {
+	t.Parallel()
+
+	candidates := []agent.Agent{scopeCandidate("A"), scopeCandidate("B"), scopeCandidate("C"), scopeCandidate("D")}
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates:      candidates,
+		Judge:           noOpJudge(),
+		MaxRounds:       2,
+		MinRounds:       2,
+		SamplesPerAgent: 2,
+		Seed:            7,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	run := func(sessionID string) []string {
+		ctx := t.Context()
+		svc := session.InMemoryService()
+		if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: sessionID}); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+		if err != nil {
+			t.Fatalf("runner: %v", err)
+		}
+		var answers []string
+		for event, err := range r.Run(ctx, "u", sessionID, genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+			if err != nil {
+				t.Fatalf("run err: %v", err)
+			}
+			if event.Author != "tumix" && event.Author != "judge" {
+				answers = append(answers, firstTextFromContent(event.Content))
+			}
+		}
+		return answers
+	}
+
+	first := run("s1")
+	if len(first) != 2*2*len(candidates) {
+		t.Fatalf("got %d candidate answers, want %d", len(first), 2*2*len(candidates))
+	}
+	for i := range 3 {
+		if diff := cmp.Diff(first, run(fmt.Sprintf("s%d", i+2))); diff != "" {
+			t.Fatalf("seeded runs differ (-first +got):\n%s", diff)
+		}
+	}
+
+	// The first sample of the first round follows the seeded order of the candidates.
+	rank := seededOrder(7, 1, 1, candidates)
+	want := []string{"A", "B", "C", "D"}
+	slices.SortFunc(want, func(a, b string) int { return rank[a] - rank[b] })
+	for i, name := range want {
+		want[i] = "<<<" + name + "/1/0>>>"
+	}
+	if diff := cmp.Diff(want, first[:len(candidates)]); diff != "" {
+		t.Fatalf("first sample order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTieBreaker(t *testing.T) {
+	t.Parallel()
+
+	if !tieBreaker(0).less("a", "b") || tieBreaker(0).less("b", "a") {
+		t.Fatal("unseeded tieBreaker is not lexical")
+	}
+
+	answers := []string{"a", "b", "c", "d", "e", "f"}
+	for _, seed := range []tieBreaker{1, 2, 3} {
+		for _, a := range answers {
+			for _, b := range answers {
+				if a != b && seed.less(a, b) == seed.less(b, a) {
+					t.Fatalf("tieBreaker(%d) does not order %q and %q", seed, a, b)
+				}
+			}
+		}
+	}
+
+	// Some seed lets a lexically larger answer win.
+	var reordered bool
+	for seed := tieBreaker(1); seed < 16 && !reordered; seed++ {
+		reordered = seed.less("b", "a")
+	}
+	if !reordered {
+		t.Fatal("seeded tieBreaker always prefers the lexically smaller answer")
+	}
+}
@@ -35,7 +35,7 @@ func TestComputeStats(t *testing.T) {
 			Text:  "bar",
 		},
 	}
-	stats := computeStats(answers, 5)
+	stats := computeStats(DelimitedAnswers, answers, 5, 0)
 	if stats.voteMargin <= 0.0 {
 		t.Fatalf("expected positive vote margin, got %f", stats.voteMargin)
 	}
@@ -65,7 +65,7 @@ func TestMajorityVoteNormalizesDelimiters(t *testing.T) {
 			Text:  "<<<foo >>>",
 		},
 	}
-	answer, conf := majorityVote(ans)
+	answer, conf := majorityVote(DelimitedAnswers, ans, 0)
 	if answer != "foo" {
 		t.Fatalf("expected normalized foo, got %s", answer)
 	}
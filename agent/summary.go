@@ -0,0 +1,203 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const answerSummaryInstruction = `Summarize the following candidate answers to one question for the candidates of the next round.
+Keep every distinct final answer, the candidates giving it, and the key step or evidence behind it; drop repetition.
+Do not judge which answer is right and do not add information. Output only the summary.`
+
+// AnswerSummary shrinks the previous answers the candidates read in the shared context of every round after the
+// first, which otherwise repeats every answer verbatim in every candidate prompt.
+//
+// The steps apply in order: Cluster, then MaxAnswerTokens, then Model. The Judge and the final event still see the
+// answers verbatim. The tokens of the shared answers and those saved are recorded in the round statistics (see
+// [RoundStats.SharedAnswerTokens]).
+type AnswerSummary struct {
+	// Cluster lists the answers the vote counts as the same once, with every candidate giving them.
+	Cluster bool
+	// MaxAnswerTokens, when positive, truncates the text of every answer to about this many tokens, keeping its
+	// final answer (see [AnswerProtocol]).
+	MaxAnswerTokens int
+	// Model, when set, summarizes the answers with one call per round. A failed or longer summary falls back to the
+	// answers of the previous steps.
+	Model model.LLM
+	// GenerateContentConfig is the generation config of the summary request.
+	GenerateContentConfig *genai.GenerateContentConfig
+	// CountTokens counts the tokens of text. Nil estimates four characters per token.
+	CountTokens func(ctx context.Context, text string) (int, error)
+}
+
+// summarize returns the previous answers ans, given in protocol p, as the candidates read them.
+func (s *AnswerSummary) summarize(ctx context.Context, p *AnswerProtocol, ans []candidateAnswer) string {
+	truncate := func(text string) string { return s.truncate(p, text) }
+	var joined string
+	if s.Cluster {
+		joined = joinClusters(p, ans, truncate)
+	} else {
+		shortened := make([]candidateAnswer, len(ans))
+		for i, a := range ans {
+			shortened[i] = a
+			shortened[i].Text = truncate(a.Text)
+		}
+		joined = joinAnswers(shortened)
+	}
+	if s.Model == nil {
+		return joined
+	}
+	summary, err := s.summarizeWithModel(ctx, joined)
+	if err != nil || summary == "" || len(summary) >= len(joined) {
+		return joined
+	}
+	return summary
+}
+
+// truncate cuts text to about MaxAnswerTokens tokens, four characters each, keeping its final answer block in
+// protocol p.
+func (s *AnswerSummary) truncate(p *AnswerProtocol, text string) string {
+	text = strings.TrimSpace(text)
+	limit := s.MaxAnswerTokens * 4
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	var final string
+	if i := strings.LastIndex(text, p.orDefault().Open); i >= 0 {
+		final = text[i:]
+		text = text[:i]
+	}
+	head := max(limit-len(final), 0)
+	for head > 0 && !utf8.RuneStart(text[head]) {
+		head--
+	}
+	if final == "" {
+		return strings.TrimSpace(text[:head]) + " …"
+	}
+	return strings.TrimSpace(text[:head]) + " … " + final
+}
+
+func (s *AnswerSummary) countTokens(ctx context.Context, text string) (int, error) {
+	if s.CountTokens != nil {
+		return s.CountTokens(ctx, text)
+	}
+	return (len(text) + 3) / 4, nil
+}
+
+func (s *AnswerSummary) summarizeWithModel(ctx context.Context, joined string) (string, error) {
+	cfg := cloneGenConfig(s.GenerateContentConfig)
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	cfg.SystemInstruction = genai.NewContentFromText(answerSummaryInstruction, genai.RoleUser)
+
+	req := &model.LLMRequest{
+		Model:    s.Model.Name(),
+		Contents: []*genai.Content{genai.NewContentFromText(joined, genai.RoleUser)},
+		Config:   cfg,
+	}
+	var sb strings.Builder
+	for resp, err := range s.Model.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", fmt.Errorf("answer summary: %w", err)
+		}
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if part != nil && !part.Thought {
+				sb.WriteString(part.Text)
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// joinClusters joins the answers of ans like [joinAnswers], listing the answers the vote counts as the same once,
+// by the text of the first, after the names of every candidate sample giving them.
+//
+// The answers are considered in agent and sample order, not in the order the parallel candidates answered in.
+func joinClusters(p *AnswerProtocol, ans []candidateAnswer, text func(string) string) string {
+	ans = slices.Clone(ans)
+	slices.SortStableFunc(ans, func(a, b candidateAnswer) int {
+		return cmp.Or(strings.Compare(a.Agent, b.Agent), cmp.Compare(a.Sample, b.Sample))
+	})
+	tallies, groupOf := groupAnswers(p, ans)
+	members := make([][]int, len(tallies))
+	for i, g := range groupOf {
+		members[g] = append(members[g], i)
+	}
+
+	buf := getAnswerBuffer()
+	defer putAnswerBuffer(buf)
+	for g, m := range members {
+		if g > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("- ")
+		for j, i := range m {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			writeAnswerLabel(buf, ans[i])
+		}
+		if len(m) > 1 {
+			fmt.Fprintf(buf, " (%d answers)", len(m))
+		}
+		buf.WriteString(": ")
+		buf.WriteString(text(ans[m[0]].Text))
+	}
+	return buf.String()
+}
+
+// sharedAnswers is what the summary of the previous answers saved in one round.
+type sharedAnswers struct {
+	// tokens is the size of the shared answers in one candidate prompt.
+	tokens int
+	// saved is the tokens saved over the verbatim answers, summed over the candidate prompts of the round.
+	saved int
+}
+
+// shareAnswers returns the previous answers ans as the candidates of the round read them, summarized when an
+// [AnswerSummary] is configured, and what the summary saved. A summary that is not shorter is not used.
+func (t *tumixOrchestrator) shareAnswers(ctx agent.InvocationContext, ans []candidateAnswer) (string, sharedAnswers) {
+	joined := joinAnswers(ans)
+	if t.answerSummary == nil || len(ans) == 0 {
+		return joined, sharedAnswers{}
+	}
+	full, err := t.answerSummary.countTokens(ctx, joined)
+	if err != nil {
+		return joined, sharedAnswers{}
+	}
+	summary := t.answerSummary.summarize(ctx, t.protocol, ans)
+	short, err := t.answerSummary.countTokens(ctx, summary)
+	if err != nil || short >= full {
+		return joined, sharedAnswers{tokens: full}
+	}
+	prompts := len(t.candidates(ctx).SubAgents()) * int(t.samples()) //nolint:gosec // samples is small
+	return summary, sharedAnswers{tokens: short, saved: (full - short) * prompts}
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"iter"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestAnswerSummaryTruncate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		maxTokens int
+		text      string
+		want      string
+	}{
+		"unlimited": {
+			text: strings.Repeat("x", 100),
+			want: strings.Repeat("x", 100),
+		},
+		"short enough": {
+			maxTokens: 10,
+			text:      "short <<<1>>>",
+			want:      "short <<<1>>>",
+		},
+		"keeps final answer": {
+			maxTokens: 4,
+			text:      "some long reasoning here <<<42>>>",
+			want:      "some lon … <<<42>>>",
+		},
+		"no final answer": {
+			maxTokens: 2,
+			text:      "some long reasoning",
+			want:      "some lon …",
+		},
+		"rune boundary": {
+			maxTokens: 1,
+			text:      "abcé long",
+			want:      "abc …",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &AnswerSummary{MaxAnswerTokens: tt.maxTokens}
+			if got := s.truncate(DelimitedAnswers, tt.text); got != tt.want {
+				t.Fatalf("truncate(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinClusters(t *testing.T) {
+	t.Parallel()
+
+	// In arrival order, which the clusters do not follow.
+	ans := []candidateAnswer{
+		{Agent: "sc", Text: "<<<2>>>"},
+		{Agent: "cot", Sample: 2, Text: "<<<0.33>>>"},
+		{Agent: "cot", Sample: 1, Text: "<<<2.0>>>"},
+		{Agent: "base", Text: "because <<<1/3>>>"},
+	}
+	got := joinClusters(DelimitedAnswers, ans, strings.TrimSpace)
+	want := "- base, cot#2 (2 answers): because <<<1/3>>>\n- cot#1, sc (2 answers): <<<2.0>>>"
+	if got != want {
+		t.Fatalf("joinClusters() = %q, want %q", got, want)
+	}
+}
+
+func TestAnswerSummarySummarize(t *testing.T) {
+	t.Parallel()
+
+	ans := []candidateAnswer{
+		{Agent: "base", Text: "long reasoning for the first answer <<<1>>>"},
+		{Agent: "cot", Text: "long reasoning for the second answer <<<1>>>"},
+	}
+	tests := map[string]struct {
+		summary AnswerSummary
+		want    string
+	}{
+		"cluster": {
+			summary: AnswerSummary{Cluster: true},
+			want:    "- base, cot (2 answers): long reasoning for the first answer <<<1>>>",
+		},
+		"truncate": {
+			summary: AnswerSummary{MaxAnswerTokens: 3},
+			want:    "- base: long … <<<1>>>\n- cot: long … <<<1>>>",
+		},
+		"model": {
+			summary: AnswerSummary{Cluster: true, Model: &summaryLLM{summary: "both say 1"}},
+			want:    "both say 1",
+		},
+		"model failure": {
+			summary: AnswerSummary{Cluster: true, Model: &summaryLLM{err: errors.New("boom")}},
+			want:    "- base, cot (2 answers): long reasoning for the first answer <<<1>>>",
+		},
+		"longer model summary": {
+			summary: AnswerSummary{MaxAnswerTokens: 3, Model: &summaryLLM{summary: strings.Repeat("x", 200)}},
+			want:    "- base: long … <<<1>>>\n- cot: long … <<<1>>>",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.summary.summarize(t.Context(), DelimitedAnswers, ans); got != tt.want {
+				t.Fatalf("summarize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTumixAnswerSummary(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		joined []string
+	)
+	answer := "a long derivation that every candidate repeats at length <<<7>>>"
+	recorder := mustAgent(agent.New(agent.Config{
+		Name:        "Y",
+		Description: "recording candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				val, _ := ctx.Session().State().Get(stateKeyJoined)
+				text, _ := val.(string)
+				mu.Lock()
+				joined = append(joined, text)
+				mu.Unlock()
+
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(answer, genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+
+	loader, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates:    []agent.Agent{staticCandidate("X", answer), recorder},
+		Judge:         noOpJudge(),
+		MaxRounds:     2,
+		MinRounds:     2,
+		AnswerSummary: &AnswerSummary{Cluster: true},
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	var final *session.Event
+	for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run err: %v", err)
+		}
+		if event.Author == "tumix" {
+			final = event
+		}
+	}
+
+	clustered := "- X, Y (2 answers): " + answer
+	if len(joined) != 2 || joined[0] != "" || joined[1] != clustered {
+		t.Fatalf("shared answers = %q, want [\"\" %q]", joined, clustered)
+	}
+
+	stats := RoundStatsFromEvent(final)
+	if len(stats) != 2 {
+		t.Fatalf("RoundStatsFromEvent() = %+v, want 2 rounds", stats)
+	}
+	if stats[0].SharedAnswerTokens != 0 || stats[0].SavedAnswerTokens != 0 {
+		t.Fatalf("round 1 shared tokens = %d, saved %d, want none", stats[0].SharedAnswerTokens, stats[0].SavedAnswerTokens)
+	}
+	full := (len(joinAnswers([]candidateAnswer{{Agent: "X", Text: answer}, {Agent: "Y", Text: answer}})) + 3) / 4
+	short := (len(clustered) + 3) / 4
+	if got := stats[1]; got.SharedAnswerTokens != short || got.SavedAnswerTokens != 2*(full-short) {
+		t.Fatalf("round 2 shared tokens = %d, saved %d, want %d, %d", got.SharedAnswerTokens, got.SavedAnswerTokens, short, 2*(full-short))
+	}
+}
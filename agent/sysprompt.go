@@ -0,0 +1,77 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"strings"
+
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/genai"
+)
+
+// System prompt template variables, substituted when a candidate agent is built.
+const (
+	SystemPromptAgentVar = "{agent_name}"
+	SystemPromptModelVar = "{model_name}"
+)
+
+// WithSystemPrompt returns a copy of genCfg carrying prompt as the system prompt of the candidate agents built from
+// it, e.g. organization-specific instructions such as "answer in French".
+//
+// Every candidate merges the prompt into its global instruction ahead of the shared TUMIX context, replacing
+// [SystemPromptAgentVar] and [SystemPromptModelVar] with its own agent and model name. Other {key} placeholders are
+// resolved from the session state like the rest of the instruction. An empty prompt returns genCfg unchanged.
+func WithSystemPrompt(genCfg *genai.GenerateContentConfig, prompt string) *genai.GenerateContentConfig {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return genCfg
+	}
+	cfg := cloneGenConfig(genCfg)
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	cfg.SystemInstruction = genai.NewContentFromText(prompt, genai.RoleUser)
+	return cfg
+}
+
+// takeSystemPrompt removes the system prompt set by [WithSystemPrompt] from cfg and returns it rendered for the agent.
+func takeSystemPrompt(cfg *llmagent.Config) string {
+	genCfg := cfg.GenerateContentConfig
+	if genCfg == nil || genCfg.SystemInstruction == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, p := range genCfg.SystemInstruction.Parts {
+		if p != nil && p.Text != "" {
+			parts = append(parts, p.Text)
+		}
+	}
+	// Clear it on a copy: ADK would otherwise send the unrendered prompt ahead of the instructions as well.
+	genCfg = cloneGenConfig(genCfg)
+	genCfg.SystemInstruction = nil
+	cfg.GenerateContentConfig = genCfg
+
+	modelName := ""
+	if cfg.Model != nil {
+		modelName = cfg.Model.Name()
+	}
+	return strings.NewReplacer(
+		SystemPromptAgentVar, cfg.Name,
+		SystemPromptModelVar, modelName,
+	).Replace(strings.TrimSpace(strings.Join(parts, "\n")))
+}
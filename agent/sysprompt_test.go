@@ -0,0 +1,93 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/genai"
+)
+
+func TestApplySharedContextSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	temp := float32(0.2)
+	base := &genai.GenerateContentConfig{Temperature: &temp}
+	shared := sharedContext(nil) + "\n{debate_CoT?}"
+
+	tests := map[string]struct {
+		genCfg *genai.GenerateContentConfig
+		want   string
+	}{
+		"no system prompt": {
+			genCfg: base,
+			want:   shared,
+		},
+		"nil config": {
+			want: shared,
+		},
+		"empty prompt": {
+			genCfg: WithSystemPrompt(base, "  "),
+			want:   shared,
+		},
+		"rendered ahead of shared context": {
+			genCfg: WithSystemPrompt(base, "Answer in French as {agent_name} on {model_name}. Round {round_num}.\n"),
+			want:   "Answer in French as CoT on stub. Round {round_num}.\n\n" + shared,
+		},
+		"nil base config": {
+			genCfg: WithSystemPrompt(nil, "Be terse."),
+			want:   "Be terse.\n\n" + shared,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := llmagent.Config{Name: "CoT", Model: &stubLLM{}, GenerateContentConfig: cloneGenConfig(tt.genCfg)}
+			applySharedContext(&cfg, nil)
+
+			if cfg.GlobalInstruction != tt.want {
+				t.Fatalf("GlobalInstruction = %q, want %q", cfg.GlobalInstruction, tt.want)
+			}
+			if cfg.GenerateContentConfig != nil && cfg.GenerateContentConfig.SystemInstruction != nil {
+				t.Fatalf("SystemInstruction = %v, want it moved into the global instruction", cfg.GenerateContentConfig.SystemInstruction)
+			}
+		})
+	}
+
+	if base.SystemInstruction != nil {
+		t.Fatal("WithSystemPrompt modified its input config")
+	}
+}
+
+func TestWithSystemPromptSharedByCandidates(t *testing.T) {
+	t.Parallel()
+
+	genCfg := WithSystemPrompt(nil, "You are {agent_name}.")
+	for _, name := range []string{"A", "B"} {
+		cfg := llmagent.Config{Name: name, Model: &stubLLM{}, GenerateContentConfig: cloneGenConfig(genCfg)}
+		applySharedContext(&cfg, nil)
+		if want := "You are " + name + ".\n\n" + sharedContext(nil) + "\n" + debatePlaceholder(name); cfg.GlobalInstruction != want {
+			t.Fatalf("GlobalInstruction of %s = %q, want %q", name, cfg.GlobalInstruction, want)
+		}
+	}
+	if genCfg.SystemInstruction == nil {
+		t.Fatal("building a candidate cleared the shared system prompt")
+	}
+}
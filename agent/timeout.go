@@ -0,0 +1,125 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// Causes of the deadlines the TUMIX Agent sets with [TumixConfig.RoundTimeout] and [TumixConfig.RunTimeout].
+var (
+	ErrRoundTimeout = errors.New("round timeout")
+	ErrRunTimeout   = errors.New("run timeout")
+)
+
+// MetadataKeyTimeouts is the [session.Event] custom metadata key carrying, as []string, why rounds were cut short by
+// a deadline or an interruption on the final TUMIX event.
+const MetadataKeyTimeouts = "tumix_timeouts"
+
+const stateKeyTimeouts = "timeouts"
+
+// TimeoutsFromEvent returns why rounds were cut short by a deadline or an interruption, as recorded on the final TUMIX event.
+func TimeoutsFromEvent(event *session.Event) []string {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	timeouts, _ := event.CustomMetadata[MetadataKeyTimeouts].([]string)
+	return timeouts
+}
+
+// deadlineContext is an invocation context canceled by a context derived from it, so the orchestrator can bound the
+// agents it runs without ending the invocation.
+type deadlineContext struct {
+	agent.InvocationContext
+	ctx context.Context
+}
+
+func (c *deadlineContext) Deadline() (time.Time, bool) { return c.ctx.Deadline() }
+func (c *deadlineContext) Done() <-chan struct{}       { return c.ctx.Done() }
+func (c *deadlineContext) Err() error                  { return c.ctx.Err() }
+func (c *deadlineContext) Value(key any) any           { return c.ctx.Value(key) }
+
+// withTimeout returns ctx canceled with cause after d, or ctx itself when d is not positive.
+func withTimeout(ctx agent.InvocationContext, d time.Duration, cause error) (agent.InvocationContext, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	dctx, cancel := context.WithTimeoutCause(ctx, d, cause)
+	return &deadlineContext{InvocationContext: ctx, ctx: dctx}, cancel
+}
+
+// cutShortCause returns the deadline or interruption that canceled ctx, or nil when ctx is live or was canceled
+// otherwise.
+func cutShortCause(ctx context.Context) error {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, ErrRoundTimeout), errors.Is(cause, ErrRunTimeout), errors.Is(cause, ErrInterrupted):
+		return cause
+	default:
+		return nil
+	}
+}
+
+// endsRun reports whether cause, returned by [cutShortCause], leaves no time for another round or the Judge.
+func endsRun(cause error) bool {
+	return errors.Is(cause, ErrRunTimeout) || errors.Is(cause, ErrInterrupted)
+}
+
+// roundContext returns the context of one round, bounded by the round timeout, and a yield that drops the errors of
+// the agents cut short by a deadline of the round or the run, or by an interruption: the orchestrator finalizes with
+// what it has instead.
+func (t *tumixOrchestrator) roundContext(ctx agent.InvocationContext, yield func(*session.Event, error) bool) (agent.InvocationContext, func(*session.Event, error) bool, context.CancelFunc) {
+	rctx, cancel := withTimeout(ctx, t.roundTimeout, ErrRoundTimeout)
+	return rctx, dropTimeoutErrors(rctx, yield), cancel
+}
+
+// dropTimeoutErrors wraps yield to drop the errors yielded once ctx was cut short.
+func dropTimeoutErrors(ctx context.Context, yield func(*session.Event, error) bool) func(*session.Event, error) bool {
+	return func(event *session.Event, err error) bool {
+		if err != nil && cutShortCause(ctx) != nil {
+			return true
+		}
+		return yield(event, err)
+	}
+}
+
+// recordTimeout appends to reasons, and to the session state, why round was cut short when a deadline fired or the
+// run was interrupted in rctx. It returns the cause, if any, and whether the run must stop.
+func (t *tumixOrchestrator) recordTimeout(rctx agent.InvocationContext, round uint, reasons *[]string, yield func(*session.Event, error) bool) (cause error, stop bool) {
+	cause = cutShortCause(rctx)
+	if cause == nil {
+		return nil, false
+	}
+	reason := fmt.Sprintf("round %d: %v", round, cause)
+	switch {
+	case errors.Is(cause, ErrRoundTimeout):
+		reason += " after " + t.roundTimeout.String()
+	case errors.Is(cause, ErrRunTimeout):
+		reason += " after " + t.runTimeout.String()
+	}
+	*reasons = append(*reasons, reason)
+	if err := setState(rctx, stateKeyTimeouts, *reasons); err != nil {
+		yield(nil, err)
+		return cause, true
+	}
+	return cause, false
+}
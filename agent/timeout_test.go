@@ -0,0 +1,132 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"iter"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// blockingCandidate never answers; it fails once its context is done.
+func blockingCandidate(name string) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        name,
+		Description: "blocking candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				<-ctx.Done()
+				yield(nil, ctx.Err())
+			}
+		},
+	}))
+}
+
+func TestTumixTimeouts(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mode         Mode
+		roundTimeout time.Duration
+		runTimeout   time.Duration
+		wantTimeouts []string
+		wantJudge    int64
+	}{
+		"round timeout ends every round": {
+			roundTimeout: 20 * time.Millisecond,
+			wantTimeouts: []string{
+				"round 1: round timeout after 20ms",
+				"round 2: round timeout after 20ms",
+			},
+		},
+		"run timeout finalizes on the first round": {
+			runTimeout:   20 * time.Millisecond,
+			wantTimeouts: []string{"round 1: run timeout after 20ms"},
+		},
+		"debate run timeout skips the judge": {
+			mode:         ModeDebate,
+			runTimeout:   20 * time.Millisecond,
+			wantTimeouts: []string{"round 1: run timeout after 20ms"},
+		},
+		"debate round timeout keeps the judge": {
+			mode:         ModeDebate,
+			roundTimeout: 20 * time.Millisecond,
+			// The only answer of the first round is a consensus.
+			wantTimeouts: []string{"round 1: round timeout after 20ms"},
+			wantJudge:    1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var judgeCalls atomic.Int64
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates:   []agent.Agent{staticCandidate("X", "foo"), blockingCandidate("slow")},
+				Judge:        countingJudge(&judgeCalls, noOpJudge()),
+				MaxRounds:    2,
+				MinRounds:    1,
+				Mode:         tt.mode,
+				RoundTimeout: tt.roundTimeout,
+				RunTimeout:   tt.runTimeout,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+			var final *session.Event
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == "tumix" {
+					final = event
+				}
+			}
+
+			if final == nil {
+				t.Fatal("no final event")
+			}
+			if got, want := firstTextFromContent(final.Content), "Final answer (conf 1): foo"; got != want {
+				t.Fatalf("final answer = %q, want %q", got, want)
+			}
+			if diff := cmp.Diff(tt.wantTimeouts, TimeoutsFromEvent(final)); diff != "" {
+				t.Fatalf("timeouts mismatch (-want +got):\n%s", diff)
+			}
+			if got := judgeCalls.Load(); got != tt.wantJudge {
+				t.Fatalf("judge calls = %d, want %d", got, tt.wantJudge)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// NewToolsetAgent creates a candidate agent that solves problems with externally provided tools,
+// such as the tools of MCP servers.
+//
+// It joins the mixture alongside the pre-designed agents so external tools contribute their own candidate answer.
+func NewToolsetAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol, toolsets ...tool.Toolset) (agent.Agent, error) {
+	if len(toolsets) == 0 {
+		return nil, errors.New("at least one toolset is required")
+	}
+
+	cfg := llmagent.Config{
+		Name: "external-tools",
+		Description: `Uses external tools (e.g. MCP servers) to gather facts or act before answering.
+- Short name: {X}`,
+		Model:                 llm,
+		GenerateContentConfig: cloneGenConfig(genCfg),
+		Toolsets:              toolsets,
+		Instruction: `You are a helpful AI assistant. Solve tasks using the available tools.
+
+Call a tool whenever it can provide facts, computation, or data you do not reliably know; prefer one focused call per
+step and read its output before deciding the next step. Do not invent tool outputs.
+
+Respond with the final answer ` + p.Enclosure() + `.`,
+	}
+
+	applySharedContext(&cfg, p)
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build Toolset agent: %w", err)
+	}
+
+	return a, nil
+}
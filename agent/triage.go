@@ -0,0 +1,237 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// MetadataKeyTriage is the [session.Event] custom metadata key carrying the [TriageDecision] of the run on the final
+// TUMIX event.
+const MetadataKeyTriage = "tumix_triage"
+
+const stateKeyTriage = "triage"
+
+// DefaultEasyCandidates is the [Triage.EasyCandidates] used when it is zero.
+const DefaultEasyCandidates = 3
+
+// easyMaxChars is the longest question the heuristic rates easy.
+const easyMaxChars = 280
+
+const triageInstruction = `Rate the difficulty of the user's question for a panel of expert solvers.
+Reply with one word: EASY if a single careful expert would answer it correctly at once, or HARD if it needs
+multi-step reasoning, computation, code, or research.`
+
+// Difficulty is the difficulty of a question rated by the triage stage.
+type Difficulty string
+
+const (
+	// DifficultyEasy questions run with the first [Triage.EasyCandidates] candidates.
+	DifficultyEasy Difficulty = "easy"
+	// DifficultyHard questions run with the full mixture.
+	DifficultyHard Difficulty = "hard"
+)
+
+// Triage rates the difficulty of the question once before the first round and runs easy questions with a small
+// subset of the candidates instead of the full mixture, trading the diversity hard questions need for the cost easy
+// ones do not. The decision is recorded on the final event (see [TriageFromEvent]).
+type Triage struct {
+	// Model, when set, rates the difficulty with one call. Without it, or when the call fails, a heuristic on the
+	// length and shape of the question does.
+	Model model.LLM
+	// GenerateContentConfig is the generation config of the rating request.
+	GenerateContentConfig *genai.GenerateContentConfig
+	// EasyCandidates is the number of candidates, the first ones, that run for easy questions; zero uses
+	// [DefaultEasyCandidates].
+	EasyCandidates int
+}
+
+// TriageDecision is the outcome of the triage stage of a run.
+type TriageDecision struct {
+	Difficulty Difficulty `json:"difficulty"`
+	// Reason is how the difficulty was rated.
+	Reason string `json:"reason"`
+	// Candidates is the number of candidates run out of TotalCandidates.
+	Candidates      int `json:"candidates"`
+	TotalCandidates int `json:"total_candidates"`
+	// SavedCalls is the number of candidate model calls saved when every round runs, before tool calls.
+	SavedCalls int `json:"saved_calls"`
+}
+
+// TriageFromEvent returns the triage decision attached to the final TUMIX event, or nil when the run had no triage.
+func TriageFromEvent(event *session.Event) *TriageDecision {
+	if event == nil || event.CustomMetadata == nil {
+		return nil
+	}
+	decision, _ := event.CustomMetadata[MetadataKeyTriage].(*TriageDecision)
+	return decision
+}
+
+func (tr *Triage) easyCandidates() int {
+	if tr.EasyCandidates > 0 {
+		return tr.EasyCandidates
+	}
+	return DefaultEasyCandidates
+}
+
+// rate rates the difficulty of question with the model, falling back to the heuristic.
+func (tr *Triage) rate(ctx context.Context, question string) (Difficulty, string) {
+	if tr.Model == nil {
+		return heuristicDifficulty(question)
+	}
+	difficulty, err := tr.ask(ctx, question)
+	if err != nil {
+		d, reason := heuristicDifficulty(question)
+		return d, fmt.Sprintf("%s (model failed: %v)", reason, err)
+	}
+	return difficulty, "model"
+}
+
+func (tr *Triage) ask(ctx context.Context, question string) (Difficulty, error) {
+	cfg := cloneGenConfig(tr.GenerateContentConfig)
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	cfg.SystemInstruction = genai.NewContentFromText(triageInstruction, genai.RoleUser)
+
+	req := &model.LLMRequest{
+		Model:    tr.Model.Name(),
+		Contents: []*genai.Content{genai.NewContentFromText(question, genai.RoleUser)},
+		Config:   cfg,
+	}
+	var sb strings.Builder
+	for resp, err := range tr.Model.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", fmt.Errorf("triage: %w", err)
+		}
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if part != nil && !part.Thought {
+				sb.WriteString(part.Text)
+			}
+		}
+	}
+	reply := strings.ToUpper(sb.String())
+	switch easy, hard := strings.Contains(reply, "EASY"), strings.Contains(reply, "HARD"); {
+	case easy && !hard:
+		return DifficultyEasy, nil
+	case hard && !easy:
+		return DifficultyHard, nil
+	default:
+		return "", fmt.Errorf("triage: unclear rating %q", strings.TrimSpace(sb.String()))
+	}
+}
+
+// hardMarkers are phrases of questions that need more than one careful expert.
+var hardMarkers = []string{"prove", "derive", "step by step", "algorithm", "optimiz", "analyz", "analys", "design", "compare", "explain why", "calculate", "implement"}
+
+// heuristicDifficulty rates short single-line questions without code or hard markers easy.
+func heuristicDifficulty(question string) (Difficulty, string) {
+	question = strings.TrimSpace(question)
+	switch {
+	case len(question) > easyMaxChars:
+		return DifficultyHard, "heuristic: long question"
+	case strings.Count(question, "\n") > 1:
+		return DifficultyHard, "heuristic: multi-line question"
+	case strings.Contains(question, "```"):
+		return DifficultyHard, "heuristic: code"
+	}
+	lower := strings.ToLower(question)
+	for _, marker := range hardMarkers {
+		if strings.Contains(lower, marker) {
+			return DifficultyHard, fmt.Sprintf("heuristic: asks to %s", strings.TrimSpace(marker))
+		}
+	}
+	return DifficultyEasy, "heuristic: short question"
+}
+
+// triageQuestion runs the triage stage on question and records its decision in the session state, where
+// [tumixOrchestrator.candidates] reads it.
+func (t *tumixOrchestrator) triageQuestion(ctx agent.InvocationContext, question string) error {
+	if t.triage == nil {
+		return setState(ctx, stateKeyTriage, nil)
+	}
+	total := len(t.candidateAgent.SubAgents())
+	difficulty, reason := t.triage.rate(ctx, question)
+	decision := &TriageDecision{
+		Difficulty:      difficulty,
+		Reason:          reason,
+		Candidates:      total,
+		TotalCandidates: total,
+	}
+	if difficulty == DifficultyEasy && t.easyAgent != nil {
+		decision.Candidates = len(t.easyAgent.SubAgents())
+		decision.SavedCalls = (total - decision.Candidates) * int(t.samples()) * int(t.maxRounds) //nolint:gosec // small
+	}
+	return setState(ctx, stateKeyTriage, decision)
+}
+
+// candidates returns the parallel agent of the candidates of the run: the easy subset when the triage rated the
+// question easy, all of them otherwise.
+func (t *tumixOrchestrator) candidates(ctx agent.InvocationContext) agent.Agent {
+	if t.easyAgent == nil {
+		return t.candidateAgent
+	}
+	if decision, err := stateTriage(ctx); err == nil && decision != nil && decision.Difficulty == DifficultyEasy {
+		return t.easyAgent
+	}
+	return t.candidateAgent
+}
+
+// stateTriage returns the triage decision of the run in the session state, or nil.
+func stateTriage(ctx agent.InvocationContext) (*TriageDecision, error) {
+	val, err := ctx.Session().State().Get(stateKeyTriage)
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("state %s: %w", stateKeyTriage, err)
+	}
+	return triageDecision(val)
+}
+
+// triageDecision decodes the triage decision stored in the session state, a *TriageDecision when set in this
+// process and a generic JSON value when the state was reloaded from a persistent session store.
+func triageDecision(val any) (*TriageDecision, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case *TriageDecision:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal triage decision: %w", err)
+		}
+		var decision TriageDecision
+		if err := json.Unmarshal(b, &decision); err != nil {
+			return nil, fmt.Errorf("unmarshal triage decision: %w", err)
+		}
+		return &decision, nil
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestHeuristicDifficulty(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		question string
+		want     Difficulty
+	}{
+		"short":      {question: "What is the capital of France?", want: DifficultyEasy},
+		"long":       {question: strings.Repeat("word ", 100) + "?", want: DifficultyHard},
+		"multi-line": {question: "Given:\na = 1\nb = 2\nWhat is a+b?", want: DifficultyHard},
+		"code":       {question: "Fix ```x := 1```", want: DifficultyHard},
+		"marker":     {question: "Prove that there are infinitely many primes.", want: DifficultyHard},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, reason := heuristicDifficulty(tt.question); got != tt.want || !strings.HasPrefix(reason, "heuristic: ") {
+				t.Fatalf("heuristicDifficulty() = %q (%s), want %q", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriageRate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		llm        *summaryLLM
+		want       Difficulty
+		wantReason string
+	}{
+		"model easy": {
+			llm:        &summaryLLM{summary: "EASY"},
+			want:       DifficultyEasy,
+			wantReason: "model",
+		},
+		"model hard": {
+			llm:        &summaryLLM{summary: "Hard."},
+			want:       DifficultyHard,
+			wantReason: "model",
+		},
+		"unclear": {
+			llm:        &summaryLLM{summary: "It depends"},
+			want:       DifficultyEasy,
+			wantReason: `heuristic: short question (model failed: triage: unclear rating "It depends")`,
+		},
+		"model error": {
+			llm:        &summaryLLM{err: errors.New("boom")},
+			want:       DifficultyEasy,
+			wantReason: "heuristic: short question (model failed: triage: boom)",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tr := &Triage{Model: tt.llm}
+			got, reason := tr.rate(t.Context(), "What is 6*7?")
+			if got != tt.want || reason != tt.wantReason {
+				t.Fatalf("rate() = (%q, %q), want (%q, %q)", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTumixTriage(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		question       string
+		wantCandidates []string
+		want           *TriageDecision
+	}{
+		"easy": {
+			question:       "What is 6*7?",
+			wantCandidates: []string{"A", "B"},
+			want: &TriageDecision{
+				Difficulty: DifficultyEasy, Reason: "heuristic: short question",
+				Candidates: 2, TotalCandidates: 4, SavedCalls: 2 * 2,
+			},
+		},
+		"hard": {
+			question:       "Prove that 6*7 is 42.",
+			wantCandidates: []string{"A", "B", "C", "D"},
+			want: &TriageDecision{
+				Difficulty: DifficultyHard, Reason: "heuristic: asks to prove",
+				Candidates: 4, TotalCandidates: 4,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{
+					staticCandidate("A", "42"), staticCandidate("B", "42"), staticCandidate("C", "41"), staticCandidate("D", "40"),
+				},
+				Judge:     noOpJudge(),
+				MaxRounds: 2,
+				MinRounds: 2,
+				Triage:    &Triage{EasyCandidates: 2},
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+
+			seen := make(map[string]bool)
+			var final *session.Event
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText(tt.question, genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				switch event.Author {
+				case "tumix":
+					final = event
+				case "judge":
+				default:
+					seen[event.Author] = true
+				}
+			}
+
+			var got []string
+			for _, name := range []string{"A", "B", "C", "D"} {
+				if seen[name] {
+					got = append(got, name)
+				}
+			}
+			if diff := cmp.Diff(tt.wantCandidates, got); diff != "" {
+				t.Fatalf("candidates run mismatch (-want +got):\n%s", diff)
+			}
+			if final == nil {
+				t.Fatal("no final event")
+			}
+			if diff := cmp.Diff(tt.want, TriageFromEvent(final)); diff != "" {
+				t.Fatalf("TriageFromEvent() mismatch (-want +got):\n%s", diff)
+			}
+			if stats := RoundStatsFromEvent(final); len(stats) == 0 || stats[0].Coverage != 1 {
+				t.Fatalf("round stats = %+v, want full coverage of the candidates run", stats)
+			}
+		})
+	}
+}
+
+func TestTriageDecisionFromState(t *testing.T) {
+	t.Parallel()
+
+	want := &TriageDecision{Difficulty: DifficultyEasy, Reason: "model", Candidates: 3, TotalCandidates: 12, SavedCalls: 27}
+	generic := map[string]any{
+		"difficulty": "easy", "reason": "model", "candidates": 3, "total_candidates": 12, "saved_calls": 27,
+	}
+	for _, val := range []any{want, generic} {
+		got, err := triageDecision(val)
+		if err != nil {
+			t.Fatalf("triageDecision(%T) error = %v", val, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("triageDecision(%T) mismatch (-want +got):\n%s", val, diff)
+		}
+	}
+	if got, err := triageDecision(nil); got != nil || err != nil {
+		t.Fatalf("triageDecision(nil) = %v, %v; want nil", got, err)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// VerifierAgentName is the name of the Verifier Agent.
+const VerifierAgentName = "verifier"
+
+// Verdict markers ending the response of the Verifier Agent.
+const (
+	verdictPass = "<<<PASS>>>"
+	verdictFail = "<<<FAIL>>>"
+)
+
+// NewVerifierAgent creates a Verifier Agent that independently checks the answer the TUMIX Agent is about to finalize
+// (see [TumixConfig.Verifier]).
+//
+// With nativeCode, the agent re-derives the answer with the model's built-in code execution tool, which is the case
+// for Gemini; otherwise it re-derives it step by step in text.
+func NewVerifierAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, nativeCode bool) (agent.Agent, error) {
+	method := `Re-derive the answer yourself step by step, checking every computation and every claimed fact.`
+	genCfg = cloneGenConfig(genCfg)
+	if nativeCode {
+		method = `**Use the code execution tool** to re-derive the answer and to check every computation; each script must
+print its output.`
+		genCfg = withNativeTools(genCfg, &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}})
+	}
+
+	cfg := llmagent.Config{
+		Name:                  VerifierAgentName,
+		Description:           `Independently checks the proposed final answer before it is finalized.`,
+		Model:                 llm,
+		GenerateContentConfig: genCfg,
+		Instruction: `Task: Verify the proposed final answer to the question; do not trust it.
+
+Question:
+{question}
+
+Proposed final answer:
+{` + stateKeyVerifyAnswer + `}
+
+Instructions:
+1. ` + method + `
+2. Compare your result with the proposed answer; minor formatting differences do not matter.
+3. If the proposed answer is wrong or unsupported, report concisely which step fails and why.
+
+End with ` + code(verdictPass) + ` when the proposed answer is correct, else ` + code(verdictFail) + `.`,
+	}
+	// The verifier judges the answer on its own, without the candidates' shared context.
+	if prompt := takeSystemPrompt(&cfg); prompt != "" {
+		cfg.GlobalInstruction = prompt
+	}
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build Verifier agent: %w", err)
+	}
+
+	return a, nil
+}
+
+// verificationFailed reports whether the response of the Verifier Agent rejects the answer, going by the last verdict
+// marker in text. A response without a verdict does not reject the answer.
+func verificationFailed(text string) bool {
+	text = strings.ToUpper(text)
+	return strings.LastIndex(text, verdictFail) > strings.LastIndex(text, verdictPass)
+}
+
+// finalize emits the final answer from the session state unless the verifier rejects it, in which case the rejection
+// is recorded for the next round. It reports whether the run is over.
+func (t *tumixOrchestrator) finalize(ctx agent.InvocationContext, round uint, citations []Citation, yield func(*session.Event, error) bool) bool {
+	ok, stop := t.verify(ctx, round, yield)
+	if stop {
+		return true
+	}
+	if !ok {
+		return false
+	}
+	t.emitFinalFromState(ctx, citations, yield)
+	return true
+}
+
+// verify runs the verifier on the answer about to be finalized. It reports whether the answer passed, which it always
+// does without a verifier or a round left to fix it, and whether the run must stop.
+//
+// A rejected answer is dropped from the session state and the verifier's report takes its place in the shared context.
+func (t *tumixOrchestrator) verify(ctx agent.InvocationContext, round uint, yield func(*session.Event, error) bool) (ok, stop bool) {
+	if t.verifier == nil || round >= t.maxRounds {
+		return true, false
+	}
+
+	answer, err := stateAnswer(ctx)
+	if err != nil {
+		yield(nil, err)
+		return false, true
+	}
+	if answer == "" {
+		return true, false
+	}
+	if err := setState(ctx, stateKeyVerifyAnswer, answer); err != nil {
+		yield(nil, err)
+		return false, true
+	}
+
+	var report string
+	for event, err := range t.verifier.Run(ctx) {
+		if !yield(event, err) {
+			return false, true
+		}
+		if err != nil || event == nil || event.Partial || event.Content == nil {
+			continue
+		}
+		if text := candidateText(event.Content); text != "" {
+			report = text
+		}
+	}
+
+	if !verificationFailed(report) {
+		if err := setState(ctx, stateKeyVerificationReport, ""); err != nil {
+			yield(nil, err)
+			return false, true
+		}
+		return true, false
+	}
+
+	report = fmt.Sprintf("Round %d proposed %q, which the verifier rejected:\n%s", round, answer, strings.TrimSpace(report))
+	if err := setState(ctx, stateKeyVerificationReport, report); err != nil {
+		yield(nil, err)
+		return false, true
+	}
+	for _, key := range []string{stateKeyAnswer, stateKeyConfidence, stateKeyJudgeAnswer} {
+		if err := setState(ctx, key, nil); err != nil {
+			yield(nil, err)
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// stateAnswer returns the answer recorded in the session state, falling back to the Judge's recommendation.
+func stateAnswer(ctx agent.InvocationContext) (string, error) {
+	for _, key := range []string{stateKeyAnswer, stateKeyJudgeAnswer} {
+		val, err := getState(ctx, key)
+		if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+			return "", err
+		}
+		if val != nil {
+			if s := fmt.Sprintf("%v", val); s != "" {
+				return s, nil
+			}
+		}
+	}
+	return "", nil
+}
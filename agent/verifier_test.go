@@ -0,0 +1,220 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestVerificationFailed(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text string
+		want bool
+	}{
+		"pass":             {text: "checked: 42 <<<PASS>>>", want: false},
+		"fail":             {text: "step 2 is wrong <<<FAIL>>>", want: true},
+		"lower case":       {text: "<<<fail>>>", want: true},
+		"last verdict":     {text: "first <<<FAIL>>>, on second thought <<<PASS>>>", want: false},
+		"no verdict":       {text: "looks fine", want: false},
+		"empty":            {text: "", want: false},
+		"fail after pass":  {text: "<<<PASS>>> ... <<<FAIL>>>", want: true},
+		"answer in marker": {text: "<<<42>>>", want: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := verificationFailed(tt.text); got != tt.want {
+				t.Fatalf("verificationFailed(%q) = %t, want %t", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// reportingCandidate answers with its name and round, and records the verification report it was shown in each round.
+type reportingCandidate struct {
+	name string
+
+	mu      sync.Mutex
+	reports []string
+}
+
+func (c *reportingCandidate) agent() agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        c.name,
+		Description: "reporting candidate",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				round, err := ctx.Session().State().Get(stateKeyRound)
+				if err != nil {
+					yield(nil, fmt.Errorf("state round: %w", err))
+					return
+				}
+				report, err := ctx.Session().State().Get(stateKeyVerificationReport)
+				if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+					yield(nil, fmt.Errorf("state verification report: %w", err))
+					return
+				}
+				c.mu.Lock()
+				c.reports = append(c.reports, fmt.Sprint(report))
+				c.mu.Unlock()
+
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(fmt.Sprintf("%s-%v", c.name, round), genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+// stubVerifier rejects the first fails answers it is shown and accepts the rest.
+func stubVerifier(calls *atomic.Int64, fails int64) agent.Agent {
+	return mustAgent(agent.New(agent.Config{
+		Name:        VerifierAgentName,
+		Description: "stub verifier",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				answer, err := ctx.Session().State().Get(stateKeyVerifyAnswer)
+				if err != nil {
+					yield(nil, fmt.Errorf("state verify answer: %w", err))
+					return
+				}
+				text := fmt.Sprintf("%v checks out %s", answer, verdictPass)
+				if calls.Add(1) <= fails {
+					text = fmt.Sprintf("%v is off by one %s", answer, verdictFail)
+				}
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel)}
+				yield(ev, nil)
+			}
+		},
+	}))
+}
+
+func TestTumixVerifier(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		maxRounds  uint
+		fails      int64
+		wantCalls  int64
+		wantRounds int
+	}{
+		"accepted answer is finalized": {
+			maxRounds:  3,
+			wantCalls:  1,
+			wantRounds: 1,
+		},
+		"rejected answer forces another round": {
+			maxRounds:  3,
+			fails:      1,
+			wantCalls:  2,
+			wantRounds: 2,
+		},
+		"last round is not verified": {
+			maxRounds:  2,
+			fails:      5,
+			wantCalls:  1,
+			wantRounds: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			candidate := &reportingCandidate{name: "A"}
+			var calls atomic.Int64
+			loader, err := NewTumixAgentWithConfig(TumixConfig{
+				Candidates: []agent.Agent{candidate.agent()},
+				Judge:      stubJudge("41"),
+				Verifier:   stubVerifier(&calls, tt.fails),
+				MaxRounds:  tt.maxRounds,
+				MinRounds:  1,
+			})
+			if err != nil {
+				t.Fatalf("loader: %v", err)
+			}
+
+			ctx := t.Context()
+			svc := session.InMemoryService()
+			if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+			if err != nil {
+				t.Fatalf("runner: %v", err)
+			}
+			var final string
+			for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("q", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					t.Fatalf("run err: %v", err)
+				}
+				if event.Author == "tumix" {
+					final = firstTextFromContent(event.Content)
+				}
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("verifier calls = %d, want %d", got, tt.wantCalls)
+			}
+			if got := len(candidate.reports); got != tt.wantRounds {
+				t.Fatalf("candidate rounds = %d, want %d", got, tt.wantRounds)
+			}
+			if got := candidate.reports[0]; got != "<nil>" {
+				t.Fatalf("first round verification report = %q, want none", got)
+			}
+			if tt.fails > 0 {
+				// The round after a rejection sees the verifier's report.
+				if got := candidate.reports[1]; !strings.Contains(got, `Round 1 proposed "41"`) || !strings.Contains(got, "off by one") {
+					t.Fatalf("second round verification report = %q, want the rejection of round 1", got)
+				}
+			}
+			if !strings.Contains(final, "41") {
+				t.Fatalf("final answer = %q, want containing 41", final)
+			}
+		})
+	}
+}
+
+func TestNewTumixAgentVerifierRequiresTumixMode(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewTumixAgentWithConfig(TumixConfig{
+		Candidates: []agent.Agent{stubCandidate("A")},
+		Judge:      noOpJudge(),
+		Verifier:   stubVerifier(new(atomic.Int64), 0),
+		Mode:       ModeDebate,
+	})
+	if got, want := fmt.Sprint(err), `verifier requires mode "tumix"`; got != want {
+		t.Fatalf("error = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// NewVisionAgent creates a candidate agent that grounds its reasoning in the images and documents of the question,
+// such as charts, diagrams, plots, and scanned pages.
+//
+// It writes down what it reads from the visual content before reasoning, so the Judge can check the answer against
+// the extracted facts. The backend model must accept image input.
+func NewVisionAgent(llm model.LLM, genCfg *genai.GenerateContentConfig, p *AnswerProtocol) (agent.Agent, error) {
+	cfg := llmagent.Config{
+		Name: "vision",
+		Description: `Image-grounded reasoning over charts, diagrams, and documents attached to the question.
+- Short name: {V}`,
+		Model:                 llm,
+		GenerateContentConfig: cloneGenConfig(genCfg),
+		Instruction: `You are an expert at reading charts, plots, diagrams, geometry figures, tables, and scanned documents.
+The question comes with images or documents; answer from what they actually show.
+
+1. Under a "Visual analysis" heading, describe every attached image or document that matters for the question:
+   its type, titles, axes and units, legends, labels, and the exact values, positions, or relations you read.
+   Say so when a value can only be estimated or a detail is illegible instead of guessing.
+2. Under a "Reasoning" heading, solve the question step by step using only the facts listed in your analysis and
+   the question text.
+3. In the end of your response, output the final answer ` + p.Enclosure() + `.
+
+If no image or document is attached, say so in the analysis and answer from the question text alone.`,
+	}
+
+	applySharedContext(&cfg, p)
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build Vision agent: %w", err)
+	}
+
+	return a, nil
+}
+
+// HasVisualContent reports whether any of the contents carries an image or PDF part, inline or by URI, which is
+// what [NewVisionAgent] reasons over.
+func HasVisualContent(contents ...*genai.Content) bool {
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		for _, p := range c.Parts {
+			if p == nil {
+				continue
+			}
+			switch {
+			case p.InlineData != nil && isVisualMIME(p.InlineData.MIMEType):
+				return true
+			case p.FileData != nil && isVisualMIME(p.FileData.MIMEType):
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isVisualMIME(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/") || mimeType == "application/pdf"
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestHasVisualContent(t *testing.T) {
+	t.Parallel()
+
+	question := genai.NewContentFromText("What is the peak value?", genai.RoleUser)
+
+	tests := map[string]struct {
+		contents []*genai.Content
+		want     bool
+	}{
+		"none": {},
+		"text only": {
+			contents: []*genai.Content{question},
+		},
+		"text attachment": {
+			contents: []*genai.Content{question, {Parts: []*genai.Part{genai.NewPartFromBytes([]byte("x"), "text/plain")}}},
+		},
+		"nil entries": {
+			contents: []*genai.Content{nil, {Parts: []*genai.Part{nil}}},
+		},
+		"inline image": {
+			contents: []*genai.Content{question, {Parts: []*genai.Part{genai.NewPartFromBytes([]byte("png"), "image/png")}}},
+			want:     true,
+		},
+		"inline pdf": {
+			contents: []*genai.Content{{Parts: []*genai.Part{genai.NewPartFromBytes([]byte("pdf"), "application/pdf")}}},
+			want:     true,
+		},
+		"image uri": {
+			contents: []*genai.Content{{Parts: []*genai.Part{genai.NewPartFromURI("https://example.com/chart.jpg", "image/jpeg")}}},
+			want:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := HasVisualContent(tt.contents...); got != tt.want {
+				t.Fatalf("HasVisualContent() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoder for image token estimates
+	_ "image/jpeg" // register decoder for image token estimates
+	_ "image/png"  // register decoder for image token estimates
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// defaultMaxAttachBytes is the default total size limit of -attach files, the inline request limit of Gemini.
+const defaultMaxAttachBytes = 20 << 20
+
+// Rough per-attachment token estimates used by the prompt guards and the cost cap.
+const (
+	// imageTokensMax is the token cost of a large image after provider-side downscaling.
+	imageTokensMax = 1600
+	// imagePixelsPerToken approximates the image token cost of the Anthropic and OpenAI vision models.
+	imagePixelsPerToken = 750
+	// pdfTokensPerPage approximates the text and page image tokens of one PDF page.
+	pdfTokensPerPage = 1500
+)
+
+// loadAttachments reads the -attach files into inline data parts, failing when their total size exceeds maxBytes.
+func loadAttachments(paths []string, maxBytes int) ([]*genai.Part, error) {
+	parts := make([]*genai.Part, 0, len(paths))
+	total := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read attachment: %w", err)
+		}
+		total += len(data)
+		if maxBytes > 0 && total > maxBytes {
+			return nil, fmt.Errorf("attachments exceed max_attach_bytes %d at %s", maxBytes, path)
+		}
+		parts = append(parts, &genai.Part{InlineData: &genai.Blob{
+			MIMEType:    attachmentMIMEType(path, data),
+			Data:        data,
+			DisplayName: filepath.Base(path),
+		}})
+	}
+	return parts, nil
+}
+
+// attachmentMIMEType returns the media type of the attachment from its extension, sniffing the content otherwise.
+func attachmentMIMEType(path string, data []byte) string {
+	typ := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if typ == "" {
+		typ = http.DetectContentType(data)
+	}
+	if mediaType, _, err := mime.ParseMediaType(typ); err == nil {
+		return mediaType
+	}
+	return typ
+}
+
+// attachmentSupported reports whether backend accepts inline attachments of mimeType.
+func attachmentSupported(backend, mimeType string) bool {
+	isImage := strings.HasPrefix(mimeType, "image/")
+	isText := strings.HasPrefix(mimeType, "text/")
+	isPDF := mimeType == "application/pdf"
+	switch backend {
+	case "gemini":
+		return isImage || isText || isPDF || strings.HasPrefix(mimeType, "audio/") || strings.HasPrefix(mimeType, "video/")
+	case "openai":
+		return isImage || isText || isPDF
+	case "anthropic":
+		switch mimeType {
+		case "image/jpeg", "image/png", "image/gif", "image/webp":
+			return true
+		}
+		return isText || isPDF
+	case "xai":
+		return mimeType == "image/jpeg" || mimeType == "image/png" || isText
+	default:
+		return false
+	}
+}
+
+// checkAttachments fails when backend cannot take one of the attachments.
+func checkAttachments(backend string, parts []*genai.Part) error {
+	for _, p := range parts {
+		if !attachmentSupported(backend, p.InlineData.MIMEType) {
+			return fmt.Errorf("backend %s does not support %s attachments (%s)", backend, p.InlineData.MIMEType, p.InlineData.DisplayName)
+		}
+	}
+	return nil
+}
+
+// attachmentTokens estimates the input tokens of the attachments.
+func attachmentTokens(parts []*genai.Part) int {
+	tokens := 0
+	for _, p := range parts {
+		blob := p.InlineData
+		switch {
+		case strings.HasPrefix(blob.MIMEType, "text/"):
+			tokens += estimateTokensFromChars(len(blob.Data))
+		case blob.MIMEType == "application/pdf":
+			pages := bytes.Count(blob.Data, []byte("/Type /Page")) - bytes.Count(blob.Data, []byte("/Type /Pages"))
+			tokens += max(pages, 1) * pdfTokensPerPage
+		default:
+			tokens += imageTokens(blob.Data)
+		}
+	}
+	return tokens
+}
+
+// imageTokens estimates the tokens of an image from its dimensions, assuming the maximum when they are unknown.
+func imageTokens(data []byte) int {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return imageTokensMax
+	}
+	return min((cfg.Width*cfg.Height+imagePixelsPerToken-1)/imagePixelsPerToken, imageTokensMax)
+}
+
+// userContent returns the user turn of the run: the prompt text followed by the attachments.
+func userContent(cfg *config) *genai.Content {
+	content := genai.NewContentFromText(cfg.Prompt, genai.RoleUser)
+	content.Parts = append(content.Parts, cfg.attachments...)
+	return content
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func writeAttachment(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write attachment: %v", err)
+	}
+	return path
+}
+
+func TestLoadAttachments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	img := testPNG(t, 10, 10)
+	paths := []string{
+		writeAttachment(t, dir, "chart.PNG", img),
+		writeAttachment(t, dir, "notes.txt", []byte("notes")),
+		writeAttachment(t, dir, "scan", img),
+	}
+
+	got, err := loadAttachments(paths, 0)
+	if err != nil {
+		t.Fatalf("loadAttachments() error = %v", err)
+	}
+	want := []*genai.Part{
+		{InlineData: &genai.Blob{MIMEType: "image/png", Data: img, DisplayName: "chart.PNG"}},
+		{InlineData: &genai.Blob{MIMEType: "text/plain", Data: []byte("notes"), DisplayName: "notes.txt"}},
+		{InlineData: &genai.Blob{MIMEType: "image/png", Data: img, DisplayName: "scan"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("loadAttachments() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := loadAttachments(paths, len(img)+4); err == nil {
+		t.Fatal("loadAttachments() over max_attach_bytes error = nil, want error")
+	}
+	if _, err := loadAttachments([]string{filepath.Join(dir, "missing.png")}, 0); err == nil {
+		t.Fatal("loadAttachments() missing file error = nil, want error")
+	}
+}
+
+func TestCheckAttachments(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		backend  string
+		mimeType string
+		wantErr  bool
+	}{
+		"gemini audio":     {backend: "gemini", mimeType: "audio/mpeg"},
+		"openai pdf":       {backend: "openai", mimeType: "application/pdf"},
+		"openai audio":     {backend: "openai", mimeType: "audio/mpeg", wantErr: true},
+		"anthropic png":    {backend: "anthropic", mimeType: "image/png"},
+		"anthropic bmp":    {backend: "anthropic", mimeType: "image/bmp", wantErr: true},
+		"xai image":        {backend: "xai", mimeType: "image/jpeg"},
+		"xai gif":          {backend: "xai", mimeType: "image/gif", wantErr: true},
+		"xai pdf":          {backend: "xai", mimeType: "application/pdf", wantErr: true},
+		"any backend text": {backend: "xai", mimeType: "text/markdown"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			parts := []*genai.Part{{InlineData: &genai.Blob{MIMEType: tt.mimeType, DisplayName: "file"}}}
+			if err := checkAttachments(tt.backend, parts); (err != nil) != tt.wantErr {
+				t.Fatalf("checkAttachments(%s, %s) error = %v, wantErr %t", tt.backend, tt.mimeType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttachmentTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		blob *genai.Blob
+		want int
+	}{
+		"small image": {
+			blob: &genai.Blob{MIMEType: "image/png", Data: testPNG(t, 30, 50)},
+			want: 2,
+		},
+		"large image": {
+			blob: &genai.Blob{MIMEType: "image/png", Data: testPNG(t, 2000, 2000)},
+			want: imageTokensMax,
+		},
+		"undecodable image": {
+			blob: &genai.Blob{MIMEType: "image/webp", Data: []byte("webp")},
+			want: imageTokensMax,
+		},
+		"pdf pages": {
+			blob: &genai.Blob{MIMEType: "application/pdf", Data: []byte("<</Type /Pages>> <</Type /Page>> <</Type /Page>>")},
+			want: 2 * pdfTokensPerPage,
+		},
+		"text": {
+			blob: &genai.Blob{MIMEType: "text/plain", Data: []byte("12345678")},
+			want: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := attachmentTokens([]*genai.Part{{InlineData: tt.blob}}); got != tt.want {
+				t.Fatalf("attachmentTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserContent(t *testing.T) {
+	t.Parallel()
+
+	img := &genai.Part{InlineData: &genai.Blob{MIMEType: "image/png", Data: []byte("png")}}
+	cfg := &config{Prompt: "What is shown?", attachments: []*genai.Part{img}}
+
+	got := userContent(cfg)
+	want := &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{genai.NewPartFromText("What is shown?"), img}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("userContent() mismatch (-want +got):\n%s", diff)
+	}
+	if len(cfg.attachments) != 1 {
+		t.Fatalf("userContent() modified the attachments: %v", cfg.attachments)
+	}
+}
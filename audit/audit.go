@@ -0,0 +1,343 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package audit writes tamper-evident audit trails of TUMIX runs.
+//
+// Each run is recorded as an append-only JSONL file. Every record carries the SHA-256 hash of the previous record,
+// so removing, reordering, or editing a line breaks the chain, and is optionally signed with HMAC-SHA256.
+// Sensitive values are removed with a [Redactor] before they are written.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/session"
+
+	"github.com/zchee/tumix/internal/storage"
+)
+
+// Record kinds.
+const (
+	KindRunStart   = "run_start"
+	KindMessage    = "message"
+	KindToolCall   = "tool_call"
+	KindToolResult = "tool_result"
+	KindState      = "state"
+	KindRunEnd     = "run_end"
+)
+
+// Record is a single line of the audit trail.
+type Record struct {
+	Seq          uint64         `json:"seq"`
+	Time         time.Time      `json:"time"`
+	RunID        string         `json:"run_id"`
+	Kind         string         `json:"kind"`
+	Author       string         `json:"author,omitzero"`
+	InvocationID string         `json:"invocation_id,omitzero"`
+	Data         map[string]any `json:"data,omitzero"`
+	PrevHash     string         `json:"prev_hash"`
+	Hash         string         `json:"hash,omitzero"`
+	Signature    string         `json:"signature,omitzero"`
+}
+
+// digest returns the hex SHA-256 of the record without its hash and signature.
+func (r Record) digest() (string, error) {
+	r.Hash, r.Signature = "", ""
+	data, err := json.Marshal(r, json.Deterministic(true))
+	if err != nil {
+		return "", fmt.Errorf("marshal audit record: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// plainData converts data to its JSON form of maps, slices, strings, numbers, and booleans.
+//
+// This lets the redactor see inside arbitrary values, and makes the hashed form identical to what [Verify] decodes.
+func plainData(data map[string]any) (map[string]any, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal audit data: %w", err)
+	}
+	var plain map[string]any
+	if err := json.Unmarshal(raw, &plain); err != nil {
+		return nil, fmt.Errorf("unmarshal audit data: %w", err)
+	}
+	return plain, nil
+}
+
+func sign(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Option configures a [Logger].
+type Option func(*Logger)
+
+// WithKey signs every record with HMAC-SHA256 under key.
+func WithKey(key []byte) Option {
+	return func(l *Logger) { l.key = key }
+}
+
+// WithRedactor sets the redactor applied to record data.
+func WithRedactor(r *Redactor) Option {
+	return func(l *Logger) { l.redactor = r }
+}
+
+// Logger appends hash-chained records to the audit file of one run. It is safe for concurrent use.
+type Logger struct {
+	key      []byte
+	redactor *Redactor
+
+	path string
+
+	mu       sync.Mutex
+	f        *os.File
+	w        *bufio.Writer
+	runID    string
+	seq      uint64
+	prevHash string
+}
+
+// Open creates the audit file of run runID in dir.
+//
+// The file is named "<UTC time>-<runID>.jsonl" and created exclusively, so an existing trail is never appended to
+// or overwritten.
+func Open(dir, runID string, opts ...Option) (*Logger, error) {
+	d, err := storage.OpenDir(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+
+	name := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + sanitizeFileName(runID) + ".jsonl"
+	path, err := d.Join(name)
+	if err != nil {
+		return nil, fmt.Errorf("create audit file: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create audit file: %w", err)
+	}
+
+	l := &Logger{
+		path:  f.Name(),
+		f:     f,
+		w:     bufio.NewWriter(f),
+		runID: runID,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Path returns the path of the audit file.
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Log appends a record of kind with redacted data.
+func (l *Logger) Log(kind, author, invocationID string, data map[string]any) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return errors.New("audit logger is closed")
+	}
+
+	rec := Record{
+		Seq:          l.seq + 1,
+		Time:         time.Now().UTC(),
+		RunID:        l.runID,
+		Kind:         kind,
+		Author:       author,
+		InvocationID: invocationID,
+		PrevHash:     l.prevHash,
+	}
+	if len(data) > 0 {
+		plain, err := plainData(data)
+		if err != nil {
+			return err
+		}
+		rec.Data, _ = l.redactor.Value(plain).(map[string]any)
+	}
+
+	hash, err := rec.digest()
+	if err != nil {
+		return err
+	}
+	rec.Hash = hash
+	if len(l.key) > 0 {
+		rec.Signature = sign(l.key, hash)
+	}
+
+	line, err := json.Marshal(rec, json.Deterministic(true))
+	if err != nil {
+		return fmt.Errorf("marshal audit record: %w", err)
+	}
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit record: %w", err)
+	}
+	// Flush every record so a crash loses at most the record being written.
+	if err := l.w.Flush(); err != nil {
+		return fmt.Errorf("flush audit record: %w", err)
+	}
+
+	l.seq, l.prevHash = rec.Seq, hash
+	return nil
+}
+
+// LogEvent records the messages, tool calls, tool results, and state changes of a session event.
+//
+// Partial (streaming) events are skipped; their content is recorded by the final event.
+func (l *Logger) LogEvent(event *session.Event) error {
+	if event == nil || event.Partial {
+		return nil
+	}
+
+	var errs []error
+	logf := func(kind string, data map[string]any) {
+		if err := l.Log(kind, event.Author, event.InvocationID, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if event.Content != nil {
+		var texts []string
+		for _, p := range event.Content.Parts {
+			switch {
+			case p == nil || p.Thought:
+			case p.FunctionCall != nil:
+				logf(KindToolCall, map[string]any{
+					"id":   p.FunctionCall.ID,
+					"name": p.FunctionCall.Name,
+					"args": p.FunctionCall.Args,
+				})
+			case p.FunctionResponse != nil:
+				logf(KindToolResult, map[string]any{
+					"id":       p.FunctionResponse.ID,
+					"name":     p.FunctionResponse.Name,
+					"response": p.FunctionResponse.Response,
+				})
+			case p.Text != "":
+				texts = append(texts, p.Text)
+			}
+		}
+		if len(texts) > 0 {
+			logf(KindMessage, map[string]any{
+				"role": event.Content.Role,
+				"text": strings.Join(texts, "\n"),
+			})
+		}
+	}
+	if len(event.Actions.StateDelta) > 0 {
+		logf(KindState, map[string]any{"delta": event.Actions.StateDelta})
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close flushes and syncs the audit file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	f := l.f
+	l.f = nil
+
+	err := l.w.Flush()
+	err = errors.Join(err, f.Sync(), f.Close())
+	if err != nil {
+		return fmt.Errorf("close audit file: %w", err)
+	}
+	return nil
+}
+
+// Verify checks the hash chain of an audit trail and, when key is non-empty, every signature.
+//
+// It returns the number of valid records read before the first error.
+func Verify(r io.Reader, key []byte) (int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 64<<20)
+
+	var (
+		n        int
+		prevHash string
+	)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return n, fmt.Errorf("record %d: decode: %w", n+1, err)
+		}
+		switch {
+		case rec.Seq != uint64(n+1):
+			return n, fmt.Errorf("record %d: sequence number %d out of order", n+1, rec.Seq)
+		case rec.PrevHash != prevHash:
+			return n, fmt.Errorf("record %d: broken hash chain", n+1)
+		}
+		hash, err := rec.digest()
+		if err != nil {
+			return n, fmt.Errorf("record %d: %w", n+1, err)
+		}
+		if hash != rec.Hash {
+			return n, fmt.Errorf("record %d: hash mismatch", n+1)
+		}
+		if len(key) > 0 && !hmac.Equal([]byte(sign(key, hash)), []byte(rec.Signature)) {
+			return n, fmt.Errorf("record %d: invalid signature", n+1)
+		}
+		prevHash = rec.Hash
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return n, fmt.Errorf("read audit trail: %w", err)
+	}
+	return n, nil
+}
+
+// maxRunIDLen is the maximum length of the run ID in an audit file name, the file name limit less the time, the
+// separator, and the extension.
+const maxRunIDLen = storage.MaxNameLen - len("20060102T150405.000000000Z-.jsonl")
+
+func sanitizeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+	if s == "" {
+		return "run"
+	}
+	// Leave room for the time and the extension within the file name limit.
+	return s[:min(len(s), maxRunIDLen)]
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	json "encoding/json/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/internal/storage"
+)
+
+func writeTrail(t *testing.T, key []byte) (path string, lines [][]byte) {
+	t.Helper()
+
+	redactor, err := NewRedactor(DefaultPatterns, []string{"api_token"})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	l, err := Open(t.TempDir(), "session/1", WithKey(key), WithRedactor(redactor))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if err := l.Log(KindRunStart, "", "", map[string]any{"prompt": "mail me at alice@example.com"}); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	event := session.NewEvent("inv-1")
+	event.Author = "search"
+	event.Content = genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromText("looking it up"),
+		genai.NewPartFromFunctionCall("web_fetch", map[string]any{"url": "https://example.com", "api_token": "s3cret"}),
+	}, genai.RoleModel)
+	event.Actions.StateDelta = map[string]any{"api_token": "s3cret", "round": 1}
+	if err := l.LogEvent(event); err != nil {
+		t.Fatalf("LogEvent() error = %v", err)
+	}
+	if err := l.Log(KindRunEnd, "", "", map[string]any{"answer": "42"}); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(l.Path())
+	if err != nil {
+		t.Fatalf("read trail: %v", err)
+	}
+	return l.Path(), bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+}
+
+func TestLoggerWritesRedactedChain(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("audit-key")
+	path, lines := writeTrail(t, key)
+	if !strings.HasSuffix(path, "-session_1.jsonl") {
+		t.Fatalf("Path() = %q, want sanitized run id suffix", path)
+	}
+
+	var kinds []string
+	for _, line := range lines {
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("decode record: %v", err)
+		}
+		kinds = append(kinds, rec.Kind)
+		if rec.Signature == "" {
+			t.Fatalf("record %d is not signed", rec.Seq)
+		}
+	}
+	want := []string{KindRunStart, KindToolCall, KindMessage, KindState, KindRunEnd}
+	if diff := cmp.Diff(want, kinds); diff != "" {
+		t.Fatalf("record kinds mismatch (-want +got):\n%s", diff)
+	}
+
+	all := string(bytes.Join(lines, []byte("\n")))
+	for _, secret := range []string{"alice@example.com", "s3cret"} {
+		if strings.Contains(all, secret) {
+			t.Fatalf("audit trail leaks %q:\n%s", secret, all)
+		}
+	}
+
+	n, err := Verify(bytes.NewReader(bytes.Join(lines, []byte("\n"))), key)
+	if err != nil || n != len(lines) {
+		t.Fatalf("Verify() = (%d, %v), want (%d, nil)", n, err, len(lines))
+	}
+}
+
+func TestOpenLongRunID(t *testing.T) {
+	t.Parallel()
+
+	l, err := Open(t.TempDir(), strings.Repeat("r", 1000))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	if name := filepath.Base(l.Path()); len(name) > storage.MaxNameLen {
+		t.Fatalf("file name is %d bytes long, want at most %d", len(name), storage.MaxNameLen)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("audit-key")
+	_, lines := writeTrail(t, key)
+
+	tests := map[string]struct {
+		tamper  func([][]byte) [][]byte
+		key     []byte
+		wantErr string
+	}{
+		"edited record": {
+			tamper: func(l [][]byte) [][]byte {
+				l[4] = bytes.Replace(l[4], []byte(`"42"`), []byte(`"43"`), 1)
+				return l
+			},
+			key:     key,
+			wantErr: "record 5: hash mismatch",
+		},
+		"removed record": {
+			tamper:  func(l [][]byte) [][]byte { return append(l[:1], l[2:]...) },
+			key:     key,
+			wantErr: "record 2: sequence number 3 out of order",
+		},
+		"wrong key": {
+			tamper:  func(l [][]byte) [][]byte { return l },
+			key:     []byte("other-key"),
+			wantErr: "record 1: invalid signature",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cloned := make([][]byte, len(lines))
+			for i, l := range lines {
+				cloned[i] = bytes.Clone(l)
+			}
+			_, err := Verify(bytes.NewReader(bytes.Join(tt.tamper(cloned), []byte("\n"))), tt.key)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedactorValue(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRedactor(slices.Concat(DefaultPatterns, []string{PhonePattern}), []string{"password"})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+
+	got := r.Value(map[string]any{
+		"text":     "call +1 415-555-0100 or use sk-abcdefghijklmnopqrstu",
+		"password": map[string]any{"nested": "value"},
+		"list":     []any{"bob@example.org", 3.0},
+	})
+	want := map[string]any{
+		"text":     "call " + Redacted + " or use " + Redacted,
+		"password": Redacted,
+		"list":     []any{Redacted, 3.0},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Value() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewRedactor([]string{"("}, nil); err == nil {
+		t.Fatal("NewRedactor() with invalid pattern error = nil, want error")
+	}
+}
+
+func TestRedactorString(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		patterns []string
+		in       string
+		want     string
+	}{
+		"card number": {
+			patterns: DefaultPatterns,
+			in:       "card 4111 1111 1111 1111 on file",
+			want:     "card " + Redacted + " on file",
+		},
+		"numeric answer failing the Luhn check": {
+			patterns: DefaultPatterns,
+			in:       "The answer is 1234567890123456.",
+			want:     "The answer is 1234567890123456.",
+		},
+		"grouped numeric answer": {
+			patterns: DefaultPatterns,
+			in:       "order 123 456 7890 shipped",
+			want:     "order 123 456 7890 shipped",
+		},
+		"phone number opted in": {
+			patterns: []string{PhonePattern},
+			in:       "call +1 415-555-0100 today",
+			want:     "call " + Redacted + " today",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := NewRedactor(tt.patterns, nil)
+			if err != nil {
+				t.Fatalf("NewRedactor() error = %v", err)
+			}
+			if got := r.String(tt.in); got != tt.want {
+				t.Fatalf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces every redacted value in the audit trail.
+const Redacted = "[REDACTED]"
+
+const (
+	// CardPattern matches payment card numbers. A match is only redacted when its digits pass the Luhn check, so
+	// long numeric answers and IDs mostly survive.
+	CardPattern = `\b\d(?:[ -]?\d){12,15}\b`
+	// PhonePattern matches phone numbers. It is not one of the [DefaultPatterns] because it also matches numeric
+	// answers and IDs written in groups; add it to a patterns file to redact phone numbers.
+	PhonePattern = `\+?\d{1,3}[ .-]?\(?\d{2,4}\)?[ .-]\d{3,4}[ .-]\d{3,4}`
+)
+
+// DefaultPatterns match common PII and secrets: email addresses, payment card numbers (see [CardPattern]), US social
+// security numbers, bearer tokens, and well-known API key formats.
+var DefaultPatterns = []string{
+	`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	CardPattern,
+	`\b\d{3}-\d{2}-\d{4}\b`,
+	`(?i)bearer\s+[A-Za-z0-9._~+/-]+=*`,
+	`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}\b`,
+	`\bAIza[0-9A-Za-z_-]{35}\b`,
+	`\bxai-[A-Za-z0-9]{20,}\b`,
+}
+
+// Redactor removes sensitive data from audit records.
+//
+// String values matching any pattern have the match replaced with [Redacted]. Values stored under one of the
+// redacted keys, such as session state keys or tool argument names, are replaced as a whole regardless of content.
+type Redactor struct {
+	patterns []redactPattern
+	keys     map[string]bool
+}
+
+// redactPattern is a compiled pattern whose matches are redacted when valid accepts them, or always when valid is nil.
+type redactPattern struct {
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// NewRedactor compiles patterns and returns a Redactor also redacting the values of keys.
+//
+// Matches of [CardPattern] are only redacted when they pass the Luhn check.
+func NewRedactor(patterns, keys []string) (*Redactor, error) {
+	r := &Redactor{keys: make(map[string]bool, len(keys))}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("compile redaction pattern %q: %w", p, err)
+		}
+		rp := redactPattern{re: re}
+		if p == CardPattern {
+			rp.valid = luhn
+		}
+		r.patterns = append(r.patterns, rp)
+	}
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			r.keys[k] = true
+		}
+	}
+	return r, nil
+}
+
+// LoadPatterns reads redaction patterns from a file, one regular expression per line.
+//
+// Blank lines and lines starting with '#' are ignored.
+func LoadPatterns(path string) ([]string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("open redaction patterns: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read redaction patterns: %w", err)
+	}
+	return patterns, nil
+}
+
+// String redacts pattern matches in s.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, p := range r.patterns {
+		if p.valid == nil {
+			s = p.re.ReplaceAllString(s, Redacted)
+			continue
+		}
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.valid(match) {
+				return Redacted
+			}
+			return match
+		})
+	}
+	return s
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of payment card numbers.
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// Value returns a redacted deep copy of v.
+//
+// Maps and slices are walked recursively; map entries under a redacted key are replaced entirely.
+func (r *Redactor) Value(v any) any {
+	if r == nil {
+		return v
+	}
+
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if r.keys[k] {
+				out[k] = Redacted
+				continue
+			}
+			out[k] = r.Value(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = r.Value(val)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, val := range v {
+			out[i] = r.String(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
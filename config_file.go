@@ -0,0 +1,326 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	tumixagent "github.com/zchee/tumix/agent"
+)
+
+// fileConfig is the typed layout of a -config file.
+//
+// Every field is optional; unset fields keep the built-in default. Environment variables and flags override the file.
+// API keys are deliberately not part of the file and are only read from the environment or -api_key.
+type fileConfig struct {
+	Model     fileModel     `yaml:"model"`
+	Agents    fileAgents    `yaml:"agents"`
+	Budget    fileBudget    `yaml:"budget"`
+	Batch     fileBatch     `yaml:"batch"`
+	Quota     fileQuota     `yaml:"quota"`
+	Output    fileOutput    `yaml:"output"`
+	Telemetry fileTelemetry `yaml:"telemetry"`
+	Session   fileSession   `yaml:"session"`
+}
+
+type fileModel struct {
+	Backend     *string  `yaml:"backend"`
+	Name        *string  `yaml:"name"`
+	JudgeModel  *string  `yaml:"judge_model"`
+	Failover    *string  `yaml:"failover"`
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+	TopK        *int     `yaml:"top_k"`
+	MaxTokens   *int     `yaml:"max_tokens"`
+	Seed        *int64   `yaml:"seed"`
+	XAIRPS      *float64 `yaml:"xai_rps"`
+	XAIBurst    *int     `yaml:"xai_burst"`
+	Catalog     *bool    `yaml:"model_catalog"`
+	Logprobs    *bool    `yaml:"logprobs"`
+	Preflight   *bool    `yaml:"preflight"`
+}
+
+type fileAgents struct {
+	MaxRounds        *uint          `yaml:"max_rounds"`
+	MinRounds        *uint          `yaml:"min_rounds"`
+	Mode             *string        `yaml:"mode"`
+	Verify           *bool          `yaml:"verify"`
+	Triage           *string        `yaml:"triage"`
+	TriageCandidates *int           `yaml:"triage_candidates"`
+	RoundTimeout     *time.Duration `yaml:"round_timeout"`
+	RunTimeout       *time.Duration `yaml:"run_timeout"`
+	AutoAgents       *int           `yaml:"auto_agents"`
+	SamplesPerAgent  *uint          `yaml:"samples_per_agent"`
+	DedupRequests    *bool          `yaml:"dedup_requests"`
+	Hierarchical     *bool          `yaml:"hierarchical"`
+	MaxSubTasks      *int           `yaml:"max_subtasks"`
+	SubTaskRounds    *uint          `yaml:"subtask_rounds"`
+	Workflow         *string        `yaml:"workflow"`
+	CompressPrompt   *bool          `yaml:"compress_prompt"`
+	CompressTokens   *int           `yaml:"compress_threshold_tokens"`
+	SummarizeAnswers *string        `yaml:"summarize_answers"`
+	SummaryTokens    *int           `yaml:"summary_answer_tokens"`
+	ReformatAnswers  *bool          `yaml:"reformat_answers"`
+	AnswerProtocol   *string        `yaml:"answer_protocol"`
+	StateMerge       *string        `yaml:"state_merge"`
+	SystemPrompt     *string        `yaml:"system_prompt"`
+	SystemPromptFile *string        `yaml:"system_prompt_file"`
+	MCPConfig        *string        `yaml:"mcp_config"`
+	WebFetch         *bool          `yaml:"webfetch"`
+	Python           *bool          `yaml:"python"`
+	A2AAgents        *string        `yaml:"a2a_agents"`
+}
+
+type fileBudget struct {
+	MaxCostUSD      *float64 `yaml:"max_cost_usd"`
+	BudgetTokens    *int     `yaml:"budget_tokens"`
+	MaxPromptChars  *int     `yaml:"max_prompt_chars"`
+	MaxPromptTokens *int     `yaml:"max_prompt_tokens"`
+	MaxAttachBytes  *int     `yaml:"max_attach_bytes"`
+	CallWarn        *int     `yaml:"call_warn"`
+}
+
+type fileBatch struct {
+	File            *string `yaml:"file"`
+	Concurrency     *int    `yaml:"concurrency"`
+	MaxRetries      *int    `yaml:"max_retries"`
+	ContinueOnError *bool   `yaml:"continue_on_error"`
+	Adaptive        *bool   `yaml:"adaptive"`
+	Isolate         *bool   `yaml:"isolate"`
+}
+
+type fileQuota struct {
+	RequestsPerDay *int64 `yaml:"requests_per_day"`
+	TokensPerDay   *int64 `yaml:"tokens_per_day"`
+	MaxConcurrent  *int   `yaml:"max_concurrent"`
+}
+
+type fileOutput struct {
+	JSON     *bool `yaml:"json"`
+	Stream   *bool `yaml:"stream"`
+	Progress *bool `yaml:"progress"`
+	Explain  *bool `yaml:"explain"`
+}
+
+type fileTelemetry struct {
+	LogJSON          *bool   `yaml:"log_json"`
+	HTTPTrace        *bool   `yaml:"http_trace"`
+	OTLPEndpoint     *string `yaml:"otlp_endpoint"`
+	Observability    *string `yaml:"observability"`
+	ObservabilityURL *string `yaml:"observability_endpoint"`
+	MetricsAddr      *string `yaml:"metrics_addr"`
+	RunLabels        *string `yaml:"run_labels"`
+	AuditDir         *string `yaml:"audit_dir"`
+	AuditRedactKeys  *string `yaml:"audit_redact_keys"`
+	AuditPatterns    *string `yaml:"audit_redact_patterns"`
+}
+
+type fileSession struct {
+	AppName    *string        `yaml:"app_name"`
+	User       *string        `yaml:"user"`
+	ID         *string        `yaml:"id"`
+	Dir        *string        `yaml:"dir"`
+	Sync       *string        `yaml:"sync"`
+	MaxAge     *time.Duration `yaml:"max_age"`
+	MaxPerUser *int           `yaml:"max_per_user"`
+	MaxBytes   *int64         `yaml:"max_bytes"`
+	GCDryRun   *bool          `yaml:"gc_dry_run"`
+	GCInterval *time.Duration `yaml:"gc_interval"`
+}
+
+// defaultConfig returns the built-in defaults, the bottom layer below the config file, environment, and flags.
+func defaultConfig() config {
+	return config{
+		AppName:          "tumix",
+		LLMBackend:       "gemini",
+		ModelName:        "gemini-2.5-flash",
+		UserID:           "user",
+		MaxRounds:        3,
+		MinRounds:        2,
+		Mode:             string(tumixagent.ModeTumix),
+		Temperature:      -1,
+		TopP:             -1,
+		CallWarn:         300,
+		Concurrency:      1,
+		TriageCandidates: tumixagent.DefaultEasyCandidates,
+		MaxPromptChars:   8000,
+		MaxAttachBytes:   defaultMaxAttachBytes,
+		CompressTokens:   tumixagent.DefaultCompressionThresholdTokens,
+		SummaryTokens:    256,
+		MaxCostUSD:       0.01,
+		SamplesPerAgent:  1,
+		MaxSubTasks:      4,
+		SubTaskRounds:    2,
+	}
+}
+
+// loadConfigFile reads the YAML (or JSON) config file at path, rejecting unknown keys.
+func loadConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var fc fileConfig
+	if err := yaml.UnmarshalWithOptions(data, &fc, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return &fc, nil
+}
+
+// apply overlays the values set in fc onto cfg.
+func (fc *fileConfig) apply(cfg *config) {
+	set(&cfg.LLMBackend, fc.Model.Backend)
+	set(&cfg.ModelName, fc.Model.Name)
+	set(&cfg.JudgeModel, fc.Model.JudgeModel)
+	set(&cfg.Failover, fc.Model.Failover)
+	set(&cfg.Temperature, fc.Model.Temperature)
+	set(&cfg.TopP, fc.Model.TopP)
+	set(&cfg.TopK, fc.Model.TopK)
+	set(&cfg.MaxTokens, fc.Model.MaxTokens)
+	set(&cfg.Seed, fc.Model.Seed)
+	set(&cfg.XAIRPS, fc.Model.XAIRPS)
+	set(&cfg.XAIBurst, fc.Model.XAIBurst)
+	set(&cfg.ModelCatalog, fc.Model.Catalog)
+	set(&cfg.Logprobs, fc.Model.Logprobs)
+	set(&cfg.Preflight, fc.Model.Preflight)
+
+	set(&cfg.MaxRounds, fc.Agents.MaxRounds)
+	set(&cfg.MinRounds, fc.Agents.MinRounds)
+	set(&cfg.Mode, fc.Agents.Mode)
+	set(&cfg.Verify, fc.Agents.Verify)
+	set(&cfg.Triage, fc.Agents.Triage)
+	set(&cfg.TriageCandidates, fc.Agents.TriageCandidates)
+	set(&cfg.RoundTimeout, fc.Agents.RoundTimeout)
+	set(&cfg.RunTimeout, fc.Agents.RunTimeout)
+	set(&cfg.AutoAgents, fc.Agents.AutoAgents)
+	set(&cfg.SamplesPerAgent, fc.Agents.SamplesPerAgent)
+	set(&cfg.DedupRequests, fc.Agents.DedupRequests)
+	set(&cfg.Hierarchical, fc.Agents.Hierarchical)
+	set(&cfg.MaxSubTasks, fc.Agents.MaxSubTasks)
+	set(&cfg.SubTaskRounds, fc.Agents.SubTaskRounds)
+	set(&cfg.Workflow, fc.Agents.Workflow)
+	set(&cfg.CompressPrompt, fc.Agents.CompressPrompt)
+	set(&cfg.CompressTokens, fc.Agents.CompressTokens)
+	set(&cfg.SummarizeAnswers, fc.Agents.SummarizeAnswers)
+	set(&cfg.SummaryTokens, fc.Agents.SummaryTokens)
+	set(&cfg.ReformatAnswers, fc.Agents.ReformatAnswers)
+	set(&cfg.AnswerProtocol, fc.Agents.AnswerProtocol)
+	set(&cfg.StateMerge, fc.Agents.StateMerge)
+	set(&cfg.SystemPrompt, fc.Agents.SystemPrompt)
+	set(&cfg.SystemPromptFile, fc.Agents.SystemPromptFile)
+	set(&cfg.MCPConfig, fc.Agents.MCPConfig)
+	set(&cfg.WebFetch, fc.Agents.WebFetch)
+	set(&cfg.Python, fc.Agents.Python)
+	set(&cfg.A2AAgents, fc.Agents.A2AAgents)
+
+	set(&cfg.MaxCostUSD, fc.Budget.MaxCostUSD)
+	set(&cfg.BudgetTokens, fc.Budget.BudgetTokens)
+	set(&cfg.MaxPromptChars, fc.Budget.MaxPromptChars)
+	set(&cfg.MaxPromptTokens, fc.Budget.MaxPromptTokens)
+	set(&cfg.MaxAttachBytes, fc.Budget.MaxAttachBytes)
+	set(&cfg.CallWarn, fc.Budget.CallWarn)
+
+	set(&cfg.BatchFile, fc.Batch.File)
+	set(&cfg.Concurrency, fc.Batch.Concurrency)
+	set(&cfg.BatchMaxRetries, fc.Batch.MaxRetries)
+	set(&cfg.BatchContinue, fc.Batch.ContinueOnError)
+	set(&cfg.BatchAdaptive, fc.Batch.Adaptive)
+	set(&cfg.BatchIsolate, fc.Batch.Isolate)
+
+	set(&cfg.QuotaRequests, fc.Quota.RequestsPerDay)
+	set(&cfg.QuotaTokens, fc.Quota.TokensPerDay)
+	set(&cfg.QuotaConcurrent, fc.Quota.MaxConcurrent)
+
+	set(&cfg.OutputJSON, fc.Output.JSON)
+	set(&cfg.Stream, fc.Output.Stream)
+	set(&cfg.Progress, fc.Output.Progress)
+	set(&cfg.Explain, fc.Output.Explain)
+
+	set(&cfg.LogJSON, fc.Telemetry.LogJSON)
+	set(&cfg.TraceHTTP, fc.Telemetry.HTTPTrace)
+	set(&cfg.OTLPEndpoint, fc.Telemetry.OTLPEndpoint)
+	set(&cfg.Observability, fc.Telemetry.Observability)
+	set(&cfg.ObservabilityURL, fc.Telemetry.ObservabilityURL)
+	set(&cfg.MetricsAddr, fc.Telemetry.MetricsAddr)
+	set(&cfg.RunLabels, fc.Telemetry.RunLabels)
+	set(&cfg.AuditDir, fc.Telemetry.AuditDir)
+	set(&cfg.AuditRedactKeys, fc.Telemetry.AuditRedactKeys)
+	set(&cfg.AuditPatterns, fc.Telemetry.AuditPatterns)
+
+	set(&cfg.AppName, fc.Session.AppName)
+	set(&cfg.UserID, fc.Session.User)
+	set(&cfg.SessionID, fc.Session.ID)
+	set(&cfg.SessionDir, fc.Session.Dir)
+	set(&cfg.SessionSync, fc.Session.Sync)
+	set(&cfg.SessionMaxAge, fc.Session.MaxAge)
+	set(&cfg.SessionMaxUser, fc.Session.MaxPerUser)
+	set(&cfg.SessionMaxBytes, fc.Session.MaxBytes)
+	set(&cfg.SessionGCDryRun, fc.Session.GCDryRun)
+	set(&cfg.SessionGCEvery, fc.Session.GCInterval)
+}
+
+func set[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// configFilePath returns the -config flag value from args, scanned ahead of flag parsing because the file supplies
+// the flag defaults, falling back to TUMIX_CONFIG.
+func configFilePath(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv("TUMIX_CONFIG")
+}
+
+// runConfigValidate runs "tumix config validate [flags]", which resolves the configuration like a run would and
+// prints it without requiring a prompt or API key.
+func runConfigValidate(args []string) int {
+	os.Args = append([]string{os.Args[0]}, args...)
+	cfg, err := loadConfig(true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 2
+	}
+	if err := printConfig(&cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "print config: %v\n", err)
+		return 1
+	}
+	return 0
+}
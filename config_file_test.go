@@ -0,0 +1,236 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testConfigYAML = `
+model:
+  backend: openai
+  name: gpt-5-mini
+  temperature: 0.3
+agents:
+  max_rounds: 5
+  samples_per_agent: 2
+  run_timeout: 2m
+budget:
+  max_cost_usd: 0.5
+output:
+  stream: false
+session:
+  user: alice
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tumix.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func withArgs(t *testing.T, args ...string) {
+	t.Helper()
+
+	origArgs := os.Args
+	origFlag := flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flag.CommandLine.SetOutput(os.Stdout)
+	os.Args = args
+	t.Cleanup(func() {
+		os.Args = origArgs
+		flag.CommandLine = origFlag
+	})
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	path := writeConfigFile(t, testConfigYAML)
+
+	type resolved struct {
+		LLMBackend      string
+		ModelName       string
+		Temperature     float64
+		MaxRounds       uint
+		SamplesPerAgent uint
+		MaxCostUSD      float64
+		Stream          bool
+		UserID          string
+		CallWarn        int
+		RunTimeout      time.Duration
+	}
+	fromFile := resolved{
+		LLMBackend:      "openai",
+		ModelName:       "gpt-5-mini",
+		Temperature:     0.3,
+		MaxRounds:       5,
+		SamplesPerAgent: 2,
+		MaxCostUSD:      0.5,
+		Stream:          false,
+		UserID:          "alice",
+		CallWarn:        300,
+		RunTimeout:      2 * time.Minute,
+	}
+
+	tests := map[string]struct {
+		args []string
+		env  map[string]string
+		want func(r resolved) resolved
+	}{
+		"file": {
+			args: []string{"cmd", "-config", path, "hello"},
+			want: func(r resolved) resolved { return r },
+		},
+		"file from env": {
+			args: []string{"cmd", "hello"},
+			env:  map[string]string{"TUMIX_CONFIG": path},
+			want: func(r resolved) resolved { return r },
+		},
+		"env overrides file": {
+			args: []string{"cmd", "-config=" + path, "hello"},
+			env:  map[string]string{"TUMIX_MODEL": "gpt-5", "TUMIX_MAX_ROUNDS": "4", "TUMIX_RUN_TIMEOUT": "90s"},
+			want: func(r resolved) resolved {
+				r.ModelName = "gpt-5"
+				r.MaxRounds = 4
+				r.RunTimeout = 90 * time.Second
+				return r
+			},
+		},
+		"flags override env and file": {
+			args: []string{"cmd", "-model", "gpt-5-nano", "--config", path, "-max_rounds=6", "-stream", "hello"},
+			env:  map[string]string{"TUMIX_MODEL": "gpt-5", "TUMIX_MAX_ROUNDS": "4"},
+			want: func(r resolved) resolved {
+				r.ModelName = "gpt-5-nano"
+				r.MaxRounds = 6
+				r.Stream = true
+				return r
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			withArgs(t, tt.args...)
+			t.Setenv("OPENAI_API_KEY", "key")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := parseConfig()
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+			if cfg.ConfigFile != path {
+				t.Fatalf("ConfigFile = %q, want %q", cfg.ConfigFile, path)
+			}
+			got := resolved{
+				LLMBackend:      cfg.LLMBackend,
+				ModelName:       cfg.ModelName,
+				Temperature:     cfg.Temperature,
+				MaxRounds:       cfg.MaxRounds,
+				SamplesPerAgent: cfg.SamplesPerAgent,
+				MaxCostUSD:      cfg.MaxCostUSD,
+				Stream:          cfg.Stream,
+				UserID:          cfg.UserID,
+				CallWarn:        cfg.CallWarn,
+				RunTimeout:      cfg.RunTimeout,
+			}
+			if diff := cmp.Diff(tt.want(fromFile), got); diff != "" {
+				t.Fatalf("resolved config mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		missing bool
+	}{
+		"unknown section": {content: "models:\n  name: x\n"},
+		"unknown key":     {content: "model:\n  api_key: secret\n"},
+		"wrong type":      {content: "agents:\n  max_rounds: many\n"},
+		"missing file":    {missing: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.yaml")
+			if !tt.missing {
+				path = writeConfigFile(t, tt.content)
+			}
+			withArgs(t, "cmd", "-config", path, "hello")
+
+			if _, err := parseConfig(); err == nil {
+				t.Fatalf("parseConfig() error = nil, want error for %s", name)
+			}
+		})
+	}
+}
+
+func TestLoadConfigValidateOnly(t *testing.T) {
+	withArgs(t, "cmd", "-config", writeConfigFile(t, testConfigYAML))
+	t.Setenv("OPENAI_API_KEY", "")
+
+	cfg, err := loadConfig(true)
+	if err != nil {
+		t.Fatalf("loadConfig(true) error = %v", err)
+	}
+	if cfg.LLMBackend != "openai" || cfg.Prompt != "" {
+		t.Fatalf("loadConfig(true) = backend %q prompt %q, want openai and no prompt", cfg.LLMBackend, cfg.Prompt)
+	}
+
+	withArgs(t, "cmd", "-config", writeConfigFile(t, "model:\n  backend: bad\n"))
+	if _, err := loadConfig(true); err == nil {
+		t.Fatal("loadConfig(true) error = nil, want invalid backend error")
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		env  string
+		want string
+	}{
+		"none":            {args: []string{"-model", "x", "hi"}},
+		"separate value":  {args: []string{"-config", "a.yaml", "hi"}, want: "a.yaml"},
+		"equals":          {args: []string{"--config=a.yaml"}, want: "a.yaml"},
+		"after flags":     {args: []string{"-backend", "xai", "-config", "a.yaml", "hi"}, want: "a.yaml"},
+		"env fallback":    {args: []string{"hi"}, env: "b.yaml", want: "b.yaml"},
+		"flag beats env":  {args: []string{"-config=a.yaml"}, env: "b.yaml", want: "a.yaml"},
+		"after separator": {args: []string{"--", "-config", "a.yaml"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("TUMIX_CONFIG", tt.env)
+
+			if got := configFilePath(tt.args); got != tt.want {
+				t.Fatalf("configFilePath(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm/xai"
+)
+
+// doctorTimeout bounds each check of "tumix doctor".
+const doctorTimeout = 10 * time.Second
+
+// Statuses of a doctorCheck.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+// doctorCheck is the result of one check of "tumix doctor".
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitzero"`
+	// Hint is how to fix a failed or warned check.
+	Hint string `json:"hint,omitzero"`
+}
+
+// doctorReport is the output of "tumix doctor".
+type doctorReport struct {
+	OK     bool          `json:"ok"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+// doctor runs the checks of "tumix doctor". The functions are replaced in tests.
+type doctor struct {
+	dial func(ctx context.Context, addr string) error
+	// auth makes the cheapest authenticated call of backend with apiKey, returning a description of the key.
+	auth func(ctx context.Context, backend, apiKey string) (string, error)
+	// grpc waits until the gRPC connections to xAI are ready.
+	grpc func(ctx context.Context, apiKey string) error
+	// environ is the environment checked, in the form of [os.Environ].
+	environ []string
+}
+
+// newDoctor returns the doctor calling the backends with httpClient.
+func newDoctor(httpClient *http.Client) *doctor {
+	return &doctor{
+		dial: func(ctx context.Context, addr string) error {
+			return dialCheck(addr)(ctx)
+		},
+		auth: func(ctx context.Context, backend, apiKey string) (string, error) {
+			return authCheck(ctx, backend, apiKey, httpClient)
+		},
+		grpc: func(ctx context.Context, apiKey string) error {
+			// The channels connect without a valid key; a placeholder lets the check run without one.
+			client, err := xai.NewClient(cmp.Or(apiKey, "doctor"))
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+			return client.Healthy(ctx)
+		},
+		environ: os.Environ(),
+	}
+}
+
+// runDoctor implements "tumix doctor [flags]", which checks the setup of the run the flags describe and prints a report
+// with remediation hints, as JSON with -json. It exits with 1 if any check fails.
+func runDoctor(args []string) int {
+	os.Args = append([]string{os.Args[0]}, args...)
+	cfg, err := loadConfig(true)
+	if err != nil {
+		report := doctorReport{Checks: []doctorCheck{{
+			Name:   "config",
+			Status: doctorFail,
+			Detail: err.Error(),
+			Hint:   "fix the flag, environment variable, or -config file named in the error; see tumix -help",
+		}}}
+		return writeDoctorReport(os.Stdout, os.Stderr, &report, cfg.OutputJSON)
+	}
+	report := newDoctor(newHTTPClient(cfg.TraceHTTP)).run(context.Background(), &cfg)
+	return writeDoctorReport(os.Stdout, os.Stderr, report, cfg.OutputJSON)
+}
+
+// run checks the configuration and environment, the API key of and connection to every backend, the OTLP endpoint,
+// and the session store of cfg. The network checks run concurrently; the report keeps their order.
+func (d *doctor) run(ctx context.Context, cfg *config) *doctorReport {
+	checks := []func(context.Context) doctorCheck{
+		func(context.Context) doctorCheck { return configCheck(cfg) },
+		func(context.Context) doctorCheck { return envCheck(d.environ) },
+	}
+
+	backends := []string{cfg.LLMBackend}
+	if targets, err := parseFailover(cfg.Failover); err == nil {
+		for _, t := range targets {
+			if !slices.Contains(backends, t.backend) {
+				backends = append(backends, t.backend)
+			}
+		}
+	}
+	for _, backend := range backends {
+		apiKey := backendAPIKey(backend)
+		if backend == cfg.LLMBackend {
+			apiKey = cfg.APIKey
+		}
+		checks = append(checks,
+			func(ctx context.Context) doctorCheck {
+				return d.apiKeyCheck(ctx, backend, apiKey, backend == cfg.LLMBackend)
+			},
+			func(ctx context.Context) doctorCheck { return d.connectCheck(ctx, backend, apiKey) },
+		)
+	}
+
+	checks = append(checks,
+		func(ctx context.Context) doctorCheck { return d.otlpCheck(ctx, cfg.OTLPEndpoint) },
+		func(context.Context) doctorCheck {
+			return sessionStoreCheck(cfg.SessionDir, os.Getenv("TUMIX_SESSION_SQLITE"))
+		},
+	)
+
+	report := &doctorReport{OK: true, Checks: make([]doctorCheck, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+			defer cancel()
+			report.Checks[i] = check(ctx)
+		})
+	}
+	wg.Wait()
+	for _, c := range report.Checks {
+		if c.Status == doctorFail {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func configCheck(cfg *config) doctorCheck {
+	c := doctorCheck{Name: "config", Status: doctorOK, Detail: "defaults, environment, and flags are valid"}
+	if cfg.ConfigFile != "" {
+		c.Detail = cfg.ConfigFile + " is valid"
+	}
+	return c
+}
+
+// envCheck reports the TUMIX_* variables set in environ, and fails on those ignored because their values do not parse.
+func envCheck(environ []string) doctorCheck {
+	var set, invalid []string
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "TUMIX_") {
+			continue
+		}
+		set = append(set, name)
+		if raw, ok := invalidEnv.Load(name); ok && raw == value {
+			invalid = append(invalid, fmt.Sprintf("%s=%q", name, value))
+		}
+	}
+	slices.Sort(set)
+	slices.Sort(invalid)
+
+	if len(invalid) > 0 {
+		return doctorCheck{
+			Name:   "environment",
+			Status: doctorFail,
+			Detail: "ignored invalid values: " + strings.Join(invalid, ", "),
+			Hint:   "set a number, boolean (true/false), or duration (e.g. 30s) as the variable expects, or unset it",
+		}
+	}
+	if len(set) == 0 {
+		return doctorCheck{Name: "environment", Status: doctorOK, Detail: "no TUMIX_* variables set"}
+	}
+	return doctorCheck{Name: "environment", Status: doctorOK, Detail: strings.Join(set, ", ")}
+}
+
+// apiKeyCheck verifies the API key of backend with a cheap authenticated call. The key of a failover backend is only
+// a warning, as the run works without it until it fails over.
+func (d *doctor) apiKeyCheck(ctx context.Context, backend, apiKey string, primary bool) doctorCheck {
+	c := doctorCheck{Name: "api_key:" + backend}
+	failed := doctorFail
+	if !primary {
+		failed = doctorWarn
+	}
+	if apiKey == "" {
+		c.Status, c.Detail = failed, "no API key"
+		c.Hint = "set " + backendAPIKeyEnv(backend)
+		if primary {
+			c.Hint += " or -api_key"
+		}
+		return c
+	}
+	detail, err := d.auth(ctx, backend, apiKey)
+	if err != nil {
+		c.Status, c.Detail = failed, err.Error()
+		c.Hint = strings.TrimPrefix(preflightHint(err), "; ")
+		if c.Hint == "" {
+			c.Hint = "check the API key and the network; run with -preflight to also test a completion"
+		}
+		return c
+	}
+	c.Status, c.Detail = doctorOK, detail
+	return c
+}
+
+// connectCheck opens a connection to the API host of backend; for xAI, it waits until the gRPC channel is ready.
+func (d *doctor) connectCheck(ctx context.Context, backend, apiKey string) doctorCheck {
+	host := backendHost(backend)
+	if backend == "xai" {
+		c := doctorCheck{Name: "grpc:xai", Status: doctorOK, Detail: host + " ready"}
+		if err := d.grpc(ctx, apiKey); err != nil {
+			c.Status, c.Detail = doctorFail, err.Error()
+			c.Hint = "check that outbound gRPC (HTTP/2 over TLS) to " + host + " is allowed by the network, proxy, and firewall"
+		}
+		return c
+	}
+	c := doctorCheck{Name: "connect:" + backend, Status: doctorOK, Detail: host + " reachable"}
+	if host == "" {
+		c.Status, c.Detail = doctorSkip, "no known API host"
+		return c
+	}
+	if err := d.dial(ctx, host); err != nil {
+		c.Status, c.Detail = doctorFail, err.Error()
+		c.Hint = "check the network, proxy, and firewall settings"
+	}
+	return c
+}
+
+func (d *doctor) otlpCheck(ctx context.Context, endpoint string) doctorCheck {
+	c := doctorCheck{Name: "otlp_endpoint", Status: doctorOK, Detail: endpoint + " reachable"}
+	if endpoint == "" {
+		c.Status, c.Detail = doctorSkip, "tracing disabled; set -otlp_endpoint to export traces"
+		return c
+	}
+	if err := d.dial(ctx, endpoint); err != nil {
+		c.Status, c.Detail = doctorFail, err.Error()
+		c.Hint = "start the OpenTelemetry collector or fix -otlp_endpoint; it takes host:port of the OTLP gRPC receiver (usually 4317)"
+	}
+	return c
+}
+
+// sessionStoreCheck verifies that the session directory, or the directory of the SQLite database, is writable, and
+// warns when other users may read the sessions.
+func sessionStoreCheck(sessionDir, dbPath string) doctorCheck {
+	c := doctorCheck{Name: "session_store", Status: doctorOK}
+	dir := sessionDir
+	switch {
+	case sessionDir != "":
+	case dbPath != "":
+		dir = filepath.Dir(dbPath)
+	default:
+		c.Status, c.Detail = doctorSkip, "sessions are kept in memory; set -session_dir or TUMIX_SESSION_SQLITE to persist them"
+		return c
+	}
+
+	if err := writableDirCheck(dir)(context.Background()); err != nil {
+		c.Status, c.Detail = doctorFail, err.Error()
+		c.Hint = "create " + dir + " or grant the current user write permission on it"
+		return c
+	}
+	c.Detail = dir + " writable"
+	if info, err := os.Stat(dir); err == nil && info.Mode().Perm()&0o007 != 0 {
+		c.Status = doctorWarn
+		c.Detail = fmt.Sprintf("%s writable, but other users may access it (mode %s)", dir, info.Mode().Perm())
+		c.Hint = "sessions hold prompts and answers; restrict the directory, e.g. chmod 700 " + dir
+	}
+	return c
+}
+
+// authCheck makes the cheapest authenticated call of backend: listing a page of models, or for xAI, reading the API
+// key information.
+func authCheck(ctx context.Context, backend, apiKey string, httpClient *http.Client) (string, error) {
+	switch backend {
+	case "gemini":
+		client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey, HTTPClient: httpClient})
+		if err != nil {
+			return "", err
+		}
+		if _, err := client.Models.List(ctx, &genai.ListModelsConfig{PageSize: 1}); err != nil {
+			return "", err
+		}
+		return "valid", nil
+	case "openai":
+		client := openai.NewClient(option.WithAPIKey(apiKey), option.WithHTTPClient(httpClient))
+		if _, err := client.Models.List(ctx); err != nil {
+			return "", err
+		}
+		return "valid", nil
+	case "anthropic":
+		client := anthropic.NewClient(anthropicoption.WithAPIKey(apiKey), anthropicoption.WithHTTPClient(httpClient))
+		if _, err := client.Models.List(ctx, anthropic.ModelListParams{Limit: anthropic.Int(1)}); err != nil {
+			return "", err
+		}
+		return "valid", nil
+	case "xai":
+		client, err := xai.NewClient(apiKey)
+		if err != nil {
+			return "", err
+		}
+		defer client.Close()
+		info, err := client.Auth.GetAPIKeyInfo(ctx)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case info.GetDisabled():
+			return "", errors.New("API key is disabled")
+		case info.GetApiKeyBlocked():
+			return "", errors.New("API key is blocked")
+		case info.GetTeamBlocked():
+			return "", errors.New("team of the API key is blocked")
+		}
+		return fmt.Sprintf("valid (%s, %s)", cmp.Or(info.GetName(), "unnamed key"), info.GetRedactedApiKey()), nil
+	default:
+		return "", fmt.Errorf("unknown backend %q", backend)
+	}
+}
+
+// writeDoctorReport prints report as a table, or JSON with asJSON, and returns the exit code: 1 if a check failed.
+func writeDoctorReport(stdout, stderr io.Writer, report *doctorReport, asJSON bool) int {
+	code := 0
+	if !report.OK {
+		code = 1
+	}
+	if asJSON {
+		if err := json.MarshalEncode(jsontext.NewEncoder(stdout, jsontext.WithIndent("  ")), report); err != nil {
+			fmt.Fprintf(stderr, "doctor: encode: %v\n", err)
+			return 1
+		}
+		return code
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, c := range report.Checks {
+		fmt.Fprintf(tw, "[%s]\t%s\t%s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+		if c.Hint != "" {
+			fmt.Fprintf(tw, "\t\thint: %s\n", c.Hint)
+		}
+	}
+	_ = tw.Flush()
+	if report.OK {
+		fmt.Fprintln(stdout, "\nno problems found")
+	} else {
+		fmt.Fprintln(stdout, "\nsome checks failed; see the hints above")
+	}
+	return code
+}
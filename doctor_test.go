@@ -0,0 +1,219 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDoctorRun(t *testing.T) {
+	t.Setenv("TUMIX_SESSION_SQLITE", "")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	sessionDir := filepath.Join(t.TempDir(), "sessions")
+	if err := os.Mkdir(sessionDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct{ name, status string }
+	tests := map[string]struct {
+		cfg      config
+		dialErr  error
+		authErr  error
+		grpcErr  error
+		want     []result
+		wantOK   bool
+		wantHint string
+	}{
+		"ok": {
+			cfg: config{LLMBackend: "gemini", APIKey: "k", OTLPEndpoint: "localhost:4317", SessionDir: sessionDir},
+			want: []result{
+				{"config", doctorOK}, {"environment", doctorOK}, {"api_key:gemini", doctorOK}, {"connect:gemini", doctorOK},
+				{"otlp_endpoint", doctorOK}, {"session_store", doctorOK},
+			},
+			wantOK: true,
+		},
+		"no api key": {
+			cfg: config{LLMBackend: "gemini"},
+			want: []result{
+				{"config", doctorOK}, {"environment", doctorOK}, {"api_key:gemini", doctorFail}, {"connect:gemini", doctorOK},
+				{"otlp_endpoint", doctorSkip}, {"session_store", doctorSkip},
+			},
+			wantHint: "set GOOGLE_API_KEY or -api_key",
+		},
+		"invalid api key": {
+			cfg:     config{LLMBackend: "xai", APIKey: "k"},
+			authErr: status.Error(codes.Unauthenticated, "invalid key"),
+			want: []result{
+				{"config", doctorOK}, {"environment", doctorOK}, {"api_key:xai", doctorFail}, {"grpc:xai", doctorOK},
+				{"otlp_endpoint", doctorSkip}, {"session_store", doctorSkip},
+			},
+			wantHint: "check that the API key is valid",
+		},
+		"grpc unreachable": {
+			cfg:     config{LLMBackend: "xai", APIKey: "k"},
+			grpcErr: errors.New("api: connection is TRANSIENT_FAILURE"),
+			want: []result{
+				{"config", doctorOK}, {"environment", doctorOK}, {"api_key:xai", doctorOK}, {"grpc:xai", doctorFail},
+				{"otlp_endpoint", doctorSkip}, {"session_store", doctorSkip},
+			},
+			wantHint: "outbound gRPC",
+		},
+		"failover without key": {
+			cfg: config{LLMBackend: "gemini", APIKey: "k", Failover: "openai:gpt-5"},
+			want: []result{
+				{"config", doctorOK}, {"environment", doctorOK}, {"api_key:gemini", doctorOK}, {"connect:gemini", doctorOK},
+				{"api_key:openai", doctorWarn}, {"connect:openai", doctorOK}, {"otlp_endpoint", doctorSkip}, {"session_store", doctorSkip},
+			},
+			wantOK: true,
+		},
+		"unreachable": {
+			cfg:     config{LLMBackend: "gemini", APIKey: "k", OTLPEndpoint: "localhost:1"},
+			dialErr: errors.New("connection refused"),
+			want: []result{
+				{"config", doctorOK}, {"environment", doctorOK}, {"api_key:gemini", doctorOK}, {"connect:gemini", doctorFail},
+				{"otlp_endpoint", doctorFail}, {"session_store", doctorSkip},
+			},
+			wantHint: "OpenTelemetry collector",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &doctor{
+				dial:    func(context.Context, string) error { return tt.dialErr },
+				auth:    func(context.Context, string, string) (string, error) { return "valid", tt.authErr },
+				grpc:    func(context.Context, string) error { return tt.grpcErr },
+				environ: []string{"HOME=/home/tumix"},
+			}
+			report := d.run(t.Context(), &tt.cfg)
+
+			var got []result
+			for _, c := range report.Checks {
+				got = append(got, result{c.Name, c.Status})
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(result{})); diff != "" {
+				t.Fatalf("run() mismatch (-want +got):\n%s", diff)
+			}
+			if report.OK != tt.wantOK {
+				t.Fatalf("run() ok = %t, want %t", report.OK, tt.wantOK)
+			}
+			var out bytes.Buffer
+			wantCode := 1
+			if tt.wantOK {
+				wantCode = 0
+			}
+			if code := writeDoctorReport(&out, &out, report, false); code != wantCode {
+				t.Fatalf("writeDoctorReport() = %d, want %d", code, wantCode)
+			}
+			if !strings.Contains(out.String(), tt.wantHint) {
+				t.Fatalf("report = %q, want it to contain %q", out.String(), tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestDoctorEnvCheck(t *testing.T) {
+	if c := envCheck([]string{"HOME=/home/tumix", "TUMIX_DOCTOR_TEST=1"}); c.Status != doctorOK || c.Detail != "TUMIX_DOCTOR_TEST" {
+		t.Fatalf("envCheck() = %+v, want ok listing TUMIX_DOCTOR_TEST", c)
+	}
+	if c := envCheck(nil); c.Status != doctorOK || c.Detail != "no TUMIX_* variables set" {
+		t.Fatalf("envCheck(nil) = %+v, want ok without variables", c)
+	}
+
+	t.Setenv("TUMIX_MAX_ROUNDS", "three")
+	t.Cleanup(func() { invalidEnv.Delete("TUMIX_MAX_ROUNDS") })
+	if got := parseEnv[uint]("TUMIX_MAX_ROUNDS", 3); got != 3 {
+		t.Fatalf("parseEnv() = %d, want the fallback 3", got)
+	}
+	environ := []string{"TUMIX_MAX_ROUNDS=three", "TUMIX_DOCTOR_TEST=1"}
+	if c := envCheck(environ); c.Status != doctorFail || c.Detail != `ignored invalid values: TUMIX_MAX_ROUNDS="three"` {
+		t.Fatalf("envCheck() = %+v, want a failure naming only TUMIX_MAX_ROUNDS", c)
+	}
+	// A value set since the rejected one is not reported.
+	if c := envCheck([]string{"TUMIX_MAX_ROUNDS=4"}); c.Status != doctorOK {
+		t.Fatalf("envCheck() = %+v, want ok once TUMIX_MAX_ROUNDS changed", c)
+	}
+}
+
+func TestDoctorSessionStoreCheck(t *testing.T) {
+	t.Parallel()
+
+	private := filepath.Join(t.TempDir(), "private")
+	shared := filepath.Join(t.TempDir(), "shared")
+	for dir, perm := range map[string]os.FileMode{private: 0o700, shared: 0o777} {
+		if err := os.Mkdir(dir, perm); err != nil {
+			t.Fatal(err)
+		}
+		// Undo the umask.
+		if err := os.Chmod(dir, perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		dir, db string
+		want    string
+	}{
+		"memory":       {want: doctorSkip},
+		"private dir":  {dir: private, want: doctorOK},
+		"shared dir":   {dir: shared, want: doctorWarn},
+		"sqlite":       {db: filepath.Join(private, "sessions.db"), want: doctorOK},
+		"not writable": {dir: filepath.Join(private, "file", "sessions"), want: doctorFail},
+	}
+	if err := os.WriteFile(filepath.Join(private, "file"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if c := sessionStoreCheck(tt.dir, tt.db); c.Status != tt.want {
+				t.Fatalf("sessionStoreCheck(%q, %q) = %+v, want status %s", tt.dir, tt.db, c, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDoctorReportJSON(t *testing.T) {
+	t.Parallel()
+
+	report := &doctorReport{OK: true, Checks: []doctorCheck{{Name: "config", Status: doctorOK, Detail: "valid"}}}
+	var stdout, stderr bytes.Buffer
+	if code := writeDoctorReport(&stdout, &stderr, report, true); code != 0 {
+		t.Fatalf("writeDoctorReport() = %d; stderr: %s", code, stderr.String())
+	}
+	var got doctorReport
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", stdout.String(), err)
+	}
+	if diff := cmp.Diff(report, &got); diff != "" {
+		t.Fatalf("report mismatch (-want +got):\n%s", diff)
+	}
+}
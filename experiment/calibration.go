@@ -0,0 +1,107 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package experiment
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// calibrationBins is the number of equal-width confidence bins of the reliability diagram.
+const calibrationBins = 10
+
+// Calibration compares the confidence an arm reported for its answers with their graded correctness.
+//
+// Only successful runs reporting a confidence (see [Outcome.Confidence]) are counted. A well calibrated arm is right
+// about 80% of the time when it reports a confidence of 0.8, which shows as bins whose accuracy matches their mean
+// confidence and as a low Brier score and expected calibration error.
+type Calibration struct {
+	// Runs is the number of runs counted.
+	Runs int `json:"runs"`
+	// Brier is the mean squared difference between the confidence and the score: 0 is perfect, and always reporting
+	// 0.5 scores 0.25.
+	Brier float64 `json:"brier_score"`
+	// ECE is the expected calibration error: the gap between the mean confidence and the accuracy of every bin,
+	// weighted by its share of the runs.
+	ECE float64 `json:"expected_calibration_error"`
+	// Bins is the reliability diagram, the non-empty confidence bins in increasing order.
+	Bins []CalibrationBin `json:"bins"`
+}
+
+// CalibrationBin is one bar of the reliability diagram: the runs whose confidence is in [Low, High), the last bin
+// also including 1.
+type CalibrationBin struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	Runs int     `json:"runs"`
+	// MeanConfidence is the mean confidence of the runs and Accuracy their mean score.
+	MeanConfidence float64 `json:"mean_confidence"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// Calibrate returns the calibration of runs, or nil when none of them reported a confidence.
+func Calibrate(runs []ArmRun) *Calibration {
+	var bins [calibrationBins]CalibrationBin
+	c := &Calibration{}
+	for _, run := range runs {
+		if !run.ok() || run.Confidence <= 0 {
+			continue
+		}
+		conf := math.Min(run.Confidence, 1)
+		c.Runs++
+		c.Brier += (conf - run.Score) * (conf - run.Score)
+
+		b := &bins[min(int(conf*calibrationBins), calibrationBins-1)]
+		b.Runs++
+		b.MeanConfidence += conf
+		b.Accuracy += run.Score
+	}
+	if c.Runs == 0 {
+		return nil
+	}
+	c.Brier /= float64(c.Runs)
+
+	for i, b := range bins {
+		if b.Runs == 0 {
+			continue
+		}
+		b.Low, b.High = float64(i)/calibrationBins, float64(i+1)/calibrationBins
+		b.MeanConfidence /= float64(b.Runs)
+		b.Accuracy /= float64(b.Runs)
+		c.ECE += float64(b.Runs) / float64(c.Runs) * math.Abs(b.MeanConfidence-b.Accuracy)
+		c.Bins = append(c.Bins, b)
+	}
+	return c
+}
+
+// WriteText writes the reliability diagram of c to w as a table, one row per bin.
+func (c *Calibration) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "calibration over %d runs: brier %.3f, ece %.3f\n", c.Runs, c.Brier, c.ECE); err != nil {
+		return fmt.Errorf("write calibration: %w", err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "confidence\truns\tmean confidence\taccuracy")
+	for _, b := range c.Bins {
+		fmt.Fprintf(tw, "[%.1f, %.1f)\t%d\t%.3f\t%.3f\n", b.Low, b.High, b.Runs, b.MeanConfidence, b.Accuracy)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write calibration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package experiment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCalibrate(t *testing.T) {
+	t.Parallel()
+
+	conf := func(c, score float64) ArmRun { return ArmRun{Outcome: Outcome{Confidence: c}, Score: score} }
+
+	tests := map[string]struct {
+		runs []ArmRun
+		want *Calibration
+	}{
+		"no confidence": {
+			runs: []ArmRun{{Score: 1}, {Error: "boom"}},
+		},
+		"perfect": {
+			runs: []ArmRun{conf(1, 1), conf(1, 1)},
+			want: &Calibration{Runs: 2, Bins: []CalibrationBin{{Low: 0.9, High: 1, Runs: 2, MeanConfidence: 1, Accuracy: 1}}},
+		},
+		"overconfident": {
+			// Brier: (0.9² + 0.1² + 0.4² + 0.6²) / 4 = 0.335.
+			// ECE: 2/4 * |0.9 - 0.5| + 2/4 * |0.6 - 0.5| = 0.25.
+			runs: []ArmRun{conf(0.9, 0), conf(0.9, 1), conf(0.6, 1), conf(0.6, 0), {Outcome: Outcome{Confidence: 0.9}, Error: "boom"}},
+			want: &Calibration{
+				Runs:  4,
+				Brier: 0.335,
+				ECE:   0.25,
+				Bins: []CalibrationBin{
+					{Low: 0.6, High: 0.7, Runs: 2, MeanConfidence: 0.6, Accuracy: 0.5},
+					{Low: 0.9, High: 1, Runs: 2, MeanConfidence: 0.9, Accuracy: 0.5},
+				},
+			},
+		},
+		"clamps above one": {
+			runs: []ArmRun{conf(1.5, 0)},
+			want: &Calibration{Runs: 1, Brier: 1, ECE: 1, Bins: []CalibrationBin{{Low: 0.9, High: 1, Runs: 1, MeanConfidence: 1}}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, Calibrate(tt.runs), cmpFloat()); diff != "" {
+				t.Fatalf("Calibrate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReportCalibration(t *testing.T) {
+	t.Parallel()
+
+	pairs := []Pair{
+		{Case: Case{ID: "1"}, A: ArmRun{Outcome: Outcome{Confidence: 0.8}, Score: 1}, B: ArmRun{Score: 1}},
+		{Case: Case{ID: "2"}, A: ArmRun{Outcome: Outcome{Confidence: 0.8}}, B: ArmRun{Score: 1}},
+	}
+	r := newReport("base", "cand", Paired, pairs)
+	if r.B.Calibration != nil {
+		t.Fatalf("B calibration = %+v, want nil", r.B.Calibration)
+	}
+	if r.A.Calibration == nil || r.A.Calibration.Runs != 2 {
+		t.Fatalf("A calibration = %+v, want 2 runs", r.A.Calibration)
+	}
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{"brier", "0.340", "ece", "0.300", "-"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Fatalf("WriteText() output missing %q:\n%s", want, sb.String())
+		}
+	}
+
+	sb.Reset()
+	if err := r.A.Calibration.WriteText(&sb); err != nil {
+		t.Fatalf("Calibration.WriteText() error = %v", err)
+	}
+	for _, want := range []string{"calibration over 2 runs: brier 0.340, ece 0.300", "[0.8, 0.9)", "0.800", "0.500"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Fatalf("Calibration.WriteText() output missing %q:\n%s", want, sb.String())
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package experiment runs A/B comparisons of two TUMIX configurations over the same prompt set.
+//
+// Each [Arm] wraps one agent mixture and stop policy. [Run] answers every [Case] with both arms, grades the answers
+// with a [Grader], and returns a [Report] comparing accuracy, cost, rounds, and latency case by case, so the
+// deltas are paired and the arms see identical prompts. The report also holds the [Calibration] of the confidence
+// each arm reported against the correctness of its answers.
+package experiment
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zchee/tumix/internal/mathcheck"
+)
+
+// Case is one prompt of the experiment.
+type Case struct {
+	// ID identifies the case in the report. Empty IDs are replaced by the 1-based case index.
+	ID     string `json:"id,omitempty"`
+	Prompt string `json:"prompt"`
+	// Want is the reference answer handed to the grader.
+	Want string `json:"want,omitempty"`
+}
+
+// Outcome is what an arm reports for one run.
+type Outcome struct {
+	Answer       string  `json:"answer"`
+	Rounds       int     `json:"rounds"`
+	CostUSD      float64 `json:"cost_usd"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	// Confidence is the confidence the arm reported for the answer, e.g. the vote margin or the Judge's confidence,
+	// from 0 to 1; zero means none was reported. It feeds the [Calibration] of the arm.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// Arm is one configuration under test.
+type Arm struct {
+	Name string
+	// Run answers prompt with the arm's configuration.
+	Run func(ctx context.Context, prompt string) (Outcome, error)
+}
+
+// Grader scores an answer against a case, from 0 (wrong) to 1 (correct).
+type Grader interface {
+	Grade(ctx context.Context, c Case, answer string) (float64, error)
+}
+
+// GraderFunc adapts a function to [Grader].
+type GraderFunc func(ctx context.Context, c Case, answer string) (float64, error)
+
+// Grade implements [Grader].
+func (f GraderFunc) Grade(ctx context.Context, c Case, answer string) (float64, error) {
+	return f(ctx, c, answer)
+}
+
+// ExactMatch grades 1 when the answer equals the case's Want ignoring case, surrounding whitespace, and the "<<<"
+// and ">>>" answer markers, or when both are equivalent numbers such as "1/3" and "0.333…".
+var ExactMatch Grader = GraderFunc(func(_ context.Context, c Case, answer string) (float64, error) {
+	got, want := normalize(answer), normalize(c.Want)
+	if strings.EqualFold(got, want) {
+		return 1, nil
+	}
+	if a, ok := mathcheck.Parse(got); ok {
+		if b, ok := mathcheck.Parse(want); ok && mathcheck.Equivalent(a, b) {
+			return 1, nil
+		}
+	}
+	return 0, nil
+})
+
+func normalize(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "<<<")
+	s = strings.TrimSuffix(s, ">>>")
+	return strings.TrimSpace(s)
+}
+
+// Order is how the runs of the two arms are scheduled.
+type Order int
+
+const (
+	// Paired runs both arms on a case back to back, alternating which arm goes first, so both see the same provider
+	// conditions.
+	Paired Order = iota
+	// Interleaved runs every (case, arm) pair in a seeded random order, spreading drift in provider latency and
+	// rate limits evenly across the arms.
+	Interleaved
+)
+
+// String returns the name of o.
+func (o Order) String() string {
+	switch o {
+	case Paired:
+		return "paired"
+	case Interleaved:
+		return "interleaved"
+	default:
+		return fmt.Sprintf("Order(%d)", int(o))
+	}
+}
+
+// Config configures an experiment.
+type Config struct {
+	A, B   Arm
+	Grader Grader
+	Order  Order
+	// Seed seeds the Interleaved schedule.
+	Seed uint64
+	// Concurrency is the number of runs in flight. Zero means one.
+	Concurrency int
+	// Repeats runs every case this many times per arm; each repeat is paired separately. Zero means one.
+	Repeats int
+}
+
+// Run runs the experiment over cases and reports the comparison.
+//
+// A failed run does not stop the experiment; it is recorded in the report and its pair is left out of the deltas.
+// Run returns an error only for an invalid configuration or a canceled ctx.
+func Run(ctx context.Context, cfg Config, cases []Case) (*Report, error) {
+	switch {
+	case cfg.A.Run == nil || cfg.B.Run == nil:
+		return nil, errors.New("experiment: both arms need a Run function")
+	case cfg.Grader == nil:
+		return nil, errors.New("experiment: grader is required")
+	case len(cases) == 0:
+		return nil, errors.New("experiment: no cases")
+	}
+	cfg.A.Name = cmp.Or(cfg.A.Name, "A")
+	cfg.B.Name = cmp.Or(cfg.B.Name, "B")
+	repeats := max(cfg.Repeats, 1)
+
+	pairs := make([]Pair, 0, len(cases)*repeats)
+	for i, c := range cases {
+		if c.ID == "" {
+			c.ID = fmt.Sprint(i + 1)
+		}
+		for r := range repeats {
+			pairs = append(pairs, Pair{Case: c, Repeat: r})
+		}
+	}
+
+	units := schedule(len(pairs), cfg.Order, cfg.Seed)
+	arms := [2]Arm{cfg.A, cfg.B}
+
+	ch := make(chan unit)
+	var wg sync.WaitGroup
+	for range max(cfg.Concurrency, 1) {
+		wg.Go(func() {
+			for u := range ch {
+				p := &pairs[u.pair]
+				run := runArm(ctx, arms[u.arm], cfg.Grader, p.Case)
+				// Each unit owns one side of one pair, so no two workers write the same field.
+				if u.arm == 0 {
+					p.A = run
+				} else {
+					p.B = run
+				}
+			}
+		})
+	}
+	for _, u := range units {
+		select {
+		case ch <- u:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(ch)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("experiment: %w", err)
+	}
+
+	return newReport(cfg.A.Name, cfg.B.Name, cfg.Order, pairs), nil
+}
+
+// unit is one run: a side of a pair.
+type unit struct {
+	pair int
+	arm  int
+}
+
+// schedule returns the run order of n pairs.
+func schedule(n int, order Order, seed uint64) []unit {
+	units := make([]unit, 0, 2*n)
+	for i := range n {
+		// Alternate the first arm so neither consistently benefits from warm caches or suffers a cold start.
+		first := i % 2
+		units = append(units, unit{pair: i, arm: first}, unit{pair: i, arm: 1 - first})
+	}
+	if order == Interleaved {
+		r := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)) //nolint:gosec // scheduling order, not security sensitive
+		r.Shuffle(len(units), func(i, j int) { units[i], units[j] = units[j], units[i] })
+	}
+	return units
+}
+
+func runArm(ctx context.Context, arm Arm, grader Grader, c Case) ArmRun {
+	start := time.Now()
+	out, err := arm.Run(ctx, c.Prompt)
+	run := ArmRun{Outcome: out, Latency: time.Since(start)}
+	if err != nil {
+		run.Error = err.Error()
+		return run
+	}
+	score, err := grader.Grade(ctx, c, out.Answer)
+	if err != nil {
+		run.Error = fmt.Sprintf("grade: %v", err)
+		return run
+	}
+	run.Score = score
+	return run
+}
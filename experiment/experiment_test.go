@@ -0,0 +1,193 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package experiment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	cases := []Case{
+		{Prompt: "1+1", Want: "2"},
+		{Prompt: "2+2", Want: "4"},
+		{Prompt: "3+3", Want: "6"},
+		{Prompt: "fail", Want: "x"},
+	}
+	// A answers only the first case right; B answers all of them right with one more round at double the cost.
+	armA := Arm{Name: "baseline", Run: func(_ context.Context, prompt string) (Outcome, error) {
+		switch prompt {
+		case "1+1":
+			return Outcome{Answer: "2", Rounds: 2, CostUSD: 0.01}, nil
+		case "fail":
+			return Outcome{}, errors.New("quota")
+		default:
+			return Outcome{Answer: "0", Rounds: 2, CostUSD: 0.01}, nil
+		}
+	}}
+	answers := map[string]string{"1+1": "<<<2>>>", "2+2": "4.0", "3+3": "6", "fail": "x"}
+	armB := Arm{Name: "more-rounds", Run: func(_ context.Context, prompt string) (Outcome, error) {
+		return Outcome{Answer: answers[prompt], Rounds: 3, CostUSD: 0.02}, nil
+	}}
+
+	for _, order := range []Order{Paired, Interleaved} {
+		t.Run(order.String(), func(t *testing.T) {
+			t.Parallel()
+
+			report, err := Run(t.Context(), Config{A: armA, B: armB, Grader: ExactMatch, Order: order, Seed: 1, Concurrency: 3}, cases)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if diff := cmp.Diff(ArmSummary{Name: "baseline", Runs: 4, Errors: 1, Accuracy: 1.0 / 3, TotalCostUSD: 0.03, MeanCostUSD: 0.01, MeanRounds: 2}, report.A, cmpFloat(), ignoreLatency()); diff != "" {
+				t.Fatalf("A summary mismatch (-want +got):\n%s", diff)
+			}
+			if report.Compared != 3 || report.OnlyA != 0 || report.OnlyB != 2 {
+				t.Fatalf("Compared, OnlyA, OnlyB = %d, %d, %d, want 3, 0, 2", report.Compared, report.OnlyA, report.OnlyB)
+			}
+			if diff := cmp.Diff(Delta{Mean: 1, Low: 1, High: 1, P: 0}, report.Rounds, cmpFloat()); diff != "" {
+				t.Fatalf("Rounds delta mismatch (-want +got):\n%s", diff)
+			}
+			if got := report.Accuracy.Mean; got < 0.66 || got > 0.67 {
+				t.Fatalf("Accuracy.Mean = %v, want 2/3", got)
+			}
+			if got := report.Pairs[3].A.Error; got != "quota" {
+				t.Fatalf("failed pair error = %q, want %q", got, "quota")
+			}
+			if got := report.Pairs[0].Case.ID; got != "1" {
+				t.Fatalf("default case ID = %q, want %q", got, "1")
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	arm := Arm{Run: func(context.Context, string) (Outcome, error) { return Outcome{}, nil }}
+	tests := map[string]struct {
+		cfg   Config
+		cases []Case
+	}{
+		"missing arm":    {cfg: Config{A: arm, Grader: ExactMatch}, cases: []Case{{Prompt: "p"}}},
+		"missing grader": {cfg: Config{A: arm, B: arm}, cases: []Case{{Prompt: "p"}}},
+		"no cases":       {cfg: Config{A: arm, B: arm, Grader: ExactMatch}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := Run(t.Context(), tt.cfg, tt.cases); err == nil {
+				t.Fatal("Run() error = nil, want error")
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := Run(ctx, Config{A: arm, B: arm, Grader: ExactMatch}, []Case{{Prompt: "p"}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	want := []unit{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {2, 0}, {2, 1}}
+	if diff := cmp.Diff(want, schedule(3, Paired, 0), cmp.AllowUnexported(unit{})); diff != "" {
+		t.Fatalf("Paired schedule mismatch (-want +got):\n%s", diff)
+	}
+
+	got := schedule(20, Interleaved, 42)
+	if diff := cmp.Diff(got, schedule(20, Interleaved, 42), cmp.AllowUnexported(unit{})); diff != "" {
+		t.Fatalf("Interleaved schedule not reproducible (-first +second):\n%s", diff)
+	}
+	seen := make(map[unit]bool, len(got))
+	for _, u := range got {
+		seen[u] = true
+	}
+	if len(seen) != 40 {
+		t.Fatalf("Interleaved schedule has %d distinct runs, want 40", len(seen))
+	}
+}
+
+func TestRunRepeatsAndConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	arm := func(name string) Arm {
+		return Arm{Name: name, Run: func(_ context.Context, prompt string) (Outcome, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name+":"+prompt]++
+			return Outcome{Answer: strings.ToUpper(prompt)}, nil
+		}}
+	}
+
+	report, err := Run(t.Context(), Config{A: arm("a"), B: arm("b"), Grader: ExactMatch, Repeats: 3, Concurrency: 4}, []Case{{Prompt: "x", Want: "X"}, {Prompt: "y", Want: "Y"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if diff := cmp.Diff(map[string]int{"a:x": 3, "a:y": 3, "b:x": 3, "b:y": 3}, calls); diff != "" {
+		t.Fatalf("calls mismatch (-want +got):\n%s", diff)
+	}
+	if report.Compared != 6 || report.A.Accuracy != 1 || report.B.Accuracy != 1 {
+		t.Fatalf("Compared, A.Accuracy, B.Accuracy = %d, %v, %v, want 6, 1, 1", report.Compared, report.A.Accuracy, report.B.Accuracy)
+	}
+}
+
+func TestExactMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		answer string
+		want   string
+		score  float64
+	}{
+		"equal":             {answer: "Paris", want: "Paris", score: 1},
+		"case insensitive":  {answer: "paris", want: "Paris", score: 1},
+		"markers":           {answer: " <<<Paris>>> ", want: "Paris", score: 1},
+		"equivalent number": {answer: "1/3", want: "0.333…", score: 1},
+		"different":         {answer: "London", want: "Paris", score: 0},
+		"different number":  {answer: "0.3", want: "1/3", score: 0},
+		"rounded number":    {answer: "1/3", want: "0.33", score: 1},
+		"another rounding":  {answer: "0.325", want: "0.33", score: 0},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ExactMatch.Grade(t.Context(), Case{Want: tt.want}, tt.answer)
+			if err != nil {
+				t.Fatalf("Grade() error = %v", err)
+			}
+			if got != tt.score {
+				t.Fatalf("Grade(%q, %q) = %v, want %v", tt.answer, tt.want, got, tt.score)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package experiment
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+)
+
+// correctScore is the score from which an answer counts as correct for the McNemar test.
+const correctScore = 0.5
+
+// z95 is the two-sided 95% normal quantile.
+const z95 = 1.959963984540054
+
+// ArmRun is the result of one arm on one case.
+type ArmRun struct {
+	Outcome
+	Score   float64       `json:"score"`
+	Latency time.Duration `json:"latency_ns"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// ok reports whether the run finished and was graded.
+func (r ArmRun) ok() bool { return r.Error == "" }
+
+// Pair is both arms' results on one case.
+type Pair struct {
+	Case   Case   `json:"case"`
+	Repeat int    `json:"repeat,omitempty"`
+	A      ArmRun `json:"a"`
+	B      ArmRun `json:"b"`
+}
+
+// ArmSummary aggregates the runs of one arm.
+type ArmSummary struct {
+	Name   string `json:"name"`
+	Runs   int    `json:"runs"`
+	Errors int    `json:"errors"`
+	// Accuracy is the mean score of the successful runs.
+	Accuracy float64 `json:"accuracy"`
+	// TotalCostUSD includes the cost of failed runs.
+	TotalCostUSD float64       `json:"total_cost_usd"`
+	MeanCostUSD  float64       `json:"mean_cost_usd"`
+	MeanRounds   float64       `json:"mean_rounds"`
+	MeanLatency  time.Duration `json:"mean_latency_ns"`
+	// Calibration compares the reported confidence with the scores; nil when no run reported a confidence.
+	Calibration *Calibration `json:"calibration,omitempty"`
+}
+
+// Delta is the mean paired difference B − A of a metric with its 95% confidence interval.
+//
+// The interval and the two-sided P value use the normal approximation of the paired mean, which is adequate from a
+// few dozen pairs; treat them as indicative on smaller sets.
+type Delta struct {
+	Mean   float64 `json:"mean"`
+	StdErr float64 `json:"std_err"`
+	Low    float64 `json:"ci95_low"`
+	High   float64 `json:"ci95_high"`
+	P      float64 `json:"p_value"`
+}
+
+// Significant reports whether the difference is significant at level alpha.
+func (d Delta) Significant(alpha float64) bool { return d.P < alpha }
+
+// Report is the comparison of two arms.
+type Report struct {
+	Order string     `json:"order"`
+	A     ArmSummary `json:"a"`
+	B     ArmSummary `json:"b"`
+	// Compared is the number of pairs where both arms succeeded; the deltas are computed over these pairs only.
+	Compared int   `json:"compared"`
+	Accuracy Delta `json:"accuracy"`
+	CostUSD  Delta `json:"cost_usd"`
+	Rounds   Delta `json:"rounds"`
+	// LatencySeconds is the latency delta in seconds.
+	LatencySeconds Delta `json:"latency_seconds"`
+	// OnlyA and OnlyB count the compared pairs only A, or only B, answered correctly.
+	OnlyA int `json:"only_a_correct"`
+	OnlyB int `json:"only_b_correct"`
+	// McNemarP is the two-sided P value of McNemar's test on the discordant pairs, the paired test for a
+	// difference in accuracy.
+	McNemarP float64 `json:"mcnemar_p"`
+	Pairs    []Pair  `json:"pairs"`
+}
+
+func newReport(nameA, nameB string, order Order, pairs []Pair) *Report {
+	r := &Report{
+		Order: order.String(),
+		A:     summarize(nameA, pairs, func(p Pair) ArmRun { return p.A }),
+		B:     summarize(nameB, pairs, func(p Pair) ArmRun { return p.B }),
+		Pairs: pairs,
+	}
+
+	var acc, cost, rounds, latency []float64
+	for _, p := range pairs {
+		if !p.A.ok() || !p.B.ok() {
+			continue
+		}
+		acc = append(acc, p.B.Score-p.A.Score)
+		cost = append(cost, p.B.CostUSD-p.A.CostUSD)
+		rounds = append(rounds, float64(p.B.Rounds-p.A.Rounds))
+		latency = append(latency, (p.B.Latency - p.A.Latency).Seconds())
+
+		aCorrect, bCorrect := p.A.Score >= correctScore, p.B.Score >= correctScore
+		switch {
+		case aCorrect && !bCorrect:
+			r.OnlyA++
+		case bCorrect && !aCorrect:
+			r.OnlyB++
+		}
+	}
+	r.Compared = len(acc)
+	r.Accuracy = pairedDelta(acc)
+	r.CostUSD = pairedDelta(cost)
+	r.Rounds = pairedDelta(rounds)
+	r.LatencySeconds = pairedDelta(latency)
+	r.McNemarP = mcNemar(r.OnlyA, r.OnlyB)
+	return r
+}
+
+func summarize(name string, pairs []Pair, side func(Pair) ArmRun) ArmSummary {
+	s := ArmSummary{Name: name, Runs: len(pairs)}
+	var score, cost, rounds float64
+	var latency time.Duration
+	runs := make([]ArmRun, 0, len(pairs))
+	for _, p := range pairs {
+		run := side(p)
+		runs = append(runs, run)
+		s.TotalCostUSD += run.CostUSD
+		if !run.ok() {
+			s.Errors++
+			continue
+		}
+		score += run.Score
+		cost += run.CostUSD
+		rounds += float64(run.Rounds)
+		latency += run.Latency
+	}
+	if n := s.Runs - s.Errors; n > 0 {
+		s.Accuracy = score / float64(n)
+		s.MeanCostUSD = cost / float64(n)
+		s.MeanRounds = rounds / float64(n)
+		s.MeanLatency = latency / time.Duration(n)
+	}
+	s.Calibration = Calibrate(runs)
+	return s
+}
+
+// pairedDelta returns the mean of the paired differences d with its normal-approximation interval.
+func pairedDelta(d []float64) Delta {
+	n := float64(len(d))
+	if n == 0 {
+		return Delta{P: 1}
+	}
+	var sum float64
+	for _, v := range d {
+		sum += v
+	}
+	mean := sum / n
+
+	if n < 2 {
+		return Delta{Mean: mean, Low: mean, High: mean, P: 1}
+	}
+	var ss float64
+	for _, v := range d {
+		ss += (v - mean) * (v - mean)
+	}
+	se := math.Sqrt(ss/(n-1)) / math.Sqrt(n)
+
+	dl := Delta{Mean: mean, StdErr: se, Low: mean - z95*se, High: mean + z95*se}
+	switch {
+	case se > 0:
+		dl.P = math.Erfc(math.Abs(mean/se) / math.Sqrt2)
+	case mean == 0:
+		dl.P = 1
+	default:
+		// Every pair moved by exactly the same nonzero amount.
+		dl.P = 0
+	}
+	return dl
+}
+
+// mcNemar returns the two-sided P value of McNemar's test for b and c discordant pairs, exact (binomial) below 25
+// discordant pairs and with the continuity-corrected chi-square approximation above.
+func mcNemar(b, c int) float64 {
+	n := b + c
+	if n == 0 {
+		return 1
+	}
+	if n < 25 {
+		k := min(b, c)
+		var tail float64
+		for i := 0; i <= k; i++ {
+			tail += binomial(n, i)
+		}
+		return math.Min(1, 2*tail*math.Pow(0.5, float64(n)))
+	}
+	chi := math.Abs(float64(b-c)) - 1
+	chi2 := chi * chi / float64(n)
+	return math.Erfc(math.Sqrt(chi2 / 2))
+}
+
+func binomial(n, k int) float64 {
+	v := 1.0
+	for i := 1; i <= k; i++ {
+		v = v * float64(n-k+i) / float64(i)
+	}
+	return v
+}
+
+// WriteText writes a human readable summary of r to w.
+func (r *Report) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "A: %s, B: %s (%s, %d of %d pairs compared)\n", r.A.Name, r.B.Name, r.Order, r.Compared, len(r.Pairs)); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric (mean per run)\tA\tB\tdelta (B-A)\t95% CI\tp")
+	fmt.Fprintf(tw, "accuracy\t%.3f\t%.3f\t%+.3f\t[%+.3f, %+.3f]\t%.3g\n", r.A.Accuracy, r.B.Accuracy, r.Accuracy.Mean, r.Accuracy.Low, r.Accuracy.High, r.McNemarP)
+	fmt.Fprintf(tw, "cost_usd\t%.4f\t%.4f\t%+.4f\t[%+.4f, %+.4f]\t%.3g\n", r.A.MeanCostUSD, r.B.MeanCostUSD, r.CostUSD.Mean, r.CostUSD.Low, r.CostUSD.High, r.CostUSD.P)
+	fmt.Fprintf(tw, "rounds\t%.2f\t%.2f\t%+.2f\t[%+.2f, %+.2f]\t%.3g\n", r.A.MeanRounds, r.B.MeanRounds, r.Rounds.Mean, r.Rounds.Low, r.Rounds.High, r.Rounds.P)
+	fmt.Fprintf(tw, "latency_s\t%.2f\t%.2f\t%+.2f\t[%+.2f, %+.2f]\t%.3g\n", r.A.MeanLatency.Seconds(), r.B.MeanLatency.Seconds(), r.LatencySeconds.Mean, r.LatencySeconds.Low, r.LatencySeconds.High, r.LatencySeconds.P)
+	fmt.Fprintf(tw, "total_cost_usd\t%.4f\t%.4f\t\t\t\n", r.A.TotalCostUSD, r.B.TotalCostUSD)
+	fmt.Fprintf(tw, "errors\t%d\t%d\t\t\t\n", r.A.Errors, r.B.Errors)
+	if r.A.Calibration != nil || r.B.Calibration != nil {
+		a, b := r.A.Calibration, r.B.Calibration
+		fmt.Fprintf(tw, "brier\t%s\t%s\t\t\t\n", calibrationMetric(a, func(c *Calibration) float64 { return c.Brier }), calibrationMetric(b, func(c *Calibration) float64 { return c.Brier }))
+		fmt.Fprintf(tw, "ece\t%s\t%s\t\t\t\n", calibrationMetric(a, func(c *Calibration) float64 { return c.ECE }), calibrationMetric(b, func(c *Calibration) float64 { return c.ECE }))
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// calibrationMetric formats a metric of c, or "-" when the arm reported no confidence.
+func calibrationMetric(c *Calibration, metric func(*Calibration) float64) string {
+	if c == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", metric(c))
+}
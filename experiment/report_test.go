@@ -0,0 +1,118 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package experiment
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func cmpFloat() cmp.Option { return cmpopts.EquateApprox(0, 1e-9) }
+
+func ignoreLatency() cmp.Option { return cmpopts.IgnoreFields(ArmSummary{}, "MeanLatency") }
+
+func TestPairedDelta(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		d    []float64
+		want Delta
+	}{
+		"empty":    {d: nil, want: Delta{P: 1}},
+		"single":   {d: []float64{2}, want: Delta{Mean: 2, Low: 2, High: 2, P: 1}},
+		"constant": {d: []float64{0, 0, 0}, want: Delta{P: 1}},
+		"spread": {
+			// mean 2, sample sd 1, se 1/sqrt(3).
+			d: []float64{1, 2, 3},
+			want: Delta{
+				Mean:   2,
+				StdErr: 1 / math.Sqrt(3),
+				Low:    2 - z95/math.Sqrt(3),
+				High:   2 + z95/math.Sqrt(3),
+				P:      math.Erfc(2 * math.Sqrt(3) / math.Sqrt2),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, pairedDelta(tt.d), cmpFloat()); diff != "" {
+				t.Fatalf("pairedDelta(%v) mismatch (-want +got):\n%s", tt.d, diff)
+			}
+		})
+	}
+}
+
+func TestMcNemar(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		b, c int
+		want float64
+	}{
+		"no discordant pairs": {b: 0, c: 0, want: 1},
+		"balanced":            {b: 3, c: 3, want: 1},
+		// Exact: 2 * P(X <= 0 | n=5, p=0.5) = 2/32.
+		"exact one sided": {b: 0, c: 5, want: 0.0625},
+		// Chi-square with continuity correction: (|10-30|-1)^2/40 = 9.025.
+		"approximation": {b: 10, c: 30, want: math.Erfc(math.Sqrt(9.025 / 2))},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := mcNemar(tt.b, tt.c); math.Abs(got-tt.want) > 1e-12 {
+				t.Fatalf("mcNemar(%d, %d) = %v, want %v", tt.b, tt.c, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportWriteText(t *testing.T) {
+	t.Parallel()
+
+	pairs := []Pair{
+		{Case: Case{ID: "1"}, A: ArmRun{Outcome: Outcome{Rounds: 2, CostUSD: 0.01}, Latency: time.Second}, B: ArmRun{Outcome: Outcome{Rounds: 1, CostUSD: 0.005}, Score: 1, Latency: time.Second}},
+		{Case: Case{ID: "2"}, A: ArmRun{Error: "boom"}, B: ArmRun{Score: 1}},
+	}
+	r := newReport("base", "cand", Paired, pairs)
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"A: base, B: cand (paired, 1 of 2 pairs compared)",
+		"accuracy",
+		"+1.000",
+		"-0.0050",
+		"errors",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("WriteText() output missing %q:\n%s", want, out)
+		}
+	}
+}
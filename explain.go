@@ -0,0 +1,70 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	tumixagent "github.com/zchee/tumix/agent"
+	tumixrun "github.com/zchee/tumix/run"
+)
+
+// writeExplanation writes the -explain report of res: why its final answer was chosen, from the vote of the last
+// round, the Judge's scores of the candidates, and the Judge's analysis.
+func writeExplanation(w io.Writer, res *tumixrun.Result) error {
+	var sb strings.Builder
+	answer := cmp.Or(res.Answer, res.Text)
+	fmt.Fprintf(&sb, "Why this answer: %s", answer)
+	if res.Confidence > 0 {
+		fmt.Fprintf(&sb, " (confidence %.2f)", res.Confidence)
+	}
+	sb.WriteString("\n")
+	if res.Provisional {
+		sb.WriteString("The run was interrupted; the answer is provisional, salvaged from the rounds run so far.\n")
+	}
+
+	if n := len(res.Rounds); n > 0 {
+		last := res.Rounds[n-1]
+		fmt.Fprintf(&sb, "Round %d vote: %q got %.0f%% of the answers (%d distinct, %.0f%% of the candidates answered).\n",
+			last.Round, last.TopAnswer, last.VoteMargin*100, last.Unique, last.Coverage*100)
+	}
+
+	if len(res.CandidateScores) > 0 {
+		scores := slices.Clone(res.CandidateScores)
+		slices.SortStableFunc(scores, func(a, b tumixagent.CandidateScore) int { return cmp.Compare(b.Weight(), a.Weight()) })
+		sb.WriteString("Judge's scores of the candidates:\n")
+		for _, s := range scores {
+			fmt.Fprintf(&sb, "  - %s: correctness %.2f, reasoning %.2f\n", s.Agent, s.Correctness, s.Reasoning)
+		}
+	}
+
+	if r := res.JudgeRationale; r != nil {
+		fmt.Fprintf(&sb, "Judge's analysis (round %d):\n", r.Round)
+		for line := range strings.SplitSeq(strings.TrimSpace(r.Text), "\n") {
+			sb.WriteString("  " + line + "\n")
+		}
+	} else {
+		sb.WriteString("The Judge wrote no analysis; the answer comes from the candidates' vote.\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -81,6 +81,11 @@ func NewAnthropicLLM(_ context.Context, apiKey, modelName string, params *Provid
 // Name implements [model.LLM].
 func (m *anthropicLLM) Name() string { return m.name }
 
+// Capabilities implements [CapabilityReporter].
+func (m *anthropicLLM) Capabilities() Capabilities {
+	return ProviderCapabilities(ProviderAnthropic, m.name)
+}
+
 // GenerateContent implements [model.LLM].
 func (m *anthropicLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
 	ctx, span := anthropicTracer.Start(ctx, "gollm.anthropic.GenerateContent")
@@ -0,0 +1,214 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gollm
+
+import (
+	"context"
+	"iter"
+	"strings"
+
+	"google.golang.org/adk/model"
+)
+
+// ProviderAnthropic names the Anthropic backend in [ProviderCapabilities]. [NewModel] does not support it.
+const ProviderAnthropic = "anthropic"
+
+// Capabilities describes the features a backend model supports, so callers can adapt to a backend up front instead of
+// failing at request time.
+type Capabilities struct {
+	// Tools reports support for function calling.
+	Tools bool
+	// NativeTools reports that the backend runs the GenAI GoogleSearch and CodeExecution tools itself.
+	NativeTools bool
+	// Vision reports support for image input.
+	Vision bool
+	// StructuredOutput reports support for ResponseSchema and ResponseJsonSchema.
+	StructuredOutput bool
+	// Streaming reports support for streamed responses.
+	Streaming bool
+	// MaxContextTokens is the context window size in tokens, or zero when unknown.
+	MaxContextTokens int
+	// Embeddings reports whether the provider serves embedding models.
+	Embeddings bool
+}
+
+// Intersect returns the capabilities supported by both c and o. The context window is the smaller known one.
+func (c Capabilities) Intersect(o Capabilities) Capabilities {
+	maxTokens := min(c.MaxContextTokens, o.MaxContextTokens)
+	if maxTokens == 0 {
+		maxTokens = max(c.MaxContextTokens, o.MaxContextTokens)
+	}
+	return Capabilities{
+		Tools:            c.Tools && o.Tools,
+		NativeTools:      c.NativeTools && o.NativeTools,
+		Vision:           c.Vision && o.Vision,
+		StructuredOutput: c.StructuredOutput && o.StructuredOutput,
+		Streaming:        c.Streaming && o.Streaming,
+		MaxContextTokens: maxTokens,
+		Embeddings:       c.Embeddings && o.Embeddings,
+	}
+}
+
+// CapabilityReporter is implemented by [model.LLM] implementations that report their [Capabilities].
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities reported by llm, and false when llm does not report any.
+func CapabilitiesOf(llm model.LLM) (Capabilities, bool) {
+	r, ok := llm.(CapabilityReporter)
+	if !ok {
+		return Capabilities{}, false
+	}
+	return r.Capabilities(), true
+}
+
+// WithCapabilities wraps llm so that it reports caps. It is meant for models created by other packages, such as the
+// ADK Gemini model.
+func WithCapabilities(llm model.LLM, caps Capabilities) model.LLM {
+	return &capableLLM{llm: llm, caps: caps}
+}
+
+type capableLLM struct {
+	llm  model.LLM
+	caps Capabilities
+}
+
+var (
+	_ model.LLM          = (*capableLLM)(nil)
+	_ CapabilityReporter = (*capableLLM)(nil)
+)
+
+// Name implements [model.LLM].
+func (c *capableLLM) Name() string { return c.llm.Name() }
+
+// GenerateContent implements [model.LLM].
+func (c *capableLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return c.llm.GenerateContent(ctx, req, stream)
+}
+
+// Capabilities implements [CapabilityReporter].
+func (c *capableLLM) Capabilities() Capabilities { return c.caps }
+
+// contextWindow is the context window of the models whose name starts with prefix.
+type contextWindow struct {
+	prefix string
+	tokens int
+}
+
+// Context windows by model name prefix, most specific prefix first.
+var (
+	geminiContextWindows = []contextWindow{
+		{"gemini-1.5-pro", 2_097_152},
+		{"gemini-", 1_048_576},
+	}
+	openAIContextWindows = []contextWindow{
+		{"gpt-5", 400_000},
+		{"gpt-4.1", 1_047_576},
+		{"gpt-4o", 128_000},
+		{"o1", 200_000},
+		{"o3", 200_000},
+		{"o4", 200_000},
+	}
+	anthropicContextWindows = []contextWindow{
+		{"claude-", 200_000},
+	}
+	xaiContextWindows = []contextWindow{
+		{"grok-4-fast", 2_000_000},
+		{"grok-4", 256_000},
+		{"grok-code-fast", 256_000},
+		{"grok-3", 131_072},
+	}
+)
+
+func lookupContextWindow(windows []contextWindow, modelName string) int {
+	for _, w := range windows {
+		if strings.HasPrefix(modelName, w.prefix) {
+			return w.tokens
+		}
+	}
+	return 0
+}
+
+// ProviderCapabilities returns the capabilities of modelName served by provider, one of [ProviderGemini],
+// [ProviderOpenAI], [ProviderAnthropic] or [ProviderXAI]. Unknown providers support nothing.
+//
+// Vision and the context window depend on the model family; unknown models of a known provider get its
+// provider-wide features, no vision, and an unknown context window.
+func ProviderCapabilities(provider, modelName string) Capabilities {
+	modelName = strings.ToLower(strings.TrimPrefix(modelName, "models/"))
+	switch provider {
+	case ProviderGemini:
+		return Capabilities{
+			Tools:            true,
+			NativeTools:      true,
+			Vision:           strings.HasPrefix(modelName, "gemini-") && !strings.HasPrefix(modelName, "gemini-embedding"),
+			StructuredOutput: true,
+			Streaming:        true,
+			MaxContextTokens: lookupContextWindow(geminiContextWindows, modelName),
+			Embeddings:       true,
+		}
+	case ProviderOpenAI:
+		return Capabilities{
+			Tools:            true,
+			NativeTools:      true,
+			Vision:           openAIVision(modelName),
+			StructuredOutput: true,
+			Streaming:        true,
+			MaxContextTokens: lookupContextWindow(openAIContextWindows, modelName),
+			Embeddings:       true,
+		}
+	case ProviderAnthropic:
+		return Capabilities{
+			Tools:            true,
+			Vision:           strings.HasPrefix(modelName, "claude-") && !strings.HasPrefix(modelName, "claude-2") && !strings.HasPrefix(modelName, "claude-instant"),
+			Streaming:        true,
+			MaxContextTokens: lookupContextWindow(anthropicContextWindows, modelName),
+		}
+	case ProviderXAI:
+		return Capabilities{
+			Tools:            true,
+			NativeTools:      true,
+			Vision:           xaiVision(modelName),
+			StructuredOutput: true,
+			Streaming:        true,
+			MaxContextTokens: lookupContextWindow(xaiContextWindows, modelName),
+			Embeddings:       true,
+		}
+	default:
+		return Capabilities{}
+	}
+}
+
+// openAIVision reports whether the OpenAI model accepts image input. The mini o1 and o3 reasoning models do not.
+func openAIVision(modelName string) bool {
+	switch {
+	case strings.HasPrefix(modelName, "o1-mini"), strings.HasPrefix(modelName, "o3-mini"):
+		return false
+	}
+	for _, prefix := range []string{"gpt-5", "gpt-4.1", "gpt-4.5", "gpt-4o", "chatgpt-4o", "o1", "o3", "o4"} {
+		if strings.HasPrefix(modelName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// xaiVision reports whether the xAI model accepts image input: the Grok 4 family and the dedicated vision models.
+func xaiVision(modelName string) bool {
+	return strings.HasPrefix(modelName, "grok-4") || strings.Contains(modelName, "vision")
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gollm
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+)
+
+func TestProviderCapabilities(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		provider  string
+		modelName string
+		want      Capabilities
+	}{
+		"gemini": {
+			provider:  ProviderGemini,
+			modelName: "models/gemini-2.5-flash",
+			want:      Capabilities{Tools: true, NativeTools: true, Vision: true, StructuredOutput: true, Streaming: true, MaxContextTokens: 1_048_576, Embeddings: true},
+		},
+		"gemini embedding": {
+			provider:  ProviderGemini,
+			modelName: "gemini-embedding-001",
+			want:      Capabilities{Tools: true, NativeTools: true, StructuredOutput: true, Streaming: true, MaxContextTokens: 1_048_576, Embeddings: true},
+		},
+		"openai gpt-5": {
+			provider:  ProviderOpenAI,
+			modelName: "gpt-5-mini",
+			want:      Capabilities{Tools: true, NativeTools: true, Vision: true, StructuredOutput: true, Streaming: true, MaxContextTokens: 400_000, Embeddings: true},
+		},
+		"openai o3-mini": {
+			provider:  ProviderOpenAI,
+			modelName: "o3-mini",
+			want:      Capabilities{Tools: true, NativeTools: true, StructuredOutput: true, Streaming: true, MaxContextTokens: 200_000, Embeddings: true},
+		},
+		"anthropic": {
+			provider:  ProviderAnthropic,
+			modelName: "claude-sonnet-4-5",
+			want:      Capabilities{Tools: true, Vision: true, Streaming: true, MaxContextTokens: 200_000},
+		},
+		"xai grok-4-fast": {
+			provider:  ProviderXAI,
+			modelName: "grok-4-fast-reasoning",
+			want:      Capabilities{Tools: true, NativeTools: true, Vision: true, StructuredOutput: true, Streaming: true, MaxContextTokens: 2_000_000, Embeddings: true},
+		},
+		"xai grok-3": {
+			provider:  ProviderXAI,
+			modelName: "grok-3-mini",
+			want:      Capabilities{Tools: true, NativeTools: true, StructuredOutput: true, Streaming: true, MaxContextTokens: 131_072, Embeddings: true},
+		},
+		"unknown model": {
+			provider:  ProviderXAI,
+			modelName: "grok-next",
+			want:      Capabilities{Tools: true, NativeTools: true, StructuredOutput: true, Streaming: true, Embeddings: true},
+		},
+		"unknown provider": {
+			provider:  "ollama",
+			modelName: "llama3",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, ProviderCapabilities(tt.provider, tt.modelName)); diff != "" {
+				t.Fatalf("ProviderCapabilities(%q, %q) mismatch (-want +got):\n%s", tt.provider, tt.modelName, diff)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesIntersect(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		a, b Capabilities
+		want Capabilities
+	}{
+		"shared features": {
+			a:    Capabilities{Tools: true, Vision: true, Streaming: true, MaxContextTokens: 200_000},
+			b:    Capabilities{Tools: true, NativeTools: true, Streaming: true, MaxContextTokens: 1_000_000},
+			want: Capabilities{Tools: true, Streaming: true, MaxContextTokens: 200_000},
+		},
+		"unknown context window": {
+			a:    Capabilities{MaxContextTokens: 0},
+			b:    Capabilities{MaxContextTokens: 128_000},
+			want: Capabilities{MaxContextTokens: 128_000},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, tt.a.Intersect(tt.b)); diff != "" {
+				t.Fatalf("Intersect() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := CapabilitiesOf(&fakeLLM{}); ok {
+		t.Fatal("CapabilitiesOf(unreported) ok = true, want false")
+	}
+
+	caps := Capabilities{Tools: true, MaxContextTokens: 42}
+	var llm model.LLM = WithCapabilities(&fakeLLM{}, caps)
+	got, ok := CapabilitiesOf(llm)
+	if !ok || got != caps {
+		t.Fatalf("CapabilitiesOf(WithCapabilities) = %+v, %t, want %+v, true", got, ok, caps)
+	}
+	if llm.Name() != "fake" {
+		t.Fatalf("Name() = %q, want fake", llm.Name())
+	}
+
+	openai, err := NewOpenAILLM(t.Context(), "key", "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("NewOpenAILLM() error = %v", err)
+	}
+	if got, ok := CapabilitiesOf(openai); !ok || !got.Vision || got.MaxContextTokens != 128_000 {
+		t.Fatalf("CapabilitiesOf(openai) = %+v, %t, want gpt-4o capabilities", got, ok)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package catalog keeps a disk-cached catalog of the models offered by the LLM providers, with their context windows
+// and, where the provider API exposes them, their prices.
+package catalog
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zchee/tumix/pricing"
+)
+
+// Model is the catalog entry of a model.
+type Model struct {
+	// Name is the model name used in API requests, without the Gemini "models/" prefix.
+	Name string `json:"name"`
+	// Aliases are other names the provider accepts for the model.
+	Aliases []string `json:"aliases,omitzero"`
+	// ContextWindow is the maximum number of input tokens, or zero when unknown.
+	ContextWindow int `json:"context_window,omitzero"`
+	// OutputLimit is the maximum number of output tokens, or zero when unknown.
+	OutputLimit int `json:"output_limit,omitzero"`
+	// Price is the list price, or nil when the provider does not publish prices through its API.
+	Price *pricing.Price `json:"price,omitzero"`
+}
+
+// Source lists the models offered by one provider.
+type Source interface {
+	// Provider returns the provider name, such as "gemini".
+	Provider() string
+	// ListModels fetches the models of the provider.
+	ListModels(ctx context.Context) ([]Model, error)
+}
+
+// ProviderModels are the models of one provider as of FetchedAt.
+type ProviderModels struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Models    []Model   `json:"models"`
+}
+
+// Catalog is the model catalog of several providers. The zero value is an empty catalog.
+//
+// A Catalog is not safe for concurrent modification; refresh it at startup before sharing it.
+type Catalog struct {
+	Providers map[string]*ProviderModels `json:"providers"`
+}
+
+// Load reads the catalog cached at path. A missing file yields an empty catalog.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Catalog{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read model catalog: %w", err)
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse model catalog %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the catalog to path, creating its directory as needed. The file is replaced atomically.
+func (c *Catalog) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshal model catalog: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create model catalog dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create model catalog: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // already renamed on success
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write model catalog: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write model catalog: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("save model catalog: %w", err)
+	}
+	return nil
+}
+
+// Refresh fetches the models of every source whose entry is missing or older than maxAge.
+//
+// A source that fails keeps its previous, possibly stale, entry; the failures are joined into the returned error and
+// the other sources are still refreshed. Refresh reports whether any entry changed.
+func (c *Catalog) Refresh(ctx context.Context, maxAge time.Duration, sources ...Source) (bool, error) {
+	now := time.Now()
+	var (
+		changed bool
+		errs    []error
+	)
+	for _, src := range sources {
+		provider := src.Provider()
+		if pm := c.Providers[provider]; pm != nil && now.Sub(pm.FetchedAt) < maxAge {
+			continue
+		}
+		models, err := src.ListModels(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list %s models: %w", provider, err))
+			continue
+		}
+		if c.Providers == nil {
+			c.Providers = make(map[string]*ProviderModels)
+		}
+		c.Providers[provider] = &ProviderModels{FetchedAt: now, Models: models}
+		changed = true
+	}
+	return changed, errors.Join(errs...)
+}
+
+// Lookup returns the entry of the provider model called name or one of its aliases. A "models/" prefix is ignored.
+func (c *Catalog) Lookup(provider, name string) (Model, bool) {
+	pm := c.Providers[provider]
+	if pm == nil {
+		return Model{}, false
+	}
+	name = strings.TrimPrefix(name, "models/")
+	for _, m := range pm.Models {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	for _, m := range pm.Models {
+		for _, alias := range m.Aliases {
+			if alias == name {
+				return m, true
+			}
+		}
+	}
+	return Model{}, false
+}
+
+// ContextWindow returns the context window of the provider model, or zero when it is unknown.
+func (c *Catalog) ContextWindow(provider, name string) int {
+	m, _ := c.Lookup(provider, name)
+	return m.ContextWindow
+}
+
+// ApplyPrices sets the catalog prices, under the model names and aliases, in p and returns the number of models
+// priced.
+func (c *Catalog) ApplyPrices(p *pricing.Catalog) int {
+	n := 0
+	for _, pm := range c.Providers {
+		for _, m := range pm.Models {
+			if m.Price == nil {
+				continue
+			}
+			p.Set(m.Name, *m.Price)
+			for _, alias := range m.Aliases {
+				p.Set(alias, *m.Price)
+			}
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package catalog
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/zchee/tumix/pricing"
+)
+
+type fakeSource struct {
+	provider string
+	models   []Model
+	err      error
+	calls    int
+}
+
+func (f *fakeSource) Provider() string { return f.provider }
+
+func (f *fakeSource) ListModels(context.Context) ([]Model, error) {
+	f.calls++
+	return f.models, f.err
+}
+
+func TestLoadMissing(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(c.Providers) != 0 {
+		t.Fatalf("Load() = %+v, want empty catalog", c)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cache", "models.json")
+	want := &Catalog{Providers: map[string]*ProviderModels{
+		"xai": {
+			FetchedAt: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+			Models: []Model{{
+				Name:          "grok-4-0709",
+				Aliases:       []string{"grok-4"},
+				ContextWindow: 256_000,
+				Price:         &pricing.Price{InputPerKT: 0.003, OutputPerKT: 0.015},
+			}},
+		},
+	}}
+	if err := want.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Load() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+
+	stale := &ProviderModels{FetchedAt: time.Now().Add(-48 * time.Hour), Models: []Model{{Name: "old"}}}
+	fresh := &ProviderModels{FetchedAt: time.Now(), Models: []Model{{Name: "gpt-5"}}}
+	c := &Catalog{Providers: map[string]*ProviderModels{"xai": stale, "openai": fresh, "gemini": stale}}
+
+	xaiSrc := &fakeSource{provider: "xai", models: []Model{{Name: "grok-4"}}}
+	openaiSrc := &fakeSource{provider: "openai"}
+	geminiSrc := &fakeSource{provider: "gemini", err: errors.New("unavailable")}
+
+	changed, err := c.Refresh(t.Context(), 24*time.Hour, xaiSrc, openaiSrc, geminiSrc)
+	if err == nil {
+		t.Fatal("Refresh() error = nil, want gemini error")
+	}
+	if !changed {
+		t.Fatal("Refresh() changed = false, want true")
+	}
+	if xaiSrc.calls != 1 || openaiSrc.calls != 0 || geminiSrc.calls != 1 {
+		t.Fatalf("source calls = xai %d, openai %d, gemini %d, want 1, 0, 1", xaiSrc.calls, openaiSrc.calls, geminiSrc.calls)
+	}
+	if diff := cmp.Diff([]Model{{Name: "grok-4"}}, c.Providers["xai"].Models); diff != "" {
+		t.Fatalf("xai models mismatch (-want +got):\n%s", diff)
+	}
+	if c.Providers["openai"] != fresh || c.Providers["gemini"] != stale {
+		t.Fatal("Refresh() replaced a fresh or failed entry")
+	}
+
+	var empty Catalog
+	if _, err := empty.Refresh(t.Context(), time.Hour, &fakeSource{provider: "xai"}); err != nil {
+		t.Fatalf("Refresh() on an empty catalog error = %v", err)
+	}
+	if _, ok := empty.Providers["xai"]; !ok {
+		t.Fatal("Refresh() on an empty catalog did not add the provider")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	c := &Catalog{Providers: map[string]*ProviderModels{
+		"gemini": {Models: []Model{{Name: "gemini-2.5-flash", ContextWindow: 1_048_576}}},
+		"xai":    {Models: []Model{{Name: "grok-4-0709", Aliases: []string{"grok-4", "grok-4-latest"}, ContextWindow: 256_000}}},
+	}}
+
+	tests := map[string]struct {
+		provider string
+		name     string
+		want     int
+	}{
+		"name":             {provider: "gemini", name: "gemini-2.5-flash", want: 1_048_576},
+		"models prefix":    {provider: "gemini", name: "models/gemini-2.5-flash", want: 1_048_576},
+		"alias":            {provider: "xai", name: "grok-4-latest", want: 256_000},
+		"unknown model":    {provider: "xai", name: "grok-3"},
+		"unknown provider": {provider: "openai", name: "gpt-5"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := c.ContextWindow(tt.provider, tt.name); got != tt.want {
+				t.Fatalf("ContextWindow(%q, %q) = %d, want %d", tt.provider, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPrices(t *testing.T) {
+	t.Parallel()
+
+	price := pricing.Price{InputPerKT: 0.0002, OutputPerKT: 0.0005}
+	c := &Catalog{Providers: map[string]*ProviderModels{
+		"xai":    {Models: []Model{{Name: "grok-new-0101", Aliases: []string{"grok-new"}, Price: &price}}},
+		"gemini": {Models: []Model{{Name: "gemini-2.5-flash"}}},
+	}}
+	prices := pricing.New(nil, "")
+
+	if n := c.ApplyPrices(prices); n != 1 {
+		t.Fatalf("ApplyPrices() = %d, want 1", n)
+	}
+	for _, name := range []string{"grok-new-0101", "grok-new"} {
+		got, ok := prices.Lookup(name)
+		if !ok || got != price {
+			t.Fatalf("Lookup(%q) = %+v, %t, want %+v", name, got, ok, price)
+		}
+	}
+	if _, ok := prices.Lookup("gemini-2.5-flash"); ok {
+		t.Fatal("Lookup(gemini-2.5-flash) found a price, want none")
+	}
+}
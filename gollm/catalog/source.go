@@ -0,0 +1,123 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package catalog
+
+import (
+	"context"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm/xai"
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+	"github.com/zchee/tumix/pricing"
+)
+
+// NewGeminiSource returns a [Source] listing the Gemini models with their token limits. Gemini does not expose prices.
+func NewGeminiSource(client *genai.Client) Source {
+	return &geminiSource{client: client}
+}
+
+type geminiSource struct {
+	client *genai.Client
+}
+
+func (s *geminiSource) Provider() string { return "gemini" }
+
+func (s *geminiSource) ListModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	for m, err := range s.client.Models.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, Model{
+			Name:          strings.TrimPrefix(m.Name, "models/"),
+			ContextWindow: int(m.InputTokenLimit),
+			OutputLimit:   int(m.OutputTokenLimit),
+		})
+	}
+	return models, nil
+}
+
+// NewOpenAISource returns a [Source] listing the OpenAI models. OpenAI exposes neither token limits nor prices.
+func NewOpenAISource(client *openai.Client) Source {
+	return &openAISource{client: client}
+}
+
+type openAISource struct {
+	client *openai.Client
+}
+
+func (s *openAISource) Provider() string { return "openai" }
+
+func (s *openAISource) ListModels(ctx context.Context) ([]Model, error) {
+	pager := s.client.Models.ListAutoPaging(ctx)
+	var models []Model
+	for pager.Next() {
+		models = append(models, Model{Name: pager.Current().ID})
+	}
+	if err := pager.Err(); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// NewXAISource returns a [Source] listing the xAI language models with their context windows and prices.
+func NewXAISource(client *xai.ModelsClient) Source {
+	return &xaiSource{client: client}
+}
+
+type xaiSource struct {
+	client *xai.ModelsClient
+}
+
+func (s *xaiSource) Provider() string { return "xai" }
+
+func (s *xaiSource) ListModels(ctx context.Context) ([]Model, error) {
+	resp, err := s.client.ListLanguageModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]Model, 0, len(resp.GetModels()))
+	for _, m := range resp.GetModels() {
+		models = append(models, xaiModel(m))
+	}
+	return models, nil
+}
+
+// xaiPricePerKT converts an xAI price in 1/100 USD cents per 1M tokens, which is also USD cents per 100M tokens,
+// into USD per 1K tokens.
+func xaiPricePerKT(v int64) float64 {
+	return float64(v) / 1e7
+}
+
+func xaiModel(m *xaipb.LanguageModel) Model {
+	out := Model{
+		Name:          m.GetName(),
+		Aliases:       m.GetAliases(),
+		ContextWindow: int(m.GetMaxPromptLength()),
+	}
+	if m.GetPromptTextTokenPrice() > 0 || m.GetCompletionTextTokenPrice() > 0 {
+		out.Price = &pricing.Price{
+			InputPerKT:  xaiPricePerKT(m.GetPromptTextTokenPrice()),
+			OutputPerKT: xaiPricePerKT(m.GetCompletionTextTokenPrice()),
+			CachedPerKT: xaiPricePerKT(m.GetCachedPromptTokenPrice()),
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package catalog
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"google.golang.org/genai"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+	"github.com/zchee/tumix/pricing"
+)
+
+func newTestServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGeminiSource(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, `{"models":[
+		{"name":"models/gemini-2.5-flash","inputTokenLimit":1048576,"outputTokenLimit":65536},
+		{"name":"models/embedding-001","inputTokenLimit":2048}]}`)
+	client, err := genai.NewClient(t.Context(), &genai.ClientConfig{
+		APIKey:      "key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient() error = %v", err)
+	}
+
+	src := NewGeminiSource(client)
+	if src.Provider() != "gemini" {
+		t.Fatalf("Provider() = %q, want gemini", src.Provider())
+	}
+	got, err := src.ListModels(t.Context())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	want := []Model{
+		{Name: "gemini-2.5-flash", ContextWindow: 1_048_576, OutputLimit: 65_536},
+		{Name: "embedding-001", ContextWindow: 2048},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ListModels() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOpenAISource(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, `{"object":"list","data":[
+		{"id":"gpt-5","object":"model","created":1,"owned_by":"openai"},
+		{"id":"gpt-4o","object":"model","created":1,"owned_by":"openai"}]}`)
+	client := openai.NewClient(option.WithAPIKey("key"), option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+
+	got, err := NewOpenAISource(&client).ListModels(t.Context())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if diff := cmp.Diff([]Model{{Name: "gpt-5"}, {Name: "gpt-4o"}}, got); diff != "" {
+		t.Fatalf("ListModels() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestXAIModel(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		model *xaipb.LanguageModel
+		want  Model
+	}{
+		"priced": {
+			model: &xaipb.LanguageModel{
+				Name:                     "grok-4-0709",
+				Aliases:                  []string{"grok-4"},
+				MaxPromptLength:          256_000,
+				PromptTextTokenPrice:     30_000,
+				CompletionTextTokenPrice: 150_000,
+				CachedPromptTokenPrice:   7_500,
+			},
+			want: Model{
+				Name:          "grok-4-0709",
+				Aliases:       []string{"grok-4"},
+				ContextWindow: 256_000,
+				Price:         &pricing.Price{InputPerKT: 0.003, OutputPerKT: 0.015, CachedPerKT: 0.00075},
+			},
+		},
+		"unpriced": {
+			model: &xaipb.LanguageModel{Name: "grok-beta", MaxPromptLength: 131_072},
+			want:  Model{Name: "grok-beta", ContextWindow: 131_072},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, xaiModel(tt.model)); diff != "" {
+				t.Fatalf("xaiModel() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
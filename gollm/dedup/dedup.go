@@ -0,0 +1,329 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dedup provides a [model.LLM] that serves identical requests issued within one scope, such as a TUMIX
+// round, with a single backend call.
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm"
+)
+
+// MetadataKeyShared is the [model.LLMResponse] custom metadata key set to true on responses replayed from the call of
+// an identical request.
+const MetadataKeyShared = "dedup_shared"
+
+const defaultMaxScopes = 256
+
+// Option configures a dedup [LLM].
+type Option func(*options)
+
+type options struct {
+	maxScopes int
+	meter     metric.Meter
+	onSaved   func()
+}
+
+// WithMaxScopes sets how many scopes keep their responses for replay; the oldest scope is dropped beyond that.
+func WithMaxScopes(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxScopes = n
+		}
+	}
+}
+
+// WithMeter sets the OpenTelemetry meter used to record the saved calls.
+func WithMeter(m metric.Meter) Option {
+	return func(o *options) {
+		if m != nil {
+			o.meter = m
+		}
+	}
+}
+
+// WithOnSaved sets a function called for every saved model call, e.g. to update an expvar.
+func WithOnSaved(fn func()) Option {
+	return func(o *options) {
+		o.onSaved = fn
+	}
+}
+
+// call is one backend call shared by every identical request of a scope.
+//
+// resps and ok are written by the leading request only and read by the others once done is closed.
+type call struct {
+	done  chan struct{}
+	resps []*model.LLMResponse
+	ok    bool
+}
+
+// LLM is a [model.LLM] that hashes every request and, within a scope, fans the response of the first call out to
+// every later or concurrent identical request instead of calling the backend again.
+//
+// Only requests with identical model, contents, generation config, and streaming mode share a call, so the saving
+// comes from candidates with the same instructions and sampling parameters. A failed or abandoned call is not shared;
+// the waiting requests then call the backend themselves. Replayed responses carry [MetadataKeyShared] and no usage
+// metadata, since they cost no tokens.
+type LLM struct {
+	llm   model.LLM
+	scope func(context.Context) string
+	opts  options
+
+	mu     sync.Mutex
+	calls  map[string]map[string]*call
+	scopes []string
+
+	saved        atomic.Int64
+	savedCounter metric.Int64Counter
+}
+
+var _ model.LLM = (*LLM)(nil)
+
+// New returns a dedup [LLM] over llm.
+//
+// scope names the scope of a call's context; requests in an empty scope are never deduplicated.
+func New(llm model.LLM, scope func(context.Context) string, opts ...Option) (*LLM, error) {
+	if llm == nil {
+		return nil, errors.New("dedup: model is required")
+	}
+	if scope == nil {
+		return nil, errors.New("dedup: scope function is required")
+	}
+
+	o := options{
+		maxScopes: defaultMaxScopes,
+		meter:     otel.GetMeterProvider().Meter("tumix/dedup"),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l := &LLM{
+		llm:   llm,
+		scope: scope,
+		opts:  o,
+		calls: make(map[string]map[string]*call),
+	}
+
+	var err error
+	l.savedCounter, err = o.meter.Int64Counter("tumix.dedup.saved_calls", metric.WithDescription("Model calls saved by serving identical requests once"))
+	if err != nil {
+		return nil, fmt.Errorf("init dedup saved counter: %w", err)
+	}
+
+	return l, nil
+}
+
+// Name implements [model.LLM].
+func (l *LLM) Name() string { return l.llm.Name() }
+
+// Capabilities implements [gollm.CapabilityReporter] with the capabilities of the wrapped model, or none when it does
+// not report them.
+func (l *LLM) Capabilities() gollm.Capabilities {
+	caps, _ := gollm.CapabilitiesOf(l.llm)
+	return caps
+}
+
+// Saved returns the number of model calls saved so far.
+func (l *LLM) Saved() int64 { return l.saved.Load() }
+
+// GenerateContent implements [model.LLM].
+func (l *LLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		scope := l.scope(ctx)
+		key, err := requestKey(req, stream)
+		if scope == "" || err != nil {
+			l.forward(ctx, req, stream, yield)
+			return
+		}
+
+		c, leader := l.join(scope, key)
+		if leader {
+			l.lead(ctx, scope, key, c, req, stream, yield)
+			return
+		}
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			yield(nil, ctx.Err())
+			return
+		}
+		if !c.ok {
+			l.forward(ctx, req, stream, yield)
+			return
+		}
+
+		l.saved.Add(1)
+		l.savedCounter.Add(ctx, 1)
+		if l.opts.onSaved != nil {
+			l.opts.onSaved()
+		}
+		for _, resp := range c.resps {
+			if !yield(shared(resp), nil) {
+				return // Consumer stopped
+			}
+		}
+	}
+}
+
+func (l *LLM) forward(ctx context.Context, req *model.LLMRequest, stream bool, yield func(*model.LLMResponse, error) bool) {
+	for resp, err := range l.llm.GenerateContent(ctx, req, stream) {
+		if !yield(resp, err) {
+			return // Consumer stopped
+		}
+	}
+}
+
+// lead calls the backend for c, recording every response for the identical requests of the scope.
+func (l *LLM) lead(ctx context.Context, scope, key string, c *call, req *model.LLMRequest, stream bool, yield func(*model.LLMResponse, error) bool) {
+	ok := false
+	defer func() { l.finish(scope, key, c, ok) }()
+
+	for resp, err := range l.llm.GenerateContent(ctx, req, stream) {
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		// Record a copy: the consumer may mutate resp, e.g. by assigning function call IDs.
+		c.resps = append(c.resps, clone(resp))
+		if !yield(resp, nil) {
+			return // Consumer stopped
+		}
+	}
+	ok = true
+}
+
+// join returns the call of key in scope, reporting whether the caller created it and must lead it.
+func (l *LLM) join(scope, key string) (*call, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	calls, ok := l.calls[scope]
+	if !ok {
+		calls = make(map[string]*call)
+		l.calls[scope] = calls
+		l.scopes = append(l.scopes, scope)
+		for len(l.scopes) > l.opts.maxScopes {
+			delete(l.calls, l.scopes[0])
+			l.scopes = l.scopes[1:]
+		}
+	}
+	if c, ok := calls[key]; ok {
+		return c, false
+	}
+	c := &call{done: make(chan struct{})}
+	calls[key] = c
+	return c, true
+}
+
+// finish releases the requests waiting on c; an unsuccessful call is forgotten so later requests retry it.
+func (l *LLM) finish(scope, key string, c *call, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c.ok = ok
+	if !ok {
+		if calls := l.calls[scope]; calls[key] == c {
+			delete(calls, key)
+		}
+	}
+	close(c.done)
+}
+
+// requestKey hashes everything of req that reaches the backend.
+func requestKey(req *model.LLMRequest, stream bool) (string, error) {
+	if req == nil {
+		return "", errors.New("nil request")
+	}
+	b, err := json.Marshal(struct {
+		Model    string                       `json:"model"`
+		Contents []*genai.Content             `json:"contents"`
+		Config   *genai.GenerateContentConfig `json:"config"`
+		Stream   bool                         `json:"stream"`
+	}{req.Model, req.Contents, req.Config, stream})
+	if err != nil {
+		return "", fmt.Errorf("encode request: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// clone copies resp deeply enough that consumers of the copies cannot observe each other's mutations.
+func clone(resp *model.LLMResponse) *model.LLMResponse {
+	if resp == nil {
+		return nil
+	}
+	r := *resp
+	r.CustomMetadata = maps.Clone(resp.CustomMetadata)
+	if resp.Content != nil {
+		content := *resp.Content
+		content.Parts = make([]*genai.Part, len(resp.Content.Parts))
+		for i, p := range resp.Content.Parts {
+			if p == nil {
+				continue
+			}
+			part := *p
+			if p.FunctionCall != nil {
+				fc := *p.FunctionCall
+				part.FunctionCall = &fc
+			}
+			content.Parts[i] = &part
+		}
+		r.Content = &content
+	}
+	return &r
+}
+
+// shared returns a copy of a recorded response for a request that did not call the backend.
+func shared(resp *model.LLMResponse) *model.LLMResponse {
+	r := clone(resp)
+	if r == nil {
+		return nil
+	}
+	r.UsageMetadata = nil
+	if r.CustomMetadata == nil {
+		r.CustomMetadata = make(map[string]any, 1)
+	}
+	r.CustomMetadata[MetadataKeyShared] = true
+	return r
+}
+
+// IsShared reports whether resp was replayed from the call of an identical request.
+func IsShared(resp *model.LLMResponse) bool {
+	if resp == nil {
+		return false
+	}
+	v, _ := resp.CustomMetadata[MetadataKeyShared].(bool)
+	return v
+}
@@ -0,0 +1,252 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dedup
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm"
+)
+
+type scopeKey struct{}
+
+func withScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+func ctxScope(ctx context.Context) string {
+	scope, _ := ctx.Value(scopeKey{}).(string)
+	return scope
+}
+
+type countingLLM struct {
+	calls atomic.Int64
+	err   error
+}
+
+func (f *countingLLM) Name() string { return "fake" }
+
+func (f *countingLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		f.calls.Add(1)
+		if f.err != nil {
+			yield(nil, f.err)
+			return
+		}
+		yield(&model.LLMResponse{
+			Content:       genai.NewContentFromText("echo: "+req.Contents[0].Parts[0].Text, genai.RoleModel),
+			UsageMetadata: &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 10},
+		}, nil)
+	}
+}
+
+type testCall struct {
+	scope  string
+	prompt string
+	stream bool
+}
+
+func request(prompt string) *model.LLMRequest {
+	return &model.LLMRequest{
+		Model:    "m",
+		Contents: []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)},
+		Config:   &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("be brief", genai.RoleUser)},
+	}
+}
+
+func TestGenerateContentDedup(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		calls      []testCall
+		err        error
+		wantCalls  int64
+		wantSaved  int64
+		wantShared []bool
+	}{
+		"identical requests in a scope share one call": {
+			calls:      []testCall{{scope: "r1", prompt: "q"}, {scope: "r1", prompt: "q"}, {scope: "r1", prompt: "q"}},
+			wantCalls:  1,
+			wantSaved:  2,
+			wantShared: []bool{false, true, true},
+		},
+		"scopes do not share": {
+			calls:      []testCall{{scope: "r1", prompt: "q"}, {scope: "r2", prompt: "q"}},
+			wantCalls:  2,
+			wantShared: []bool{false, false},
+		},
+		"different requests do not share": {
+			calls:      []testCall{{scope: "r1", prompt: "q"}, {scope: "r1", prompt: "other"}, {scope: "r1", prompt: "q", stream: true}},
+			wantCalls:  3,
+			wantShared: []bool{false, false, false},
+		},
+		"empty scope is never deduplicated": {
+			calls:      []testCall{{prompt: "q"}, {prompt: "q"}},
+			wantCalls:  2,
+			wantShared: []bool{false, false},
+		},
+		"failed calls are not shared": {
+			calls:      []testCall{{scope: "r1", prompt: "q"}, {scope: "r1", prompt: "q"}},
+			err:        errors.New("boom"),
+			wantCalls:  2,
+			wantShared: []bool{false, false},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &countingLLM{err: tt.err}
+			llm, err := New(backend, ctxScope)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			var gotShared []bool
+			for _, c := range tt.calls {
+				shared := false
+				for resp, err := range llm.GenerateContent(withScope(t.Context(), c.scope), request(c.prompt), c.stream) {
+					if err != nil {
+						if tt.err == nil {
+							t.Fatalf("GenerateContent() error = %v", err)
+						}
+						continue
+					}
+					if got, want := resp.Content.Parts[0].Text, "echo: "+c.prompt; got != want {
+						t.Fatalf("response text = %q, want %q", got, want)
+					}
+					shared = IsShared(resp)
+					if shared && resp.UsageMetadata != nil {
+						t.Fatalf("shared response carries usage %+v, want none", resp.UsageMetadata)
+					}
+				}
+				gotShared = append(gotShared, shared)
+			}
+
+			if got := backend.calls.Load(); got != tt.wantCalls {
+				t.Fatalf("backend calls = %d, want %d", got, tt.wantCalls)
+			}
+			if got := llm.Saved(); got != tt.wantSaved {
+				t.Fatalf("Saved() = %d, want %d", got, tt.wantSaved)
+			}
+			if diff := cmp.Diff(tt.wantShared, gotShared); diff != "" {
+				t.Fatalf("shared responses mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateContentDedupConcurrent(t *testing.T) {
+	t.Parallel()
+
+	backend := &countingLLM{}
+	llm, err := New(backend, ctxScope)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := withScope(t.Context(), "round-1")
+	var wg sync.WaitGroup
+	texts := make([]string, 8)
+	for i := range texts {
+		wg.Go(func() {
+			for resp, err := range llm.GenerateContent(ctx, request("q"), false) {
+				if err != nil {
+					t.Errorf("GenerateContent() error = %v", err)
+					return
+				}
+				texts[i] = resp.Content.Parts[0].Text
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := backend.calls.Load(); got != 1 {
+		t.Fatalf("backend calls = %d, want 1", got)
+	}
+	if got := llm.Saved(); got != 7 {
+		t.Fatalf("Saved() = %d, want 7", got)
+	}
+	for i, text := range texts {
+		if text != "echo: q" {
+			t.Fatalf("response %d = %q, want %q", i, text, "echo: q")
+		}
+	}
+}
+
+func TestMaxScopes(t *testing.T) {
+	t.Parallel()
+
+	backend := &countingLLM{}
+	llm, err := New(backend, ctxScope, WithMaxScopes(1))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, scope := range []string{"r1", "r2", "r1"} {
+		for _, err := range llm.GenerateContent(withScope(t.Context(), scope), request("q"), false) {
+			if err != nil {
+				t.Fatalf("GenerateContent() error = %v", err)
+			}
+		}
+	}
+	// r1 was dropped when r2 started, so its third request calls the backend again.
+	if got := backend.calls.Load(); got != 3 {
+		t.Fatalf("backend calls = %d, want 3", got)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(nil, ctxScope); err == nil {
+		t.Fatal("New(nil model) error = nil, want error")
+	}
+	if _, err := New(&countingLLM{}, nil); err == nil {
+		t.Fatal("New(nil scope) error = nil, want error")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	caps := gollm.Capabilities{Tools: true, Streaming: true, MaxContextTokens: 8}
+	l, err := New(gollm.WithCapabilities(&countingLLM{}, caps), ctxScope)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if diff := cmp.Diff(caps, l.Capabilities()); diff != "" {
+		t.Fatalf("Capabilities() mismatch (-want +got):\n%s", diff)
+	}
+
+	l, err = New(&countingLLM{}, ctxScope)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if diff := cmp.Diff(gollm.Capabilities{}, l.Capabilities()); diff != "" {
+		t.Fatalf("Capabilities() of an unreported model mismatch (-want +got):\n%s", diff)
+	}
+}
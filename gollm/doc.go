@@ -15,4 +15,10 @@
 // SPDX-License-Identifier: Apache-2.0
 
 // Package gollm provides ADK LLM integrations backed by external providers.
+//
+// [NewModel] additionally returns a provider-agnostic [Model] for Gemini, OpenAI and xAI that generates, streams,
+// counts tokens and embeds without ADK request plumbing.
+//
+// The LLMs report their [Capabilities] through [CapabilityReporter]; [WithCapabilities] attaches them to models
+// created elsewhere, such as the ADK Gemini model.
 package gollm
@@ -0,0 +1,370 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package failover provides a [model.LLM] that transparently fails over across an ordered list of backends.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/zchee/tumix/gollm"
+	"github.com/zchee/tumix/log"
+)
+
+// MetadataKeyBackend is the [model.LLMResponse] custom metadata key holding the name of the backend that served the call.
+const MetadataKeyBackend = "failover_backend"
+
+const defaultCooldown = 30 * time.Second
+
+// Option configures a failover [LLM].
+type Option func(*options)
+
+type options struct {
+	shouldFailover func(error) bool
+	cooldown       time.Duration
+	meter          metric.Meter
+	now            func() time.Time
+}
+
+// WithClassifier overrides the function deciding whether an error triggers failover to the next backend.
+//
+// The default is [ShouldFailover].
+func WithClassifier(fn func(error) bool) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.shouldFailover = fn
+		}
+	}
+}
+
+// WithCooldown sets how long a backend is considered unhealthy after a failover-triggering error.
+//
+// Unhealthy backends are tried only after every healthy backend has failed.
+func WithCooldown(d time.Duration) Option {
+	return func(o *options) {
+		if d >= 0 {
+			o.cooldown = d
+		}
+	}
+}
+
+// WithMeter sets the OpenTelemetry meter used to record per-backend metrics.
+func WithMeter(m metric.Meter) Option {
+	return func(o *options) {
+		if m != nil {
+			o.meter = m
+		}
+	}
+}
+
+// Health is a point-in-time snapshot of a backend's health.
+type Health struct {
+	// Name is the backend model name.
+	Name string
+	// Healthy reports whether the backend is outside its cooldown window.
+	Healthy bool
+	// ConsecutiveFailures counts failover-triggering errors since the last successful call.
+	ConsecutiveFailures int
+	// Served counts calls answered by the backend.
+	Served int64
+	// Failed counts failover-triggering errors returned by the backend.
+	Failed int64
+}
+
+type backend struct {
+	llm model.LLM
+
+	mu             sync.Mutex
+	failures       int
+	unhealthyUntil time.Time
+	served         int64
+	failed         int64
+}
+
+func (b *backend) healthy(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.unhealthyUntil)
+}
+
+func (b *backend) markServed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.unhealthyUntil = time.Time{}
+	b.served++
+}
+
+func (b *backend) markFailed(now time.Time, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.failed++
+	b.unhealthyUntil = now.Add(cooldown)
+}
+
+// LLM is a [model.LLM] that calls its backends in order and fails over to the next one on quota or availability errors.
+//
+// Failover happens per call and only before the first response has been yielded; errors surfacing mid-stream are
+// returned to the caller as-is. Each yielded response carries the serving backend name under [MetadataKeyBackend].
+type LLM struct {
+	backends []*backend
+	opts     options
+
+	servedCounter   metric.Int64Counter
+	failoverCounter metric.Int64Counter
+}
+
+var _ model.LLM = (*LLM)(nil)
+
+// New returns a failover [LLM] over backends, tried in the given order.
+func New(backends []model.LLM, opts ...Option) (*LLM, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("failover: at least one backend is required")
+	}
+
+	o := options{
+		shouldFailover: ShouldFailover,
+		cooldown:       defaultCooldown,
+		meter:          otel.GetMeterProvider().Meter("tumix/failover"),
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l := &LLM{
+		backends: make([]*backend, 0, len(backends)),
+		opts:     o,
+	}
+	for i, b := range backends {
+		if b == nil {
+			return nil, fmt.Errorf("failover: backend %d is nil", i)
+		}
+		l.backends = append(l.backends, &backend{llm: b})
+	}
+
+	var err error
+	l.servedCounter, err = o.meter.Int64Counter("tumix.failover.served", metric.WithDescription("Model calls served per backend"))
+	if err != nil {
+		return nil, fmt.Errorf("init failover served counter: %w", err)
+	}
+	l.failoverCounter, err = o.meter.Int64Counter("tumix.failover.failovers", metric.WithDescription("Failover-triggering errors per backend"))
+	if err != nil {
+		return nil, fmt.Errorf("init failover failovers counter: %w", err)
+	}
+
+	return l, nil
+}
+
+// Name implements [model.LLM].
+//
+// It reports the primary backend name.
+func (l *LLM) Name() string { return l.backends[0].llm.Name() }
+
+// Capabilities implements [gollm.CapabilityReporter].
+//
+// It reports the capabilities shared by every backend, since any of them may serve a call; a backend that does not
+// report its capabilities is assumed to support nothing.
+func (l *LLM) Capabilities() gollm.Capabilities {
+	caps, _ := gollm.CapabilitiesOf(l.backends[0].llm)
+	for _, b := range l.backends[1:] {
+		bc, _ := gollm.CapabilitiesOf(b.llm)
+		caps = caps.Intersect(bc)
+	}
+	return caps
+}
+
+// Health returns a snapshot of every backend in failover order.
+func (l *LLM) Health() []Health {
+	now := l.opts.now()
+	out := make([]Health, 0, len(l.backends))
+	for _, b := range l.backends {
+		b.mu.Lock()
+		out = append(out, Health{
+			Name:                b.llm.Name(),
+			Healthy:             !now.Before(b.unhealthyUntil),
+			ConsecutiveFailures: b.failures,
+			Served:              b.served,
+			Failed:              b.failed,
+		})
+		b.mu.Unlock()
+	}
+	return out
+}
+
+// GenerateContent implements [model.LLM].
+func (l *LLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var errs []error
+		for _, b := range l.order() {
+			name := b.llm.Name()
+			yielded := false
+			var callErr error
+
+			for resp, err := range b.llm.GenerateContent(ctx, l.backendRequest(req, name), stream) {
+				if err != nil {
+					callErr = err
+					break
+				}
+				yielded = true
+				if !yield(annotate(resp, name), nil) {
+					l.served(ctx, b)
+					return // Consumer stopped
+				}
+			}
+
+			switch {
+			case callErr == nil:
+				l.served(ctx, b)
+				return
+			case yielded || ctx.Err() != nil || !l.opts.shouldFailover(callErr):
+				yield(nil, callErr)
+				return
+			}
+
+			b.markFailed(l.opts.now(), l.opts.cooldown)
+			l.failoverCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("backend", name)))
+			log.Warn(ctx, "model backend failed; failing over", "backend", name, "error", callErr)
+			errs = append(errs, fmt.Errorf("%s: %w", name, callErr))
+		}
+
+		yield(nil, fmt.Errorf("failover: all backends failed: %w", errors.Join(errs...)))
+	}
+}
+
+// order returns healthy backends first, then the unhealthy ones as a last resort, each group in configured order.
+func (l *LLM) order() []*backend {
+	now := l.opts.now()
+	healthy := make([]*backend, 0, len(l.backends))
+	var unhealthy []*backend
+	for _, b := range l.backends {
+		if b.healthy(now) {
+			healthy = append(healthy, b)
+			continue
+		}
+		unhealthy = append(unhealthy, b)
+	}
+	return append(healthy, unhealthy...)
+}
+
+// backendRequest rewrites the request model name for the given backend.
+//
+// ADK sets [model.LLMRequest.Model] to the primary name, which must not leak into other backends.
+func (l *LLM) backendRequest(req *model.LLMRequest, name string) *model.LLMRequest {
+	if req == nil || req.Model == "" || req.Model != l.Name() || name == l.Name() {
+		return req
+	}
+	r := *req
+	r.Model = name
+	return &r
+}
+
+func (l *LLM) served(ctx context.Context, b *backend) {
+	b.markServed()
+	l.servedCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("backend", b.llm.Name())))
+}
+
+func annotate(resp *model.LLMResponse, name string) *model.LLMResponse {
+	if resp == nil {
+		return nil
+	}
+	if resp.CustomMetadata == nil {
+		resp.CustomMetadata = make(map[string]any, 1)
+	}
+	resp.CustomMetadata[MetadataKeyBackend] = name
+	return resp
+}
+
+// BackendFromResponse returns the name of the backend that served resp, if it was produced by a failover [LLM].
+func BackendFromResponse(resp *model.LLMResponse) string {
+	if resp == nil {
+		return ""
+	}
+	name, _ := resp.CustomMetadata[MetadataKeyBackend].(string)
+	return name
+}
+
+// ShouldFailover reports whether err is a quota or availability error worth retrying on another backend.
+//
+// It understands gRPC status errors (xAI), [genai.APIError] (Gemini), and the OpenAI and Anthropic SDK errors.
+func ShouldFailover(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unknown {
+		switch st.Code() {
+		case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var gerr genai.APIError
+	if errors.As(err, &gerr) {
+		return failoverHTTPStatus(gerr.Code)
+	}
+	var oerr *openai.Error
+	if errors.As(err, &oerr) {
+		return failoverHTTPStatus(oerr.StatusCode)
+	}
+	var aerr *anthropic.Error
+	if errors.As(err, &aerr) {
+		return failoverHTTPStatus(aerr.StatusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"rate limit", "quota", "overloaded", "unavailable", "resource_exhausted", "too many requests"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func failoverHTTPStatus(code int) bool {
+	switch {
+	case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout:
+		return true
+	case code >= http.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
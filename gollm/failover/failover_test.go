@@ -0,0 +1,254 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/zchee/tumix/gollm"
+)
+
+type fakeLLM struct {
+	name  string
+	err   error
+	calls int
+	model string
+}
+
+func (f *fakeLLM) Name() string { return f.name }
+
+func (f *fakeLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		f.calls++
+		f.model = req.Model
+		if f.err != nil {
+			yield(nil, f.err)
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(f.name, genai.RoleModel)}, nil)
+	}
+}
+
+func collect(t *testing.T, llm model.LLM, req *model.LLMRequest) (string, error) {
+	t.Helper()
+
+	var backend string
+	for resp, err := range llm.GenerateContent(t.Context(), req, false) {
+		if err != nil {
+			return backend, err
+		}
+		backend = BackendFromResponse(resp)
+	}
+	return backend, nil
+}
+
+func TestGenerateContentFailover(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		errs        []error
+		wantBackend string
+		wantErr     bool
+		wantCalls   []int
+	}{
+		"success: primary serves": {
+			errs:        []error{nil, nil},
+			wantBackend: "primary",
+			wantCalls:   []int{1, 0},
+		},
+		"success: quota error fails over": {
+			errs:        []error{status.Error(codes.ResourceExhausted, "quota"), nil},
+			wantBackend: "secondary",
+			wantCalls:   []int{1, 1},
+		},
+		"success: http 503 fails over": {
+			errs:        []error{genai.APIError{Code: 503}, nil},
+			wantBackend: "secondary",
+			wantCalls:   []int{1, 1},
+		},
+		"error: non-retryable error is returned": {
+			errs:      []error{status.Error(codes.InvalidArgument, "bad"), nil},
+			wantErr:   true,
+			wantCalls: []int{1, 0},
+		},
+		"error: all backends fail": {
+			errs:      []error{status.Error(codes.Unavailable, "down"), genai.APIError{Code: 429}},
+			wantErr:   true,
+			wantCalls: []int{1, 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fakes := []*fakeLLM{{name: "primary", err: tt.errs[0]}, {name: "secondary", err: tt.errs[1]}}
+			llm, err := New([]model.LLM{fakes[0], fakes[1]})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			backend, err := collect(t, llm, &model.LLMRequest{Model: "primary"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if backend != tt.wantBackend {
+				t.Fatalf("backend = %q, want %q", backend, tt.wantBackend)
+			}
+			gotCalls := []int{fakes[0].calls, fakes[1].calls}
+			if diff := cmp.Diff(tt.wantCalls, gotCalls); diff != "" {
+				t.Fatalf("calls mismatch (-want +got):\n%s", diff)
+			}
+			if fakes[1].calls > 0 && fakes[1].model != "secondary" {
+				t.Fatalf("secondary request model = %q, want secondary", fakes[1].model)
+			}
+		})
+	}
+}
+
+func TestHealthCooldown(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	primary := &fakeLLM{name: "primary", err: status.Error(codes.Unavailable, "down")}
+	secondary := &fakeLLM{name: "secondary"}
+	llm, err := New([]model.LLM{primary, secondary}, WithCooldown(time.Minute))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	llm.opts.now = func() time.Time { return now }
+
+	if _, err := collect(t, llm, &model.LLMRequest{}); err != nil {
+		t.Fatalf("first call error = %v", err)
+	}
+
+	want := []Health{
+		{Name: "primary", ConsecutiveFailures: 1, Failed: 1},
+		{Name: "secondary", Healthy: true, Served: 1},
+	}
+	if diff := cmp.Diff(want, llm.Health()); diff != "" {
+		t.Fatalf("Health() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The unhealthy primary is skipped during its cooldown.
+	if _, err := collect(t, llm, &model.LLMRequest{}); err != nil {
+		t.Fatalf("second call error = %v", err)
+	}
+	if primary.calls != 1 {
+		t.Fatalf("primary calls = %d, want 1", primary.calls)
+	}
+
+	// After the cooldown the recovered primary is preferred again.
+	now = now.Add(2 * time.Minute)
+	primary.err = nil
+	backend, err := collect(t, llm, &model.LLMRequest{})
+	if err != nil {
+		t.Fatalf("third call error = %v", err)
+	}
+	if backend != "primary" {
+		t.Fatalf("backend = %q, want primary", backend)
+	}
+	if got := llm.Health()[0]; got.ConsecutiveFailures != 0 || !got.Healthy {
+		t.Fatalf("primary health = %+v, want recovered", got)
+	}
+}
+
+func TestShouldFailover(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"nil":                {err: nil, want: false},
+		"canceled":           {err: context.Canceled, want: false},
+		"deadline":           {err: context.DeadlineExceeded, want: true},
+		"grpc exhausted":     {err: status.Error(codes.ResourceExhausted, "x"), want: true},
+		"grpc permission":    {err: status.Error(codes.PermissionDenied, "x"), want: false},
+		"genai 429":          {err: genai.APIError{Code: 429}, want: true},
+		"genai 400":          {err: genai.APIError{Code: 400}, want: false},
+		"message rate limit": {err: errors.New("rate limit exceeded"), want: true},
+		"plain":              {err: errors.New("boom"), want: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ShouldFailover(tt.err); got != tt.want {
+				t.Fatalf("ShouldFailover(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequiresBackend(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(nil); err == nil {
+		t.Fatal("New(nil) error = nil, want error")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	gemini := gollm.WithCapabilities(&fakeLLM{name: "gemini"}, gollm.ProviderCapabilities(gollm.ProviderGemini, "gemini-2.5-flash"))
+	anthropic := gollm.WithCapabilities(&fakeLLM{name: "anthropic"}, gollm.ProviderCapabilities(gollm.ProviderAnthropic, "claude-sonnet-4-5"))
+
+	tests := map[string]struct {
+		backends []model.LLM
+		want     gollm.Capabilities
+	}{
+		"single backend": {
+			backends: []model.LLM{gemini},
+			want:     gollm.ProviderCapabilities(gollm.ProviderGemini, "gemini-2.5-flash"),
+		},
+		"shared capabilities": {
+			backends: []model.LLM{gemini, anthropic},
+			want:     gollm.Capabilities{Tools: true, Vision: true, Streaming: true, MaxContextTokens: 200_000},
+		},
+		"unreported backend": {
+			backends: []model.LLM{gemini, &fakeLLM{name: "unknown"}},
+			want:     gollm.Capabilities{MaxContextTokens: 1_048_576},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			llm, err := New(tt.backends)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, llm.Capabilities()); diff != "" {
+				t.Fatalf("Capabilities() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
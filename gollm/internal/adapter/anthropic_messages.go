@@ -17,8 +17,10 @@
 package adapter
 
 import (
+	"encoding/base64"
 	json "encoding/json/v2"
 	"fmt"
+	"slices"
 	"strings"
 
 	anthropic "github.com/anthropics/anthropic-sdk-go"
@@ -62,6 +64,20 @@ func GenAIToAnthropicMessages(system *genai.Content, contents []*genai.Content)
 			case part.Text != "":
 				mp.Content = append(mp.Content, anthropic.NewBetaTextBlock(part.Text))
 
+			case part.InlineData != nil:
+				block, err := inlineDataToAnthropicBlock(part.InlineData)
+				if err != nil {
+					return nil, nil, fmt.Errorf("content[%d] part[%d]: %w", idx, pi, err)
+				}
+				mp.Content = append(mp.Content, block)
+
+			case part.FileData != nil:
+				block, err := fileDataToAnthropicBlock(part.FileData)
+				if err != nil {
+					return nil, nil, fmt.Errorf("content[%d] part[%d]: %w", idx, pi, err)
+				}
+				mp.Content = append(mp.Content, block)
+
 			case part.FunctionCall != nil:
 				fc := part.FunctionCall
 				if fc.Name == "" {
@@ -117,3 +133,41 @@ func GenAIToAnthropicMessages(system *genai.Content, contents []*genai.Content)
 
 	return systemBlocks, msgs, nil
 }
+
+// inlineDataToAnthropicBlock converts inline image, PDF, and text data into an Anthropic image or document block.
+func inlineDataToAnthropicBlock(b *genai.Blob) (anthropic.BetaContentBlockParamUnion, error) {
+	switch mediaType := anthropic.BetaBase64ImageSourceMediaType(b.MIMEType); {
+	case slices.Contains([]anthropic.BetaBase64ImageSourceMediaType{
+		anthropic.BetaBase64ImageSourceMediaTypeImageJPEG,
+		anthropic.BetaBase64ImageSourceMediaTypeImagePNG,
+		anthropic.BetaBase64ImageSourceMediaTypeImageGIF,
+		anthropic.BetaBase64ImageSourceMediaTypeImageWebP,
+	}, mediaType):
+		return anthropic.NewBetaImageBlock(anthropic.BetaBase64ImageSourceParam{
+			Data:      base64.StdEncoding.EncodeToString(b.Data),
+			MediaType: mediaType,
+		}), nil
+	case b.MIMEType == "application/pdf":
+		return anthropic.NewBetaDocumentBlock(anthropic.BetaBase64PDFSourceParam{
+			Data: base64.StdEncoding.EncodeToString(b.Data),
+		}), nil
+	case isTextMIME(b.MIMEType):
+		return anthropic.NewBetaDocumentBlock(anthropic.BetaPlainTextSourceParam{
+			Data: string(b.Data),
+		}), nil
+	default:
+		return anthropic.BetaContentBlockParamUnion{}, fmt.Errorf("unsupported inline data type %q", b.MIMEType)
+	}
+}
+
+// fileDataToAnthropicBlock converts an image or PDF URL into an Anthropic image or document block.
+func fileDataToAnthropicBlock(f *genai.FileData) (anthropic.BetaContentBlockParamUnion, error) {
+	switch {
+	case isImageMIME(f.MIMEType):
+		return anthropic.NewBetaImageBlock(anthropic.BetaURLImageSourceParam{URL: f.FileURI}), nil
+	case f.MIMEType == "application/pdf":
+		return anthropic.NewBetaDocumentBlock(anthropic.BetaURLPDFSourceParam{URL: f.FileURI}), nil
+	default:
+		return anthropic.BetaContentBlockParamUnion{}, fmt.Errorf("unsupported file data type %q", f.MIMEType)
+	}
+}
@@ -71,6 +71,48 @@ func TestGenAIToAnthropicMessages(t *testing.T) {
 	}
 }
 
+func TestGenAIToAnthropicMessagesMedia(t *testing.T) {
+	t.Parallel()
+
+	_, msgs, err := GenAIToAnthropicMessages(nil, []*genai.Content{
+		{
+			Role: genai.RoleUser,
+			Parts: []*genai.Part{
+				genai.NewPartFromText("Describe these."),
+				genai.NewPartFromBytes([]byte("png"), "image/png"),
+				genai.NewPartFromBytes([]byte("pdf"), "application/pdf"),
+				genai.NewPartFromBytes([]byte("notes"), "text/plain"),
+				genai.NewPartFromURI("https://example.com/cat.jpg", "image/jpeg"),
+				genai.NewPartFromURI("https://example.com/doc.pdf", "application/pdf"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GenAIToAnthropicMessages() error = %v", err)
+	}
+	if len(msgs) != 1 || len(msgs[0].Content) != 6 {
+		t.Fatalf("messages = %+v, want one message with 6 blocks", msgs)
+	}
+
+	blocks := msgs[0].Content
+	if src := blocks[1].OfImage; src == nil || src.Source.OfBase64 == nil || src.Source.OfBase64.Data != "cG5n" ||
+		src.Source.OfBase64.MediaType != anthropic.BetaBase64ImageSourceMediaTypeImagePNG {
+		t.Fatalf("blocks[1] = %+v, want base64 PNG image", blocks[1])
+	}
+	if doc := blocks[2].OfDocument; doc == nil || doc.Source.OfBase64 == nil || doc.Source.OfBase64.Data != "cGRm" {
+		t.Fatalf("blocks[2] = %+v, want base64 PDF document", blocks[2])
+	}
+	if doc := blocks[3].OfDocument; doc == nil || doc.Source.OfText == nil || doc.Source.OfText.Data != "notes" {
+		t.Fatalf("blocks[3] = %+v, want plain text document", blocks[3])
+	}
+	if img := blocks[4].OfImage; img == nil || img.Source.OfURL == nil || img.Source.OfURL.URL != "https://example.com/cat.jpg" {
+		t.Fatalf("blocks[4] = %+v, want URL image", blocks[4])
+	}
+	if doc := blocks[5].OfDocument; doc == nil || doc.Source.OfURL == nil || doc.Source.OfURL.URL != "https://example.com/doc.pdf" {
+		t.Fatalf("blocks[5] = %+v, want URL PDF document", blocks[5])
+	}
+}
+
 func TestGenAIToAnthropicMessagesErrors(t *testing.T) {
 	t.Parallel()
 
@@ -97,11 +139,16 @@ func TestGenAIToAnthropicMessagesErrors(t *testing.T) {
 			Parts: []*genai.Part{
 				{
 					InlineData: &genai.Blob{
-						MIMEType: "text/plain",
+						MIMEType: "image/tiff",
 					},
 				},
 			},
 		},
+		"unsupported file data": {
+			Parts: []*genai.Part{
+				genai.NewPartFromURI("https://example.com/a.wav", "audio/wav"),
+			},
+		},
 		"empty parts": {
 			Parts: []*genai.Part{},
 		},
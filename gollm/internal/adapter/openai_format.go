@@ -0,0 +1,351 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+	"google.golang.org/genai"
+)
+
+// openAIDefaultSchemaName names response schemas without a usable title.
+const openAIDefaultSchemaName = "response"
+
+var openAISchemaNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// GenAIToResponsesFormat maps the GenAI structured-output settings of cfg to a Responses text format.
+//
+// ResponseJsonSchema takes precedence over ResponseSchema; both become a json_schema format, in strict mode when the
+// schema can be made strict-compatible (see [StrictJSONSchema]). A JSON ResponseMIMEType without a schema selects
+// JSON mode. The second result is false when no structured output is requested.
+func GenAIToResponsesFormat(cfg *genai.GenerateContentConfig) (responses.ResponseFormatTextConfigUnionParam, bool, error) {
+	var format responses.ResponseFormatTextConfigUnionParam
+	if cfg == nil {
+		return format, false, nil
+	}
+
+	var (
+		schema map[string]any
+		title  string
+	)
+	switch {
+	case cfg.ResponseJsonSchema != nil:
+		params, err := toFunctionParameters(cfg.ResponseJsonSchema)
+		if err != nil {
+			return format, false, fmt.Errorf("convert response json schema: %w", err)
+		}
+		schema = params
+		title, _ = schema["title"].(string)
+	case cfg.ResponseSchema != nil:
+		schema = GenAISchemaToJSONSchema(cfg.ResponseSchema)
+		title = cfg.ResponseSchema.Title
+	default:
+		mime := strings.ToLower(strings.TrimSpace(cfg.ResponseMIMEType))
+		if !strings.HasPrefix(mime, "application/json") {
+			return format, false, nil
+		}
+		format.OfJSONObject = &shared.ResponseFormatJSONObjectParam{}
+		return format, true, nil
+	}
+	if len(schema) == 0 {
+		return format, false, errors.New("response schema is empty")
+	}
+
+	strictSchema, strict := StrictJSONSchema(schema)
+	if strict {
+		schema = strictSchema
+	}
+	format.OfJSONSchema = &responses.ResponseFormatTextJSONSchemaConfigParam{
+		Name:   openAISchemaName(title),
+		Schema: schema,
+		Strict: param.NewOpt(strict),
+	}
+	if desc, ok := schema["description"].(string); ok && desc != "" {
+		format.OfJSONSchema.Description = param.NewOpt(desc)
+	}
+	return format, true, nil
+}
+
+// openAISchemaName derives a json_schema name, which must match ^[a-zA-Z0-9_-]{1,64}$, from a schema title.
+func openAISchemaName(title string) string {
+	name := strings.Trim(openAISchemaNameInvalid.ReplaceAllString(strings.TrimSpace(title), "_"), "_")
+	if name == "" {
+		return openAIDefaultSchemaName
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// GenAISchemaToJSONSchema converts a GenAI [genai.Schema] into a JSON Schema object.
+//
+// Types are lower-cased and Nullable becomes a ["type", "null"] union; PropertyOrdering has no JSON Schema equivalent
+// and is dropped.
+func GenAISchemaToJSONSchema(s *genai.Schema) map[string]any {
+	if s == nil {
+		return nil
+	}
+
+	out := make(map[string]any)
+	if s.Type != "" && s.Type != genai.TypeUnspecified {
+		typ := strings.ToLower(string(s.Type))
+		if s.Nullable != nil && *s.Nullable {
+			out["type"] = []any{typ, "null"}
+		} else {
+			out["type"] = typ
+		}
+	}
+	if s.Title != "" {
+		out["title"] = s.Title
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if s.Pattern != "" {
+		out["pattern"] = s.Pattern
+	}
+	if len(s.Enum) > 0 {
+		enum := make([]any, len(s.Enum))
+		for i, v := range s.Enum {
+			enum[i] = v
+		}
+		out["enum"] = enum
+	}
+	if s.Default != nil {
+		out["default"] = s.Default
+	}
+	if s.Items != nil {
+		out["items"] = GenAISchemaToJSONSchema(s.Items)
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = GenAISchemaToJSONSchema(prop)
+		}
+		out["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		out["required"] = toAnySlice(s.Required)
+	}
+	if len(s.AnyOf) > 0 {
+		anyOf := make([]any, 0, len(s.AnyOf))
+		for _, sub := range s.AnyOf {
+			anyOf = append(anyOf, GenAISchemaToJSONSchema(sub))
+		}
+		out["anyOf"] = anyOf
+	}
+	setInt := func(key string, v *int64) {
+		if v != nil {
+			out[key] = *v
+		}
+	}
+	setInt("minItems", s.MinItems)
+	setInt("maxItems", s.MaxItems)
+	setInt("minLength", s.MinLength)
+	setInt("maxLength", s.MaxLength)
+	setInt("minProperties", s.MinProperties)
+	setInt("maxProperties", s.MaxProperties)
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+
+	return out
+}
+
+// StrictJSONSchema returns a copy of schema rewritten for OpenAI structured outputs strict mode, and whether the
+// schema could be made strict-compatible.
+//
+// Strict mode requires every object to list all of its properties as required and to disallow additional
+// properties; optional properties are therefore made nullable instead. Objects that explicitly allow additional
+// properties cannot be expressed in strict mode.
+func StrictJSONSchema(schema map[string]any) (map[string]any, bool) {
+	out := maps.Clone(schema)
+	ok := strictify(out)
+	return out, ok
+}
+
+func strictify(s map[string]any) bool {
+	ok := true
+
+	if props, isObj := s["properties"].(map[string]any); isObj || schemaHasType(s, "object") {
+		switch ap := s["additionalProperties"].(type) {
+		case nil:
+			s["additionalProperties"] = false
+		case bool:
+			if ap {
+				ok = false
+			}
+		default:
+			ok = false
+		}
+
+		props = maps.Clone(props)
+		required := make(map[string]bool)
+		if req, isList := s["required"].([]any); isList {
+			for _, r := range req {
+				if name, isStr := r.(string); isStr {
+					required[name] = true
+				}
+			}
+		} else if req, isList := s["required"].([]string); isList {
+			for _, name := range req {
+				required[name] = true
+			}
+		}
+
+		names := slices.Sorted(maps.Keys(props))
+		for _, name := range names {
+			sub, isMap := props[name].(map[string]any)
+			if !isMap {
+				continue
+			}
+			sub = maps.Clone(sub)
+			if !strictify(sub) {
+				ok = false
+			}
+			if !required[name] {
+				makeNullable(sub)
+			}
+			props[name] = sub
+		}
+		if props == nil {
+			props = map[string]any{}
+		}
+		s["properties"] = props
+		s["required"] = toAnySlice(names)
+	}
+
+	if items, isMap := s["items"].(map[string]any); isMap {
+		items = maps.Clone(items)
+		if !strictify(items) {
+			ok = false
+		}
+		s["items"] = items
+	}
+	for _, key := range []string{"anyOf", "allOf", "oneOf"} {
+		subs, isList := s[key].([]any)
+		if !isList {
+			continue
+		}
+		subs = slices.Clone(subs)
+		for i, sub := range subs {
+			if m, isMap := sub.(map[string]any); isMap {
+				m = maps.Clone(m)
+				if !strictify(m) {
+					ok = false
+				}
+				subs[i] = m
+			}
+		}
+		s[key] = subs
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, isMap := s[key].(map[string]any)
+		if !isMap {
+			continue
+		}
+		defs = maps.Clone(defs)
+		for name, def := range defs {
+			if m, isDef := def.(map[string]any); isDef {
+				m = maps.Clone(m)
+				if !strictify(m) {
+					ok = false
+				}
+				defs[name] = m
+			}
+		}
+		s[key] = defs
+	}
+
+	return ok
+}
+
+// schemaHasType reports whether the type keyword of s is or includes typ.
+func schemaHasType(s map[string]any, typ string) bool {
+	switch t := s["type"].(type) {
+	case string:
+		return t == typ
+	case []any:
+		return slices.Contains(t, any(typ))
+	case []string:
+		return slices.Contains(t, typ)
+	}
+	return false
+}
+
+// makeNullable lets s also accept null.
+func makeNullable(s map[string]any) {
+	switch t := s["type"].(type) {
+	case string:
+		if t != "null" {
+			s["type"] = []any{t, "null"}
+		}
+		return
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			s["type"] = append(slices.Clone(t), "null")
+		}
+		return
+	case []string:
+		if !slices.Contains(t, "null") {
+			s["type"] = append(toAnySlice(t), "null")
+		}
+		return
+	}
+	if anyOf, isList := s["anyOf"].([]any); isList {
+		s["anyOf"] = append(slices.Clone(anyOf), map[string]any{"type": "null"})
+		return
+	}
+	if enum, isList := s["enum"].([]any); isList && !slices.Contains(enum, nil) {
+		s["enum"] = append(slices.Clone(enum), nil)
+	}
+}
+
+func toAnySlice(ss []string) []any {
+	out := make([]any, len(ss))
+	for i, s := range ss {
+		out[i] = s
+	}
+	return out
+}
+
+// IsOpenAISchemaError reports whether err is an OpenAI API rejection of the structured-output response schema.
+func IsOpenAISchemaError(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return strings.HasPrefix(apiErr.Param, "text.format") || strings.HasPrefix(apiErr.Param, "response_format") ||
+		strings.Contains(strings.ToLower(apiErr.Message), "invalid schema")
+}
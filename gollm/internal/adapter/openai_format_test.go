@@ -0,0 +1,286 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	openai "github.com/openai/openai-go/v3"
+	"google.golang.org/genai"
+)
+
+func TestGenAISchemaToJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	nullable := true
+	minItems := int64(1)
+	schema := &genai.Schema{
+		Type:        genai.TypeObject,
+		Title:       "Answer",
+		Description: "final answer",
+		Properties: map[string]*genai.Schema{
+			"answer":     {Type: genai.TypeString},
+			"confidence": {Type: genai.TypeNumber, Nullable: &nullable},
+			"sources": {
+				Type:     genai.TypeArray,
+				Items:    &genai.Schema{Type: genai.TypeString, Format: "uri"},
+				MinItems: &minItems,
+			},
+			"verdict": {Type: genai.TypeString, Enum: []string{"yes", "no"}},
+		},
+		Required:         []string{"answer"},
+		PropertyOrdering: []string{"answer", "confidence"},
+	}
+
+	want := map[string]any{
+		"type":        "object",
+		"title":       "Answer",
+		"description": "final answer",
+		"properties": map[string]any{
+			"answer":     map[string]any{"type": "string"},
+			"confidence": map[string]any{"type": []any{"number", "null"}},
+			"sources": map[string]any{
+				"type":     "array",
+				"items":    map[string]any{"type": "string", "format": "uri"},
+				"minItems": int64(1),
+			},
+			"verdict": map[string]any{"type": "string", "enum": []any{"yes", "no"}},
+		},
+		"required": []any{"answer"},
+	}
+	if diff := cmp.Diff(want, GenAISchemaToJSONSchema(schema)); diff != "" {
+		t.Fatalf("GenAISchemaToJSONSchema() mismatch (-want +got):\n%s", diff)
+	}
+	if got := GenAISchemaToJSONSchema(nil); got != nil {
+		t.Fatalf("GenAISchemaToJSONSchema(nil) = %v, want nil", got)
+	}
+}
+
+func TestStrictJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		schema     map[string]any
+		want       map[string]any
+		wantStrict bool
+	}{
+		"optional properties become nullable": {
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"a": map[string]any{"type": "string"},
+					"b": map[string]any{"type": "integer"},
+					"c": map[string]any{"anyOf": []any{map[string]any{"type": "string"}}},
+				},
+				"required": []any{"a"},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"a": map[string]any{"type": "string"},
+					"b": map[string]any{"type": []any{"integer", "null"}},
+					"c": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+				},
+				"required":             []any{"a", "b", "c"},
+				"additionalProperties": false,
+			},
+			wantStrict: true,
+		},
+		"nested objects and arrays": {
+			schema: map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"x": map[string]any{"type": "number"}},
+					"required":   []string{"x"},
+				},
+			},
+			want: map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":                 "object",
+					"properties":           map[string]any{"x": map[string]any{"type": "number"}},
+					"required":             []any{"x"},
+					"additionalProperties": false,
+				},
+			},
+			wantStrict: true,
+		},
+		"additional properties allowed": {
+			schema: map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			wantStrict: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			before := fmt.Sprint(tt.schema)
+			got, strict := StrictJSONSchema(tt.schema)
+			if strict != tt.wantStrict {
+				t.Fatalf("StrictJSONSchema() strict = %t, want %t", strict, tt.wantStrict)
+			}
+			if tt.want != nil {
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Fatalf("StrictJSONSchema() mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if after := fmt.Sprint(tt.schema); after != before {
+				t.Fatalf("StrictJSONSchema() modified its input: %s, want %s", after, before)
+			}
+		})
+	}
+}
+
+func TestGenAIToResponsesFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		cfg        *genai.GenerateContentConfig
+		wantOK     bool
+		wantJSON   bool
+		wantName   string
+		wantStrict bool
+		wantErr    bool
+	}{
+		"nil config": {},
+		"plain text": {
+			cfg: &genai.GenerateContentConfig{ResponseMIMEType: "text/plain"},
+		},
+		"json mode": {
+			cfg:      &genai.GenerateContentConfig{ResponseMIMEType: "application/json"},
+			wantOK:   true,
+			wantJSON: true,
+		},
+		"genai schema": {
+			cfg: &genai.GenerateContentConfig{
+				ResponseMIMEType: "application/json",
+				ResponseSchema: &genai.Schema{
+					Type:       genai.TypeObject,
+					Title:      "Final Answer",
+					Properties: map[string]*genai.Schema{"answer": {Type: genai.TypeString}},
+				},
+			},
+			wantOK:     true,
+			wantName:   "Final_Answer",
+			wantStrict: true,
+		},
+		"json schema takes precedence": {
+			cfg: &genai.GenerateContentConfig{
+				ResponseSchema:     &genai.Schema{Type: genai.TypeString},
+				ResponseJsonSchema: []byte(`{"type":"object","additionalProperties":true}`),
+			},
+			wantOK:   true,
+			wantName: "response",
+		},
+		"invalid json schema": {
+			cfg:     &genai.GenerateContentConfig{ResponseJsonSchema: []byte(`{`)},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			format, ok, err := GenAIToResponsesFormat(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenAIToResponsesFormat() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("GenAIToResponsesFormat() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if got := format.OfJSONObject != nil; got != tt.wantJSON {
+				t.Fatalf("json mode = %t, want %t", got, tt.wantJSON)
+			}
+			if tt.wantName == "" {
+				if format.OfJSONSchema != nil {
+					t.Fatalf("json schema = %+v, want none", format.OfJSONSchema)
+				}
+				return
+			}
+			if format.OfJSONSchema == nil {
+				t.Fatal("json schema = nil, want set")
+			}
+			if format.OfJSONSchema.Name != tt.wantName {
+				t.Fatalf("schema name = %q, want %q", format.OfJSONSchema.Name, tt.wantName)
+			}
+			if got := format.OfJSONSchema.Strict.Value; got != tt.wantStrict {
+				t.Fatalf("strict = %t, want %t", got, tt.wantStrict)
+			}
+		})
+	}
+}
+
+func TestOpenAISchemaName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		title string
+		want  string
+	}{
+		"empty":      {title: "", want: "response"},
+		"symbols":    {title: "  !!  ", want: "response"},
+		"spaces":     {title: "TUMIX answer v2", want: "TUMIX_answer_v2"},
+		"truncated":  {title: strings.Repeat("a", 70), want: strings.Repeat("a", 64)},
+		"kept as is": {title: "final-answer_1", want: "final-answer_1"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := openAISchemaName(tt.title); got != tt.want {
+				t.Fatalf("openAISchemaName(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOpenAISchemaError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"nil":           {},
+		"other error":   {err: errors.New("boom")},
+		"schema param":  {err: &openai.Error{StatusCode: 400, Param: "text.format.schema", Message: "bad"}, want: true},
+		"wrapped":       {err: fmt.Errorf("call: %w", &openai.Error{StatusCode: 400, Message: "Invalid schema for response_format 'x'"}), want: true},
+		"other param":   {err: &openai.Error{StatusCode: 400, Param: "input", Message: "bad"}},
+		"server errors": {err: &openai.Error{StatusCode: 500, Param: "text.format.schema"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := IsOpenAISchemaError(tt.err); got != tt.want {
+				t.Fatalf("IsOpenAISchemaError() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
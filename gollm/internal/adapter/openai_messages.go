@@ -17,17 +17,25 @@
 package adapter
 
 import (
+	"cmp"
 	json "encoding/json/v2"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/responses"
 	"google.golang.org/genai"
 )
 
 // GenAIToResponsesInput converts GenAI content slices into OpenAI Responses input items.
+//
+// Parts keep their order, so text, function calls and function results interleave as in the transcript. Function
+// calls without an ID get a generated call ID, and function responses without an ID are paired with the oldest
+// unanswered call of the same name. Thought parts are model output only and are not sent back.
 func GenAIToResponsesInput(contents []*genai.Content) ([]responses.ResponseInputItemUnionParam, error) {
 	var items []responses.ResponseInputItemUnionParam
+	calls := make(pendingCalls)
 
 	for i, c := range contents {
 		if c == nil {
@@ -36,14 +44,28 @@ func GenAIToResponsesInput(contents []*genai.Content) ([]responses.ResponseInput
 
 		role := toEasyRole(c.Role)
 		var text strings.Builder
+		// content collects the parts of a message carrying images or files, which need the content list form.
+		var content responses.ResponseInputMessageContentListParam
 
-		flush := func() {
+		flushText := func() {
 			if text.Len() == 0 {
 				return
 			}
-			items = append(items, responses.ResponseInputItemParamOfMessage(text.String(), role))
+			content = append(content, responses.ResponseInputContentParamOfInputText(text.String()))
 			text.Reset()
 		}
+		flush := func() {
+			if len(content) == 0 {
+				if text.Len() > 0 {
+					items = append(items, responses.ResponseInputItemParamOfMessage(text.String(), role))
+					text.Reset()
+				}
+				return
+			}
+			flushText()
+			items = append(items, responses.ResponseInputItemParamOfMessage(content, role))
+			content = nil
+		}
 
 		for j, part := range c.Parts {
 			if part == nil {
@@ -51,9 +73,24 @@ func GenAIToResponsesInput(contents []*genai.Content) ([]responses.ResponseInput
 			}
 
 			switch {
+			case part.Thought:
+				continue
+
 			case part.Text != "":
 				text.WriteString(part.Text)
 
+			case part.InlineData != nil:
+				media, err := inlineDataToResponsesContent(part.InlineData)
+				if err != nil {
+					return nil, fmt.Errorf("content[%d] part[%d]: %w", i, j, err)
+				}
+				flushText()
+				content = append(content, media)
+
+			case part.FileData != nil:
+				flushText()
+				content = append(content, fileDataToResponsesContent(part.FileData))
+
 			case part.FunctionCall != nil:
 				flush()
 				fc := part.FunctionCall
@@ -65,8 +102,10 @@ func GenAIToResponsesInput(contents []*genai.Content) ([]responses.ResponseInput
 					return nil, fmt.Errorf("content[%d] part[%d]: marshal function args: %w", i, j, err)
 				}
 
+				id := toolID(fc.ID, i, j)
+				calls.add(fc.Name, id)
 				items = append(items,
-					responses.ResponseInputItemParamOfFunctionCall(string(argsJSON), toolID(fc.ID, i, j), fc.Name),
+					responses.ResponseInputItemParamOfFunctionCall(string(argsJSON), id, fc.Name),
 				)
 
 			case part.FunctionResponse != nil:
@@ -81,7 +120,7 @@ func GenAIToResponsesInput(contents []*genai.Content) ([]responses.ResponseInput
 				}
 
 				items = append(items,
-					responses.ResponseInputItemParamOfFunctionCallOutput(toolID(fr.ID, i, j), string(data)),
+					responses.ResponseInputItemParamOfFunctionCallOutput(calls.resolve(fr.Name, fr.ID, i, j), string(data)),
 				)
 
 			default:
@@ -95,6 +134,65 @@ func GenAIToResponsesInput(contents []*genai.Content) ([]responses.ResponseInput
 	return items, nil
 }
 
+// pendingCalls tracks the call IDs of function calls without a response yet, in call order per function name.
+type pendingCalls map[string][]string
+
+func (p pendingCalls) add(name, id string) {
+	p[name] = append(p[name], id)
+}
+
+// resolve returns the call ID answered by a function response and marks that call as answered.
+//
+// A response without an ID answers the oldest pending call of the same name; when there is none it falls back to a
+// generated ID.
+func (p pendingCalls) resolve(name, id string, contentIdx, partIdx int) string {
+	queue := p[name]
+	if strings.TrimSpace(id) != "" {
+		if k := slices.Index(queue, id); k >= 0 {
+			p[name] = slices.Delete(queue, k, k+1)
+		}
+		return id
+	}
+	if len(queue) > 0 {
+		p[name] = queue[1:]
+		return queue[0]
+	}
+	return toolID("", contentIdx, partIdx)
+}
+
+// inlineDataToResponsesContent converts inline image, PDF, and text data into a Responses input content part.
+func inlineDataToResponsesContent(b *genai.Blob) (responses.ResponseInputContentUnionParam, error) {
+	switch {
+	case isImageMIME(b.MIMEType):
+		c := responses.ResponseInputContentParamOfInputImage(responses.ResponseInputImageDetailAuto)
+		c.OfInputImage.ImageURL = param.NewOpt(dataURL(b))
+		return c, nil
+	case b.MIMEType == "application/pdf":
+		return responses.ResponseInputContentUnionParam{OfInputFile: &responses.ResponseInputFileParam{
+			FileData: param.NewOpt(dataURL(b)),
+			Filename: param.NewOpt(cmp.Or(b.DisplayName, "attachment.pdf")),
+		}}, nil
+	case isTextMIME(b.MIMEType):
+		return responses.ResponseInputContentParamOfInputText(string(b.Data)), nil
+	default:
+		return responses.ResponseInputContentUnionParam{}, fmt.Errorf("unsupported inline data type %q", b.MIMEType)
+	}
+}
+
+// fileDataToResponsesContent converts a file URI into an image or file Responses input content part.
+func fileDataToResponsesContent(f *genai.FileData) responses.ResponseInputContentUnionParam {
+	if isImageMIME(f.MIMEType) {
+		c := responses.ResponseInputContentParamOfInputImage(responses.ResponseInputImageDetailAuto)
+		c.OfInputImage.ImageURL = param.NewOpt(f.FileURI)
+		return c
+	}
+	file := &responses.ResponseInputFileParam{FileURL: param.NewOpt(f.FileURI)}
+	if f.DisplayName != "" {
+		file.Filename = param.NewOpt(f.DisplayName)
+	}
+	return responses.ResponseInputContentUnionParam{OfInputFile: file}
+}
+
 func toEasyRole(role string) responses.EasyInputMessageRole {
 	switch strings.ToLower(strings.TrimSpace(role)) {
 	case genai.RoleUser:
@@ -18,9 +18,12 @@ package adapter
 
 import (
 	json "encoding/json/v2"
+	"slices"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared/constant"
 	"google.golang.org/genai"
 )
 
@@ -157,7 +160,7 @@ func TestGenAIToResponsesInputErrors(t *testing.T) {
 					Parts: []*genai.Part{
 						{
 							InlineData: &genai.Blob{
-								MIMEType: "text/plain",
+								MIMEType: "audio/wav",
 							},
 						},
 					},
@@ -177,6 +180,50 @@ func TestGenAIToResponsesInputErrors(t *testing.T) {
 	}
 }
 
+func TestGenAIToResponsesInputMedia(t *testing.T) {
+	t.Parallel()
+
+	items, err := GenAIToResponsesInput([]*genai.Content{
+		{
+			Role: genai.RoleUser,
+			Parts: []*genai.Part{
+				genai.NewPartFromText("Describe "),
+				genai.NewPartFromText("these."),
+				genai.NewPartFromBytes([]byte("png"), "image/png"),
+				{InlineData: &genai.Blob{MIMEType: "application/pdf", Data: []byte("pdf"), DisplayName: "doc.pdf"}},
+				genai.NewPartFromBytes([]byte("notes"), "text/plain"),
+				genai.NewPartFromURI("https://example.com/cat.jpg", "image/jpeg"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GenAIToResponsesInput() error = %v", err)
+	}
+	if len(items) != 1 || items[0].OfMessage == nil {
+		t.Fatalf("items = %+v, want one message", items)
+	}
+
+	content := items[0].OfMessage.Content.OfInputItemContentList
+	if len(content) != 5 {
+		t.Fatalf("content len = %d, want 5", len(content))
+	}
+	if got := content[0].OfInputText; got == nil || got.Text != "Describe these." {
+		t.Fatalf("content[0] = %+v, want leading text", content[0])
+	}
+	if got := content[1].OfInputImage; got == nil || got.ImageURL.Or("") != "data:image/png;base64,cG5n" {
+		t.Fatalf("content[1] = %+v, want inline image data URL", content[1])
+	}
+	if got := content[2].OfInputFile; got == nil || got.FileData.Or("") != "data:application/pdf;base64,cGRm" || got.Filename.Or("") != "doc.pdf" {
+		t.Fatalf("content[2] = %+v, want inline PDF file", content[2])
+	}
+	if got := content[3].OfInputText; got == nil || got.Text != "notes" {
+		t.Fatalf("content[3] = %+v, want text attachment", content[3])
+	}
+	if got := content[4].OfInputImage; got == nil || got.ImageURL.Or("") != "https://example.com/cat.jpg" {
+		t.Fatalf("content[4] = %+v, want image URL", content[4])
+	}
+}
+
 func TestToEasyRoleFallback(t *testing.T) {
 	t.Parallel()
 
@@ -194,3 +241,212 @@ func TestToEasyRoleFallback(t *testing.T) {
 		}
 	}
 }
+
+// summarizeResponsesInput describes input items as "kind:role-or-call-id:payload" strings for comparison.
+func summarizeResponsesInput(t *testing.T, items []responses.ResponseInputItemUnionParam) []string {
+	t.Helper()
+
+	out := make([]string, 0, len(items))
+	for i, item := range items {
+		switch {
+		case item.OfMessage != nil:
+			out = append(out, "message:"+string(item.OfMessage.Role)+":"+item.OfMessage.Content.OfString.Or(""))
+		case item.OfFunctionCall != nil:
+			out = append(out, "call:"+item.OfFunctionCall.CallID+":"+item.OfFunctionCall.Name+" "+item.OfFunctionCall.Arguments)
+		case item.OfFunctionCallOutput != nil:
+			out = append(out, "output:"+item.OfFunctionCallOutput.CallID+":"+item.OfFunctionCallOutput.Output.OfString.Or(""))
+		default:
+			t.Fatalf("item[%d] has an unexpected type: %+v", i, item)
+		}
+	}
+	return out
+}
+
+func TestGenAIToResponsesInputParallelToolCalls(t *testing.T) {
+	t.Parallel()
+
+	call := func(id, name, q string) *genai.Part {
+		return &genai.Part{FunctionCall: &genai.FunctionCall{ID: id, Name: name, Args: map[string]any{"q": q}}}
+	}
+	result := func(id, name, r string) *genai.Part {
+		return &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: id, Name: name, Response: map[string]any{"r": r}}}
+	}
+
+	tests := map[string]struct {
+		contents []*genai.Content
+		want     []string
+	}{
+		"results answered out of order": {
+			contents: []*genai.Content{
+				genai.NewContentFromText("weather in Paris and Rome?", genai.RoleUser),
+				{Role: genai.RoleModel, Parts: []*genai.Part{
+					genai.NewPartFromText("Checking both."),
+					call("call_a", "weather", "Paris"),
+					call("call_b", "weather", "Rome"),
+				}},
+				{Role: genai.RoleUser, Parts: []*genai.Part{
+					result("call_b", "weather", "sun"),
+					result("call_a", "weather", "rain"),
+				}},
+				genai.NewContentFromText("Rain in Paris, sun in Rome.", genai.RoleModel),
+			},
+			want: []string{
+				"message:user:weather in Paris and Rome?",
+				"message:assistant:Checking both.",
+				`call:call_a:weather {"q":"Paris"}`,
+				`call:call_b:weather {"q":"Rome"}`,
+				`output:call_b:{"r":"sun"}`,
+				`output:call_a:{"r":"rain"}`,
+				"message:assistant:Rain in Paris, sun in Rome.",
+			},
+		},
+		"missing ids pair in call order per name": {
+			contents: []*genai.Content{
+				{Role: genai.RoleModel, Parts: []*genai.Part{
+					call("", "search", "one"),
+					call("", "calc", "1+1"),
+					call("", "search", "two"),
+				}},
+				{Role: genai.RoleUser, Parts: []*genai.Part{
+					result("", "calc", "2"),
+					result("", "search", "first"),
+					result("", "search", "second"),
+				}},
+			},
+			want: []string{
+				`call:tool_0_0:search {"q":"one"}`,
+				`call:tool_0_1:calc {"q":"1+1"}`,
+				`call:tool_0_2:search {"q":"two"}`,
+				`output:tool_0_1:{"r":"2"}`,
+				`output:tool_0_0:{"r":"first"}`,
+				`output:tool_0_2:{"r":"second"}`,
+			},
+		},
+		"mixed explicit and missing ids": {
+			contents: []*genai.Content{
+				{Role: genai.RoleModel, Parts: []*genai.Part{
+					call("call_x", "search", "one"),
+					call("", "search", "two"),
+				}},
+				{Role: genai.RoleUser, Parts: []*genai.Part{
+					result("call_x", "search", "first"),
+					result("", "search", "second"),
+				}},
+			},
+			want: []string{
+				`call:call_x:search {"q":"one"}`,
+				`call:tool_0_1:search {"q":"two"}`,
+				`output:call_x:{"r":"first"}`,
+				`output:tool_0_1:{"r":"second"}`,
+			},
+		},
+		"text interleaved with calls and results": {
+			contents: []*genai.Content{
+				{Role: genai.RoleModel, Parts: []*genai.Part{
+					genai.NewPartFromText("First "),
+					call("c1", "search", "a"),
+					genai.NewPartFromText("then "),
+					call("c2", "search", "b"),
+				}},
+				{Role: genai.RoleUser, Parts: []*genai.Part{
+					result("c1", "search", "x"),
+					genai.NewPartFromText("note"),
+					result("c2", "search", "y"),
+				}},
+			},
+			want: []string{
+				"message:assistant:First ",
+				`call:c1:search {"q":"a"}`,
+				"message:assistant:then ",
+				`call:c2:search {"q":"b"}`,
+				`output:c1:{"r":"x"}`,
+				"message:user:note",
+				`output:c2:{"r":"y"}`,
+			},
+		},
+		"thoughts are dropped": {
+			contents: []*genai.Content{
+				{Role: genai.RoleModel, Parts: []*genai.Part{
+					{Text: "private reasoning", Thought: true},
+					genai.NewPartFromText("answer"),
+				}},
+			},
+			want: []string{"message:assistant:answer"},
+		},
+		"result without a matching call": {
+			contents: []*genai.Content{
+				{Role: genai.RoleUser, Parts: []*genai.Part{result("", "search", "orphan")}},
+			},
+			want: []string{`output:tool_0_0:{"r":"orphan"}`},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			items, err := GenAIToResponsesInput(tt.contents)
+			if err != nil {
+				t.Fatalf("GenAIToResponsesInput() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, summarizeResponsesInput(t, items)); diff != "" {
+				t.Fatalf("GenAIToResponsesInput() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOpenAIToolCallRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	resp := &responses.Response{
+		Status: responses.ResponseStatusCompleted,
+		Output: []responses.ResponseOutputItemUnion{
+			{
+				Type:    "message",
+				Role:    constant.ValueOf[constant.Assistant](),
+				Content: []responses.ResponseOutputMessageContentUnion{{Type: "output_text", Text: "Looking up both."}},
+			},
+			{Type: "function_call", ID: "fc_1", CallID: "call_1", Name: "weather", Arguments: `{"city":"Paris"}`},
+			{Type: "function_call", ID: "fc_2", CallID: "call_2", Name: "time", Arguments: `{"city":"Paris"}`},
+		},
+	}
+
+	llmResp, err := OpenAIResponseToLLM(resp, nil)
+	if err != nil {
+		t.Fatalf("OpenAIResponseToLLM() error = %v", err)
+	}
+
+	// Answer the calls in reverse order, as a tool runner finishing them concurrently could.
+	var results []*genai.Part
+	for _, part := range slices.Backward(llmResp.Content.Parts) {
+		if fc := part.FunctionCall; fc != nil {
+			results = append(results, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:       fc.ID,
+				Name:     fc.Name,
+				Response: map[string]any{"ok": fc.Name},
+			}})
+		}
+	}
+
+	items, err := GenAIToResponsesInput([]*genai.Content{
+		genai.NewContentFromText("Weather and time in Paris?", genai.RoleUser),
+		llmResp.Content,
+		{Role: genai.RoleUser, Parts: results},
+	})
+	if err != nil {
+		t.Fatalf("GenAIToResponsesInput() error = %v", err)
+	}
+
+	want := []string{
+		"message:user:Weather and time in Paris?",
+		"message:assistant:Looking up both.",
+		`call:call_1:weather {"city":"Paris"}`,
+		`call:call_2:time {"city":"Paris"}`,
+		`output:call_2:{"ok":"time"}`,
+		`output:call_1:{"ok":"weather"}`,
+	}
+	if diff := cmp.Diff(want, summarizeResponsesInput(t, items)); diff != "" {
+		t.Fatalf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package adapter
+
+import (
+	"github.com/openai/openai-go/v3/shared"
+	"google.golang.org/genai"
+)
+
+// Thinking budget thresholds mapped to OpenAI reasoning effort levels.
+const (
+	openAILowReasoningBudget    = 1024
+	openAIMediumReasoningBudget = 8192
+)
+
+// GenAIThinkingToReasoning maps a GenAI thinking config to OpenAI reasoning parameters.
+//
+// ThinkingLevel maps to the effort of the same name. Otherwise ThinkingBudget selects the effort: 0 disables reasoning,
+// up to 1024 tokens is low, up to 8192 medium and anything larger high; a negative (dynamic) budget keeps the model
+// default. IncludeThoughts requests reasoning summaries. The second result is false when nothing is set.
+func GenAIThinkingToReasoning(cfg *genai.ThinkingConfig) (shared.ReasoningParam, bool) {
+	var reasoning shared.ReasoningParam
+	if cfg == nil {
+		return reasoning, false
+	}
+
+	switch cfg.ThinkingLevel {
+	case genai.ThinkingLevelMinimal:
+		reasoning.Effort = shared.ReasoningEffortMinimal
+	case genai.ThinkingLevelLow:
+		reasoning.Effort = shared.ReasoningEffortLow
+	case genai.ThinkingLevelMedium:
+		reasoning.Effort = shared.ReasoningEffortMedium
+	case genai.ThinkingLevelHigh:
+		reasoning.Effort = shared.ReasoningEffortHigh
+	default:
+		if cfg.ThinkingBudget != nil {
+			switch budget := *cfg.ThinkingBudget; {
+			case budget < 0:
+			case budget == 0:
+				reasoning.Effort = shared.ReasoningEffortNone
+			case budget <= openAILowReasoningBudget:
+				reasoning.Effort = shared.ReasoningEffortLow
+			case budget <= openAIMediumReasoningBudget:
+				reasoning.Effort = shared.ReasoningEffortMedium
+			default:
+				reasoning.Effort = shared.ReasoningEffortHigh
+			}
+		}
+	}
+	if cfg.IncludeThoughts {
+		reasoning.Summary = shared.ReasoningSummaryAuto
+	}
+
+	return reasoning, reasoning.Effort != "" || reasoning.Summary != ""
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package adapter
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openai/openai-go/v3/shared"
+	"google.golang.org/genai"
+)
+
+func TestGenAIThinkingToReasoning(t *testing.T) {
+	t.Parallel()
+
+	budget := func(v int32) *int32 { return &v }
+
+	tests := map[string]struct {
+		cfg         *genai.ThinkingConfig
+		wantEffort  shared.ReasoningEffort
+		wantSummary shared.ReasoningSummary
+		wantOK      bool
+	}{
+		"nil":               {},
+		"empty":             {cfg: &genai.ThinkingConfig{}},
+		"dynamic budget":    {cfg: &genai.ThinkingConfig{ThinkingBudget: budget(-1)}},
+		"level minimal":     {cfg: &genai.ThinkingConfig{ThinkingLevel: genai.ThinkingLevelMinimal}, wantEffort: shared.ReasoningEffortMinimal, wantOK: true},
+		"level high":        {cfg: &genai.ThinkingConfig{ThinkingLevel: genai.ThinkingLevelHigh}, wantEffort: shared.ReasoningEffortHigh, wantOK: true},
+		"zero budget":       {cfg: &genai.ThinkingConfig{ThinkingBudget: budget(0)}, wantEffort: shared.ReasoningEffortNone, wantOK: true},
+		"low budget":        {cfg: &genai.ThinkingConfig{ThinkingBudget: budget(1024)}, wantEffort: shared.ReasoningEffortLow, wantOK: true},
+		"medium budget":     {cfg: &genai.ThinkingConfig{ThinkingBudget: budget(4096)}, wantEffort: shared.ReasoningEffortMedium, wantOK: true},
+		"high budget":       {cfg: &genai.ThinkingConfig{ThinkingBudget: budget(32768)}, wantEffort: shared.ReasoningEffortHigh, wantOK: true},
+		"level over budget": {cfg: &genai.ThinkingConfig{ThinkingLevel: genai.ThinkingLevelLow, ThinkingBudget: budget(32768)}, wantEffort: shared.ReasoningEffortLow, wantOK: true},
+		"include thoughts":  {cfg: &genai.ThinkingConfig{IncludeThoughts: true}, wantSummary: shared.ReasoningSummaryAuto, wantOK: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := GenAIThinkingToReasoning(tt.cfg)
+			if ok != tt.wantOK {
+				t.Fatalf("GenAIThinkingToReasoning() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.wantEffort, got.Effort); diff != "" {
+				t.Fatalf("Effort mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantSummary, got.Summary); diff != "" {
+				t.Fatalf("Summary mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
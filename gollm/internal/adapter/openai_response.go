@@ -35,6 +35,7 @@ var (
 	openAIResponseOutputItemTypeMessage         = string(constant.ValueOf[constant.Message]())
 	openAIResponseOutputItemTypeFunctionCall    = string(constant.ValueOf[constant.FunctionCall]())
 	openAIResponseOutputItemTypeShellCallOutput = string(constant.ValueOf[constant.ShellCallOutput]())
+	openAIResponseOutputItemTypeReasoning       = string(constant.ValueOf[constant.Reasoning]())
 
 	openAIResponseMessageContentTypeOutputText = string(constant.ValueOf[constant.OutputText]())
 	openAIResponseMessageContentTypeRefusal    = string(constant.ValueOf[constant.Refusal]())
@@ -48,6 +49,8 @@ func openAIResponseOutputItemVariant(itemType string) any {
 		return responses.ResponseFunctionToolCall{}
 	case openAIResponseOutputItemTypeShellCallOutput:
 		return responses.ResponseFunctionShellToolCallOutput{}
+	case openAIResponseOutputItemTypeReasoning:
+		return responses.ResponseReasoningItem{}
 	default:
 		return nil
 	}
@@ -75,11 +78,21 @@ func OpenAIResponseToLLM(resp *responses.Response, stopSequences []string) (*mod
 	}
 
 	parts := make([]*genai.Part, 0, len(resp.Output))
-	var sawText bool
+	var (
+		sawText  bool
+		logprobs []responses.ResponseOutputTextLogprob
+	)
 
 	for i := range resp.Output {
 		item := &resp.Output[i]
 		switch openAIResponseOutputItemVariant(item.Type).(type) {
+		case responses.ResponseReasoningItem:
+			// Reasoning models only return summaries of their reasoning, and only when requested.
+			for _, summary := range item.Summary {
+				if summary.Text != "" {
+					parts = append(parts, &genai.Part{Text: summary.Text, Thought: true})
+				}
+			}
 		case responses.ResponseOutputMessage:
 			for ci := range item.Content {
 				c := &item.Content[ci]
@@ -89,6 +102,7 @@ func OpenAIResponseToLLM(resp *responses.Response, stopSequences []string) (*mod
 						parts = append(parts, genai.NewPartFromText(c.Text))
 						sawText = true
 					}
+					logprobs = append(logprobs, c.Logprobs...)
 				case responses.ResponseOutputRefusal:
 					if c.Refusal != "" {
 						parts = append(parts, genai.NewPartFromText(c.Refusal))
@@ -134,7 +148,7 @@ func OpenAIResponseToLLM(resp *responses.Response, stopSequences []string) (*mod
 		finish = genai.FinishReasonStop
 	}
 
-	return &model.LLMResponse{
+	llmResp := &model.LLMResponse{
 		Content: &genai.Content{
 			Role:  genai.RoleModel,
 			Parts: parts,
@@ -142,7 +156,37 @@ func OpenAIResponseToLLM(resp *responses.Response, stopSequences []string) (*mod
 		UsageMetadata: usage,
 		TurnComplete:  resp.Status == responses.ResponseStatusCompleted || resp.Status == responses.ResponseStatusIncomplete,
 		FinishReason:  finish,
-	}, nil
+	}
+	if len(logprobs) > 0 {
+		llmResp.LogprobsResult, llmResp.AvgLogprobs = openAILogprobsToGenAI(logprobs)
+	}
+
+	return llmResp, nil
+}
+
+// openAILogprobsToGenAI converts the token log probabilities of output text into a GenAI logprobs result and their
+// mean.
+func openAILogprobsToGenAI(logprobs []responses.ResponseOutputTextLogprob) (*genai.LogprobsResult, float64) {
+	res := &genai.LogprobsResult{
+		ChosenCandidates: make([]*genai.LogprobsResultCandidate, len(logprobs)),
+	}
+	hasTop := false
+	top := make([]*genai.LogprobsResultTopCandidates, len(logprobs))
+	var sum float64
+	for i, lp := range logprobs {
+		sum += lp.Logprob
+		res.ChosenCandidates[i] = &genai.LogprobsResultCandidate{Token: lp.Token, LogProbability: float32(lp.Logprob)}
+
+		top[i] = &genai.LogprobsResultTopCandidates{}
+		for _, alt := range lp.TopLogprobs {
+			top[i].Candidates = append(top[i].Candidates, &genai.LogprobsResultCandidate{Token: alt.Token, LogProbability: float32(alt.Logprob)})
+		}
+		hasTop = hasTop || len(top[i].Candidates) > 0
+	}
+	if hasTop {
+		res.TopCandidates = top
+	}
+	return res, sum / float64(len(logprobs))
 }
 
 type toolCallState struct {
@@ -176,7 +220,8 @@ func NewOpenAIStreamAggregator(stopSequences []string) *OpenAIStreamAggregator {
 // because it re-unmarshals the raw JSON payload for every event. Process already
 // has the fully decoded union fields, so we only need the variant *type*.
 var (
-	openAIStreamEventTypeOutputTextDelta = string(constant.ValueOf[constant.ResponseOutputTextDelta]())
+	openAIStreamEventTypeOutputTextDelta           = string(constant.ValueOf[constant.ResponseOutputTextDelta]())
+	openAIStreamEventTypeReasoningSummaryTextDelta = string(constant.ValueOf[constant.ResponseReasoningSummaryTextDelta]())
 
 	openAIStreamEventTypeFunctionCallArgumentsDelta = string(constant.ValueOf[constant.ResponseFunctionCallArgumentsDelta]())
 	openAIStreamEventTypeFunctionCallArgumentsDone  = string(constant.ValueOf[constant.ResponseFunctionCallArgumentsDone]())
@@ -191,6 +236,8 @@ func openAIStreamEventVariant(eventType string) any {
 	switch eventType {
 	case openAIStreamEventTypeOutputTextDelta:
 		return responses.ResponseTextDeltaEvent{}
+	case openAIStreamEventTypeReasoningSummaryTextDelta:
+		return responses.ResponseReasoningSummaryTextDeltaEvent{}
 
 	case openAIStreamEventTypeFunctionCallArgumentsDelta:
 		return responses.ResponseFunctionCallArgumentsDeltaEvent{}
@@ -234,6 +281,20 @@ func (a *OpenAIStreamAggregator) Process(event *responses.ResponseStreamEventUni
 			},
 		}
 
+	case responses.ResponseReasoningSummaryTextDeltaEvent:
+		if event.Delta == "" {
+			return nil
+		}
+		return []*model.LLMResponse{
+			{
+				Content: &genai.Content{
+					Role:  genai.RoleModel,
+					Parts: []*genai.Part{{Text: event.Delta, Thought: true}},
+				},
+				Partial: true,
+			},
+		}
+
 	case responses.ResponseFunctionCallArgumentsDeltaEvent:
 		state := a.ensureToolCall(event.OutputIndex, event.ItemID)
 		if event.Delta != "" {
@@ -475,7 +536,7 @@ func trimAtStop(s string, stops []string) stopTrimResult {
 func trimPartsAtStop(parts []*genai.Part, stops []string) bool {
 	var trimmed bool
 	for i, p := range parts {
-		if p == nil || p.Text == "" {
+		if p == nil || p.Text == "" || p.Thought {
 			continue
 		}
 		res := trimAtStop(p.Text, stops)
@@ -484,7 +545,7 @@ func trimPartsAtStop(parts []*genai.Part, stops []string) bool {
 			trimmed = true
 			// blank out any subsequent text parts to avoid leaking content past stop.
 			for j := i + 1; j < len(parts); j++ {
-				if parts[j] != nil {
+				if parts[j] != nil && !parts[j].Thought {
 					parts[j].Text = ""
 				}
 			}
@@ -534,9 +595,10 @@ func openAIUsage(u *responses.ResponseUsage) *genai.GenerateContentResponseUsage
 	}
 
 	return &genai.GenerateContentResponseUsageMetadata{
-		PromptTokenCount:     int32(u.InputTokens),  //nolint:gosec // TODO(zchee): fix nolint
-		CandidatesTokenCount: int32(u.OutputTokens), //nolint:gosec // TODO(zchee): fix nolint
-		TotalTokenCount:      int32(u.TotalTokens),  //nolint:gosec // TODO(zchee): fix nolint
+		PromptTokenCount:     int32(u.InputTokens),                         //nolint:gosec // TODO(zchee): fix nolint
+		CandidatesTokenCount: int32(u.OutputTokens),                        //nolint:gosec // TODO(zchee): fix nolint
+		TotalTokenCount:      int32(u.TotalTokens),                         //nolint:gosec // TODO(zchee): fix nolint
+		ThoughtsTokenCount:   int32(u.OutputTokensDetails.ReasoningTokens), //nolint:gosec // TODO(zchee): fix nolint
 	}
 }
 
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/openai/openai-go/v3/responses"
 	"github.com/openai/openai-go/v3/shared/constant"
 	"google.golang.org/adk/model"
@@ -188,6 +189,10 @@ func TestOpenAIStreamEventVariant(t *testing.T) {
 		t.Fatalf("output_text.delta did not map to responses.ResponseTextDeltaEvent")
 	}
 
+	if _, ok := openAIStreamEventVariant("response.reasoning_summary_text.delta").(responses.ResponseReasoningSummaryTextDeltaEvent); !ok {
+		t.Fatalf("reasoning_summary_text.delta did not map to responses.ResponseReasoningSummaryTextDeltaEvent")
+	}
+
 	if _, ok := openAIStreamEventVariant("response.function_call_arguments.delta").(responses.ResponseFunctionCallArgumentsDeltaEvent); !ok {
 		t.Fatalf("function_call_arguments.delta did not map to responses.ResponseFunctionCallArgumentsDeltaEvent")
 	}
@@ -229,6 +234,9 @@ func TestOpenAIResponseOutputItemVariant(t *testing.T) {
 	if _, ok := openAIResponseOutputItemVariant("shell_call_output").(responses.ResponseFunctionShellToolCallOutput); !ok {
 		t.Fatalf("shell_call_output did not map to responses.ResponseFunctionShellToolCallOutput")
 	}
+	if _, ok := openAIResponseOutputItemVariant("reasoning").(responses.ResponseReasoningItem); !ok {
+		t.Fatalf("reasoning did not map to responses.ResponseReasoningItem")
+	}
 	if _, ok := openAIResponseOutputItemVariant("  MESSAGE ").(responses.ResponseOutputMessage); !ok {
 		t.Fatalf("trim/case did not map to responses.ResponseOutputMessage")
 	}
@@ -570,6 +578,48 @@ func TestOpenAIResponseToLLM_Refusal(t *testing.T) {
 	}
 }
 
+func TestOpenAIResponseToLLM_Logprobs(t *testing.T) {
+	t.Parallel()
+
+	resp := &responses.Response{
+		Status: responses.ResponseStatusCompleted,
+		Output: []responses.ResponseOutputItemUnion{
+			{
+				Type: "message",
+				Role: constant.ValueOf[constant.Assistant](),
+				Content: []responses.ResponseOutputMessageContentUnion{
+					{
+						Type: "output_text",
+						Text: "yes",
+						Logprobs: []responses.ResponseOutputTextLogprob{
+							{Token: "y", Logprob: -0.5, TopLogprobs: []responses.ResponseOutputTextLogprobTopLogprob{{Token: "n", Logprob: -1}}},
+							{Token: "es", Logprob: -1.5},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got, err := OpenAIResponseToLLM(resp, nil)
+	if err != nil {
+		t.Fatalf("OpenAIResponseToLLM err = %v", err)
+	}
+	want := &genai.LogprobsResult{
+		ChosenCandidates: []*genai.LogprobsResultCandidate{{Token: "y", LogProbability: -0.5}, {Token: "es", LogProbability: -1.5}},
+		TopCandidates: []*genai.LogprobsResultTopCandidates{
+			{Candidates: []*genai.LogprobsResultCandidate{{Token: "n", LogProbability: -1}}},
+			{},
+		},
+	}
+	if diff := cmp.Diff(want, got.LogprobsResult); diff != "" {
+		t.Fatalf("LogprobsResult mismatch (-want +got):\n%s", diff)
+	}
+	if got.AvgLogprobs != -1 {
+		t.Fatalf("AvgLogprobs = %v, want -1", got.AvgLogprobs)
+	}
+}
+
 func TestOpenAIResponseToLLM_NoConvertibleOutputItems(t *testing.T) {
 	t.Parallel()
 
@@ -586,3 +636,71 @@ func TestOpenAIResponseToLLM_NoConvertibleOutputItems(t *testing.T) {
 		t.Fatalf("expected no convertible output items error, got %v", err)
 	}
 }
+
+func TestOpenAIResponseToLLM_Reasoning(t *testing.T) {
+	t.Parallel()
+
+	resp := &responses.Response{
+		Status: responses.ResponseStatusCompleted,
+		Output: []responses.ResponseOutputItemUnion{
+			{
+				Type: "reasoning",
+				Summary: []responses.ResponseReasoningItemSummary{
+					{Text: "Compare both options. STOP"},
+					{Text: ""},
+				},
+			},
+			{
+				Type: "message",
+				Role: constant.ValueOf[constant.Assistant](),
+				Content: []responses.ResponseOutputMessageContentUnion{
+					{
+						Type: "output_text",
+						Text: "42 STOP trailing",
+					},
+				},
+			},
+		},
+		Usage: responses.ResponseUsage{
+			InputTokens:         3,
+			OutputTokens:        10,
+			TotalTokens:         13,
+			OutputTokensDetails: responses.ResponseUsageOutputTokensDetails{ReasoningTokens: 8},
+		},
+	}
+
+	got, err := OpenAIResponseToLLM(resp, []string{" STOP"})
+	if err != nil {
+		t.Fatalf("OpenAIResponseToLLM err = %v", err)
+	}
+	want := []*genai.Part{
+		{Text: "Compare both options. STOP", Thought: true},
+		{Text: "42"},
+	}
+	if diff := cmp.Diff(want, got.Content.Parts); diff != "" {
+		t.Fatalf("parts mismatch (-want +got):\n%s", diff)
+	}
+	if got.UsageMetadata.ThoughtsTokenCount != 8 {
+		t.Fatalf("ThoughtsTokenCount = %d, want 8", got.UsageMetadata.ThoughtsTokenCount)
+	}
+}
+
+func TestOpenAIStreamAggregator_ReasoningSummaryDelta(t *testing.T) {
+	t.Parallel()
+
+	agg := NewOpenAIStreamAggregator(nil)
+	got := agg.Process(&responses.ResponseStreamEventUnion{
+		Type:  "response.reasoning_summary_text.delta",
+		Delta: "thinking",
+	})
+	if len(got) != 1 || !got[0].Partial {
+		t.Fatalf("Process() = %+v, want one partial response", got)
+	}
+	want := []*genai.Part{{Text: "thinking", Thought: true}}
+	if diff := cmp.Diff(want, got[0].Content.Parts); diff != "" {
+		t.Fatalf("parts mismatch (-want +got):\n%s", diff)
+	}
+	if final := agg.Final(); final != nil {
+		t.Fatalf("Final() = %+v, want nil for reasoning-only deltas", final)
+	}
+}
@@ -88,8 +88,8 @@ func TestGenAIToResponsesInput(t *testing.T) {
 	if fcOut == nil {
 		t.Fatalf("function call output missing")
 	}
-	if got, want := fcOut.CallID, "tool_1_2"; got != want {
-		t.Fatalf("function call output id = %q, want %q", got, want)
+	if got, want := fcOut.CallID, fc.CallID; got != want {
+		t.Fatalf("function call output id = %q, want the call id %q", got, want)
 	}
 	if got := fcOut.Output.OfString.Value; got == "" || got == "{}" {
 		t.Fatalf("function call output empty: %q", got)
@@ -29,6 +29,9 @@ import (
 )
 
 // GenAIToolsToResponses maps GenAI tool declarations into Responses tool parameters and choice options.
+//
+// Built-in GenAI tools map to OpenAI hosted tools: GoogleSearch and GoogleSearchRetrieval to web search, CodeExecution
+// to the code interpreter running in an automatically created container.
 func GenAIToolsToResponses(tools []*genai.Tool, cfg *genai.ToolConfig) (params []responses.ToolUnionParam, choiceOpt *responses.ResponseNewParamsToolChoiceUnion) {
 	if len(tools) == 0 {
 		return nil, nil
@@ -36,6 +39,15 @@ func GenAIToolsToResponses(tools []*genai.Tool, cfg *genai.ToolConfig) (params [
 
 	params = make([]responses.ToolUnionParam, 0, len(tools))
 	for _, t := range tools {
+		if t == nil {
+			continue
+		}
+		if t.GoogleSearch != nil || t.GoogleSearchRetrieval != nil {
+			params = append(params, responses.ToolParamOfWebSearch(responses.WebSearchToolTypeWebSearch))
+		}
+		if t.CodeExecution != nil {
+			params = append(params, responses.ToolParamOfCodeInterpreter(responses.ToolCodeInterpreterContainerCodeInterpreterContainerAutoParam{}))
+		}
 		for _, decl := range t.FunctionDeclarations {
 			if decl == nil || decl.Name == "" {
 				continue
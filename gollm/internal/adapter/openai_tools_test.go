@@ -157,6 +157,28 @@ func TestGenAIToolsToResponses(t *testing.T) {
 				}
 			},
 		},
+		"maps built-in tools to hosted tools": {
+			tools: []*genai.Tool{
+				{GoogleSearch: &genai.GoogleSearch{}},
+				{CodeExecution: &genai.ToolCodeExecution{}, FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "f1"}}},
+				nil,
+			},
+			check: func(t *testing.T, params []responses.ToolUnionParam, _ *responses.ResponseNewParamsToolChoiceUnion) {
+				t.Helper()
+				if len(params) != 3 {
+					t.Fatalf("len(params) = %d, want 3", len(params))
+				}
+				if params[0].OfWebSearch == nil || params[0].OfWebSearch.Type != responses.WebSearchToolTypeWebSearch {
+					t.Fatalf("params[0] = %+v, want web search", params[0])
+				}
+				if params[1].OfCodeInterpreter == nil || params[1].OfCodeInterpreter.Container.OfCodeInterpreterToolAuto == nil {
+					t.Fatalf("params[1] = %+v, want code interpreter with auto container", params[1])
+				}
+				if params[2].OfFunction == nil || params[2].OfFunction.Name != "f1" {
+					t.Fatalf("params[2] = %+v, want function f1", params[2])
+				}
+			},
+		},
 		"multiple allowed functions leaves choice nil": {
 			tools: []*genai.Tool{
 				{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "f1"}}},
@@ -17,6 +17,7 @@
 package adapter
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strings"
 
@@ -43,3 +44,16 @@ func joinTextParts(parts []*genai.Part) string {
 	}
 	return sb.String()
 }
+
+// dataURL encodes the blob as a base64 data URL.
+func dataURL(b *genai.Blob) string {
+	return "data:" + b.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(b.Data)
+}
+
+func isImageMIME(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
+
+func isTextMIME(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/")
+}
@@ -133,9 +133,7 @@ func GenAI2XAIChatOptions(config *genai.GenerateContentConfig) xai.ChatOption {
 				})
 			}
 		}
-		if tool.CodeExecution != nil {
-			tools = append(tools, xai.CodeExecutionTool())
-		}
+		tools = append(tools, GenAIToXAIServerSideTools(tool)...)
 	}
 
 	if tc := config.ToolConfig; tc != nil && tc.FunctionCallingConfig != nil {
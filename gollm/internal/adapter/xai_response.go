@@ -26,6 +26,7 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/zchee/tumix/gollm/xai"
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
 // XAIResponseToLLM converts an xAI response into an ADK LLMResponse, preserving usage and metadata.
@@ -51,23 +52,34 @@ func XAIResponseToLLM(resp *xai.Response) *model.LLMResponse {
 
 	parts := make([]*genai.Part, 0, 3)
 
+	// Encrypted reasoning rides on the thought part as its signature so it is sent back on the next turn.
+	signature := xai.ThoughtSignature(resp.EncryptedContent())
 	if reasoning := resp.ReasoningContent(); reasoning != "" {
 		parts = append(parts, &genai.Part{
-			Text:    reasoning,
-			Thought: true,
+			Text:             reasoning,
+			Thought:          true,
+			ThoughtSignature: signature,
 		})
+		signature = nil
 	}
 
 	if content := resp.Content(); content != "" {
 		parts = append(parts, genai.NewPartFromText(content))
 	}
 
-	var argErrors []string
+	if signature != nil {
+		parts = append(parts, &genai.Part{Thought: true, ThoughtSignature: signature})
+	}
+
+	var (
+		argErrors   []string
+		serverTools []string
+	)
 	if toolCalls := resp.ToolCalls(); len(toolCalls) > 0 { //nolint:nestif // TODO(zchee): fix nolint
 		dec := jsontext.NewDecoder(strings.NewReader(""))
 		for _, call := range toolCalls {
 			fc := call.GetFunction()
-			if fc == nil {
+			if fc == nil && !IsXAIServerSideToolCall(call) {
 				continue
 			}
 
@@ -90,6 +102,14 @@ func XAIResponseToLLM(resp *xai.Response) *model.LLMResponse {
 				}
 			}
 
+			if IsXAIServerSideToolCall(call) {
+				serverTools = append(serverTools, XAIServerSideToolName(call))
+				if part := XAIServerSideToolCallToPart(call, args); part != nil {
+					parts = append(parts, part)
+				}
+				continue
+			}
+
 			parts = append(parts, &genai.Part{
 				FunctionCall: &genai.FunctionCall{
 					ID:   call.GetId(),
@@ -121,6 +141,9 @@ func XAIResponseToLLM(resp *xai.Response) *model.LLMResponse {
 	if citations := resp.Citations(); len(citations) > 0 {
 		custom["xai_citations"] = slices.Clone(citations)
 	}
+	if len(serverTools) > 0 {
+		custom[XAIServerSideToolsKey] = serverTools
+	}
 	if len(argErrors) > 0 {
 		custom["tool_call_args_errors"] = slices.Clone(argErrors)
 	}
@@ -128,7 +151,7 @@ func XAIResponseToLLM(resp *xai.Response) *model.LLMResponse {
 		custom = nil
 	}
 
-	return &model.LLMResponse{
+	llmResp := &model.LLMResponse{
 		Content: &genai.Content{
 			Role:  role,
 			Parts: parts,
@@ -137,6 +160,44 @@ func XAIResponseToLLM(resp *xai.Response) *model.LLMResponse {
 		UsageMetadata:  usageMetadata,
 		FinishReason:   finishReason,
 	}
+	if logprobs := resp.Logprobs(); len(logprobs) > 0 {
+		llmResp.LogprobsResult = xaiLogprobsToGenAI(logprobs)
+		llmResp.AvgLogprobs = xai.NewLogprobStats(logprobs).MeanLogprob
+	}
+
+	return llmResp
+}
+
+// xaiLogprobsToGenAI converts xAI token log probabilities into a GenAI logprobs result.
+func xaiLogprobsToGenAI(logprobs []*xaipb.LogProb) *genai.LogprobsResult {
+	res := &genai.LogprobsResult{
+		ChosenCandidates: make([]*genai.LogprobsResultCandidate, 0, len(logprobs)),
+	}
+	hasTop := false
+	top := make([]*genai.LogprobsResultTopCandidates, 0, len(logprobs))
+	for _, lp := range logprobs {
+		if lp == nil {
+			continue
+		}
+		res.ChosenCandidates = append(res.ChosenCandidates, &genai.LogprobsResultCandidate{
+			Token:          lp.GetToken(),
+			LogProbability: lp.GetLogprob(),
+		})
+
+		alts := &genai.LogprobsResultTopCandidates{}
+		for _, alt := range lp.GetTopLogprobs() {
+			alts.Candidates = append(alts.Candidates, &genai.LogprobsResultCandidate{
+				Token:          alt.GetToken(),
+				LogProbability: alt.GetLogprob(),
+			})
+		}
+		hasTop = hasTop || len(alts.Candidates) > 0
+		top = append(top, alts)
+	}
+	if hasTop {
+		res.TopCandidates = top
+	}
+	return res
 }
 
 func mapXAIFinishReason(fr string) genai.FinishReason {
@@ -0,0 +1,98 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package adapter
+
+import (
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm/xai"
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// XAIServerSideToolsKey is the [model.LLMResponse] custom metadata key listing the xAI server-side tools invoked by the model.
+//
+// Server-side tool calls are executed by xAI and must not be surfaced as [genai.FunctionCall] parts, otherwise ADK
+// would try to dispatch them to a local tool.
+const XAIServerSideToolsKey = "xai_server_side_tools"
+
+// GenAIToXAIServerSideTools converts the built-in tools of a GenAI tool into xAI server-side tools.
+//
+// GoogleSearch and GoogleSearchRetrieval map to the xAI web search tool, CodeExecution to the xAI code execution tool.
+func GenAIToXAIServerSideTools(tool *genai.Tool) []*xaipb.Tool {
+	if tool == nil {
+		return nil
+	}
+
+	var tools []*xaipb.Tool
+	switch {
+	case tool.GoogleSearch != nil:
+		tools = append(tools, xai.WebSearchTool(slices.Clone(tool.GoogleSearch.ExcludeDomains), nil, false))
+	case tool.GoogleSearchRetrieval != nil:
+		tools = append(tools, xai.WebSearchTool(nil, nil, false))
+	}
+	if tool.CodeExecution != nil {
+		tools = append(tools, xai.CodeExecutionTool())
+	}
+
+	return tools
+}
+
+// IsXAIServerSideToolCall reports whether call was executed server-side by xAI.
+func IsXAIServerSideToolCall(call *xaipb.ToolCall) bool {
+	switch call.GetType() {
+	case xaipb.ToolCallType_TOOL_CALL_TYPE_INVALID, xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL:
+		return false
+	default:
+		return true
+	}
+}
+
+// XAIServerSideToolName returns the stable tool name of a server-side tool call, e.g. "web_search".
+func XAIServerSideToolName(call *xaipb.ToolCall) string {
+	if name := call.GetFunction().GetName(); name != "" {
+		return name
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(call.GetType().String(), "TOOL_CALL_TYPE_"), "_TOOL"))
+}
+
+// XAIServerSideToolCallToPart converts a server-side tool call back into a GenAI part.
+//
+// Code execution calls become [genai.ExecutableCode] parts; other server-side tools have no GenAI part
+// equivalent and return nil.
+func XAIServerSideToolCallToPart(call *xaipb.ToolCall, args map[string]any) *genai.Part {
+	if call.GetType() != xaipb.ToolCallType_TOOL_CALL_TYPE_CODE_EXECUTION_TOOL {
+		return nil
+	}
+
+	code, ok := args["code"].(string)
+	if !ok || code == "" {
+		code = call.GetFunction().GetArguments()
+	}
+	if code == "" {
+		return nil
+	}
+
+	return &genai.Part{
+		ExecutableCode: &genai.ExecutableCode{
+			Code:     code,
+			Language: genai.LanguagePython,
+		},
+	}
+}
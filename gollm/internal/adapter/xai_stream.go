@@ -30,8 +30,8 @@ import (
 
 // XAIStreamAggregator accumulates streaming xAI responses into coherent LLM responses.
 type XAIStreamAggregator struct {
-	text        strings.Builder
-	thoughtText strings.Builder
+	text        streamText
+	thoughtText streamText
 	response    *model.LLMResponse
 	role        string
 }
@@ -76,12 +76,11 @@ func (s *XAIStreamAggregator) aggregateResponse(llmResponse *model.LLMResponse)
 
 	// If part is text append it
 	if part0 != nil && part0.Text != "" {
-		appendDeltaToBuilder(part0.Text, func() *strings.Builder {
-			if part0.Thought {
-				return &s.thoughtText
-			}
-			return &s.text
-		}())
+		if part0.Thought {
+			s.thoughtText.append(part0.Text)
+		} else {
+			s.text.append(part0.Text)
+		}
 		llmResponse.Partial = true
 		return nil
 	}
@@ -122,6 +121,8 @@ func (s *XAIStreamAggregator) Close() *model.LLMResponse {
 			ErrorMessage:      s.response.ErrorMessage,
 			UsageMetadata:     s.response.UsageMetadata,
 			GroundingMetadata: s.response.GroundingMetadata,
+			LogprobsResult:    s.response.LogprobsResult,
+			AvgLogprobs:       s.response.AvgLogprobs,
 			FinishReason:      s.response.FinishReason,
 		}
 		s.clear()
@@ -138,26 +139,48 @@ func (s *XAIStreamAggregator) clear() {
 	s.role = ""
 }
 
-func appendDeltaToBuilder(incoming string, acc *strings.Builder) string {
-	if acc == nil || incoming == "" {
-		return incoming
+// streamText accumulates the text of a stream whose responses carry either a delta or the whole text so far.
+type streamText struct {
+	strings.Builder
+
+	// last equals the accumulated text. After a response carrying the whole text so far, it is that text, which the
+	// next response usually extends in the same memory, so the prefix check compares the pointers rather than every
+	// byte streamed so far.
+	last string
+}
+
+// append appends incoming to t, only its new suffix when incoming is the whole text so far, and returns what it
+// appended.
+func (t *streamText) append(incoming string) string {
+	if incoming == "" {
+		return ""
 	}
 
 	// Avoid quadratic concatenation by appending only the new suffix when the incoming
 	// text already contains the previous accumulated text as a prefix.
-	existing := acc.String()
 	delta := incoming
-	if len(incoming) >= len(existing) && strings.HasPrefix(incoming, existing) {
-		delta = incoming[len(existing):]
+	if strings.HasPrefix(incoming, t.last) {
+		delta = incoming[len(t.last):]
 	}
 	if delta == "" {
 		return ""
 	}
 
-	acc.WriteString(delta)
+	t.WriteString(delta)
+	if len(delta) < len(incoming) {
+		t.last = incoming
+	} else {
+		t.last = t.String()
+	}
 	return delta
 }
 
+// Reset resets t to be empty.
+func (t *streamText) Reset() {
+	t.Builder.Reset()
+	t.last = ""
+}
+
 func isZeroPart(p *genai.Part) bool {
 	if p == nil {
 		return false
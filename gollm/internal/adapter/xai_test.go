@@ -139,6 +139,32 @@ func TestGenAI2XAIChatOptionsMapsConfig(t *testing.T) {
 				}
 			},
 		},
+		"success: maps built-in tools to server-side tools": {
+			config: genai.GenerateContentConfig{
+				Tools: []*genai.Tool{
+					{GoogleSearch: &genai.GoogleSearch{ExcludeDomains: []string{"example.com"}}},
+					{GoogleSearchRetrieval: &genai.GoogleSearchRetrieval{}},
+				},
+			},
+			assertf: func(t *testing.T, req *xaipb.GetCompletionsRequest) {
+				t.Helper()
+
+				tools := req.GetTools()
+				if len(tools) != 2 {
+					t.Fatalf("Tools len = %d, want 2", len(tools))
+				}
+				ws := tools[0].GetWebSearch()
+				if ws == nil {
+					t.Fatalf("first tool not web search: %+v", tools[0])
+				}
+				if diff := cmp.Diff([]string{"example.com"}, ws.GetExcludedDomains()); diff != "" {
+					t.Fatalf("ExcludedDomains diff (-want +got):\n%s", diff)
+				}
+				if tools[1].GetWebSearch() == nil {
+					t.Fatalf("second tool not web search: %+v", tools[1])
+				}
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -258,6 +284,92 @@ func TestXAIResponseToLLM(t *testing.T) {
 				}
 			},
 		},
+		"success: server-side tool calls are not function calls": {
+			resp: &xaipb.GetChatCompletionResponse{
+				Outputs: []*xaipb.CompletionOutput{{
+					Message: &xaipb.CompletionMessage{
+						Role:    xaipb.MessageRole_ROLE_ASSISTANT,
+						Content: "done",
+						ToolCalls: []*xaipb.ToolCall{
+							{
+								Id:   "ws1",
+								Type: xaipb.ToolCallType_TOOL_CALL_TYPE_WEB_SEARCH_TOOL,
+								Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{
+									Name:      "web_search",
+									Arguments: `{"query":"tumix"}`,
+								}},
+							},
+							{
+								Id:   "ce1",
+								Type: xaipb.ToolCallType_TOOL_CALL_TYPE_CODE_EXECUTION_TOOL,
+								Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{
+									Arguments: `{"code":"print(1)"}`,
+								}},
+							},
+						},
+					},
+				}},
+			},
+			assertf: func(t *testing.T, got *genai.Content, _ *genai.GenerateContentResponseUsageMetadata, meta map[string]any, _ genai.FinishReason) {
+				t.Helper()
+
+				if len(got.Parts) != 2 || got.Parts[0].Text != "done" {
+					t.Fatalf("parts = %+v", got.Parts)
+				}
+				code := got.Parts[1].ExecutableCode
+				if code == nil || code.Code != "print(1)" || code.Language != genai.LanguagePython {
+					t.Fatalf("executable code = %+v", code)
+				}
+				if diff := cmp.Diff([]string{"web_search", "code_execution"}, meta[XAIServerSideToolsKey]); diff != "" {
+					t.Fatalf("server-side tools diff (-want +got):\n%s", diff)
+				}
+			},
+		},
+		"success: encrypted reasoning rides on the thought signature": {
+			resp: &xaipb.GetChatCompletionResponse{
+				Outputs: []*xaipb.CompletionOutput{{
+					Message: &xaipb.CompletionMessage{
+						Role:             xaipb.MessageRole_ROLE_ASSISTANT,
+						Content:          "answer",
+						ReasoningContent: "think",
+						EncryptedContent: "blob",
+					},
+				}},
+			},
+			assertf: func(t *testing.T, got *genai.Content, _ *genai.GenerateContentResponseUsageMetadata, _ map[string]any, _ genai.FinishReason) {
+				t.Helper()
+
+				want := []*genai.Part{
+					{Text: "think", Thought: true, ThoughtSignature: xai.ThoughtSignature("blob")},
+					{Text: "answer"},
+				}
+				if diff := cmp.Diff(want, got.Parts); diff != "" {
+					t.Fatalf("parts diff (-want +got):\n%s", diff)
+				}
+			},
+		},
+		"success: encrypted reasoning without reasoning text": {
+			resp: &xaipb.GetChatCompletionResponse{
+				Outputs: []*xaipb.CompletionOutput{{
+					Message: &xaipb.CompletionMessage{
+						Role:             xaipb.MessageRole_ROLE_ASSISTANT,
+						Content:          "answer",
+						EncryptedContent: "blob",
+					},
+				}},
+			},
+			assertf: func(t *testing.T, got *genai.Content, _ *genai.GenerateContentResponseUsageMetadata, _ map[string]any, _ genai.FinishReason) {
+				t.Helper()
+
+				want := []*genai.Part{
+					{Text: "answer"},
+					{Thought: true, ThoughtSignature: xai.ThoughtSignature("blob")},
+				}
+				if diff := cmp.Diff(want, got.Parts); diff != "" {
+					t.Fatalf("parts diff (-want +got):\n%s", diff)
+				}
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -275,6 +387,42 @@ func TestXAIResponseToLLM(t *testing.T) {
 	}
 }
 
+func TestXAIResponseToLLMLogprobs(t *testing.T) {
+	t.Parallel()
+
+	resp := newTestXAIResponse(t, &xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "yes"},
+			Logprobs: &xaipb.LogProbs{Content: []*xaipb.LogProb{
+				{Token: "y", Logprob: -0.5, TopLogprobs: []*xaipb.TopLogProb{{Token: "y", Logprob: -0.5}, {Token: "n", Logprob: -1}}},
+				{Token: "es", Logprob: -1.5},
+			}},
+		}},
+	})
+
+	got := XAIResponseToLLM(resp)
+	want := &genai.LogprobsResult{
+		ChosenCandidates: []*genai.LogprobsResultCandidate{{Token: "y", LogProbability: -0.5}, {Token: "es", LogProbability: -1.5}},
+		TopCandidates: []*genai.LogprobsResultTopCandidates{
+			{Candidates: []*genai.LogprobsResultCandidate{{Token: "y", LogProbability: -0.5}, {Token: "n", LogProbability: -1}}},
+			{},
+		},
+	}
+	if diff := cmp.Diff(want, got.LogprobsResult); diff != "" {
+		t.Fatalf("LogprobsResult mismatch (-want +got):\n%s", diff)
+	}
+	if got.AvgLogprobs != -1 {
+		t.Fatalf("AvgLogprobs = %v, want -1", got.AvgLogprobs)
+	}
+
+	plain := XAIResponseToLLM(newTestXAIResponse(t, &xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "yes"}}},
+	}))
+	if plain.LogprobsResult != nil || plain.AvgLogprobs != 0 {
+		t.Fatalf("logprobs without request = %+v, %v, want none", plain.LogprobsResult, plain.AvgLogprobs)
+	}
+}
+
 func TestXAIResponseToLLMNil(t *testing.T) {
 	got := XAIResponseToLLM(nil)
 	if got.ErrorCode != "NIL_RESPONSE" {
@@ -370,15 +518,27 @@ func TestXAIStreamAggregatorEmptyContent(t *testing.T) {
 	}
 }
 
-func TestAppendDelta(t *testing.T) {
-	var acc strings.Builder
-	acc.WriteString("Hello")
-	if delta := appendDeltaToBuilder("Hello world", &acc); delta != " world" {
+func TestStreamTextAppend(t *testing.T) {
+	var acc streamText
+	if delta := acc.append("Hello"); delta != "Hello" {
+		t.Fatalf("delta = %q, want Hello", delta)
+	}
+	if delta := acc.append("Hello world"); delta != " world" {
 		t.Fatalf("delta = %q, want %q", delta, " world")
 	}
+	if delta := acc.append("Hello world"); delta != "" {
+		t.Fatalf("delta = %q, want none", delta)
+	}
+	if delta := acc.append("!"); delta != "!" || acc.String() != "Hello world!" {
+		t.Fatalf("delta = %q, text = %q, want ! appended", delta, acc.String())
+	}
+	if delta := acc.append("Hello world! Bye"); delta != " Bye" || acc.String() != "Hello world! Bye" {
+		t.Fatalf("delta = %q, text = %q, want the suffix appended", delta, acc.String())
+	}
 	acc.Reset()
 	acc.WriteString("foo")
-	if delta := appendDeltaToBuilder("bar", &acc); delta != "bar" {
+	acc.last = "foo"
+	if delta := acc.append("bar"); delta != "bar" {
 		t.Fatalf("delta = %q, want bar", delta)
 	}
 }
@@ -438,6 +598,48 @@ func BenchmarkXAIStreamAggregator(b *testing.B) {
 	}
 }
 
+// BenchmarkXAIStreamAggregatorStream aggregates a streamed xAI completion and reports the throughput in streamed text.
+func BenchmarkXAIStreamAggregatorStream(b *testing.B) {
+	tests := map[string]struct {
+		chunks    int
+		deltaSize int
+	}{
+		"chunks=64/delta=16":    {chunks: 64, deltaSize: 16},
+		"chunks=1024/delta=16":  {chunks: 1024, deltaSize: 16},
+		"chunks=4096/delta=64":  {chunks: 4096, deltaSize: 64},
+		"chunks=256/delta=4096": {chunks: 256, deltaSize: 4096},
+	}
+
+	for name, tt := range tests {
+		b.Run(name, func(b *testing.B) {
+			text := strings.Repeat("x", tt.chunks*tt.deltaSize)
+			msg := &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT}
+			resp := newTestXAIResponse(b, &xaipb.GetChatCompletionResponse{
+				Outputs: []*xaipb.CompletionOutput{{Message: msg}},
+			})
+
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			for b.Loop() {
+				aggr := NewXAIStreamAggregator()
+				for i := 1; i <= tt.chunks; i++ {
+					// Like the responses of a stream, each holds the text streamed so far, in the same buffer.
+					msg.Content = text[:i*tt.deltaSize]
+					for _, err := range aggr.Process(b.Context(), resp) {
+						if err != nil {
+							b.Fatalf("Process() error = %v", err)
+						}
+					}
+				}
+				final := aggr.Close()
+				if final == nil || len(final.Content.Parts[0].Text) != len(text) {
+					b.Fatalf("Close() = %+v, want the whole text", final)
+				}
+			}
+		})
+	}
+}
+
 func TestMapXAIFinishReason(t *testing.T) {
 	tests := map[string]struct {
 		in   string
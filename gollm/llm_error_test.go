@@ -65,7 +65,7 @@ func TestXAILLM_GenerateContent_InvalidMessages(t *testing.T) {
 				Parts: []*genai.Part{
 					{
 						InlineData: &genai.Blob{
-							MIMEType: "text/plain",
+							MIMEType: "application/pdf",
 						},
 					},
 				},
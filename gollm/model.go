@@ -0,0 +1,502 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gollm
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm/internal/adapter"
+	"github.com/zchee/tumix/gollm/xai"
+	"github.com/zchee/tumix/internal/version"
+)
+
+// Providers accepted by [NewModel].
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderXAI    = "xai"
+)
+
+// Default embedding models used when [WithEmbeddingModel] is not given.
+//
+// xAI has no default; Embed fails unless an embedding model is configured.
+const (
+	DefaultGeminiEmbeddingModel = "gemini-embedding-001"
+	DefaultOpenAIEmbeddingModel = openai.EmbeddingModelTextEmbedding3Small
+)
+
+// Model is a provider-agnostic LLM client.
+//
+// Unlike [model.LLM], it does not depend on ADK request plumbing, so callers can switch providers by changing the
+// provider name passed to [NewModel].
+type Model interface {
+	// Name returns the model name.
+	Name() string
+	// Generate returns the first candidate of a non-streaming completion.
+	Generate(ctx context.Context, req *Request) (*Response, error)
+	// Stream returns the completion as a sequence of partial responses followed by the final one.
+	Stream(ctx context.Context, req *Request) iter.Seq2[*Response, error]
+	// CountTokens returns the number of input tokens of contents.
+	CountTokens(ctx context.Context, contents []*genai.Content) (int, error)
+	// Embed returns one embedding vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Request is a generation request sent to a [Model].
+type Request struct {
+	Contents []*genai.Content
+	Config   *genai.GenerateContentConfig
+}
+
+// Response is a generation response returned by a [Model].
+type Response struct {
+	Content      *genai.Content
+	Usage        *genai.GenerateContentResponseUsageMetadata
+	FinishReason genai.FinishReason
+	// Partial reports whether the response is an incremental stream chunk.
+	Partial bool
+}
+
+// Text returns the concatenated non-thought text parts of the response.
+func (r *Response) Text() string {
+	if r == nil || r.Content == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range r.Content.Parts {
+		if part != nil && !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// ModelOption configures [NewModel].
+type ModelOption func(*modelOptions)
+
+type modelOptions struct {
+	embeddingModel string
+	providerParams *ProviderParams
+	httpClient     *http.Client
+	geminiConfig   *genai.ClientConfig
+	openaiOptions  []option.RequestOption
+	xaiOptions     []xai.ClientOption
+}
+
+// WithEmbeddingModel sets the model used by [Model.Embed].
+func WithEmbeddingModel(name string) ModelOption {
+	return func(o *modelOptions) { o.embeddingModel = name }
+}
+
+// WithProviderParams sets the default provider-specific parameters applied to OpenAI and xAI requests.
+func WithProviderParams(params *ProviderParams) ModelOption {
+	return func(o *modelOptions) { o.providerParams = params }
+}
+
+// WithHTTPClient sets the HTTP client used by the Gemini and OpenAI providers. The xAI provider uses gRPC and ignores it.
+func WithHTTPClient(client *http.Client) ModelOption {
+	return func(o *modelOptions) { o.httpClient = client }
+}
+
+// WithGeminiConfig sets the base Gemini client configuration. The API key and HTTP client given to [NewModel] take
+// precedence over its fields.
+func WithGeminiConfig(cfg *genai.ClientConfig) ModelOption {
+	return func(o *modelOptions) { o.geminiConfig = cfg }
+}
+
+// WithOpenAIOptions appends OpenAI request options.
+func WithOpenAIOptions(opts ...option.RequestOption) ModelOption {
+	return func(o *modelOptions) { o.openaiOptions = append(o.openaiOptions, opts...) }
+}
+
+// WithXAIOptions appends xAI client options.
+func WithXAIOptions(opts ...xai.ClientOption) ModelOption {
+	return func(o *modelOptions) { o.xaiOptions = append(o.xaiOptions, opts...) }
+}
+
+// NewModel creates a [Model] for provider, one of [ProviderGemini], [ProviderOpenAI] or [ProviderXAI].
+//
+// An empty apiKey lets each SDK fall back to its environment variable.
+func NewModel(ctx context.Context, provider, apiKey, modelName string, opts ...ModelOption) (Model, error) {
+	var o modelOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		m   Model
+		err error
+	)
+	switch provider {
+	case ProviderGemini:
+		m, err = newGeminiModel(ctx, apiKey, modelName, &o)
+	case ProviderOpenAI:
+		m, err = newOpenAIModel(ctx, apiKey, modelName, &o)
+	case ProviderXAI:
+		m, err = newXAIModel(ctx, apiKey, modelName, &o)
+	default:
+		return nil, fmt.Errorf("unsupported provider %q; must be one of: %s, %s, %s", provider, ProviderGemini, ProviderOpenAI, ProviderXAI)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s model %s: %w", provider, modelName, err)
+	}
+	return m, nil
+}
+
+// llmModel implements the Generate and Stream methods of [Model] on top of an adk [model.LLM].
+type llmModel struct {
+	llm model.LLM
+}
+
+// Name implements [Model].
+func (m *llmModel) Name() string { return m.llm.Name() }
+
+// Generate implements [Model].
+func (m *llmModel) Generate(ctx context.Context, req *Request) (*Response, error) {
+	for resp, err := range m.llm.GenerateContent(ctx, m.llmRequest(req), false) {
+		if err != nil {
+			return nil, err
+		}
+		return responseFromLLM(resp), nil
+	}
+	return nil, errors.New("empty response")
+}
+
+// Stream implements [Model].
+func (m *llmModel) Stream(ctx context.Context, req *Request) iter.Seq2[*Response, error] {
+	return func(yield func(*Response, error) bool) {
+		for resp, err := range m.llm.GenerateContent(ctx, m.llmRequest(req), true) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(responseFromLLM(resp), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *llmModel) llmRequest(req *Request) *model.LLMRequest {
+	if req == nil {
+		req = &Request{}
+	}
+	return &model.LLMRequest{
+		Model:    m.llm.Name(),
+		Contents: req.Contents,
+		Config:   req.Config,
+	}
+}
+
+func responseFromLLM(resp *model.LLMResponse) *Response {
+	if resp == nil {
+		return &Response{}
+	}
+	return &Response{
+		Content:      resp.Content,
+		Usage:        resp.UsageMetadata,
+		FinishReason: resp.FinishReason,
+		Partial:      resp.Partial,
+	}
+}
+
+// openAIModel implements [Model] using the OpenAI Responses and Embeddings APIs.
+type openAIModel struct {
+	llmModel
+	client         openai.Client
+	embeddingModel string
+}
+
+var _ Model = (*openAIModel)(nil)
+
+func newOpenAIModel(ctx context.Context, apiKey, modelName string, o *modelOptions) (*openAIModel, error) {
+	opts := o.openaiOptions
+	if o.httpClient != nil {
+		opts = append([]option.RequestOption{option.WithHTTPClient(o.httpClient)}, opts...)
+	}
+	llm, err := NewOpenAILLM(ctx, apiKey, modelName, o.providerParams, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &openAIModel{
+		llmModel:       llmModel{llm: llm},
+		client:         llm.(*openAILLM).client,
+		embeddingModel: o.embeddingModel,
+	}, nil
+}
+
+// CountTokens implements [Model].
+func (m *openAIModel) CountTokens(ctx context.Context, contents []*genai.Content) (int, error) {
+	items, err := adapter.GenAIToResponsesInput(contents)
+	if err != nil {
+		return 0, fmt.Errorf("convert content: %w", err)
+	}
+	resp, err := m.client.Responses.InputTokens.Count(ctx, responses.InputTokenCountParams{
+		Model: param.NewOpt(m.Name()),
+		Input: responses.InputTokenCountParamsInputUnion{OfResponseInputItemArray: items},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("count tokens: %w", err)
+	}
+	return int(resp.InputTokens), nil
+}
+
+// Embed implements [Model].
+func (m *openAIModel) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	resp, err := m.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: cmp.Or(openai.EmbeddingModel(m.embeddingModel), DefaultOpenAIEmbeddingModel),
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+
+	out := make([][]float32, len(texts))
+	for _, e := range resp.Data {
+		if e.Index < 0 || int(e.Index) >= len(out) {
+			return nil, fmt.Errorf("embed: index %d out of range", e.Index)
+		}
+		vec := make([]float32, len(e.Embedding))
+		for i, v := range e.Embedding {
+			vec[i] = float32(v)
+		}
+		out[e.Index] = vec
+	}
+	return out, nil
+}
+
+// xaiModel implements [Model] using the xAI chat, tokenizer and embedding services.
+type xaiModel struct {
+	llmModel
+	client         *xai.Client
+	embeddingModel string
+}
+
+var _ Model = (*xaiModel)(nil)
+
+func newXAIModel(ctx context.Context, apiKey, modelName string, o *modelOptions) (*xaiModel, error) {
+	llm, err := NewXAILLM(ctx, apiKey, modelName, o.providerParams, o.xaiOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return &xaiModel{
+		llmModel:       llmModel{llm: llm},
+		client:         llm.(*xaiLLM).client,
+		embeddingModel: o.embeddingModel,
+	}, nil
+}
+
+// CountTokens implements [Model].
+//
+// The xAI tokenizer only accepts text, so non-text parts such as images are not counted.
+func (m *xaiModel) CountTokens(ctx context.Context, contents []*genai.Content) (int, error) {
+	text := contentsText(contents)
+	if text == "" {
+		return 0, nil
+	}
+	resp, err := m.client.Tokenizer.Tokenize(ctx, text, m.Name())
+	if err != nil {
+		return 0, fmt.Errorf("count tokens: %w", err)
+	}
+	return len(resp.GetTokens()), nil
+}
+
+// Embed implements [Model].
+func (m *xaiModel) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if m.embeddingModel == "" {
+		return nil, errors.New("embed: xAI embedding model is not configured")
+	}
+	resp, err := m.client.Embed.CreateStrings(ctx, m.embeddingModel, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+
+	out := make([][]float32, len(texts))
+	for _, e := range resp.GetEmbeddings() {
+		if e.GetIndex() < 0 || int(e.GetIndex()) >= len(out) {
+			return nil, fmt.Errorf("embed: index %d out of range", e.GetIndex())
+		}
+		if vecs := e.GetEmbeddings(); len(vecs) > 0 {
+			out[e.GetIndex()] = vecs[0].GetFloatArray()
+		}
+	}
+	return out, nil
+}
+
+// contentsText joins the text parts of contents with newlines.
+func contentsText(contents []*genai.Content) string {
+	var texts []string
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		for _, part := range c.Parts {
+			if part != nil && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// geminiModel implements [Model] using the Gemini API.
+type geminiModel struct {
+	client         *genai.Client
+	name           string
+	embeddingModel string
+}
+
+var _ Model = (*geminiModel)(nil)
+
+func newGeminiModel(ctx context.Context, apiKey, modelName string, o *modelOptions) (*geminiModel, error) {
+	var cfg genai.ClientConfig
+	if o.geminiConfig != nil {
+		cfg = *o.geminiConfig
+	}
+	if apiKey != "" {
+		cfg.APIKey = apiKey
+	}
+	if o.httpClient != nil {
+		cfg.HTTPClient = o.httpClient
+	}
+	if cfg.HTTPOptions.Headers == nil {
+		cfg.HTTPOptions.Headers = make(http.Header)
+	} else {
+		cfg.HTTPOptions.Headers = cfg.HTTPOptions.Headers.Clone()
+	}
+	if cfg.HTTPOptions.Headers.Get("User-Agent") == "" {
+		cfg.HTTPOptions.Headers.Set("User-Agent", version.UserAgent("genai"))
+	}
+
+	client, err := genai.NewClient(ctx, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new Gemini client: %w", err)
+	}
+	return &geminiModel{
+		client:         client,
+		name:           modelName,
+		embeddingModel: cmp.Or(o.embeddingModel, DefaultGeminiEmbeddingModel),
+	}, nil
+}
+
+// Name implements [Model].
+func (m *geminiModel) Name() string { return m.name }
+
+// Generate implements [Model].
+func (m *geminiModel) Generate(ctx context.Context, req *Request) (*Response, error) {
+	contents, cfg := requestParts(req)
+	resp, err := m.client.Models.GenerateContent(ctx, m.name, contents, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("generate content: %w", err)
+	}
+	return responseFromGenAI(resp, false)
+}
+
+// Stream implements [Model].
+func (m *geminiModel) Stream(ctx context.Context, req *Request) iter.Seq2[*Response, error] {
+	contents, cfg := requestParts(req)
+	return func(yield func(*Response, error) bool) {
+		for resp, err := range m.client.Models.GenerateContentStream(ctx, m.name, contents, cfg) {
+			if err != nil {
+				yield(nil, fmt.Errorf("generate content: %w", err))
+				return
+			}
+			out, err := responseFromGenAI(resp, true)
+			if !yield(out, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CountTokens implements [Model].
+func (m *geminiModel) CountTokens(ctx context.Context, contents []*genai.Content) (int, error) {
+	resp, err := m.client.Models.CountTokens(ctx, m.name, contents, nil)
+	if err != nil {
+		return 0, fmt.Errorf("count tokens: %w", err)
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// Embed implements [Model].
+func (m *geminiModel) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	resp, err := m.client.Models.EmbedContent(ctx, m.embeddingModel, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embed: got %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+	}
+
+	out := make([][]float32, len(texts))
+	for i, e := range resp.Embeddings {
+		if e != nil {
+			out[i] = e.Values
+		}
+	}
+	return out, nil
+}
+
+func requestParts(req *Request) ([]*genai.Content, *genai.GenerateContentConfig) {
+	if req == nil {
+		return nil, nil
+	}
+	return req.Contents, req.Config
+}
+
+// responseFromGenAI converts the first candidate of resp. Stream chunks without a finish reason are partial.
+func responseFromGenAI(resp *genai.GenerateContentResponse, stream bool) (*Response, error) {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0] == nil {
+		if resp != nil && resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("prompt blocked: %s", resp.PromptFeedback.BlockReason)
+		}
+		return nil, errors.New("empty response")
+	}
+	cand := resp.Candidates[0]
+	return &Response{
+		Content:      cand.Content,
+		Usage:        resp.UsageMetadata,
+		FinishReason: cand.FinishReason,
+		Partial:      stream && (cand.FinishReason == "" || cand.FinishReason == genai.FinishReasonUnspecified),
+	}, nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gollm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openai/openai-go/v3/option"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// fakeLLM replays fixed responses for both streaming and non-streaming calls.
+type fakeLLM struct {
+	resps []*model.LLMResponse
+	err   error
+	req   *model.LLMRequest
+}
+
+func (f *fakeLLM) Name() string { return "fake" }
+
+func (f *fakeLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	f.req = req
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if f.err != nil {
+			yield(nil, f.err)
+			return
+		}
+		for _, resp := range f.resps {
+			if !yield(resp, nil) {
+				return
+			}
+		}
+	}
+}
+
+// newTestServer serves body for requests whose path has the given suffix, recording the request body.
+func newTestServer(t *testing.T, routes map[string]string, gotBody *string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if gotBody != nil {
+			*gotBody = string(data)
+		}
+		for suffix, body := range routes {
+			if strings.HasSuffix(r.URL.Path, suffix) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, body)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewModelUnsupportedProvider(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewModel(t.Context(), "anthropic", "key", "claude")
+	if err == nil || m != nil {
+		t.Fatalf("NewModel(anthropic) = %v, %v, want nil model and error", m, err)
+	}
+}
+
+func TestLLMModelGenerate(t *testing.T) {
+	t.Parallel()
+
+	usage := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 3, CandidatesTokenCount: 1}
+	llm := &fakeLLM{resps: []*model.LLMResponse{
+		{Content: genai.NewContentFromText("first", genai.RoleModel), UsageMetadata: usage, FinishReason: genai.FinishReasonStop},
+		{Content: genai.NewContentFromText("second", genai.RoleModel)},
+	}}
+	m := &llmModel{llm: llm}
+	cfg := &genai.GenerateContentConfig{MaxOutputTokens: 8}
+
+	got, err := m.Generate(t.Context(), &Request{Contents: genai.Text("ping"), Config: cfg})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := &Response{Content: genai.NewContentFromText("first", genai.RoleModel), Usage: usage, FinishReason: genai.FinishReasonStop}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Generate() mismatch (-want +got):\n%s", diff)
+	}
+	if llm.req.Model != "fake" || llm.req.Config != cfg {
+		t.Fatalf("LLM request = %+v, want model and config forwarded", llm.req)
+	}
+
+	if _, err := (&llmModel{llm: &fakeLLM{}}).Generate(t.Context(), nil); err == nil {
+		t.Fatal("Generate() with no responses error = nil, want error")
+	}
+	if _, err := (&llmModel{llm: &fakeLLM{err: errors.New("boom")}}).Generate(t.Context(), nil); err == nil {
+		t.Fatal("Generate() with LLM error = nil, want error")
+	}
+}
+
+func TestLLMModelStream(t *testing.T) {
+	t.Parallel()
+
+	m := &llmModel{llm: &fakeLLM{resps: []*model.LLMResponse{
+		{Content: genai.NewContentFromText("he", genai.RoleModel), Partial: true},
+		{Content: genai.NewContentFromText("hello", genai.RoleModel), FinishReason: genai.FinishReasonStop},
+	}}}
+
+	var texts []string
+	var partial []bool
+	for resp, err := range m.Stream(t.Context(), &Request{Contents: genai.Text("ping")}) {
+		if err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+		texts = append(texts, resp.Text())
+		partial = append(partial, resp.Partial)
+	}
+	if diff := cmp.Diff([]string{"he", "hello"}, texts); diff != "" {
+		t.Fatalf("Stream() texts mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]bool{true, false}, partial); diff != "" {
+		t.Fatalf("Stream() partial mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResponseText(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		resp *Response
+		want string
+	}{
+		"nil":   {},
+		"empty": {resp: &Response{}},
+		"text": {
+			resp: &Response{Content: genai.NewContentFromText("hi", genai.RoleModel)},
+			want: "hi",
+		},
+		"thought": {
+			resp: &Response{Content: &genai.Content{Parts: []*genai.Part{{Text: "hmm", Thought: true}, nil, {Text: "hi"}}}},
+			want: "hi",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.resp.Text(); got != tt.want {
+				t.Fatalf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentsText(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		contents []*genai.Content
+		want     string
+	}{
+		"none": {},
+		"text": {
+			contents: []*genai.Content{genai.NewContentFromText("a", genai.RoleUser), nil, genai.NewContentFromText("b", genai.RoleModel)},
+			want:     "a\nb",
+		},
+		"image skipped": {
+			contents: []*genai.Content{{Parts: []*genai.Part{genai.NewPartFromText("a"), genai.NewPartFromBytes([]byte("png"), "image/png"), nil}}},
+			want:     "a",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := contentsText(tt.contents); got != tt.want {
+				t.Fatalf("contentsText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIModel(t *testing.T) {
+	t.Parallel()
+
+	var body string
+	srv := newTestServer(t, map[string]string{
+		"/responses/input_tokens": `{"input_tokens":7,"object":"response.input_tokens"}`,
+		"/embeddings": `{"object":"list","model":"text-embedding-3-small","data":[
+			{"object":"embedding","index":1,"embedding":[0.5,1]},
+			{"object":"embedding","index":0,"embedding":[0.25]}]}`,
+	}, &body)
+
+	m, err := NewModel(t.Context(), ProviderOpenAI, "key", "gpt-test", WithOpenAIOptions(option.WithBaseURL(srv.URL), option.WithMaxRetries(0)))
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+	if m.Name() != "gpt-test" {
+		t.Fatalf("Name() = %q, want gpt-test", m.Name())
+	}
+
+	tokens, err := m.CountTokens(t.Context(), genai.Text("ping"))
+	if err != nil {
+		t.Fatalf("CountTokens() error = %v", err)
+	}
+	if tokens != 7 {
+		t.Fatalf("CountTokens() = %d, want 7", tokens)
+	}
+	if !strings.Contains(body, `"model":"gpt-test"`) {
+		t.Fatalf("count request body = %s, want model gpt-test", body)
+	}
+
+	got, err := m.Embed(t.Context(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if diff := cmp.Diff([][]float32{{0.25}, {0.5, 1}}, got); diff != "" {
+		t.Fatalf("Embed() mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(body, `"model":"text-embedding-3-small"`) {
+		t.Fatalf("embed request body = %s, want default embedding model", body)
+	}
+}
+
+func TestGeminiModel(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, map[string]string{
+		":generateContent": `{"candidates":[{"content":{"role":"model","parts":[{"text":"pong"}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":2,"candidatesTokenCount":1,"totalTokenCount":3}}`,
+		":streamGenerateContent": "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"po\"}]}}]}\n\n" +
+			"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"ng\"}]},\"finishReason\":\"STOP\"}]}\n\n",
+		":countTokens":        `{"totalTokens":5}`,
+		":batchEmbedContents": `{"embeddings":[{"values":[1,2]},{"values":[3]}]}`,
+	}, nil)
+
+	m, err := NewModel(t.Context(), ProviderGemini, "key", "gemini-test", WithGeminiConfig(&genai.ClientConfig{
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+	}))
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+
+	resp, err := m.Generate(t.Context(), &Request{Contents: genai.Text("ping")})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Text() != "pong" || resp.FinishReason != genai.FinishReasonStop || resp.Partial || resp.Usage.TotalTokenCount != 3 {
+		t.Fatalf("Generate() = %+v, want final pong with usage", resp)
+	}
+
+	var texts []string
+	var partial []bool
+	for resp, err := range m.Stream(t.Context(), &Request{Contents: genai.Text("ping")}) {
+		if err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+		texts = append(texts, resp.Text())
+		partial = append(partial, resp.Partial)
+	}
+	if diff := cmp.Diff([]string{"po", "ng"}, texts); diff != "" {
+		t.Fatalf("Stream() texts mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]bool{true, false}, partial); diff != "" {
+		t.Fatalf("Stream() partial mismatch (-want +got):\n%s", diff)
+	}
+
+	tokens, err := m.CountTokens(t.Context(), genai.Text("ping"))
+	if err != nil {
+		t.Fatalf("CountTokens() error = %v", err)
+	}
+	if tokens != 5 {
+		t.Fatalf("CountTokens() = %d, want 5", tokens)
+	}
+
+	got, err := m.Embed(t.Context(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if diff := cmp.Diff([][]float32{{1, 2}, {3}}, got); diff != "" {
+		t.Fatalf("Embed() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestXAIModelEmbedRequiresModel(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewModel(t.Context(), ProviderXAI, "key", "grok-test")
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+	if _, err := m.Embed(t.Context(), []string{"a"}); err == nil {
+		t.Fatal("Embed() without embedding model error = nil, want error")
+	}
+	if got, err := m.Embed(t.Context(), nil); err != nil || got != nil {
+		t.Fatalf("Embed(nil) = %v, %v, want nil, nil", got, err)
+	}
+}
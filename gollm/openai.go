@@ -17,6 +17,7 @@
 package gollm
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -28,6 +29,7 @@ import (
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/adk/model"
@@ -40,12 +42,16 @@ import (
 
 var openaiTracer = otel.Tracer("github.com/zchee/tumix/gollm/openai")
 
+// openAIBackgroundPollInterval is how often a background response is polled until it finishes.
+const openAIBackgroundPollInterval = 2 * time.Second
+
 // openAILLM implements the adk [model.LLM] interface using OpenAI SDK.
 type openAILLM struct {
 	client         openai.Client
 	name           string
 	userAgent      string
 	providerParams *ProviderParams
+	pollInterval   time.Duration
 }
 
 var _ model.LLM = (*openAILLM)(nil)
@@ -77,12 +83,16 @@ func NewOpenAILLM(_ context.Context, apiKey, modelName string, params *ProviderP
 		name:           modelName,
 		userAgent:      userAgent,
 		providerParams: params,
+		pollInterval:   openAIBackgroundPollInterval,
 	}, nil
 }
 
 // Name implements [model.LLM].
 func (m *openAILLM) Name() string { return m.name }
 
+// Capabilities implements [CapabilityReporter].
+func (m *openAILLM) Capabilities() Capabilities { return ProviderCapabilities(ProviderOpenAI, m.name) }
+
 // GenerateContent implements [model.LLM].
 func (m *openAILLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
 	ctx, span := openaiTracer.Start(ctx, "gollm.openai.GenerateContent")
@@ -110,9 +120,12 @@ func (m *openAILLM) GenerateContent(ctx context.Context, req *model.LLMRequest,
 
 		for range count {
 			resp, err := m.client.Responses.New(ctx, *params)
+			if err == nil {
+				resp, err = m.awaitBackground(ctx, resp)
+			}
 			if err != nil {
-				spanErr = err
-				yield(nil, err)
+				spanErr = wrapOpenAIError(err)
+				yield(nil, spanErr)
 				return
 			}
 
@@ -166,6 +179,16 @@ func (m *openAILLM) responseParams(req *model.LLMRequest) (*responses.ResponseNe
 	case cfg.ResponseLogprobs:
 		params.Include = append(params.Include, responses.ResponseIncludableMessageOutputTextLogprobs)
 	}
+	format, ok, err := adapter.GenAIToResponsesFormat(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("convert response format: %w", err)
+	}
+	if ok {
+		params.Text.Format = format
+	}
+	if reasoning, ok := adapter.GenAIThinkingToReasoning(cfg.ThinkingConfig); ok {
+		params.Reasoning = reasoning
+	}
 	if len(cfg.Tools) > 0 {
 		tools, tc := adapter.GenAIToolsToResponses(cfg.Tools, cfg.ToolConfig)
 		params.Tools = tools
@@ -179,6 +202,36 @@ func (m *openAILLM) responseParams(req *model.LLMRequest) (*responses.ResponseNe
 	return &params, nil
 }
 
+// awaitBackground polls a response created in background mode until it leaves the queued and in-progress states.
+//
+// Responses created in the foreground are returned as is.
+func (m *openAILLM) awaitBackground(ctx context.Context, resp *responses.Response) (*responses.Response, error) {
+	ticker := time.NewTicker(cmp.Or(m.pollInterval, openAIBackgroundPollInterval))
+	defer ticker.Stop()
+
+	for resp.Status == responses.ResponseStatusQueued || resp.Status == responses.ResponseStatusInProgress {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		var err error
+		resp, err = m.client.Responses.Get(ctx, resp.ID, responses.ResponseGetParams{})
+		if err != nil {
+			return nil, fmt.Errorf("poll background response: %w", err)
+		}
+	}
+
+	switch resp.Status {
+	case responses.ResponseStatusFailed:
+		return nil, fmt.Errorf("background response %s failed: %s", resp.ID, resp.Error.Message)
+	case responses.ResponseStatusCancelled:
+		return nil, fmt.Errorf("background response %s was cancelled", resp.ID)
+	}
+	return resp, nil
+}
+
 // stream executes a streaming chat completion request and aggregates partial responses.
 //
 // It forwards each streamed chunk through the OpenAI aggregator and emits final output
@@ -205,8 +258,8 @@ func (m *openAILLM) stream(ctx context.Context, params *responses.ResponseNewPar
 		}
 
 		if err := stream.Err(); err != nil && !errors.Is(err, io.EOF) {
-			spanErr = err
-			yield(nil, err)
+			spanErr = wrapOpenAIError(err)
+			yield(nil, spanErr)
 			return
 		}
 
@@ -222,6 +275,15 @@ func (m *openAILLM) stream(ctx context.Context, params *responses.ResponseNewPar
 	}
 }
 
+// wrapOpenAIError marks API rejections of the structured-output response schema, which otherwise read as a generic
+// bad request.
+func wrapOpenAIError(err error) error {
+	if adapter.IsOpenAISchemaError(err) {
+		return fmt.Errorf("response schema rejected by OpenAI: %w", err)
+	}
+	return err
+}
+
 func applyOpenAIProviderParams(req *model.LLMRequest, defaults *ProviderParams, params *responses.ResponseNewParams) {
 	pp, ok := effectiveProviderParams(req, defaults)
 	if !ok || pp.OpenAI == nil {
@@ -234,3 +296,29 @@ func applyOpenAIProviderParams(req *model.LLMRequest, defaults *ProviderParams,
 		mutate(params)
 	}
 }
+
+// OpenAIReasoningEffort returns a mutator that sets the reasoning effort of OpenAI reasoning models, overriding the
+// effort derived from the request thinking config.
+func OpenAIReasoningEffort(effort shared.ReasoningEffort) OpenAIParamMutator {
+	return func(p *responses.ResponseNewParams) {
+		p.Reasoning.Effort = effort
+	}
+}
+
+// OpenAIReasoningSummary returns a mutator that requests reasoning summaries, which are surfaced as thought parts.
+func OpenAIReasoningSummary(summary shared.ReasoningSummary) OpenAIParamMutator {
+	return func(p *responses.ResponseNewParams) {
+		p.Reasoning.Summary = summary
+	}
+}
+
+// OpenAIBackground returns a mutator that runs OpenAI requests in background mode.
+//
+// Non-streaming calls poll the background response until it finishes, so long-running reasoning requests are not
+// bound to a single HTTP request. Background responses are stored by OpenAI.
+func OpenAIBackground() OpenAIParamMutator {
+	return func(p *responses.ResponseNewParams) {
+		p.Background = param.NewOpt(true)
+		p.Store = param.NewOpt(true)
+	}
+}
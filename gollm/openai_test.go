@@ -17,12 +17,19 @@
 package gollm
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	openai "github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/shared"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
 
@@ -224,3 +231,188 @@ func TestOpenAILLM_GenerateStream(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenAIResponseParamsReasoning(t *testing.T) {
+	t.Parallel()
+
+	budget := int32(2048)
+	tests := map[string]struct {
+		thinking    *genai.ThinkingConfig
+		defaults    *ProviderParams
+		wantEffort  shared.ReasoningEffort
+		wantSummary shared.ReasoningSummary
+		wantBgValid bool
+	}{
+		"no thinking config": {},
+		"thinking budget and summaries": {
+			thinking:    &genai.ThinkingConfig{ThinkingBudget: &budget, IncludeThoughts: true},
+			wantEffort:  shared.ReasoningEffortMedium,
+			wantSummary: shared.ReasoningSummaryAuto,
+		},
+		"mutators override thinking config": {
+			thinking: &genai.ThinkingConfig{ThinkingLevel: genai.ThinkingLevelLow},
+			defaults: &ProviderParams{OpenAI: &OpenAIProviderParams{Mutate: []OpenAIParamMutator{
+				OpenAIReasoningEffort(shared.ReasoningEffortHigh),
+				OpenAIReasoningSummary(shared.ReasoningSummaryDetailed),
+				OpenAIBackground(),
+			}}},
+			wantEffort:  shared.ReasoningEffortHigh,
+			wantSummary: shared.ReasoningSummaryDetailed,
+			wantBgValid: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			llm := &openAILLM{name: "o4-mini", providerParams: tt.defaults}
+			params, err := llm.responseParams(&model.LLMRequest{
+				Contents: genai.Text("ping"),
+				Config:   &genai.GenerateContentConfig{ThinkingConfig: tt.thinking},
+			})
+			if err != nil {
+				t.Fatalf("responseParams() error = %v", err)
+			}
+			if params.Reasoning.Effort != tt.wantEffort {
+				t.Fatalf("Reasoning.Effort = %q, want %q", params.Reasoning.Effort, tt.wantEffort)
+			}
+			if params.Reasoning.Summary != tt.wantSummary {
+				t.Fatalf("Reasoning.Summary = %q, want %q", params.Reasoning.Summary, tt.wantSummary)
+			}
+			if params.Background.Valid() != tt.wantBgValid || params.Store.Valid() != tt.wantBgValid {
+				t.Fatalf("Background = %+v, Store = %+v, want set %t", params.Background, params.Store, tt.wantBgValid)
+			}
+		})
+	}
+}
+
+func TestOpenAILLM_GenerateBackground(t *testing.T) {
+	t.Parallel()
+
+	const completed = `{"id":"resp_1","object":"response","status":"completed","output":[{"type":"message","role":"assistant",
+		"content":[{"type":"output_text","text":"done"}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`
+
+	tests := map[string]struct {
+		polls   []string
+		want    string
+		wantErr bool
+	}{
+		"polls until completed": {
+			polls: []string{`{"id":"resp_1","object":"response","status":"in_progress"}`, completed},
+			want:  "done",
+		},
+		"failed": {
+			polls:   []string{`{"id":"resp_1","object":"response","status":"failed","error":{"code":"server_error","message":"boom"}}`},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var gets int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodPost {
+					_, _ = io.WriteString(w, `{"id":"resp_1","object":"response","status":"queued"}`)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				_, _ = io.WriteString(w, tt.polls[min(gets, len(tt.polls)-1)])
+				gets++
+			}))
+			t.Cleanup(srv.Close)
+
+			params := &ProviderParams{OpenAI: &OpenAIProviderParams{Mutate: []OpenAIParamMutator{OpenAIBackground()}}}
+			llm, err := NewOpenAILLM(t.Context(), "key", "o4-mini", params, option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+			if err != nil {
+				t.Fatalf("NewOpenAILLM() error = %v", err)
+			}
+			llm.(*openAILLM).pollInterval = time.Millisecond
+
+			got, err := readResponse(llm.GenerateContent(t.Context(), &model.LLMRequest{
+				Contents: genai.Text("ping"),
+				Config:   &genai.GenerateContentConfig{},
+			}, false))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateContent() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got.FinalText != tt.want {
+				t.Fatalf("GenerateContent() text = %q, want %q", got.FinalText, tt.want)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if gets != len(tt.polls) {
+				t.Fatalf("polled %d times, want %d", gets, len(tt.polls))
+			}
+		})
+	}
+}
+
+func TestOpenAIResponseParamsFormat(t *testing.T) {
+	t.Parallel()
+
+	llm := &openAILLM{name: "gpt-4o-mini"}
+	params, err := llm.responseParams(&model.LLMRequest{
+		Contents: genai.Text("ping"),
+		Config: &genai.GenerateContentConfig{
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &genai.Schema{
+				Type:       genai.TypeObject,
+				Properties: map[string]*genai.Schema{"answer": {Type: genai.TypeString}},
+				Required:   []string{"answer"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("responseParams() error = %v", err)
+	}
+	format := params.Text.Format.OfJSONSchema
+	if format == nil {
+		t.Fatalf("Text.Format = %+v, want json_schema", params.Text.Format)
+	}
+	want := map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"answer": map[string]any{"type": "string"}},
+		"required":             []any{"answer"},
+		"additionalProperties": false,
+	}
+	if diff := cmp.Diff(want, format.Schema); diff != "" {
+		t.Fatalf("schema mismatch (-want +got):\n%s", diff)
+	}
+	if !format.Strict.Value {
+		t.Fatal("Strict = false, want true")
+	}
+}
+
+func TestOpenAILLM_GenerateSchemaRejected(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":{"message":"Invalid schema for response_format 'response': 'foo' is not valid.",
+			"type":"invalid_request_error","param":"text.format.schema","code":"invalid_json_schema"}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	llm, err := NewOpenAILLM(t.Context(), "key", "gpt-4o-mini", nil, option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewOpenAILLM() error = %v", err)
+	}
+	req := &model.LLMRequest{
+		Contents: genai.Text("ping"),
+		Config:   &genai.GenerateContentConfig{ResponseJsonSchema: map[string]any{"type": "foo"}},
+	}
+
+	for _, stream := range []bool{false, true} {
+		_, err := readResponse(llm.GenerateContent(t.Context(), req, stream))
+		if err == nil || !strings.Contains(err.Error(), "response schema rejected") {
+			t.Fatalf("GenerateContent(stream=%t) error = %v, want response schema rejected", stream, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package seed provides a [model.LLM] that derives the seed of every request from the configured seed and the scope
+// of the call, such as the agent, round, and sample of a TUMIX run.
+package seed
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"iter"
+	"math"
+
+	"google.golang.org/adk/model"
+
+	"github.com/zchee/tumix/gollm"
+)
+
+// LLM is a [model.LLM] that replaces the seed of every request made in a scope with the seed derived for that scope
+// (see [Derive]), so agents and samples sharing one configured seed still sample independently while every call
+// stays reproducible. Requests without a seed, or outside any scope, are forwarded unchanged.
+type LLM struct {
+	llm   model.LLM
+	scope func(context.Context) string
+}
+
+var _ model.LLM = (*LLM)(nil)
+
+// New returns a seed deriving [LLM] over llm.
+//
+// scope names the scope of a call's context, e.g. [github.com/zchee/tumix/agent.SeedScope].
+func New(llm model.LLM, scope func(context.Context) string) (*LLM, error) {
+	if llm == nil {
+		return nil, errors.New("seed: model is required")
+	}
+	if scope == nil {
+		return nil, errors.New("seed: scope function is required")
+	}
+	return &LLM{llm: llm, scope: scope}, nil
+}
+
+// Name implements [model.LLM].
+func (l *LLM) Name() string { return l.llm.Name() }
+
+// Capabilities implements [gollm.CapabilityReporter] with the capabilities of the wrapped model, or none when it does
+// not report them.
+func (l *LLM) Capabilities() gollm.Capabilities {
+	caps, _ := gollm.CapabilitiesOf(l.llm)
+	return caps
+}
+
+// GenerateContent implements [model.LLM].
+func (l *LLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if req != nil && req.Config != nil && req.Config.Seed != nil {
+		if scope := l.scope(ctx); scope != "" {
+			seed := Derive(*req.Config.Seed, scope)
+			config := *req.Config
+			config.Seed = &seed
+			seeded := *req
+			seeded.Config = &config
+			req = &seeded
+		}
+	}
+	return l.llm.GenerateContent(ctx, req, stream)
+}
+
+// Derive returns the non-negative seed derived from seed for scope. It only depends on its arguments.
+func Derive(seed int32, scope string) int32 {
+	h := fnv.New32a()
+	_ = binary.Write(h, binary.LittleEndian, seed)
+	_, _ = h.Write([]byte(scope))
+	return int32(h.Sum32() & math.MaxInt32) //nolint:gosec // masked to 31 bits
+}
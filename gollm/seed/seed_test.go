@@ -0,0 +1,150 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package seed
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/gollm"
+)
+
+type scopeKey struct{}
+
+func ctxScope(ctx context.Context) string {
+	scope, _ := ctx.Value(scopeKey{}).(string)
+	return scope
+}
+
+// recordingLLM records the seed of every request.
+type recordingLLM struct {
+	seeds []*int32
+}
+
+func (f *recordingLLM) Name() string { return "fake" }
+
+func (f *recordingLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var seed *int32
+		if req.Config != nil {
+			seed = req.Config.Seed
+		}
+		f.seeds = append(f.seeds, seed)
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("ok", genai.RoleModel)}, nil)
+	}
+}
+
+func TestGenerateContent(t *testing.T) {
+	t.Parallel()
+
+	seed, derived := int32(42), Derive(42, "A/1/0")
+	tests := map[string]struct {
+		scope  string
+		config *genai.GenerateContentConfig
+		want   *int32
+	}{
+		"scoped": {
+			scope:  "A/1/0",
+			config: &genai.GenerateContentConfig{Seed: &seed},
+			want:   &derived,
+		},
+		"no scope": {
+			config: &genai.GenerateContentConfig{Seed: &seed},
+			want:   &seed,
+		},
+		"no seed": {
+			scope:  "A/1/0",
+			config: &genai.GenerateContentConfig{},
+		},
+		"no config": {
+			scope: "A/1/0",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &recordingLLM{}
+			l, err := New(fake, ctxScope)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			req := &model.LLMRequest{Model: "m", Config: tt.config}
+			ctx := context.WithValue(t.Context(), scopeKey{}, tt.scope)
+			for _, err := range l.GenerateContent(ctx, req, false) {
+				if err != nil {
+					t.Fatalf("GenerateContent() error = %v", err)
+				}
+			}
+
+			got := fake.seeds[0]
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("request seed = %v, want %v", deref(got), deref(tt.want))
+			}
+			if tt.config != nil && tt.config.Seed != nil && *tt.config.Seed != 42 {
+				t.Fatalf("caller config seed changed to %d", *tt.config.Seed)
+			}
+		})
+	}
+}
+
+func deref(p *int32) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestDerive(t *testing.T) {
+	t.Parallel()
+
+	a := Derive(42, "A/1/0")
+	if a < 0 {
+		t.Fatalf("Derive() = %d, want non-negative", a)
+	}
+	if got := Derive(42, "A/1/0"); got != a {
+		t.Fatalf("Derive() = %d, then %d; want stable", a, got)
+	}
+	for _, other := range []int32{Derive(42, "B/1/0"), Derive(42, "A/2/0"), Derive(42, "A/1/1"), Derive(7, "A/1/0")} {
+		if other == a {
+			t.Fatalf("Derive() = %d for different inputs", a)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(nil, ctxScope); err == nil {
+		t.Fatal("New(nil model) error = nil")
+	}
+	if _, err := New(&recordingLLM{}, nil); err == nil {
+		t.Fatal("New(nil scope) error = nil")
+	}
+	l, err := New(&recordingLLM{}, ctxScope)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if caps := l.Capabilities(); caps != (gollm.Capabilities{}) {
+		t.Fatalf("Capabilities() = %+v, want none", caps)
+	}
+}
@@ -62,6 +62,9 @@ func NewXAILLM(_ context.Context, apiKey, modelName string, params *ProviderPara
 // Name implements [model.LLM].
 func (m *xaiLLM) Name() string { return m.name }
 
+// Capabilities implements [CapabilityReporter].
+func (m *xaiLLM) Capabilities() Capabilities { return ProviderCapabilities(ProviderXAI, m.name) }
+
 // GenerateContent implements [model.LLM].
 func (m *xaiLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
 	cfg := adapter.NormalizeRequest(req, m.userAgent)
@@ -0,0 +1,220 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	analyticspb "github.com/zchee/tumix/gollm/xai/shared/analytics"
+)
+
+// Analytics field names used by [BillingClient.UsageReport] by default.
+const (
+	UsageFieldModel            = "model_name"
+	UsageFieldPromptTokens     = "prompt_tokens"
+	UsageFieldCompletionTokens = "completion_tokens"
+	UsageFieldCost             = "cost_in_usd"
+)
+
+const analyticsTimeLayout = "2006-01-02 15:04:05"
+
+// UsageReportOption customizes a usage report request.
+type UsageReportOption func(*usageReportRequest)
+
+type usageReportRequest struct {
+	values   []string
+	location *time.Location
+	timeUnit analyticspb.TimeUnit
+	filters  []string
+}
+
+// WithUsageValues replaces the summed fields, which default to prompt tokens, completion tokens, and cost.
+func WithUsageValues(fields ...string) UsageReportOption {
+	return func(r *usageReportRequest) {
+		r.values = fields
+	}
+}
+
+// WithUsageTimezone buckets days in loc instead of UTC. loc must be an IANA zone such as America/New_York.
+func WithUsageTimezone(loc *time.Location) UsageReportOption {
+	return func(r *usageReportRequest) {
+		if loc != nil {
+			r.location = loc
+		}
+	}
+}
+
+// WithUsageTimeUnit changes the bucket size from one day, e.g. to [analyticspb.TimeUnit_TIME_UNIT_HOUR].
+func WithUsageTimeUnit(unit analyticspb.TimeUnit) UsageReportOption {
+	return func(r *usageReportRequest) {
+		r.timeUnit = unit
+	}
+}
+
+// WithUsageFilters restricts the report with analytics filter conditions such as "api_key_id:<id>".
+func WithUsageFilters(filters ...string) UsageReportOption {
+	return func(r *usageReportRequest) {
+		r.filters = append(r.filters, filters...)
+	}
+}
+
+func applyUsageReportOptions(opts []UsageReportOption) usageReportRequest {
+	r := usageReportRequest{
+		values:   []string{UsageFieldPromptTokens, UsageFieldCompletionTokens, UsageFieldCost},
+		location: time.UTC,
+		timeUnit: analyticspb.TimeUnit_TIME_UNIT_DAY,
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// UsageReport is API usage aggregated per time bucket and group.
+type UsageReport struct {
+	// GroupBy names the grouped fields, in the order of [UsageRow.Group].
+	GroupBy []string
+	// Values names the summed fields, in the order of [UsageRow.Values].
+	Values []string
+	// Rows holds one row per bucket and group with non-zero usage, in server order (by group, then time).
+	Rows []UsageRow
+	// LimitReached reports that the server truncated the result.
+	LimitReached bool
+
+	dates bool
+}
+
+// UsageRow is the usage of one group in one time bucket.
+type UsageRow struct {
+	Time   time.Time
+	Group  []string
+	Values []float64
+}
+
+// UsageReport returns the usage of teamID in [from, to), summed per day and per value of the groupBy fields
+// (for example [UsageFieldModel]).
+func (c *BillingClient) UsageReport(ctx context.Context, teamID string, from, to time.Time, groupBy []string, opts ...UsageReportOption) (*UsageReport, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("usage report: from %s is not before to %s", from, to)
+	}
+	r := applyUsageReportOptions(opts)
+	if len(r.values) == 0 {
+		return nil, errors.New("usage report: no value fields")
+	}
+
+	values := make([]*analyticspb.Value, len(r.values))
+	for i, name := range r.values {
+		values[i] = &analyticspb.Value{Name: name, Aggregation: analyticspb.Aggregation_AGGREGATION_SUM}
+	}
+	resp, err := c.AnalyzeBillingItems(ctx, teamID, &analyticspb.AnalyticsRequest{
+		TimeRange: &analyticspb.TimeRange{
+			StartTime: from.In(r.location).Format(analyticsTimeLayout),
+			EndTime:   to.In(r.location).Format(analyticsTimeLayout),
+			Timezone:  r.location.String(),
+		},
+		TimeUnit: r.timeUnit,
+		Values:   values,
+		GroupBy:  groupBy,
+		Filters:  r.filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newUsageReport(resp, groupBy, r.values, r.location, r.timeUnit), nil
+}
+
+func newUsageReport(resp *analyticspb.AnalyticsResponse, groupBy, values []string, loc *time.Location, unit analyticspb.TimeUnit) *UsageReport {
+	report := &UsageReport{
+		GroupBy:      slices.Clone(groupBy),
+		Values:       slices.Clone(values),
+		LimitReached: resp.GetLimitReached(),
+		dates:        unit >= analyticspb.TimeUnit_TIME_UNIT_MONTH && unit <= analyticspb.TimeUnit_TIME_UNIT_DAY,
+	}
+	for _, series := range resp.GetTimeSeries() {
+		for _, dp := range series.GetDataPoints() {
+			if !slices.ContainsFunc(dp.GetValues(), func(v float64) bool { return v != 0 }) {
+				continue
+			}
+			report.Rows = append(report.Rows, UsageRow{
+				Time:   dp.GetTimestamp().AsTime().In(loc),
+				Group:  series.GetGroup(),
+				Values: dp.GetValues(),
+			})
+		}
+	}
+	return report
+}
+
+// Totals sums every value field over all rows.
+func (r *UsageReport) Totals() []float64 {
+	totals := make([]float64, len(r.Values))
+	for _, row := range r.Rows {
+		for i, v := range row.Values {
+			if i < len(totals) {
+				totals[i] += v
+			}
+		}
+	}
+	return totals
+}
+
+// WriteCSV writes the report as CSV with a header of the bucket start, group fields, and value fields.
+//
+// Daily, weekly, and monthly buckets are written as dates, finer buckets as RFC 3339 timestamps.
+func (r *UsageReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := append(append([]string{"time"}, r.GroupBy...), r.Values...)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write usage header: %w", err)
+	}
+
+	for _, row := range r.Rows {
+		record := make([]string, 0, len(header))
+		if r.dates {
+			record = append(record, row.Time.Format(time.DateOnly))
+		} else {
+			record = append(record, row.Time.Format(time.RFC3339))
+		}
+		for i := range r.GroupBy {
+			var g string
+			if i < len(row.Group) {
+				g = row.Group[i]
+			}
+			record = append(record, g)
+		}
+		for _, v := range row.Values {
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write usage row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("flush usage csv: %w", err)
+	}
+	return nil
+}
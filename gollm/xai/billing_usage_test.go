@@ -0,0 +1,106 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	managementpb "github.com/zchee/tumix/gollm/xai/management_api/v1"
+	analyticspb "github.com/zchee/tumix/gollm/xai/shared/analytics"
+)
+
+type fakeUISvcClient struct {
+	managementpb.UISvcClient
+
+	req  *managementpb.AnalyzeBillingItemsRequest
+	resp *analyticspb.AnalyticsResponse
+}
+
+func (f *fakeUISvcClient) AnalyzeBillingItems(_ context.Context, in *managementpb.AnalyzeBillingItemsRequest, _ ...grpc.CallOption) (*analyticspb.AnalyticsResponse, error) {
+	f.req = in
+	return f.resp, nil
+}
+
+func TestBillingUsageReport(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) *timestamppb.Timestamp {
+		return timestamppb.New(time.Date(2025, time.March, d, 0, 0, 0, 0, time.UTC))
+	}
+	fake := &fakeUISvcClient{resp: &analyticspb.AnalyticsResponse{
+		TimeSeries: []*analyticspb.TimeSeries{
+			{Group: []string{"grok-4"}, DataPoints: []*analyticspb.DataPoint{
+				{Timestamp: day(1), Values: []float64{1000, 200, 0.5}},
+				{Timestamp: day(2), Values: []float64{0, 0, 0}},
+			}},
+			{Group: []string{"grok-3-mini"}, DataPoints: []*analyticspb.DataPoint{
+				{Timestamp: day(2), Values: []float64{300, 30, 0.01}},
+			}},
+		},
+	}}
+	billing := &BillingClient{uisvc: fake}
+
+	from := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	report, err := billing.UsageReport(t.Context(), "team-1", from, from.AddDate(0, 0, 2), []string{UsageFieldModel})
+	if err != nil {
+		t.Fatalf("UsageReport() error = %v", err)
+	}
+
+	wantReq := &managementpb.AnalyzeBillingItemsRequest{
+		TeamId: "team-1",
+		AnalyticsRequest: &analyticspb.AnalyticsRequest{
+			TimeRange: &analyticspb.TimeRange{StartTime: "2025-03-01 00:00:00", EndTime: "2025-03-03 00:00:00", Timezone: "UTC"},
+			TimeUnit:  analyticspb.TimeUnit_TIME_UNIT_DAY,
+			Values: []*analyticspb.Value{
+				{Name: UsageFieldPromptTokens, Aggregation: analyticspb.Aggregation_AGGREGATION_SUM},
+				{Name: UsageFieldCompletionTokens, Aggregation: analyticspb.Aggregation_AGGREGATION_SUM},
+				{Name: UsageFieldCost, Aggregation: analyticspb.Aggregation_AGGREGATION_SUM},
+			},
+			GroupBy: []string{UsageFieldModel},
+		},
+	}
+	if diff := cmp.Diff(wantReq, fake.req, protocmp.Transform()); diff != "" {
+		t.Fatalf("request mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]float64{1300, 230, 0.51}, report.Totals()); diff != "" {
+		t.Fatalf("Totals() mismatch (-want +got):\n%s", diff)
+	}
+
+	var sb strings.Builder
+	if err := report.WriteCSV(&sb); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "time,model_name,prompt_tokens,completion_tokens,cost_in_usd\n" +
+		"2025-03-01,grok-4,1000,200,0.5\n" +
+		"2025-03-02,grok-3-mini,300,30,0.01\n"
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Fatalf("WriteCSV() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := billing.UsageReport(t.Context(), "team-1", from, from, nil); err == nil {
+		t.Fatal("UsageReport() with empty range error = nil, want error")
+	}
+}
@@ -24,7 +24,10 @@ import (
 
 // ChatClient handles chat operations.
 type ChatClient struct {
-	chat xaipb.ChatClient
+	chat           xaipb.ChatClient
+	tokenize       xaipb.TokenizeClient
+	traceEncrypted bool
+	spanContent    SpanContentPolicy
 }
 
 // Create initializes a new chat session for the specified model.
@@ -33,8 +36,11 @@ func (c *ChatClient) Create(model string, opts ...ChatOption) *ChatSession {
 		Model: model,
 	}
 	session := &ChatSession{
-		chat:    c.chat,
-		request: req,
+		chat:           c.chat,
+		tokenize:       c.tokenize,
+		request:        req,
+		traceEncrypted: c.traceEncrypted,
+		spanContent:    c.spanContent,
 	}
 	for _, opt := range opts {
 		opt(req, session)
@@ -48,7 +54,8 @@ func (c *ChatClient) GetStoredCompletion(ctx context.Context, responseID string)
 	req := &xaipb.GetStoredCompletionRequest{
 		ResponseId: responseID,
 	}
-	return c.chat.GetStoredCompletion(ctx, req)
+	resp, err := c.chat.GetStoredCompletion(ctx, req)
+	return resp, WrapError(err)
 }
 
 // DeleteStoredCompletion deletes a stored response using the response ID.
@@ -57,12 +64,13 @@ func (c *ChatClient) DeleteStoredCompletion(ctx context.Context, responseID stri
 		ResponseId: responseID,
 	}
 	_, err := c.chat.DeleteStoredCompletion(ctx, req)
-	return err
+	return WrapError(err)
 }
 
 // StartDeferredCompletion starts sampling of the model and immediately returns a response containing a request id.
 func (c *ChatClient) StartDeferredCompletion(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.StartDeferredResponse, error) {
-	return c.chat.StartDeferredCompletion(ctx, req)
+	resp, err := c.chat.StartDeferredCompletion(ctx, req)
+	return resp, WrapError(err)
 }
 
 // GetDeferredCompletion gets the result of a deferred completion.
@@ -70,7 +78,8 @@ func (c *ChatClient) GetDeferredCompletion(ctx context.Context, requestID string
 	req := &xaipb.GetDeferredRequest{
 		RequestId: requestID,
 	}
-	return c.chat.GetDeferredCompletion(ctx, req)
+	resp, err := c.chat.GetDeferredCompletion(ctx, req)
+	return resp, WrapError(err)
 }
 
 // ParseInto is a generic convenience for structured outputs into type T.
@@ -124,6 +124,76 @@ func BenchmarkResponseProcessChunkHeavy(b *testing.B) {
 	})
 }
 
+// BenchmarkResponseStream aggregates a streamed completion the way [ChatStream.Recv] consumers read it, the
+// accumulated content after every chunk, and reports the throughput in streamed text.
+func BenchmarkResponseStream(b *testing.B) {
+	tests := map[string]struct {
+		chunks    int
+		deltaSize int
+	}{
+		"chunks=64/delta=16":    {chunks: 64, deltaSize: 16},
+		"chunks=1024/delta=16":  {chunks: 1024, deltaSize: 16},
+		"chunks=4096/delta=64":  {chunks: 4096, deltaSize: 64},
+		"chunks=256/delta=4096": {chunks: 256, deltaSize: 4096},
+	}
+
+	for name, tt := range tests {
+		b.Run(name, func(b *testing.B) {
+			delta := strings.Repeat("x", tt.deltaSize)
+			chunks := make([]*xaipb.GetChatCompletionChunk, tt.chunks)
+			for i := range chunks {
+				chunks[i] = &xaipb.GetChatCompletionChunk{
+					Outputs: []*xaipb.CompletionOutputChunk{{
+						Delta: &xaipb.Delta{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: delta},
+					}},
+				}
+			}
+
+			b.SetBytes(int64(tt.chunks * tt.deltaSize))
+			b.ReportAllocs()
+			for b.Loop() {
+				resp := newResponse(&xaipb.GetChatCompletionResponse{}, nil)
+				for _, chunk := range chunks {
+					resp.processChunk(chunk)
+					_ = resp.Content()
+				}
+				if got := len(resp.Content()); got != tt.chunks*tt.deltaSize {
+					b.Fatalf("len(Content()) = %d, want %d", got, tt.chunks*tt.deltaSize)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkChatSessionCompletionLongConversation completes a session whose history is long, which every request shares
+// rather than copies.
+func BenchmarkChatSessionCompletionLongConversation(b *testing.B) {
+	tests := map[string]struct {
+		messages int
+		size     int
+	}{
+		"messages=16/size=256":   {messages: 16, size: 256},
+		"messages=256/size=4096": {messages: 256, size: 4096},
+	}
+
+	for name, tt := range tests {
+		b.Run(name, func(b *testing.B) {
+			msgs := make([]*xaipb.Message, tt.messages)
+			for i := range msgs {
+				msgs[i] = User(strings.Repeat("x", tt.size))
+			}
+			s := (&ChatClient{chat: &historyLenChatClient{}}).Create("grok-4", WithMessages(msgs...))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := s.Completion(b.Context()); err != nil {
+					b.Fatalf("Completion() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkChunkAccessors(b *testing.B) {
 	chunk := heavyChunk(10, 2, strings.Repeat("content-", 6))
 	wrapped := newChunk(chunk, nil)
@@ -241,7 +311,7 @@ func BenchmarkSpanResponseAttributes(b *testing.B) {
 
 	b.ReportAllocs()
 	for b.Loop() {
-		_ = s.makeSpanResponseAttributes(responses)
+		_ = s.makeSpanResponseAttributes(responses, true)
 	}
 }
 
@@ -0,0 +1,305 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// ContextWindowStrategy selects how a [ChatSession] trims history that no longer fits the context window.
+type ContextWindowStrategy int
+
+const (
+	// DropOldest removes the oldest turns until the prompt fits MaxPromptTokens.
+	DropOldest ContextWindowStrategy = iota
+	// SlidingWindow keeps at most MaxTurns recent turns, then drops the oldest of those until the prompt fits.
+	SlidingWindow
+	// SummarizeOlder trims like SlidingWindow and replaces the removed turns with a summary written by SummaryModel.
+	SummarizeOlder
+)
+
+// String implements fmt.Stringer.
+func (s ContextWindowStrategy) String() string {
+	switch s {
+	case DropOldest:
+		return "drop-oldest"
+	case SlidingWindow:
+		return "sliding-window"
+	case SummarizeOlder:
+		return "summarize-older"
+	default:
+		return fmt.Sprintf("ContextWindowStrategy(%d)", int(s))
+	}
+}
+
+// ContextWindowPolicy configures automatic context-window management for a [ChatSession].
+//
+// A turn starts at a user message and holds every following assistant and tool message. System messages are never
+// trimmed and are sent first, and the latest turn is always kept.
+type ContextWindowPolicy struct {
+	Strategy ContextWindowStrategy
+
+	// MaxPromptTokens is the prompt token budget. Zero disables token-based trimming.
+	MaxPromptTokens int
+
+	// MaxTurns bounds the number of turns kept by SlidingWindow and SummarizeOlder. Zero means no bound.
+	MaxTurns int
+
+	// SummaryModel is the model that writes summaries for SummarizeOlder. Empty uses the session model.
+	SummaryModel string
+
+	// SummaryMaxTokens caps the summary length and is reserved from MaxPromptTokens.
+	// Zero uses a quarter of MaxPromptTokens, or 512 when MaxPromptTokens is zero.
+	SummaryMaxTokens int
+
+	// CountTokens counts the tokens of text for model. Nil uses the Tokenizer service, falling back to an estimate
+	// of four bytes per token when the session has no tokenizer.
+	CountTokens func(ctx context.Context, model, text string) (int, error)
+}
+
+// messageTokenOverhead approximates the role and separator tokens added to every message.
+const messageTokenOverhead = 4
+
+const summaryInstruction = `You compress chat transcripts. Summarize the conversation below so that it can replace the
+original messages: keep facts, decisions, numbers, names, open questions, and tool results that later turns may rely on.
+Write plain prose without preamble.`
+
+// WithContextWindowPolicy trims the conversation history according to policy before every completion, stream, and
+// deferred request.
+//
+// Trimming only affects the request sent to the model; [ChatSession.Messages] keeps the full history.
+func WithContextWindowPolicy(policy ContextWindowPolicy) ChatOption {
+	return func(_ *xaipb.GetCompletionsRequest, s *ChatSession) {
+		s.window = newContextWindow(policy)
+	}
+}
+
+func newContextWindow(policy ContextWindowPolicy) *contextWindow {
+	return &contextWindow{
+		policy: policy,
+		counts: make(map[*xaipb.Message]int),
+	}
+}
+
+// contextWindow holds the policy of a session with its token count and summary caches.
+type contextWindow struct {
+	policy ContextWindowPolicy
+
+	// mu serializes fits of concurrent requests, which share the caches below.
+	mu sync.Mutex
+
+	// counts caches token counts by message; session messages are never mutated once appended.
+	counts map[*xaipb.Message]int
+
+	// summary caches the summary of the first summarized messages of the history, ending with last.
+	summarized int
+	last       *xaipb.Message
+	summary    string
+}
+
+func (w *contextWindow) summaryTokens() int {
+	switch {
+	case w.policy.SummaryMaxTokens > 0:
+		return w.policy.SummaryMaxTokens
+	case w.policy.MaxPromptTokens > 0:
+		return w.policy.MaxPromptTokens / 4
+	default:
+		return 512
+	}
+}
+
+// fitContextWindow replaces the messages of req with the history trimmed by the session policy.
+func (s *ChatSession) fitContextWindow(ctx context.Context, req *xaipb.GetCompletionsRequest) error {
+	if s.window == nil {
+		return nil
+	}
+	// The messages of req are the session's own (see [ChatSession.cloneRequest]), which key the caches.
+	history := req.GetMessages()
+
+	s.window.mu.Lock()
+	defer s.window.mu.Unlock()
+	msgs, err := s.window.fit(ctx, s, history)
+	if err != nil {
+		return fmt.Errorf("apply %s context window policy: %w", s.window.policy.Strategy, err)
+	}
+	req.Messages = msgs
+	return nil
+}
+
+func (w *contextWindow) fit(ctx context.Context, s *ChatSession, msgs []*xaipb.Message) ([]*xaipb.Message, error) {
+	var (
+		pinned []*xaipb.Message
+		turns  [][]*xaipb.Message
+	)
+	for _, m := range msgs {
+		switch {
+		case m.GetRole() == xaipb.MessageRole_ROLE_SYSTEM:
+			pinned = append(pinned, m)
+		case m.GetRole() == xaipb.MessageRole_ROLE_USER || len(turns) == 0:
+			turns = append(turns, []*xaipb.Message{m})
+		default:
+			turns[len(turns)-1] = append(turns[len(turns)-1], m)
+		}
+	}
+
+	// keep is the index of the first turn sent verbatim.
+	keep := 0
+	if w.policy.Strategy != DropOldest && w.policy.MaxTurns > 0 {
+		keep = max(0, len(turns)-w.policy.MaxTurns)
+	}
+
+	if budget := w.policy.MaxPromptTokens; budget > 0 {
+		if w.policy.Strategy == SummarizeOlder {
+			budget -= w.summaryTokens()
+		}
+		used, err := w.count(ctx, s, pinned)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(turns) - 1; i >= keep; i-- {
+			n, err := w.count(ctx, s, turns[i])
+			if err != nil {
+				return nil, err
+			}
+			if used+n > budget && i < len(turns)-1 {
+				keep = i + 1
+				break
+			}
+			used += n
+		}
+	}
+
+	if keep == 0 {
+		return msgs, nil
+	}
+
+	out := make([]*xaipb.Message, 0, len(msgs))
+	out = append(out, pinned...)
+	if w.policy.Strategy == SummarizeOlder {
+		var dropped []*xaipb.Message
+		for _, turn := range turns[:keep] {
+			dropped = append(dropped, turn...)
+		}
+		summary, err := w.summarize(ctx, s, dropped)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, System("Summary of the earlier conversation:\n"+summary))
+	}
+	for _, turn := range turns[keep:] {
+		out = append(out, turn...)
+	}
+
+	return out, nil
+}
+
+// count returns the prompt tokens of msgs.
+func (w *contextWindow) count(ctx context.Context, s *ChatSession, msgs []*xaipb.Message) (int, error) {
+	total := 0
+	for _, m := range msgs {
+		n, ok := w.counts[m]
+		if !ok {
+			var err error
+			n, err = w.countText(ctx, s, messageText(m))
+			if err != nil {
+				return 0, err
+			}
+			n += messageTokenOverhead
+			w.counts[m] = n
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (w *contextWindow) countText(ctx context.Context, s *ChatSession, text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+
+	model := s.request.GetModel()
+	switch {
+	case w.policy.CountTokens != nil:
+		return w.policy.CountTokens(ctx, model, text)
+	case s.tokenize != nil:
+		resp, err := s.tokenize.TokenizeText(ctx, &xaipb.TokenizeTextRequest{Text: text, Model: model})
+		if err != nil {
+			return 0, fmt.Errorf("count tokens: %w", WrapError(err))
+		}
+		return len(resp.GetTokens()), nil
+	default:
+		return (len(text) + 3) / 4, nil
+	}
+}
+
+// summarize returns a summary of msgs, extending the cached summary when msgs continues the summarized prefix.
+func (w *contextWindow) summarize(ctx context.Context, s *ChatSession, msgs []*xaipb.Message) (string, error) {
+	var b strings.Builder
+	start := 0
+	if w.summarized > 0 && w.summarized <= len(msgs) && msgs[w.summarized-1] == w.last {
+		if w.summarized == len(msgs) {
+			return w.summary, nil
+		}
+		start = w.summarized
+		b.WriteString("Summary so far:\n")
+		b.WriteString(w.summary)
+		b.WriteString("\n\nLater messages:\n")
+	}
+	for _, m := range msgs[start:] {
+		role := strings.ToLower(strings.TrimPrefix(m.GetRole().String(), "ROLE_"))
+		fmt.Fprintf(&b, "%s: %s\n", role, messageText(m))
+	}
+
+	resp, err := s.chat.GetCompletion(ctx, &xaipb.GetCompletionsRequest{
+		Model:     cmp.Or(w.policy.SummaryModel, s.request.GetModel()),
+		Messages:  []*xaipb.Message{System(summaryInstruction), User(b.String())},
+		MaxTokens: ptr(int32(w.summaryTokens())),
+	})
+	if err != nil {
+		return "", fmt.Errorf("summarize history: %w", WrapError(err))
+	}
+	if len(resp.GetOutputs()) == 0 {
+		return "", errors.New("summarize history: empty response")
+	}
+
+	w.summarized, w.last = len(msgs), msgs[len(msgs)-1]
+	w.summary = strings.TrimSpace(resp.GetOutputs()[0].GetMessage().GetContent())
+	return w.summary, nil
+}
+
+// messageText returns the text a message contributes to the prompt, including tool calls.
+func messageText(m *xaipb.Message) string {
+	var parts []string
+	for _, c := range m.GetContent() {
+		if text := c.GetText(); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	for _, tc := range m.GetToolCalls() {
+		if fn := tc.GetFunction(); fn != nil {
+			parts = append(parts, fn.GetName()+"("+fn.GetArguments()+")")
+		}
+	}
+	return strings.Join(parts, "\n")
+}
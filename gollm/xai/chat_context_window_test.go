@@ -0,0 +1,171 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// recordingChatClient records completion requests and answers each with reply.
+type recordingChatClient struct {
+	xaipb.ChatClient
+
+	reply    string
+	requests []*xaipb.GetCompletionsRequest
+}
+
+func (c *recordingChatClient) GetCompletion(_ context.Context, in *xaipb.GetCompletionsRequest, _ ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	c.requests = append(c.requests, in)
+	return &xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: c.reply},
+		}},
+	}, nil
+}
+
+// wordCount counts one token per word so budgets in tests are easy to follow.
+func wordCount(_ context.Context, _, text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+func messageTexts(msgs []*xaipb.Message) []string {
+	out := make([]string, len(msgs))
+	for i, m := range msgs {
+		out[i] = messageText(m)
+	}
+	return out
+}
+
+func TestContextWindowPolicy(t *testing.T) {
+	t.Parallel()
+
+	// Every message costs its word count plus messageTokenOverhead (4): the system prompt 6, each turn 12.
+	history := []*xaipb.Message{
+		System("be brief"),
+		User("one two"), Assistant("three four"),
+		User("five six"), Assistant("seven eight"),
+		User("nine ten"), Assistant("eleven twelve"),
+		User("last question"),
+	}
+
+	tests := map[string]struct {
+		policy        ContextWindowPolicy
+		want          []string
+		wantSummaries int
+	}{
+		"fits": {
+			policy: ContextWindowPolicy{Strategy: DropOldest, MaxPromptTokens: 100},
+			want:   []string{"be brief", "one two", "three four", "five six", "seven eight", "nine ten", "eleven twelve", "last question"},
+		},
+		"drop oldest": {
+			policy: ContextWindowPolicy{Strategy: DropOldest, MaxPromptTokens: 30},
+			want:   []string{"be brief", "nine ten", "eleven twelve", "last question"},
+		},
+		"drop oldest keeps latest turn": {
+			policy: ContextWindowPolicy{Strategy: DropOldest, MaxPromptTokens: 1},
+			want:   []string{"be brief", "last question"},
+		},
+		"sliding window": {
+			policy: ContextWindowPolicy{Strategy: SlidingWindow, MaxTurns: 3},
+			want:   []string{"be brief", "five six", "seven eight", "nine ten", "eleven twelve", "last question"},
+		},
+		"sliding window with budget": {
+			policy: ContextWindowPolicy{Strategy: SlidingWindow, MaxTurns: 3, MaxPromptTokens: 20},
+			want:   []string{"be brief", "last question"},
+		},
+		"summarize older": {
+			policy: ContextWindowPolicy{Strategy: SummarizeOlder, MaxTurns: 2, SummaryModel: "grok-3-mini"},
+			want: []string{
+				"be brief",
+				"Summary of the earlier conversation:\nearlier: 1 to 8",
+				"nine ten", "eleven twelve", "last question",
+			},
+			wantSummaries: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			chat := &recordingChatClient{reply: "earlier: 1 to 8"}
+			s := &ChatSession{
+				chat:    chat,
+				request: &xaipb.GetCompletionsRequest{Model: "grok-4", Messages: history},
+			}
+			tt.policy.CountTokens = wordCount
+			WithContextWindowPolicy(tt.policy)(s.request, s)
+
+			for range 2 {
+				if _, err := s.Completion(t.Context()); err != nil {
+					t.Fatalf("Completion() error = %v", err)
+				}
+			}
+
+			sent := chat.requests[len(chat.requests)-1]
+			if diff := cmp.Diff(tt.want, messageTexts(sent.GetMessages())); diff != "" {
+				t.Fatalf("sent messages mismatch (-want +got):\n%s", diff)
+			}
+			if got := len(s.Messages()); got != len(history) {
+				t.Fatalf("len(Messages()) = %d, want full history %d", got, len(history))
+			}
+
+			// Two completions, plus one summary request that is cached across calls.
+			if got := len(chat.requests) - 2; got != tt.wantSummaries {
+				t.Fatalf("summary requests = %d, want %d", got, tt.wantSummaries)
+			}
+			if tt.wantSummaries > 0 && chat.requests[0].GetModel() != tt.policy.SummaryModel {
+				t.Fatalf("summary model = %q, want %q", chat.requests[0].GetModel(), tt.policy.SummaryModel)
+			}
+		})
+	}
+}
+
+func TestContextWindowSummaryExtends(t *testing.T) {
+	t.Parallel()
+
+	chat := &recordingChatClient{reply: "summary"}
+	s := &ChatSession{
+		chat:    chat,
+		request: &xaipb.GetCompletionsRequest{Model: "grok-4", Messages: []*xaipb.Message{User("a"), Assistant("b"), User("c")}},
+	}
+	WithContextWindowPolicy(ContextWindowPolicy{Strategy: SummarizeOlder, MaxTurns: 1, CountTokens: wordCount})(s.request, s)
+
+	if _, err := s.Completion(t.Context()); err != nil {
+		t.Fatalf("Completion() error = %v", err)
+	}
+	s.Append(Assistant("d")).Append(User("e"))
+	if _, err := s.Completion(t.Context()); err != nil {
+		t.Fatalf("Completion() error = %v", err)
+	}
+
+	// Requests: summary, completion, incremental summary, completion.
+	if len(chat.requests) != 4 {
+		t.Fatalf("requests = %d, want 4", len(chat.requests))
+	}
+	prompt := messageText(chat.requests[2].GetMessages()[1])
+	if !strings.HasPrefix(prompt, "Summary so far:\nsummary") || strings.Contains(prompt, "user: a") {
+		t.Fatalf("incremental summary prompt = %q, want previous summary and only new messages", prompt)
+	}
+}
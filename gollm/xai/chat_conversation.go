@@ -0,0 +1,223 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// RetentionPolicy bounds the stored responses a [Conversation] keeps on the server.
+//
+// The latest response is always kept so the conversation can continue.
+type RetentionPolicy struct {
+	// MaxAge deletes stored responses older than this; zero keeps them regardless of age.
+	MaxAge time.Duration
+	// MaxResponses deletes the oldest stored responses beyond this count; zero keeps every response.
+	MaxResponses int
+}
+
+// StoredResponse describes a response stored by a [Conversation].
+type StoredResponse struct {
+	// ID is the response ID.
+	ID string
+	// Model is the model that produced the response.
+	Model string
+	// Created is when the response was created.
+	Created time.Time
+}
+
+// Conversation chains chat turns through stored completions.
+//
+// Each turn sends only its new messages with the ID of the previous response, so the server replays the earlier
+// history instead of the client resending it. The API cannot list stored responses, so a Conversation tracks the
+// ones it stored and applies its [RetentionPolicy] to them.
+type Conversation struct {
+	client    *ChatClient
+	model     string
+	opts      []ChatOption
+	retention RetentionPolicy
+	now       func() time.Time
+
+	mu     sync.Mutex
+	stored []StoredResponse
+}
+
+// NewConversation starts a conversation with model. The options apply to every turn.
+func (c *ChatClient) NewConversation(model string, retention RetentionPolicy, opts ...ChatOption) *Conversation {
+	return &Conversation{
+		client:    c,
+		model:     model,
+		opts:      opts,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// ResumeConversation continues the conversation that produced the stored response responseID.
+func (c *ChatClient) ResumeConversation(ctx context.Context, responseID string, retention RetentionPolicy, opts ...ChatOption) (*Conversation, error) {
+	resp, err := c.GetStoredCompletion(ctx, responseID)
+	if err != nil {
+		return nil, fmt.Errorf("get stored completion %s: %w", responseID, err)
+	}
+
+	conv := c.NewConversation(resp.GetModel(), retention, opts...)
+	conv.stored = append(conv.stored, conv.storedResponse(resp))
+	return conv, nil
+}
+
+// SessionFromStored reconstructs a chat session that continues from the stored response responseID.
+//
+// The session uses the model of the stored response, stores its own response, and starts without messages: append the
+// next turn before sampling. The stored response is returned so its content can be inspected.
+func (c *ChatClient) SessionFromStored(ctx context.Context, responseID string, opts ...ChatOption) (*ChatSession, *Response, error) {
+	resp, err := c.GetStoredCompletion(ctx, responseID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get stored completion %s: %w", responseID, err)
+	}
+
+	sessionOpts := make([]ChatOption, 0, len(opts)+2)
+	sessionOpts = append(sessionOpts, opts...)
+	sessionOpts = append(sessionOpts, WithStoreMessages(true), WithPreviousResponse(responseID))
+	return c.Create(resp.GetModel(), sessionOpts...), newResponse(resp, nil), nil
+}
+
+// Session returns a chat session for the next turn, chained to the latest stored response.
+func (c *Conversation) Session(msgs ...*xaipb.Message) *ChatSession {
+	opts := make([]ChatOption, 0, len(c.opts)+3)
+	opts = append(opts, c.opts...)
+	opts = append(opts, WithStoreMessages(true), WithMessages(msgs...))
+	if id := c.LastResponseID(); id != "" {
+		opts = append(opts, WithPreviousResponse(id))
+	}
+	return c.client.Create(c.model, opts...)
+}
+
+// Send sends the next turn, records the stored response, and prunes stored responses that fall outside the
+// retention policy.
+//
+// A pruning failure is returned with the response, which is still recorded.
+func (c *Conversation) Send(ctx context.Context, msgs ...*xaipb.Message) (*Response, error) {
+	resp, err := c.Session(msgs...).Completion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Proto().GetId() == "" {
+		return resp, errors.New("conversation: stored response has no id")
+	}
+
+	c.mu.Lock()
+	c.stored = append(c.stored, c.storedResponse(resp.Proto()))
+	c.mu.Unlock()
+
+	if err := c.Prune(ctx); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// LastResponseID returns the ID of the latest stored response, or "" before the first turn.
+func (c *Conversation) LastResponseID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.stored) == 0 {
+		return ""
+	}
+	return c.stored[len(c.stored)-1].ID
+}
+
+// Stored returns the stored responses of the conversation, oldest first.
+func (c *Conversation) Stored() []StoredResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.stored)
+}
+
+// Prune deletes the stored responses that fall outside the retention policy.
+//
+// Responses that fail to delete are kept for the next call and their errors are joined.
+func (c *Conversation) Prune(ctx context.Context) error {
+	c.mu.Lock()
+	expired := c.expired()
+	c.mu.Unlock()
+
+	return c.delete(ctx, expired)
+}
+
+// Delete deletes every stored response and resets the conversation, so the next turn starts a new history.
+func (c *Conversation) Delete(ctx context.Context) error {
+	return c.delete(ctx, c.Stored())
+}
+
+// expired returns the stored responses outside the retention policy. The caller must hold c.mu.
+func (c *Conversation) expired() []StoredResponse {
+	if len(c.stored) <= 1 {
+		return nil
+	}
+
+	// The latest response is never expired.
+	older := c.stored[:len(c.stored)-1]
+	cut := 0
+	if c.retention.MaxResponses > 0 && len(c.stored) > c.retention.MaxResponses {
+		cut = len(c.stored) - c.retention.MaxResponses
+	}
+	if c.retention.MaxAge > 0 {
+		deadline := c.now().Add(-c.retention.MaxAge)
+		for cut < len(older) && older[cut].Created.Before(deadline) {
+			cut++
+		}
+	}
+	return slices.Clone(older[:min(cut, len(older))])
+}
+
+func (c *Conversation) delete(ctx context.Context, responses []StoredResponse) error {
+	var errs []error
+	deleted := make(map[string]bool, len(responses))
+	for _, r := range responses {
+		if err := c.client.DeleteStoredCompletion(ctx, r.ID); err != nil {
+			errs = append(errs, fmt.Errorf("delete stored completion %s: %w", r.ID, err))
+			continue
+		}
+		deleted[r.ID] = true
+	}
+
+	c.mu.Lock()
+	c.stored = slices.DeleteFunc(c.stored, func(r StoredResponse) bool { return deleted[r.ID] })
+	c.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+func (c *Conversation) storedResponse(resp *xaipb.GetChatCompletionResponse) StoredResponse {
+	created := c.now()
+	if ts := resp.GetCreated(); ts != nil {
+		created = ts.AsTime()
+	}
+	return StoredResponse{
+		ID:      resp.GetId(),
+		Model:   resp.GetModel(),
+		Created: created,
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// storingChatClient numbers each completion and keeps it as a stored response until deleted.
+type storingChatClient struct {
+	xaipb.ChatClient
+
+	clock      func() time.Time
+	requests   []*xaipb.GetCompletionsRequest
+	stored     map[string]*xaipb.GetChatCompletionResponse
+	failDelete map[string]bool
+}
+
+func newStoringChatClient(clock func() time.Time) *storingChatClient {
+	return &storingChatClient{
+		clock:      clock,
+		stored:     make(map[string]*xaipb.GetChatCompletionResponse),
+		failDelete: make(map[string]bool),
+	}
+}
+
+func (c *storingChatClient) GetCompletion(_ context.Context, in *xaipb.GetCompletionsRequest, _ ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	c.requests = append(c.requests, in)
+	resp := &xaipb.GetChatCompletionResponse{
+		Id:      fmt.Sprintf("resp-%d", len(c.requests)),
+		Model:   in.GetModel(),
+		Created: timestamppb.New(c.clock()),
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: fmt.Sprintf("reply %d", len(c.requests))},
+		}},
+	}
+	if in.GetStoreMessages() {
+		c.stored[resp.GetId()] = resp
+	}
+	return resp, nil
+}
+
+func (c *storingChatClient) GetStoredCompletion(_ context.Context, in *xaipb.GetStoredCompletionRequest, _ ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	resp, ok := c.stored[in.GetResponseId()]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return resp, nil
+}
+
+func (c *storingChatClient) DeleteStoredCompletion(_ context.Context, in *xaipb.DeleteStoredCompletionRequest, _ ...grpc.CallOption) (*xaipb.DeleteStoredCompletionResponse, error) {
+	if c.failDelete[in.GetResponseId()] {
+		return nil, errors.New("delete failed")
+	}
+	delete(c.stored, in.GetResponseId())
+	return &xaipb.DeleteStoredCompletionResponse{}, nil
+}
+
+func storedIDs(stored []StoredResponse) []string {
+	ids := make([]string, len(stored))
+	for i, r := range stored {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestConversationChainsTurns(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	chat := newStoringChatClient(func() time.Time { return now })
+	conv := (&ChatClient{chat: chat}).NewConversation("grok-4", RetentionPolicy{}, WithTemperature(0.5))
+
+	for i, text := range []string{"hi", "and then?"} {
+		resp, err := conv.Send(t.Context(), User(text))
+		if err != nil {
+			t.Fatalf("Send(%q) error = %v", text, err)
+		}
+		if want := fmt.Sprintf("reply %d", i+1); resp.Content() != want {
+			t.Fatalf("Send(%q) content = %q, want %q", text, resp.Content(), want)
+		}
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(chat.requests))
+	}
+	first, second := chat.requests[0], chat.requests[1]
+	if first.PreviousResponseId != nil {
+		t.Fatalf("first turn previous response = %q, want none", first.GetPreviousResponseId())
+	}
+	if got := second.GetPreviousResponseId(); got != "resp-1" {
+		t.Fatalf("second turn previous response = %q, want resp-1", got)
+	}
+	for i, req := range chat.requests {
+		if !req.GetStoreMessages() || req.GetTemperature() != 0.5 || len(req.GetMessages()) != 1 {
+			t.Fatalf("request %d = %v, want stored single-message turn with shared options", i, req)
+		}
+	}
+
+	want := []StoredResponse{
+		{ID: "resp-1", Model: "grok-4", Created: now},
+		{ID: "resp-2", Model: "grok-4", Created: now},
+	}
+	if diff := cmp.Diff(want, conv.Stored()); diff != "" {
+		t.Fatalf("Stored() mismatch (-want +got):\n%s", diff)
+	}
+	if got := conv.LastResponseID(); got != "resp-2" {
+		t.Fatalf("LastResponseID() = %q, want resp-2", got)
+	}
+
+	if err := conv.Delete(t.Context()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if len(chat.stored) != 0 || len(conv.Stored()) != 0 || conv.LastResponseID() != "" {
+		t.Fatalf("after Delete() server = %v, conversation = %v, want both empty", chat.stored, conv.Stored())
+	}
+}
+
+func TestConversationRetention(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		retention  RetentionPolicy
+		failDelete string
+		want       []string
+		wantErr    bool
+	}{
+		"keep all": {
+			want: []string{"resp-1", "resp-2", "resp-3", "resp-4"},
+		},
+		"max responses": {
+			retention: RetentionPolicy{MaxResponses: 2},
+			want:      []string{"resp-3", "resp-4"},
+		},
+		"max age": {
+			retention: RetentionPolicy{MaxAge: 90 * time.Minute},
+			want:      []string{"resp-3", "resp-4"},
+		},
+		"latest kept": {
+			retention: RetentionPolicy{MaxAge: time.Nanosecond, MaxResponses: 1},
+			want:      []string{"resp-4"},
+		},
+		"delete failure": {
+			retention:  RetentionPolicy{MaxResponses: 1},
+			failDelete: "resp-1",
+			want:       []string{"resp-1", "resp-4"},
+			wantErr:    true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Turns are an hour apart.
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			clock := func() time.Time { return now }
+			chat := newStoringChatClient(clock)
+			chat.failDelete[tt.failDelete] = true
+			conv := (&ChatClient{chat: chat}).NewConversation("grok-4", tt.retention)
+			conv.now = clock
+
+			var err error
+			for i := range 4 {
+				if i > 0 {
+					now = now.Add(time.Hour)
+				}
+				if _, sendErr := conv.Send(t.Context(), User("turn")); sendErr != nil {
+					err = sendErr
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, storedIDs(conv.Stored())); diff != "" {
+				t.Fatalf("Stored() mismatch (-want +got):\n%s", diff)
+			}
+			if len(chat.stored) != len(tt.want) {
+				t.Fatalf("server stored %d responses, want %d", len(chat.stored), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSessionFromStored(t *testing.T) {
+	t.Parallel()
+
+	chat := newStoringChatClient(time.Now)
+	client := &ChatClient{chat: chat}
+	if _, err := client.Create("grok-3", WithMessages(User("hi")), WithStoreMessages(true)).Completion(t.Context()); err != nil {
+		t.Fatalf("Completion() error = %v", err)
+	}
+
+	session, stored, err := client.SessionFromStored(t.Context(), "resp-1", WithMaxTokens(16))
+	if err != nil {
+		t.Fatalf("SessionFromStored() error = %v", err)
+	}
+	if stored.Content() != "reply 1" {
+		t.Fatalf("stored content = %q, want reply 1", stored.Content())
+	}
+	if _, err := session.Append(User("more")).Completion(t.Context()); err != nil {
+		t.Fatalf("Completion() error = %v", err)
+	}
+	req := chat.requests[1]
+	if req.GetModel() != "grok-3" || req.GetPreviousResponseId() != "resp-1" || !req.GetStoreMessages() || req.GetMaxTokens() != 16 {
+		t.Fatalf("resumed request = %v, want grok-3 chained to resp-1 with options", req)
+	}
+
+	conv, err := client.ResumeConversation(t.Context(), "resp-2", RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("ResumeConversation() error = %v", err)
+	}
+	if got := conv.LastResponseID(); got != "resp-2" {
+		t.Fatalf("LastResponseID() = %q, want resp-2", got)
+	}
+	if _, err := conv.Send(t.Context(), User("again")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := chat.requests[2].GetPreviousResponseId(); got != "resp-2" {
+		t.Fatalf("resumed conversation previous response = %q, want resp-2", got)
+	}
+
+	if _, _, err := client.SessionFromStored(t.Context(), "missing"); err == nil {
+		t.Fatal("SessionFromStored(missing) error = nil, want error")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// SampleTarget is one model configuration sampled by [ChatClient.SampleAcrossTargets].
+type SampleTarget struct {
+	// Model is the model name.
+	Model string
+	// Options are applied after the shared options, so they can override them, e.g. with [WithReasoningEffort].
+	Options []ChatOption
+}
+
+// SampleResult is the outcome of sampling one [SampleTarget].
+type SampleResult struct {
+	// Target is the sampled target.
+	Target SampleTarget
+	// Response is the completion, or nil when Err is set.
+	Response *Response
+	// Latency is the wall time of the call.
+	Latency time.Duration
+	// Usage is the token usage of the call, if reported.
+	Usage *xaipb.SamplingUsage
+	// Err is the error of the call.
+	Err error
+}
+
+// SampleAcross sends the same conversation to every model concurrently and returns one result per model, in order.
+//
+// It is [ChatClient.SampleAcrossTargets] with one target per model.
+func (c *ChatClient) SampleAcross(ctx context.Context, models []string, opts ...ChatOption) ([]SampleResult, error) {
+	targets := make([]SampleTarget, len(models))
+	for i, model := range models {
+		targets[i] = SampleTarget{Model: model}
+	}
+	return c.SampleAcrossTargets(ctx, targets, opts...)
+}
+
+// SampleAcrossTargets sends the same conversation, built from opts, to every target concurrently and returns one
+// result per target, in order, with its latency and usage.
+//
+// Targets can repeat a model with different options, e.g. several reasoning efforts, which makes the results a cheap
+// source of diverse answers for ensembling. A failing target records its error in its result; the returned error is
+// non-nil only when every target failed, and joins their errors.
+func (c *ChatClient) SampleAcrossTargets(ctx context.Context, targets []SampleTarget, opts ...ChatOption) ([]SampleResult, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("sample across: no targets")
+	}
+
+	results := make([]SampleResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Go(func() {
+			sessionOpts := make([]ChatOption, 0, len(opts)+len(target.Options))
+			sessionOpts = append(sessionOpts, opts...)
+			sessionOpts = append(sessionOpts, target.Options...)
+
+			start := time.Now()
+			resp, err := c.Create(target.Model, sessionOpts...).Completion(ctx)
+			results[i] = SampleResult{Target: target, Latency: time.Since(start)}
+			if err != nil {
+				results[i].Err = fmt.Errorf("sample %s: %w", target.Model, err)
+				return
+			}
+			results[i].Response = resp
+			results[i].Usage = resp.Usage()
+		})
+	}
+	wg.Wait()
+
+	errs := make([]error, 0, len(results))
+	for _, r := range results {
+		if r.Err == nil {
+			return results, nil
+		}
+		errs = append(errs, r.Err)
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// modelEchoChatClient answers with the requested model and reasoning effort, failing for the models in fail.
+type modelEchoChatClient struct {
+	xaipb.ChatClient
+
+	fail map[string]bool
+}
+
+func (c *modelEchoChatClient) GetCompletion(_ context.Context, in *xaipb.GetCompletionsRequest, _ ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	if c.fail[in.GetModel()] {
+		return nil, errors.New("unavailable")
+	}
+	reply := in.GetModel()
+	if in.ReasoningEffort != nil {
+		reply += ":" + in.GetReasoningEffort().String()
+	}
+	return &xaipb.GetChatCompletionResponse{
+		Model: in.GetModel(),
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: reply},
+		}},
+		Usage: &xaipb.SamplingUsage{PromptTokens: int32(len(in.GetMessages())), CompletionTokens: 1},
+	}, nil
+}
+
+func TestSampleAcross(t *testing.T) {
+	t.Parallel()
+
+	c := &ChatClient{chat: &modelEchoChatClient{fail: map[string]bool{"grok-bad": true}}}
+	results, err := c.SampleAcross(t.Context(), []string{"grok-4", "grok-bad", "grok-3-mini"}, WithMessages(System("be brief"), User("hi")))
+	if err != nil {
+		t.Fatalf("SampleAcross() error = %v", err)
+	}
+
+	var contents []string
+	for _, r := range results {
+		if r.Response == nil {
+			contents = append(contents, "error: "+r.Err.Error())
+			continue
+		}
+		contents = append(contents, r.Response.Content())
+		if r.Usage.GetPromptTokens() != 2 {
+			t.Fatalf("%s usage = %v, want the shared conversation of 2 messages", r.Target.Model, r.Usage)
+		}
+		if r.Latency <= 0 {
+			t.Fatalf("%s latency = %v, want positive", r.Target.Model, r.Latency)
+		}
+	}
+	want := []string{"grok-4", "error: sample grok-bad: unavailable", "grok-3-mini"}
+	if diff := cmp.Diff(want, contents); diff != "" {
+		t.Fatalf("SampleAcross() contents mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSampleAcrossTargets(t *testing.T) {
+	t.Parallel()
+
+	c := &ChatClient{chat: &modelEchoChatClient{}}
+	targets := []SampleTarget{
+		{Model: "grok-3-mini", Options: []ChatOption{WithReasoningEffort(xaipb.ReasoningEffort_EFFORT_LOW)}},
+		{Model: "grok-3-mini", Options: []ChatOption{WithReasoningEffort(xaipb.ReasoningEffort_EFFORT_HIGH)}},
+	}
+	results, err := c.SampleAcrossTargets(t.Context(), targets, WithMessages(User("hi")), WithReasoningEffort(xaipb.ReasoningEffort_EFFORT_MEDIUM))
+	if err != nil {
+		t.Fatalf("SampleAcrossTargets() error = %v", err)
+	}
+
+	got := make([]string, len(results))
+	for i, r := range results {
+		got[i] = r.Response.Content()
+	}
+	want := []string{"grok-3-mini:EFFORT_LOW", "grok-3-mini:EFFORT_HIGH"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("SampleAcrossTargets() contents mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSampleAcrossErrors(t *testing.T) {
+	t.Parallel()
+
+	c := &ChatClient{chat: &modelEchoChatClient{fail: map[string]bool{"a": true, "b": true}}}
+	if _, err := c.SampleAcross(t.Context(), nil); err == nil {
+		t.Fatal("SampleAcross(no models) error = nil, want error")
+	}
+	results, err := c.SampleAcross(t.Context(), []string{"a", "b"}, WithMessages(User("hi")))
+	if err == nil {
+		t.Fatal("SampleAcross() with every model failing error = nil, want error")
+	}
+	if len(results) != 2 || results[0].Err == nil || results[1].Err == nil {
+		t.Fatalf("SampleAcross() results = %+v, want two failed results", results)
+	}
+}
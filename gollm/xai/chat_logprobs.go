@@ -0,0 +1,80 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"math"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// LogprobStats summarizes the token log probabilities of a sampled sequence.
+type LogprobStats struct {
+	// Tokens is the number of tokens with a log probability.
+	Tokens int
+	// MeanLogprob is the mean token log probability, or zero without tokens.
+	MeanLogprob float64
+}
+
+// NewLogprobStats summarizes logprobs.
+func NewLogprobStats(logprobs []*xaipb.LogProb) LogprobStats {
+	var stats LogprobStats
+	var sum float64
+	for _, lp := range logprobs {
+		if lp == nil {
+			continue
+		}
+		sum += float64(lp.GetLogprob())
+		stats.Tokens++
+	}
+	if stats.Tokens > 0 {
+		stats.MeanLogprob = sum / float64(stats.Tokens)
+	}
+	return stats
+}
+
+// Confidence returns the length-normalized sequence confidence, the geometric mean of the token probabilities,
+// between 0 and 1. It is zero without tokens.
+func (s LogprobStats) Confidence() float64 {
+	if s.Tokens == 0 {
+		return 0
+	}
+	return math.Exp(s.MeanLogprob)
+}
+
+// Perplexity returns the perplexity of the sequence, the inverse of [LogprobStats.Confidence]. It is zero without
+// tokens.
+func (s LogprobStats) Perplexity() float64 {
+	if s.Tokens == 0 {
+		return 0
+	}
+	return math.Exp(-s.MeanLogprob)
+}
+
+// Logprobs returns the log probabilities of the sampled tokens, which are only present when requested with
+// [WithLogprobs].
+func (r *Response) Logprobs() []*xaipb.LogProb {
+	if out := r.outputNoFlush(); out != nil {
+		return out.GetLogprobs().GetContent()
+	}
+	return nil
+}
+
+// LogprobStats summarizes the log probabilities of the sampled tokens.
+func (r *Response) LogprobStats() LogprobStats {
+	return NewLogprobStats(r.Logprobs())
+}
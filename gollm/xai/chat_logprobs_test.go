@@ -0,0 +1,102 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"math"
+	"testing"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func TestLogprobStats(t *testing.T) {
+	t.Parallel()
+
+	half := float32(math.Log(0.5))
+	tests := map[string]struct {
+		logprobs       []*xaipb.LogProb
+		wantTokens     int
+		wantConfidence float64
+		wantPerplexity float64
+	}{
+		"none": {},
+		"certain": {
+			logprobs:       []*xaipb.LogProb{{Token: "a"}, {Token: "b"}},
+			wantTokens:     2,
+			wantConfidence: 1,
+			wantPerplexity: 1,
+		},
+		"coin flips": {
+			logprobs:       []*xaipb.LogProb{{Token: "a", Logprob: half}, nil, {Token: "b", Logprob: half}},
+			wantTokens:     2,
+			wantConfidence: 0.5,
+			wantPerplexity: 2,
+		},
+		"mixed": {
+			logprobs:       []*xaipb.LogProb{{Token: "a"}, {Token: "b", Logprob: 2 * half}},
+			wantTokens:     2,
+			wantConfidence: 0.5,
+			wantPerplexity: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stats := NewLogprobStats(tt.logprobs)
+			if stats.Tokens != tt.wantTokens {
+				t.Fatalf("Tokens = %d, want %d", stats.Tokens, tt.wantTokens)
+			}
+			if got := stats.Confidence(); math.Abs(got-tt.wantConfidence) > 1e-6 {
+				t.Fatalf("Confidence() = %v, want %v", got, tt.wantConfidence)
+			}
+			if got := stats.Perplexity(); math.Abs(got-tt.wantPerplexity) > 1e-6 {
+				t.Fatalf("Perplexity() = %v, want %v", got, tt.wantPerplexity)
+			}
+		})
+	}
+}
+
+func TestResponseLogprobs(t *testing.T) {
+	t.Parallel()
+
+	resp := newResponse(&xaipb.GetChatCompletionResponse{}, nil)
+	for _, chunk := range []*xaipb.GetChatCompletionChunk{
+		{Outputs: []*xaipb.CompletionOutputChunk{{
+			Delta:    &xaipb.Delta{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "he"},
+			Logprobs: &xaipb.LogProbs{Content: []*xaipb.LogProb{{Token: "he", Logprob: -0.5}}},
+		}}},
+		{Outputs: []*xaipb.CompletionOutputChunk{{
+			Delta:    &xaipb.Delta{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "llo"},
+			Logprobs: &xaipb.LogProbs{Content: []*xaipb.LogProb{{Token: "llo", Logprob: -1.5}}},
+		}}},
+	} {
+		resp.processChunk(chunk)
+	}
+
+	if got := len(resp.Logprobs()); got != 2 {
+		t.Fatalf("Logprobs() = %d tokens, want 2", got)
+	}
+	stats := resp.LogprobStats()
+	if stats.Tokens != 2 || stats.MeanLogprob != -1 {
+		t.Fatalf("LogprobStats() = %+v, want 2 tokens with mean -1", stats)
+	}
+	if got := newResponse(&xaipb.GetChatCompletionResponse{}, nil).Logprobs(); got != nil {
+		t.Fatalf("Logprobs() without output = %v, want nil", got)
+	}
+}
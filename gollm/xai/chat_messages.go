@@ -17,9 +17,25 @@
 package xai
 
 import (
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
+// ToolCallIDKey is the member of a tool result message content that names the tool call it answers.
+//
+// The chat proto has no tool call ID on ROLE_TOOL messages, so [ToolCallResult] carries it in the content as the JSON
+// object {"tool_call_id":"call_1","result":...}, with the result as JSON if it is valid JSON and as a string otherwise,
+// and [ParseToolResult] reads it back.
+const ToolCallIDKey = "tool_call_id"
+
+// toolResultContent is the content of a tool result message with a tool call ID.
+type toolResultContent struct {
+	ToolCallID string         `json:"tool_call_id"`
+	Result     jsontext.Value `json:"result"`
+}
+
 // User creates a user message with text or content parts.
 func User(parts ...any) *xaipb.Message {
 	return newMessage(xaipb.MessageRole_ROLE_USER, parts...)
@@ -35,11 +51,46 @@ func Assistant(parts ...any) *xaipb.Message {
 	return newMessage(xaipb.MessageRole_ROLE_ASSISTANT, parts...)
 }
 
-// ToolResult creates a tool result message.
+// ToolResult creates a tool result message. Use [ToolCallResult] to tell the model which tool call it answers.
 func ToolResult(result string) *xaipb.Message {
 	return newMessage(xaipb.MessageRole_ROLE_TOOL, result)
 }
 
+// ToolCallResult creates a tool result message answering the tool call with toolCallID, which is carried in the
+// content as described at [ToolCallIDKey]. An empty toolCallID gives the plain [ToolResult].
+func ToolCallResult(toolCallID, result string) *xaipb.Message {
+	if toolCallID == "" {
+		return ToolResult(result)
+	}
+	content := toolResultContent{ToolCallID: toolCallID, Result: jsontext.Value(result)}
+	if !content.Result.IsValid() {
+		content.Result, _ = jsontext.AppendQuote(nil, result)
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		// Unreachable: the ID is a string and the result is valid JSON.
+		panic(err)
+	}
+	return ToolResult(string(b))
+}
+
+// ParseToolResult returns the tool call ID and the result of a tool result message made by [ToolCallResult], with a
+// JSON string result unquoted. For any other message it returns an empty ID and the text of msg.
+func ParseToolResult(msg *xaipb.Message) (toolCallID, result string) {
+	text := messageText(msg)
+	var content toolResultContent
+	if err := json.Unmarshal([]byte(text), &content, json.RejectUnknownMembers(true)); err != nil || content.ToolCallID == "" || content.Result == nil {
+		return "", text
+	}
+	if content.Result.Kind() == '"' {
+		var s string
+		if err := json.Unmarshal(content.Result, &s); err == nil {
+			return content.ToolCallID, s
+		}
+	}
+	return content.ToolCallID, string(content.Result)
+}
+
 func newMessage(role xaipb.MessageRole, parts ...any) *xaipb.Message {
 	contents := make([]*xaipb.Content, 0, len(parts))
 	for _, part := range parts {
@@ -0,0 +1,102 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"testing"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func TestToolCallResult(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		id, result string
+		wantText   string
+		wantResult string
+	}{
+		"json result": {
+			id:         "call_1",
+			result:     `{"city":"Tokyo","celsius":21.5}`,
+			wantText:   `{"tool_call_id":"call_1","result":{"city":"Tokyo","celsius":21.5}}`,
+			wantResult: `{"city":"Tokyo","celsius":21.5}`,
+		},
+		"text result": {
+			id:         "call_2",
+			result:     `it is "sunny"`,
+			wantText:   `{"tool_call_id":"call_2","result":"it is \"sunny\""}`,
+			wantResult: `it is "sunny"`,
+		},
+		"empty result": {
+			id:         "call_3",
+			wantText:   `{"tool_call_id":"call_3","result":""}`,
+			wantResult: "",
+		},
+		"no id": {
+			result:     `{"city":"Tokyo"}`,
+			wantText:   `{"city":"Tokyo"}`,
+			wantResult: `{"city":"Tokyo"}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			msg := ToolCallResult(tt.id, tt.result)
+			if msg.GetRole() != xaipb.MessageRole_ROLE_TOOL || messageText(msg) != tt.wantText {
+				t.Fatalf("ToolCallResult() = %v %q, want tool message %q", msg.GetRole(), messageText(msg), tt.wantText)
+			}
+			id, result := ParseToolResult(msg)
+			if id != tt.id || result != tt.wantResult {
+				t.Fatalf("ParseToolResult() = (%q, %q), want (%q, %q)", id, result, tt.id, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestParseToolResultOtherContent(t *testing.T) {
+	t.Parallel()
+
+	for _, text := range []string{
+		"plain text",
+		`{"tool_call_id":"call_1"}`,
+		`{"tool_call_id":"call_1","result":1,"extra":true}`,
+		`{"name":"get_weather","tool_call_id":"call_1","response":{}}`,
+	} {
+		if id, result := ParseToolResult(ToolResult(text)); id != "" || result != text {
+			t.Fatalf("ParseToolResult(%q) = (%q, %q), want no ID and the text", text, id, result)
+		}
+	}
+}
+
+func TestAppendToolResultJSON(t *testing.T) {
+	t.Parallel()
+
+	s := chatSessionForTest()
+	s.AppendToolResultJSON("call_1", map[string]any{"celsius": 21.5})
+	s.AppendToolResultJSON("", "done")
+
+	msgs := s.Messages()
+	if id, result := ParseToolResult(msgs[0]); id != "call_1" || result != `{"celsius":21.5}` {
+		t.Fatalf("first tool result = (%q, %q), want (call_1, {\"celsius\":21.5})", id, result)
+	}
+	if got := messageText(msgs[1]); got != "done" {
+		t.Fatalf("second tool result = %q, want done", got)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// CompletionHandler sends a completion request and returns its response.
+type CompletionHandler func(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.GetChatCompletionResponse, error)
+
+// CompletionStreamHandler starts a streaming completion.
+type CompletionStreamHandler func(ctx context.Context, req *xaipb.GetCompletionsRequest) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error)
+
+// DeferredCompletionHandler starts a deferred completion.
+type DeferredCompletionHandler func(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.StartDeferredResponse, error)
+
+// ChatMiddleware hooks into every chat completion sent by a [Client], in the manner of an HTTP round-tripper.
+//
+// Each hook receives the request and the next handler of the chain. It may mutate the request or the context
+// (for example to add outgoing gRPC metadata), reject the call by returning an error without calling next, and
+// inspect or replace the response. Nil hooks pass the call through.
+//
+// A request of a [ChatSession] shares its messages, tools, and other nested values with the session: a hook may append
+// to its repeated fields or replace their elements, but must not modify the shared values in place.
+type ChatMiddleware struct {
+	// Completion wraps unary completions, used by Completion, CompletionBatch, and Parse.
+	Completion func(ctx context.Context, req *xaipb.GetCompletionsRequest, next CompletionHandler) (*xaipb.GetChatCompletionResponse, error)
+
+	// Stream wraps streaming completions, used by Stream and StreamBatch. Wrap the returned stream to observe chunks.
+	Stream func(ctx context.Context, req *xaipb.GetCompletionsRequest, next CompletionStreamHandler) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error)
+
+	// Deferred wraps the start of deferred completions, used by Defer and DeferBatch.
+	Deferred func(ctx context.Context, req *xaipb.GetCompletionsRequest, next DeferredCompletionHandler) (*xaipb.StartDeferredResponse, error)
+}
+
+// WithChatMiddleware appends middleware to the chat completion chain.
+//
+// Middleware runs in registration order: the first registered is the outermost and sees the request first and the
+// response last.
+func WithChatMiddleware(mw ...ChatMiddleware) ClientOption {
+	return func(o *clientOptions) {
+		o.chatMiddleware = append(o.chatMiddleware, mw...)
+	}
+}
+
+// middlewareChatClient runs completion calls through a middleware chain.
+type middlewareChatClient struct {
+	xaipb.ChatClient
+
+	completion CompletionHandler
+	stream     CompletionStreamHandler
+	deferred   DeferredCompletionHandler
+}
+
+var _ xaipb.ChatClient = (*middlewareChatClient)(nil)
+
+// chainChatMiddleware wraps chat with mw, returning chat unchanged when mw is empty.
+func chainChatMiddleware(chat xaipb.ChatClient, mw []ChatMiddleware) xaipb.ChatClient {
+	if len(mw) == 0 {
+		return chat
+	}
+
+	c := &middlewareChatClient{ChatClient: chat}
+	// Innermost handlers call the API; the caller's options are only known per call and are carried in the context.
+	c.completion = func(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.GetChatCompletionResponse, error) {
+		return chat.GetCompletion(ctx, req, callOptionsFromContext(ctx)...)
+	}
+	c.stream = func(ctx context.Context, req *xaipb.GetCompletionsRequest) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error) {
+		return chat.GetCompletionChunk(ctx, req, callOptionsFromContext(ctx)...)
+	}
+	c.deferred = func(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.StartDeferredResponse, error) {
+		return chat.StartDeferredCompletion(ctx, req, callOptionsFromContext(ctx)...)
+	}
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		m := mw[i]
+		if m.Completion != nil {
+			next := c.completion
+			c.completion = func(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.GetChatCompletionResponse, error) {
+				return m.Completion(ctx, req, next)
+			}
+		}
+		if m.Stream != nil {
+			next := c.stream
+			c.stream = func(ctx context.Context, req *xaipb.GetCompletionsRequest) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error) {
+				return m.Stream(ctx, req, next)
+			}
+		}
+		if m.Deferred != nil {
+			next := c.deferred
+			c.deferred = func(ctx context.Context, req *xaipb.GetCompletionsRequest) (*xaipb.StartDeferredResponse, error) {
+				return m.Deferred(ctx, req, next)
+			}
+		}
+	}
+
+	return c
+}
+
+// GetCompletion implements [xaipb.ChatClient].
+func (c *middlewareChatClient) GetCompletion(ctx context.Context, in *xaipb.GetCompletionsRequest, opts ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	return c.completion(withCallOptions(ctx, opts), in)
+}
+
+// GetCompletionChunk implements [xaipb.ChatClient].
+func (c *middlewareChatClient) GetCompletionChunk(ctx context.Context, in *xaipb.GetCompletionsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error) {
+	return c.stream(withCallOptions(ctx, opts), in)
+}
+
+// StartDeferredCompletion implements [xaipb.ChatClient].
+func (c *middlewareChatClient) StartDeferredCompletion(ctx context.Context, in *xaipb.GetCompletionsRequest, opts ...grpc.CallOption) (*xaipb.StartDeferredResponse, error) {
+	return c.deferred(withCallOptions(ctx, opts), in)
+}
+
+type callOptionsKey struct{}
+
+func withCallOptions(ctx context.Context, opts []grpc.CallOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, callOptionsKey{}, opts)
+}
+
+func callOptionsFromContext(ctx context.Context) []grpc.CallOption {
+	opts, _ := ctx.Value(callOptionsKey{}).([]grpc.CallOption)
+	return opts
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func TestChatMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var trace []string
+	logging := func(name string) ChatMiddleware {
+		return ChatMiddleware{
+			Completion: func(ctx context.Context, req *xaipb.GetCompletionsRequest, next CompletionHandler) (*xaipb.GetChatCompletionResponse, error) {
+				trace = append(trace, name+" before")
+				resp, err := next(ctx, req)
+				trace = append(trace, name+" after")
+				return resp, err
+			},
+		}
+	}
+	setUser := ChatMiddleware{
+		Completion: func(ctx context.Context, req *xaipb.GetCompletionsRequest, next CompletionHandler) (*xaipb.GetChatCompletionResponse, error) {
+			req.User = "org-user"
+			return next(ctx, req)
+		},
+	}
+
+	chat := &recordingChatClient{reply: "ok"}
+	opts := DefaultClientOptions()
+	WithChatMiddleware(logging("outer"), logging("inner"))(opts)
+	WithChatMiddleware(setUser)(opts)
+
+	s := (&ChatClient{chat: chainChatMiddleware(chat, opts.chatMiddleware)}).Create("grok-4", WithMessages(User("hi")))
+	resp, err := s.Completion(t.Context())
+	if err != nil {
+		t.Fatalf("Completion() error = %v", err)
+	}
+	if resp.Content() != "ok" {
+		t.Fatalf("Content() = %q, want ok", resp.Content())
+	}
+
+	want := []string{"outer before", "inner before", "inner after", "outer after"}
+	if diff := cmp.Diff(want, trace); diff != "" {
+		t.Fatalf("middleware order mismatch (-want +got):\n%s", diff)
+	}
+	if got := chat.requests[0].GetUser(); got != "org-user" {
+		t.Fatalf("request user = %q, want middleware mutation", got)
+	}
+}
+
+func TestChatMiddlewareRejects(t *testing.T) {
+	t.Parallel()
+
+	errPolicy := errors.New("blocked by policy")
+	chat := &recordingChatClient{reply: "ok"}
+	deny := ChatMiddleware{
+		Completion: func(context.Context, *xaipb.GetCompletionsRequest, CompletionHandler) (*xaipb.GetChatCompletionResponse, error) {
+			return nil, errPolicy
+		},
+	}
+
+	s := (&ChatClient{chat: chainChatMiddleware(chat, []ChatMiddleware{deny})}).Create("grok-4", WithMessages(User("hi")))
+	if _, err := s.Completion(t.Context()); !errors.Is(err, errPolicy) {
+		t.Fatalf("Completion() error = %v, want %v", err, errPolicy)
+	}
+	if len(chat.requests) != 0 {
+		t.Fatalf("rejected request reached the API: %d requests", len(chat.requests))
+	}
+
+	if got := chainChatMiddleware(chat, nil); got != xaipb.ChatClient(chat) {
+		t.Fatalf("chainChatMiddleware() without middleware = %T, want the client unchanged", got)
+	}
+}
@@ -20,15 +20,15 @@ import (
 	"context"
 	json "encoding/json/v2"
 	"errors"
-	"fmt"
+	"io"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/invopop/jsonschema"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
@@ -203,7 +203,10 @@ func WithPreviousResponse(id string) ChatOption {
 	}
 }
 
-// WithEncryptedContent enables encrypted content in the response.
+// WithEncryptedContent requests the reasoning trace as encrypted content in the response.
+//
+// Without stored messages the API needs the encrypted content back on the next turn to keep the reasoning context;
+// [ChatSession.Append] and [Response.Message] carry it over.
 func WithEncryptedContent(enabled bool) ChatOption {
 	return func(req *xaipb.GetCompletionsRequest, _ *ChatSession) {
 		req.UseEncryptedContent = enabled
@@ -218,15 +221,33 @@ func WithMaxTurns(maxTurns int32) ChatOption {
 }
 
 // ChatSession represents an active chat session.
+//
+// A session is safe for concurrent use: appends are serialized and every request samples a snapshot of the history
+// taken when it starts. Goroutines that should continue the conversation independently use [ChatSession.Clone].
 type ChatSession struct {
-	chat           xaipb.ChatClient
+	chat     xaipb.ChatClient
+	tokenize xaipb.TokenizeClient
+
+	// mu guards request, spanReqAttrs, spanReqMeta, and usage.
+	mu             sync.Mutex
 	request        *xaipb.GetCompletionsRequest
 	conversationID string
 	spanReqAttrs   *[]attribute.KeyValue
+	spanReqMeta    int
+	usage          CumulativeUsage
+	pricing        map[string]ModelPricing
+	window         *contextWindow
+	sink           io.Writer
+	maxBuffered    int
+	traceEncrypted bool
+	spanContent    SpanContentPolicy
 }
 
 // Append adds a message or response to the chat session.
 func (s *ChatSession) Append(message any) *ChatSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	switch msg := message.(type) {
 	case *xaipb.Message:
 		s.request.Messages = append(s.request.Messages, msg)
@@ -250,8 +271,8 @@ func (s *ChatSession) Append(message any) *ChatSession {
 	return s
 }
 
-// AppendToolResultJSON appends a tool result message with JSON payload (string or marshaled value).
-// toolCallID is optional; if empty, it is omitted because the proto does not carry it.
+// AppendToolResultJSON appends a tool result message with JSON payload (string or marshaled value) answering the
+// tool call with toolCallID; see [ToolCallResult]. An empty toolCallID appends the payload alone.
 func (s *ChatSession) AppendToolResultJSON(toolCallID string, result any) *ChatSession {
 	var payload string
 
@@ -267,38 +288,54 @@ func (s *ChatSession) AppendToolResultJSON(toolCallID string, result any) *ChatS
 		payload = string(b)
 	}
 
-	msg := &xaipb.Message{
-		Role:    xaipb.MessageRole_ROLE_TOOL,
-		Content: []*xaipb.Content{TextContent(payload)},
-	}
-
-	// toolCallID currently not represented in proto; retained parameter for forward compatibility
-	_ = toolCallID
-
-	return s.Append(msg)
+	return s.Append(ToolCallResult(toolCallID, payload))
 }
 
-// Messages returns the current conversation history.
+// Messages returns a copy of the current conversation history.
 func (s *ChatSession) Messages() []*xaipb.Message {
-	return s.request.GetMessages()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.request.GetMessages())
+}
+
+// Clone returns an independent session with a deep copy of the request and history of s.
+//
+// Appends to the clone do not affect s and vice versa, so one prepared session can fan out into concurrent
+// conversations. A context window policy is carried over with empty caches, and [ChatSession.CumulativeUsage] of the
+// clone starts from zero.
+func (s *ChatSession) Clone() *ChatSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := &ChatSession{
+		chat:           s.chat,
+		tokenize:       s.tokenize,
+		request:        proto.Clone(s.request).(*xaipb.GetCompletionsRequest),
+		conversationID: s.conversationID,
+		sink:           s.sink,
+		maxBuffered:    s.maxBuffered,
+		pricing:        s.pricing,
+		traceEncrypted: s.traceEncrypted,
+		spanContent:    s.spanContent,
+	}
+	if s.window != nil {
+		clone.window = newContextWindow(s.window.policy)
+	}
+	return clone
 }
 
 // Completion sends the chat request and returns the first response.
 func (s *ChatSession) Completion(ctx context.Context) (*Response, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.completion %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.completion")
 	defer span.End()
 
 	responses, err := s.sampleN(ctx, 1)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		return nil, err
 	}
 
-	span.SetAttributes(s.makeSpanResponseAttributes(responses)...)
+	span.SetAttributes(s.makeSpanResponseAttributes(responses, content)...)
 	span.SetStatus(codes.Ok, "")
 
 	return responses[0], nil
@@ -306,20 +343,16 @@ func (s *ChatSession) Completion(ctx context.Context) (*Response, error) {
 
 // CompletionBatch requests n responses in a single call.
 func (s *ChatSession) CompletionBatch(ctx context.Context, n int32) ([]*Response, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.completion_batch %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.completion_batch")
 	defer span.End()
 
 	responses, err := s.sampleN(ctx, n)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		return nil, err
 	}
 
-	span.SetAttributes(s.makeSpanResponseAttributes(responses)...)
+	span.SetAttributes(s.makeSpanResponseAttributes(responses, content)...)
 	span.SetStatus(codes.Ok, "")
 
 	return responses, nil
@@ -327,10 +360,7 @@ func (s *ChatSession) CompletionBatch(ctx context.Context, n int32) ([]*Response
 
 // Stream returns a streaming iterator for a single response.
 func (s *ChatSession) Stream(ctx context.Context) (*ChatStream, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.stream %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.stream")
 	// Span ended by ChatStream.Close or implied lifecycle handling?
 	// Usually streaming spans end when stream closes.
 	// Here we attach span to ChatStream so user can end it or we hook into Close.
@@ -339,13 +369,13 @@ func (s *ChatSession) Stream(ctx context.Context) (*ChatStream, error) {
 
 	stream, err := s.streamN(ctx, 1)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		span.End()
 		return nil, err
 	}
 
 	stream.span = span
+	stream.content = content
 	stream.ctx = ctx
 
 	return stream, nil
@@ -353,20 +383,17 @@ func (s *ChatSession) Stream(ctx context.Context) (*ChatStream, error) {
 
 // StreamBatch returns a streaming iterator for multiple responses.
 func (s *ChatSession) StreamBatch(ctx context.Context, n int32) (*ChatStream, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.stream_batch %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.stream_batch")
 
 	stream, err := s.streamN(ctx, n)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		span.End()
 		return nil, err
 	}
 
 	stream.span = span
+	stream.content = content
 	stream.ctx = ctx
 
 	return stream, nil
@@ -374,40 +401,32 @@ func (s *ChatSession) StreamBatch(ctx context.Context, n int32) (*ChatStream, er
 
 // Defer executes the request using deferred polling.
 func (s *ChatSession) Defer(ctx context.Context, timeout, interval time.Duration) (*Response, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.defer %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.defer")
 	defer span.End()
 
 	responses, err := s.deferN(ctx, 1, timeout, interval)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		return nil, err
 	}
 
-	span.SetAttributes(s.makeSpanResponseAttributes(responses)...)
+	span.SetAttributes(s.makeSpanResponseAttributes(responses, content)...)
 
 	return responses[0], nil
 }
 
 // DeferBatch executes the request using deferred polling and returns n responses.
 func (s *ChatSession) DeferBatch(ctx context.Context, n int32, timeout, interval time.Duration) ([]*Response, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.defer_batch %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.defer_batch")
 	defer span.End()
 
 	responses, err := s.deferN(ctx, n, timeout, interval)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		return nil, err
 	}
 
-	span.SetAttributes(s.makeSpanResponseAttributes(responses)...)
+	span.SetAttributes(s.makeSpanResponseAttributes(responses, content)...)
 
 	return responses, nil
 }
@@ -415,9 +434,10 @@ func (s *ChatSession) DeferBatch(ctx context.Context, n int32, timeout, interval
 // Parse sets response_format to a JSON schema derived from the provided sample value and decodes into it.
 // Pass a pointer to a struct value to populate it.
 func (s *ChatSession) Parse(ctx context.Context, out any) (*Response, error) {
-	if s.request.GetResponseFormat() != nil && s.request.GetResponseFormat().GetFormatType() == xaipb.FormatType_FORMAT_TYPE_JSON_SCHEMA {
+	req := s.cloneRequest()
+	if req.GetResponseFormat() != nil && req.GetResponseFormat().GetFormatType() == xaipb.FormatType_FORMAT_TYPE_JSON_SCHEMA {
 		// allow caller to override schema via options; don't overwrite
-		return s.parseWithRequest(ctx, out, proto.Clone(s.request).(*xaipb.GetCompletionsRequest))
+		return s.parseWithRequest(ctx, out, req)
 	}
 
 	schemaBytes, err := schemaBytesForValue(out)
@@ -426,7 +446,6 @@ func (s *ChatSession) Parse(ctx context.Context, out any) (*Response, error) {
 	}
 
 	schemaStr := string(schemaBytes)
-	req := proto.Clone(s.request).(*xaipb.GetCompletionsRequest)
 	req.ResponseFormat = &xaipb.ResponseFormat{
 		FormatType: xaipb.FormatType_FORMAT_TYPE_JSON_SCHEMA,
 		Schema:     &schemaStr,
@@ -437,31 +456,24 @@ func (s *ChatSession) Parse(ctx context.Context, out any) (*Response, error) {
 
 // parseWithRequest executes a parse with the provided request.
 func (s *ChatSession) parseWithRequest(ctx context.Context, out any, req *xaipb.GetCompletionsRequest) (*Response, error) {
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("chat.parse %s", s.request.GetModel()),
-		trace.WithSpanKind(trace.SpanKindClient),
-		trace.WithAttributes(s.makeSpanRequestAttributes()...),
-	)
+	ctx, span, content := s.startSpan(ctx, "chat.parse")
 	defer span.End()
 
 	resp, err := s.invokeCompletion(ctx, req)
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		return nil, err
 	}
-	span.SetAttributes(s.makeSpanResponseAttributes([]*Response{resp})...)
+	span.SetAttributes(s.makeSpanResponseAttributes([]*Response{resp}, content)...)
 
 	if err := json.Unmarshal([]byte(resp.Content()), out); err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		span.RecordError(err)
+		s.failSpan(span, err, content)
 		return resp, err
 	}
 
 	return resp, nil
 }
 
-var jsonSchemaCache sync.Map
-
 func schemaBytesForValue(v any) ([]byte, error) {
 	if v == nil {
 		return nil, errors.New("schema value must be non-nil")
@@ -473,8 +485,9 @@ func schemaBytesForType(t reflect.Type) ([]byte, error) {
 	if t == nil {
 		return nil, errors.New("schema type is nil")
 	}
-	if cached, ok := jsonSchemaCache.Load(t); ok {
-		return cached.([]byte), nil
+	cached, gen, ok := jsonSchemaCache.get(t)
+	if ok {
+		return cached, nil
 	}
 
 	reflector := &jsonschema.Reflector{}
@@ -488,7 +501,7 @@ func schemaBytesForType(t reflect.Type) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	jsonSchemaCache.Store(t, b)
+	jsonSchemaCache.add(t, b, gen)
 
 	return b, nil
 }
@@ -17,8 +17,16 @@
 package xai
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
@@ -33,3 +41,107 @@ func TestWithJSONSchema(t *testing.T) {
 		t.Fatalf("schema not propagated")
 	}
 }
+
+// historyLenChatClient answers each completion with the number of messages it received; it is safe for
+// concurrent use.
+type historyLenChatClient struct {
+	xaipb.ChatClient
+
+	calls atomic.Int64
+}
+
+func (c *historyLenChatClient) GetCompletion(_ context.Context, in *xaipb.GetCompletionsRequest, _ ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	c.calls.Add(1)
+	return &xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: strconv.Itoa(len(in.GetMessages()))},
+		}},
+	}, nil
+}
+
+func TestChatSessionClone(t *testing.T) {
+	t.Parallel()
+
+	client := &ChatClient{chat: &historyLenChatClient{}}
+	s := client.Create("grok-4",
+		WithMessages(System("be brief"), User("hi")),
+		WithTemperature(0.5),
+		WithContextWindowPolicy(ContextWindowPolicy{Strategy: SlidingWindow, MaxTurns: 2, CountTokens: wordCount}),
+	)
+
+	clone := s.Clone()
+	clone.Append(Assistant("hello"))
+	clone.request.Messages[0].Content[0] = TextContent("be verbose")
+
+	if diff := cmp.Diff([]string{"be brief", "hi"}, messageTexts(s.Messages())); diff != "" {
+		t.Fatalf("original messages mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"be verbose", "hi", "hello"}, messageTexts(clone.Messages())); diff != "" {
+		t.Fatalf("clone messages mismatch (-want +got):\n%s", diff)
+	}
+	if clone.request.GetTemperature() != 0.5 || clone.request.GetModel() != "grok-4" {
+		t.Fatalf("clone request = %v, want model and options carried over", clone.request)
+	}
+	if clone.window == nil || clone.window == s.window || clone.window.policy.MaxTurns != 2 {
+		t.Fatalf("clone window = %+v, want a fresh window with the same policy", clone.window)
+	}
+}
+
+func TestChatSessionConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	const (
+		workers = 8
+		turns   = 10
+	)
+	fake := &historyLenChatClient{}
+	client := &ChatClient{chat: fake}
+	s := client.Create("grok-4",
+		WithMessages(System("be brief")),
+		WithContextWindowPolicy(ContextWindowPolicy{Strategy: SlidingWindow, MaxTurns: 3, CountTokens: wordCount}),
+	)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+	for range workers {
+		wg.Go(func() {
+			for range turns {
+				s.Append(User("question"))
+				resp, err := s.Completion(t.Context())
+				if err != nil {
+					errs <- err
+					return
+				}
+				s.Append(resp)
+				_ = s.Messages()
+			}
+		})
+		wg.Go(func() {
+			c := s.Clone()
+			for range turns {
+				c.Append(User("question"))
+				resp, err := c.Completion(t.Context())
+				if err != nil {
+					errs <- err
+					return
+				}
+				c.Append(resp)
+			}
+			if got, want := len(c.Messages()), 2*turns; got < want {
+				errs <- fmt.Errorf("clone history has %d messages, want at least %d", got, want)
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if got, want := len(s.Messages()), 1+2*workers*turns; got != want {
+		t.Fatalf("len(Messages()) = %d, want %d", got, want)
+	}
+	if got, want := fake.calls.Load(), int64(2*workers*turns); got != want {
+		t.Fatalf("completions = %d, want %d", got, want)
+	}
+}
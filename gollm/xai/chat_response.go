@@ -33,6 +33,8 @@ type Response struct {
 	reasoningBuffers  []*strings.Builder
 	encryptedBuffers  []*strings.Builder
 	toolCallScratch   [][]*xaipb.ToolCall
+	toolCallArgs      [][]*strings.Builder
+	limits            *streamLimits
 	buffersAreInProto bool
 }
 
@@ -90,6 +92,18 @@ func (r *Response) EncryptedContent() string {
 	return ""
 }
 
+// Message returns the assistant message of the response for use on the next turn, including its encrypted
+// reasoning content. It returns nil when the response has no output.
+func (r *Response) Message() *xaipb.Message {
+	r.flushBuffers()
+	out := r.output()
+	if out == nil {
+		return nil
+	}
+
+	return buildMessageFromCompletion(out)
+}
+
 // Role returns the assistant role string.
 func (r *Response) Role() string {
 	r.flushBuffers()
@@ -101,6 +115,8 @@ func (r *Response) Role() string {
 }
 
 // ToolCalls returns tool calls from all assistant outputs.
+//
+// For a streamed response, argument fragments of a call that arrive across chunks are assembled into one call.
 func (r *Response) ToolCalls() []*xaipb.ToolCall {
 	r.flushBuffers()
 	outputs := r.proto.GetOutputs()
@@ -180,33 +196,28 @@ func (r *Response) outputNoFlush() *xaipb.CompletionOutput {
 	return last
 }
 
+// flushBuffers materializes the buffered text in the proto.
+//
+// The builders stay attached to their outputs: [strings.Builder.String] does not copy, and later deltas append past
+// the text already handed out, so reading the content after every chunk does not copy the text streamed so far.
 func (r *Response) flushBuffers() {
+	r.flushToolCallArgs()
 	if r.buffersAreInProto {
 		return
 	}
 
-	for idx, b := range r.contentBuffers {
-		if b != nil && idx < len(r.proto.GetOutputs()) {
-			r.proto.Outputs[idx].Message.Content = b.String()
-		}
-	}
-
-	for idx, b := range r.reasoningBuffers {
-		if b != nil && idx < len(r.proto.GetOutputs()) {
-			r.proto.Outputs[idx].Message.ReasoningContent = b.String()
-		}
-	}
-
-	for idx, b := range r.encryptedBuffers {
-		if b != nil && idx < len(r.proto.GetOutputs()) {
-			r.proto.Outputs[idx].Message.EncryptedContent = b.String()
+	flush := func(bufs []*strings.Builder, field func(*xaipb.CompletionMessage) *string) {
+		for idx, b := range bufs {
+			if b != nil && idx < len(r.proto.GetOutputs()) {
+				*field(r.proto.Outputs[idx].Message) = b.String()
+			}
 		}
 	}
+	flush(r.contentBuffers, func(m *xaipb.CompletionMessage) *string { return &m.Content })
+	flush(r.reasoningBuffers, func(m *xaipb.CompletionMessage) *string { return &m.ReasoningContent })
+	flush(r.encryptedBuffers, func(m *xaipb.CompletionMessage) *string { return &m.EncryptedContent })
 
 	r.buffersAreInProto = true
-	releaseBuilders(&r.contentBuffers)
-	releaseBuilders(&r.reasoningBuffers)
-	releaseBuilders(&r.encryptedBuffers)
 }
 
 //nolint:cyclop,gocognit,funlen,gocyclo // TODO(zchee): fix nolint.
@@ -253,90 +264,164 @@ func (r *Response) processChunk(chunk *xaipb.GetChatCompletionChunk) {
 		msg := target.GetMessage()
 		target.Index = c.GetIndex()
 		msg.Role = delta.GetRole()
-		if calls := delta.GetToolCalls(); len(calls) > 0 {
-			existing := msg.GetToolCalls()
-			if len(existing) == 0 {
-				r.ensureToolCallSlot(idx)
-				if len(calls) == 1 {
-					buf := make([]*xaipb.ToolCall, 1, 8)
-					buf[0] = calls[0]
-					r.toolCallScratch[idx] = buf
-					msg.ToolCalls = buf
-					continue
-				}
-
-				r.toolCallScratch[idx] = calls
-				msg.ToolCalls = calls
-				continue
+		if lps := c.GetLogprobs().GetContent(); len(lps) > 0 {
+			if target.Logprobs == nil {
+				target.Logprobs = &xaipb.LogProbs{}
 			}
-
-			need := len(existing) + len(calls)
-			spare := growthSpareToolCalls(len(existing))
-			if cap(existing) < need {
-				existing = slices.Grow(existing, len(calls)+spare)
+			target.Logprobs.Content = append(target.Logprobs.Content, lps...)
+		}
+		if calls := delta.GetToolCalls(); len(calls) > 0 {
+			if r.hasToolCallFragment(msg.GetToolCalls(), calls) {
+				r.mergeToolCalls(idx, msg, calls)
+			} else {
+				r.appendToolCalls(idx, msg, calls)
 			}
-			existing = append(existing, calls...)
-			msg.ToolCalls = existing
-			r.ensureToolCallSlot(idx)
-			r.toolCallScratch[idx] = existing
 		}
 		target.FinishReason = c.GetFinishReason()
 
-		//nolint:nestif // TODO(zchee): fix nolint
-		if content := delta.GetContent(); content != "" {
-			if r.buffersAreInProto {
-				if msg.GetContent() == "" {
-					msg.Content = content
-				} else {
-					buf := ensureBuilder(&r.contentBuffers, idx)
-					buf.Grow(len(msg.GetContent()) + len(content))
-					buf.WriteString(msg.GetContent())
-					buf.WriteString(content)
-					r.buffersAreInProto = false
-				}
-			} else {
-				buf := ensureBuilder(&r.contentBuffers, idx)
-				buf.Grow(len(content))
-				buf.WriteString(content)
-			}
+		content, reasoning := delta.GetContent(), delta.GetReasoningContent()
+		if r.limits != nil {
+			content = r.limits.contentDelta(idx, content)
+			reasoning = r.limits.reasoningDelta(idx, reasoning)
 		}
 
-		//nolint:nestif // TODO(zchee): fix nolint
-		if reasoning := delta.GetReasoningContent(); reasoning != "" {
-			if r.buffersAreInProto {
-				if msg.GetReasoningContent() == "" {
-					msg.ReasoningContent = reasoning
-				} else {
-					buf := ensureBuilder(&r.reasoningBuffers, idx)
-					buf.Grow(len(msg.GetReasoningContent()) + len(reasoning))
-					buf.WriteString(msg.GetReasoningContent())
-					buf.WriteString(reasoning)
-					r.buffersAreInProto = false
-				}
-			} else {
-				buf := ensureBuilder(&r.reasoningBuffers, idx)
-				buf.Grow(len(reasoning))
-				buf.WriteString(reasoning)
+		r.appendText(&r.contentBuffers, idx, &msg.Content, content)
+		r.appendText(&r.reasoningBuffers, idx, &msg.ReasoningContent, reasoning)
+		r.appendText(&r.encryptedBuffers, idx, &msg.EncryptedContent, delta.GetEncryptedContent())
+	}
+}
+
+// appendText appends delta to the text field of output idx, whose builder is bufs[idx].
+//
+// The first delta of a field is stored in the field without a copy, which is all a field needs when the output
+// arrives in one chunk. The second delta moves the field into a builder sized for both, and every later delta
+// appends to that builder.
+func (r *Response) appendText(bufs *[]*strings.Builder, idx int, field *string, delta string) {
+	if delta == "" {
+		return
+	}
+	if *field == "" && (idx >= len(*bufs) || (*bufs)[idx] == nil) {
+		*field = delta
+		return
+	}
+	r.buffersAreInProto = false
+	buf := ensureBuilder(bufs, idx)
+	if buf.Len() > 0 {
+		buf.WriteString(delta)
+		return
+	}
+	buf.Grow(len(*field) + len(delta))
+	buf.WriteString(*field)
+	buf.WriteString(delta)
+}
+
+// flushToolCallArgs writes the arguments assembled by mergeToolCalls into their tool calls.
+func (r *Response) flushToolCallArgs() {
+	for idx, bufs := range r.toolCallArgs {
+		var calls []*xaipb.ToolCall
+		if idx < len(r.proto.GetOutputs()) {
+			calls = r.proto.Outputs[idx].GetMessage().GetToolCalls()
+		}
+		for j, b := range bufs {
+			if b != nil && j < len(calls) {
+				calls[j].GetFunction().Arguments = b.String()
 			}
 		}
+		releaseBuilders(&r.toolCallArgs[idx])
+	}
+	r.toolCallArgs = r.toolCallArgs[:0]
+}
+
+// appendToolCalls adds whole tool calls to the message of output idx.
+func (r *Response) appendToolCalls(idx int, msg *xaipb.CompletionMessage, calls []*xaipb.ToolCall) {
+	existing := msg.GetToolCalls()
+	if len(existing) == 0 {
+		r.ensureToolCallSlot(idx)
+		if len(calls) == 1 {
+			buf := make([]*xaipb.ToolCall, 1, 8)
+			buf[0] = calls[0]
+			r.toolCallScratch[idx] = buf
+			msg.ToolCalls = buf
+			return
+		}
 
-		//nolint:nestif // TODO(zchee): fix nolint
-		if encrypted := delta.GetEncryptedContent(); encrypted != "" {
-			if r.buffersAreInProto {
-				if msg.GetEncryptedContent() == "" {
-					msg.EncryptedContent = encrypted
-				} else {
-					buf := ensureBuilder(&r.encryptedBuffers, idx)
-					buf.Grow(len(msg.GetEncryptedContent()) + len(encrypted))
-					buf.WriteString(msg.GetEncryptedContent())
-					buf.WriteString(encrypted)
-					r.buffersAreInProto = false
-				}
-			} else {
-				buf := ensureBuilder(&r.encryptedBuffers, idx)
-				buf.Grow(len(encrypted))
-				buf.WriteString(encrypted)
+		r.toolCallScratch[idx] = calls
+		msg.ToolCalls = calls
+		return
+	}
+
+	need := len(existing) + len(calls)
+	spare := growthSpareToolCalls(len(existing))
+	if cap(existing) < need {
+		existing = slices.Grow(existing, len(calls)+spare)
+	}
+	existing = append(existing, calls...)
+	msg.ToolCalls = existing
+	r.ensureToolCallSlot(idx)
+	r.toolCallScratch[idx] = existing
+}
+
+// hasToolCallFragment reports whether any of calls continues a call in existing rather than starting a new one.
+func (r *Response) hasToolCallFragment(existing, calls []*xaipb.ToolCall) bool {
+	if len(existing) == 0 && len(calls) < 2 {
+		return false
+	}
+	return slices.ContainsFunc(calls, func(tc *xaipb.ToolCall) bool {
+		return tc.GetId() == "" && tc.GetFunction().GetName() == "" || tc.GetId() != "" && slices.ContainsFunc(existing, func(e *xaipb.ToolCall) bool {
+			return e.GetId() == tc.GetId()
+		})
+	})
+}
+
+// mergeToolCalls adds calls to the message of output idx, assembling partial calls in the manner of streamed
+// function-argument deltas: a call whose id matches an earlier call, or one with neither id nor function name,
+// continues that call (the latest one when unnamed) and its arguments are appended to the earlier arguments.
+func (r *Response) mergeToolCalls(idx int, msg *xaipb.CompletionMessage, calls []*xaipb.ToolCall) {
+	for _, tc := range calls {
+		existing := msg.GetToolCalls()
+		j := -1
+		switch {
+		case tc.GetId() != "":
+			j = slices.IndexFunc(existing, func(e *xaipb.ToolCall) bool { return e.GetId() == tc.GetId() })
+		case tc.GetFunction().GetName() == "":
+			j = len(existing) - 1
+		}
+		if j < 0 {
+			r.appendToolCalls(idx, msg, []*xaipb.ToolCall{tc})
+			continue
+		}
+
+		if idx >= len(r.toolCallArgs) {
+			r.toolCallArgs = append(r.toolCallArgs, make([][]*strings.Builder, idx+1-len(r.toolCallArgs))...)
+		}
+		call := existing[j]
+		buf := ensureBuilder(&r.toolCallArgs[idx], j)
+		if buf.Len() == 0 {
+			// Copy on first merge so the chunk that delivered the call is never modified.
+			call = call.CloneVT()
+			if call.GetFunction() == nil {
+				call.Tool = &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{}}
 			}
+			existing[j] = call
+			buf.WriteString(call.GetFunction().GetArguments())
+		}
+		buf.WriteString(tc.GetFunction().GetArguments())
+
+		fn := call.GetFunction()
+		if name := tc.GetFunction().GetName(); name != "" && fn.GetName() == "" {
+			fn.Name = name
+		}
+		if call.GetId() == "" {
+			call.Id = tc.GetId()
+		}
+		if tc.GetType() != xaipb.ToolCallType_TOOL_CALL_TYPE_INVALID {
+			call.Type = tc.GetType()
+		}
+		if tc.GetStatus() != xaipb.ToolCallStatus_TOOL_CALL_STATUS_IN_PROGRESS {
+			call.Status = tc.GetStatus()
+		}
+		if tc.ErrorMessage != nil {
+			call.ErrorMessage = tc.ErrorMessage
 		}
 	}
 }
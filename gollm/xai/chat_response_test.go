@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
@@ -44,6 +45,27 @@ func TestResponseDecodeJSON(t *testing.T) {
 	}
 }
 
+func TestResponseMessage(t *testing.T) {
+	t.Parallel()
+
+	resp := newResponse(&xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{
+				Role:             xaipb.MessageRole_ROLE_ASSISTANT,
+				Content:          "answer",
+				EncryptedContent: "blob",
+			},
+		}},
+	}, nil)
+	msg := resp.Message()
+	if msg.GetRole() != xaipb.MessageRole_ROLE_ASSISTANT || msg.GetContent()[0].GetText() != "answer" || msg.GetEncryptedContent() != "blob" {
+		t.Fatalf("Message() = %v, want assistant answer with encrypted content", msg)
+	}
+	if got := newResponse(&xaipb.GetChatCompletionResponse{}, nil).Message(); got != nil {
+		t.Fatalf("Message() without output = %v, want nil", got)
+	}
+}
+
 func TestSchemaBytesCacheReused(t *testing.T) {
 	type sample struct {
 		Foo int `json:"foo"`
@@ -99,3 +121,49 @@ func TestResponseProcessChunkStreamingAggregation(t *testing.T) {
 		t.Fatalf("reasoning aggregation mismatch: %q", got)
 	}
 }
+
+func TestResponseProcessChunkToolCallAssembly(t *testing.T) {
+	t.Parallel()
+
+	call := func(id, name, args string) *xaipb.ToolCall {
+		return &xaipb.ToolCall{Id: id, Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: name, Arguments: args}}}
+	}
+	chunk := func(content string, calls ...*xaipb.ToolCall) *xaipb.GetChatCompletionChunk {
+		return &xaipb.GetChatCompletionChunk{Outputs: []*xaipb.CompletionOutputChunk{{
+			Delta: &xaipb.Delta{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: content, ToolCalls: calls},
+		}}}
+	}
+	type toolCall struct{ ID, Name, Args string }
+	toolCalls := func(resp *Response) []toolCall {
+		var out []toolCall
+		for _, tc := range resp.ToolCalls() {
+			out = append(out, toolCall{tc.GetId(), tc.GetFunction().GetName(), tc.GetFunction().GetArguments()})
+		}
+		return out
+	}
+
+	first := call("call-1", "get_weather", `{"ci`)
+	resp := newResponse(&xaipb.GetChatCompletionResponse{}, nil)
+	resp.processChunk(chunk("a", first))
+	resp.processChunk(chunk("", call("", "", `ty":"Pa`)))
+	if diff := cmp.Diff([]toolCall{{"call-1", "get_weather", `{"city":"Pa`}}, toolCalls(resp)); diff != "" {
+		t.Fatalf("partial ToolCalls() mismatch (-want +got):\n%s", diff)
+	}
+
+	resp.processChunk(chunk("b", call("call-1", "", `ris"}`), call("call-2", "noop", `{}`), call("", "", ` `)))
+	resp.processChunk(chunk("", call("", "echo", `{"x":1}`)))
+	want := []toolCall{
+		{"call-1", "get_weather", `{"city":"Paris"}`},
+		{"call-2", "noop", `{} `},
+		{"", "echo", `{"x":1}`},
+	}
+	if diff := cmp.Diff(want, toolCalls(resp)); diff != "" {
+		t.Fatalf("ToolCalls() mismatch (-want +got):\n%s", diff)
+	}
+	if got := resp.Content(); got != "ab" {
+		t.Fatalf("Content() = %q, want ab", got)
+	}
+	if got := first.GetFunction().GetArguments(); got != `{"ci` {
+		t.Fatalf("first chunk tool call arguments = %q, want unmodified", got)
+	}
+}
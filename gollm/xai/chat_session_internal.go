@@ -20,10 +20,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
-	"google.golang.org/protobuf/proto"
-
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
@@ -32,13 +31,64 @@ const (
 	defaultDeferredInterval = 100 * time.Millisecond
 )
 
-func (s *ChatSession) sampleN(ctx context.Context, n int32) ([]*Response, error) {
-	if len(s.request.GetMessages()) == 0 {
-		return nil, errors.New("chat request requires at least one message")
+// cloneRequest returns a copy of the session request, so it can be sent while other goroutines append to the
+// session.
+//
+// The copy is shallow (see [shallowCloneRequest]): the history is shared rather than copied on every call.
+func (s *ChatSession) cloneRequest() *xaipb.GetCompletionsRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return shallowCloneRequest(s.request)
+}
+
+// shallowCloneRequest returns a copy of req sharing its messages, tools, and other nested values, which a session
+// never modifies once added. The repeated fields of the copy are clipped, so appending to them reallocates instead
+// of writing into the backing arrays of req; code changing the copy replaces nested values rather than modifying
+// them.
+func shallowCloneRequest(req *xaipb.GetCompletionsRequest) *xaipb.GetCompletionsRequest {
+	return &xaipb.GetCompletionsRequest{
+		Messages:            slices.Clip(req.Messages),
+		Model:               req.Model,
+		User:                req.User,
+		N:                   req.N,
+		MaxTokens:           req.MaxTokens,
+		Seed:                req.Seed,
+		Stop:                slices.Clip(req.Stop),
+		Temperature:         req.Temperature,
+		TopP:                req.TopP,
+		Logprobs:            req.Logprobs,
+		TopLogprobs:         req.TopLogprobs,
+		Tools:               slices.Clip(req.Tools),
+		ToolChoice:          req.ToolChoice,
+		ResponseFormat:      req.ResponseFormat,
+		FrequencyPenalty:    req.FrequencyPenalty,
+		PresencePenalty:     req.PresencePenalty,
+		ReasoningEffort:     req.ReasoningEffort,
+		SearchParameters:    req.SearchParameters,
+		ParallelToolCalls:   req.ParallelToolCalls,
+		PreviousResponseId:  req.PreviousResponseId,
+		StoreMessages:       req.StoreMessages,
+		UseEncryptedContent: req.UseEncryptedContent,
+		MaxTurns:            req.MaxTurns,
+		Include:             slices.Clip(req.Include),
 	}
+}
 
-	req := proto.Clone(s.request).(*xaipb.GetCompletionsRequest)
+// requestN returns a copy of the session request asking for n choices.
+func (s *ChatSession) requestN(n int32) (*xaipb.GetCompletionsRequest, error) {
+	req := s.cloneRequest()
+	if len(req.GetMessages()) == 0 {
+		return nil, errors.New("chat request requires at least one message")
+	}
 	req.N = ptr(n)
+	return req, nil
+}
+
+func (s *ChatSession) sampleN(ctx context.Context, n int32) ([]*Response, error) {
+	req, err := s.requestN(n)
+	if err != nil {
+		return nil, err
+	}
 	resp, err := s.invokeCompletion(ctx, req)
 	if err != nil {
 		return nil, err
@@ -57,15 +107,17 @@ func (s *ChatSession) sampleN(ctx context.Context, n int32) ([]*Response, error)
 }
 
 func (s *ChatSession) streamN(ctx context.Context, n int32) (*ChatStream, error) {
-	if len(s.request.GetMessages()) == 0 {
-		return nil, errors.New("chat request requires at least one message")
+	req, err := s.requestN(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fitContextWindow(ctx, req); err != nil {
+		return nil, err
 	}
-	req := proto.Clone(s.request).(*xaipb.GetCompletionsRequest)
-	req.N = ptr(n)
 
 	stream, err := s.chat.GetCompletionChunk(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, WrapError(err)
 	}
 
 	resp := &xaipb.GetChatCompletionResponse{}
@@ -80,18 +132,23 @@ func (s *ChatSession) streamN(ctx context.Context, n int32) (*ChatStream, error)
 		return ptr(int32(0))
 	}
 
+	response := newResponse(resp, intPtrIf(n == 1))
+	response.limits = newStreamLimits(s)
 	return &ChatStream{
 		stream:   stream,
-		response: newResponse(resp, intPtrIf(n == 1)),
+		response: response,
+		session:  s,
 	}, nil
 }
 
 func (s *ChatSession) deferN(ctx context.Context, n int32, timeout, interval time.Duration) ([]*Response, error) {
-	if len(s.request.GetMessages()) == 0 {
-		return nil, errors.New("chat request requires at least one message")
+	req, err := s.requestN(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fitContextWindow(ctx, req); err != nil {
+		return nil, err
 	}
-	req := proto.Clone(s.request).(*xaipb.GetCompletionsRequest)
-	req.N = ptr(n)
 
 	if timeout <= 0 {
 		timeout = defaultDeferredTimeout
@@ -100,31 +157,48 @@ func (s *ChatSession) deferN(ctx context.Context, n int32, timeout, interval tim
 		interval = defaultDeferredInterval
 	}
 
-	startResp, err := s.chat.StartDeferredCompletion(ctx, req)
+	// The timeout bounds the polling RPCs as well as the waits between them.
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	deferredErr := func(err error) error {
+		// Whether the RPC or the wait between polls sees the cancellation first, the caller gets context.Canceled.
+		if ctxErr := ctx.Err(); errors.Is(ctxErr, context.Canceled) {
+			return fmt.Errorf("deferred request: %w", ctxErr)
+		}
+		if ctx.Err() == nil && pollCtx.Err() != nil {
+			return newTimeoutError(fmt.Sprintf("deferred request timed out after %s", timeout))
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return newTimeoutError("deferred request: " + err.Error())
+		}
+		return WrapError(err)
+	}
+
+	startResp, err := s.chat.StartDeferredCompletion(pollCtx, req)
 	if err != nil {
-		return nil, WrapError(err)
+		return nil, deferredErr(err)
 	}
 
-	deadline := time.Now().Add(timeout)
 	for {
-		if time.Now().After(deadline) {
-			return nil, fmt.Errorf("deferred request timed out after %s", timeout)
-		}
-
-		res, err := s.chat.GetDeferredCompletion(ctx, &xaipb.GetDeferredRequest{
+		res, err := s.chat.GetDeferredCompletion(pollCtx, &xaipb.GetDeferredRequest{
 			RequestId: startResp.GetRequestId(),
 		})
 		if err != nil {
-			return nil, WrapError(err)
+			return nil, deferredErr(err)
 		}
 
 		switch res.GetStatus() {
 		case xaipb.DeferredStatus_DONE:
+			s.recordUsage(ctx, res.GetResponse())
 			return splitResponses(res.GetResponse(), n), nil
 		case xaipb.DeferredStatus_EXPIRED:
 			return nil, fmt.Errorf("deferred request expired")
 		case xaipb.DeferredStatus_PENDING:
-			time.Sleep(interval)
+			select {
+			case <-pollCtx.Done():
+				return nil, deferredErr(pollCtx.Err())
+			case <-time.After(interval):
+			}
 		default:
 			return nil, fmt.Errorf("unknown deferred status %v", res.GetStatus())
 		}
@@ -132,10 +206,14 @@ func (s *ChatSession) deferN(ctx context.Context, n int32, timeout, interval tim
 }
 
 func (s *ChatSession) invokeCompletion(ctx context.Context, req *xaipb.GetCompletionsRequest) (*Response, error) {
+	if err := s.fitContextWindow(ctx, req); err != nil {
+		return nil, err
+	}
 	resp, err := s.chat.GetCompletion(ctx, req)
 	if err != nil {
 		return nil, WrapError(err)
 	}
+	s.recordUsage(ctx, resp)
 
 	index := int32(0)
 	if usesServerSideTools(req.GetTools()) {
@@ -0,0 +1,98 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// populate sets every field of m to a value other than its default, leaving nested messages empty.
+func populate(m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		switch {
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			if fd.Message() != nil {
+				list.Append(list.NewElement())
+			} else {
+				list.Append(nonZero(fd))
+			}
+		case fd.Message() != nil:
+			m.Mutable(fd)
+		default:
+			m.Set(fd, nonZero(fd))
+		}
+	}
+}
+
+// nonZero returns a value of the scalar field fd other than its default.
+func nonZero(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(1)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString("x")
+	default:
+		return protoreflect.ValueOfBytes([]byte("x"))
+	}
+}
+
+func TestShallowCloneRequest(t *testing.T) {
+	t.Parallel()
+
+	req := &xaipb.GetCompletionsRequest{}
+	populate(req.ProtoReflect())
+	req.Messages = append(make([]*xaipb.Message, 0, 8), User("hi"))
+
+	clone := shallowCloneRequest(req)
+	if !proto.Equal(req, clone) {
+		t.Fatalf("shallowCloneRequest() = %v, want every field of %v", clone, req)
+	}
+	if clone.Messages[0] != req.Messages[0] {
+		t.Fatal("shallowCloneRequest() copied the messages, want them shared")
+	}
+
+	clone.Messages = append(clone.Messages, User("appended"))
+	clone.N = ptr(int32(3))
+	if got := req.Messages[:cap(req.Messages)][1]; got != nil {
+		t.Fatalf("appending to the clone wrote %v into the messages of the request", got)
+	}
+	if req.GetN() != 1 {
+		t.Fatalf("request N = %d, want it unchanged", req.GetN())
+	}
+}
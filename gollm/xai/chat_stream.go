@@ -19,6 +19,7 @@ package xai
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"iter"
 	"time"
@@ -34,7 +35,9 @@ type ChatStream struct {
 	stream             xaipb.Chat_GetCompletionChunkClient
 	response           *Response
 	ctx                context.Context
+	session            *ChatSession
 	span               trace.Span
+	content            bool
 	firstChunkReceived bool
 }
 
@@ -63,6 +66,9 @@ func (s *ChatStream) Response() *Response {
 // Recv returns an iterator over the aggregated response as it streams in. Iterate with:
 //
 //	for resp, err := range stream.Recv() { ... }
+//
+// Tool call arguments split across chunks are assembled in place, so the tool calls of the yielded response grow as
+// fragments arrive and are complete once the stream ends.
 func (s *ChatStream) Recv() iter.Seq2[*Response, error] {
 	return func(yield func(*Response, error) bool) {
 		defer s.Close()
@@ -73,12 +79,11 @@ func (s *ChatStream) Recv() iter.Seq2[*Response, error] {
 				s.finishSpan(err)
 
 				if errors.Is(err, io.EOF) {
+					s.recordUsage()
 					return
 				}
 
-				if !yield(nil, err) {
-					return
-				}
+				yield(nil, WrapError(err))
 				return
 			}
 
@@ -89,6 +94,12 @@ func (s *ChatStream) Recv() iter.Seq2[*Response, error] {
 
 			s.response.index = autoDetectMultiOutputChunks(s.response.index, chunk.GetOutputs())
 			s.response.processChunk(chunk)
+			if l := s.response.limits; l != nil && l.sinkErr != nil {
+				err := fmt.Errorf("write chunk sink: %w", l.sinkErr)
+				s.finishSpan(err)
+				yield(nil, err)
+				return
+			}
 
 			if !yield(s.response, nil) {
 				return
@@ -97,13 +108,29 @@ func (s *ChatStream) Recv() iter.Seq2[*Response, error] {
 	}
 }
 
+// recordUsage adds the usage of the completed stream to its session.
+func (s *ChatStream) recordUsage() {
+	if s.session == nil {
+		return
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.session.recordUsage(ctx, s.response.proto)
+}
+
 func (s *ChatStream) finishSpan(err error) {
 	if s.span == nil {
 		return
 	}
 
 	if !errors.Is(err, io.EOF) {
-		s.span.RecordError(err)
+		if s.session != nil {
+			s.session.failSpan(s.span, err, s.content)
+		} else {
+			s.span.RecordError(err)
+		}
 		return
 	}
 
@@ -0,0 +1,107 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"io"
+	"unicode/utf8"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// WithChunkSink writes the content of every streamed chunk to w as it arrives.
+//
+// It applies to [ChatSession.Stream] and [ChatSession.StreamBatch]; the deltas of all outputs of a batch are written
+// in arrival order. A write error ends the stream with that error. Combine with [WithMaxBufferedContent] to pipe
+// large outputs to disk without also holding them in memory.
+func WithChunkSink(w io.Writer) ChatOption {
+	return func(_ *xaipb.GetCompletionsRequest, s *ChatSession) {
+		s.sink = w
+	}
+}
+
+// WithMaxBufferedContent caps the content and the reasoning content a streamed [Response] keeps in memory to n bytes
+// per output.
+//
+// Deltas beyond the cap are dropped from the response, which then reports [Response.Truncated], but are still written
+// to the [WithChunkSink] writer. Encrypted content is never dropped. Zero or a negative n keeps everything.
+func WithMaxBufferedContent(n int) ChatOption {
+	return func(_ *xaipb.GetCompletionsRequest, s *ChatSession) {
+		s.maxBuffered = n
+	}
+}
+
+// Truncated reports whether streamed content or reasoning content was dropped by [WithMaxBufferedContent].
+func (r *Response) Truncated() bool {
+	return r.limits != nil && r.limits.truncated
+}
+
+// streamLimits applies the sink and buffer cap of a session to the chunks of one stream.
+type streamLimits struct {
+	sink        io.Writer
+	sinkErr     error
+	maxBuffered int
+	content     []int
+	reasoning   []int
+	truncated   bool
+}
+
+// newStreamLimits returns the limits configured on s, or nil when it has none.
+func newStreamLimits(s *ChatSession) *streamLimits {
+	if s.sink == nil && s.maxBuffered <= 0 {
+		return nil
+	}
+	return &streamLimits{sink: s.sink, maxBuffered: s.maxBuffered}
+}
+
+// contentDelta writes delta to the sink and returns the part of it to buffer for output idx.
+func (l *streamLimits) contentDelta(idx int, delta string) string {
+	if l.sink != nil && l.sinkErr == nil && delta != "" {
+		_, l.sinkErr = io.WriteString(l.sink, delta)
+	}
+	return l.keep(&l.content, idx, delta)
+}
+
+// reasoningDelta returns the part of delta to buffer for output idx.
+func (l *streamLimits) reasoningDelta(idx int, delta string) string {
+	return l.keep(&l.reasoning, idx, delta)
+}
+
+// keep trims delta to the room left under the cap of output idx, cutting at a rune boundary.
+func (l *streamLimits) keep(counts *[]int, idx int, delta string) string {
+	if l.maxBuffered <= 0 || delta == "" {
+		return delta
+	}
+	if idx >= len(*counts) {
+		*counts = append(*counts, make([]int, idx+1-len(*counts))...)
+	}
+
+	room := l.maxBuffered - (*counts)[idx]
+	if room >= len(delta) {
+		(*counts)[idx] += len(delta)
+		return delta
+	}
+	l.truncated = true
+	if room <= 0 {
+		return ""
+	}
+	for room > 0 && !utf8.RuneStart(delta[room]) {
+		room--
+	}
+	(*counts)[idx] += room
+	return delta[:room]
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// chunkStream replays chunks as a server-streaming completion.
+type chunkStream struct {
+	grpc.ClientStream
+
+	chunks []*xaipb.GetChatCompletionChunk
+}
+
+func (s *chunkStream) Recv() (*xaipb.GetChatCompletionChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *chunkStream) CloseSend() error { return nil }
+
+// streamingChatClient streams one chunk per content delta.
+type streamingChatClient struct {
+	xaipb.ChatClient
+
+	deltas []string
+}
+
+func (c *streamingChatClient) GetCompletionChunk(context.Context, *xaipb.GetCompletionsRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error) {
+	stream := &chunkStream{}
+	for _, d := range c.deltas {
+		stream.chunks = append(stream.chunks, &xaipb.GetChatCompletionChunk{Outputs: []*xaipb.CompletionOutputChunk{{
+			Delta: &xaipb.Delta{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: d, ReasoningContent: d},
+		}}})
+	}
+	return stream, nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestChatStreamSinkAndBufferLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		deltas        []string
+		opts          []ChatOption
+		wantContent   string
+		wantReasoning string
+		wantSink      string
+		wantTruncated bool
+	}{
+		"unlimited": {
+			deltas:        []string{"hello ", "world"},
+			wantContent:   "hello world",
+			wantReasoning: "hello world",
+		},
+		"sink only": {
+			deltas:        []string{"hello ", "world"},
+			wantContent:   "hello world",
+			wantReasoning: "hello world",
+			wantSink:      "hello world",
+		},
+		"capped": {
+			deltas:        []string{"hel", "lo ", "world"},
+			opts:          []ChatOption{WithMaxBufferedContent(5)},
+			wantContent:   "hello",
+			wantReasoning: "hello",
+			wantSink:      "hello world",
+			wantTruncated: true,
+		},
+		"cut at rune boundary": {
+			deltas:        []string{"日本語"},
+			opts:          []ChatOption{WithMaxBufferedContent(4)},
+			wantContent:   "日",
+			wantReasoning: "日",
+			wantSink:      "日本語",
+			wantTruncated: true,
+		},
+		"exactly at cap": {
+			deltas:        []string{"ab", "cd"},
+			opts:          []ChatOption{WithMaxBufferedContent(4)},
+			wantContent:   "abcd",
+			wantReasoning: "abcd",
+			wantSink:      "abcd",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var sink strings.Builder
+			client := &ChatClient{chat: &streamingChatClient{deltas: tt.deltas}}
+			s := client.Create("grok-4", append([]ChatOption{WithMessages(User("hi")), WithChunkSink(&sink)}, tt.opts...)...)
+			stream, err := s.Stream(t.Context())
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			for _, err := range stream.Recv() {
+				if err != nil {
+					t.Fatalf("Recv() error = %v", err)
+				}
+			}
+
+			resp := stream.Response()
+			if got := resp.Content(); got != tt.wantContent {
+				t.Fatalf("Content() = %q, want %q", got, tt.wantContent)
+			}
+			if got := resp.ReasoningContent(); got != tt.wantReasoning {
+				t.Fatalf("ReasoningContent() = %q, want %q", got, tt.wantReasoning)
+			}
+			if got := resp.Truncated(); got != tt.wantTruncated {
+				t.Fatalf("Truncated() = %t, want %t", got, tt.wantTruncated)
+			}
+			if want := tt.wantSink; want != "" && sink.String() != want {
+				t.Fatalf("sink = %q, want %q", sink.String(), want)
+			}
+		})
+	}
+}
+
+func TestChatStreamSinkError(t *testing.T) {
+	t.Parallel()
+
+	client := &ChatClient{chat: &streamingChatClient{deltas: []string{"a", "b"}}}
+	s := client.Create("grok-4", WithMessages(User("hi")), WithChunkSink(failingWriter{}))
+	stream, err := s.Stream(t.Context())
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var gotErr error
+	n := 0
+	for _, err := range stream.Recv() {
+		n++
+		gotErr = err
+	}
+	if n != 1 || gotErr == nil || !strings.Contains(gotErr.Error(), "write chunk sink: disk full") {
+		t.Fatalf("Recv() yielded %d times with error %v, want one sink error", n, gotErr)
+	}
+}
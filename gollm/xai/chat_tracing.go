@@ -17,23 +17,111 @@
 package xai
 
 import (
+	"context"
 	json "encoding/json/v2"
+	"fmt"
+	"math/rand/v2"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
-//nolint:cyclop,gocyclo,gocognit // TODO(zchee): fix nolint
+// SpanContentPolicy controls the prompt and completion content recorded on chat spans, which dominates their size.
+//
+// Spans always record the request parameters, response metadata, and token usage, and carry a gen_ai.capture_content
+// attribute telling whether they also record the content.
+type SpanContentPolicy struct {
+	// Disabled records no content, except for failed requests when OnError is set.
+	Disabled bool
+
+	// SampleRate is the fraction of requests, between 0 and 1, whose spans record content. Zero records the content
+	// of every request.
+	SampleRate float64
+
+	// OnError records the prompt of failed requests whose content was not sampled.
+	OnError bool
+
+	// MaxAttributeLength truncates every content attribute to at most this many bytes. Zero records it whole.
+	MaxAttributeLength int
+}
+
+// sample reports whether the span of a new request records content.
+func (p SpanContentPolicy) sample() bool {
+	switch {
+	case p.Disabled:
+		return false
+	case p.SampleRate <= 0 || p.SampleRate >= 1:
+		return true
+	default:
+		return rand.Float64() < p.SampleRate
+	}
+}
+
+// truncate shortens v to MaxAttributeLength bytes at a rune boundary, marking the cut with an ellipsis.
+func (p SpanContentPolicy) truncate(v string) string {
+	if p.MaxAttributeLength <= 0 || len(v) <= p.MaxAttributeLength {
+		return v
+	}
+	cut := p.MaxAttributeLength
+	for cut > 0 && !utf8.RuneStart(v[cut]) {
+		cut--
+	}
+	return v[:cut] + "…"
+}
+
+// startSpan starts the chat span of op and reports whether it records prompt and completion content.
+func (s *ChatSession) startSpan(ctx context.Context, op string) (context.Context, trace.Span, bool) {
+	content := s.spanContent.sample()
+	attrs, meta := s.spanRequestAttributes()
+	if !content {
+		attrs = attrs[:meta]
+	}
+	ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", op, s.request.GetModel()),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(attribute.Bool("gen_ai.capture_content", content)),
+	)
+	return ctx, span, content
+}
+
+// failSpan records err on span, adding the prompt when the span recorded no content and the policy captures the
+// content of failed requests.
+func (s *ChatSession) failSpan(span trace.Span, err error, content bool) {
+	span.SetStatus(codes.Error, err.Error())
+	span.RecordError(err)
+	if !content && s.spanContent.OnError {
+		attrs, meta := s.spanRequestAttributes()
+		span.SetAttributes(attrs[meta:]...)
+		span.SetAttributes(attribute.Bool("gen_ai.capture_content", true))
+	}
+}
+
+// makeSpanRequestAttributes returns the request attributes of a chat span, content included.
 func (s *ChatSession) makeSpanRequestAttributes() []attribute.KeyValue {
+	attrs, _ := s.spanRequestAttributes()
+	return attrs
+}
+
+// spanRequestAttributes returns the request attributes of a chat span; the prompt content attributes follow the
+// first meta ones.
+//
+//nolint:cyclop,gocyclo,gocognit // TODO(zchee): fix nolint
+func (s *ChatSession) spanRequestAttributes() (attrs []attribute.KeyValue, meta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.spanReqAttrs != nil {
-		return *s.spanReqAttrs
+		return *s.spanReqAttrs, s.spanReqMeta
 	}
 
 	msgs := s.request.GetMessages()
-	attrs := make([]attribute.KeyValue, 0, 18+len(msgs)*6)
+	attrs = make([]attribute.KeyValue, 0, 18+len(msgs)*6)
 
 	attrs = append(attrs,
 		attribute.String("gen_ai.operation.name", "chat"),
@@ -85,6 +173,7 @@ func (s *ChatSession) makeSpanRequestAttributes() []attribute.KeyValue {
 		attrs = append(attrs, attribute.String("gen_ai.request.previous_response_id", s.request.GetPreviousResponseId()))
 	}
 
+	meta = len(attrs)
 	for i, msg := range msgs {
 		prefix := "gen_ai.prompt." + strconv.Itoa(i)
 		role := messageRoleLower(msg.GetRole())
@@ -111,25 +200,28 @@ func (s *ChatSession) makeSpanRequestAttributes() []attribute.KeyValue {
 				}
 			}
 		}
-		attrs = append(attrs, attribute.String(prefix+".content", content))
+		attrs = append(attrs, attribute.String(prefix+".content", s.spanContent.truncate(content)))
 
 		if msg.GetRole() == xaipb.MessageRole_ROLE_ASSISTANT {
 			if tcs := msg.GetToolCalls(); len(tcs) > 0 {
 				if encoded := encodeToolCalls(tcs); encoded != "" {
-					attrs = append(attrs, attribute.String(prefix+".tool_calls", encoded))
+					attrs = append(attrs, attribute.String(prefix+".tool_calls", s.spanContent.truncate(encoded)))
 				}
 			}
 			if enc := msg.GetEncryptedContent(); enc != "" {
-				attrs = append(attrs, attribute.String(prefix+".encrypted_content", enc))
+				attrs = append(attrs, attribute.String(prefix+".encrypted_content", s.encryptedContentAttr(enc)))
 			}
 		}
 	}
 
 	s.spanReqAttrs = &attrs
-	return attrs
+	s.spanReqMeta = meta
+	return attrs, meta
 }
 
-func (s *ChatSession) makeSpanResponseAttributes(responses []*Response) []attribute.KeyValue {
+// makeSpanResponseAttributes returns the response attributes of a chat span, with the completion content when
+// content is set.
+func (s *ChatSession) makeSpanResponseAttributes(responses []*Response, content bool) []attribute.KeyValue {
 	if len(responses) == 0 {
 		return nil
 	}
@@ -168,21 +260,27 @@ func (s *ChatSession) makeSpanResponseAttributes(responses []*Response) []attrib
 
 		msg := out.GetMessage()
 		finishReasons[i] = finishReasonLower(out.GetFinishReason())
+		if !content {
+			continue
+		}
 
 		prefix := "gen_ai.completion." + strconv.Itoa(i)
 		role := messageRoleLower(msg.GetRole())
 		attrs = append(attrs,
 			attribute.String(prefix+".role", role),
-			attribute.String(prefix+".content", msg.GetContent()),
+			attribute.String(prefix+".content", s.spanContent.truncate(msg.GetContent())),
 		)
 
 		if rc := msg.GetReasoningContent(); rc != "" {
-			attrs = append(attrs, attribute.String(prefix+".reasoning_content", rc))
+			attrs = append(attrs, attribute.String(prefix+".reasoning_content", s.spanContent.truncate(rc)))
+		}
+		if enc := msg.GetEncryptedContent(); enc != "" {
+			attrs = append(attrs, attribute.String(prefix+".encrypted_content", s.encryptedContentAttr(enc)))
 		}
 
 		if tcs := msg.GetToolCalls(); len(tcs) > 0 {
 			if encoded := encodeToolCalls(tcs); encoded != "" {
-				attrs = append(attrs, attribute.String(prefix+".tool_calls", encoded))
+				attrs = append(attrs, attribute.String(prefix+".tool_calls", s.spanContent.truncate(encoded)))
 			}
 		}
 	}
@@ -191,6 +289,15 @@ func (s *ChatSession) makeSpanResponseAttributes(responses []*Response) []attrib
 	return attrs
 }
 
+// encryptedContentAttr returns the span value of encrypted reasoning content, redacted unless the client opted in
+// with [WithEncryptedContentTracing].
+func (s *ChatSession) encryptedContentAttr(enc string) string {
+	if s.traceEncrypted {
+		return enc
+	}
+	return "[redacted " + strconv.Itoa(len(enc)) + " bytes]"
+}
+
 func messageRoleLower(role xaipb.MessageRole) string {
 	if int(role) < len(messageRoleStrings) {
 		return messageRoleStrings[role]
@@ -17,9 +17,13 @@
 package xai
 
 import (
+	"errors"
 	"testing"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
@@ -83,3 +87,164 @@ func TestMakeSpanRequestAttributesCachingAndInvalidation(t *testing.T) {
 		t.Fatalf("expected gen_ai.prompt.1.role after Append")
 	}
 }
+
+func TestSpanAttributesEncryptedContent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		traceEncrypted bool
+		want           string
+	}{
+		"redacted by default": {want: "[redacted 4 bytes]"},
+		"opted in":            {traceEncrypted: true, want: "blob"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assistant := &xaipb.Message{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: []*xaipb.Content{TextContent("a")}, EncryptedContent: "blob"}
+			s := &ChatSession{
+				request:        &xaipb.GetCompletionsRequest{Model: "grok-4", Messages: []*xaipb.Message{User("q"), assistant}},
+				traceEncrypted: tt.traceEncrypted,
+			}
+			if got, ok := findAttr(s.makeSpanRequestAttributes(), "gen_ai.prompt.1.encrypted_content"); !ok || got.AsString() != tt.want {
+				t.Fatalf("gen_ai.prompt.1.encrypted_content = %q ok=%v, want %q", got.AsString(), ok, tt.want)
+			}
+
+			resp := newResponse(&xaipb.GetChatCompletionResponse{Outputs: []*xaipb.CompletionOutput{{
+				Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "a", EncryptedContent: "blob"},
+			}}}, nil)
+			if got, ok := findAttr(s.makeSpanResponseAttributes([]*Response{resp}, true), "gen_ai.completion.0.encrypted_content"); !ok || got.AsString() != tt.want {
+				t.Fatalf("gen_ai.completion.0.encrypted_content = %q ok=%v, want %q", got.AsString(), ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpanContentPolicyTruncate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		max  int
+		in   string
+		want string
+	}{
+		"unlimited":         {in: "hello world", want: "hello world"},
+		"within limit":      {max: 5, in: "hello", want: "hello"},
+		"cut":               {max: 5, in: "hello world", want: "hello…"},
+		"cut at rune start": {max: 2, in: "héllo", want: "h…"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (SpanContentPolicy{MaxAttributeLength: tt.max}).truncate(tt.in); got != tt.want {
+				t.Fatalf("truncate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpanContentPolicySample(t *testing.T) {
+	t.Parallel()
+
+	if !(SpanContentPolicy{}).sample() {
+		t.Fatalf("zero policy should record content")
+	}
+	if (SpanContentPolicy{Disabled: true, SampleRate: 1}).sample() {
+		t.Fatalf("disabled policy should not record content")
+	}
+
+	policy := SpanContentPolicy{SampleRate: 0.25}
+	sampled := 0
+	const n = 10000
+	for range n {
+		if policy.sample() {
+			sampled++
+		}
+	}
+	if sampled < n/5 || sampled > n*3/10 {
+		t.Fatalf("sampled %d of %d requests, want about %d", sampled, n, n/4)
+	}
+}
+
+func TestSpanContentAttributes(t *testing.T) {
+	t.Parallel()
+
+	s := &ChatSession{
+		request:     &xaipb.GetCompletionsRequest{Model: "grok-4", Messages: []*xaipb.Message{User("a long question")}},
+		spanContent: SpanContentPolicy{MaxAttributeLength: 6},
+	}
+
+	attrs, meta := s.spanRequestAttributes()
+	if _, ok := findAttr(attrs[:meta], "gen_ai.prompt.0.content"); ok {
+		t.Fatalf("metadata attributes carry prompt content")
+	}
+	if _, ok := findAttr(attrs[:meta], "gen_ai.request.model"); !ok {
+		t.Fatalf("metadata attributes miss gen_ai.request.model")
+	}
+	if got, ok := findAttr(attrs[meta:], "gen_ai.prompt.0.content"); !ok || got.AsString() != "a long…" {
+		t.Fatalf("gen_ai.prompt.0.content = %q ok=%v, want %q", got.AsString(), ok, "a long…")
+	}
+
+	resp := newResponse(&xaipb.GetChatCompletionResponse{Outputs: []*xaipb.CompletionOutput{{
+		FinishReason: xaipb.FinishReason_REASON_STOP,
+		Message:      &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "the answer"},
+	}}}, nil)
+	withContent := s.makeSpanResponseAttributes([]*Response{resp}, true)
+	if got, ok := findAttr(withContent, "gen_ai.completion.0.content"); !ok || got.AsString() != "the an…" {
+		t.Fatalf("gen_ai.completion.0.content = %q ok=%v, want %q", got.AsString(), ok, "the an…")
+	}
+	withoutContent := s.makeSpanResponseAttributes([]*Response{resp}, false)
+	if _, ok := findAttr(withoutContent, "gen_ai.completion.0.content"); ok {
+		t.Fatalf("response attributes carry completion content")
+	}
+	if got, ok := findAttr(withoutContent, "gen_ai.response.finish_reasons"); !ok || got.AsStringSlice()[0] != "reason_stop" {
+		t.Fatalf("gen_ai.response.finish_reasons = %v ok=%v, want [reason_stop]", got.AsStringSlice(), ok)
+	}
+}
+
+func TestFailSpanCapturesPromptOnError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy      SpanContentPolicy
+		content     bool
+		wantCapture bool
+	}{
+		"on error":         {policy: SpanContentPolicy{Disabled: true, OnError: true}, wantCapture: true},
+		"not on error":     {policy: SpanContentPolicy{Disabled: true}},
+		"already captured": {policy: SpanContentPolicy{OnError: true}, content: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			_, span := tp.Tracer("test").Start(t.Context(), "chat.completion")
+
+			s := &ChatSession{
+				request:     &xaipb.GetCompletionsRequest{Model: "grok-4", Messages: []*xaipb.Message{User("q")}},
+				spanContent: tt.policy,
+			}
+			s.failSpan(span, errors.New("boom"), tt.content)
+			span.End()
+
+			ended := recorder.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(ended))
+			}
+			if got := ended[0].Status().Code; got != codes.Error {
+				t.Fatalf("status = %v, want %v", got, codes.Error)
+			}
+			_, captured := findAttr(ended[0].Attributes(), "gen_ai.prompt.0.content")
+			if captured != tt.wantCapture {
+				t.Fatalf("prompt captured = %v, want %v", captured, tt.wantCapture)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"maps"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// ModelPricing is the price of a model in USD per one million tokens, used to estimate the cost of a session.
+type ModelPricing struct {
+	PromptTokens       float64
+	CachedPromptTokens float64
+	PromptImageTokens  float64
+	CompletionTokens   float64
+}
+
+// ModelPricingFromProto converts the prices reported by the Models API into a [ModelPricing].
+func ModelPricingFromProto(m *xaipb.LanguageModel) ModelPricing {
+	// Prompt, image, and completion prices are in 1/100 USD cents per million tokens; cached prompt prices are in
+	// USD cents per 100 million tokens.
+	return ModelPricing{
+		PromptTokens:       float64(m.GetPromptTextTokenPrice()) / 10_000,
+		CachedPromptTokens: float64(m.GetCachedPromptTokenPrice()) / 10_000,
+		PromptImageTokens:  float64(m.GetPromptImageTokenPrice()) / 10_000,
+		CompletionTokens:   float64(m.GetCompletionTextTokenPrice()) / 10_000,
+	}
+}
+
+// cost returns the estimated cost of usage in USD. Reasoning tokens are billed as completion tokens.
+func (p ModelPricing) cost(usage *xaipb.SamplingUsage) float64 {
+	cached := int64(usage.GetCachedPromptTextTokens())
+	image := int64(usage.GetPromptImageTokens())
+	text := int64(usage.GetPromptTextTokens())
+	if text == 0 {
+		text = int64(usage.GetPromptTokens()) - image
+	}
+	text = max(text-cached, 0)
+	completion := int64(usage.GetCompletionTokens()) + int64(usage.GetReasoningTokens())
+
+	return (float64(text)*p.PromptTokens +
+		float64(cached)*p.CachedPromptTokens +
+		float64(image)*p.PromptImageTokens +
+		float64(completion)*p.CompletionTokens) / 1_000_000
+}
+
+// WithModelPricing sets the prices used to estimate the cost reported by [ChatSession.CumulativeUsage], keyed by
+// model name. Responses whose model is not in prices count tokens but no cost.
+func WithModelPricing(prices map[string]ModelPricing) ChatOption {
+	return func(_ *xaipb.GetCompletionsRequest, s *ChatSession) {
+		s.pricing = maps.Clone(prices)
+	}
+}
+
+// UsageTotals sums the token usage and estimated cost of a number of completions.
+type UsageTotals struct {
+	Calls              int
+	PromptTokens       int64
+	CachedPromptTokens int64
+	CompletionTokens   int64
+	ReasoningTokens    int64
+	TotalTokens        int64
+	CostUSD            float64
+}
+
+func (t *UsageTotals) add(usage *xaipb.SamplingUsage, cost float64) {
+	t.Calls++
+	t.PromptTokens += int64(usage.GetPromptTokens())
+	t.CachedPromptTokens += int64(usage.GetCachedPromptTextTokens())
+	t.CompletionTokens += int64(usage.GetCompletionTokens())
+	t.ReasoningTokens += int64(usage.GetReasoningTokens())
+	t.TotalTokens += int64(usage.GetTotalTokens())
+	t.CostUSD += cost
+}
+
+// CumulativeUsage is the usage of every completion a [ChatSession] has received, in total and per response model.
+type CumulativeUsage struct {
+	UsageTotals
+	ByModel map[string]UsageTotals
+}
+
+// CumulativeUsage returns the token usage and estimated cost summed over every completion, stream, and deferred
+// request the session has made, including the extra calls of tool loops and argument repairs.
+//
+// Each Response only reports the usage of its own request; use this to budget a whole conversation. The same counts
+// are exported as the "xai.client.token.usage" and "xai.client.cost" counters of the global OpenTelemetry meter
+// provider. A clone starts from zero.
+func (s *ChatSession) CumulativeUsage() CumulativeUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := CumulativeUsage{UsageTotals: s.usage.UsageTotals}
+	if len(s.usage.ByModel) > 0 {
+		out.ByModel = maps.Clone(s.usage.ByModel)
+	}
+	return out
+}
+
+// recordUsage adds the usage of resp to the session totals and the usage metrics.
+func (s *ChatSession) recordUsage(ctx context.Context, resp *xaipb.GetChatCompletionResponse) {
+	usage := resp.GetUsage()
+	if usage == nil {
+		return
+	}
+
+	s.mu.Lock()
+	reqModel := s.request.GetModel()
+	model := resp.GetModel()
+	if model == "" {
+		model = reqModel
+	}
+	price, priced := s.pricing[model]
+	if !priced {
+		price, priced = s.pricing[reqModel]
+	}
+	var cost float64
+	if priced {
+		cost = price.cost(usage)
+	}
+	s.usage.add(usage, cost)
+	if s.usage.ByModel == nil {
+		s.usage.ByModel = make(map[string]UsageTotals)
+	}
+	perModel := s.usage.ByModel[model]
+	perModel.add(usage, cost)
+	s.usage.ByModel[model] = perModel
+	s.mu.Unlock()
+
+	usageMetrics.record(ctx, reqModel, model, usage, cost, priced)
+}
+
+// usageInstruments are the OpenTelemetry counters fed by [ChatSession.recordUsage].
+type usageInstruments struct {
+	tokens metric.Int64Counter
+	cost   metric.Float64Counter
+}
+
+var usageMetrics = newUsageInstruments()
+
+func newUsageInstruments() usageInstruments {
+	tokens, _ := meter.Int64Counter("xai.client.token.usage",
+		metric.WithDescription("Tokens used by chat completions, by token type."),
+		metric.WithUnit("{token}"),
+	)
+	cost, _ := meter.Float64Counter("xai.client.cost",
+		metric.WithDescription("Estimated cost of chat completions."),
+		metric.WithUnit("USD"),
+	)
+	return usageInstruments{tokens: tokens, cost: cost}
+}
+
+func (m usageInstruments) record(ctx context.Context, reqModel, respModel string, usage *xaipb.SamplingUsage, cost float64, priced bool) {
+	models := []attribute.KeyValue{
+		attribute.String("gen_ai.request.model", reqModel),
+		attribute.String("gen_ai.response.model", respModel),
+	}
+	if m.tokens != nil {
+		for _, c := range []struct {
+			typ string
+			n   int32
+		}{
+			{"input", usage.GetPromptTokens()},
+			{"cached_input", usage.GetCachedPromptTextTokens()},
+			{"output", usage.GetCompletionTokens()},
+			{"reasoning", usage.GetReasoningTokens()},
+		} {
+			if c.n == 0 {
+				continue
+			}
+			m.tokens.Add(ctx, int64(c.n), metric.WithAttributes(append(models, attribute.String("gen_ai.token.type", c.typ))...))
+		}
+	}
+	if m.cost != nil && priced {
+		m.cost.Add(ctx, cost, metric.WithAttributes(models...))
+	}
+}
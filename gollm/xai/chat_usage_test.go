@@ -0,0 +1,263 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// usageChatClient answers every kind of completion with the same usage.
+type usageChatClient struct {
+	xaipb.ChatClient
+
+	model string
+	usage *xaipb.SamplingUsage
+}
+
+func (c *usageChatClient) response() *xaipb.GetChatCompletionResponse {
+	return &xaipb.GetChatCompletionResponse{
+		Model: c.model,
+		Usage: c.usage,
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: "ok"},
+		}},
+	}
+}
+
+func (c *usageChatClient) GetCompletion(context.Context, *xaipb.GetCompletionsRequest, ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	return c.response(), nil
+}
+
+func (c *usageChatClient) GetCompletionChunk(context.Context, *xaipb.GetCompletionsRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[xaipb.GetChatCompletionChunk], error) {
+	delta := func(content string) []*xaipb.CompletionOutputChunk {
+		return []*xaipb.CompletionOutputChunk{{Delta: &xaipb.Delta{Role: xaipb.MessageRole_ROLE_ASSISTANT, Content: content}}}
+	}
+	return &chunkStream{chunks: []*xaipb.GetChatCompletionChunk{
+		{Model: c.model, Outputs: delta("o")},
+		{Model: c.model, Outputs: delta("k"), Usage: c.usage},
+	}}, nil
+}
+
+func (c *usageChatClient) StartDeferredCompletion(context.Context, *xaipb.GetCompletionsRequest, ...grpc.CallOption) (*xaipb.StartDeferredResponse, error) {
+	return &xaipb.StartDeferredResponse{RequestId: "req-1"}, nil
+}
+
+func (c *usageChatClient) GetDeferredCompletion(context.Context, *xaipb.GetDeferredRequest, ...grpc.CallOption) (*xaipb.GetDeferredCompletionResponse, error) {
+	return &xaipb.GetDeferredCompletionResponse{Status: xaipb.DeferredStatus_DONE, Response: c.response()}, nil
+}
+
+func TestChatSessionCumulativeUsage(t *testing.T) {
+	t.Parallel()
+
+	usage := &xaipb.SamplingUsage{
+		PromptTokens:           1000,
+		PromptTextTokens:       1000,
+		CachedPromptTextTokens: 400,
+		CompletionTokens:       100,
+		ReasoningTokens:        50,
+		TotalTokens:            1150,
+	}
+	// 600 prompt tokens at $2, 400 cached at $0.5, and 150 completion tokens at $10 per million.
+	const callCost = (600*2.0 + 400*0.5 + 150*10.0) / 1_000_000
+	perCall := UsageTotals{Calls: 1, PromptTokens: 1000, CachedPromptTokens: 400, CompletionTokens: 100, ReasoningTokens: 50, TotalTokens: 1150, CostUSD: callCost}
+	times := func(n int, priced bool) UsageTotals {
+		out := UsageTotals{
+			Calls:              n * perCall.Calls,
+			PromptTokens:       int64(n) * perCall.PromptTokens,
+			CachedPromptTokens: int64(n) * perCall.CachedPromptTokens,
+			CompletionTokens:   int64(n) * perCall.CompletionTokens,
+			ReasoningTokens:    int64(n) * perCall.ReasoningTokens,
+			TotalTokens:        int64(n) * perCall.TotalTokens,
+		}
+		if priced {
+			out.CostUSD = float64(n) * perCall.CostUSD
+		}
+		return out
+	}
+	pricing := map[string]ModelPricing{"grok-4": {PromptTokens: 2, CachedPromptTokens: 0.5, CompletionTokens: 10}}
+
+	tests := map[string]struct {
+		respModel string
+		pricing   map[string]ModelPricing
+		run       func(ctx context.Context, s *ChatSession) error
+		want      CumulativeUsage
+	}{
+		"completions": {
+			respModel: "grok-4-0709",
+			pricing:   pricing,
+			run: func(ctx context.Context, s *ChatSession) error {
+				for range 2 {
+					if _, err := s.Completion(ctx); err != nil {
+						return err
+					}
+				}
+				_, err := s.CompletionBatch(ctx, 2)
+				return err
+			},
+			want: CumulativeUsage{UsageTotals: times(3, true), ByModel: map[string]UsageTotals{"grok-4-0709": times(3, true)}},
+		},
+		"stream and deferred": {
+			respModel: "grok-4",
+			pricing:   pricing,
+			run: func(ctx context.Context, s *ChatSession) error {
+				stream, err := s.Stream(ctx)
+				if err != nil {
+					return err
+				}
+				for _, err := range stream.Recv() {
+					if err != nil {
+						return err
+					}
+				}
+				_, err = s.Defer(ctx, 0, 0)
+				return err
+			},
+			want: CumulativeUsage{UsageTotals: times(2, true), ByModel: map[string]UsageTotals{"grok-4": times(2, true)}},
+		},
+		"unpriced model": {
+			respModel: "grok-3",
+			run: func(ctx context.Context, s *ChatSession) error {
+				_, err := s.Completion(ctx)
+				return err
+			},
+			want: CumulativeUsage{UsageTotals: times(1, false), ByModel: map[string]UsageTotals{"grok-3": times(1, false)}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &ChatClient{chat: &usageChatClient{model: tt.respModel, usage: usage}}
+			s := client.Create("grok-4", WithMessages(User("hi")), WithModelPricing(tt.pricing))
+			if err := tt.run(t.Context(), s); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			opt := cmpopts.EquateApprox(0, 1e-12)
+			if diff := cmp.Diff(tt.want, s.CumulativeUsage(), opt); diff != "" {
+				t.Fatalf("CumulativeUsage() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(CumulativeUsage{}, s.Clone().CumulativeUsage()); diff != "" {
+				t.Fatalf("Clone().CumulativeUsage() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// pendingChatClient never completes a deferred request.
+type pendingChatClient struct {
+	xaipb.ChatClient
+}
+
+func (c *pendingChatClient) StartDeferredCompletion(context.Context, *xaipb.GetCompletionsRequest, ...grpc.CallOption) (*xaipb.StartDeferredResponse, error) {
+	return &xaipb.StartDeferredResponse{RequestId: "req-1"}, nil
+}
+
+func (c *pendingChatClient) GetDeferredCompletion(ctx context.Context, _ *xaipb.GetDeferredRequest, _ ...grpc.CallOption) (*xaipb.GetDeferredCompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &xaipb.GetDeferredCompletionResponse{Status: xaipb.DeferredStatus_PENDING}, nil
+}
+
+func TestChatSessionDeferTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		ctx     func(t *testing.T) context.Context
+		timeout time.Duration
+		wantErr error
+	}{
+		"timeout": {
+			ctx:     func(t *testing.T) context.Context { return t.Context() },
+			timeout: 20 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+		},
+		"context deadline": {
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+				t.Cleanup(cancel)
+				return ctx
+			},
+			timeout: time.Minute,
+			wantErr: context.DeadlineExceeded,
+		},
+		"canceled": {
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancel(t.Context())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx
+			},
+			timeout: time.Minute,
+			wantErr: context.Canceled,
+		},
+		// The poll RPC, rather than the wait between polls, sees the cancellation.
+		"canceled poll": {
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancel(t.Context())
+				cancel()
+				return ctx
+			},
+			timeout: time.Minute,
+			wantErr: context.Canceled,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &ChatClient{chat: &pendingChatClient{}}
+			s := client.Create("grok-4", WithMessages(User("hi")))
+			_, err := s.Defer(tt.ctx(t), tt.timeout, time.Millisecond)
+			var timeoutErr *TimeoutError
+			if !errors.Is(err, tt.wantErr) || errors.As(err, &timeoutErr) != (tt.wantErr == context.DeadlineExceeded) {
+				t.Fatalf("Defer() error = %v (%T), want %v", err, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModelPricingFromProto(t *testing.T) {
+	t.Parallel()
+
+	got := ModelPricingFromProto(&xaipb.LanguageModel{
+		PromptTextTokenPrice:     20_000,
+		CachedPromptTokenPrice:   5_000,
+		PromptImageTokenPrice:    20_000,
+		CompletionTextTokenPrice: 100_000,
+	})
+	want := ModelPricing{PromptTokens: 2, CachedPromptTokens: 0.5, PromptImageTokens: 2, CompletionTokens: 10}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ModelPricingFromProto() mismatch (-want +got):\n%s", diff)
+	}
+	if cost := got.cost(&xaipb.SamplingUsage{PromptTokens: 1_000_000, PromptImageTokens: 500_000}); math.Abs(cost-2) > 1e-9 {
+		t.Fatalf("cost() = %v, want 2", cost)
+	}
+}
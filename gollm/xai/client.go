@@ -17,20 +17,25 @@
 package xai
 
 import (
+	"cmp"
+	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"os"
-	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip compressor of WithCompression
 
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 	billingpb "github.com/zchee/tumix/gollm/xai/management_api/v1"
 )
 
+// defaultServiceConfig retries unavailable RPCs and limits their message sizes. The %d verbs are the request and
+// response limits of every method, then those of the document services (Files, Documents, and Collections).
 const defaultServiceConfig = `{
 	"methodConfig":
 		[
@@ -42,15 +47,38 @@ const defaultServiceConfig = `{
 					"maxBackoff":"1s",
 					"backoffMultiplier":2,
 					"retryableStatusCodes":["UNAVAILABLE"]
-				}
+				},
+				"maxRequestMessageBytes":%d,
+				"maxResponseMessageBytes":%d
+			},
+			{
+				"name":[{"service":"xai_api.Files"},{"service":"xai_api.Documents"},{"service":"collections.Collections"}],
+				"retryPolicy":{
+					"maxAttempts":5,
+					"initialBackoff":"0.1s",
+					"maxBackoff":"1s",
+					"backoffMultiplier":2,
+					"retryableStatusCodes":["UNAVAILABLE"]
+				},
+				"maxRequestMessageBytes":%d,
+				"maxResponseMessageBytes":%d
 			}
 		]
 	}`
 
+// serviceConfig returns the default service config of opts, with the message size limits of opts. The document
+// services get at least defaultDocumentMessageBytes.
+func serviceConfig(opts *clientOptions) string {
+	docSend, docRecv := max(opts.maxSendBytes, defaultDocumentMessageBytes), max(opts.maxRecvBytes, defaultDocumentMessageBytes)
+	return fmt.Sprintf(defaultServiceConfig, opts.maxSendBytes, opts.maxRecvBytes, docSend, docRecv)
+}
+
 // Client aggregates all xAI service clients.
 type Client struct {
-	apiConn        *grpc.ClientConn
+	apiConn        *connPool
+	endpoints      *endpointSet
 	managementConn *grpc.ClientConn
+	stopMonitor    context.CancelFunc
 
 	Auth        *AuthClient
 	Billing     *BillingClient
@@ -81,50 +109,83 @@ func NewClient(apiKey string, optFns ...ClientOption) (*Client, error) {
 	if opts.managementKey == "" {
 		opts.managementKey = os.Getenv("XAI_MANAGEMENT_KEY")
 	}
+	if opts.compression != "" && encoding.GetCompressor(opts.compression) == nil {
+		return nil, fmt.Errorf("compressor %q is not registered; import a package registering it with encoding.RegisterCompressor", opts.compression)
+	}
+	if err := opts.loadTransport(); err != nil {
+		return nil, err
+	}
+
+	hosts := append([]string{opts.apiHost}, opts.fallbacks...)
+	if env := endpointsFromEnv(os.Getenv(EndpointsEnv)); len(env) > 0 {
+		hosts = env
+		opts.apiHost = env[0]
+	}
 
-	apiConn := opts.apiConn
+	var apiConn *connPool
+	var endpoints *endpointSet
+	var api grpc.ClientConnInterface
 	var err error
-	if apiConn == nil {
-		apiConn, err = grpc.NewClient(opts.apiHost, BuildDialOptions(opts, opts.apiKey)...)
+	switch {
+	case opts.apiConn != nil:
+		apiConn = newConnPool(opts.apiConn)
+		api = apiConn
+	case len(hosts) > 1:
+		dialOpts := append(BuildDialOptions(opts, opts.apiKey), opts.transportOptions(opts.apiAuthority)...)
+		endpoints, err = dialEndpoints(hosts, opts.dialTarget, opts.poolSize, dialOpts, opts.endpointPolicy)
+		if err != nil {
+			return nil, err
+		}
+		apiConn = endpoints.endpoints[0].pool
+		api = endpoints
+	default:
+		dialOpts := append(BuildDialOptions(opts, opts.apiKey), opts.transportOptions(opts.apiAuthority)...)
+		apiConn, err = dialPool(opts.dialTarget(opts.apiHost), opts.poolSize, dialOpts)
 		if err != nil {
 			return nil, err
 		}
+		api = apiConn
 	}
 
 	client := &Client{
-		apiConn: apiConn,
+		apiConn:   apiConn,
+		endpoints: endpoints,
 		Auth: &AuthClient{
-			auth: xaipb.NewAuthClient(apiConn),
+			auth: xaipb.NewAuthClient(api),
 		},
 		Chat: &ChatClient{
-			chat: xaipb.NewChatClient(apiConn),
+			chat:           chainChatMiddleware(xaipb.NewChatClient(api), opts.chatMiddleware),
+			tokenize:       xaipb.NewTokenizeClient(api),
+			traceEncrypted: opts.traceEncrypted,
+			spanContent:    opts.spanContent,
 		},
 		Files: &FilesClient{
-			files: xaipb.NewFilesClient(apiConn),
+			files: xaipb.NewFilesClient(api),
 		},
 		Embed: &EmbedClient{
-			embedder: xaipb.NewEmbedderClient(apiConn),
+			embedder: xaipb.NewEmbedderClient(api),
 		},
 		Image: &ImageClient{
-			image: xaipb.NewImageClient(apiConn),
+			image: xaipb.NewImageClient(api),
 		},
 		Models: &ModelsClient{
-			models: xaipb.NewModelsClient(apiConn),
+			models: xaipb.NewModelsClient(api),
 		},
 		Sampler: &SamplerClient{
-			sample: xaipb.NewSampleClient(apiConn),
+			sample: xaipb.NewSampleClient(api),
 		},
 		Tokenizer: &TokenizerClient{
-			tokenize: xaipb.NewTokenizeClient(apiConn),
+			tokenize: xaipb.NewTokenizeClient(api),
 		},
 	}
 
 	if opts.managementKey != "" { //nolint:nestif // TODO(zchee): fix nolint
 		client.managementConn = opts.managementConn
 		if client.managementConn == nil {
-			client.managementConn, err = grpc.NewClient(opts.managementHost, BuildDialOptions(opts, opts.managementKey)...)
+			dialOpts := append(BuildDialOptions(opts, opts.managementKey), opts.transportOptions(opts.managementAuthority)...)
+			client.managementConn, err = grpc.NewClient(opts.dialTarget(opts.managementHost), dialOpts...)
 			if err != nil {
-				if cloneErr := apiConn.Close(); cloneErr != nil {
+				if cloneErr := client.closeAPI(); cloneErr != nil {
 					err = errors.Join(err, cloneErr)
 				}
 				return nil, err
@@ -134,52 +195,126 @@ func NewClient(apiKey string, optFns ...ClientOption) (*Client, error) {
 			uisvc: billingpb.NewUISvcClient(client.managementConn),
 		}
 
-		client.Collections = NewCollectionsClient(apiConn, client.managementConn)
+		client.Collections = NewCollectionsClient(api, client.managementConn)
+		client.Collections.chat = client.Chat
+		client.Collections.embed = client.Embed
+	}
+
+	healthCheck := endpoints != nil && opts.healthInterval > 0
+	if opts.autoReconnect || healthCheck {
+		ctx, cancel := context.WithCancel(context.Background())
+		client.stopMonitor = cancel
+		if healthCheck {
+			go endpoints.healthCheck(ctx, opts.healthInterval)
+		}
+		if opts.autoReconnect {
+			if endpoints != nil {
+				endpoints.monitor(ctx)
+			} else {
+				apiConn.monitor(ctx)
+			}
+			if client.managementConn != nil {
+				newConnPool(client.managementConn).monitor(ctx)
+			}
+		}
 	}
 
 	return client, nil
 }
 
+// Healthy connects idle channels and blocks until every connection of the client is ready. With [WithEndpoints],
+// the data plane is ready once the connections of one endpoint are.
+//
+// It returns nil once all connections are ready, or an error naming the first connection that is shut down or not
+// ready before ctx is done. Pass a context with a deadline; a connection in transient failure otherwise waits for
+// gRPC's reconnect backoff indefinitely.
+func (c *Client) Healthy(ctx context.Context) error {
+	if c == nil || c.apiConn == nil {
+		return errors.New("client is not initialized")
+	}
+	apiReady := c.apiConn.waitReady
+	if c.endpoints != nil {
+		apiReady = c.endpoints.waitReady
+	}
+	if err := apiReady(ctx); err != nil {
+		return fmt.Errorf("api: %w", err)
+	}
+	if c.managementConn != nil {
+		if err := waitConnReady(ctx, c.managementConn); err != nil {
+			return fmt.Errorf("management: %w", err)
+		}
+	}
+	return nil
+}
+
 // Close closes all underlying connections.
 func (c *Client) Close() error {
 	if c == nil {
 		return nil
 	}
+	if c.stopMonitor != nil {
+		c.stopMonitor()
+	}
 	var firstErr error
 	if c.managementConn != nil {
 		if err := c.managementConn.Close(); err != nil {
 			firstErr = err
 		}
 	}
-	if c.apiConn != nil {
-		if err := c.apiConn.Close(); err != nil && firstErr == nil {
-			firstErr = err
-		}
+	if err := c.closeAPI(); err != nil && firstErr == nil {
+		firstErr = err
 	}
 	return firstErr
 }
 
+// closeAPI closes the data plane connections.
+func (c *Client) closeAPI() error {
+	switch {
+	case c.endpoints != nil:
+		return c.endpoints.Close()
+	case c.apiConn != nil:
+		return c.apiConn.Close()
+	}
+	return nil
+}
+
+// Endpoints returns the health of the data plane endpoints of [WithEndpoints] in the order given, or nil without
+// fallback endpoints.
+func (c *Client) Endpoints() []EndpointStatus {
+	if c == nil || c.endpoints == nil {
+		return nil
+	}
+	return c.endpoints.status()
+}
+
 // BuildDialOptions builds gRPC dial options based on the provided client options and token.
 func BuildDialOptions(opts *clientOptions, token string) []grpc.DialOption {
-	creds := credentials.NewTLS(&tls.Config{
-		MinVersion: tls.VersionTLS12,
-	})
+	tlsConfig := opts.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	creds := credentials.NewTLS(tlsConfig)
 	if opts.useInsecure {
 		creds = insecure.NewCredentials()
 	}
 
+	// gRPC applies the smaller of the call option and service config limits, so the call options allow the largest
+	// limit and the service config narrows it per service.
+	callOpts := []grpc.CallOption{
+		grpc.MaxCallSendMsgSize(max(opts.maxSendBytes, defaultDocumentMessageBytes)),
+		grpc.MaxCallRecvMsgSize(max(opts.maxRecvBytes, defaultDocumentMessageBytes)),
+	}
+	if opts.compression != "" {
+		callOpts = append(callOpts, grpc.UseCompressor(opts.compression))
+	}
+
 	base := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallSendMsgSize(defaultMaxMessageBytes),
-			grpc.MaxCallRecvMsgSize(defaultMaxMessageBytes),
-		),
-		grpc.WithDefaultServiceConfig(defaultServiceConfig),
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                30 * time.Second,
-			Timeout:             10 * time.Second,
-			PermitWithoutStream: true,
-		}),
+		grpc.WithDefaultCallOptions(callOpts...),
+		grpc.WithDefaultServiceConfig(serviceConfig(opts)),
+		grpc.WithKeepaliveParams(cmp.Or(opts.keepalive, defaultKeepalive)),
 		grpc.WithChainUnaryInterceptor(
 			AuthUnaryInterceptor(token, opts.metadata),
 			TimeoutUnaryInterceptor(opts.timeout),
@@ -190,6 +325,20 @@ func BuildDialOptions(opts *clientOptions, token string) []grpc.DialOption {
 		),
 	}
 
+	if opts.rateLimiter != nil {
+		base = append(base,
+			grpc.WithChainUnaryInterceptor(RateLimitUnaryInterceptor(opts.rateLimiter)),
+			grpc.WithChainStreamInterceptor(RateLimitStreamInterceptor(opts.rateLimiter)),
+		)
+	}
+
+	if opts.logger != nil {
+		base = append(base,
+			grpc.WithChainUnaryInterceptor(LoggingUnaryInterceptor(opts.logger, opts.logOptions)),
+			grpc.WithChainStreamInterceptor(LoggingStreamInterceptor(opts.logger, opts.logOptions)),
+		)
+	}
+
 	if len(opts.dialOptions) > 0 {
 		base = append(base, opts.dialOptions...)
 	}
@@ -0,0 +1,152 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// payloadServer answers Tokenize and Documents.Search requests and records the compression of their headers.
+type payloadServer struct {
+	xaipb.UnimplementedTokenizeServer
+	xaipb.UnimplementedDocumentsServer
+
+	encodings chan string
+}
+
+func (s *payloadServer) TokenizeText(_ context.Context, req *xaipb.TokenizeTextRequest) (*xaipb.TokenizeTextResponse, error) {
+	return &xaipb.TokenizeTextResponse{Model: req.GetModel()}, nil
+}
+
+func (s *payloadServer) Search(context.Context, *xaipb.SearchRequest) (*xaipb.SearchResponse, error) {
+	return &xaipb.SearchResponse{}, nil
+}
+
+func (s *payloadServer) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+
+func (s *payloadServer) HandleRPC(_ context.Context, rs stats.RPCStats) {
+	if h, ok := rs.(*stats.InHeader); ok {
+		s.encodings <- h.Compression
+	}
+}
+
+func (s *payloadServer) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s *payloadServer) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestClientMessageLimits(t *testing.T) {
+	t.Parallel()
+
+	payload := strings.Repeat("a", 24<<20) // above the 20 MiB default
+	tests := map[string]struct {
+		opts         []ClientOption
+		call         func(ctx context.Context, c *Client) error
+		wantCode     codes.Code
+		wantEncoding string
+	}{
+		"default limit": {
+			call: func(ctx context.Context, c *Client) error {
+				_, err := c.Tokenizer.Tokenize(ctx, payload, "grok-4")
+				return err
+			},
+			wantCode: codes.ResourceExhausted,
+		},
+		"raised limit": {
+			opts: []ClientOption{WithMaxSendMsgSize(32 << 20)},
+			call: func(ctx context.Context, c *Client) error {
+				_, err := c.Tokenizer.Tokenize(ctx, payload, "grok-4")
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		"document service default": {
+			call: func(ctx context.Context, c *Client) error {
+				_, err := NewCollectionsClient(c.apiConn, nil).Search(ctx, payload, []string{"col"})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		"gzip": {
+			opts: []ClientOption{WithCompression(CompressionGzip)},
+			call: func(ctx context.Context, c *Client) error {
+				_, err := c.Tokenizer.Tokenize(ctx, "hello", "grok-4")
+				return err
+			},
+			wantCode:     codes.OK,
+			wantEncoding: CompressionGzip,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lis := bufconn.Listen(1 << 20)
+			ps := &payloadServer{encodings: make(chan string, 1)}
+			srv := grpc.NewServer(grpc.MaxRecvMsgSize(64<<20), grpc.StatsHandler(ps))
+			xaipb.RegisterTokenizeServer(srv, ps)
+			xaipb.RegisterDocumentsServer(srv, ps)
+			go func() { _ = srv.Serve(lis) }()
+			t.Cleanup(srv.Stop)
+
+			opts := append([]ClientOption{
+				WithAPIHost("passthrough:///bufnet"),
+				WithInsecure(),
+				WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return lis.DialContext(ctx)
+				})),
+			}, tt.opts...)
+			client, err := NewClient("test-key", opts...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			t.Cleanup(func() { _ = client.Close() })
+
+			err = tt.call(t.Context(), client)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("call error = %v, want code %v", err, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+			if got := <-ps.encodings; got != tt.wantEncoding {
+				t.Fatalf("request encoding = %q, want %q", got, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestNewClientUnknownCompressor(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewClient("test-key", WithInsecure(), WithCompression(CompressionZstd)); err == nil {
+		t.Fatal("NewClient() with an unregistered compressor error = nil, want error")
+	}
+}
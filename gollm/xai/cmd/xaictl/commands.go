@@ -0,0 +1,527 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zchee/tumix/gollm/xai"
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+var commands = map[string]command{
+	"chat": chatCmd,
+	"models": group("models", map[string]command{
+		"list": modelsListCmd,
+	}),
+	"files": group("files", map[string]command{
+		"upload":   filesUploadCmd,
+		"download": filesDownloadCmd,
+		"rm":       filesRmCmd,
+	}),
+	"collections": group("collections", map[string]command{
+		"create": collectionsCreateCmd,
+		"search": collectionsSearchCmd,
+	}),
+	"embed":    embedCmd,
+	"image":    imageCmd,
+	"tokenize": tokenizeCmd,
+	"billing": group("billing", map[string]command{
+		"info":  billingInfoCmd,
+		"usage": billingUsageCmd,
+	}),
+}
+
+// textArg joins the positional arguments, reading stdin instead when there are none or the only one is "-".
+func (c *cli) textArg(args []string) (string, error) {
+	if len(args) > 0 && (len(args) != 1 || args[0] != "-") {
+		return strings.Join(args, " "), nil
+	}
+	data, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return "", fmt.Errorf("read stdin: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", errors.New("no input text")
+	}
+	return text, nil
+}
+
+func chatCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("chat")
+	var (
+		model       = fs.String("model", "grok-4", "model name")
+		system      = fs.String("system", "", "system prompt")
+		temperature = fs.Float64("temperature", -1, "sampling temperature (server default when negative)")
+		maxTokens   = fs.Int("max-tokens", 0, "maximum completion tokens (server default when zero)")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	prompt, err := c.textArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var msgs []*xaipb.Message
+	if *system != "" {
+		msgs = append(msgs, xai.System(*system))
+	}
+	opts := []xai.ChatOption{xai.WithMessages(append(msgs, xai.User(prompt))...)}
+	if *temperature >= 0 {
+		opts = append(opts, xai.WithTemperature(float32(*temperature)))
+	}
+	if *maxTokens > 0 {
+		opts = append(opts, xai.WithMaxTokens(int32(*maxTokens)))
+	}
+
+	resp, err := c.client.Chat.Create(*model, opts...).Completion(ctx)
+	if err != nil {
+		return fmt.Errorf("chat: %w", err)
+	}
+	if *asJSON {
+		return c.printProto(resp.Proto())
+	}
+	_, err = fmt.Fprintln(c.stdout, resp.Content())
+	return err
+}
+
+func modelsListCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("models list")
+	kind := fs.String("kind", "language", "model kind: language, embedding or image")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var names []string
+	switch *kind {
+	case "language":
+		resp, err := c.client.Models.ListLanguageModels(ctx)
+		if err != nil {
+			return fmt.Errorf("list language models: %w", err)
+		}
+		if *asJSON {
+			return c.printProto(resp)
+		}
+		for _, m := range resp.GetModels() {
+			names = append(names, m.GetName())
+		}
+	case "embedding":
+		resp, err := c.client.Models.ListEmbeddingModels(ctx)
+		if err != nil {
+			return fmt.Errorf("list embedding models: %w", err)
+		}
+		if *asJSON {
+			return c.printProto(resp)
+		}
+		for _, m := range resp.GetModels() {
+			names = append(names, m.GetName())
+		}
+	case "image":
+		resp, err := c.client.Models.ListImageGenerationModels(ctx)
+		if err != nil {
+			return fmt.Errorf("list image generation models: %w", err)
+		}
+		if *asJSON {
+			return c.printProto(resp)
+		}
+		for _, m := range resp.GetModels() {
+			names = append(names, m.GetName())
+		}
+	default:
+		return fmt.Errorf("unknown model kind %q", *kind)
+	}
+
+	for _, name := range names {
+		if _, err := fmt.Fprintln(c.stdout, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func filesUploadCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("files upload")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("files upload: no paths")
+	}
+
+	files := &xaipb.ListFilesResponse{}
+	for _, path := range fs.Args() {
+		file, err := c.client.Files.Upload(ctx, path)
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", path, err)
+		}
+		files.Data = append(files.Data, file)
+	}
+	if *asJSON {
+		return c.printProto(files)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, file := range files.GetData() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", file.GetId(), file.GetFilename(), file.GetSize())
+	}
+	return tw.Flush()
+}
+
+func filesDownloadCmd(ctx context.Context, c *cli, args []string) error {
+	fs, _ := c.flagSet("files download")
+	out := fs.String("o", "", "output path (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("files download: want exactly one file id")
+	}
+
+	data, err := c.client.Files.Content(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("download %s: %w", fs.Arg(0), err)
+	}
+	if *out == "" {
+		_, err = c.stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", *out, err)
+	}
+	return nil
+}
+
+func filesRmCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("files rm")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("files rm: no file ids")
+	}
+
+	for _, id := range fs.Args() {
+		resp, err := c.client.Files.Delete(ctx, id)
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", id, err)
+		}
+		if *asJSON {
+			if err := c.printProto(resp); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(c.stdout, "deleted %s\n", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireCollections reports the missing management key instead of a nil dereference.
+func (c *cli) requireCollections() error {
+	if c.client.Collections == nil {
+		return errors.New("collections require a management API key")
+	}
+	return nil
+}
+
+func collectionsCreateCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("collections create")
+	model := fs.String("model", "", "embedding model used to index the collection (server default when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("collections create: want exactly one collection name")
+	}
+	if err := c.requireCollections(); err != nil {
+		return err
+	}
+
+	meta, err := c.client.Collections.Create(ctx, fs.Arg(0), *model, nil)
+	if err != nil {
+		return fmt.Errorf("create collection: %w", err)
+	}
+	if *asJSON {
+		return c.printProto(meta)
+	}
+	_, err = fmt.Fprintln(c.stdout, meta.GetCollectionId())
+	return err
+}
+
+func collectionsSearchCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("collections search")
+	var (
+		ids   = fs.String("collections", "", "comma-separated collection ids to search")
+		limit = fs.Int("limit", 0, "maximum number of matches (server default when zero)")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ids == "" {
+		return errors.New("collections search: -collections is required")
+	}
+	query, err := c.textArg(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := c.requireCollections(); err != nil {
+		return err
+	}
+
+	var opts []xai.DocumentSearchOption
+	if *limit > 0 {
+		opts = append(opts, xai.WithSearchLimit(int32(*limit)))
+	}
+	resp, err := c.client.Collections.Search(ctx, query, strings.Split(*ids, ","), opts...)
+	if err != nil {
+		return fmt.Errorf("search collections: %w", err)
+	}
+	if *asJSON {
+		return c.printProto(resp)
+	}
+	for _, m := range resp.GetMatches() {
+		if _, err := fmt.Fprintf(c.stdout, "%.4f\t%s\t%s\n", m.GetScore(), m.GetFileId(), oneLine(m.GetChunkContent())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func embedCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("embed")
+	model := fs.String("model", "", "embedding model name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *model == "" {
+		return errors.New("embed: -model is required")
+	}
+	texts := fs.Args()
+	if len(texts) == 0 || (len(texts) == 1 && texts[0] == "-") {
+		text, err := c.textArg(nil)
+		if err != nil {
+			return err
+		}
+		texts = []string{text}
+	}
+
+	resp, err := c.client.Embed.CreateStrings(ctx, *model, texts)
+	if err != nil {
+		return fmt.Errorf("embed: %w", err)
+	}
+	if *asJSON {
+		return c.printProto(resp)
+	}
+	for _, emb := range resp.GetEmbeddings() {
+		for _, vec := range emb.GetEmbeddings() {
+			fields := make([]string, len(vec.GetFloatArray()))
+			for i, f := range vec.GetFloatArray() {
+				fields[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+			}
+			if _, err := fmt.Fprintln(c.stdout, strings.Join(fields, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func imageCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("image")
+	var (
+		model = fs.String("model", "grok-2-image", "image generation model name")
+		n     = fs.Int("n", 1, "number of images")
+		out   = fs.String("o", "", "save the images to this path, numbered when -n is above 1 (default print URLs)")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	prompt, err := c.textArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var opts []xai.ImageOption
+	if *out != "" {
+		opts = append(opts, xai.WithImageFormat(xai.ImageFormatBase64))
+	}
+	images, err := c.client.Image.SampleBatch(ctx, prompt, *model, int32(*n), opts...)
+	if err != nil {
+		return fmt.Errorf("generate image: %w", err)
+	}
+	results := make([]imageResult, len(images))
+	for i, img := range images {
+		results[i].Prompt = img.Prompt()
+		if *out == "" {
+			if results[i].URL, err = img.URL(); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := img.Data(ctx)
+		if err != nil {
+			return fmt.Errorf("decode image %d: %w", i, err)
+		}
+		results[i].Path = numberedPath(*out, i, len(images))
+		if err := os.WriteFile(results[i].Path, data, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", results[i].Path, err)
+		}
+	}
+
+	if *asJSON {
+		return c.printJSON(results)
+	}
+	for _, r := range results {
+		if _, err := fmt.Fprintln(c.stdout, cmp.Or(r.Path, r.URL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imageResult describes one generated image in the image command output.
+type imageResult struct {
+	URL    string `json:"url,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// numberedPath inserts "-i" before the extension of path when there are several outputs.
+func numberedPath(path string, i, n int) string {
+	if n <= 1 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+}
+
+func tokenizeCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("tokenize")
+	model := fs.String("model", "grok-4", "model whose tokenizer is used")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	text, err := c.textArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Tokenizer.Tokenize(ctx, text, *model)
+	if err != nil {
+		return fmt.Errorf("tokenize: %w", err)
+	}
+	if *asJSON {
+		return c.printProto(resp)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, tok := range resp.GetTokens() {
+		fmt.Fprintf(tw, "%d\t%q\n", tok.GetTokenId(), tok.GetStringToken())
+	}
+	fmt.Fprintf(tw, "total\t%d\n", len(resp.GetTokens()))
+	return tw.Flush()
+}
+
+// requireBilling checks that billing commands have a management key and a team.
+func (c *cli) requireBilling() error {
+	if c.client.Billing == nil {
+		return errors.New("billing requires a management API key")
+	}
+	if c.teamID == "" {
+		return errors.New("billing requires -team or XAI_TEAM_ID")
+	}
+	return nil
+}
+
+func billingInfoCmd(ctx context.Context, c *cli, args []string) error {
+	fs, _ := c.flagSet("billing info")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.requireBilling(); err != nil {
+		return err
+	}
+
+	info, err := c.client.Billing.GetBillingInfo(ctx, c.teamID)
+	if err != nil {
+		return fmt.Errorf("get billing info: %w", err)
+	}
+	return c.printProto(info)
+}
+
+func billingUsageCmd(ctx context.Context, c *cli, args []string) error {
+	fs, asJSON := c.flagSet("billing usage")
+	var (
+		from    = fs.String("from", "", "first day of the report, YYYY-MM-DD (default 30 days ago)")
+		to      = fs.String("to", "", "day after the report, YYYY-MM-DD (default tomorrow)")
+		groupBy = fs.String("group-by", xai.UsageFieldModel, "comma-separated fields to group usage by")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.requireBilling(); err != nil {
+		return err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start, err := parseDay(*from, today.AddDate(0, 0, -30))
+	if err != nil {
+		return fmt.Errorf("parse -from: %w", err)
+	}
+	end, err := parseDay(*to, today.AddDate(0, 0, 1))
+	if err != nil {
+		return fmt.Errorf("parse -to: %w", err)
+	}
+	var groups []string
+	if *groupBy != "" {
+		groups = strings.Split(*groupBy, ",")
+	}
+
+	report, err := c.client.Billing.UsageReport(ctx, c.teamID, start, end, groups)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(report)
+	}
+	return report.WriteCSV(c.stdout)
+}
+
+// parseDay parses a YYYY-MM-DD day in UTC, returning def for an empty value.
+func parseDay(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// oneLine collapses the whitespace of s so a search match fits on one output line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,218 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Command xaictl exposes the xAI SDK on the command line.
+//
+// Usage:
+//
+//	xaictl [global flags] <command> [command flags] [args]
+//
+// Commands:
+//
+//	chat                 sample a chat completion for a prompt
+//	models list          list language, embedding and image generation models
+//	files upload         upload local files
+//	files download       download a file's content
+//	files rm             delete files
+//	collections create   create a document collection
+//	collections search   search document collections
+//	embed                embed texts
+//	image                generate images
+//	tokenize             tokenize text
+//	billing info         show the team billing information
+//	billing usage        show the team API usage per day
+//
+// The API key is read from -api-key or XAI_API_KEY, the management key (required by collections and billing) from
+// -management-key or XAI_MANAGEMENT_KEY. Every command accepts -json to print the raw API response as JSON.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/zchee/tumix/gollm/xai"
+)
+
+const usage = `usage: xaictl [global flags] <command> [command flags] [args]
+
+commands:
+  chat                 sample a chat completion for a prompt
+  models list          list language, embedding and image generation models
+  files upload         upload local files
+  files download       download a file's content
+  files rm             delete files
+  collections create   create a document collection
+  collections search   search document collections
+  embed                embed texts
+  image                generate images
+  tokenize             tokenize text
+  billing info         show the team billing information
+  billing usage        show the team API usage per day
+
+global flags:
+`
+
+// errUsage reports invalid command line arguments; the usage has already been printed.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr, xai.NewClient)
+	stop()
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "xaictl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// dialFunc constructs the SDK client; tests substitute an in-process server.
+type dialFunc func(apiKey string, opts ...xai.ClientOption) (*xai.Client, error)
+
+// cli carries the state shared by every command.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	client *xai.Client
+	teamID string
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer, dial dialFunc) error {
+	fs := flag.NewFlagSet("xaictl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	var (
+		apiKey         = fs.String("api-key", "", "xAI API key (default $XAI_API_KEY)")
+		managementKey  = fs.String("management-key", "", "xAI management API key (default $XAI_MANAGEMENT_KEY)")
+		apiHost        = fs.String("api-host", "", "override the API host")
+		managementHost = fs.String("management-host", "", "override the management API host")
+		teamID         = fs.String("team", os.Getenv("XAI_TEAM_ID"), "team id for billing commands (default $XAI_TEAM_ID)")
+		timeout        = fs.Duration("timeout", 2*time.Minute, "overall timeout of the command")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "xaictl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+
+	var opts []xai.ClientOption
+	if *managementKey != "" {
+		opts = append(opts, xai.WithManagementAPIKey(*managementKey))
+	}
+	if *apiHost != "" {
+		opts = append(opts, xai.WithAPIHost(*apiHost))
+	}
+	if *managementHost != "" {
+		opts = append(opts, xai.WithManagementAPIHost(*managementHost))
+	}
+	client, err := dial(*apiKey, opts...)
+	if err != nil {
+		return fmt.Errorf("create client: %w", err)
+	}
+	defer client.Close()
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	c := &cli{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		client: client,
+		teamID: *teamID,
+	}
+	return cmd(ctx, c, fs.Args()[1:])
+}
+
+// command runs one (possibly nested) subcommand with the arguments following its name.
+type command func(ctx context.Context, c *cli, args []string) error
+
+// group dispatches to the named subcommand of a command group such as "files".
+func group(name string, subs map[string]command) command {
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) == 0 {
+			fmt.Fprintf(c.stderr, "xaictl: %s requires a subcommand\n", name)
+			return errUsage
+		}
+		sub, ok := subs[args[0]]
+		if !ok {
+			fmt.Fprintf(c.stderr, "xaictl: unknown %s subcommand %q\n", name, args[0])
+			return errUsage
+		}
+		return sub(ctx, c, args[1:])
+	}
+}
+
+// flagSet returns the flag set of a subcommand, with the shared -json flag registered.
+func (c *cli) flagSet(name string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet("xaictl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs, fs.Bool("json", false, "print the raw response as JSON")
+}
+
+// printProto writes m as indented JSON followed by a newline.
+//
+// The protojson output is re-indented with encoding/json, since protojson deliberately randomizes its whitespace.
+func (c *cli) printProto(m proto.Message) error {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return fmt.Errorf("indent response: %w", err)
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(c.stdout)
+	return err
+}
+
+// printJSON writes v as indented JSON followed by a newline.
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
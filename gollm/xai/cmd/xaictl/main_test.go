@@ -0,0 +1,194 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/zchee/tumix/gollm/xai"
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+type fakeModelsServer struct {
+	xaipb.UnimplementedModelsServer
+}
+
+func (fakeModelsServer) ListLanguageModels(context.Context, *emptypb.Empty) (*xaipb.ListLanguageModelsResponse, error) {
+	return &xaipb.ListLanguageModelsResponse{Models: []*xaipb.LanguageModel{{Name: "grok-4"}, {Name: "grok-3-mini"}}}, nil
+}
+
+type fakeTokenizeServer struct {
+	xaipb.UnimplementedTokenizeServer
+}
+
+func (fakeTokenizeServer) TokenizeText(_ context.Context, req *xaipb.TokenizeTextRequest) (*xaipb.TokenizeTextResponse, error) {
+	resp := &xaipb.TokenizeTextResponse{Model: req.GetModel()}
+	for i, word := range strings.Fields(req.GetText()) {
+		resp.Tokens = append(resp.Tokens, &xaipb.Token{TokenId: uint32(i + 1), StringToken: word})
+	}
+	return resp, nil
+}
+
+type fakeFilesServer struct {
+	xaipb.UnimplementedFilesServer
+}
+
+func (fakeFilesServer) DeleteFile(_ context.Context, req *xaipb.DeleteFileRequest) (*xaipb.DeleteFileResponse, error) {
+	return &xaipb.DeleteFileResponse{Id: req.GetFileId(), Deleted: true}, nil
+}
+
+// testDial serves the fake services over an in-memory listener.
+func testDial(t *testing.T) dialFunc {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	xaipb.RegisterModelsServer(srv, fakeModelsServer{})
+	xaipb.RegisterTokenizeServer(srv, fakeTokenizeServer{})
+	xaipb.RegisterFilesServer(srv, fakeFilesServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return func(apiKey string, opts ...xai.ClientOption) (*xai.Client, error) {
+		opts = append(opts,
+			xai.WithAPIHost("passthrough:///bufnet"),
+			xai.WithInsecure(),
+			xai.WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			})),
+		)
+		if apiKey == "" {
+			apiKey = "test-key"
+		}
+		return xai.NewClient(apiKey, opts...)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args    []string
+		stdin   string
+		want    string
+		wantErr error
+	}{
+		"models list": {
+			args: []string{"models", "list"},
+			want: "grok-4\ngrok-3-mini\n",
+		},
+		"tokenize args": {
+			args: []string{"tokenize", "-model", "grok-4", "hello", "world"},
+			want: "1      \"hello\"\n2      \"world\"\ntotal  2\n",
+		},
+		"tokenize stdin json": {
+			args:  []string{"tokenize", "-json", "-"},
+			stdin: "hi\n",
+			want:  "{\n  \"tokens\": [\n    {\n      \"tokenId\": 1,\n      \"stringToken\": \"hi\"\n    }\n  ],\n  \"model\": \"grok-4\"\n}\n",
+		},
+		"files rm": {
+			args: []string{"files", "rm", "file-1", "file-2"},
+			want: "deleted file-1\ndeleted file-2\n",
+		},
+		"no command": {
+			wantErr: errUsage,
+		},
+		"unknown command": {
+			args:    []string{"nope"},
+			wantErr: errUsage,
+		},
+		"missing subcommand": {
+			args:    []string{"files"},
+			wantErr: errUsage,
+		},
+		"unknown subcommand": {
+			args:    []string{"collections", "drop"},
+			wantErr: errUsage,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr strings.Builder
+			err := run(t.Context(), tt.args, strings.NewReader(tt.stdin), &stdout, &stderr, testDial(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("run() error = %v, want %v (stderr %q)", err, tt.wantErr, stderr.String())
+			}
+			if diff := cmp.Diff(tt.want, stdout.String()); diff != "" {
+				t.Fatalf("run() output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunRequiresManagementKey(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]string{
+		"collections create": {"collections", "create", "docs"},
+		"billing info":       {"-team", "team-1", "billing", "info"},
+		"billing usage":      {"-team", "team-1", "billing", "usage"},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr strings.Builder
+			err := run(t.Context(), args, strings.NewReader(""), &stdout, &stderr, testDial(t))
+			if err == nil || !strings.Contains(err.Error(), "management API key") {
+				t.Fatalf("run(%v) error = %v, want management key error", args, err)
+			}
+		})
+	}
+}
+
+func TestNumberedPath(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		path string
+		i, n int
+		want string
+	}{
+		"single":    {path: "out.png", i: 0, n: 1, want: "out.png"},
+		"first":     {path: "out.png", i: 0, n: 3, want: "out-1.png"},
+		"no ext":    {path: "dir/out", i: 1, n: 2, want: "dir/out-2"},
+		"dotted in": {path: "a.b/out.jpg", i: 2, n: 3, want: "a.b/out-3.jpg"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := numberedPath(tt.path, tt.i, tt.n); got != tt.want {
+				t.Fatalf("numberedPath(%q, %d, %d) = %q, want %q", tt.path, tt.i, tt.n, got, tt.want)
+			}
+		})
+	}
+}
@@ -58,6 +58,9 @@ const (
 type CollectionsClient struct {
 	collections collectionspb.CollectionsClient
 	documents   xaipb.DocumentsClient
+	// chat and embed re-score matches in SearchAndRerank.
+	chat  *ChatClient
+	embed *EmbedClient
 }
 
 type collectionsOption func(*collectionsRequest)
@@ -72,6 +75,7 @@ type DocumentSearchOption func(*documentSearchRequest)
 type documentSearchRequest struct {
 	limit         *int32
 	rankingMetric *xaipb.RankingMetric
+	callOpts      []grpc.CallOption
 }
 
 // WithSearchLimit sets the maximum number of results returned.
@@ -92,6 +96,13 @@ func WithRankingMetric(metric xaipb.RankingMetric) DocumentSearchOption {
 	}
 }
 
+// WithSearchCallOptions passes opts, such as [WithCallTimeout], to the search RPC.
+func WithSearchCallOptions(opts ...grpc.CallOption) DocumentSearchOption {
+	return func(r *documentSearchRequest) {
+		r.callOpts = append(r.callOpts, opts...)
+	}
+}
+
 // WithTeamID sets an explicit team id on management requests.
 func WithTeamID(teamID string) collectionsOption {
 	return func(r *collectionsRequest) {
@@ -118,7 +129,7 @@ func applyDocumentSearchOptions(opts []DocumentSearchOption) documentSearchReque
 }
 
 // NewCollectionsClient builds a client. managementConn is required for collection mutations.
-func NewCollectionsClient(apiConn, managementConn *grpc.ClientConn) *CollectionsClient {
+func NewCollectionsClient(apiConn grpc.ClientConnInterface, managementConn *grpc.ClientConn) *CollectionsClient {
 	var collections collectionspb.CollectionsClient
 	if managementConn != nil {
 		collections = collectionspb.NewCollectionsClient(managementConn)
@@ -126,6 +137,13 @@ func NewCollectionsClient(apiConn, managementConn *grpc.ClientConn) *Collections
 	return &CollectionsClient{
 		collections: collections,
 		documents:   xaipb.NewDocumentsClient(apiConn),
+		chat: &ChatClient{
+			chat:     xaipb.NewChatClient(apiConn),
+			tokenize: xaipb.NewTokenizeClient(apiConn),
+		},
+		embed: &EmbedClient{
+			embedder: xaipb.NewEmbedderClient(apiConn),
+		},
 	}
 }
 
@@ -288,7 +306,7 @@ func (c *CollectionsClient) Search(ctx context.Context, query string, collection
 		req.Limit = params.limit
 	}
 
-	resp, err := c.documents.Search(ctx, req)
+	resp, err := c.documents.Search(ctx, req, params.callOpts...)
 	return resp, WrapError(err)
 }
 
@@ -0,0 +1,99 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// The Documents service exposes chunks only through search matches, so the chunk accessors below work on the
+// matches of a search rather than fetching chunks by id from the server.
+
+// DocumentChunks searches the collections for query and returns the matching chunks of the document fileID, in
+// search order. Raise [WithSearchLimit] to preview more of the document.
+func (c *CollectionsClient) DocumentChunks(ctx context.Context, query string, collectionIDs []string, fileID string, opts ...DocumentSearchOption) ([]*xaipb.SearchMatch, error) {
+	resp, err := c.Search(ctx, query, collectionIDs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []*xaipb.SearchMatch
+	for _, m := range resp.GetMatches() {
+		if m.GetFileId() == fileID {
+			chunks = append(chunks, m)
+		}
+	}
+	return chunks, nil
+}
+
+// FindChunk returns the match of matches with the chunk id.
+func FindChunk(matches []*xaipb.SearchMatch, chunkID string) (*xaipb.SearchMatch, bool) {
+	for _, m := range matches {
+		if m.GetChunkId() == chunkID {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// MatchesToContext assembles the chunks of matches into a context block for a prompt, in the order given, each
+// headed by its position, file id, and chunk id. It stops before the first chunk that would exceed maxTokens; zero
+// means no budget.
+//
+// countTokens counts the tokens of a chunk; nil estimates four bytes per token. Use
+// [CollectionsClient.MatchesToContext] to count with the Tokenizer service.
+func MatchesToContext(ctx context.Context, matches []*xaipb.SearchMatch, maxTokens int, countTokens func(ctx context.Context, text string) (int, error)) (string, error) {
+	if countTokens == nil {
+		countTokens = func(_ context.Context, text string) (int, error) { return (len(text) + 3) / 4, nil }
+	}
+
+	var b strings.Builder
+	used := 0
+	for i, m := range matches {
+		chunk := fmt.Sprintf("[%d] file_id=%s chunk_id=%s\n%s\n\n", i+1, m.GetFileId(), m.GetChunkId(), strings.TrimSpace(m.GetChunkContent()))
+		if maxTokens > 0 {
+			n, err := countTokens(ctx, chunk)
+			if err != nil {
+				return "", fmt.Errorf("count tokens of chunk %s: %w", m.GetChunkId(), err)
+			}
+			if used+n > maxTokens {
+				break
+			}
+			used += n
+		}
+		b.WriteString(chunk)
+	}
+	return strings.TrimSuffix(b.String(), "\n\n"), nil
+}
+
+// MatchesToContext is [MatchesToContext] counting tokens with the Tokenizer service for model.
+func (c *CollectionsClient) MatchesToContext(ctx context.Context, model string, matches []*xaipb.SearchMatch, maxTokens int) (string, error) {
+	var countTokens func(ctx context.Context, text string) (int, error)
+	if c.chat != nil && c.chat.tokenize != nil {
+		countTokens = func(ctx context.Context, text string) (int, error) {
+			resp, err := c.chat.tokenize.TokenizeText(ctx, &xaipb.TokenizeTextRequest{Text: text, Model: model})
+			if err != nil {
+				return 0, WrapError(err)
+			}
+			return len(resp.GetTokens()), nil
+		}
+	}
+	return MatchesToContext(ctx, matches, maxTokens, countTokens)
+}
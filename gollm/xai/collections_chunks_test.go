@@ -0,0 +1,117 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// wordTokenizer returns one token per word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) TokenizeText(_ context.Context, in *xaipb.TokenizeTextRequest, _ ...grpc.CallOption) (*xaipb.TokenizeTextResponse, error) {
+	resp := &xaipb.TokenizeTextResponse{}
+	for _, w := range strings.Fields(in.GetText()) {
+		resp.Tokens = append(resp.Tokens, &xaipb.Token{StringToken: w})
+	}
+	return resp, nil
+}
+
+func TestDocumentChunks(t *testing.T) {
+	t.Parallel()
+
+	docs := &fakeDocuments{matches: []*xaipb.SearchMatch{
+		{FileId: "f1", ChunkId: "c1"},
+		{FileId: "f2", ChunkId: "c2"},
+		{FileId: "f1", ChunkId: "c3"},
+	}}
+	client := &CollectionsClient{documents: docs}
+	got, err := client.DocumentChunks(t.Context(), "query", []string{"col"}, "f1", WithSearchLimit(50))
+	if err != nil {
+		t.Fatalf("DocumentChunks() error = %v", err)
+	}
+	var ids []string
+	for _, m := range got {
+		ids = append(ids, m.GetChunkId())
+	}
+	if diff := cmp.Diff([]string{"c1", "c3"}, ids); diff != "" {
+		t.Fatalf("DocumentChunks() mismatch (-want +got):\n%s", diff)
+	}
+	if limit := docs.req.GetLimit(); limit != 50 {
+		t.Fatalf("search limit = %d, want 50", limit)
+	}
+
+	if m, ok := FindChunk(docs.matches, "c2"); !ok || m.GetFileId() != "f2" {
+		t.Fatalf("FindChunk(c2) = (%v, %t)", m, ok)
+	}
+	if _, ok := FindChunk(docs.matches, "missing"); ok {
+		t.Fatal("FindChunk(missing) found a chunk")
+	}
+}
+
+func TestMatchesToContext(t *testing.T) {
+	t.Parallel()
+
+	matches := []*xaipb.SearchMatch{
+		{FileId: "f1", ChunkId: "c1", ChunkContent: " one two \n"},
+		{FileId: "f2", ChunkId: "c2", ChunkContent: "three four five"},
+		{FileId: "f3", ChunkId: "c3", ChunkContent: "six"},
+	}
+
+	// Every chunk costs three header words plus its content words.
+	tests := map[string]struct {
+		maxTokens int
+		want      string
+	}{
+		"no budget": {
+			want: "[1] file_id=f1 chunk_id=c1\none two\n\n[2] file_id=f2 chunk_id=c2\nthree four five\n\n[3] file_id=f3 chunk_id=c3\nsix",
+		},
+		"budget stops at overflow": {
+			maxTokens: 10,
+			want:      "[1] file_id=f1 chunk_id=c1\none two",
+		},
+		"budget fits all": {
+			maxTokens: 15,
+			want:      "[1] file_id=f1 chunk_id=c1\none two\n\n[2] file_id=f2 chunk_id=c2\nthree four five\n\n[3] file_id=f3 chunk_id=c3\nsix",
+		},
+		"budget below first chunk": {
+			maxTokens: 4,
+			want:      "",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &CollectionsClient{chat: &ChatClient{tokenize: wordTokenizer{}}}
+			got, err := client.MatchesToContext(t.Context(), "grok-4", matches, tt.maxTokens)
+			if err != nil {
+				t.Fatalf("MatchesToContext() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("MatchesToContext() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// defaultRerankCandidates is how many matches [CollectionsClient.SearchAndRerank] fetches to re-score.
+const defaultRerankCandidates = 20
+
+// maxRerankScore is the top of the relevance scale of the listwise rerank prompt.
+const maxRerankScore = 10
+
+const rerankSystemPrompt = `You rank passages by how well they answer a search query.
+Score every passage from 0 (irrelevant) to 10 (fully answers the query), judging the passages against each other.
+Return one score per passage, identified by its index.`
+
+// RerankOption customizes [CollectionsClient.SearchAndRerank].
+type RerankOption func(*rerankRequest)
+
+type rerankRequest struct {
+	model          string
+	embeddingModel string
+	candidates     int32
+	limit          int
+	searchOpts     []DocumentSearchOption
+}
+
+// WithRerankModel re-scores the matches with a listwise prompt to the chat model.
+func WithRerankModel(model string) RerankOption {
+	return func(r *rerankRequest) { r.model = model }
+}
+
+// WithRerankEmbeddingModel re-scores the matches by the cosine similarity of their embeddings to the query
+// embedding under the model. With [WithRerankModel] too, the scores of both are averaged.
+func WithRerankEmbeddingModel(model string) RerankOption {
+	return func(r *rerankRequest) { r.embeddingModel = model }
+}
+
+// WithRerankCandidates sets how many matches are fetched to re-score, 20 by default.
+func WithRerankCandidates(k int32) RerankOption {
+	return func(r *rerankRequest) {
+		if k > 0 {
+			r.candidates = k
+		}
+	}
+}
+
+// WithRerankLimit sets the maximum number of ranked matches returned; all candidates by default.
+func WithRerankLimit(n int) RerankOption {
+	return func(r *rerankRequest) {
+		if n > 0 {
+			r.limit = n
+		}
+	}
+}
+
+// WithRerankSearchOptions passes opts to the search fetching the candidates.
+func WithRerankSearchOptions(opts ...DocumentSearchOption) RerankOption {
+	return func(r *rerankRequest) { r.searchOpts = append(r.searchOpts, opts...) }
+}
+
+// RankedMatch is a search match with its re-ranked score.
+type RankedMatch struct {
+	Match *xaipb.SearchMatch
+	// Score is the calibrated relevance in [0, 1]: the listwise score divided by 10, the cosine similarity clamped at
+	// zero, or their mean.
+	Score float64
+}
+
+// rerankScores is the structured output of the listwise rerank prompt.
+type rerankScores struct {
+	Scores []rerankScore `json:"scores"`
+}
+
+type rerankScore struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// SearchAndRerank searches the collections for the top candidates of query and re-scores them with the chat model
+// of [WithRerankModel], the embedding model of [WithRerankEmbeddingModel], or both, returning the matches by
+// descending score. Matches with equal scores keep the search order.
+func (c *CollectionsClient) SearchAndRerank(ctx context.Context, query string, collectionIDs []string, opts ...RerankOption) ([]RankedMatch, error) {
+	params := rerankRequest{candidates: defaultRerankCandidates}
+	for _, opt := range opts {
+		opt(&params)
+	}
+	if params.model == "" && params.embeddingModel == "" {
+		return nil, errors.New("rerank: WithRerankModel or WithRerankEmbeddingModel is required")
+	}
+
+	resp, err := c.Search(ctx, query, collectionIDs, append(params.searchOpts, WithSearchLimit(params.candidates))...)
+	if err != nil {
+		return nil, fmt.Errorf("rerank: search: %w", err)
+	}
+	matches := resp.GetMatches()
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	var scores [][]float64
+	if params.model != "" {
+		s, err := c.rerankListwise(ctx, params.model, query, matches)
+		if err != nil {
+			return nil, fmt.Errorf("rerank: %s: %w", params.model, err)
+		}
+		scores = append(scores, s)
+	}
+	if params.embeddingModel != "" {
+		s, err := c.rerankEmbeddings(ctx, params.embeddingModel, query, matches)
+		if err != nil {
+			return nil, fmt.Errorf("rerank: %s: %w", params.embeddingModel, err)
+		}
+		scores = append(scores, s)
+	}
+
+	ranked := make([]RankedMatch, len(matches))
+	for i, m := range matches {
+		var sum float64
+		for _, s := range scores {
+			sum += s[i]
+		}
+		ranked[i] = RankedMatch{Match: m, Score: sum / float64(len(scores))}
+	}
+	slices.SortStableFunc(ranked, func(a, b RankedMatch) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if params.limit > 0 && len(ranked) > params.limit {
+		ranked = ranked[:params.limit]
+	}
+	return ranked, nil
+}
+
+// rerankListwise scores every match in [0, 1] with one listwise prompt to model. Matches the model leaves out
+// score zero.
+func (c *CollectionsClient) rerankListwise(ctx context.Context, model, query string, matches []*xaipb.SearchMatch) ([]float64, error) {
+	if c.chat == nil {
+		return nil, errors.New("chat client is not configured")
+	}
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Query: %s\n", query)
+	for i, m := range matches {
+		fmt.Fprintf(&prompt, "\nPassage %d:\n%s\n", i, m.GetChunkContent())
+	}
+
+	session := c.chat.Create(model, WithMessages(System(rerankSystemPrompt), User(prompt.String())), WithTemperature(0))
+	var out rerankScores
+	if _, err := session.Parse(ctx, &out); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(matches))
+	for _, s := range out.Scores {
+		if s.Index >= 0 && s.Index < len(scores) {
+			scores[s.Index] = min(max(s.Score, 0), maxRerankScore) / maxRerankScore
+		}
+	}
+	return scores, nil
+}
+
+// rerankEmbeddings scores every match by the cosine similarity of its embedding to the query embedding, clamped to
+// [0, 1].
+func (c *CollectionsClient) rerankEmbeddings(ctx context.Context, model, query string, matches []*xaipb.SearchMatch) ([]float64, error) {
+	if c.embed == nil {
+		return nil, errors.New("embed client is not configured")
+	}
+	texts := make([]string, 0, len(matches)+1)
+	texts = append(texts, query)
+	for _, m := range matches {
+		texts = append(texts, m.GetChunkContent())
+	}
+	resp, err := c.embed.CreateStrings(ctx, model, texts)
+	if err != nil {
+		return nil, err
+	}
+	vecs, err := EmbeddingVectors(resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(vecs), len(texts))
+	}
+	scores := make([]float64, len(matches))
+	for i, v := range vecs[1:] {
+		if len(v) == len(vecs[0]) {
+			scores[i] = max(CosineSimilarity(vecs[0], v), 0)
+		}
+	}
+	return scores, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+type fakeDocuments struct {
+	matches []*xaipb.SearchMatch
+	req     *xaipb.SearchRequest
+}
+
+func (d *fakeDocuments) Search(_ context.Context, in *xaipb.SearchRequest, _ ...grpc.CallOption) (*xaipb.SearchResponse, error) {
+	d.req = in
+	return &xaipb.SearchResponse{Matches: d.matches}, nil
+}
+
+// vectorEmbedder embeds each input as vectors[input].
+type vectorEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e vectorEmbedder) Embed(_ context.Context, in *xaipb.EmbedRequest, _ ...grpc.CallOption) (*xaipb.EmbedResponse, error) {
+	resp := &xaipb.EmbedResponse{}
+	for i, input := range in.GetInput() {
+		resp.Embeddings = append(resp.Embeddings, &xaipb.Embedding{
+			Index:      int32(i),
+			Embeddings: []*xaipb.FeatureVector{{FloatArray: e.vectors[input.GetString_()]}},
+		})
+	}
+	return resp, nil
+}
+
+func TestSearchAndRerank(t *testing.T) {
+	t.Parallel()
+
+	matches := []*xaipb.SearchMatch{
+		{ChunkId: "a", ChunkContent: "alpha", Score: 0.9},
+		{ChunkId: "b", ChunkContent: "beta", Score: 0.8},
+		{ChunkId: "c", ChunkContent: "gamma", Score: 0.7},
+	}
+	embedder := vectorEmbedder{vectors: map[string][]float32{
+		"query": {1, 0},
+		"alpha": {0, 1},
+		"beta":  {1, 0},
+		"gamma": {1, 1},
+	}}
+
+	tests := map[string]struct {
+		opts    []RerankOption
+		reply   string
+		want    map[string]float64
+		order   []string
+		wantErr bool
+	}{
+		"listwise": {
+			opts:  []RerankOption{WithRerankModel("grok-3-mini")},
+			reply: `{"scores":[{"index":0,"score":2},{"index":1,"score":9},{"index":2,"score":15}]}`,
+			order: []string{"c", "b", "a"},
+			want:  map[string]float64{"a": 0.2, "b": 0.9, "c": 1},
+		},
+		"listwise missing index": {
+			opts:  []RerankOption{WithRerankModel("grok-3-mini"), WithRerankLimit(2)},
+			reply: `{"scores":[{"index":2,"score":5}]}`,
+			order: []string{"c", "a"},
+			want:  map[string]float64{"a": 0, "c": 0.5},
+		},
+		"embedding": {
+			opts:  []RerankOption{WithRerankEmbeddingModel("grok-embed")},
+			order: []string{"b", "c", "a"},
+			want:  map[string]float64{"a": 0, "b": 1, "c": 0.7071067811865475},
+		},
+		"both averaged": {
+			opts:  []RerankOption{WithRerankModel("grok-3-mini"), WithRerankEmbeddingModel("grok-embed")},
+			reply: `{"scores":[{"index":0,"score":10},{"index":1,"score":0},{"index":2,"score":0}]}`,
+			order: []string{"a", "b", "c"},
+			want:  map[string]float64{"a": 0.5, "b": 0.5, "c": 0.35355339059327373},
+		},
+		"no reranker": {
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			docs := &fakeDocuments{matches: matches}
+			chat := &recordingChatClient{reply: tt.reply}
+			client := &CollectionsClient{
+				documents: docs,
+				chat:      &ChatClient{chat: chat},
+				embed:     &EmbedClient{embedder: embedder},
+			}
+			got, err := client.SearchAndRerank(t.Context(), "query", []string{"col"}, append(tt.opts, WithRerankCandidates(3))...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchAndRerank() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if limit := docs.req.GetLimit(); limit != 3 {
+				t.Fatalf("search limit = %d, want 3", limit)
+			}
+
+			var order []string
+			scores := make(map[string]float64, len(got))
+			for _, m := range got {
+				order = append(order, m.Match.GetChunkId())
+				scores[m.Match.GetChunkId()] = m.Score
+			}
+			if diff := cmp.Diff(tt.order, order); diff != "" {
+				t.Fatalf("SearchAndRerank() order mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want, scores); diff != "" {
+				t.Fatalf("SearchAndRerank() scores mismatch (-want +got):\n%s", diff)
+			}
+			if len(chat.requests) > 0 {
+				prompt := messageText(chat.requests[0].GetMessages()[1])
+				if !strings.Contains(prompt, "Query: query") || !strings.Contains(prompt, "Passage 2:\ngamma") {
+					t.Fatalf("rerank prompt = %q", prompt)
+				}
+			}
+		})
+	}
+}
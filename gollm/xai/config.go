@@ -17,12 +17,16 @@
 package xai
 
 import (
+	"crypto/tls"
+	"log/slog"
 	"maps"
+	"net/url"
 	"runtime"
 	"runtime/debug"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 const (
@@ -33,25 +37,68 @@ const (
 )
 
 const (
-	// APIHost is the default host for the data plane API.
-	defaultMaxMessageBytes int           = 20 << 20 // 20 MiB
-	defaultTimeout         time.Duration = 15 * time.Minute
+	// defaultMaxMessageBytes is the default message size limit of the RPCs.
+	defaultMaxMessageBytes int = 20 << 20 // 20 MiB
+	// defaultDocumentMessageBytes is the minimum message size limit of the Files, Documents, and Collections
+	// services, which carry whole documents and their search results.
+	defaultDocumentMessageBytes int           = 128 << 20 // 128 MiB
+	defaultTimeout              time.Duration = 15 * time.Minute
+)
+
+// Compressors accepted by [WithCompression].
+const (
+	// CompressionGzip is the gzip compressor, always registered.
+	CompressionGzip = "gzip"
+	// CompressionZstd is the zstd compressor. gRPC does not ship one, so a package registering it under this name
+	// with encoding.RegisterCompressor must be imported.
+	CompressionZstd = "zstd"
 )
 
+// defaultKeepalive pings idle connections so intermediaries do not silently drop them.
+var defaultKeepalive = keepalive.ClientParameters{
+	Time:                30 * time.Second,
+	Timeout:             10 * time.Second,
+	PermitWithoutStream: true,
+}
+
 // ClientOption configures the xAI client.
 type ClientOption func(*clientOptions)
 
 type clientOptions struct {
-	apiKey         string
-	managementKey  string
-	apiHost        string
-	managementHost string
-	metadata       map[string]string
-	dialOptions    []grpc.DialOption
-	apiConn        *grpc.ClientConn
-	managementConn *grpc.ClientConn
-	useInsecure    bool
-	timeout        time.Duration
+	apiKey              string
+	managementKey       string
+	apiHost             string
+	managementHost      string
+	metadata            map[string]string
+	dialOptions         []grpc.DialOption
+	apiConn             *grpc.ClientConn
+	managementConn      *grpc.ClientConn
+	useInsecure         bool
+	timeout             time.Duration
+	maxSendBytes        int
+	maxRecvBytes        int
+	compression         string
+	chatMiddleware      []ChatMiddleware
+	rateLimiter         *RateLimiter
+	keepalive           keepalive.ClientParameters
+	poolSize            int
+	autoReconnect       bool
+	traceEncrypted      bool
+	spanContent         SpanContentPolicy
+	proxy               string
+	proxyURL            *url.URL
+	noProxy             bool
+	tlsConfig           *tls.Config
+	caFile              string
+	certFile            string
+	keyFile             string
+	apiAuthority        string
+	managementAuthority string
+	fallbacks           []string
+	endpointPolicy      EndpointPolicy
+	healthInterval      time.Duration
+	logger              *slog.Logger
+	logOptions          LogOptions
 }
 
 // DefaultClientOptions returns the default client configuration.
@@ -63,7 +110,12 @@ func DefaultClientOptions() *clientOptions {
 			"xai-sdk-version":  "go/" + sdkVersion(),
 			"xai-sdk-language": "go/" + runtime.Version(),
 		},
-		timeout: defaultTimeout,
+		timeout:        defaultTimeout,
+		maxSendBytes:   defaultMaxMessageBytes,
+		maxRecvBytes:   defaultMaxMessageBytes,
+		keepalive:      defaultKeepalive,
+		poolSize:       1,
+		healthInterval: defaultHealthCheckInterval,
 	}
 }
 
@@ -155,11 +207,115 @@ func WithInsecure() ClientOption {
 	}
 }
 
-// WithTimeout sets the default RPC timeout applied when no deadline is present on the context.
-func WithTimeout(timeout time.Duration) ClientOption {
+// WithDefaultTimeout sets the deadline of every RPC, unary or streaming, whose context has none. It defaults to 15
+// minutes; a [WithCallTimeout] call option replaces it for a single RPC.
+func WithDefaultTimeout(timeout time.Duration) ClientOption {
 	return func(o *clientOptions) {
 		if timeout > 0 {
 			o.timeout = timeout
 		}
 	}
 }
+
+// WithTimeout is an alias of [WithDefaultTimeout].
+func WithTimeout(timeout time.Duration) ClientOption {
+	return WithDefaultTimeout(timeout)
+}
+
+// CallOption overrides a client default for a single RPC. It is a [grpc.CallOption], so it is accepted wherever the
+// client methods and the generated service clients take call options.
+type CallOption struct {
+	grpc.EmptyCallOption
+
+	timeout time.Duration
+}
+
+// WithCallTimeout bounds a single RPC, or the whole of a stream, by timeout instead of the client default timeout.
+// An earlier deadline of the context still applies.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return CallOption{timeout: timeout}
+}
+
+// callTimeout returns the timeout of the last [CallOption] of opts setting one, or zero.
+func callTimeout(opts []grpc.CallOption) time.Duration {
+	var timeout time.Duration
+	for _, opt := range opts {
+		if co, ok := opt.(CallOption); ok && co.timeout > 0 {
+			timeout = co.timeout
+		}
+	}
+	return timeout
+}
+
+// WithMaxSendMsgSize sets the largest request message the client sends, 20 MiB by default. The Files, Documents, and
+// Collections services allow at least 128 MiB.
+func WithMaxSendMsgSize(bytes int) ClientOption {
+	return func(o *clientOptions) {
+		if bytes > 0 {
+			o.maxSendBytes = bytes
+		}
+	}
+}
+
+// WithMaxRecvMsgSize sets the largest response message the client accepts, 20 MiB by default. The Files, Documents,
+// and Collections services allow at least 128 MiB.
+func WithMaxRecvMsgSize(bytes int) ClientOption {
+	return func(o *clientOptions) {
+		if bytes > 0 {
+			o.maxRecvBytes = bytes
+		}
+	}
+}
+
+// WithCompression compresses the requests of both the data plane and management clients with the named gRPC
+// compressor, such as [CompressionGzip]. The servers may then compress their responses with it too. [NewClient] fails
+// when no compressor is registered under name.
+func WithCompression(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.compression = name
+	}
+}
+
+// WithKeepalive overrides the keepalive parameters of the client connections.
+//
+// The server may close connections that ping more often than it permits, so keep params.Time at 10 seconds or more.
+func WithKeepalive(params keepalive.ClientParameters) ClientOption {
+	return func(o *clientOptions) {
+		o.keepalive = params
+	}
+}
+
+// WithConnPoolSize opens size data plane connections and spreads RPCs over them in round-robin order.
+//
+// It is ignored when the connection is injected with [WithAPIConn].
+func WithConnPoolSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		if size > 0 {
+			o.poolSize = size
+		}
+	}
+}
+
+// WithAutoReconnect monitors the channel state of the client connections and reconnects them as soon as they go
+// idle, rather than on the next RPC. The monitor stops when the client is closed.
+func WithAutoReconnect() ClientOption {
+	return func(o *clientOptions) {
+		o.autoReconnect = true
+	}
+}
+
+// WithEncryptedContentTracing records encrypted reasoning content on chat spans. By default it is redacted and only
+// its size is recorded, since the blobs are large and opaque.
+func WithEncryptedContentTracing(enabled bool) ClientOption {
+	return func(o *clientOptions) {
+		o.traceEncrypted = enabled
+	}
+}
+
+// WithSpanContent sets how much prompt and completion content chat spans record. By default every span records the
+// whole content.
+func WithSpanContent(policy SpanContentPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.spanContent = policy
+	}
+}
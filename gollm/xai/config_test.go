@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 func TestDefaultClientOptions(t *testing.T) {
@@ -106,4 +107,25 @@ func TestClientOptionMutators(t *testing.T) {
 	if opts.timeout != 2*time.Second {
 		t.Fatalf("timeout changed on non-positive value: %v", opts.timeout)
 	}
+
+	WithDefaultTimeout(time.Minute)(opts)
+	if opts.timeout != time.Minute {
+		t.Fatalf("default timeout not updated: %v", opts.timeout)
+	}
+
+	WithKeepalive(keepalive.ClientParameters{Time: time.Minute})(opts)
+	if opts.keepalive.Time != time.Minute {
+		t.Fatalf("keepalive not updated: %+v", opts.keepalive)
+	}
+
+	WithConnPoolSize(4)(opts)
+	WithConnPoolSize(0)(opts)
+	if opts.poolSize != 4 {
+		t.Fatalf("poolSize = %d, want 4", opts.poolSize)
+	}
+
+	WithAutoReconnect()(opts)
+	if !opts.autoReconnect {
+		t.Fatalf("autoReconnect not set")
+	}
 }
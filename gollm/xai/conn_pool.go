@@ -0,0 +1,137 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// connPool spreads RPCs over several connections to the same target in round-robin order.
+//
+// A single HTTP/2 connection caps the number of concurrent streams, so high-QPS workloads benefit from a few
+// connections. Each connection reconnects on its own; see [connPool.monitor].
+type connPool struct {
+	conns []*grpc.ClientConn
+	next  atomic.Uint32
+}
+
+var _ grpc.ClientConnInterface = (*connPool)(nil)
+
+// dialPool creates size connections to target.
+func dialPool(target string, size int, opts []grpc.DialOption) (*connPool, error) {
+	p := &connPool{conns: make([]*grpc.ClientConn, 0, max(1, size))}
+	for range max(1, size) {
+		conn, err := grpc.NewClient(target, opts...)
+		if err != nil {
+			return nil, errors.Join(err, p.Close())
+		}
+		p.conns = append(p.conns, conn)
+	}
+	return p, nil
+}
+
+// newConnPool wraps existing connections.
+func newConnPool(conns ...*grpc.ClientConn) *connPool {
+	return &connPool{conns: conns}
+}
+
+func (p *connPool) pick() *grpc.ClientConn {
+	if len(p.conns) == 1 {
+		return p.conns[0]
+	}
+	n := p.next.Add(1) - 1
+	return p.conns[n%uint32(len(p.conns))]
+}
+
+// Invoke implements [grpc.ClientConnInterface].
+func (p *connPool) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return p.pick().Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream implements [grpc.ClientConnInterface].
+func (p *connPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return p.pick().NewStream(ctx, desc, method, opts...)
+}
+
+// Close closes every connection of the pool.
+func (p *connPool) Close() error {
+	var errs []error
+	for _, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// monitor watches the channel state of every connection until ctx is done and reconnects connections that went
+// idle, so the next RPC after a quiet period does not pay the connection setup.
+//
+// Connections in transient failure are retried by gRPC itself with exponential backoff.
+func (p *connPool) monitor(ctx context.Context) {
+	for _, conn := range p.conns {
+		go keepConnected(ctx, conn)
+	}
+}
+
+func keepConnected(ctx context.Context, conn *grpc.ClientConn) {
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Idle:
+			conn.Connect()
+		case connectivity.Shutdown:
+			return
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+// waitReady blocks until every connection of the pool is ready, connecting idle ones.
+func (p *connPool) waitReady(ctx context.Context) error {
+	for _, conn := range p.conns {
+		if err := waitConnReady(ctx, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitConnReady(ctx context.Context, conn *grpc.ClientConn) error {
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			conn.Connect()
+		case connectivity.Shutdown:
+			return fmt.Errorf("connection to %s is shut down", conn.Target())
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection to %s is %s: %w", conn.Target(), state, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func TestConnPoolRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	pool, err := dialPool("passthrough:///xai", 3, []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
+	if err != nil {
+		t.Fatalf("dialPool() error = %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	index := make(map[*grpc.ClientConn]int, len(pool.conns))
+	for i, conn := range pool.conns {
+		index[conn] = i
+	}
+	var got []int
+	for range 5 {
+		got = append(got, index[pool.pick()])
+	}
+	if diff := cmp.Diff([]int{0, 1, 2, 0, 1}, got); diff != "" {
+		t.Fatalf("pick() order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type fakeModelsServer struct {
+	xaipb.UnimplementedModelsServer
+}
+
+func (fakeModelsServer) ListLanguageModels(context.Context, *emptypb.Empty) (*xaipb.ListLanguageModelsResponse, error) {
+	return &xaipb.ListLanguageModelsResponse{Models: []*xaipb.LanguageModel{{Name: "grok-4"}}}, nil
+}
+
+func TestClientHealthy(t *testing.T) {
+	t.Parallel()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	xaipb.RegisterModelsServer(srv, fakeModelsServer{})
+	go func() { _ = srv.Serve(lis) }()
+
+	client, err := NewClient("test-key",
+		WithAPIHost("passthrough:///bufnet"),
+		WithInsecure(),
+		WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})),
+		WithConnPoolSize(2),
+		WithAutoReconnect(),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := client.Healthy(ctx); err != nil {
+		t.Fatalf("Healthy() error = %v", err)
+	}
+	for range 2 {
+		models, err := client.Models.ListLanguageModels(ctx)
+		if err != nil || len(models.GetModels()) != 1 {
+			t.Fatalf("ListLanguageModels() = (%v, %v)", models, err)
+		}
+	}
+
+	srv.Stop()
+	for _, conn := range client.apiConn.conns {
+		if state := conn.GetState(); state == connectivity.Ready && !conn.WaitForStateChange(ctx, state) {
+			t.Fatal("connection stayed ready after server stop")
+		}
+	}
+	ctx, cancel = context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.Healthy(ctx); err == nil {
+		t.Fatal("Healthy() after server stop error = nil, want error")
+	}
+}
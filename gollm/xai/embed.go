@@ -18,10 +18,38 @@ package xai
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
 
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
+// EmbedEncoding selects the encoding of the returned embeddings.
+type EmbedEncoding string
+
+const (
+	// EmbedEncodingFloat returns the embeddings as float arrays.
+	EmbedEncodingFloat EmbedEncoding = "float"
+	// EmbedEncodingBase64 returns the embeddings as base64-encoded little-endian float32 arrays, which are smaller
+	// on the wire. Decode them with [DecodeFeatureVector] or [EmbeddingVectors].
+	EmbedEncodingBase64 EmbedEncoding = "base64"
+)
+
+// EmbedOption customizes embedding requests.
+type EmbedOption func(*xaipb.EmbedRequest)
+
+// WithEmbedEncoding sets the encoding of the returned embeddings.
+func WithEmbedEncoding(encoding EmbedEncoding) EmbedOption {
+	return func(req *xaipb.EmbedRequest) { req.EncodingFormat = embedEncodingToProto(encoding) }
+}
+
+// WithEmbedUser sets the end-user id.
+func WithEmbedUser(user string) EmbedOption {
+	return func(req *xaipb.EmbedRequest) { req.User = user }
+}
+
 // EmbedClient provides access to the Embeddings service.
 type EmbedClient struct {
 	embedder xaipb.EmbedderClient
@@ -37,7 +65,7 @@ func (c *EmbedClient) Create(ctx context.Context, req *xaipb.EmbedRequest) (*xai
 }
 
 // CreateStrings generates embeddings for a list of text strings.
-func (c *EmbedClient) CreateStrings(ctx context.Context, model string, texts []string) (*xaipb.EmbedResponse, error) {
+func (c *EmbedClient) CreateStrings(ctx context.Context, model string, texts []string, opts ...EmbedOption) (*xaipb.EmbedResponse, error) {
 	inputs := make([]*xaipb.EmbedInput, len(texts))
 	for i, t := range texts {
 		inputs[i] = &xaipb.EmbedInput{
@@ -46,8 +74,61 @@ func (c *EmbedClient) CreateStrings(ctx context.Context, model string, texts []s
 			},
 		}
 	}
-	return c.Create(ctx, &xaipb.EmbedRequest{
+	req := &xaipb.EmbedRequest{
 		Model: model,
 		Input: inputs,
-	})
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return c.Create(ctx, req)
+}
+
+// DecodeFeatureVector returns the values of v in either encoding.
+func DecodeFeatureVector(v *xaipb.FeatureVector) ([]float32, error) {
+	if v.GetBase64Array() == "" {
+		return v.GetFloatArray(), nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(v.GetBase64Array())
+	if err != nil {
+		return nil, fmt.Errorf("decode base64 embedding: %w", err)
+	}
+	if len(raw)%4 != 0 {
+		return nil, fmt.Errorf("decode base64 embedding: %d bytes is not a whole number of float32 values", len(raw))
+	}
+	out := make([]float32, len(raw)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+	}
+	return out, nil
+}
+
+// EmbeddingVectors returns the first feature vector of every embedding of resp, decoded and ordered by input index.
+func EmbeddingVectors(resp *xaipb.EmbedResponse) ([][]float32, error) {
+	embeddings := resp.GetEmbeddings()
+	out := make([][]float32, len(embeddings))
+	for _, e := range embeddings {
+		if e.GetIndex() < 0 || int(e.GetIndex()) >= len(out) {
+			return nil, fmt.Errorf("embedding index %d out of range", e.GetIndex())
+		}
+		vecs := e.GetEmbeddings()
+		if len(vecs) == 0 {
+			continue
+		}
+		vec, err := DecodeFeatureVector(vecs[0])
+		if err != nil {
+			return nil, fmt.Errorf("embedding %d: %w", e.GetIndex(), err)
+		}
+		out[e.GetIndex()] = vec
+	}
+	return out, nil
+}
+
+func embedEncodingToProto(e EmbedEncoding) xaipb.EmbedEncodingFormat {
+	switch e {
+	case EmbedEncodingBase64:
+		return xaipb.EmbedEncodingFormat_FORMAT_BASE64
+	default:
+		return xaipb.EmbedEncodingFormat_FORMAT_FLOAT
+	}
 }
@@ -0,0 +1,186 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+type recordingEmbedder struct {
+	req *xaipb.EmbedRequest
+}
+
+func (e *recordingEmbedder) Embed(_ context.Context, req *xaipb.EmbedRequest, _ ...grpc.CallOption) (*xaipb.EmbedResponse, error) {
+	e.req = req
+	return &xaipb.EmbedResponse{}, nil
+}
+
+func base64Floats(v ...float32) string {
+	raw := make([]byte, 0, 4*len(v))
+	for _, x := range v {
+		raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(x))
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestEmbedCreateStringsOptions(t *testing.T) {
+	t.Parallel()
+
+	embedder := &recordingEmbedder{}
+	client := &EmbedClient{embedder: embedder}
+	if _, err := client.CreateStrings(t.Context(), "grok-embed", []string{"a"}, WithEmbedEncoding(EmbedEncodingBase64), WithEmbedUser("u1")); err != nil {
+		t.Fatalf("CreateStrings() error = %v", err)
+	}
+	if got := embedder.req.GetEncodingFormat(); got != xaipb.EmbedEncodingFormat_FORMAT_BASE64 {
+		t.Fatalf("EncodingFormat = %v, want FORMAT_BASE64", got)
+	}
+	if got := embedder.req.GetUser(); got != "u1" {
+		t.Fatalf("User = %q, want u1", got)
+	}
+}
+
+func TestEmbeddingVectors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		resp    *xaipb.EmbedResponse
+		want    [][]float32
+		wantErr bool
+	}{
+		"float and base64 by index": {
+			resp: &xaipb.EmbedResponse{Embeddings: []*xaipb.Embedding{
+				{Index: 1, Embeddings: []*xaipb.FeatureVector{{Base64Array: base64Floats(0.5, -2)}}},
+				{Index: 0, Embeddings: []*xaipb.FeatureVector{{FloatArray: []float32{1, 2}}}},
+			}},
+			want: [][]float32{{1, 2}, {0.5, -2}},
+		},
+		"index out of range": {
+			resp: &xaipb.EmbedResponse{Embeddings: []*xaipb.Embedding{
+				{Index: 1, Embeddings: []*xaipb.FeatureVector{{FloatArray: []float32{1}}}},
+			}},
+			wantErr: true,
+		},
+		"truncated base64": {
+			resp: &xaipb.EmbedResponse{Embeddings: []*xaipb.Embedding{
+				{Index: 0, Embeddings: []*xaipb.FeatureVector{{Base64Array: base64.StdEncoding.EncodeToString([]byte{1, 2, 3})}}},
+			}},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := EmbeddingVectors(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EmbeddingVectors() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("EmbeddingVectors() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuantizeInt8(t *testing.T) {
+	t.Parallel()
+
+	v := []float32{0.5, -1, 0.25, 0}
+	q := QuantizeInt8(v)
+	if diff := cmp.Diff([]int8{64, -127, 32, 0}, q.Values); diff != "" {
+		t.Fatalf("QuantizeInt8() values mismatch (-want +got):\n%s", diff)
+	}
+	for i, x := range q.Float32() {
+		if math.Abs(float64(x-v[i])) > float64(q.Scale)/2 {
+			t.Fatalf("Float32()[%d] = %v, want %v within %v", i, x, v[i], q.Scale/2)
+		}
+	}
+	if zero := QuantizeInt8([]float32{0, 0}); zero.Scale != 0 || !cmp.Equal([]float32{0, 0}, zero.Float32()) {
+		t.Fatalf("QuantizeInt8(zero) = %+v", zero)
+	}
+}
+
+func TestQuantizeFloat16(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in   float32
+		bits uint16
+		out  float32
+	}{
+		"one":            {in: 1, bits: 0x3c00, out: 1},
+		"negative":       {in: -2.5, bits: 0xc100, out: -2.5},
+		"zero":           {in: 0, bits: 0, out: 0},
+		"max":            {in: 65504, bits: 0x7bff, out: 65504},
+		"overflow":       {in: 1e6, bits: 0x7c00, out: float32(math.Inf(1))},
+		"smallest":       {in: 0x1p-24, bits: 0x0001, out: 0x1p-24},
+		"subnormal":      {in: 0x1p-15, bits: 0x0200, out: 0x1p-15},
+		"underflow":      {in: 0x1p-30, bits: 0, out: 0},
+		"round to even":  {in: 1 + 0x1p-11, bits: 0x3c00, out: 1},
+		"round up":       {in: 1 + 0x1p-11 + 0x1p-20, bits: 0x3c01, out: 1 + 0x1p-10},
+		"carry exponent": {in: 2 - 0x1p-12, bits: 0x4000, out: 2},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			bits := QuantizeFloat16([]float32{tt.in})
+			if bits[0] != tt.bits {
+				t.Fatalf("QuantizeFloat16(%v) = %#04x, want %#04x", tt.in, bits[0], tt.bits)
+			}
+			if got := Float16ToFloat32(bits)[0]; got != tt.out {
+				t.Fatalf("Float16ToFloat32(%#04x) = %v, want %v", bits[0], got, tt.out)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		a, b       []float32
+		wantDot    float64
+		wantCosine float64
+	}{
+		"parallel":   {a: []float32{1, 2}, b: []float32{2, 4}, wantDot: 10, wantCosine: 1},
+		"orthogonal": {a: []float32{1, 0}, b: []float32{0, 3}, wantDot: 0, wantCosine: 0},
+		"opposite":   {a: []float32{1, 1}, b: []float32{-1, -1}, wantDot: -2, wantCosine: -1},
+		"zero":       {a: []float32{0, 0}, b: []float32{1, 1}, wantDot: 0, wantCosine: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := DotProduct(tt.a, tt.b); math.Abs(got-tt.wantDot) > 1e-9 {
+				t.Fatalf("DotProduct() = %v, want %v", got, tt.wantDot)
+			}
+			if got := CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.wantCosine) > 1e-9 {
+				t.Fatalf("CosineSimilarity() = %v, want %v", got, tt.wantCosine)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"math"
+)
+
+// Int8Vector is an embedding quantized to int8 with a single scale, a quarter of the size of its float32 values.
+type Int8Vector struct {
+	Values []int8
+	// Scale maps Values back to the original values: v ≈ float32(Values[i]) * Scale.
+	Scale float32
+}
+
+// QuantizeInt8 quantizes v symmetrically to int8, scaling its largest magnitude to 127.
+func QuantizeInt8(v []float32) Int8Vector {
+	var maxAbs float64
+	for _, x := range v {
+		maxAbs = max(maxAbs, math.Abs(float64(x)))
+	}
+	q := Int8Vector{Values: make([]int8, len(v))}
+	if maxAbs == 0 {
+		return q
+	}
+	q.Scale = float32(maxAbs / math.MaxInt8)
+	for i, x := range v {
+		q.Values[i] = int8(math.Round(float64(x) / float64(q.Scale)))
+	}
+	return q
+}
+
+// Float32 returns the approximate original values of q.
+func (q Int8Vector) Float32() []float32 {
+	out := make([]float32, len(q.Values))
+	for i, x := range q.Values {
+		out[i] = float32(x) * q.Scale
+	}
+	return out
+}
+
+// QuantizeFloat16 converts v to IEEE 754 half-precision bit patterns, rounding to nearest even. Values beyond the
+// float16 range become infinities.
+func QuantizeFloat16(v []float32) []uint16 {
+	out := make([]uint16, len(v))
+	for i, x := range v {
+		out[i] = float32ToFloat16(x)
+	}
+	return out
+}
+
+// Float16ToFloat32 converts IEEE 754 half-precision bit patterns back to float32 values.
+func Float16ToFloat32(h []uint16) []float32 {
+	out := make([]float32, len(h))
+	for i, x := range h {
+		out[i] = float16ToFloat32(x)
+	}
+	return out
+}
+
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff: // Inf or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127+15 >= 0x1f: // overflow
+		return sign | 0x7c00
+	case exp-127+15 <= 0: // subnormal or zero
+		if exp-127+15 < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - (exp - 127 + 15))
+		half := uint16(mant >> shift)
+		rem := mant & (1<<shift - 1)
+		if mid := uint32(1) << (shift - 1); rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return sign | half
+	}
+	half := uint16(exp-127+15)<<10 | uint16(mant>>13)
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++ // may carry into the exponent, which rounds up correctly
+	}
+	return sign | half
+}
+
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// Normalize the subnormal value.
+		exp = 127 - 15 + 1
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		return math.Float32frombits(sign | exp<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+}
+
+// DotProduct returns the dot product of a and b. It panics if their lengths differ.
+func DotProduct(a, b []float32) float64 {
+	if len(a) != len(b) {
+		panic("xai: DotProduct of vectors with different lengths")
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, in [-1, 1], or 0 if either is a zero vector. It
+// panics if their lengths differ.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		panic("xai: CosineSimilarity of vectors with different lengths")
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
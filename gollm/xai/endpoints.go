@@ -0,0 +1,340 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// EndpointsEnv names the environment variable overriding the data plane endpoints of [WithEndpoints] and
+// [WithAPIHost] with a comma-separated host list, the primary first.
+const EndpointsEnv = "XAI_API_ENDPOINTS"
+
+const (
+	// defaultEndpointCooldown is how long an endpoint that failed is skipped unless a health check passes first.
+	defaultEndpointCooldown = 30 * time.Second
+	// defaultHealthCheckInterval is the period of the endpoint health checks.
+	defaultHealthCheckInterval = 30 * time.Second
+	// healthCheckTimeout bounds each health check RPC.
+	healthCheckTimeout = 5 * time.Second
+	// latencyWeight is the weight of the newest health check in the latency moving average.
+	latencyWeight = 0.3
+)
+
+// EndpointPolicy selects the endpoint of [WithEndpoints] an RPC is sent to.
+type EndpointPolicy int
+
+const (
+	// EndpointOrdered sends RPCs to the first healthy endpoint in the order given, the primary while it is healthy.
+	EndpointOrdered EndpointPolicy = iota
+	// EndpointLowestLatency sends RPCs to the healthy endpoint with the lowest health check latency.
+	EndpointLowestLatency
+)
+
+// WithEndpoints connects the data plane to primary and the fallbacks, such as the API hosts of several regions,
+// and fails over between them: an endpoint is skipped while it is unavailable, and a unary RPC failing with
+// [codes.Unavailable] is retried on the next one. See [WithEndpointPolicy] and [WithEndpointHealthCheck].
+//
+// The [EndpointsEnv] environment variable overrides it.
+func WithEndpoints(primary string, fallbacks ...string) ClientOption {
+	return func(o *clientOptions) {
+		if primary != "" {
+			o.apiHost = primary
+		}
+		o.fallbacks = fallbacks
+	}
+}
+
+// WithEndpointPolicy sets how the endpoint of an RPC is chosen among the healthy endpoints, [EndpointOrdered] by
+// default.
+func WithEndpointPolicy(policy EndpointPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.endpointPolicy = policy
+	}
+}
+
+// WithEndpointHealthCheck sets the period of the endpoint health checks, 30 seconds by default. Each check lists
+// the language models of every endpoint, which marks unreachable endpoints unhealthy, brings recovered ones back,
+// and measures the latency of [EndpointLowestLatency]. A negative interval disables the checks.
+func WithEndpointHealthCheck(interval time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		if interval != 0 {
+			o.healthInterval = interval
+		}
+	}
+}
+
+// EndpointStatus is the health of an endpoint of [WithEndpoints].
+type EndpointStatus struct {
+	Host    string
+	Healthy bool
+	// Latency is the moving average of the health check latency, or zero before the first check.
+	Latency time.Duration
+}
+
+// endpoint is a data plane endpoint with the connections to it and its health.
+type endpoint struct {
+	host string
+	pool *connPool
+	// failedAt is the Unix time in nanoseconds of the last failure, or zero.
+	failedAt atomic.Int64
+	// latency is the moving average of the health check latency in nanoseconds, or zero.
+	latency atomic.Int64
+}
+
+func (e *endpoint) markFailed(now time.Time) { e.failedAt.Store(now.UnixNano()) }
+
+// healthy reports whether e did not fail within cooldown and not all of its connections are failing.
+func (e *endpoint) healthy(now time.Time, cooldown time.Duration) bool {
+	if failed := e.failedAt.Load(); failed != 0 && now.Sub(time.Unix(0, failed)) < cooldown {
+		return false
+	}
+	for _, conn := range e.pool.conns {
+		if state := conn.GetState(); state != connectivity.TransientFailure && state != connectivity.Shutdown {
+			return true
+		}
+	}
+	return false
+}
+
+// observe records a health check of e that took d.
+func (e *endpoint) observe(d time.Duration) {
+	e.failedAt.Store(0)
+	prev := e.latency.Load()
+	if prev == 0 {
+		e.latency.Store(int64(d))
+		return
+	}
+	e.latency.Store(int64(latencyWeight*float64(d) + (1-latencyWeight)*float64(prev)))
+}
+
+// endpointSet sends RPCs to the healthy endpoint chosen by policy, failing over to the others.
+type endpointSet struct {
+	endpoints []*endpoint
+	policy    EndpointPolicy
+	cooldown  time.Duration
+}
+
+var _ grpc.ClientConnInterface = (*endpointSet)(nil)
+
+// dialEndpoints connects to every host of hosts with a pool of size connections.
+func dialEndpoints(hosts []string, target func(string) string, size int, opts []grpc.DialOption, policy EndpointPolicy) (*endpointSet, error) {
+	s := &endpointSet{policy: policy, cooldown: defaultEndpointCooldown}
+	for _, host := range hosts {
+		pool, err := dialPool(target(host), size, opts)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("endpoint %s: %w", host, err), s.Close())
+		}
+		s.endpoints = append(s.endpoints, &endpoint{host: host, pool: pool})
+	}
+	return s, nil
+}
+
+// order returns the endpoints to try, the healthy ones first in policy order and then the others as a last resort.
+func (s *endpointSet) order(now time.Time) []*endpoint {
+	healthy := make([]*endpoint, 0, len(s.endpoints))
+	var unhealthy []*endpoint
+	for _, e := range s.endpoints {
+		if e.healthy(now, s.cooldown) {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	if s.policy == EndpointLowestLatency {
+		// Endpoints not measured yet keep their order after the measured ones.
+		slices.SortStableFunc(healthy, func(a, b *endpoint) int {
+			la, lb := a.latency.Load(), b.latency.Load()
+			switch {
+			case la == lb:
+				return 0
+			case la == 0:
+				return 1
+			case lb == 0:
+				return -1
+			case la < lb:
+				return -1
+			default:
+				return 1
+			}
+		})
+	}
+	return append(healthy, unhealthy...)
+}
+
+// unavailable reports whether err means the endpoint could not serve the RPC.
+func unavailable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// Invoke implements [grpc.ClientConnInterface], retrying on the next endpoint while the RPC fails with
+// [codes.Unavailable].
+func (s *endpointSet) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	var err error
+	for _, e := range s.order(time.Now()) {
+		err = e.pool.Invoke(ctx, method, args, reply, opts...)
+		if !unavailable(err) || ctx.Err() != nil {
+			return err
+		}
+		e.markFailed(time.Now())
+	}
+	return err
+}
+
+// NewStream implements [grpc.ClientConnInterface]. Streams that fail with [codes.Unavailable] mark their endpoint
+// failed, so the next RPC goes elsewhere.
+func (s *endpointSet) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	var err error
+	for _, e := range s.order(time.Now()) {
+		var stream grpc.ClientStream
+		stream, err = e.pool.NewStream(ctx, desc, method, opts...)
+		if err == nil {
+			return &endpointStream{ClientStream: stream, endpoint: e}, nil
+		}
+		if !unavailable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		e.markFailed(time.Now())
+	}
+	return nil, err
+}
+
+// endpointStream marks its endpoint failed when it ends with [codes.Unavailable].
+type endpointStream struct {
+	grpc.ClientStream
+
+	endpoint *endpoint
+}
+
+func (s *endpointStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if unavailable(err) {
+		s.endpoint.markFailed(time.Now())
+	}
+	return err
+}
+
+// status returns the health of every endpoint in the order given.
+func (s *endpointSet) status() []EndpointStatus {
+	now := time.Now()
+	out := make([]EndpointStatus, len(s.endpoints))
+	for i, e := range s.endpoints {
+		out[i] = EndpointStatus{Host: e.host, Healthy: e.healthy(now, s.cooldown), Latency: time.Duration(e.latency.Load())}
+	}
+	return out
+}
+
+// healthCheck checks every endpoint right away and then every interval until ctx is done.
+func (s *endpointSet) healthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAll checks every endpoint concurrently. An endpoint that answers, even with an error such as a rejected API
+// key, is reachable; one that is unavailable or too slow is marked failed.
+func (s *endpointSet) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range s.endpoints {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			_, err := xaipb.NewModelsClient(e.pool).ListLanguageModels(ctx, &emptypb.Empty{})
+			switch code := status.Code(err); {
+			case ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded):
+				// Canceled with the client.
+			case code == codes.Unavailable || code == codes.DeadlineExceeded:
+				e.markFailed(time.Now())
+			default:
+				e.observe(time.Since(start))
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// monitor keeps the connections of every endpoint connected; see [connPool.monitor].
+func (s *endpointSet) monitor(ctx context.Context) {
+	for _, e := range s.endpoints {
+		e.pool.monitor(ctx)
+	}
+}
+
+// waitReady blocks until the connections of one endpoint are ready.
+func (s *endpointSet) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(s.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range s.endpoints {
+		wg.Go(func() {
+			if errs[i] = e.pool.waitReady(ctx); errs[i] == nil {
+				cancel()
+			}
+		})
+	}
+	wg.Wait()
+	if slices.Contains(errs, nil) {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the connections of every endpoint.
+func (s *endpointSet) Close() error {
+	var errs []error
+	for _, e := range s.endpoints {
+		if err := e.pool.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// endpointsFromEnv returns the hosts of [EndpointsEnv], or nil when it is unset.
+func endpointsFromEnv(value string) []string {
+	var hosts []string
+	for host := range strings.SplitSeq(value, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// modelsEndpoint serves fakeModelsServer on a local port and returns its address.
+func modelsEndpoint(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	srv := grpc.NewServer()
+	xaipb.RegisterModelsServer(srv, fakeModelsServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// deadEndpoint returns the address of a local port nothing listens on.
+func deadEndpoint(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+	return addr
+}
+
+func hostsOf(endpoints []EndpointStatus) []string {
+	hosts := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		hosts[i] = e.Host
+	}
+	return hosts
+}
+
+func TestClientEndpointsFailover(t *testing.T) {
+	t.Parallel()
+
+	primary, fallback := deadEndpoint(t), modelsEndpoint(t)
+	client, err := NewClient("test-key",
+		WithInsecure(),
+		WithEndpoints(primary, fallback),
+		WithEndpointHealthCheck(-1),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := client.Healthy(ctx); err != nil {
+		t.Fatalf("Healthy() error = %v", err)
+	}
+	for range 2 {
+		models, err := client.Models.ListLanguageModels(ctx)
+		if err != nil || len(models.GetModels()) != 1 {
+			t.Fatalf("ListLanguageModels() = (%v, %v)", models, err)
+		}
+	}
+
+	got := client.Endpoints()
+	if diff := cmp.Diff([]string{primary, fallback}, hostsOf(got)); diff != "" {
+		t.Fatalf("Endpoints() hosts mismatch (-want +got):\n%s", diff)
+	}
+	if got[0].Healthy || !got[1].Healthy {
+		t.Fatalf("Endpoints() = %+v, want only the fallback healthy", got)
+	}
+}
+
+func TestClientEndpointsHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	primary, fallback := modelsEndpoint(t), deadEndpoint(t)
+	client, err := NewClient("test-key",
+		WithInsecure(),
+		WithEndpoints(primary, fallback),
+		WithEndpointHealthCheck(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := client.Endpoints()
+		if got[0].Healthy && got[0].Latency > 0 && !got[1].Healthy {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Endpoints() = %+v, want a measured healthy primary and an unhealthy fallback", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientEndpointsEnv(t *testing.T) {
+	t.Setenv(EndpointsEnv, " us-east-1.api.x.ai:443, ,eu-west-1.api.x.ai:443")
+
+	client, err := NewClient("test-key",
+		WithInsecure(),
+		WithAPIHost("api.x.ai:443"),
+		WithEndpointHealthCheck(-1),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	if diff := cmp.Diff([]string{"us-east-1.api.x.ai:443", "eu-west-1.api.x.ai:443"}, hostsOf(client.Endpoints())); diff != "" {
+		t.Fatalf("Endpoints() hosts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEndpointSetOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy  EndpointPolicy
+		latency []time.Duration
+		failed  []bool
+		want    []string
+	}{
+		"ordered": {
+			policy:  EndpointOrdered,
+			latency: []time.Duration{30, 10, 20},
+			failed:  []bool{false, false, false},
+			want:    []string{"a", "b", "c"},
+		},
+		"ordered skips failed": {
+			policy:  EndpointOrdered,
+			latency: []time.Duration{30, 10, 20},
+			failed:  []bool{true, false, false},
+			want:    []string{"b", "c", "a"},
+		},
+		"lowest latency": {
+			policy:  EndpointLowestLatency,
+			latency: []time.Duration{30, 10, 20},
+			failed:  []bool{false, false, false},
+			want:    []string{"b", "c", "a"},
+		},
+		"lowest latency unmeasured last": {
+			policy:  EndpointLowestLatency,
+			latency: []time.Duration{0, 20, 10},
+			failed:  []bool{false, false, true},
+			want:    []string{"b", "a", "c"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Now()
+			set := &endpointSet{policy: tt.policy, cooldown: time.Minute}
+			for i, host := range []string{"a", "b", "c"} {
+				pool, err := dialPool("passthrough:///"+host, 1, []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
+				if err != nil {
+					t.Fatalf("dialPool() error = %v", err)
+				}
+				e := &endpoint{host: host, pool: pool}
+				e.latency.Store(int64(tt.latency[i]))
+				if tt.failed[i] {
+					e.markFailed(now)
+				}
+				set.endpoints = append(set.endpoints, e)
+			}
+			t.Cleanup(func() { _ = set.Close() })
+
+			var got []string
+			for _, e := range set.order(now) {
+				got = append(got, e.host)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("order() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,9 +17,14 @@
 package xai
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"slices"
+	"strings"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -66,12 +71,157 @@ func ParseError(err error) (*Error, bool) {
 	}, true
 }
 
-// WrapError returns an [Error] if err is a gRPC status error; otherwise returns err unchanged.
+// WrapError classifies a gRPC status error into one of the typed errors below, or an [Error] when no category
+// applies; other errors are returned unchanged.
+//
+// Every typed error unwraps to its [Error], so both errors.As(err, &rateLimitErr) and [AsError] work:
+//
+//	var rl *xai.RateLimitError
+//	if errors.As(err, &rl) {
+//		time.Sleep(rl.RetryAfter)
+//	}
 func WrapError(err error) error {
-	if xe, ok := ParseError(err); ok {
+	// Already classified errors pass through so wrapping is idempotent.
+	if xe := new(Error); errors.As(err, &xe) {
+		return err
+	}
+	xe, ok := ParseError(err)
+	if !ok {
+		return err
+	}
+
+	if isModeration(xe) {
+		return &ContentModerationError{Err: xe}
+	}
+	switch xe.Code {
+	case codes.ResourceExhausted:
+		return &RateLimitError{Err: xe, RetryAfter: retryAfter(xe)}
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return &AuthError{Err: xe}
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange, codes.NotFound, codes.AlreadyExists:
+		return &InvalidRequestError{Err: xe}
+	case codes.Internal, codes.Unavailable, codes.Unknown, codes.DataLoss:
+		return &ServerError{Err: xe}
+	case codes.DeadlineExceeded:
+		return &TimeoutError{Err: xe}
+	default:
 		return xe
 	}
-	return err
+}
+
+// RateLimitError reports that a quota or rate limit was exceeded ([codes.ResourceExhausted]).
+type RateLimitError struct {
+	Err *Error
+	// RetryAfter is the delay suggested by the server, or zero when none was given.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %s", e.RetryAfter, e.Err.Message)
+	}
+	return "rate limited: " + e.Err.Message
+}
+
+// Unwrap returns the underlying [Error].
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// AuthError reports a missing, invalid, or insufficiently privileged API key
+// ([codes.Unauthenticated] or [codes.PermissionDenied]).
+type AuthError struct {
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *AuthError) Error() string { return "authentication failed: " + e.Err.Message }
+
+// Unwrap returns the underlying [Error].
+func (e *AuthError) Unwrap() error { return e.Err }
+
+// InvalidRequestError reports a request the server rejected as malformed or inapplicable, such as an unknown
+// model or an invalid argument.
+type InvalidRequestError struct {
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *InvalidRequestError) Error() string { return "invalid request: " + e.Err.Message }
+
+// Unwrap returns the underlying [Error].
+func (e *InvalidRequestError) Unwrap() error { return e.Err }
+
+// ContentModerationError reports a prompt or completion blocked by content moderation.
+type ContentModerationError struct {
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *ContentModerationError) Error() string { return "content moderation: " + e.Err.Message }
+
+// Unwrap returns the underlying [Error].
+func (e *ContentModerationError) Unwrap() error { return e.Err }
+
+// ServerError reports a failure on the xAI side that is usually transient.
+type ServerError struct {
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *ServerError) Error() string { return "server error: " + e.Err.Message }
+
+// Unwrap returns the underlying [Error].
+func (e *ServerError) Unwrap() error { return e.Err }
+
+// TimeoutError reports an RPC, stream, or deferred completion that ran out of time ([codes.DeadlineExceeded]), by the
+// context deadline, the client default timeout, or a per-call timeout.
+//
+// It matches [context.DeadlineExceeded] with [errors.Is].
+type TimeoutError struct {
+	Err *Error
+}
+
+// newTimeoutError returns a [TimeoutError] with message msg.
+func newTimeoutError(msg string) *TimeoutError {
+	return &TimeoutError{Err: &Error{Code: codes.DeadlineExceeded, Message: msg}}
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string { return "timeout: " + e.Err.Message }
+
+// Unwrap returns the underlying [Error].
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// Is reports whether target is [context.DeadlineExceeded].
+func (e *TimeoutError) Is(target error) bool { return target == context.DeadlineExceeded }
+
+// retryAfter returns the retry delay of a [errdetails.RetryInfo] detail.
+func retryAfter(e *Error) time.Duration {
+	for _, d := range e.Details {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			return ri.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
+
+// isModeration reports whether e was caused by content moderation, signaled by an [errdetails.ErrorInfo] reason
+// or the status message.
+func isModeration(e *Error) bool {
+	if e.Code != codes.InvalidArgument && e.Code != codes.PermissionDenied && e.Code != codes.FailedPrecondition {
+		return false
+	}
+	for _, d := range e.Details {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			reason := strings.ToLower(info.GetReason())
+			if strings.Contains(reason, "moderation") || strings.Contains(reason, "safety") {
+				return true
+			}
+		}
+	}
+	msg := strings.ToLower(e.Message)
+	return strings.Contains(msg, "moderation") || strings.Contains(msg, "content policy") ||
+		strings.Contains(msg, "usage guidelines")
 }
 
 var retryableCodes = []codes.Code{
@@ -17,11 +17,16 @@
 package xai
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func TestErrorImplements(t *testing.T) {
@@ -129,3 +134,91 @@ func TestAsError(t *testing.T) {
 		t.Fatalf("AsError should return original *Error")
 	}
 }
+
+func TestWrapErrorTaxonomy(t *testing.T) {
+	withDetails := func(code codes.Code, msg string, details ...*errdetails.RetryInfo) error {
+		st := status.New(code, msg)
+		for _, d := range details {
+			var err error
+			if st, err = st.WithDetails(d); err != nil {
+				t.Fatalf("WithDetails: %v", err)
+			}
+		}
+		return st.Err()
+	}
+	moderated, err := status.New(codes.InvalidArgument, "blocked").WithDetails(&errdetails.ErrorInfo{Reason: "CONTENT_MODERATION"})
+	if err != nil {
+		t.Fatalf("WithDetails: %v", err)
+	}
+
+	tests := map[string]struct {
+		err   error
+		check func(error) bool
+	}{
+		"rate limit with retry info": {
+			err: withDetails(codes.ResourceExhausted, "quota", &errdetails.RetryInfo{RetryDelay: durationpb.New(3 * time.Second)}),
+			check: func(err error) bool {
+				var rl *RateLimitError
+				return errors.As(err, &rl) && rl.RetryAfter == 3*time.Second
+			},
+		},
+		"unauthenticated": {
+			err:   status.Error(codes.Unauthenticated, "bad key"),
+			check: func(err error) bool { var e *AuthError; return errors.As(err, &e) },
+		},
+		"permission denied": {
+			err:   status.Error(codes.PermissionDenied, "team disabled"),
+			check: func(err error) bool { var e *AuthError; return errors.As(err, &e) },
+		},
+		"invalid argument": {
+			err:   status.Error(codes.InvalidArgument, "unknown model"),
+			check: func(err error) bool { var e *InvalidRequestError; return errors.As(err, &e) },
+		},
+		"moderation reason": {
+			err:   moderated.Err(),
+			check: func(err error) bool { var e *ContentModerationError; return errors.As(err, &e) },
+		},
+		"moderation message": {
+			err:   status.Error(codes.PermissionDenied, "Content violates usage guidelines"),
+			check: func(err error) bool { var e *ContentModerationError; return errors.As(err, &e) },
+		},
+		"server": {
+			err:   status.Error(codes.Internal, "boom"),
+			check: func(err error) bool { var e *ServerError; return errors.As(err, &e) },
+		},
+		"deadline exceeded": {
+			err: status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			check: func(err error) bool {
+				var e *TimeoutError
+				return errors.As(err, &e) && errors.Is(err, context.DeadlineExceeded)
+			},
+		},
+		"unclassified": {
+			err: status.Error(codes.Canceled, "canceled"),
+			check: func(err error) bool {
+				_, ok := err.(*Error)
+				return ok
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			wrapped := WrapError(tt.err)
+			if !tt.check(wrapped) {
+				t.Fatalf("WrapError(%v) = %T, wrong category", tt.err, wrapped)
+			}
+			xe, ok := AsError(fmt.Errorf("context: %w", wrapped))
+			if !ok || xe.Code != status.Code(tt.err) {
+				t.Fatalf("AsError(wrapped) = (%+v, %v), want code %v", xe, ok, status.Code(tt.err))
+			}
+			if WrapError(wrapped) != wrapped {
+				t.Fatalf("WrapError is not idempotent for %T", wrapped)
+			}
+		})
+	}
+
+	if !IsRetryable(WrapError(status.Error(codes.Unavailable, "down"))) {
+		t.Fatalf("typed ServerError for Unavailable should stay retryable")
+	}
+}
@@ -26,6 +26,8 @@ import (
 	"path/filepath"
 	"sync"
 
+	"google.golang.org/grpc"
+
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
@@ -37,6 +39,7 @@ type ProgressFunc func(uploaded, total int64)
 type fileUploadConfig struct {
 	filename string
 	progress ProgressFunc
+	callOpts []grpc.CallOption
 }
 
 // FileOption customizes upload behavior.
@@ -52,6 +55,11 @@ func WithProgress(fn ProgressFunc) FileOption {
 	return func(cfg *fileUploadConfig) { cfg.progress = fn }
 }
 
+// WithUploadCallOptions passes opts, such as [WithCallTimeout], to the upload stream.
+func WithUploadCallOptions(opts ...grpc.CallOption) FileOption {
+	return func(cfg *fileUploadConfig) { cfg.callOpts = append(cfg.callOpts, opts...) }
+}
+
 // FilesClient wraps the Files service.
 type FilesClient struct {
 	files xaipb.FilesClient
@@ -114,7 +122,7 @@ func (c *FilesClient) Upload(ctx context.Context, src any, opts ...FileOption) (
 		return nil, fmt.Errorf("unsupported upload source type %T", src)
 	}
 
-	stream, err := c.files.UploadFile(ctx)
+	stream, err := c.files.UploadFile(ctx, cfg.callOpts...)
 	if err != nil {
 		return nil, WrapError(err)
 	}
@@ -128,7 +136,7 @@ func (c *FilesClient) Upload(ctx context.Context, src any, opts ...FileOption) (
 		},
 	}
 	if err := stream.Send(init); err != nil {
-		return nil, err
+		return nil, uploadSendError(stream, err)
 	}
 
 	var uploaded int64
@@ -142,7 +150,7 @@ func (c *FilesClient) Upload(ctx context.Context, src any, opts ...FileOption) (
 				},
 			}
 			if err := stream.Send(chunk); err != nil {
-				return nil, err
+				return nil, uploadSendError(stream, err)
 			}
 			uploaded += int64(n)
 			if cfg.progress != nil {
@@ -164,6 +172,17 @@ func (c *FilesClient) Upload(ctx context.Context, src any, opts ...FileOption) (
 	return resp, nil
 }
 
+// uploadSendError returns the error of a failed upload Send. Send reports io.EOF when the server has already
+// ended the stream; the server's status is then available from CloseAndRecv.
+func uploadSendError(stream xaipb.Files_UploadFileClient, err error) error {
+	if errors.Is(err, io.EOF) {
+		if _, recvErr := stream.CloseAndRecv(); recvErr != nil {
+			err = recvErr
+		}
+	}
+	return WrapError(err)
+}
+
 // BatchUpload uploads multiple files concurrently. Sources must be paths.
 // Results are returned in index order; errors are stored per entry.
 func (c *FilesClient) BatchUpload(ctx context.Context, paths []string, concurrency int, progress ProgressFunc) ([]*xaipb.File, []error) {
@@ -190,7 +209,7 @@ func (c *FilesClient) BatchUpload(ctx context.Context, paths []string, concurren
 }
 
 // List returns file metadata.
-func (c *FilesClient) List(ctx context.Context, limit int32, order Order, sortBy FileSortBy, paginationToken string) (*xaipb.ListFilesResponse, error) {
+func (c *FilesClient) List(ctx context.Context, limit int32, order Order, sortBy FileSortBy, paginationToken string, opts ...grpc.CallOption) (*xaipb.ListFilesResponse, error) {
 	req := &xaipb.ListFilesRequest{}
 	if limit > 0 {
 		req.Limit = limit
@@ -202,28 +221,31 @@ func (c *FilesClient) List(ctx context.Context, limit int32, order Order, sortBy
 	if paginationToken != "" {
 		req.PaginationToken = &paginationToken
 	}
-	return c.files.ListFiles(ctx, req)
+	resp, err := c.files.ListFiles(ctx, req, opts...)
+	return resp, WrapError(err)
 }
 
 // Get retrieves metadata for a file.
-func (c *FilesClient) Get(ctx context.Context, fileID string) (*xaipb.File, error) {
-	return c.files.RetrieveFile(ctx, &xaipb.RetrieveFileRequest{
+func (c *FilesClient) Get(ctx context.Context, fileID string, opts ...grpc.CallOption) (*xaipb.File, error) {
+	resp, err := c.files.RetrieveFile(ctx, &xaipb.RetrieveFileRequest{
 		FileId: fileID,
-	})
+	}, opts...)
+	return resp, WrapError(err)
 }
 
 // Delete removes a file by ID.
-func (c *FilesClient) Delete(ctx context.Context, fileID string) (*xaipb.DeleteFileResponse, error) {
-	return c.files.DeleteFile(ctx, &xaipb.DeleteFileRequest{
+func (c *FilesClient) Delete(ctx context.Context, fileID string, opts ...grpc.CallOption) (*xaipb.DeleteFileResponse, error) {
+	resp, err := c.files.DeleteFile(ctx, &xaipb.DeleteFileRequest{
 		FileId: fileID,
-	})
+	}, opts...)
+	return resp, WrapError(err)
 }
 
-// Content downloads the full file content.
-func (c *FilesClient) Content(ctx context.Context, fileID string) ([]byte, error) {
+// Content downloads the full file content. A [WithCallTimeout] option bounds the whole download.
+func (c *FilesClient) Content(ctx context.Context, fileID string, opts ...grpc.CallOption) ([]byte, error) {
 	stream, err := c.files.RetrieveFileContent(ctx, &xaipb.RetrieveFileContentRequest{
 		FileId: fileID,
-	})
+	}, opts...)
 	if err != nil {
 		return nil, WrapError(err)
 	}
@@ -234,7 +256,7 @@ func (c *FilesClient) Content(ctx context.Context, fileID string) ([]byte, error
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return nil, err
+			return nil, WrapError(err)
 		}
 		buf.Write(chunk.GetData())
 	}
@@ -242,10 +264,10 @@ func (c *FilesClient) Content(ctx context.Context, fileID string) ([]byte, error
 }
 
 // URL retrieves a signed URL for file download when available.
-func (c *FilesClient) URL(ctx context.Context, fileID string) (string, error) {
+func (c *FilesClient) URL(ctx context.Context, fileID string, opts ...grpc.CallOption) (string, error) {
 	resp, err := c.files.RetrieveFileURL(ctx, &xaipb.RetrieveFileURLRequest{
 		FileId: fileID,
-	})
+	}, opts...)
 	if err != nil {
 		return "", WrapError(err)
 	}
@@ -0,0 +1,280 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// FunctionTool is a client-side function tool that can be offered to the model and invoked with its tool calls.
+type FunctionTool interface {
+	// Name returns the function name the model calls.
+	Name() string
+	// Proto returns the tool definition passed to [WithTools].
+	Proto() *xaipb.Tool
+	// Call decodes the JSON arguments, runs the tool, and returns its JSON or text result.
+	Call(ctx context.Context, arguments string) (string, error)
+}
+
+// TypedTool is a [FunctionTool] whose arguments are decoded into TArgs.
+type TypedTool[TArgs, TResult any] struct {
+	name    string
+	handler func(context.Context, TArgs) (TResult, error)
+	proto   *xaipb.Tool
+}
+
+var _ FunctionTool = (*TypedTool[struct{}, string])(nil)
+
+// NewTool builds a function tool whose parameter schema is generated from TArgs.
+//
+// TArgs should be a struct; field names and requirements follow its json and jsonschema tags
+// (e.g. `json:"city" jsonschema:"description=City name"`). A string TResult is returned to the model verbatim, any
+// other result is encoded as JSON.
+func NewTool[TArgs, TResult any](name, description string, handler func(context.Context, TArgs) (TResult, error)) (*TypedTool[TArgs, TResult], error) {
+	if name == "" {
+		return nil, errors.New("tool name is required")
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("tool %q: handler is nil", name)
+	}
+
+	params, err := toolSchemaForType(reflect.TypeFor[TArgs]())
+	if err != nil {
+		return nil, fmt.Errorf("tool %q: %w", name, err)
+	}
+
+	return &TypedTool[TArgs, TResult]{
+		name:    name,
+		handler: handler,
+		proto: &xaipb.Tool{Tool: &xaipb.Tool_Function{Function: &xaipb.Function{
+			Name:        name,
+			Description: description,
+			Parameters:  string(params),
+		}}},
+	}, nil
+}
+
+// MustNewTool is like [NewTool] but panics on error; useful in init paths.
+func MustNewTool[TArgs, TResult any](name, description string, handler func(context.Context, TArgs) (TResult, error)) *TypedTool[TArgs, TResult] {
+	tool, err := NewTool(name, description, handler)
+	if err != nil {
+		panic(err)
+	}
+	return tool
+}
+
+// Name implements [FunctionTool].
+func (t *TypedTool[TArgs, TResult]) Name() string { return t.name }
+
+// Proto implements [FunctionTool].
+func (t *TypedTool[TArgs, TResult]) Proto() *xaipb.Tool { return t.proto }
+
+// Call implements [FunctionTool].
+func (t *TypedTool[TArgs, TResult]) Call(ctx context.Context, arguments string) (string, error) {
+	var args TArgs
+	if arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("decode %s arguments: %w", t.name, err)
+		}
+	}
+
+	result, err := t.handler(ctx, args)
+	if err != nil {
+		return "", fmt.Errorf("call %s: %w", t.name, err)
+	}
+
+	if s, ok := any(result).(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("encode %s result: %w", t.name, err)
+	}
+	return string(data), nil
+}
+
+// toolSchemaForType returns an inline JSON schema for t suitable for function parameters.
+func toolSchemaForType(t reflect.Type) ([]byte, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	reflector := &jsonschema.Reflector{
+		Anonymous:      true,
+		DoNotReference: true,
+	}
+	schema := reflector.ReflectFromType(t)
+	if schema == nil {
+		return nil, errors.New("schema reflection returned nil")
+	}
+	schema.Version = ""
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("marshal parameters schema: %w", err)
+	}
+	return b, nil
+}
+
+// ArgumentValidation selects how a [ToolRegistry] checks tool call arguments against the tool's parameter schema.
+type ArgumentValidation int
+
+const (
+	// ArgumentValidationOff passes arguments to the tools as the model produced them.
+	ArgumentValidationOff ArgumentValidation = iota
+	// ArgumentValidationStrict rejects arguments that violate the schema with a *[ToolArgumentsError].
+	ArgumentValidationStrict
+	// ArgumentValidationRepair behaves like ArgumentValidationStrict in [ToolRegistry.Dispatch], while
+	// [ToolRegistry.DispatchAll] re-prompts the model once with the violations before giving up.
+	ArgumentValidationRepair
+)
+
+// ToolRegistry dispatches tool calls to registered function tools.
+type ToolRegistry struct {
+	tools      []FunctionTool
+	byName     map[string]FunctionTool
+	validation ArgumentValidation
+}
+
+// NewToolRegistry returns a registry of tools, which must have unique names.
+func NewToolRegistry(tools ...FunctionTool) (*ToolRegistry, error) {
+	r := &ToolRegistry{byName: make(map[string]FunctionTool, len(tools))}
+	for _, t := range tools {
+		if _, dup := r.byName[t.Name()]; dup {
+			return nil, fmt.Errorf("duplicate tool %q", t.Name())
+		}
+		r.byName[t.Name()] = t
+		r.tools = append(r.tools, t)
+	}
+	return r, nil
+}
+
+// SetArgumentValidation sets how tool call arguments are validated before dispatch and returns r.
+func (r *ToolRegistry) SetArgumentValidation(mode ArgumentValidation) *ToolRegistry {
+	r.validation = mode
+	return r
+}
+
+// Tools returns the tool definitions in registration order, ready for [WithTools].
+func (r *ToolRegistry) Tools() []*xaipb.Tool {
+	out := make([]*xaipb.Tool, len(r.tools))
+	for i, t := range r.tools {
+		out[i] = t.Proto()
+	}
+	return out
+}
+
+// Dispatch runs the tool named by tc and returns its result as a tool message for [ChatSession.Append], carrying the
+// ID of tc as [ToolCallResult] does.
+func (r *ToolRegistry) Dispatch(ctx context.Context, tc *xaipb.ToolCall) (*xaipb.Message, error) {
+	fn := tc.GetFunction()
+	if fn == nil {
+		return nil, errors.New("tool call does not contain a function")
+	}
+	t, ok := r.byName[fn.GetName()]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q", fn.GetName())
+	}
+	if r.validation != ArgumentValidationOff {
+		if err := ValidateToolArguments(t.Proto(), fn.GetArguments()); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := t.Call(ctx, fn.GetArguments())
+	if err != nil {
+		return nil, err
+	}
+	return ToolCallResult(tc.GetId(), result), nil
+}
+
+// DispatchAll runs every client-side tool call of resp in order and appends the results to s, which should
+// already hold resp.
+//
+// Server-side tool calls, which xAI executes itself, are skipped. With [ArgumentValidationRepair], a call whose
+// arguments violate the tool schema is answered with the violations and the model is asked once, forced to the same
+// tool, to call it again; the corrected call is appended to s and dispatched in place of the invalid one. It returns
+// the number of tool calls dispatched.
+func (r *ToolRegistry) DispatchAll(ctx context.Context, s *ChatSession, resp *Response) (int, error) {
+	n := 0
+	for _, tc := range resp.ToolCalls() {
+		switch tc.GetType() {
+		case xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL, xaipb.ToolCallType_TOOL_CALL_TYPE_INVALID:
+		default:
+			continue
+		}
+		msg, err := r.Dispatch(ctx, tc)
+		var argErr *ToolArgumentsError
+		if r.validation == ArgumentValidationRepair && errors.As(err, &argErr) {
+			msg, err = r.repair(ctx, s, tc.GetId(), argErr)
+		}
+		if err != nil {
+			return n, err
+		}
+		s.Append(msg)
+		n++
+	}
+	return n, nil
+}
+
+// repair reports argErr to the model as the result of the tool call with toolCallID, samples a corrected call of the
+// same tool, and dispatches it. A second invalid call is returned as its *[ToolArgumentsError] without another attempt.
+func (r *ToolRegistry) repair(ctx context.Context, s *ChatSession, toolCallID string, argErr *ToolArgumentsError) (*xaipb.Message, error) {
+	s.Append(ToolCallResult(toolCallID, repairPrompt(argErr)))
+
+	s.mu.Lock()
+	choice := s.request.ToolChoice
+	s.request.ToolChoice = RequiredTool(argErr.Tool)
+	s.spanReqAttrs = nil
+	s.mu.Unlock()
+	resp, err := s.Completion(ctx)
+	s.mu.Lock()
+	s.request.ToolChoice = choice
+	s.spanReqAttrs = nil
+	s.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("repair %s arguments: %w", argErr.Tool, err)
+	}
+	s.Append(resp)
+
+	for _, tc := range resp.ToolCalls() {
+		if tc.GetFunction().GetName() == argErr.Tool {
+			return r.Dispatch(ctx, tc)
+		}
+	}
+	return nil, argErr
+}
+
+// repairPrompt asks the model to correct the arguments listed in argErr.
+func repairPrompt(argErr *ToolArgumentsError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Error: the arguments of this %s call do not match its parameter schema:\n", argErr.Tool)
+	for _, v := range argErr.Violations {
+		fmt.Fprintf(&b, "- %s\n", v)
+	}
+	fmt.Fprintf(&b, "Call %s again with corrected arguments.", argErr.Tool)
+	return b.String()
+}
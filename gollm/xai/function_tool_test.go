@@ -0,0 +1,358 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+type weatherArgs struct {
+	City string `json:"city" jsonschema:"description=City name"`
+	Unit string `json:"unit,omitempty" jsonschema:"enum=celsius,enum=fahrenheit"`
+}
+
+type weatherResult struct {
+	City    string  `json:"city"`
+	Celsius float64 `json:"celsius"`
+}
+
+func weatherTool(t *testing.T) *TypedTool[weatherArgs, weatherResult] {
+	t.Helper()
+
+	tool, err := NewTool("get_weather", "Current weather", func(_ context.Context, args weatherArgs) (weatherResult, error) {
+		if args.City == "" {
+			return weatherResult{}, errors.New("city is required")
+		}
+		return weatherResult{City: args.City, Celsius: 21.5}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTool() error = %v", err)
+	}
+	return tool
+}
+
+func TestNewToolSchema(t *testing.T) {
+	t.Parallel()
+
+	fn := weatherTool(t).Proto().GetFunction()
+	if fn.GetName() != "get_weather" || fn.GetDescription() != "Current weather" {
+		t.Fatalf("function = %+v", fn)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(fn.GetParameters()), &schema); err != nil {
+		t.Fatalf("decode parameters: %v", err)
+	}
+	if _, ok := schema["$ref"]; ok {
+		t.Fatalf("parameters schema uses $ref: %s", fn.GetParameters())
+	}
+	if schema["type"] != "object" {
+		t.Fatalf("schema type = %v, want object", schema["type"])
+	}
+	if diff := cmp.Diff([]any{"city"}, schema["required"]); diff != "" {
+		t.Fatalf("required mismatch (-want +got):\n%s", diff)
+	}
+	props := schema["properties"].(map[string]any)
+	if got := props["city"].(map[string]any)["description"]; got != "City name" {
+		t.Fatalf("city description = %v", got)
+	}
+
+	if _, err := NewTool[weatherArgs, string]("", "", nil); err == nil {
+		t.Fatal("NewTool() without name error = nil, want error")
+	}
+}
+
+func TestToolRegistryDispatch(t *testing.T) {
+	t.Parallel()
+
+	echo := MustNewTool("echo", "Echo text", func(_ context.Context, args struct {
+		Text string `json:"text"`
+	},
+	) (string, error) {
+		return args.Text, nil
+	})
+	registry, err := NewToolRegistry(weatherTool(t), echo)
+	if err != nil {
+		t.Fatalf("NewToolRegistry() error = %v", err)
+	}
+	if got := len(registry.Tools()); got != 2 {
+		t.Fatalf("len(Tools()) = %d, want 2", got)
+	}
+	if _, err := NewToolRegistry(echo, echo); err == nil {
+		t.Fatal("NewToolRegistry() with duplicate names error = nil, want error")
+	}
+
+	call := func(name, args string) *xaipb.ToolCall {
+		return &xaipb.ToolCall{
+			Type: xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL,
+			Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: name, Arguments: args}},
+		}
+	}
+
+	tests := map[string]struct {
+		call    *xaipb.ToolCall
+		want    string
+		wantErr string
+	}{
+		"json result":    {call: call("get_weather", `{"city":"Tokyo"}`), want: `{"city":"Tokyo","celsius":21.5}`},
+		"string result":  {call: call("echo", `{"text":"hi"}`), want: "hi"},
+		"handler error":  {call: call("get_weather", `{}`), wantErr: "call get_weather: city is required"},
+		"bad arguments":  {call: call("get_weather", `{"city":1}`), wantErr: "decode get_weather arguments"},
+		"unknown tool":   {call: call("nope", `{}`), wantErr: `unknown tool "nope"`},
+		"not a function": {call: &xaipb.ToolCall{}, wantErr: "does not contain a function"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			msg, err := registry.Dispatch(t.Context(), tt.call)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Dispatch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Dispatch() error = %v", err)
+			}
+			if msg.GetRole() != xaipb.MessageRole_ROLE_TOOL || messageText(msg) != tt.want {
+				t.Fatalf("Dispatch() = %v %q, want tool message %q", msg.GetRole(), messageText(msg), tt.want)
+			}
+		})
+	}
+}
+
+func TestToolRegistryDispatchAll(t *testing.T) {
+	t.Parallel()
+
+	registry, err := NewToolRegistry(weatherTool(t))
+	if err != nil {
+		t.Fatalf("NewToolRegistry() error = %v", err)
+	}
+
+	resp := newResponse(&xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{
+				Role: xaipb.MessageRole_ROLE_ASSISTANT,
+				ToolCalls: []*xaipb.ToolCall{
+					{
+						Type: xaipb.ToolCallType_TOOL_CALL_TYPE_WEB_SEARCH_TOOL,
+						Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: "web_search"}},
+					},
+					{
+						Type: xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL,
+						Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+					},
+				},
+			},
+		}},
+	}, ptr(int32(0)))
+
+	s := chatSessionForTest()
+	s.Append(resp)
+	n, err := registry.DispatchAll(t.Context(), s, resp)
+	if err != nil || n != 1 {
+		t.Fatalf("DispatchAll() = (%d, %v), want (1, nil)", n, err)
+	}
+	msgs := s.Messages()
+	if got := messageText(msgs[len(msgs)-1]); got != `{"city":"Paris","celsius":21.5}` {
+		t.Fatalf("appended tool result = %q", got)
+	}
+}
+
+func TestToolRegistryDispatchAllToolCallIDs(t *testing.T) {
+	t.Parallel()
+
+	registry, err := NewToolRegistry(weatherTool(t))
+	if err != nil {
+		t.Fatalf("NewToolRegistry() error = %v", err)
+	}
+
+	call := func(id, city string) *xaipb.ToolCall {
+		return &xaipb.ToolCall{
+			Id:   id,
+			Type: xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL,
+			Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: "get_weather", Arguments: `{"city":"` + city + `"}`}},
+		}
+	}
+	resp := newResponse(&xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{
+				Role:      xaipb.MessageRole_ROLE_ASSISTANT,
+				ToolCalls: []*xaipb.ToolCall{call("call_paris", "Paris"), call("call_tokyo", "Tokyo")},
+			},
+		}},
+	}, ptr(int32(0)))
+
+	fake := newStoringChatClient(time.Now)
+	s := &ChatSession{chat: fake, request: &xaipb.GetCompletionsRequest{Model: "grok-4"}}
+	s.Append(User("weather in Paris and Tokyo?"))
+	s.Append(resp)
+	if n, err := registry.DispatchAll(t.Context(), s, resp); err != nil || n != 2 {
+		t.Fatalf("DispatchAll() = (%d, %v), want (2, nil)", n, err)
+	}
+	if _, err := s.Completion(t.Context()); err != nil {
+		t.Fatalf("Completion() error = %v", err)
+	}
+
+	type result struct{ ID, Result string }
+	var got []result
+	for _, msg := range fake.requests[0].GetMessages() {
+		if msg.GetRole() != xaipb.MessageRole_ROLE_TOOL {
+			continue
+		}
+		id, res := ParseToolResult(msg)
+		got = append(got, result{id, res})
+	}
+	want := []result{
+		{"call_paris", `{"city":"Paris","celsius":21.5}`},
+		{"call_tokyo", `{"city":"Tokyo","celsius":21.5}`},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("tool results sent (-want +got):\n%s", diff)
+	}
+}
+
+// repairingChatClient answers every completion with a get_weather call using args, recording the tool choice.
+type repairingChatClient struct {
+	xaipb.ChatClient
+
+	args    string
+	choices []*xaipb.ToolChoice
+}
+
+func (c *repairingChatClient) GetCompletion(_ context.Context, in *xaipb.GetCompletionsRequest, _ ...grpc.CallOption) (*xaipb.GetChatCompletionResponse, error) {
+	c.choices = append(c.choices, in.GetToolChoice())
+	return &xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{
+				Role: xaipb.MessageRole_ROLE_ASSISTANT,
+				ToolCalls: []*xaipb.ToolCall{{
+					Type: xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL,
+					Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: "get_weather", Arguments: c.args}},
+				}},
+			},
+		}},
+	}, nil
+}
+
+func TestToolRegistryArgumentValidation(t *testing.T) {
+	t.Parallel()
+
+	invalid := newResponse(&xaipb.GetChatCompletionResponse{
+		Outputs: []*xaipb.CompletionOutput{{
+			Message: &xaipb.CompletionMessage{
+				Role: xaipb.MessageRole_ROLE_ASSISTANT,
+				ToolCalls: []*xaipb.ToolCall{{
+					Type: xaipb.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL,
+					Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris","unit":"kelvin"}`}},
+				}},
+			},
+		}},
+	}, ptr(int32(0)))
+
+	tests := map[string]struct {
+		mode        ArgumentValidation
+		repairArgs  string
+		wantN       int
+		wantArgErr  bool
+		wantResult  string
+		wantSamples int
+	}{
+		"off dispatches as is": {
+			mode:       ArgumentValidationOff,
+			wantN:      1,
+			wantResult: `{"city":"Paris","celsius":21.5}`,
+		},
+		"strict rejects": {
+			mode:       ArgumentValidationStrict,
+			wantArgErr: true,
+		},
+		"repair succeeds": {
+			mode:        ArgumentValidationRepair,
+			repairArgs:  `{"city":"Paris","unit":"celsius"}`,
+			wantN:       1,
+			wantResult:  `{"city":"Paris","celsius":21.5}`,
+			wantSamples: 1,
+		},
+		"repair retries once": {
+			mode:        ArgumentValidationRepair,
+			repairArgs:  `{"unit":"celsius"}`,
+			wantArgErr:  true,
+			wantSamples: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			registry, err := NewToolRegistry(weatherTool(t))
+			if err != nil {
+				t.Fatalf("NewToolRegistry() error = %v", err)
+			}
+			registry.SetArgumentValidation(tt.mode)
+
+			fake := &repairingChatClient{args: tt.repairArgs}
+			s := &ChatSession{chat: fake, request: &xaipb.GetCompletionsRequest{Model: "grok-4", ToolChoice: &xaipb.ToolChoice{}}}
+			s.Append(invalid)
+
+			n, err := registry.DispatchAll(t.Context(), s, invalid)
+			var argErr *ToolArgumentsError
+			if got := errors.As(err, &argErr); got != tt.wantArgErr {
+				t.Fatalf("DispatchAll() error = %v, want ToolArgumentsError %t", err, tt.wantArgErr)
+			}
+			if !tt.wantArgErr && err != nil {
+				t.Fatalf("DispatchAll() error = %v", err)
+			}
+			if n != tt.wantN {
+				t.Fatalf("DispatchAll() n = %d, want %d", n, tt.wantN)
+			}
+			if len(fake.choices) != tt.wantSamples {
+				t.Fatalf("repair samples = %d, want %d", len(fake.choices), tt.wantSamples)
+			}
+			for _, choice := range fake.choices {
+				if choice.GetFunctionName() != "get_weather" {
+					t.Fatalf("repair tool choice = %v, want get_weather", choice)
+				}
+			}
+			if s.request.GetToolChoice().GetFunctionName() != "" {
+				t.Fatalf("session tool choice = %v, want restored", s.request.GetToolChoice())
+			}
+			if tt.wantResult != "" {
+				msgs := s.Messages()
+				if got := messageText(msgs[len(msgs)-1]); got != tt.wantResult {
+					t.Fatalf("appended tool result = %q, want %q", got, tt.wantResult)
+				}
+			}
+			if tt.wantSamples > 0 && !strings.Contains(messageText(s.Messages()[1]), `/unit: value "kelvin" is not one of`) {
+				t.Fatalf("repair prompt = %q, want the violation", messageText(s.Messages()[1]))
+			}
+		})
+	}
+}
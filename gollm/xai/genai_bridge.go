@@ -27,10 +27,34 @@ import (
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
+// encryptedSignaturePrefix marks genai thought signatures that carry xAI encrypted reasoning, so signatures from
+// other providers are never sent to xAI.
+const encryptedSignaturePrefix = "xai.encrypted:"
+
+// ThoughtSignature encodes encrypted reasoning content as a genai thought signature.
+//
+// [GenAIContentsToMessages] re-attaches it to the assistant message it came from, which lets encrypted reasoning
+// round-trip through genai histories.
+func ThoughtSignature(encrypted string) []byte {
+	if encrypted == "" {
+		return nil
+	}
+	return []byte(encryptedSignaturePrefix + encrypted)
+}
+
+// encryptedFromThoughtSignature returns the encrypted reasoning content of a signature made by [ThoughtSignature].
+func encryptedFromThoughtSignature(sig []byte) (string, bool) {
+	enc, ok := strings.CutPrefix(string(sig), encryptedSignaturePrefix)
+	return enc, ok && enc != ""
+}
+
 // GenAIContentsToMessages converts a GenerateContent request payload into xAI chat messages.
 // It maps system instruction (when present) and each content entry into the closest xAI shape,
 // preserving roles and tool calling information. Unsupported parts return an error so callers
 // can fail fast instead of silently dropping context.
+//
+// Thought parts are model output and their text is not sent back; a thought signature made by [ThoughtSignature]
+// is re-attached as the encrypted content of the message. Contents holding nothing else are skipped.
 func GenAIContentsToMessages(system *genai.Content, contents []*genai.Content) ([]*xaipb.Message, error) {
 	msgs := make([]*xaipb.Message, 0, len(contents)+1)
 
@@ -43,7 +67,7 @@ func GenAIContentsToMessages(system *genai.Content, contents []*genai.Content) (
 	}
 
 	for i, c := range contents {
-		if c == nil {
+		if c == nil || onlyThoughts(c) {
 			continue
 		}
 		msg, err := genaiContentToMessage(c, xaipb.MessageRole_INVALID_ROLE)
@@ -84,6 +108,11 @@ func genaiContentToMessage(c *genai.Content, overrideRole xaipb.MessageRole) (*x
 		}
 
 		switch {
+		case part.Thought:
+			if enc, ok := encryptedFromThoughtSignature(part.ThoughtSignature); ok {
+				msg.EncryptedContent += enc
+			}
+
 		case part.Text != "":
 			msg.Content = append(msg.Content, TextContent(part.Text))
 
@@ -105,23 +134,56 @@ func genaiContentToMessage(c *genai.Content, overrideRole xaipb.MessageRole) (*x
 			msg.Content = append(msg.Content, TextContent(payload))
 
 		case part.FileData != nil:
+			if strings.HasPrefix(part.FileData.MIMEType, "image/") && strings.HasPrefix(part.FileData.FileURI, "https://") {
+				msg.Content = append(msg.Content, ImageContent(part.FileData.FileURI, xaipb.ImageDetail_DETAIL_AUTO))
+				continue
+			}
 			msg.Content = append(msg.Content, FileContent(part.FileData.FileURI))
 
 		case part.InlineData != nil:
-			return nil, fmt.Errorf("part[%d]: inline data is not supported by xAI chat", pi)
+			switch mimeType := part.InlineData.MIMEType; {
+			case strings.HasPrefix(mimeType, "image/"):
+				img, err := ImageContentFromBytes(part.InlineData.Data, mimeType)
+				if err != nil {
+					return nil, fmt.Errorf("part[%d] inline image: %w", pi, err)
+				}
+				msg.Content = append(msg.Content, img)
+			case strings.HasPrefix(mimeType, "text/"):
+				msg.Content = append(msg.Content, TextContent(string(part.InlineData.Data)))
+			default:
+				return nil, fmt.Errorf("part[%d]: inline %s data is not supported by xAI chat; upload the file and pass it as file data", pi, mimeType)
+			}
 
 		default:
 			return nil, fmt.Errorf("part[%d]: unsupported part", pi)
 		}
 	}
 
-	if len(msg.GetContent()) == 0 && len(msg.GetToolCalls()) == 0 {
+	if len(msg.GetContent()) == 0 && len(msg.GetToolCalls()) == 0 && msg.GetEncryptedContent() == "" {
 		return nil, errors.New("message has neither content nor tool calls")
 	}
 
 	return msg, nil
 }
 
+// onlyThoughts reports whether c has thought parts without xAI encrypted content and nothing else.
+func onlyThoughts(c *genai.Content) bool {
+	thoughts := false
+	for _, part := range c.Parts {
+		if part == nil {
+			continue
+		}
+		if !part.Thought {
+			return false
+		}
+		if _, ok := encryptedFromThoughtSignature(part.ThoughtSignature); ok {
+			return false
+		}
+		thoughts = true
+	}
+	return thoughts
+}
+
 func mapGenAIRole(role string) (xaipb.MessageRole, error) {
 	switch strings.ToLower(role) {
 	case "", string(genai.RoleUser):
@@ -181,7 +243,7 @@ func encodeFunctionResponse(fr *genai.FunctionResponse) (string, error) {
 	}
 
 	if fr.ID != "" {
-		payload["tool_call_id"] = fr.ID
+		payload[ToolCallIDKey] = fr.ID
 	}
 	if fr.Response != nil {
 		payload["response"] = fr.Response
@@ -0,0 +1,159 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+func TestGenAIContentsToMessagesMedia(t *testing.T) {
+	t.Parallel()
+
+	pngData := encodeTestImage(t, "png", 2, 2)
+	tests := map[string]struct {
+		part      *genai.Part
+		wantImage string
+		wantText  string
+		wantFile  string
+		wantErr   bool
+	}{
+		"inline image": {
+			part:      genai.NewPartFromBytes(pngData, "image/png"),
+			wantImage: "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData),
+		},
+		"inline gif": {
+			part:    genai.NewPartFromBytes(encodeTestImage(t, "gif", 2, 2), "image/gif"),
+			wantErr: true,
+		},
+		"inline text": {
+			part:     genai.NewPartFromBytes([]byte("notes"), "text/markdown"),
+			wantText: "notes",
+		},
+		"inline pdf": {
+			part:    genai.NewPartFromBytes([]byte("pdf"), "application/pdf"),
+			wantErr: true,
+		},
+		"image url": {
+			part:      genai.NewPartFromURI("https://example.com/cat.jpg", "image/jpeg"),
+			wantImage: "https://example.com/cat.jpg",
+		},
+		"uploaded file": {
+			part:     genai.NewPartFromURI("file-123", "application/pdf"),
+			wantFile: "file-123",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			msgs, err := GenAIContentsToMessages(nil, []*genai.Content{{Role: genai.RoleUser, Parts: []*genai.Part{tt.part}}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenAIContentsToMessages() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(msgs) != 1 || len(msgs[0].GetContent()) != 1 {
+				t.Fatalf("messages = %v, want one message with one content", msgs)
+			}
+			c := msgs[0].GetContent()[0]
+			if got := c.GetImageUrl().GetImageUrl(); got != tt.wantImage {
+				t.Fatalf("image url = %q, want %q", got, tt.wantImage)
+			}
+			if got := c.GetText(); got != tt.wantText {
+				t.Fatalf("text = %q, want %q", got, tt.wantText)
+			}
+			if got := c.GetFile().GetFileId(); got != tt.wantFile {
+				t.Fatalf("file id = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestGenAIContentsToMessagesThoughts(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		contents      []*genai.Content
+		wantTexts     []string
+		wantEncrypted []string
+	}{
+		"thought text dropped": {
+			contents: []*genai.Content{
+				genai.NewContentFromText("q", genai.RoleUser),
+				{Role: genai.RoleModel, Parts: []*genai.Part{{Text: "private", Thought: true}, genai.NewPartFromText("a")}},
+			},
+			wantTexts:     []string{"q", "a"},
+			wantEncrypted: []string{"", ""},
+		},
+		"encrypted content re-attached": {
+			contents: []*genai.Content{
+				genai.NewContentFromText("q", genai.RoleUser),
+				{Role: genai.RoleModel, Parts: []*genai.Part{
+					{Text: "private", Thought: true, ThoughtSignature: ThoughtSignature("blob")},
+					genai.NewPartFromText("a"),
+				}},
+			},
+			wantTexts:     []string{"q", "a"},
+			wantEncrypted: []string{"", "blob"},
+		},
+		"encrypted content only": {
+			contents: []*genai.Content{
+				genai.NewContentFromText("q", genai.RoleUser),
+				{Role: genai.RoleModel, Parts: []*genai.Part{{Thought: true, ThoughtSignature: ThoughtSignature("blob")}}},
+			},
+			wantTexts:     []string{"q", ""},
+			wantEncrypted: []string{"", "blob"},
+		},
+		"foreign signature and thought-only content skipped": {
+			contents: []*genai.Content{
+				genai.NewContentFromText("q", genai.RoleUser),
+				{Role: genai.RoleModel, Parts: []*genai.Part{{Text: "private", Thought: true, ThoughtSignature: []byte("gemini")}}},
+			},
+			wantTexts:     []string{"q"},
+			wantEncrypted: []string{""},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			msgs, err := GenAIContentsToMessages(nil, tt.contents)
+			if err != nil {
+				t.Fatalf("GenAIContentsToMessages() error = %v", err)
+			}
+			texts := make([]string, len(msgs))
+			encrypted := make([]string, len(msgs))
+			for i, msg := range msgs {
+				texts[i] = messageText(msg)
+				encrypted[i] = msg.GetEncryptedContent()
+			}
+			if diff := cmp.Diff(tt.wantTexts, texts); diff != "" {
+				t.Fatalf("message texts mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantEncrypted, encrypted); diff != "" {
+				t.Fatalf("encrypted content mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
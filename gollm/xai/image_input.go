@@ -0,0 +1,169 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// Limits applied by [ImageContentFromBytes] and [ImageContentFromFile] to local images.
+const (
+	// MaxImageBytes is the largest encoded image the chat API accepts.
+	MaxImageBytes = 10 << 20
+	// MaxImageDimension is the longest image side sent; larger images are downscaled to fit, since the server
+	// would downscale them anyway.
+	MaxImageDimension = 2048
+)
+
+const (
+	mimeJPEG = "image/jpeg"
+	mimePNG  = "image/png"
+
+	// minImageDimension bounds the shrinking of images that stay over MaxImageBytes after downscaling.
+	minImageDimension = 64
+	jpegQuality       = 90
+)
+
+// ImageContentFromFile reads a local JPEG or PNG image into an image content entry, see [ImageContentFromBytes].
+func ImageContentFromFile(path string) (*xaipb.Content, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	return ImageContentFromBytes(data, mime.TypeByExtension(strings.ToLower(filepath.Ext(path))))
+}
+
+// ImageContentFromBytes creates an image content entry from an encoded JPEG or PNG image, sent inline as a base64
+// data URI.
+//
+// The content type is detected from data; a non-empty mimeType must agree with it. Images larger than
+// [MaxImageDimension] or [MaxImageBytes] are downscaled and re-encoded in their original format.
+func ImageContentFromBytes(data []byte, mimeType string) (*xaipb.Content, error) {
+	if len(data) == 0 {
+		return nil, errors.New("image data is empty")
+	}
+
+	detected := http.DetectContentType(data)
+	if detected != mimeJPEG && detected != mimePNG {
+		return nil, fmt.Errorf("unsupported image format %q: want %s or %s", detected, mimeJPEG, mimePNG)
+	}
+	if mimeType != "" {
+		mediaType, _, err := mime.ParseMediaType(mimeType)
+		if err != nil {
+			return nil, fmt.Errorf("parse image content type: %w", err)
+		}
+		if mediaType != detected {
+			return nil, fmt.Errorf("image content type %q does not match detected %q", mediaType, detected)
+		}
+	}
+
+	data, err := fitImage(data, detected)
+	if err != nil {
+		return nil, err
+	}
+
+	url := "data:" + detected + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return ImageContent(url, xaipb.ImageDetail_DETAIL_AUTO), nil
+}
+
+// fitImage downscales data until it is within MaxImageDimension and MaxImageBytes, returning it as is when it fits.
+func fitImage(data []byte, mimeType string) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if max(cfg.Width, cfg.Height) <= MaxImageDimension && len(data) <= MaxImageBytes {
+		return data, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	scale := min(1, float64(MaxImageDimension)/float64(max(cfg.Width, cfg.Height)))
+	for {
+		w := max(int(float64(cfg.Width)*scale), 1)
+		h := max(int(float64(cfg.Height)*scale), 1)
+
+		var buf bytes.Buffer
+		dst := resizeImage(src, w, h)
+		if mimeType == mimePNG {
+			err = png.Encode(&buf, dst)
+		} else {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode image: %w", err)
+		}
+		if buf.Len() <= MaxImageBytes {
+			return buf.Bytes(), nil
+		}
+		if max(w, h) <= minImageDimension {
+			return nil, fmt.Errorf("image exceeds %d bytes even at %dx%d", MaxImageBytes, w, h)
+		}
+		scale *= 0.75
+	}
+}
+
+// resizeImage downscales src to w x h by averaging the source pixels covered by each destination pixel.
+func resizeImage(src image.Image, w, h int) *image.RGBA64 {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0 := b.Min.Y + y*sh/h
+		y1 := max(b.Min.Y+(y+1)*sh/h, y0+1)
+		for x := range w {
+			x0 := b.Min.X + x*sw/w
+			x1 := max(b.Min.X+(x+1)*sw/w, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),  //nolint:gosec // average of 16-bit values
+				G: uint16(g / n),  //nolint:gosec // average of 16-bit values
+				B: uint16(bl / n), //nolint:gosec // average of 16-bit values
+				A: uint16(a / n),  //nolint:gosec // average of 16-bit values
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func encodeTestImage(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255}) //nolint:gosec // test pattern
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// decodeImageContent returns the media type and decoded image of a data URI image content.
+func decodeImageContent(t *testing.T, c *xaipb.Content) (string, image.Image) {
+	t.Helper()
+
+	if c.GetImageUrl().GetDetail() != xaipb.ImageDetail_DETAIL_AUTO {
+		t.Fatalf("detail = %v, want auto", c.GetImageUrl().GetDetail())
+	}
+	header, payload, ok := strings.Cut(c.GetImageUrl().GetImageUrl(), ",")
+	if !ok {
+		t.Fatalf("image url %q is not a data URI", c.GetImageUrl().GetImageUrl())
+	}
+	mediaType, ok := strings.CutSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	if !ok {
+		t.Fatalf("data URI header %q is not base64", header)
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatalf("decode data URI: %v", err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode image: %v", err)
+	}
+	return mediaType, img
+}
+
+func TestImageContentFromBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data     []byte
+		mimeType string
+		wantType string
+		wantSize image.Point
+		wantErr  bool
+	}{
+		"png detected": {
+			data:     encodeTestImage(t, "png", 40, 30),
+			wantType: "image/png",
+			wantSize: image.Pt(40, 30),
+		},
+		"jpeg with content type": {
+			data:     encodeTestImage(t, "jpeg", 40, 30),
+			mimeType: "image/jpeg",
+			wantType: "image/jpeg",
+			wantSize: image.Pt(40, 30),
+		},
+		"oversized png downscaled": {
+			data:     encodeTestImage(t, "png", 3000, 150),
+			wantType: "image/png",
+			wantSize: image.Pt(MaxImageDimension, 102),
+		},
+		"oversized jpeg downscaled": {
+			data:     encodeTestImage(t, "jpeg", 100, 4096),
+			wantType: "image/jpeg",
+			wantSize: image.Pt(50, MaxImageDimension),
+		},
+		"unsupported gif": {
+			data:    encodeTestImage(t, "gif", 4, 4),
+			wantErr: true,
+		},
+		"content type mismatch": {
+			data:     encodeTestImage(t, "png", 4, 4),
+			mimeType: "image/jpeg",
+			wantErr:  true,
+		},
+		"not an image": {
+			data:    []byte("hello"),
+			wantErr: true,
+		},
+		"empty": {
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := ImageContentFromBytes(tt.data, tt.mimeType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImageContentFromBytes() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			mediaType, img := decodeImageContent(t, c)
+			if mediaType != tt.wantType {
+				t.Fatalf("media type = %q, want %q", mediaType, tt.wantType)
+			}
+			if got := img.Bounds().Size(); got != tt.wantSize {
+				t.Fatalf("image size = %v, want %v", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestImageContentFromBytesKeepsSmallImages(t *testing.T) {
+	t.Parallel()
+
+	data := encodeTestImage(t, "png", 8, 8)
+	c, err := ImageContentFromBytes(data, "")
+	if err != nil {
+		t.Fatalf("ImageContentFromBytes() error = %v", err)
+	}
+	want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+	if got := c.GetImageUrl().GetImageUrl(); got != want {
+		t.Fatalf("image url = %q, want the original bytes", got)
+	}
+}
+
+func TestImageContentFromFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chart.JPG")
+	if err := os.WriteFile(path, encodeTestImage(t, "jpeg", 16, 16), 0o600); err != nil {
+		t.Fatalf("write image: %v", err)
+	}
+
+	c, err := ImageContentFromFile(path)
+	if err != nil {
+		t.Fatalf("ImageContentFromFile() error = %v", err)
+	}
+	if mediaType, _ := decodeImageContent(t, c); mediaType != "image/jpeg" {
+		t.Fatalf("media type = %q, want image/jpeg", mediaType)
+	}
+
+	misnamed := filepath.Join(dir, "chart.png")
+	if err := os.WriteFile(misnamed, encodeTestImage(t, "jpeg", 16, 16), 0o600); err != nil {
+		t.Fatalf("write image: %v", err)
+	}
+	if _, err := ImageContentFromFile(misnamed); err == nil {
+		t.Fatal("ImageContentFromFile() with mismatched extension error = nil, want error")
+	}
+	if _, err := ImageContentFromFile(filepath.Join(dir, "missing.png")); err == nil {
+		t.Fatal("ImageContentFromFile() missing file error = nil, want error")
+	}
+}
@@ -39,28 +39,29 @@ func AuthStreamInterceptor(token string, md map[string]string) grpc.StreamClient
 	}
 }
 
+// TimeoutUnaryInterceptor bounds unary RPCs by the timeout of their [WithCallTimeout] call option, or by timeout
+// when they have none and their context has no deadline.
 func TimeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
-		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+		if d := rpcTimeout(ctx, timeout, callOpts); d > 0 {
 			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(ctx, timeout)
+			ctx, cancel = context.WithTimeout(ctx, d)
 			defer cancel()
 		}
 		return invoker(ctx, method, req, reply, cc, callOpts...)
 	}
 }
 
+// TimeoutStreamInterceptor bounds streams like [TimeoutUnaryInterceptor], from their start until they end.
 func TimeoutStreamInterceptor(timeout time.Duration) grpc.StreamClientInterceptor {
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
-		if timeout <= 0 {
-			return streamer(ctx, desc, cc, method, callOpts...)
-		}
-		if _, ok := ctx.Deadline(); ok {
+		d := rpcTimeout(ctx, timeout, callOpts)
+		if d <= 0 {
 			return streamer(ctx, desc, cc, method, callOpts...)
 		}
 
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, timeout)
+		ctx, cancel = context.WithTimeout(ctx, d)
 
 		stream, err := streamer(ctx, desc, cc, method, callOpts...)
 		if err != nil {
@@ -75,6 +76,18 @@ func TimeoutStreamInterceptor(timeout time.Duration) grpc.StreamClientIntercepto
 	}
 }
 
+// rpcTimeout returns the timeout to bound an RPC by: the per-call timeout of callOpts, or def when the context has no
+// deadline, or zero for none.
+func rpcTimeout(ctx context.Context, def time.Duration, callOpts []grpc.CallOption) time.Duration {
+	if d := callTimeout(callOpts); d > 0 {
+		return d
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return 0
+	}
+	return def
+}
+
 type cancelOnCloseClientStream struct {
 	grpc.ClientStream
 
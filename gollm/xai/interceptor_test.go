@@ -134,6 +134,67 @@ func TestTimeoutStreamInterceptor_CloseSendDoesNotCancel(t *testing.T) {
 	}
 }
 
+func TestTimeoutUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	const def = time.Hour
+	tests := map[string]struct {
+		deadline time.Duration
+		callOpts []grpc.CallOption
+		want     time.Duration
+	}{
+		"default": {
+			want: def,
+		},
+		"context deadline": {
+			deadline: time.Minute,
+			want:     time.Minute,
+		},
+		"call timeout": {
+			callOpts: []grpc.CallOption{WithCallTimeout(time.Second)},
+			want:     time.Second,
+		},
+		"call timeout within context deadline": {
+			deadline: time.Minute,
+			callOpts: []grpc.CallOption{grpc.WaitForReady(true), WithCallTimeout(time.Second)},
+			want:     time.Second,
+		},
+		"context deadline before call timeout": {
+			deadline: time.Second,
+			callOpts: []grpc.CallOption{WithCallTimeout(time.Minute)},
+			want:     time.Second,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			start := time.Now()
+			var got time.Duration
+			invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				if deadline, ok := ctx.Deadline(); ok {
+					got = deadline.Sub(start)
+				}
+				return nil
+			}
+			if err := TimeoutUnaryInterceptor(def)(ctx, "/xai.Files/RetrieveFile", nil, nil, nil, invoker, tt.callOpts...); err != nil {
+				t.Fatalf("interceptor returned error: %v", err)
+			}
+			if diff := got - tt.want; diff < -time.Second/2 || diff > time.Second/2 {
+				t.Fatalf("deadline in %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 type noopClientStream struct {
 	ctx       context.Context
 	recvCalls int
@@ -0,0 +1,318 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// LogOptions configures the RPC logging of [WithLogger].
+type LogOptions struct {
+	// RedactPrompts replaces every string of the logged payloads, except ids and model names, with its length, and
+	// drops bytes fields, so no prompt or completion content reaches the logs.
+	RedactPrompts bool
+
+	// MaxBodyBytes is the maximum size of the request and response snippets logged with each RPC. Zero logs no
+	// payloads.
+	MaxBodyBytes int
+}
+
+// WithLogger logs every RPC of the client to h: its method, model, duration, status code, and token usage, plus
+// payload snippets when opts.MaxBodyBytes is set. API keys, bearer tokens, and email addresses are always redacted
+// from the snippets; set opts.RedactPrompts to keep prompt contents out of the logs too.
+//
+// Successful RPCs are logged at [slog.LevelInfo] and failed ones at [slog.LevelWarn]. A stream is logged when it
+// ends, so streams that are abandoned before reading to the end are not logged.
+func WithLogger(h slog.Handler, opts LogOptions) ClientOption {
+	return func(o *clientOptions) {
+		o.logger = slog.New(h)
+		o.logOptions = opts
+	}
+}
+
+// secretPatterns match credentials and personal data redacted from every logged payload.
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\b(?:xai|sk)-[A-Za-z0-9_\-]{16,}`), "[REDACTED_KEY]"},
+	{regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/\-]+=*`), "Bearer [REDACTED]"},
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+}
+
+// rpcLogger logs RPCs according to its options.
+type rpcLogger struct {
+	logger *slog.Logger
+	opts   LogOptions
+}
+
+// LoggingUnaryInterceptor logs every unary call to logger; see [WithLogger].
+func LoggingUnaryInterceptor(logger *slog.Logger, opts LogOptions) grpc.UnaryClientInterceptor {
+	l := &rpcLogger{logger: logger, opts: opts}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		var resp any
+		if err == nil {
+			resp = reply
+		}
+		l.log(ctx, method, req, resp, usageOf(resp), 0, time.Since(start), err)
+		return err
+	}
+}
+
+// LoggingStreamInterceptor logs every stream to logger when it ends; see [WithLogger].
+func LoggingStreamInterceptor(logger *slog.Logger, opts LogOptions) grpc.StreamClientInterceptor {
+	l := &rpcLogger{logger: logger, opts: opts}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			l.log(ctx, method, nil, nil, nil, 0, time.Since(start), err)
+			return stream, err
+		}
+		return &loggingClientStream{ClientStream: stream, ctx: ctx, method: method, start: start, logger: l}, nil
+	}
+}
+
+type loggingClientStream struct {
+	grpc.ClientStream
+
+	ctx    context.Context
+	method string
+	start  time.Time
+	logger *rpcLogger
+
+	mu       sync.Mutex
+	req      any
+	last     any
+	usage    any
+	received int
+	once     sync.Once
+}
+
+func (s *loggingClientStream) SendMsg(m any) error {
+	s.mu.Lock()
+	if s.req == nil {
+		s.req = m
+	}
+	s.mu.Unlock()
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *loggingClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.mu.Lock()
+	if err == nil {
+		s.received++
+		s.last = m
+		if u := usageOf(m); u != nil {
+			s.usage = u
+		}
+	}
+	req, last, usage, received := s.req, s.last, s.usage, s.received
+	s.mu.Unlock()
+
+	if err != nil {
+		s.once.Do(func() {
+			logErr := err
+			if errors.Is(err, io.EOF) {
+				logErr = nil
+			}
+			s.logger.log(s.ctx, s.method, req, last, usage, received, time.Since(s.start), logErr)
+		})
+	}
+	return err
+}
+
+// usageOf returns the token usage of a response message, if any.
+func usageOf(m any) any {
+	switch m := m.(type) {
+	case interface{ GetUsage() *xaipb.SamplingUsage }:
+		if u := m.GetUsage(); u != nil {
+			return u
+		}
+	case interface{ GetUsage() *xaipb.EmbeddingUsage }:
+		if u := m.GetUsage(); u != nil {
+			return u
+		}
+	}
+	return nil
+}
+
+// log logs an RPC. received is the number of stream messages, zero for unary calls; resp is the last response.
+func (l *rpcLogger) log(ctx context.Context, method string, req, resp, usage any, received int, d time.Duration, err error) {
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.Duration("duration", d),
+		slog.String("code", status.Code(err).String()),
+	}
+	if model := modelOf(req); model != "" {
+		attrs = append(attrs, slog.String("model", model))
+	}
+	if received > 0 {
+		attrs = append(attrs, slog.Int("messages", received))
+	}
+	switch u := usage.(type) {
+	case *xaipb.SamplingUsage:
+		attrs = append(attrs, slog.Group("usage",
+			slog.Int("prompt_tokens", int(u.GetPromptTokens())),
+			slog.Int("cached_prompt_tokens", int(u.GetCachedPromptTextTokens())),
+			slog.Int("completion_tokens", int(u.GetCompletionTokens())),
+			slog.Int("reasoning_tokens", int(u.GetReasoningTokens())),
+		))
+	case *xaipb.EmbeddingUsage:
+		attrs = append(attrs, slog.Group("usage",
+			slog.Int("text_embeddings", int(u.GetNumTextEmbeddings())),
+			slog.Int("image_embeddings", int(u.GetNumImageEmbeddings())),
+		))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", l.snippet(status.Convert(err).Message())))
+	}
+	if l.opts.MaxBodyBytes > 0 {
+		if req != nil {
+			attrs = append(attrs, slog.String("request", l.payload(req)))
+		}
+		if resp != nil {
+			attrs = append(attrs, slog.String("response", l.payload(resp)))
+		}
+	}
+	l.logger.LogAttrs(ctx, level, "xai rpc", attrs...)
+}
+
+// payload returns the redacted JSON snippet of a message.
+func (l *rpcLogger) payload(m any) string {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Sprintf("%T", m)
+	}
+	if l.opts.RedactPrompts {
+		msg = proto.Clone(msg)
+		redactMessage(msg.ProtoReflect())
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("%T", m)
+	}
+	return l.snippet(string(b))
+}
+
+// snippet redacts secrets from s and truncates it to MaxBodyBytes when set.
+func (l *rpcLogger) snippet(s string) string {
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	if l.opts.MaxBodyBytes <= 0 || len(s) <= l.opts.MaxBodyBytes {
+		return s
+	}
+	cut := l.opts.MaxBodyBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// logSafeField reports whether the strings of field identify resources rather than carry content.
+func logSafeField(fd protoreflect.FieldDescriptor) bool {
+	name := string(fd.Name())
+	return name == "id" || name == "model" || name == "system_fingerprint" ||
+		strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "_ids")
+}
+
+// redactMessage replaces the strings of m, except those of logSafeField fields, with their length and clears its
+// bytes fields, recursively.
+func redactMessage(m protoreflect.Message) {
+	type field struct {
+		fd protoreflect.FieldDescriptor
+		v  protoreflect.Value
+	}
+	var fields []field
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fields = append(fields, field{fd, v})
+		return true
+	})
+
+	for _, f := range fields {
+		switch {
+		case f.fd.IsMap():
+			mv := f.v.Map()
+			mv.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				switch f.fd.MapValue().Kind() {
+				case protoreflect.MessageKind, protoreflect.GroupKind:
+					redactMessage(v.Message())
+				case protoreflect.StringKind:
+					mv.Set(k, redactedString(v.String()))
+				case protoreflect.BytesKind:
+					mv.Set(k, protoreflect.ValueOfBytes(nil))
+				}
+				return true
+			})
+		case f.fd.IsList():
+			list := f.v.List()
+			for i := range list.Len() {
+				switch f.fd.Kind() {
+				case protoreflect.MessageKind, protoreflect.GroupKind:
+					redactMessage(list.Get(i).Message())
+				case protoreflect.StringKind:
+					if !logSafeField(f.fd) {
+						list.Set(i, redactedString(list.Get(i).String()))
+					}
+				case protoreflect.BytesKind:
+					list.Set(i, protoreflect.ValueOfBytes(nil))
+				}
+			}
+		case f.fd.Kind() == protoreflect.MessageKind || f.fd.Kind() == protoreflect.GroupKind:
+			redactMessage(f.v.Message())
+		case f.fd.Kind() == protoreflect.StringKind:
+			if !logSafeField(f.fd) {
+				m.Set(f.fd, redactedString(f.v.String()))
+			}
+		case f.fd.Kind() == protoreflect.BytesKind:
+			m.Clear(f.fd)
+		}
+	}
+}
+
+func redactedString(s string) protoreflect.Value {
+	return protoreflect.ValueOfString(fmt.Sprintf("[REDACTED %d bytes]", len(s)))
+}
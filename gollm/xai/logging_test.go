@@ -0,0 +1,195 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func TestLoggingUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	req := &xaipb.GetCompletionsRequest{
+		Model: "grok-4",
+		Messages: []*xaipb.Message{{
+			Content: []*xaipb.Content{{Content: &xaipb.Content_Text{Text: "my secret plan, key xai-abcdefghijklmnopqrstuvwxyz, mail bob@example.com"}}},
+		}},
+	}
+	want := &xaipb.GetChatCompletionResponse{
+		Id:    "resp-1",
+		Usage: &xaipb.SamplingUsage{PromptTokens: 12, CompletionTokens: 34},
+	}
+
+	tests := map[string]struct {
+		opts        LogOptions
+		invokeErr   error
+		wantLevel   string
+		wantContain []string
+		wantAbsent  []string
+	}{
+		"success without payloads": {
+			opts:        LogOptions{},
+			wantLevel:   "level=INFO",
+			wantContain: []string{"method=/xai_api.Chat/GetCompletion", "model=grok-4", "code=OK", "usage.prompt_tokens=12", "usage.completion_tokens=34"},
+			wantAbsent:  []string{"request=", "response=", "secret plan"},
+		},
+		"payloads redact secrets": {
+			opts:        LogOptions{MaxBodyBytes: 4096},
+			wantLevel:   "level=INFO",
+			wantContain: []string{"secret plan", "[REDACTED_KEY]", "[REDACTED_EMAIL]", "resp-1"},
+			wantAbsent:  []string{"xai-abcdefghijklmnopqrstuvwxyz", "bob@example.com"},
+		},
+		"payloads redact prompts": {
+			opts:        LogOptions{MaxBodyBytes: 4096, RedactPrompts: true},
+			wantLevel:   "level=INFO",
+			wantContain: []string{"grok-4", "[REDACTED 72 bytes]", "resp-1"},
+			wantAbsent:  []string{"secret plan"},
+		},
+		"failure": {
+			opts:        LogOptions{},
+			invokeErr:   status.Error(codes.ResourceExhausted, "rate limited for Bearer abc.def"),
+			wantLevel:   "level=WARN",
+			wantContain: []string{"code=ResourceExhausted", "Bearer [REDACTED]"},
+			wantAbsent:  []string{"abc.def", "usage."},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			interceptor := LoggingUnaryInterceptor(newTestLogger(&buf), tt.opts)
+			err := interceptor(t.Context(), "/xai_api.Chat/GetCompletion", req, &xaipb.GetChatCompletionResponse{}, nil,
+				func(_ context.Context, _ string, _, got any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+					if tt.invokeErr != nil {
+						return tt.invokeErr
+					}
+					proto.Merge(got.(proto.Message), want)
+					return nil
+				},
+			)
+			if err != tt.invokeErr { //nolint:errorlint
+				t.Fatalf("interceptor error = %v, want %v", err, tt.invokeErr)
+			}
+
+			out := buf.String()
+			if !strings.Contains(out, tt.wantLevel) {
+				t.Fatalf("log %q does not contain %q", out, tt.wantLevel)
+			}
+			for _, s := range tt.wantContain {
+				if !strings.Contains(out, s) {
+					t.Errorf("log %q does not contain %q", out, s)
+				}
+			}
+			for _, s := range tt.wantAbsent {
+				if strings.Contains(out, s) {
+					t.Errorf("log %q contains %q", out, s)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingStreamInterceptor(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	interceptor := LoggingStreamInterceptor(newTestLogger(&buf), LogOptions{})
+
+	chunks := []*xaipb.GetChatCompletionChunk{
+		{Id: "chunk-1"},
+		{Id: "chunk-2", Usage: &xaipb.SamplingUsage{PromptTokens: 5, CompletionTokens: 7}},
+	}
+	stream, err := interceptor(t.Context(), &grpc.StreamDesc{ServerStreams: true}, nil, "/xai_api.Chat/GetCompletionChunk",
+		func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &chunkClientStream{noopClientStream: noopClientStream{ctx: ctx}, chunks: chunks}, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("interceptor returned error: %v", err)
+	}
+	if err := stream.SendMsg(&xaipb.GetCompletionsRequest{Model: "grok-4"}); err != nil {
+		t.Fatalf("SendMsg error: %v", err)
+	}
+
+	for {
+		var chunk xaipb.GetChatCompletionChunk
+		if err := stream.RecvMsg(&chunk); err != nil {
+			if err != io.EOF { //nolint:errorlint
+				t.Fatalf("RecvMsg error: %v", err)
+			}
+			break
+		}
+		if buf.Len() > 0 {
+			t.Fatalf("stream logged before it ended: %q", buf.String())
+		}
+	}
+
+	out := buf.String()
+	for _, s := range []string{"level=INFO", "model=grok-4", "code=OK", "messages=2", "usage.prompt_tokens=5", "usage.completion_tokens=7"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("log %q does not contain %q", out, s)
+		}
+	}
+	if n := strings.Count(out, "xai rpc"); n != 1 {
+		t.Fatalf("stream logged %d times, want 1", n)
+	}
+}
+
+func TestRPCLoggerSnippetTruncates(t *testing.T) {
+	t.Parallel()
+
+	l := &rpcLogger{opts: LogOptions{MaxBodyBytes: 5}}
+	if got, want := l.snippet("héllo world"), "héll…"; got != want {
+		t.Fatalf("snippet = %q, want %q", got, want)
+	}
+	if got, want := l.snippet("hi"), "hi"; got != want {
+		t.Fatalf("snippet = %q, want %q", got, want)
+	}
+}
+
+type chunkClientStream struct {
+	noopClientStream
+
+	chunks []*xaipb.GetChatCompletionChunk
+}
+
+func (s *chunkClientStream) RecvMsg(m any) error {
+	if len(s.chunks) == 0 {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.chunks[0])
+	s.chunks = s.chunks[1:]
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+)
+
+var meter = otel.Meter("github.com/zchee/tumix/gollm/xai")
+
+// RateLimiter is a client-side token-bucket limiter with one bucket per RPC method and model.
+//
+// Every bucket refills at the same rate, so a limit of 2 rps allows 2 chat completions and 2 embeddings per second
+// for each model. Time spent waiting is recorded in the "xai.client.rate_limit.wait" histogram (seconds) of the
+// global OpenTelemetry meter provider. A RateLimiter is safe for concurrent use and may be shared by several
+// clients with [WithRateLimiter].
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	waitHist metric.Float64Histogram
+
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*bucket
+}
+
+type rateLimitKey struct {
+	method string
+	model  string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rps requests per second per method and model with bursts of up to
+// burst requests. A burst below one defaults to max(1, ceil(rps)).
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = max(1, int(math.Ceil(rps)))
+	}
+	hist, _ := meter.Float64Histogram("xai.client.rate_limit.wait",
+		metric.WithDescription("Time requests waited for the client-side rate limiter."),
+		metric.WithUnit("s"),
+	)
+	return &RateLimiter{
+		rate:     rps,
+		burst:    float64(burst),
+		now:      time.Now,
+		waitHist: hist,
+		buckets:  make(map[rateLimitKey]*bucket),
+	}
+}
+
+// Wait blocks until a request of method for model may proceed and returns the time waited.
+//
+// It returns the context error if ctx is done first; the reserved token is then released.
+func (l *RateLimiter) Wait(ctx context.Context, method, model string) (time.Duration, error) {
+	if l == nil || l.rate <= 0 {
+		return 0, nil
+	}
+
+	key := rateLimitKey{method: method, model: model}
+	delay := l.reserve(key)
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			l.release(key)
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.waitHist != nil {
+		l.waitHist.Record(ctx, delay.Seconds(), metric.WithAttributes(
+			attribute.String("rpc.method", method),
+			attribute.String("gen_ai.request.model", model),
+		))
+	}
+	return delay, nil
+}
+
+// reserve takes a token from the bucket of key and returns how long to wait until it is available.
+func (l *RateLimiter) reserve(key rateLimitKey) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+func (l *RateLimiter) release(key rateLimitKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = min(l.burst, b.tokens+1)
+	}
+}
+
+// WithRateLimit limits the client to rps requests per second per RPC method and model, with bursts of up to burst.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return WithRateLimiter(NewRateLimiter(rps, burst))
+}
+
+// WithRateLimiter shares limiter with the client, so several clients draw from the same buckets.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(o *clientOptions) {
+		o.rateLimiter = limiter
+	}
+}
+
+// modelOf returns the model named by a request message, if any.
+func modelOf(req any) string {
+	if m, ok := req.(interface{ GetModel() string }); ok {
+		return m.GetModel()
+	}
+	return ""
+}
+
+// RateLimitUnaryInterceptor waits for limiter before every unary call.
+func RateLimitUnaryInterceptor(limiter *RateLimiter) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if _, err := limiter.Wait(ctx, method, modelOf(req)); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
+
+// RateLimitStreamInterceptor waits for limiter before the first message of every stream, when the model of the
+// request is known.
+func RateLimitStreamInterceptor(limiter *RateLimiter) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			return stream, err
+		}
+		return &rateLimitedClientStream{
+			ClientStream: stream,
+			ctx:          ctx,
+			method:       method,
+			limiter:      limiter,
+		}, nil
+	}
+}
+
+type rateLimitedClientStream struct {
+	grpc.ClientStream
+
+	ctx     context.Context
+	method  string
+	limiter *RateLimiter
+	once    sync.Once
+}
+
+func (s *rateLimitedClientStream) SendMsg(m any) error {
+	var err error
+	s.once.Do(func() {
+		_, err = s.limiter.Wait(s.ctx, s.method, modelOf(m))
+	})
+	if err != nil {
+		return err
+	}
+	return s.ClientStream.SendMsg(m)
+}
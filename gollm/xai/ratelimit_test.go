@@ -0,0 +1,132 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	t.Parallel()
+
+	type step struct {
+		advance time.Duration
+		key     rateLimitKey
+	}
+	chat := rateLimitKey{method: "/xai_api.Chat/GetCompletion", model: "grok-4"}
+	embed := rateLimitKey{method: "/xai_api.Embedder/Embed", model: "grok-4"}
+
+	tests := map[string]struct {
+		rps   float64
+		burst int
+		steps []step
+		want  []time.Duration
+	}{
+		"burst then wait": {
+			rps:   2,
+			burst: 2,
+			steps: []step{{key: chat}, {key: chat}, {key: chat}, {key: chat}},
+			want:  []time.Duration{0, 0, 500 * time.Millisecond, time.Second},
+		},
+		"refill over time": {
+			rps:   2,
+			burst: 1,
+			steps: []step{{key: chat}, {advance: 250 * time.Millisecond, key: chat}, {advance: time.Second, key: chat}},
+			want:  []time.Duration{0, 250 * time.Millisecond, 0},
+		},
+		"buckets per endpoint": {
+			rps:   1,
+			burst: 1,
+			steps: []step{{key: chat}, {key: embed}, {key: chat}},
+			want:  []time.Duration{0, 0, time.Second},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Unix(0, 0)
+			l := NewRateLimiter(tt.rps, tt.burst)
+			l.now = func() time.Time { return now }
+
+			var got []time.Duration
+			for _, s := range tt.steps {
+				now = now.Add(s.advance)
+				got = append(got, l.reserve(s.key))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("reserve() delays mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(0.001, 1)
+	if _, err := l.Wait(t.Context(), "m", "grok-4"); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, "m", "grok-4"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The canceled reservation is released, so the next caller queues behind one token only.
+	if d := l.reserve(rateLimitKey{method: "m", model: "grok-4"}); d > 1001*time.Second {
+		t.Fatalf("reserve() after cancel = %v, want at most one token of delay", d)
+	}
+}
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(1, 1)
+	var key rateLimitKey
+	l.now = func() time.Time { return time.Unix(0, 0) }
+	intercept := RateLimitUnaryInterceptor(l)
+	invoker := func(_ context.Context, method string, req, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		key = rateLimitKey{method: method, model: modelOf(req)}
+		return nil
+	}
+
+	req := &xaipb.GetCompletionsRequest{Model: "grok-4"}
+	if err := intercept(t.Context(), "/xai_api.Chat/GetCompletion", req, nil, nil, invoker); err != nil {
+		t.Fatalf("interceptor error = %v", err)
+	}
+	if diff := cmp.Diff(rateLimitKey{method: "/xai_api.Chat/GetCompletion", model: "grok-4"}, key, cmp.AllowUnexported(rateLimitKey{})); diff != "" {
+		t.Fatalf("invoked key mismatch (-want +got):\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := intercept(ctx, "/xai_api.Chat/GetCompletion", req, nil, nil, invoker); !errors.Is(err, context.Canceled) {
+		t.Fatalf("interceptor error = %v, want %v once the bucket is empty", err, context.Canceled)
+	}
+}
@@ -38,5 +38,6 @@ func (c *SamplerClient) SampleText(ctx context.Context, req *xaipb.SampleTextReq
 
 // SampleTextStreaming opens a server-streaming sampling request.
 func (c *SamplerClient) SampleTextStreaming(ctx context.Context, req *xaipb.SampleTextRequest) (xaipb.Sample_SampleTextStreamingClient, error) {
-	return c.sample.SampleTextStreaming(ctx, req)
+	stream, err := c.sample.SampleTextStreaming(ctx, req)
+	return stream, WrapError(err)
 }
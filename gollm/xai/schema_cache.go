@@ -0,0 +1,172 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// schemaCacheSize is the number of JSON schemas [WithJSONStruct], [ChatSession.Parse], and [ParseInto] keep, the
+// least recently used evicted first.
+const schemaCacheSize = 512
+
+// jsonSchemaCache caches the JSON schemas generated for Go types.
+var jsonSchemaCache = newSchemaCache(schemaCacheSize)
+
+// InvalidateSchemaCache drops the cached JSON schema of t, so the next structured output request for t generates it
+// again; the schemas of *t and, for a pointer type, of the type it points to are dropped too. A nil t drops every
+// cached schema.
+//
+// Schemas are cached per type for the life of the process, up to a fixed number of types; applications that
+// hot-reload struct definitions or change how a type reflects call it after a reload.
+func InvalidateSchemaCache(t reflect.Type) {
+	if t == nil {
+		jsonSchemaCache.clear()
+		return
+	}
+	types := []reflect.Type{t, reflect.PointerTo(t)}
+	if t.Kind() == reflect.Pointer {
+		types = append(types, t.Elem())
+	}
+	jsonSchemaCache.invalidate(types...)
+}
+
+// schemaCache is a concurrency-safe LRU cache of JSON schemas by Go type. Its lookups and evictions are counted in
+// the "xai.client.schema_cache.lookups" and "xai.client.schema_cache.evictions" counters of the global
+// OpenTelemetry meter provider.
+type schemaCache struct {
+	size int
+
+	lookups   metric.Int64Counter
+	evictions metric.Int64Counter
+
+	mu      sync.Mutex
+	entries map[reflect.Type]*list.Element
+	// order lists the entries from the most to the least recently used.
+	order list.List
+	// gen counts invalidations, so a schema generated before one is not cached after it.
+	gen uint64
+}
+
+type schemaCacheEntry struct {
+	typ    reflect.Type
+	schema []byte
+}
+
+func newSchemaCache(size int) *schemaCache {
+	lookups, _ := meter.Int64Counter("xai.client.schema_cache.lookups",
+		metric.WithDescription("Lookups of generated JSON schemas, by result."),
+		metric.WithUnit("{lookup}"),
+	)
+	evictions, _ := meter.Int64Counter("xai.client.schema_cache.evictions",
+		metric.WithDescription("Generated JSON schemas evicted from the cache to bound its size."),
+		metric.WithUnit("{schema}"),
+	)
+	return &schemaCache{
+		size:      size,
+		lookups:   lookups,
+		evictions: evictions,
+		entries:   make(map[reflect.Type]*list.Element),
+	}
+}
+
+// get returns the schema of t, if cached, and the generation to pass to [schemaCache.add] when it is not.
+func (c *schemaCache) get(t reflect.Type) (schema []byte, gen uint64, ok bool) {
+	c.mu.Lock()
+	e, ok := c.entries[t]
+	if ok {
+		c.order.MoveToFront(e)
+		schema = e.Value.(*schemaCacheEntry).schema
+	}
+	gen = c.gen
+	c.mu.Unlock()
+
+	result := "miss"
+	if ok {
+		result = "hit"
+	}
+	if c.lookups != nil {
+		c.lookups.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", result)))
+	}
+	return schema, gen, ok
+}
+
+// add caches the schema of t generated after the lookup of generation gen, unless the cache was invalidated since,
+// and evicts the least recently used schemas beyond the size of the cache.
+func (c *schemaCache) add(t reflect.Type, schema []byte, gen uint64) {
+	c.mu.Lock()
+	if gen != c.gen {
+		c.mu.Unlock()
+		return
+	}
+	if e, ok := c.entries[t]; ok {
+		// Another goroutine generated it meanwhile.
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return
+	}
+	c.entries[t] = c.order.PushFront(&schemaCacheEntry{typ: t, schema: schema})
+	evicted := 0
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*schemaCacheEntry).typ)
+		evicted++
+	}
+	c.mu.Unlock()
+
+	if evicted > 0 && c.evictions != nil {
+		c.evictions.Add(context.Background(), int64(evicted))
+	}
+}
+
+// invalidate drops the schemas of types.
+func (c *schemaCache) invalidate(types ...reflect.Type) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, t := range types {
+		if e, ok := c.entries[t]; ok {
+			c.order.Remove(e)
+			delete(c.entries, t)
+		}
+	}
+}
+
+// clear drops every schema.
+func (c *schemaCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	clear(c.entries)
+	c.order.Init()
+}
+
+// count returns the number of cached schemas.
+func (c *schemaCache) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
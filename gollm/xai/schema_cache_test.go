@@ -0,0 +1,141 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSchemaCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	types := []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[string](), reflect.TypeFor[bool]()}
+	c := newSchemaCache(2)
+	for _, typ := range types[:2] {
+		_, gen, _ := c.get(typ)
+		c.add(typ, []byte(typ.String()), gen)
+	}
+	// Using int makes string the least recently used.
+	if _, _, ok := c.get(types[0]); !ok {
+		t.Fatal("get(int) missed")
+	}
+	_, gen, _ := c.get(types[2])
+	c.add(types[2], []byte("bool"), gen)
+
+	if n := c.count(); n != 2 {
+		t.Fatalf("count() = %d, want 2", n)
+	}
+	for typ, want := range map[reflect.Type]bool{types[0]: true, types[1]: false, types[2]: true} {
+		if _, _, ok := c.get(typ); ok != want {
+			t.Fatalf("get(%s) cached = %t, want %t", typ, ok, want)
+		}
+	}
+}
+
+func TestInvalidateSchemaCache(t *testing.T) {
+	type reloaded struct {
+		Foo int `json:"foo"`
+	}
+	typ := reflect.TypeFor[reloaded]()
+
+	tests := map[string]struct {
+		invalidate reflect.Type
+		wantCached []bool
+	}{
+		"value type": {
+			invalidate: typ,
+			wantCached: []bool{false, false},
+		},
+		"pointer type": {
+			invalidate: reflect.PointerTo(typ),
+			wantCached: []bool{false, false},
+		},
+		"other type": {
+			invalidate: reflect.TypeFor[int](),
+			wantCached: []bool{true, true},
+		},
+		"every type": {
+			wantCached: []bool{false, false},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, typ := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+				if _, err := schemaBytesForType(typ); err != nil {
+					t.Fatalf("schemaBytesForType(%s) error = %v", typ, err)
+				}
+			}
+			InvalidateSchemaCache(tt.invalidate)
+			for i, typ := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+				if _, _, ok := jsonSchemaCache.get(typ); ok != tt.wantCached[i] {
+					t.Fatalf("schema of %s cached = %t, want %t", typ, ok, tt.wantCached[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaCacheSkipsSchemasOfInvalidatedLookups(t *testing.T) {
+	t.Parallel()
+
+	typ := reflect.TypeFor[int]()
+	c := newSchemaCache(2)
+	_, gen, _ := c.get(typ)
+	c.invalidate(typ)
+	c.add(typ, []byte("stale"), gen)
+
+	if _, _, ok := c.get(typ); ok {
+		t.Fatal("a schema generated before the invalidation was cached")
+	}
+}
+
+func TestSchemaCacheConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	const size = 8
+	types := make([]reflect.Type, 4*size)
+	for i := range types {
+		types[i] = reflect.ArrayOf(i+1, reflect.TypeFor[byte]())
+	}
+	c := newSchemaCache(size)
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Go(func() {
+			for i := range 200 {
+				typ := types[(w*7+i)%len(types)]
+				if schema, gen, ok := c.get(typ); !ok {
+					c.add(typ, []byte(strconv.Itoa(typ.Len())), gen)
+				} else if string(schema) != strconv.Itoa(typ.Len()) {
+					t.Errorf("schema of %s = %s", typ, schema)
+				}
+				if i%50 == 0 {
+					c.invalidate(typ)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := c.count(); n > size {
+		t.Fatalf("count() = %d, want at most %d", n, size)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// Search source limits enforced by the API.
+const (
+	maxSearchWebsites = 5
+	maxSearchXHandles = 10
+	maxSearchRSSLinks = 1
+)
+
+// WebSearch filters the web search source.
+type WebSearch struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country to bias results to, e.g. "US".
+	Country string
+	// ExcludedWebsites lists up to 5 websites to leave out. It cannot be combined with AllowedWebsites.
+	ExcludedWebsites []string
+	// AllowedWebsites lists up to 5 websites to search exclusively.
+	AllowedWebsites []string
+	// SafeSearch filters adult content.
+	SafeSearch bool
+}
+
+// NewsSearch filters the news search source.
+type NewsSearch struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country to bias results to, e.g. "US".
+	Country string
+	// ExcludedWebsites lists up to 5 news websites to leave out.
+	ExcludedWebsites []string
+	// SafeSearch filters adult content.
+	SafeSearch bool
+}
+
+// XSearch filters the X (Twitter) search source.
+type XSearch struct {
+	// IncludedHandles lists up to 10 handles to search exclusively, without the leading "@". It cannot be combined
+	// with ExcludedHandles.
+	IncludedHandles []string
+	// ExcludedHandles lists up to 10 handles to leave out.
+	ExcludedHandles []string
+	// MinFavorites only keeps posts with at least this many favorites; zero disables the filter.
+	MinFavorites int32
+	// MinViews only keeps posts with at least this many views; zero disables the filter.
+	MinViews int32
+}
+
+// SearchBuilder builds validated [SearchParameters] fluently:
+//
+//	params, err := xai.NewSearchBuilder().
+//		Mode(xai.SearchModeOn).
+//		Since(time.Now().AddDate(0, -1, 0)).
+//		Web(xai.WebSearch{Country: "US", SafeSearch: true}).
+//		X(xai.XSearch{IncludedHandles: []string{"xai"}}).
+//		Citations(true).
+//		Build()
+//
+// Every setter returns the builder; Build reports every invalid setting at once.
+type SearchBuilder struct {
+	params SearchParameters
+	errs   []error
+}
+
+// NewSearchBuilder returns a builder of search parameters in [SearchModeAuto].
+func NewSearchBuilder() *SearchBuilder {
+	return &SearchBuilder{params: SearchParameters{Mode: SearchModeAuto}}
+}
+
+// Mode sets when the model searches.
+func (b *SearchBuilder) Mode(mode SearchMode) *SearchBuilder {
+	b.params.Mode = mode
+	return b
+}
+
+// Since only returns results published at or after from.
+func (b *SearchBuilder) Since(from time.Time) *SearchBuilder {
+	b.params.FromDate = &from
+	return b
+}
+
+// Until only returns results published at or before to.
+func (b *SearchBuilder) Until(to time.Time) *SearchBuilder {
+	b.params.ToDate = &to
+	return b
+}
+
+// Between only returns results published between from and to, inclusive.
+func (b *SearchBuilder) Between(from, to time.Time) *SearchBuilder {
+	return b.Since(from).Until(to)
+}
+
+// MaxResults caps the number of search results the model considers; zero leaves the server default.
+func (b *SearchBuilder) MaxResults(n int32) *SearchBuilder {
+	b.params.MaxSearchResults = n
+	return b
+}
+
+// Citations sets whether the response lists the sources it used.
+func (b *SearchBuilder) Citations(enabled bool) *SearchBuilder {
+	b.params.ReturnCitations = enabled
+	return b
+}
+
+// Web adds the web source.
+func (b *SearchBuilder) Web(web WebSearch) *SearchBuilder {
+	b.params.Sources = append(b.params.Sources, WebSource(web.Country, web.ExcludedWebsites, web.AllowedWebsites, web.SafeSearch))
+	return b
+}
+
+// News adds the news source.
+func (b *SearchBuilder) News(news NewsSearch) *SearchBuilder {
+	b.params.Sources = append(b.params.Sources, NewsSource(news.Country, news.ExcludedWebsites, news.SafeSearch))
+	return b
+}
+
+// X adds the X (Twitter) source.
+func (b *SearchBuilder) X(x XSearch) *SearchBuilder {
+	if x.MinFavorites < 0 || x.MinViews < 0 {
+		b.errs = append(b.errs, errors.New("x source: minimum favorites and views must not be negative"))
+	}
+	b.params.Sources = append(b.params.Sources, XSource(trimHandles(x.IncludedHandles), trimHandles(x.ExcludedHandles), x.MinFavorites, x.MinViews))
+	return b
+}
+
+// RSS adds an RSS feed source.
+func (b *SearchBuilder) RSS(links ...string) *SearchBuilder {
+	b.params.Sources = append(b.params.Sources, RSSSource(links))
+	return b
+}
+
+// Build validates and returns the search parameters.
+func (b *SearchBuilder) Build() (SearchParameters, error) {
+	err := errors.Join(append(b.errs, b.params.Validate())...)
+	if err != nil {
+		return SearchParameters{}, err
+	}
+	return b.params, nil
+}
+
+// Validate reports settings the API rejects: an inverted date range, a negative result cap, and sources that break
+// their limits or combine mutually exclusive filters.
+func (p SearchParameters) Validate() error {
+	var errs []error
+	switch p.Mode {
+	case "", SearchModeAuto, SearchModeOn, SearchModeOff:
+	default:
+		errs = append(errs, fmt.Errorf("unknown search mode %q", p.Mode))
+	}
+	if p.FromDate != nil && p.ToDate != nil && p.FromDate.After(*p.ToDate) {
+		errs = append(errs, fmt.Errorf("search date range: from %s is after to %s", p.FromDate.Format(time.DateOnly), p.ToDate.Format(time.DateOnly)))
+	}
+	if p.MaxSearchResults < 0 {
+		errs = append(errs, fmt.Errorf("max search results must not be negative, got %d", p.MaxSearchResults))
+	}
+
+	seen := make(map[string]bool, len(p.Sources))
+	for i, src := range p.Sources {
+		kind, err := validateSource(src)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source[%d]: %w", i, err))
+		}
+		if kind != "" && seen[kind] {
+			errs = append(errs, fmt.Errorf("source[%d]: duplicate %s source", i, kind))
+		}
+		seen[kind] = true
+	}
+	return errors.Join(errs...)
+}
+
+// validateSource validates src and returns its kind.
+func validateSource(src *xaipb.Source) (string, error) {
+	switch s := src.GetSource().(type) {
+	case *xaipb.Source_Web:
+		web := s.Web
+		return "web", errors.Join(
+			validateCountry(web.GetCountry()),
+			validateList("excluded websites", web.GetExcludedWebsites(), maxSearchWebsites),
+			validateList("allowed websites", web.GetAllowedWebsites(), maxSearchWebsites),
+			exclusive("allowed websites", web.GetAllowedWebsites(), "excluded websites", web.GetExcludedWebsites()),
+		)
+	case *xaipb.Source_News:
+		news := s.News
+		return "news", errors.Join(
+			validateCountry(news.GetCountry()),
+			validateList("excluded websites", news.GetExcludedWebsites(), maxSearchWebsites),
+		)
+	case *xaipb.Source_X:
+		x := s.X
+		return "x", errors.Join(
+			validateList("included handles", x.GetIncludedXHandles(), maxSearchXHandles),
+			validateList("excluded handles", x.GetExcludedXHandles(), maxSearchXHandles),
+			exclusive("included handles", x.GetIncludedXHandles(), "excluded handles", x.GetExcludedXHandles()),
+		)
+	case *xaipb.Source_Rss:
+		links := s.Rss.GetLinks()
+		if len(links) == 0 || len(links) > maxSearchRSSLinks {
+			return "rss", fmt.Errorf("rss source takes exactly %d link, got %d", maxSearchRSSLinks, len(links))
+		}
+		for _, link := range links {
+			if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return "rss", fmt.Errorf("rss link %q is not an http(s) URL", link)
+			}
+		}
+		return "rss", nil
+	default:
+		return "", errors.New("source is empty")
+	}
+}
+
+func validateCountry(country string) error {
+	if country == "" {
+		return nil
+	}
+	if len(country) != 2 || strings.ContainsFunc(country, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+		return fmt.Errorf("country %q is not an ISO 3166-1 alpha-2 code", country)
+	}
+	return nil
+}
+
+func validateList(name string, items []string, limit int) error {
+	if len(items) > limit {
+		return fmt.Errorf("%s: at most %d allowed, got %d", name, limit, len(items))
+	}
+	for _, item := range items {
+		if strings.TrimSpace(item) == "" {
+			return fmt.Errorf("%s: empty entry", name)
+		}
+	}
+	return nil
+}
+
+func exclusive(name string, items []string, otherName string, other []string) error {
+	if len(items) > 0 && len(other) > 0 {
+		return fmt.Errorf("%s and %s cannot be combined", name, otherName)
+	}
+	return nil
+}
+
+// trimHandles strips the leading "@" of X handles.
+func trimHandles(handles []string) []string {
+	if len(handles) == 0 {
+		return nil
+	}
+	out := make([]string, len(handles))
+	for i, h := range handles {
+		out[i] = strings.TrimPrefix(strings.TrimSpace(h), "@")
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+func TestSearchBuilder(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	params, err := NewSearchBuilder().
+		Mode(SearchModeOn).
+		Between(from, to).
+		MaxResults(8).
+		Citations(true).
+		Web(WebSearch{Country: "US", ExcludedWebsites: []string{"example.com"}, SafeSearch: true}).
+		News(NewsSearch{Country: "GB", ExcludedWebsites: []string{"tabloid.example"}}).
+		X(XSearch{IncludedHandles: []string{"@xai", "grok"}, MinFavorites: 10, MinViews: 100}).
+		RSS("https://status.x.ai/feed.xml").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	country := func(c string) *string { return &c }
+	count := func(n int32) *int32 { return &n }
+	maxResults := int32(8)
+	want := &xaipb.SearchParameters{
+		Mode:             xaipb.SearchMode_ON_SEARCH_MODE,
+		FromDate:         timestamppb.New(from),
+		ToDate:           timestamppb.New(to),
+		ReturnCitations:  true,
+		MaxSearchResults: &maxResults,
+		Sources: []*xaipb.Source{
+			{Source: &xaipb.Source_Web{Web: &xaipb.WebSource{Country: country("US"), ExcludedWebsites: []string{"example.com"}, SafeSearch: true}}},
+			{Source: &xaipb.Source_News{News: &xaipb.NewsSource{Country: country("GB"), ExcludedWebsites: []string{"tabloid.example"}}}},
+			{Source: &xaipb.Source_X{X: &xaipb.XSource{IncludedXHandles: []string{"xai", "grok"}, PostFavoriteCount: count(10), PostViewCount: count(100)}}},
+			{Source: &xaipb.Source_Rss{Rss: &xaipb.RssSource{Links: []string{"https://status.x.ai/feed.xml"}}}},
+		},
+	}
+	if diff := cmp.Diff(want, params.Proto(), protocmp.Transform()); diff != "" {
+		t.Fatalf("Build().Proto() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchBuilderValidation(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sites := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = "site" + string(rune('a'+i)) + ".example"
+		}
+		return out
+	}
+
+	tests := map[string]struct {
+		build   func(*SearchBuilder) *SearchBuilder
+		wantErr []string
+	}{
+		"defaults": {
+			build: func(b *SearchBuilder) *SearchBuilder { return b },
+		},
+		"open date range": {
+			build: func(b *SearchBuilder) *SearchBuilder { return b.Since(now) },
+		},
+		"inverted date range": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.Between(now, now.AddDate(0, 0, -1)) },
+			wantErr: []string{"date range"},
+		},
+		"unknown mode": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.Mode("sometimes") },
+			wantErr: []string{"unknown search mode"},
+		},
+		"negative max results": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.MaxResults(-1) },
+			wantErr: []string{"max search results"},
+		},
+		"web allowed and excluded": {
+			build: func(b *SearchBuilder) *SearchBuilder {
+				return b.Web(WebSearch{AllowedWebsites: []string{"a.example"}, ExcludedWebsites: []string{"b.example"}})
+			},
+			wantErr: []string{"cannot be combined"},
+		},
+		"web too many sites and bad country": {
+			build: func(b *SearchBuilder) *SearchBuilder {
+				return b.Web(WebSearch{Country: "usa", ExcludedWebsites: sites(6)})
+			},
+			wantErr: []string{"ISO 3166-1", "at most 5"},
+		},
+		"news lower-case country": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.News(NewsSearch{Country: "us"}) },
+			wantErr: []string{"ISO 3166-1"},
+		},
+		"x included and excluded": {
+			build: func(b *SearchBuilder) *SearchBuilder {
+				return b.X(XSearch{IncludedHandles: []string{"a"}, ExcludedHandles: []string{"b"}})
+			},
+			wantErr: []string{"cannot be combined"},
+		},
+		"x empty handle and negative count": {
+			build: func(b *SearchBuilder) *SearchBuilder {
+				return b.X(XSearch{IncludedHandles: []string{"@"}, MinViews: -1})
+			},
+			wantErr: []string{"empty entry", "must not be negative"},
+		},
+		"x too many handles": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.X(XSearch{ExcludedHandles: sites(11)}) },
+			wantErr: []string{"at most 10"},
+		},
+		"rss several links": {
+			build: func(b *SearchBuilder) *SearchBuilder {
+				return b.RSS("https://a.example/feed", "https://b.example/feed")
+			},
+			wantErr: []string{"exactly 1 link"},
+		},
+		"rss not a url": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.RSS("feed.xml") },
+			wantErr: []string{"not an http(s) URL"},
+		},
+		"duplicate source": {
+			build:   func(b *SearchBuilder) *SearchBuilder { return b.Web(WebSearch{}).Web(WebSearch{SafeSearch: true}) },
+			wantErr: []string{"duplicate web source"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tt.build(NewSearchBuilder()).Build()
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("Build() error = %v, want errors %q", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Fatalf("Build() error = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchParametersValidateEmptySource(t *testing.T) {
+	t.Parallel()
+
+	if err := (SearchParameters{Sources: []*xaipb.Source{{}}}).Validate(); err == nil || !strings.Contains(err.Error(), "source is empty") {
+		t.Fatalf("Validate() error = %v, want empty source error", err)
+	}
+}
@@ -19,6 +19,8 @@ package xai
 import (
 	"context"
 
+	"google.golang.org/grpc"
+
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
@@ -28,11 +30,11 @@ type TokenizerClient struct {
 }
 
 // Tokenize converts text into tokens using the specified model.
-func (c *TokenizerClient) Tokenize(ctx context.Context, text, model string) (*xaipb.TokenizeTextResponse, error) {
+func (c *TokenizerClient) Tokenize(ctx context.Context, text, model string, opts ...grpc.CallOption) (*xaipb.TokenizeTextResponse, error) {
 	resp, err := c.tokenize.TokenizeText(ctx, &xaipb.TokenizeTextRequest{
 		Text:  text,
 		Model: model,
-	})
+	}, opts...)
 	if err != nil {
 		return nil, WrapError(err)
 	}
@@ -40,6 +42,6 @@ func (c *TokenizerClient) Tokenize(ctx context.Context, text, model string) (*xa
 }
 
 // TokenizeText is an alias for Tokenize for parity with the Python SDK.
-func (c *TokenizerClient) TokenizeText(ctx context.Context, text, model string) (*xaipb.TokenizeTextResponse, error) {
-	return c.Tokenize(ctx, text, model)
+func (c *TokenizerClient) TokenizeText(ctx context.Context, text, model string, opts ...grpc.CallOption) (*xaipb.TokenizeTextResponse, error) {
+	return c.Tokenize(ctx, text, model, opts...)
 }
@@ -23,9 +23,25 @@ import (
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
 )
 
+// ToolArgumentsOption customizes [ToolCallArguments].
+type ToolArgumentsOption func(*toolArgumentsConfig)
+
+type toolArgumentsConfig struct {
+	tools []*xaipb.Tool
+}
+
+// WithArgumentSchema validates the arguments against the parameter schema of the tool among tools whose name matches
+// the call before unmarshaling; see [ValidateToolArguments]. Calls to tools missing from tools are not validated.
+func WithArgumentSchema(tools ...*xaipb.Tool) ToolArgumentsOption {
+	return func(cfg *toolArgumentsConfig) {
+		cfg.tools = append(cfg.tools, tools...)
+	}
+}
+
 // ToolCallArguments unmarshals a tool call's arguments into the provided destination.
-// Returns an error if the tool call has no function payload or JSON is invalid.
-func ToolCallArguments(tc *xaipb.ToolCall, out any) error {
+// Returns an error if the tool call has no function payload or JSON is invalid, and a *[ToolArgumentsError] if
+// [WithArgumentSchema] is given and the arguments violate the tool's schema.
+func ToolCallArguments(tc *xaipb.ToolCall, out any, opts ...ToolArgumentsOption) error {
 	if tc == nil {
 		return errors.New("tool call is nil")
 	}
@@ -38,6 +54,20 @@ func ToolCallArguments(tc *xaipb.ToolCall, out any) error {
 		return errors.New("tool call arguments empty")
 	}
 
+	var cfg toolArgumentsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	for _, tool := range cfg.tools {
+		if tool.GetFunction().GetName() != fn.GetName() {
+			continue
+		}
+		if err := ValidateToolArguments(tool, fn.GetArguments()); err != nil {
+			return err
+		}
+		break
+	}
+
 	return json.Unmarshal([]byte(fn.GetArguments()), out)
 }
 
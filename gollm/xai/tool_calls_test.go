@@ -18,6 +18,7 @@ package xai
 
 import (
 	json "encoding/json/v2"
+	"errors"
 	"testing"
 
 	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
@@ -50,3 +51,28 @@ func TestToolCallArgumentsMissing(t *testing.T) {
 		t.Fatalf("expected error for missing function")
 	}
 }
+
+func TestToolCallArgumentsWithSchema(t *testing.T) {
+	t.Parallel()
+
+	tool := MustTool("add", "Add numbers", map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"a": map[string]any{"type": "integer"}, "b": map[string]any{"type": "integer"}},
+		"required":   []any{"a", "b"},
+	})
+	call := func(name, args string) *xaipb.ToolCall {
+		return &xaipb.ToolCall{Tool: &xaipb.ToolCall_Function{Function: &xaipb.FunctionCall{Name: name, Arguments: args}}}
+	}
+
+	var out map[string]any
+	if err := ToolCallArguments(call("add", `{"a":1,"b":2}`), &out, WithArgumentSchema(tool)); err != nil {
+		t.Fatalf("ToolCallArguments() valid error = %v", err)
+	}
+	var argErr *ToolArgumentsError
+	if err := ToolCallArguments(call("add", `{"a":1}`), &out, WithArgumentSchema(tool)); !errors.As(err, &argErr) {
+		t.Fatalf("ToolCallArguments() invalid error = %v, want *ToolArgumentsError", err)
+	}
+	if err := ToolCallArguments(call("other", `{"a":1}`), &out, WithArgumentSchema(tool)); err != nil {
+		t.Fatalf("ToolCallArguments() for an undeclared tool error = %v, want nil", err)
+	}
+}
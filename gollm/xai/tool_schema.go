@@ -0,0 +1,349 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// SchemaViolation is one way tool call arguments fail their parameter schema.
+type SchemaViolation struct {
+	// Path is the JSON Pointer of the offending value; empty for the arguments object itself.
+	Path string
+	// Keyword is the schema keyword that failed, such as "required" or "type".
+	Keyword string
+	// Message describes the failure.
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// ToolArgumentsError reports tool call arguments that do not match the tool's declared parameter schema.
+type ToolArgumentsError struct {
+	// Tool is the called function name.
+	Tool string
+	// Arguments is the raw JSON the model produced.
+	Arguments string
+	// Violations lists every schema failure, in document order.
+	Violations []SchemaViolation
+}
+
+func (e *ToolArgumentsError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("tool %q: invalid arguments: %s", e.Tool, strings.Join(msgs, "; "))
+}
+
+// ValidateToolArguments checks arguments against the parameter schema of the function tool.
+//
+// It returns a *[ToolArgumentsError] when the arguments are not valid JSON or violate the schema, and nil when the
+// tool declares no parameters. The supported keywords cover what [Tool], [NewTool] and [WithJSONStruct] generate:
+// type, enum, const, properties, required, additionalProperties, items, prefixItems, the numeric and length bounds,
+// pattern, allOf, anyOf, oneOf, not, and local $ref pointers.
+func ValidateToolArguments(tool *xaipb.Tool, arguments string) error {
+	fn := tool.GetFunction()
+	if fn == nil {
+		return errors.New("tool is not a function tool")
+	}
+	if fn.GetParameters() == "" {
+		return nil
+	}
+	var schema any
+	if err := json.Unmarshal([]byte(fn.GetParameters()), &schema); err != nil {
+		return fmt.Errorf("tool %q: decode parameters schema: %w", fn.GetName(), err)
+	}
+
+	argErr := &ToolArgumentsError{Tool: fn.GetName(), Arguments: arguments}
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	var value any
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		argErr.Violations = []SchemaViolation{{Keyword: "json", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+		return argErr
+	}
+
+	v := &schemaValidator{root: schema}
+	v.validate(schema, value, "")
+	if len(v.violations) == 0 {
+		return nil
+	}
+	argErr.Violations = v.violations
+	return argErr
+}
+
+// schemaValidator collects the violations of a value against a decoded JSON schema.
+type schemaValidator struct {
+	root       any
+	violations []SchemaViolation
+	depth      int
+}
+
+// maxSchemaDepth bounds $ref expansion so recursive schemas cannot loop forever.
+const maxSchemaDepth = 64
+
+func (v *schemaValidator) fail(path, keyword, format string, args ...any) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid reports whether value matches schema without recording violations.
+func (v *schemaValidator) valid(schema, value any, path string) bool {
+	sub := &schemaValidator{root: v.root, depth: v.depth}
+	sub.validate(schema, value, path)
+	return len(sub.violations) == 0
+}
+
+func (v *schemaValidator) validate(schema, value any, path string) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "false", "no value is allowed here")
+		}
+		return
+	case map[string]any:
+		v.validateObjectSchema(s, value, path)
+	}
+}
+
+//nolint:gocognit,gocyclo,cyclop,funlen // one branch per keyword.
+func (v *schemaValidator) validateObjectSchema(s map[string]any, value any, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		target, ok := v.resolve(ref)
+		if !ok || v.depth >= maxSchemaDepth {
+			v.fail(path, "$ref", "cannot resolve schema reference %q", ref)
+			return
+		}
+		v.depth++
+		v.validate(target, value, path)
+		v.depth--
+	}
+
+	if types := schemaTypes(s["type"]); len(types) > 0 {
+		got := jsonType(value)
+		if !slices.ContainsFunc(types, func(t string) bool { return typeMatches(t, got, value) }) {
+			v.fail(path, "type", "expected %s, got %s", strings.Join(types, " or "), got)
+			return
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, value) }) {
+		v.fail(path, "enum", "value %s is not one of %s", jsonText(value), jsonText(enum))
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.fail(path, "const", "value %s is not %s", jsonText(value), jsonText(c))
+	}
+
+	switch val := value.(type) {
+	case string:
+		n := float64(utf8.RuneCountInString(val))
+		if lo, ok := s["minLength"].(float64); ok && n < lo {
+			v.fail(path, "minLength", "string is shorter than %v characters", lo)
+		}
+		if hi, ok := s["maxLength"].(float64); ok && n > hi {
+			v.fail(path, "maxLength", "string is longer than %v characters", hi)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(val) {
+				v.fail(path, "pattern", "string does not match pattern %q", pattern)
+			}
+		}
+
+	case float64:
+		if lo, ok := s["minimum"].(float64); ok && val < lo {
+			v.fail(path, "minimum", "%v is less than %v", val, lo)
+		}
+		if hi, ok := s["maximum"].(float64); ok && val > hi {
+			v.fail(path, "maximum", "%v is greater than %v", val, hi)
+		}
+		if lo, ok := s["exclusiveMinimum"].(float64); ok && val <= lo {
+			v.fail(path, "exclusiveMinimum", "%v is not greater than %v", val, lo)
+		}
+		if hi, ok := s["exclusiveMaximum"].(float64); ok && val >= hi {
+			v.fail(path, "exclusiveMaximum", "%v is not less than %v", val, hi)
+		}
+		if m, ok := s["multipleOf"].(float64); ok && m > 0 {
+			if q := val / m; math.Abs(q-math.Round(q)) > 1e-9 {
+				v.fail(path, "multipleOf", "%v is not a multiple of %v", val, m)
+			}
+		}
+
+	case map[string]any:
+		if required, ok := s["required"].([]any); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := val[name]; !present {
+						v.fail(path, "required", "missing required property %q", name)
+					}
+				}
+			}
+		}
+		props, _ := s["properties"].(map[string]any)
+		additional, hasAdditional := s["additionalProperties"]
+		for _, name := range slices.Sorted(maps.Keys(val)) {
+			child := path + "/" + escapePointer(name)
+			if sub, ok := props[name]; ok {
+				v.validate(sub, val[name], child)
+				continue
+			}
+			if !hasAdditional {
+				continue
+			}
+			if allowed, ok := additional.(bool); ok && !allowed {
+				v.fail(child, "additionalProperties", "unknown property %q", name)
+				continue
+			}
+			v.validate(additional, val[name], child)
+		}
+
+	case []any:
+		n := float64(len(val))
+		if lo, ok := s["minItems"].(float64); ok && n < lo {
+			v.fail(path, "minItems", "array has fewer than %v items", lo)
+		}
+		if hi, ok := s["maxItems"].(float64); ok && n > hi {
+			v.fail(path, "maxItems", "array has more than %v items", hi)
+		}
+		prefix, _ := s["prefixItems"].([]any)
+		for i, item := range val {
+			child := path + "/" + strconv.Itoa(i)
+			if i < len(prefix) {
+				v.validate(prefix[i], item, child)
+			} else if items, ok := s["items"]; ok {
+				v.validate(items, item, child)
+			}
+		}
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && !slices.ContainsFunc(anyOf, func(sub any) bool { return v.valid(sub, value, path) }) {
+		v.fail(path, "anyOf", "value matches none of the allowed schemas")
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.valid(sub, value, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "oneOf", "value matches %d of the schemas, want exactly one", matches)
+		}
+	}
+	if not, ok := s["not"]; ok && v.valid(not, value, path) {
+		v.fail(path, "not", "value matches a disallowed schema")
+	}
+}
+
+// resolve looks up a local JSON Pointer reference such as "#/$defs/Item".
+func (v *schemaValidator) resolve(ref string) (any, bool) {
+	if ref == "#" {
+		return v.root, true
+	}
+	ptr, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, false
+	}
+	cur := v.root
+	for tok := range strings.SplitSeq(ptr, "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[tok]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func typeMatches(want, got string, value any) bool {
+	if want == "integer" {
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return want == got
+}
+
+func jsonText(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateToolArguments(t *testing.T) {
+	t.Parallel()
+
+	tool := MustTool("book", "Book a table", map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":   map[string]any{"type": "string", "minLength": 1, "pattern": "^[A-Za-z ]+$"},
+			"guests": map[string]any{"type": "integer", "minimum": 1, "maximum": 8},
+			"time":   map[string]any{"type": "string", "enum": []any{"lunch", "dinner"}},
+			"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 2},
+			"table":  map[string]any{"$ref": "#/$defs/table"},
+			"note":   map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+		},
+		"required":             []any{"name", "guests"},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"table": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"id": map[string]any{"type": "integer"}},
+				"required":   []any{"id"},
+			},
+		},
+	})
+
+	type violation struct{ Path, Keyword string }
+	tests := map[string]struct {
+		args string
+		want []violation
+	}{
+		"valid": {
+			args: `{"name":"Ada","guests":2,"time":"dinner","tags":["window"],"table":{"id":3},"note":null}`,
+		},
+		"invalid json": {
+			args: `{"name":`,
+			want: []violation{{"", "json"}},
+		},
+		"missing required": {
+			args: `{}`,
+			want: []violation{{"", "required"}, {"", "required"}},
+		},
+		"empty arguments are an empty object": {
+			args: ``,
+			want: []violation{{"", "required"}, {"", "required"}},
+		},
+		"wrong types": {
+			args: `{"name":1,"guests":2.5}`,
+			want: []violation{{"/guests", "type"}, {"/name", "type"}},
+		},
+		"bounds and enum": {
+			args: `{"name":"Ada!","guests":9,"time":"brunch","tags":["a","b","c"]}`,
+			want: []violation{{"/guests", "maximum"}, {"/name", "pattern"}, {"/tags", "maxItems"}, {"/time", "enum"}},
+		},
+		"unknown property": {
+			args: `{"name":"Ada","guests":1,"extra":true}`,
+			want: []violation{{"/extra", "additionalProperties"}},
+		},
+		"nested via ref": {
+			args: `{"name":"Ada","guests":1,"table":{"id":"x"},"tags":[1]}`,
+			want: []violation{{"/table/id", "type"}, {"/tags/0", "type"}},
+		},
+		"any of": {
+			args: `{"name":"Ada","guests":1,"note":3}`,
+			want: []violation{{"/note", "anyOf"}},
+		},
+		"not an object": {
+			args: `[]`,
+			want: []violation{{"", "type"}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateToolArguments(tool, tt.args)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateToolArguments() error = %v, want nil", err)
+				}
+				return
+			}
+			var argErr *ToolArgumentsError
+			if !errors.As(err, &argErr) {
+				t.Fatalf("ValidateToolArguments() error = %v, want *ToolArgumentsError", err)
+			}
+			if argErr.Tool != "book" || argErr.Arguments != tt.args {
+				t.Fatalf("ToolArgumentsError = %+v, want tool book with the raw arguments", argErr)
+			}
+			got := make([]violation, len(argErr.Violations))
+			for i, v := range argErr.Violations {
+				got[i] = violation{v.Path, v.Keyword}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("violations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateToolArgumentsWithoutSchema(t *testing.T) {
+	t.Parallel()
+
+	if err := ValidateToolArguments(MustTool("noop", "", nil), `{"x":1}`); err != nil {
+		t.Fatalf("ValidateToolArguments() with null schema error = %v, want nil", err)
+	}
+	if err := ValidateToolArguments(WebSearchTool(nil, nil, false), `{}`); err == nil {
+		t.Fatal("ValidateToolArguments() on a server-side tool error = nil, want error")
+	}
+	if err := ValidateToolArguments(weatherTool(t).Proto(), `{"city":"Tokyo","unit":"kelvin"}`); err == nil {
+		t.Fatal("ValidateToolArguments() with enum violation on a generated schema error = nil, want error")
+	}
+}
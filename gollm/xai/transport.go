@@ -0,0 +1,239 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// WithProxy routes the connections of the data plane and management clients through the HTTP CONNECT proxy at
+// proxyURL, with the http or https scheme and optional user info for basic authentication.
+//
+// Without it, the clients honor the HTTPS_PROXY and NO_PROXY environment variables like gRPC does.
+func WithProxy(proxyURL string) ClientOption {
+	return func(o *clientOptions) {
+		o.proxy = proxyURL
+	}
+}
+
+// WithoutProxy connects directly, ignoring the HTTPS_PROXY environment variable.
+func WithoutProxy() ClientOption {
+	return func(o *clientOptions) {
+		o.noProxy = true
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the data plane and management connections. [WithCABundle] and
+// [WithClientCertificate] add to a clone of it.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithCABundle trusts the PEM certificates of file, such as the CA of a TLS-inspecting proxy, in place of the
+// system roots.
+func WithCABundle(file string) ClientOption {
+	return func(o *clientOptions) {
+		o.caFile = file
+	}
+}
+
+// WithClientCertificate presents the PEM certificate and key of certFile and keyFile for mutual TLS.
+func WithClientCertificate(certFile, keyFile string) ClientOption {
+	return func(o *clientOptions) {
+		o.certFile, o.keyFile = certFile, keyFile
+	}
+}
+
+// WithAuthority overrides the :authority header and TLS server name of the data plane connections, for an API host
+// set with [WithAPIHost] that serves the API under another name, such as a private endpoint.
+func WithAuthority(authority string) ClientOption {
+	return func(o *clientOptions) {
+		o.apiAuthority = authority
+	}
+}
+
+// WithManagementAuthority is [WithAuthority] for the management connection.
+func WithManagementAuthority(authority string) ClientOption {
+	return func(o *clientOptions) {
+		o.managementAuthority = authority
+	}
+}
+
+// loadTransport resolves the proxy and TLS options of o, reading the certificate files.
+func (o *clientOptions) loadTransport() error {
+	if o.proxy != "" {
+		u, err := url.Parse(o.proxy)
+		if err != nil {
+			return fmt.Errorf("parse proxy URL: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("proxy URL %q must be http://host:port or https://host:port", u.Redacted())
+		}
+		o.proxyURL = u
+	}
+
+	if o.caFile == "" && o.certFile == "" {
+		return nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.tlsConfig != nil {
+		cfg = o.tlsConfig.Clone()
+	}
+	if o.caFile != "" {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("CA bundle %s has no PEM certificates", o.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	o.tlsConfig = cfg
+	return nil
+}
+
+// transportOptions returns the dial options of the proxy and authority settings of o for a connection to the API
+// with authority, which may be empty.
+func (o *clientOptions) transportOptions(authority string) []grpc.DialOption {
+	var opts []grpc.DialOption
+	switch {
+	case o.proxyURL != nil:
+		opts = append(opts, grpc.WithContextDialer(proxyDialer(o.proxyURL)))
+	case o.noProxy:
+		opts = append(opts, grpc.WithNoProxy())
+	}
+	if authority != "" {
+		opts = append(opts, grpc.WithAuthority(authority))
+	}
+	return opts
+}
+
+// dialTarget returns the gRPC target of host. With a proxy, host is passed to the proxy unresolved, like gRPC does
+// for HTTPS_PROXY.
+func (o *clientOptions) dialTarget(host string) string {
+	if o.proxyURL == nil || strings.Contains(host, ":///") {
+		return host
+	}
+	return "passthrough:///" + host
+}
+
+// proxyDialer returns a dialer that tunnels connections through the HTTP CONNECT proxy at proxy.
+func proxyDialer(proxy *url.URL) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		proxyAddr := proxy.Host
+		if proxy.Port() == "" {
+			port := "80"
+			if proxy.Scheme == "https" {
+				port = "443"
+			}
+			proxyAddr = net.JoinHostPort(proxy.Hostname(), port)
+		}
+
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("dial proxy %s: %w", proxy.Redacted(), err)
+		}
+		if proxy.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{MinVersion: tls.VersionTLS12, ServerName: proxy.Hostname()})
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("proxy %s TLS handshake: %w", proxy.Redacted(), err)
+			}
+			conn = tlsConn
+		}
+
+		tunnel, err := connectTunnel(ctx, conn, proxy, addr)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: %w", proxy.Redacted(), err)
+		}
+		return tunnel, nil
+	}
+}
+
+// connectTunnel asks the proxy on conn to CONNECT to addr and returns the tunneled connection.
+func connectTunnel(ctx context.Context, conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u := proxy.User; u != nil {
+		password, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("write CONNECT: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("CONNECT " + addr + ": " + resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose reads first drain the bytes buffered while reading the CONNECT response.
+type bufferedConn struct {
+	net.Conn
+
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
@@ -0,0 +1,264 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package xai
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	xaipb "github.com/zchee/tumix/gollm/xai/api/v1"
+)
+
+// connectProxy is an HTTP CONNECT proxy that records the target and credentials of every tunnel, and refuses them
+// when reject is set.
+type connectProxy struct {
+	lis     net.Listener
+	reject  bool
+	tunnels chan string
+}
+
+func newConnectProxy(t *testing.T, reject bool) *connectProxy {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	p := &connectProxy{lis: lis, reject: reject, tunnels: make(chan string, 8)}
+	t.Cleanup(func() { _ = lis.Close() })
+	go p.serve()
+	return p
+}
+
+func (p *connectProxy) serve() {
+	for {
+		conn, err := p.lis.Accept()
+		if err != nil {
+			return
+		}
+		go p.tunnel(conn)
+	}
+}
+
+func (p *connectProxy) tunnel(conn net.Conn) {
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	p.tunnels <- req.Host + " " + req.Header.Get("Proxy-Authorization")
+	if p.reject {
+		_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return
+	}
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		_, _ = io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+	_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func TestClientProxy(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	xaipb.RegisterModelsServer(srv, fakeModelsServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	apiHost := lis.Addr().String()
+
+	tests := map[string]struct {
+		reject  bool
+		wantErr string
+	}{
+		"tunnel": {},
+		"rejected": {
+			reject:  true,
+			wantErr: "407 Proxy Authentication Required",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			proxy := newConnectProxy(t, tt.reject)
+			client, err := NewClient("test-key",
+				WithAPIHost(apiHost),
+				WithInsecure(),
+				WithProxy("http://user:secret@"+proxy.lis.Addr().String()),
+			)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			t.Cleanup(func() { _ = client.Close() })
+
+			ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+			defer cancel()
+			_, err = client.Models.ListLanguageModels(ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ListLanguageModels() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ListLanguageModels() error = %v", err)
+			}
+
+			// "dXNlcjpzZWNyZXQ=" is base64("user:secret").
+			if got, want := <-proxy.tunnels, apiHost+" Basic dXNlcjpzZWNyZXQ="; got != want {
+				t.Fatalf("proxy tunnel = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// writeCertificate writes a self-signed PEM certificate and its key to dir and returns their paths.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tumix test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadTransport(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	notPEM := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opts      []ClientOption
+		wantErr   string
+		wantRoots bool
+		wantCerts int
+	}{
+		"defaults": {},
+		"proxy": {
+			opts: []ClientOption{WithProxy("http://proxy.internal:3128")},
+		},
+		"proxy scheme": {
+			opts:    []ClientOption{WithProxy("socks5://proxy.internal:1080")},
+			wantErr: "must be http://host:port or https://host:port",
+		},
+		"ca bundle": {
+			opts:      []ClientOption{WithCABundle(certFile)},
+			wantRoots: true,
+		},
+		"ca bundle missing": {
+			opts:    []ClientOption{WithCABundle(filepath.Join(dir, "missing.pem"))},
+			wantErr: "read CA bundle",
+		},
+		"ca bundle without certificates": {
+			opts:    []ClientOption{WithCABundle(notPEM)},
+			wantErr: "has no PEM certificates",
+		},
+		"client certificate": {
+			opts:      []ClientOption{WithCABundle(certFile), WithClientCertificate(certFile, keyFile)},
+			wantRoots: true,
+			wantCerts: 1,
+		},
+		"client certificate mismatch": {
+			opts:    []ClientOption{WithClientCertificate(certFile, notPEM)},
+			wantErr: "load client certificate",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := DefaultClientOptions()
+			for _, fn := range tt.opts {
+				fn(opts)
+			}
+			err := opts.loadTransport()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadTransport() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadTransport() error = %v", err)
+			}
+			var roots bool
+			var certs int
+			if cfg := opts.tlsConfig; cfg != nil {
+				roots, certs = cfg.RootCAs != nil, len(cfg.Certificates)
+			}
+			if roots != tt.wantRoots || certs != tt.wantCerts {
+				t.Fatalf("TLS config roots = %t, certificates = %d, want %t and %d", roots, certs, tt.wantRoots, tt.wantCerts)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zchee/tumix/gollm/xai"
+	"github.com/zchee/tumix/internal/version"
+)
+
+// readinessTimeout bounds each check of /readyz.
+const readinessTimeout = 3 * time.Second
+
+// readinessCheck is a named dependency probe of /readyz.
+type readinessCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// readiness serves /readyz from the checks of the dependencies a run needs, registered as they are configured.
+type readiness struct {
+	mu     sync.Mutex
+	checks []readinessCheck
+}
+
+// add registers the check of the dependency name.
+func (r *readiness) add(name string, check func(context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, readinessCheck{name: name, check: check})
+}
+
+// run runs every check concurrently and returns the result of each by name, "ok" or the error, and whether all passed.
+func (r *readiness) run(ctx context.Context) (map[string]string, bool) {
+	r.mu.Lock()
+	checks := slices.Clone(r.checks)
+	r.mu.Unlock()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			results[i] = c.check(ctx)
+		})
+	}
+	wg.Wait()
+
+	out := make(map[string]string, len(checks))
+	ready := true
+	for i, c := range checks {
+		out[c.name] = "ok"
+		if err := results[i]; err != nil {
+			out[c.name] = err.Error()
+			ready = false
+		}
+	}
+	return out, ready
+}
+
+// ServeHTTP reports 200 when every dependency is reachable and 503 otherwise, with the result of each check.
+func (r *readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	checks, ready := r.run(req.Context())
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{"status": status, "checks": checks})
+}
+
+// registerReadiness adds the checks of the model backends, the session store, and the OTLP exporter of cfg to r.
+func registerReadiness(r *readiness, cfg *config) error {
+	backends := []string{cfg.LLMBackend}
+	targets, err := parseFailover(cfg.Failover)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if !slices.Contains(backends, target.backend) {
+			backends = append(backends, target.backend)
+		}
+	}
+	for _, backend := range backends {
+		if host := backendHost(backend); host != "" {
+			r.add("model:"+backend, dialCheck(host))
+		}
+	}
+
+	switch dbPath := os.Getenv("TUMIX_SESSION_SQLITE"); {
+	case cfg.SessionDir != "":
+		r.add("session_store", writableDirCheck(cfg.SessionDir))
+	case dbPath != "":
+		r.add("session_store", writableDirCheck(filepath.Dir(dbPath)))
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		r.add("otlp_exporter", dialCheck(cfg.OTLPEndpoint))
+	}
+	return nil
+}
+
+// backendHost returns the API host:port of the model backend.
+func backendHost(backend string) string {
+	switch backend {
+	case "gemini":
+		return "generativelanguage.googleapis.com:443"
+	case "openai":
+		return "api.openai.com:443"
+	case "anthropic":
+		return "api.anthropic.com:443"
+	case "xai":
+		return xai.APIHost
+	default:
+		return ""
+	}
+}
+
+// dialCheck returns a check that opens, and closes, a TCP connection to addr.
+func dialCheck(addr string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("dial %s: %w", addr, err)
+		}
+		return conn.Close()
+	}
+}
+
+// writableDirCheck returns a check that creates, and removes, a file in dir.
+func writableDirCheck(dir string) func(context.Context) error {
+	return func(context.Context) error {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("session store: %w", err)
+		}
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return fmt.Errorf("session store: %w", err)
+		}
+		f.Close()
+		return os.Remove(f.Name())
+	}
+}
+
+// serveVersion writes the build information as JSON.
+func serveVersion(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, version.Build())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.MarshalWrite(w, v)
+}
+
+// runVersion implements "tumix version [-json]".
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the commit, build date, and dependency versions as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	info := version.Build()
+	if !*asJSON {
+		fmt.Fprintln(os.Stdout, info)
+		return 0
+	}
+	if err := json.MarshalEncode(jsontext.NewEncoder(os.Stdout, jsontext.WithIndent("  ")), info); err != nil {
+		fmt.Fprintf(os.Stderr, "encode version: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadiness(t *testing.T) {
+	t.Parallel()
+
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("unreachable") }
+
+	tests := map[string]struct {
+		checks   map[string]func(context.Context) error
+		wantCode int
+		want     map[string]any
+	}{
+		"no checks": {
+			wantCode: http.StatusOK,
+			want:     map[string]any{"status": "ready", "checks": map[string]any{}},
+		},
+		"all pass": {
+			checks:   map[string]func(context.Context) error{"model:xai": ok, "session_store": ok},
+			wantCode: http.StatusOK,
+			want:     map[string]any{"status": "ready", "checks": map[string]any{"model:xai": "ok", "session_store": "ok"}},
+		},
+		"one fails": {
+			checks:   map[string]func(context.Context) error{"model:xai": ok, "otlp_exporter": fail},
+			wantCode: http.StatusServiceUnavailable,
+			want:     map[string]any{"status": "not ready", "checks": map[string]any{"model:xai": "ok", "otlp_exporter": "unreachable"}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &readiness{}
+			for name, check := range tt.checks {
+				r.add(name, check)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode body %q: %v", rec.Body, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	tests := map[string]struct {
+		check   func(context.Context) error
+		wantErr bool
+	}{
+		"writable dir":     {check: writableDirCheck(filepath.Join(dir, "sessions"))},
+		"dir is a file":    {check: writableDirCheck(file), wantErr: true},
+		"closed port":      {check: dialCheck(addr), wantErr: true},
+		"listening server": {check: dialCheck(srv.Listener.Addr().String())},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.check(t.Context()); (err != nil) != tt.wantErr {
+				t.Fatalf("check() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterReadiness(t *testing.T) {
+	cfg := &config{
+		LLMBackend:   "gemini",
+		Failover:     "xai:grok-4,gemini:gemini-2.5-pro",
+		SessionDir:   t.TempDir(),
+		OTLPEndpoint: "localhost:4317",
+	}
+	t.Setenv("TUMIX_SESSION_SQLITE", "")
+
+	r := &readiness{}
+	if err := registerReadiness(r, cfg); err != nil {
+		t.Fatalf("registerReadiness() error = %v", err)
+	}
+	var got []string
+	for _, c := range r.checks {
+		got = append(got, c.name)
+	}
+	want := []string{"model:gemini", "model:xai", "session_store", "otlp_exporter"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("checks mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,409 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package a2a implements the client side of the Agent2Agent (A2A) protocol over JSON-RPC.
+//
+// It covers agent card discovery and the message/send, message/stream, and tasks/cancel methods, which is what TUMIX
+// needs to use a remote agent as a candidate.
+package a2a
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Well-known agent card paths, current first.
+var cardPaths = []string{"/.well-known/agent-card.json", "/.well-known/agent.json"}
+
+// maxMessageSize caps a single response or stream event.
+const maxMessageSize = 16 << 20
+
+// TaskState is the lifecycle state of an A2A task.
+type TaskState string
+
+// Task states defined by the A2A specification.
+const (
+	TaskStateSubmitted     TaskState = "submitted"
+	TaskStateWorking       TaskState = "working"
+	TaskStateInputRequired TaskState = "input-required"
+	TaskStateAuthRequired  TaskState = "auth-required"
+	TaskStateCompleted     TaskState = "completed"
+	TaskStateCanceled      TaskState = "canceled"
+	TaskStateFailed        TaskState = "failed"
+	TaskStateRejected      TaskState = "rejected"
+	TaskStateUnknown       TaskState = "unknown"
+)
+
+// Terminal reports whether a task in state s is done and can no longer be canceled.
+func (s TaskState) Terminal() bool {
+	switch s {
+	case TaskStateCompleted, TaskStateCanceled, TaskStateFailed, TaskStateRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Event kinds returned by message/send and message/stream.
+const (
+	KindTask           = "task"
+	KindMessage        = "message"
+	KindStatusUpdate   = "status-update"
+	KindArtifactUpdate = "artifact-update"
+)
+
+// AgentCard describes a remote agent.
+type AgentCard struct {
+	Name         string       `json:"name"`
+	Description  string       `json:"description,omitzero"`
+	URL          string       `json:"url"`
+	Version      string       `json:"version,omitzero"`
+	Capabilities Capabilities `json:"capabilities,omitzero"`
+	Skills       []Skill      `json:"skills,omitzero"`
+}
+
+// Capabilities lists the optional protocol features an agent supports.
+type Capabilities struct {
+	Streaming bool `json:"streaming,omitzero"`
+}
+
+// Skill is a capability advertised in an [AgentCard].
+type Skill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name,omitzero"`
+	Description string   `json:"description,omitzero"`
+	Tags        []string `json:"tags,omitzero"`
+}
+
+// FindSkill returns the skill with the given id or name.
+func (c *AgentCard) FindSkill(skill string) (Skill, bool) {
+	for _, s := range c.Skills {
+		if s.ID == skill || s.Name == skill {
+			return s, true
+		}
+	}
+	return Skill{}, false
+}
+
+// Part is a piece of message or artifact content. Only text parts carry Text.
+type Part struct {
+	Kind string         `json:"kind"`
+	Text string         `json:"text,omitzero"`
+	Data jsontext.Value `json:"data,omitzero"`
+}
+
+// TextPart returns a text part.
+func TextPart(text string) Part {
+	return Part{Kind: "text", Text: text}
+}
+
+// PartsText joins the text of every text part.
+func PartsText(parts []Part) string {
+	var texts []string
+	for _, p := range parts {
+		if p.Kind == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Message is a single turn of a conversation with an agent.
+type Message struct {
+	Kind      string         `json:"kind"`
+	MessageID string         `json:"messageId"`
+	Role      string         `json:"role"`
+	Parts     []Part         `json:"parts"`
+	ContextID string         `json:"contextId,omitzero"`
+	TaskID    string         `json:"taskId,omitzero"`
+	Metadata  map[string]any `json:"metadata,omitzero"`
+}
+
+// TaskStatus is the current state of a task.
+type TaskStatus struct {
+	State   TaskState `json:"state"`
+	Message *Message  `json:"message,omitzero"`
+}
+
+// Artifact is an output produced by a task.
+type Artifact struct {
+	ArtifactID string `json:"artifactId"`
+	Name       string `json:"name,omitzero"`
+	Parts      []Part `json:"parts"`
+}
+
+// Event is any result of message/send or message/stream, discriminated by Kind.
+//
+// Tasks fill ID, ContextID, Status, and Artifacts; messages fill MessageID, Role, Parts, ContextID, and TaskID;
+// status updates fill TaskID, ContextID, Status, and Final; artifact updates fill TaskID, ContextID, Artifact,
+// Append, and LastChunk.
+type Event struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id,omitzero"`
+	TaskID    string     `json:"taskId,omitzero"`
+	ContextID string     `json:"contextId,omitzero"`
+	Status    TaskStatus `json:"status,omitzero"`
+	Artifacts []Artifact `json:"artifacts,omitzero"`
+	MessageID string     `json:"messageId,omitzero"`
+	Role      string     `json:"role,omitzero"`
+	Parts     []Part     `json:"parts,omitzero"`
+	Artifact  Artifact   `json:"artifact,omitzero"`
+	Append    bool       `json:"append,omitzero"`
+	LastChunk bool       `json:"lastChunk,omitzero"`
+	Final     bool       `json:"final,omitzero"`
+}
+
+// MessageSendParams are the parameters of message/send and message/stream.
+type MessageSendParams struct {
+	Message  Message        `json:"message"`
+	Metadata map[string]any `json:"metadata,omitzero"`
+}
+
+// TaskIDParams are the parameters of tasks/cancel.
+type TaskIDParams struct {
+	ID string `json:"id"`
+}
+
+// RPCError is a JSON-RPC error returned by an agent.
+type RPCError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    jsontext.Value `json:"data,omitzero"`
+}
+
+// Error implements error.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("a2a rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     *int64    `json:"id"`
+	Result *Event    `json:"result,omitzero"`
+	Error  *RPCError `json:"error,omitzero"`
+}
+
+// ResolveCard fetches the agent card of baseURL.
+//
+// baseURL is either the URL of the card itself (ending in ".json") or the agent's origin, in which case the
+// well-known card paths are tried in order.
+func ResolveCard(ctx context.Context, hc *http.Client, baseURL string) (*AgentCard, error) {
+	candidates := []string{baseURL}
+	if !strings.HasSuffix(baseURL, ".json") {
+		candidates = candidates[:0]
+		for _, p := range cardPaths {
+			candidates = append(candidates, strings.TrimSuffix(baseURL, "/")+p)
+		}
+	}
+
+	var errs []error
+	for _, u := range candidates {
+		card, err := fetchCard(ctx, hc, u)
+		if err == nil {
+			if card.URL == "" {
+				card.URL = baseURL
+			}
+			return card, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("resolve agent card of %s: %w", baseURL, errors.Join(errs...))
+}
+
+func fetchCard(ctx context.Context, hc *http.Client, u string) (*AgentCard, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: unexpected status %s", u, resp.Status)
+	}
+
+	var card AgentCard
+	if err := json.UnmarshalRead(io.LimitReader(resp.Body, maxMessageSize), &card); err != nil {
+		return nil, fmt.Errorf("decode agent card %s: %w", u, err)
+	}
+	return &card, nil
+}
+
+// Client calls the JSON-RPC endpoint of a remote agent.
+type Client struct {
+	hc       *http.Client
+	endpoint string
+	nextID   atomic.Int64
+}
+
+// NewClient returns a Client for the JSON-RPC endpoint, usually [AgentCard.URL].
+func NewClient(hc *http.Client, endpoint string) *Client {
+	return &Client{hc: hc, endpoint: endpoint}
+}
+
+// SendMessage calls message/send and returns the resulting task or message.
+func (c *Client) SendMessage(ctx context.Context, params *MessageSendParams) (*Event, error) {
+	id := c.nextID.Add(1)
+	resp, err := c.post(ctx, "message/send", id, params, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg rpcResponse
+	if err := json.UnmarshalRead(io.LimitReader(resp.Body, maxMessageSize), &msg); err != nil {
+		return nil, fmt.Errorf("decode message/send response: %w", err)
+	}
+	return msg.result()
+}
+
+// CancelTask calls tasks/cancel and returns the canceled task.
+func (c *Client) CancelTask(ctx context.Context, taskID string) (*Event, error) {
+	id := c.nextID.Add(1)
+	resp, err := c.post(ctx, "tasks/cancel", id, &TaskIDParams{ID: taskID}, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg rpcResponse
+	if err := json.UnmarshalRead(io.LimitReader(resp.Body, maxMessageSize), &msg); err != nil {
+		return nil, fmt.Errorf("decode tasks/cancel response: %w", err)
+	}
+	return msg.result()
+}
+
+// StreamMessage calls message/stream and yields every task, message, and update event until the stream ends.
+func (c *Client) StreamMessage(ctx context.Context, params *MessageSendParams) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		id := c.nextID.Add(1)
+		resp, err := c.post(ctx, "message/stream", id, params, "text/event-stream")
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		for data, err := range sseData(resp.Body) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			var msg rpcResponse
+			if err := json.Unmarshal(data, &msg); err != nil {
+				yield(nil, fmt.Errorf("decode message/stream event: %w", err))
+				return
+			}
+			ev, err := msg.result()
+			if !yield(ev, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) post(ctx context.Context, method string, id int64, params any, accept string) (*http.Response, error) {
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("encode %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %s", method, resp.Status)
+	}
+	return resp, nil
+}
+
+func (r *rpcResponse) result() (*Event, error) {
+	switch {
+	case r.Error != nil:
+		return nil, r.Error
+	case r.Result == nil:
+		return nil, errors.New("a2a response has neither result nor error")
+	default:
+		return r.Result, nil
+	}
+}
+
+// sseData yields the data payload of every server-sent event in r.
+func sseData(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+
+		var data bytes.Buffer
+		flush := func() bool {
+			if data.Len() == 0 {
+				return true
+			}
+			payload := bytes.Clone(data.Bytes())
+			data.Reset()
+			return yield(payload, nil)
+		}
+
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if !flush() {
+					return
+				}
+				continue
+			}
+			if rest, ok := strings.CutPrefix(line, "data:"); ok {
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(strings.TrimPrefix(rest, " "))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, fmt.Errorf("read event stream: %w", err))
+			return
+		}
+		flush()
+	}
+}
@@ -0,0 +1,494 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mathcheck evaluates numeric answers exactly so equivalent forms such as "1/3", "0.333…", and "0.33"
+// can be compared deterministically.
+//
+// Expressions are evaluated over arbitrary precision rationals. Supported syntax covers integers, decimals,
+// repeating decimals ("0.333…", "0.(3)"), scientific notation, percentages, + - * / ^ with integer exponents,
+// parentheses, and the common LaTeX forms \frac, \dfrac, \tfrac, \cdot, \times, \div, and \boxed.
+package mathcheck
+
+import (
+	"cmp"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// MinRoundedDecimals is the number of fractional digits a decimal literal needs before it may match a value
+	// it rounds from, so "0.33" matches 1/3 but "0.3" does not.
+	MinRoundedDecimals = 2
+
+	// maxExponent bounds integer exponents to keep evaluation cheap.
+	maxExponent = 256
+	// maxPowerBits bounds the bit length of the numerator and denominator of a power, which maxExponent alone does
+	// not do for nested powers such as "((9^256)^256)^256".
+	maxPowerBits = 4096
+)
+
+// Number is an exactly evaluated numeric answer.
+type Number struct {
+	// Rat is the exact value.
+	Rat *big.Rat
+	// Decimals is the number of fractional digits when the answer is a single finite decimal literal, which may
+	// be a rounded form of another value; it is -1 for every other answer.
+	Decimals int
+}
+
+// String returns the canonical form of n, e.g. "1/3" or "42".
+func (n Number) String() string {
+	return n.Rat.RatString()
+}
+
+// Equivalent reports whether a and b denote the same number.
+//
+// Exact values must be equal. A decimal literal with at least [MinRoundedDecimals] digits also matches any value that
+// rounds (half away from zero) to it and is not itself a decimal literal, e.g. "0.33" matches "1/3" but not "0.334":
+// two decimal literals are both rounded answers, so they match only when they are equal.
+func Equivalent(a, b Number) bool {
+	if a.Rat == nil || b.Rat == nil {
+		return false
+	}
+	if a.Rat.Cmp(b.Rat) == 0 {
+		return true
+	}
+	return roundsTo(b, a) || roundsTo(a, b)
+}
+
+// roundsTo reports whether the value x, which is not a decimal literal, rounded to the precision of the decimal
+// literal d equals d.
+func roundsTo(x, d Number) bool {
+	if d.Decimals < MinRoundedDecimals || x.Decimals >= 0 {
+		return false
+	}
+	return round(x.Rat, d.Decimals).Cmp(d.Rat) == 0
+}
+
+// Group partitions nums into classes of equivalent numbers and returns the class of each, numbered in order of first
+// appearance.
+//
+// [Equivalent] is not transitive: "0.33" matches both "1/3" and "331/1000", which do not match each other. Group
+// therefore first collects equal values, then merges the values given only as decimal literals into the single
+// other value that rounds to all of them. Literals that more than one value rounds to stay apart, so the classes do not
+// depend on the order of nums.
+func Group(nums []Number) []int {
+	type class struct {
+		num     Number // a member that is not a decimal literal, if any
+		rounded bool   // every member is a decimal literal
+		members []Number
+		parent  int
+	}
+
+	var classes []*class
+	classOf := make([]int, len(nums))
+	byValue := make(map[string]int, len(nums))
+	for i, n := range nums {
+		key := n.String()
+		c, ok := byValue[key]
+		if !ok {
+			c = len(classes)
+			classes = append(classes, &class{num: n, rounded: true, parent: c})
+			byValue[key] = c
+		}
+		cl := classes[c]
+		cl.members = append(cl.members, n)
+		if n.Decimals < 0 && cl.rounded {
+			cl.num, cl.rounded = n, false
+		}
+		classOf[i] = c
+	}
+
+	for _, cl := range classes {
+		if !cl.rounded {
+			continue
+		}
+		match := -1
+		for j, other := range classes {
+			if other.rounded || slices.ContainsFunc(cl.members, func(d Number) bool { return !roundsTo(other.num, d) }) {
+				continue
+			}
+			if match >= 0 {
+				match = -1
+				break
+			}
+			match = j
+		}
+		if match >= 0 {
+			cl.parent = match
+		}
+	}
+
+	ids := make(map[int]int, len(classes))
+	groups := make([]int, len(nums))
+	for i, c := range classOf {
+		root := classes[c].parent
+		id, ok := ids[root]
+		if !ok {
+			id = len(ids)
+			ids[root] = id
+		}
+		groups[i] = id
+	}
+	return groups
+}
+
+// round rounds r to n fractional digits, half away from zero.
+func round(r *big.Rat, n int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+
+	num := new(big.Int).Abs(scaled.Num())
+	q, m := new(big.Int).QuoRem(num, scaled.Denom(), new(big.Int))
+	if new(big.Int).Lsh(m, 1).Cmp(scaled.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if scaled.Sign() < 0 {
+		q.Neg(q)
+	}
+	return new(big.Rat).SetFrac(q, scale)
+}
+
+var (
+	thousandsRe = regexp.MustCompile(`^[-+]?\d{1,3}(,\d{3})+(\.\d+)?$`)
+	decimalRe   = regexp.MustCompile(`^[-+]?(\d+\.(\d+)|\.(\d+))$`)
+
+	replacer = strings.NewReplacer(
+		`\left`, "",
+		`\right`, "",
+		`\,`, "",
+		`\!`, "",
+		`\ `, "",
+		`\cdot`, "*",
+		`\times`, "*",
+		`\div`, "/",
+		"×", "*",
+		"·", "*",
+		"÷", "/",
+		"−", "-",
+		`\%`, "%",
+		"**", "^",
+		"…", "...",
+		`\ldots`, "...",
+		`\dots`, "...",
+		`\cdots`, "...",
+	)
+)
+
+// Parse evaluates s as a numeric answer.
+//
+// It reports false when s is not a closed-form rational expression, e.g. when it contains variables, words, or
+// irrational functions.
+func Parse(s string) (Number, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "$")
+	s = strings.TrimPrefix(s, `\(`)
+	s = strings.TrimSuffix(s, `\)`)
+	s = strings.TrimPrefix(s, `\[`)
+	s = strings.TrimSuffix(s, `\]`)
+	if strings.HasSuffix(s, ".") && !strings.HasSuffix(s, "...") {
+		s = strings.TrimSpace(strings.TrimSuffix(s, "."))
+	}
+	if rest, ok := strings.CutPrefix(s, `\boxed{`); ok && strings.HasSuffix(rest, "}") {
+		s = strings.TrimSuffix(rest, "}")
+	}
+	s = replacer.Replace(s)
+	s = strings.Join(strings.Fields(s), "")
+	if thousandsRe.MatchString(s) {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	if s == "" {
+		return Number{}, false
+	}
+
+	p := &parser{src: s}
+	r, ok := p.parseExpr()
+	if !ok || p.pos != len(p.src) {
+		return Number{}, false
+	}
+
+	n := Number{Rat: r, Decimals: -1}
+	if m := decimalRe.FindStringSubmatch(s); m != nil {
+		n.Decimals = len(m[2]) + len(m[3])
+	}
+	return n, true
+}
+
+// parser is a recursive descent evaluator over big.Rat.
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = { "+" | "-" } power
+//	power   = postfix [ "^" unary ]
+//	postfix = atom [ "%" ]
+//	atom    = number | "(" expr ")" | "{" expr "}" | frac
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseExpr() (*big.Rat, bool) {
+	x, ok := p.parseTerm()
+	if !ok {
+		return nil, false
+	}
+	for {
+		switch {
+		case p.consume("+"):
+			y, ok := p.parseTerm()
+			if !ok {
+				return nil, false
+			}
+			x.Add(x, y)
+		case p.consume("-"):
+			y, ok := p.parseTerm()
+			if !ok {
+				return nil, false
+			}
+			x.Sub(x, y)
+		default:
+			return x, true
+		}
+	}
+}
+
+func (p *parser) parseTerm() (*big.Rat, bool) {
+	x, ok := p.parseUnary()
+	if !ok {
+		return nil, false
+	}
+	for {
+		switch {
+		case p.consume("*"):
+			y, ok := p.parseUnary()
+			if !ok {
+				return nil, false
+			}
+			x.Mul(x, y)
+		case p.consume("/"):
+			y, ok := p.parseUnary()
+			if !ok || y.Sign() == 0 {
+				return nil, false
+			}
+			x.Quo(x, y)
+		default:
+			return x, true
+		}
+	}
+}
+
+func (p *parser) parseUnary() (*big.Rat, bool) {
+	switch {
+	case p.consume("-"):
+		x, ok := p.parseUnary()
+		if !ok {
+			return nil, false
+		}
+		return x.Neg(x), true
+	case p.consume("+"):
+		return p.parseUnary()
+	default:
+		return p.parsePower()
+	}
+}
+
+func (p *parser) parsePower() (*big.Rat, bool) {
+	base, ok := p.parsePostfix()
+	if !ok {
+		return nil, false
+	}
+	if !p.consume("^") {
+		return base, true
+	}
+
+	exp, ok := p.parseUnary()
+	if !ok || !exp.IsInt() || !exp.Num().IsInt64() {
+		return nil, false
+	}
+	e := exp.Num().Int64()
+	if e > maxExponent || e < -maxExponent || (e < 0 && base.Sign() == 0) {
+		return nil, false
+	}
+
+	abs := e
+	if abs < 0 {
+		abs = -abs
+	}
+	if int64(max(base.Num().BitLen(), base.Denom().BitLen()))*abs > maxPowerBits {
+		return nil, false
+	}
+	num := new(big.Int).Exp(base.Num(), big.NewInt(abs), nil)
+	den := new(big.Int).Exp(base.Denom(), big.NewInt(abs), nil)
+	if e < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), true
+}
+
+func (p *parser) parsePostfix() (*big.Rat, bool) {
+	x, ok := p.parseAtom()
+	if !ok {
+		return nil, false
+	}
+	if p.consume("%") {
+		x.Quo(x, big.NewRat(100, 1))
+	}
+	return x, true
+}
+
+func (p *parser) parseAtom() (*big.Rat, bool) {
+	switch {
+	case p.consume("("):
+		return p.parseGroup(")")
+	case p.consume("{"):
+		return p.parseGroup("}")
+	case p.consume(`\frac`), p.consume(`\dfrac`), p.consume(`\tfrac`):
+		if !p.consume("{") {
+			return nil, false
+		}
+		num, ok := p.parseGroup("}")
+		if !ok || !p.consume("{") {
+			return nil, false
+		}
+		den, ok := p.parseGroup("}")
+		if !ok || den.Sign() == 0 {
+			return nil, false
+		}
+		return num.Quo(num, den), true
+	default:
+		return p.parseNumber()
+	}
+}
+
+func (p *parser) parseGroup(closing string) (*big.Rat, bool) {
+	x, ok := p.parseExpr()
+	if !ok || !p.consume(closing) {
+		return nil, false
+	}
+	return x, true
+}
+
+// parseNumber parses an unsigned number literal, including repeating decimals and scientific notation.
+func (p *parser) parseNumber() (*big.Rat, bool) {
+	start := p.pos
+	intPart := p.digits()
+	var frac, repeat string
+	if p.consume(".") {
+		frac = p.digits()
+		switch {
+		case p.consume("("):
+			repeat = p.digits()
+			if repeat == "" || !p.consume(")") {
+				return nil, false
+			}
+		case frac != "" && p.consume("..."):
+			frac, repeat = splitRepeating(frac)
+		}
+	}
+	if intPart == "" && frac == "" && repeat == "" {
+		p.pos = start
+		return nil, false
+	}
+
+	x, ok := new(big.Rat).SetString(cmp.Or(intPart, "0") + "." + cmp.Or(frac, "0"))
+	if !ok {
+		return nil, false
+	}
+	if repeat != "" {
+		// 0.ab(cd) adds cd / (99 * 10^len(ab)).
+		period := new(big.Int).Sub(pow10(len(repeat)), big.NewInt(1))
+		den := new(big.Int).Mul(period, pow10(len(frac)))
+		rep, _ := new(big.Int).SetString(repeat, 10)
+		x.Add(x, new(big.Rat).SetFrac(rep, den))
+	}
+
+	if c := p.peek(); c == 'e' || c == 'E' {
+		save := p.pos
+		p.pos++
+		neg := p.consume("-")
+		if !neg {
+			p.consume("+")
+		}
+		exp := p.digits()
+		if exp == "" || len(exp) > 3 {
+			p.pos = save
+			return x, true
+		}
+		e := new(big.Int)
+		e.SetString(exp, 10)
+		if e.Int64() > maxExponent {
+			return nil, false
+		}
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), e, nil))
+		if neg {
+			x.Quo(x, scale)
+		} else {
+			x.Mul(x, scale)
+		}
+	}
+
+	return x, true
+}
+
+func (p *parser) digits() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// splitRepeating splits the fractional digits of an ellipsis-terminated decimal into the fixed prefix and the
+// repeating period.
+//
+// The period is the shortest digit group repeated at least twice at the end ("142857142857…" → "142857"); if no
+// group repeats, the last digit is taken to repeat ("0.3…" → "3").
+func splitRepeating(frac string) (prefix, period string) {
+	for n := 1; 2*n <= len(frac); n++ {
+		tail := frac[len(frac)-n:]
+		if frac[len(frac)-2*n:len(frac)-n] != tail {
+			continue
+		}
+		// Drop every full repetition of the period from the fixed prefix.
+		prefix = frac[:len(frac)-n]
+		for strings.HasSuffix(prefix, tail) {
+			prefix = strings.TrimSuffix(prefix, tail)
+		}
+		return prefix, tail
+	}
+	return frac[:len(frac)-1], frac[len(frac)-1:]
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,224 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mathcheck
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in           string
+		want         string
+		wantDecimals int
+		wantOK       bool
+	}{
+		"integer":               {in: "42", want: "42", wantDecimals: -1, wantOK: true},
+		"negative decimal":      {in: "-0.25", want: "-1/4", wantDecimals: 2, wantOK: true},
+		"leading dot":           {in: ".5", want: "1/2", wantDecimals: 1, wantOK: true},
+		"fraction":              {in: "2/6", want: "1/3", wantDecimals: -1, wantOK: true},
+		"unicode ellipsis":      {in: "0.333…", want: "1/3", wantDecimals: -1, wantOK: true},
+		"ascii ellipsis":        {in: "0.1666...", want: "1/6", wantDecimals: -1, wantOK: true},
+		"repeating group":       {in: "0.142857142857…", want: "1/7", wantDecimals: -1, wantOK: true},
+		"parenthesized period":  {in: "1.2(3)", want: "37/30", wantDecimals: -1, wantOK: true},
+		"latex frac":            {in: `$\frac{1}{3}$`, want: "1/3", wantDecimals: -1, wantOK: true},
+		"boxed dfrac":           {in: `\boxed{-\dfrac{3}{4}}`, want: "-3/4", wantDecimals: -1, wantOK: true},
+		"arithmetic precedence": {in: "2 + 3 * 4 ^ 2", want: "50", wantDecimals: -1, wantOK: true},
+		"negative exponent":     {in: "2^-2", want: "1/4", wantDecimals: -1, wantOK: true},
+		"scientific":            {in: "1.5e3", want: "1500", wantDecimals: -1, wantOK: true},
+		"times ten power":       {in: `1.5 \times 10^{-2}`, want: "3/200", wantDecimals: -1, wantOK: true},
+		"largest power":         {in: "2^256", want: "115792089237316195423570985008687907853269984665640564039457584007913129639936", wantDecimals: -1, wantOK: true},
+		"exponent too large":    {in: "2^257", wantOK: false},
+		"nested power":          {in: "9^256^256", wantOK: false},
+		"grouped nested power":  {in: "((9^256)^256)^256", wantOK: false},
+		"power too large":       {in: "(9^256)^256", wantOK: false},
+		"percent":               {in: "12.5%", want: "1/8", wantDecimals: -1, wantOK: true},
+		"thousands separators":  {in: "1,234,567", want: "1234567", wantDecimals: -1, wantOK: true},
+		"trailing period":       {in: "7.", want: "7", wantDecimals: -1, wantOK: true},
+		"variable":              {in: "x + 1", wantOK: false},
+		"word":                  {in: "Paris", wantOK: false},
+		"division by zero":      {in: "1/0", wantOK: false},
+		"unbalanced":            {in: "(1 + 2", wantOK: false},
+		"irrational function":   {in: `\sqrt{2}`, wantOK: false},
+		"empty":                 {in: "  ", wantOK: false},
+		"list":                  {in: "1, 2", wantOK: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := Parse(tt.in)
+			if ok != tt.wantOK {
+				t.Fatalf("Parse(%q) ok = %v, want %v", tt.in, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.String() != tt.want || got.Decimals != tt.wantDecimals {
+				t.Fatalf("Parse(%q) = (%s, %d), want (%s, %d)", tt.in, got, got.Decimals, tt.want, tt.wantDecimals)
+			}
+		})
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		a, b string
+		want bool
+	}{
+		"fraction and repeating decimal": {a: "1/3", b: "0.333…", want: true},
+		"fraction and rounded decimal":   {a: "1/3", b: "0.33", want: true},
+		"repeating and rounded decimal":  {a: "0.(3)", b: "0.33", want: true},
+		"rounded up":                     {a: "2/3", b: "0.67", want: true},
+		"negative rounding":              {a: "-2/3", b: "-0.67", want: true},
+		"equal decimals":                 {a: "0.50", b: "1/2", want: true},
+		"one decimal is too coarse":      {a: "1/3", b: "0.3", want: false},
+		"wrong rounding":                 {a: "1/3", b: "0.34", want: false},
+		"less precise decimal":           {a: "0.3", b: "0.33", want: false},
+		"rounded decimals":               {a: "0.334", b: "0.33", want: false},
+		"integers are exact":             {a: "3", b: "3.0001", want: false},
+		"different values":               {a: "1/3", b: "1/4", want: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			a, ok := Parse(tt.a)
+			if !ok {
+				t.Fatalf("Parse(%q) failed", tt.a)
+			}
+			b, ok := Parse(tt.b)
+			if !ok {
+				t.Fatalf("Parse(%q) failed", tt.b)
+			}
+			if got := Equivalent(a, b); got != tt.want {
+				t.Fatalf("Equivalent(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := Equivalent(b, a); got != tt.want {
+				t.Fatalf("Equivalent(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in []string
+		// want maps each input to the sorted inputs of its class.
+		want map[string][]string
+	}{
+		"rounded decimals stay apart": {
+			in: []string{"0.325", "0.334", "0.33"},
+			want: map[string][]string{
+				"0.325": {"0.325"},
+				"0.334": {"0.334"},
+				"0.33":  {"0.33"},
+			},
+		},
+		"rounded decimal joins the value it rounds from": {
+			in: []string{"0.33", "0.334", "1/3"},
+			want: map[string][]string{
+				"0.33":  {"0.33", "1/3"},
+				"0.334": {"0.334"},
+				"1/3":   {"0.33", "1/3"},
+			},
+		},
+		"equal values": {
+			in: []string{"0.33", "1/3", "0.333…", "0.(3)"},
+			want: map[string][]string{
+				"0.33":   {"0.(3)", "0.33", "0.333…", "1/3"},
+				"1/3":    {"0.(3)", "0.33", "0.333…", "1/3"},
+				"0.333…": {"0.(3)", "0.33", "0.333…", "1/3"},
+				"0.(3)":  {"0.(3)", "0.33", "0.333…", "1/3"},
+			},
+		},
+		"ambiguous rounding": {
+			in: []string{"0.33", "1/3", "331/1000"},
+			want: map[string][]string{
+				"0.33":     {"0.33"},
+				"1/3":      {"1/3"},
+				"331/1000": {"331/1000"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, in := range permutations(tt.in) {
+				nums := make([]Number, len(in))
+				for i, s := range in {
+					n, ok := Parse(s)
+					if !ok {
+						t.Fatalf("Parse(%q) failed", s)
+					}
+					nums[i] = n
+				}
+				groups := Group(nums)
+
+				got := make(map[string][]string, len(in))
+				for i, s := range in {
+					for j, g := range groups {
+						if g == groups[i] {
+							got[s] = append(got[s], in[j])
+						}
+					}
+					slices.Sort(got[s])
+				}
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Fatalf("Group(%q) mismatch (-want +got):\n%s", in, diff)
+				}
+				next := 0
+				for _, g := range groups {
+					if g > next {
+						t.Fatalf("Group(%q) = %v, want classes numbered in order of first appearance", in, groups)
+					}
+					if g == next {
+						next++
+					}
+				}
+			}
+		})
+	}
+}
+
+// permutations returns every ordering of s.
+func permutations(s []string) [][]string {
+	if len(s) <= 1 {
+		return [][]string{slices.Clone(s)}
+	}
+	var perms [][]string
+	for i := range s {
+		rest := slices.Concat(s[:i], s[i+1:])
+		for _, p := range permutations(rest) {
+			perms = append(perms, append([]string{s[i]}, p...))
+		}
+	}
+	return perms
+}
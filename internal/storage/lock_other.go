@@ -0,0 +1,27 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix && !windows
+
+package storage
+
+import "os"
+
+// lockFile does nothing on the platforms without file locks, such as WebAssembly; there, a directory must not be
+// shared by processes.
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,40 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxDirPath is the path length limit of directories in the Windows APIs: MAX_PATH (260) less room for an 8.3 file
+// name.
+const maxDirPath = 248
+
+// LongPath returns path in the extended-length \\?\ form when it is an absolute path reaching the MAX_PATH limits, so
+// it can be opened although the system does not enable long paths; other paths are returned unchanged.
+func LongPath(path string) string {
+	if len(path) < maxDirPath || strings.HasPrefix(path, `\\?\`) || !filepath.IsAbs(path) {
+		return path
+	}
+	path = filepath.Clean(path)
+	if rest, ok := strings.CutPrefix(path, `\\`); ok {
+		// A UNC path \\server\share\... becomes \\?\UNC\server\share\....
+		return `\\?\UNC\` + rest
+	}
+	return `\\?\` + path
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package storage keeps the files of the TUMIX stores, such as the sessions of -session_dir, the quota usage, and the
+// audit trails, in a [Dir].
+//
+// A Dir only creates files named by a single path element checked by [ValidateName], so an ID used as a file name
+// cannot escape the directory or name a device on Windows. Paths beyond MAX_PATH work on Windows through [LongPath],
+// and [Dir.Lock] takes an advisory lock held against other processes: flock on Unix, LockFileEx on Windows.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNameLen is the maximum length in bytes of a name accepted by [ValidateName], the file name limit of common file
+// systems.
+const MaxNameLen = 255
+
+// ErrInvalidName is the error of [ValidateName].
+var ErrInvalidName = errors.New("invalid name")
+
+// windowsReserved are the device names Windows reserves in every directory, with or without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ValidateName reports whether name can be used as a file name on every platform: a single non-empty path element of
+// valid UTF-8, other than "." and "..", without separators, control characters, or the characters Windows forbids
+// (<>:"|?*), not a reserved Windows device name such as CON or NUL.3, not ending in a dot or a space, and at most
+// [MaxNameLen] bytes long. The error wraps [ErrInvalidName].
+func ValidateName(name string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidName, name, reason)
+	}
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: empty", ErrInvalidName)
+	case name == "." || name == "..":
+		return invalid("relative path element")
+	case len(name) > MaxNameLen:
+		return invalid(fmt.Sprintf("longer than %d bytes", MaxNameLen))
+	case !utf8.ValidString(name):
+		return invalid("not valid UTF-8")
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+		return invalid("ends in a dot or a space")
+	}
+	for _, r := range name {
+		switch {
+		case r == '/' || r == '\\':
+			return invalid("contains a path separator")
+		case r < 0x20 || r == 0x7f:
+			return invalid("contains a control character")
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return invalid(fmt.Sprintf("contains %q", r))
+		}
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if windowsReserved[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return invalid("reserved device name on Windows")
+	}
+	return nil
+}
+
+// Dir is a directory of files named by validated names.
+type Dir struct {
+	path string
+}
+
+// OpenDir returns the directory at path, creating it and its parents with perm if needed.
+func OpenDir(path string, perm os.FileMode) (*Dir, error) {
+	if path == "" {
+		return nil, errors.New("storage: empty directory path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	if err := os.MkdirAll(LongPath(abs), perm); err != nil {
+		return nil, fmt.Errorf("storage: mkdir %s: %w", path, err)
+	}
+	return &Dir{path: abs}, nil
+}
+
+// Path returns the absolute path of d.
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// Join returns the path of the file name in d, in its [LongPath] form, or an error if name is not valid.
+func (d *Dir) Join(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", fmt.Errorf("storage: %w", err)
+	}
+	return LongPath(filepath.Join(d.path, name)), nil
+}
+
+// WriteFile writes data to the file name in d, replacing it atomically: the data is written to a temporary file,
+// flushed with fsync if sync is set, and renamed over name.
+func (d *Dir) WriteFile(name string, data []byte, perm os.FileMode, sync bool) error {
+	path, err := d.Join(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(LongPath(d.path), "."+name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("storage: create temp file: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) //nolint:errcheck // gone after the rename
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("storage: write %s: %w", name, err)
+	}
+	if sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("storage: sync %s: %w", name, err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("storage: close %s: %w", name, err)
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return fmt.Errorf("storage: chmod %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("storage: rename %s: %w", name, err)
+	}
+	return nil
+}
+
+// Lock is an exclusive advisory lock on a file, held until [Lock.Unlock].
+type Lock struct {
+	f *os.File
+}
+
+// Lock blocks until it holds the exclusive lock of the lock file name in d, creating the file if needed. Processes
+// locking the same file exclude each other; within a process, each Lock call opens its own file, so they exclude
+// each other too.
+func (d *Dir) Lock(name string) (*Lock, error) {
+	path, err := d.Join(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("storage: lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("storage: lock %s: %w", name, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock. Unlocking a released lock does nothing.
+func (l *Lock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	f := l.f
+	l.f = nil
+	err := unlockFile(f)
+	return errors.Join(err, f.Close())
+}
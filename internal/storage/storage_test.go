@@ -0,0 +1,162 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		name   string
+		wantOK bool
+	}{
+		"plain":           {name: "session-1", wantOK: true},
+		"dotted":          {name: "sessions.json", wantOK: true},
+		"unicode":         {name: "セッション", wantOK: true},
+		"inner dot dot":   {name: "a..b", wantOK: true},
+		"reserved prefix": {name: "CONSOLE", wantOK: true},
+		"max length":      {name: strings.Repeat("a", MaxNameLen), wantOK: true},
+		"empty":           {name: ""},
+		"dot":             {name: "."},
+		"dot dot":         {name: ".."},
+		"too long":        {name: strings.Repeat("a", MaxNameLen+1)},
+		"invalid utf8":    {name: "a\xffb"},
+		"trailing dot":    {name: "a."},
+		"trailing space":  {name: "a "},
+		"slash":           {name: "../etc/passwd"},
+		"backslash":       {name: `..\windows`},
+		"nul":             {name: "a\x00b"},
+		"newline":         {name: "a\nb"},
+		"colon":           {name: "C:"},
+		"wildcard":        {name: "a*"},
+		"reserved":        {name: "NUL"},
+		"reserved lower":  {name: "com1"},
+		"reserved ext":    {name: "aux.json"},
+		"reserved padded": {name: "LPT9 .txt"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateName(tt.name)
+			if gotOK := err == nil; gotOK != tt.wantOK {
+				t.Fatalf("ValidateName(%q) = %v, want ok %t", tt.name, err, tt.wantOK)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidName) {
+				t.Fatalf("ValidateName(%q) = %v, want it to wrap ErrInvalidName", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestDirJoin(t *testing.T) {
+	t.Parallel()
+
+	d, err := OpenDir(filepath.Join(t.TempDir(), "a", "b"), 0o700)
+	if err != nil {
+		t.Fatalf("OpenDir() error = %v", err)
+	}
+	if info, err := os.Stat(d.Path()); err != nil || !info.IsDir() {
+		t.Fatalf("OpenDir() did not create the directory: %v", err)
+	}
+
+	got, err := d.Join("x.json")
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	if want := LongPath(filepath.Join(d.Path(), "x.json")); got != want {
+		t.Fatalf("Join() = %q, want %q", got, want)
+	}
+	if _, err := d.Join("../x.json"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("Join(../x.json) error = %v, want %v", err, ErrInvalidName)
+	}
+}
+
+func TestDirWriteFile(t *testing.T) {
+	t.Parallel()
+
+	d, err := OpenDir(t.TempDir(), 0o700)
+	if err != nil {
+		t.Fatalf("OpenDir() error = %v", err)
+	}
+	for _, data := range []string{"first", "second"} {
+		if err := d.WriteFile("data.json", []byte(data), 0o600, true); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	got, err := os.ReadFile(filepath.Join(d.Path(), "data.json"))
+	if err != nil || string(got) != "second" {
+		t.Fatalf("file = %q, %v, want second", got, err)
+	}
+	entries, err := os.ReadDir(d.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("directory has %d entries, want no temporary file left", len(entries))
+	}
+	if err := d.WriteFile("../data.json", nil, 0o600, false); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("WriteFile(../data.json) error = %v, want %v", err, ErrInvalidName)
+	}
+}
+
+func TestDirLock(t *testing.T) {
+	t.Parallel()
+
+	d, err := OpenDir(t.TempDir(), 0o700)
+	if err != nil {
+		t.Fatalf("OpenDir() error = %v", err)
+	}
+	lock, err := d.Lock("test.lock")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	locked := make(chan *Lock)
+	go func() {
+		l, err := d.Lock("test.lock")
+		if err != nil {
+			t.Errorf("second Lock() error = %v", err)
+		}
+		locked <- l
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second Lock() acquired a held lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("second Unlock() error = %v", err)
+	}
+	if err := (<-locked).Unlock(); err != nil {
+		t.Fatalf("Unlock() of the second lock error = %v", err)
+	}
+}
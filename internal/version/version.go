@@ -20,12 +20,100 @@ import (
 	"fmt"
 	goversion "go/version"
 	"runtime"
+	"runtime/debug"
 	"strings"
 )
 
 // Version exposes the current version of tumix.
 const Version = "0.1.0"
 
+// Commit and BuildDate may be set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/zchee/tumix/internal/version.Commit=$(git rev-parse HEAD) -X github.com/zchee/tumix/internal/version.BuildDate=$(date -u +%FT%TZ)"
+//
+// When empty, [Build] falls back to the VCS information stamped by the go command: the revision and its commit time.
+var (
+	Commit    string
+	BuildDate string
+)
+
+// BuildInfo describes the running tumix binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitzero"`
+	BuildDate string `json:"build_date,omitzero"`
+	// Modified reports that the binary was built from a working tree with uncommitted changes.
+	Modified  bool   `json:"modified,omitzero"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// Deps lists the module dependencies linked into the binary.
+	Deps []Module `json:"deps,omitzero"`
+}
+
+// Module is a module dependency of the binary.
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Replace is the path of the module replacing Path, if any.
+	Replace string `json:"replace,omitzero"`
+}
+
+// Build returns the build information of the running binary.
+func Build() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	for _, dep := range bi.Deps {
+		m := Module{Path: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			m.Replace = dep.Replace.Path
+			m.Version = dep.Replace.Version
+		}
+		info.Deps = append(info.Deps, m)
+	}
+	return info
+}
+
+// String returns a one-line summary of b, without the dependencies.
+func (b BuildInfo) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "tumix %s", b.Version)
+	if b.Commit != "" {
+		fmt.Fprintf(&sb, " (%s", b.Commit)
+		if b.Modified {
+			sb.WriteString(", modified")
+		}
+		if b.BuildDate != "" {
+			fmt.Fprintf(&sb, ", %s", b.BuildDate)
+		}
+		sb.WriteString(")")
+	}
+	fmt.Fprintf(&sb, " %s %s", b.GoVersion, b.Platform)
+	return sb.String()
+}
+
 // UserAgent returns the User-Agent header value for tumix API requests.
 func UserAgent(apiName string) string {
 	v := strings.Split(runtime.Version(), " X")
@@ -29,6 +29,7 @@ import (
 	"expvar"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"math"
 	"net/http"
@@ -36,90 +37,206 @@ import (
 	"os/signal"
 	"path/filepath"
 	"reflect"
+	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"golang.org/x/sync/errgroup"
 	adkagent "google.golang.org/adk/agent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/model/gemini"
-	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	tumixagent "github.com/zchee/tumix/agent"
+	"github.com/zchee/tumix/audit"
+	"github.com/zchee/tumix/experiment"
 	"github.com/zchee/tumix/gollm"
+	"github.com/zchee/tumix/gollm/catalog"
+	"github.com/zchee/tumix/gollm/dedup"
+	"github.com/zchee/tumix/gollm/failover"
+	"github.com/zchee/tumix/gollm/seed"
+	"github.com/zchee/tumix/gollm/xai"
+	"github.com/zchee/tumix/internal/storage"
 	"github.com/zchee/tumix/internal/version"
 	"github.com/zchee/tumix/log"
+	"github.com/zchee/tumix/pricing"
+	"github.com/zchee/tumix/quota"
+	tumixrun "github.com/zchee/tumix/run"
+	"github.com/zchee/tumix/session/retention"
 	"github.com/zchee/tumix/session/sessiondb"
 	"github.com/zchee/tumix/session/sessionfs"
 	"github.com/zchee/tumix/telemetry/httptelemetry"
+	"github.com/zchee/tumix/telemetry/openinference"
+	"github.com/zchee/tumix/telemetry/runmeta"
+	"github.com/zchee/tumix/tool/mcp"
+	"github.com/zchee/tumix/tool/python"
+	"github.com/zchee/tumix/tool/webfetch"
+	"github.com/zchee/tumix/usage"
+	"github.com/zchee/tumix/workflow"
 )
 
 type config struct {
-	AppName         string
-	LLMBackend      string
-	ModelName       string
-	APIKey          string
-	TraceHTTP       bool
-	UserID          string
-	SessionID       string
-	SessionDir      string
-	MaxRounds       uint
-	MinRounds       uint
-	Temperature     float64
-	TopP            float64
-	TopK            int
-	MaxTokens       int
-	Seed            int64
-	OutputJSON      bool
-	DryRun          bool
-	LogJSON         bool
-	OTLPEndpoint    string
-	CallWarn        int
-	BatchFile       string
-	Concurrency     int
-	MaxPromptChars  int
-	MaxPromptTokens int
-	MaxCostUSD      float64
-	AutoAgents      int
-	BudgetTokens    int
-	BenchLocal      int
-	MetricsAddr     string
-	Prompt          string
+	ConfigFile       string
+	AppName          string
+	LLMBackend       string
+	ModelName        string
+	JudgeModel       string
+	APIKey           string
+	TraceHTTP        bool
+	UserID           string
+	SessionID        string
+	SessionDir       string
+	SessionSync      string
+	SessionMaxAge    time.Duration
+	SessionMaxUser   int
+	SessionMaxBytes  int64
+	SessionGCDryRun  bool
+	SessionGCEvery   time.Duration
+	MaxRounds        uint
+	MinRounds        uint
+	Mode             string
+	Verify           bool
+	Triage           string
+	TriageCandidates int
+	RoundTimeout     time.Duration
+	RunTimeout       time.Duration
+	Temperature      float64
+	TopP             float64
+	TopK             int
+	MaxTokens        int
+	Seed             int64
+	OutputJSON       bool
+	Stream           bool
+	Progress         bool
+	Explain          bool
+	DryRun           bool
+	LogJSON          bool
+	OTLPEndpoint     string
+	Observability    string
+	ObservabilityURL string
+	CallWarn         int
+	BatchFile        string
+	BatchMaxRetries  int
+	BatchContinue    bool
+	BatchAdaptive    bool
+	BatchIsolate     bool
+	Concurrency      int
+	MaxPromptChars   int
+	MaxPromptTokens  int
+	CompressPrompt   bool
+	CompressTokens   int
+	SummarizeAnswers string
+	SummaryTokens    int
+	ReformatAnswers  bool
+	AnswerProtocol   string
+	StateMerge       string
+	MaxCostUSD       float64
+	AutoAgents       int
+	SamplesPerAgent  uint
+	DedupRequests    bool
+	Hierarchical     bool
+	MaxSubTasks      int
+	SubTaskRounds    uint
+	Workflow         string
+	Failover         string
+	MCPConfig        string
+	WebFetch         bool
+	Python           bool
+	AuditDir         string
+	AuditRedactKeys  string
+	AuditPatterns    string
+	A2AAgents        string
+	XAIRPS           float64
+	XAIBurst         int
+	ModelCatalog     bool
+	Logprobs         bool
+	Preflight        bool
+	BudgetTokens     int
+	BenchLocal       int
+	MetricsAddr      string
+	RunLabels        string
+	SystemPrompt     string
+	SystemPromptFile string
+	Attachments      []string
+	MaxAttachBytes   int
+	QuotaRequests    int64
+	QuotaTokens      int64
+	QuotaConcurrent  int
+	Prompt           string
+
+	// attachments are the loaded Attachments sent after the prompt text in the user turn.
+	attachments []*genai.Part
+
+	// contextWindow is the context window of the model from the model catalog, or zero when unknown.
+	contextWindow int
+
+	// runLabels are the parsed RunLabels attached to every model call of the run.
+	runLabels map[string]string
+
+	// answerProtocol is the AnswerProtocol named by AnswerProtocol.
+	answerProtocol *tumixagent.AnswerProtocol
+
+	// batchWorker is the batch worker running the prompt of a batch run.
+	batchWorker int
+
+	// onResult, when set, receives the result of a successful run, e.g. to grade the answer of a batch prompt.
+	onResult func(*tumixrun.Result)
+
+	// workflow is the loaded Workflow spec, which replaces the TUMIX orchestration when set.
+	workflow *workflow.Spec
+
+	// xaiLimiter is shared by every xai model of the run so candidates, judge, and failover draw from one quota.
+	xaiLimiter *xai.RateLimiter
+
+	// quota admits the runs of UserID within the -quota_* limits, or is nil without limits.
+	quota *quota.Limiter
+
+	// observer exports every run to the -observability_endpoint, or is nil without -observability.
+	observer *openinference.Exporter
+
+	// sessions is the -session_dir or TUMIX_SESSION_SQLITE store shared by every run of the process, or nil to open
+	// one per run.
+	sessions session.Service
 }
 
 var (
-	prices = map[string]struct {
-		inUSDPerKT, outUSDPerKT float64
-	}{
-		"gemini-2.5-flash":     {inUSDPerKT: 0.00015, outUSDPerKT: 0.0006},
-		"gemini-2.5-flash-002": {inUSDPerKT: 0.00015, outUSDPerKT: 0.0006},
-		"gemini-2.5-pro":       {inUSDPerKT: 0.00125, outUSDPerKT: 0.0050},
-		"gemini-2.5-pro-002":   {inUSDPerKT: 0.00125, outUSDPerKT: 0.0050},
-		"gemini-1.5-flash":     {inUSDPerKT: 0.00020, outUSDPerKT: 0.0008},
-		"gemini-1.5-flash-8b":  {inUSDPerKT: 0.00020, outUSDPerKT: 0.0008},
-		"gemini-1.5-pro":       {inUSDPerKT: 0.00100, outUSDPerKT: 0.0040},
-	}
-	meter            metric.Meter
-	requestCounter   metric.Int64Counter
-	inputTokCounter  metric.Int64Counter
-	outputTokCounter metric.Int64Counter
-	costCounter      metric.Float64Counter
-	expRequests      = expvar.NewInt("tumix_requests")
-	expInputTokens   = expvar.NewInt("tumix_input_tokens")
-	expOutputTokens  = expvar.NewInt("tumix_output_tokens")
-	expCostUSD       = expvar.NewFloat("tumix_cost_usd")
+	prices             = pricing.Default()
+	meter              metric.Meter
+	requestCounter     metric.Int64Counter
+	inputTokCounter    metric.Int64Counter
+	outputTokCounter   metric.Int64Counter
+	costCounter        metric.Float64Counter
+	batchWindowGauge   metric.Int64Gauge
+	quotaRejected      metric.Int64Counter
+	sessionsEvicted    metric.Int64Counter
+	panicCounter       metric.Int64Counter
+	expRequests        = expvar.NewInt("tumix_requests")
+	expInputTokens     = expvar.NewInt("tumix_input_tokens")
+	expOutputTokens    = expvar.NewInt("tumix_output_tokens")
+	expCostUSD         = expvar.NewFloat("tumix_cost_usd")
+	expBatchWindow     = expvar.NewInt("tumix_batch_concurrency")
+	expDedupSaved      = expvar.NewInt("tumix_dedup_saved_calls")
+	expQuotaRejected   = expvar.NewInt("tumix_quota_rejections")
+	expSessionsEvicted = expvar.NewInt("tumix_sessions_evicted")
+	expPanics          = expvar.NewInt("tumix_panics")
 )
 
 func main() {
@@ -127,6 +244,19 @@ func main() {
 }
 
 func run() int {
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "validate" {
+		return runConfigValidate(os.Args[3:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		return runVersion(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sessions" {
+		return runSessions(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		return runDoctor(os.Args[2:])
+	}
+
 	cfg, err := parseConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
@@ -136,11 +266,11 @@ func run() int {
 	logger := log.New(log.Options{JSON: cfg.LogJSON})
 	ctx, stop := signal.NotifyContext(log.WithLogger(context.Background(), logger), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+	// The first signal salvages a provisional answer (see runOnce); a second one terminates right away.
+	context.AfterFunc(ctx, stop)
 
 	ctx = log.WithLogger(ctx, logger)
 
-	loadPricing(ctx)
-
 	shutdownTrace, traceErr := initTracing(ctx, &cfg)
 	if traceErr != nil {
 		log.Warn(ctx, "tracing disabled", "error", traceErr)
@@ -149,24 +279,91 @@ func run() int {
 	if err := initMetrics(); err != nil {
 		log.Warn(ctx, "init metrics failed", "error", err)
 	}
+	if err := initQuota(&cfg); err != nil {
+		log.Error(ctx, "failed to init quota", err)
+		return 1
+	}
+	if err := initSessionStore(ctx, &cfg); err != nil {
+		log.Error(ctx, "failed to init session store", err)
+		return 1
+	}
+	if err := initObserver(&cfg); err != nil {
+		log.Error(ctx, "failed to init observability exporter", err)
+		return 1
+	}
 	if cfg.MetricsAddr != "" {
-		go serveMetrics(ctx, cfg.MetricsAddr)
+		ready := &readiness{}
+		if err := registerReadiness(ready, &cfg); err != nil {
+			log.Warn(ctx, "readiness checks incomplete", "error", err)
+		}
+		go serveMetrics(ctx, cfg.MetricsAddr, ready)
 	}
 
 	httpClient := newHTTPClient(cfg.TraceHTTP)
+	if cfg.ModelCatalog {
+		cat, err := loadModelCatalog(ctx, &cfg, httpClient)
+		if err != nil {
+			log.Warn(ctx, "model catalog refresh failed", "error", err)
+		}
+		if cat != nil {
+			cfg.contextWindow = cat.ContextWindow(cfg.LLMBackend, cfg.ModelName)
+			log.Info(ctx, "model catalog", "priced_models", cat.ApplyPrices(prices), "context_window", cfg.contextWindow)
+		}
+	}
+	// The pricing file is loaded last so that it overrides the catalog prices.
+	loadPricing(ctx)
+
+	if cfg.Preflight {
+		if err := runPreflight(ctx, &cfg, httpClient); err != nil {
+			log.Error(ctx, "preflight failed", err)
+			return 1
+		}
+		if cfg.Prompt == "" && cfg.BatchFile == "" {
+			return 0
+		}
+	}
+
 	if err := enforcePromptTokens(ctx, &cfg, httpClient); err != nil {
 		log.Error(ctx, "prompt too large", err)
 		return 1
 	}
 	log.Info(ctx, "using model", "llm backend", cfg.LLMBackend)
-	llm, err := buildModel(ctx, &cfg, httpClient)
+	llm, err := buildModel(ctx, &cfg, cfg.ModelName, httpClient)
 	if err != nil {
 		log.Error(ctx, "failed to create model", err)
 		return 1
 	}
+	judgeLLM := llm
+	if name := judgeModelName(&cfg); name != cfg.ModelName {
+		log.Info(ctx, "using judge model", "judge_model", name)
+		judgeLLM, err = buildModel(ctx, &cfg, name, httpClient)
+		if err != nil {
+			log.Error(ctx, "failed to create judge model", err)
+			return 1
+		}
+	}
+
+	if cfg.DedupRequests {
+		llm, err = dedup.New(llm, tumixagent.RequestScope, dedup.WithMeter(meter), dedup.WithOnSaved(func() { expDedupSaved.Add(1) }))
+		if err != nil {
+			log.Error(ctx, "failed to create request dedup model", err)
+			return 1
+		}
+	}
+	if cfg.Seed != 0 {
+		// Derive the seed of every call from its agent, round, and sample. Deriving it after dedup keeps a shared call
+		// from depending on which of the identical requests came first.
+		if llm, err = seed.New(llm, tumixagent.SeedScope); err == nil {
+			judgeLLM, err = seed.New(judgeLLM, tumixagent.SeedScope)
+		}
+		if err != nil {
+			log.Error(ctx, "failed to create seeded model", err)
+			return 1
+		}
+	}
 
 	genCfg := buildGenConfig(&cfg)
-	candidateCount := 15 + cfg.AutoAgents
+	candidateCount := (15 + cfg.AutoAgents) * int(cfg.SamplesPerAgent) //nolint:gosec // TODO(zchee): fix nolint
 	if cfg.MaxCostUSD > 0 {
 		capRounds := max(capRoundsByBudget(&cfg, candidateCount), cfg.MinRounds)
 		if capRounds < cfg.MaxRounds {
@@ -175,7 +372,37 @@ func run() int {
 		}
 	}
 
-	loader, _, err := buildTumixLoader(llm, genCfg, cfg.MinRounds, cfg.MaxRounds, cfg.AutoAgents)
+	var toolsets []tool.Toolset
+	if cfg.MCPConfig != "" {
+		mcpToolset, err := buildMCPToolset(ctx, cfg.MCPConfig)
+		if err != nil {
+			log.Error(ctx, "failed to connect mcp servers", err)
+			return 1
+		}
+		defer mcpToolset.Close()
+		toolsets = append(toolsets, mcpToolset)
+	}
+	if cfg.WebFetch {
+		fetchToolset, err := webfetch.NewToolset(webfetch.New(webfetch.WithHTTPClient(&http.Client{
+			Transport: httptelemetry.NewTransportWithTrace(webfetch.NewTransport(), cfg.TraceHTTP),
+		})))
+		if err != nil {
+			log.Error(ctx, "failed to build web fetch tool", err)
+			return 1
+		}
+		toolsets = append(toolsets, fetchToolset)
+	}
+	if cfg.Python {
+		pyToolset, err := python.NewToolset(python.NewManager())
+		if err != nil {
+			log.Error(ctx, "failed to build python tool", err)
+			return 1
+		}
+		defer pyToolset.Close()
+		toolsets = append(toolsets, pyToolset)
+	}
+
+	loader, _, err := buildTumixLoader(llm, judgeLLM, genCfg, &cfg, toolsets...)
 	if err != nil {
 		log.Error(ctx, "failed to build tumix agent", err)
 		return 1
@@ -207,85 +434,246 @@ func run() int {
 	}
 
 	if cfg.BatchFile != "" {
-		if err := runBatch(ctx, &cfg, loader); err != nil {
+		// Batch prompts pick up the agents reloaded on SIGHUP from the next prompt on.
+		reloading := newReloader(&cfg, loader, overriddenKeys(), func(next *config) (adkagent.Loader, error) {
+			loader, _, err := buildTumixLoader(llm, judgeLLM, genCfg, next, toolsets...)
+			return loader, err
+		})
+		go reloading.watch(ctx)
+		report, err := runBatch(ctx, &cfg, reloading)
+		if err != nil {
 			log.Error(ctx, "batch run failed", err)
 			return 1
 		}
-		return 0
+		return report.exitCode()
 	}
 
 	log.Info(ctx, "run tumix", slog.Any("cfg", &cfg), slog.Any("loader", &loader))
 	if err := runOnce(ctx, &cfg, loader); err != nil {
 		log.Error(ctx, "run failed", err)
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			writeQuotaError(&cfg, exceeded)
+			return exitQuotaExceeded
+		}
 		return 1
 	}
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
 	return 0
 }
 
 func parseConfig() (config, error) {
-	cfg := config{
-		AppName:         "tumix",
-		LLMBackend:      cmp.Or(os.Getenv("TUMIX_BACKEND"), "gemini"),
-		ModelName:       cmp.Or(os.Getenv("TUMIX_MODEL"), "gemini-2.5-flash"),
-		TraceHTTP:       parseEnv("TUMIX_HTTP_TRACE", false),
-		UserID:          cmp.Or(os.Getenv("TUMIX_USER"), "user"),
-		SessionID:       cmp.Or(os.Getenv("TUMIX_SESSION"), ""),
-		SessionDir:      cmp.Or(os.Getenv("TUMIX_SESSION_DIR"), ""),
-		MaxRounds:       parseEnv("TUMIX_MAX_ROUNDS", uint(3)),
-		MinRounds:       parseEnv("TUMIX_MIN_ROUNDS", uint(2)),
-		Temperature:     parseEnv("TUMIX_TEMPERATURE", float64(-1)),
-		TopP:            parseEnv("TUMIX_TOP_P", float64(-1)),
-		TopK:            parseEnv("TUMIX_TOP_K", int(0)),
-		MaxTokens:       parseEnv("TUMIX_MAX_TOKENS", int(0)),
-		Seed:            parseEnv("TUMIX_SEED", int64(0)),
-		CallWarn:        parseEnv("TUMIX_CALL_WARN", int(300)),
-		Concurrency:     parseEnv("TUMIX_CONCURRENCY", int(1)),
-		MaxPromptChars:  parseEnv("TUMIX_MAX_PROMPT_CHARS", int(8000)),
-		MaxPromptTokens: parseEnv("TUMIX_MAX_PROMPT_TOKENS", int(0)),
-		MaxCostUSD:      parseEnv("TUMIX_MAX_COST_USD", float64(0.01)),
-		AutoAgents:      parseEnv("TUMIX_AUTO_AGENTS", int(0)),
-		BudgetTokens:    parseEnv("TUMIX_BUDGET_TOKENS", int(0)),
+	return loadConfig(false)
+}
+
+// loadConfig resolves the run configuration from the built-in defaults, the -config file, the environment, and the
+// flags, each layer overriding the previous one. validateOnly skips the checks that only matter for a run, the prompt
+// and the API key.
+func loadConfig(validateOnly bool) (config, error) {
+	base := defaultConfig()
+	base.ConfigFile = configFilePath(os.Args[1:])
+	if base.ConfigFile != "" {
+		fc, err := loadConfigFile(base.ConfigFile)
+		if err != nil {
+			return base, err
+		}
+		fc.apply(&base)
 	}
 
+	cfg := config{
+		ConfigFile:       base.ConfigFile,
+		AppName:          base.AppName,
+		LLMBackend:       cmp.Or(os.Getenv("TUMIX_BACKEND"), base.LLMBackend),
+		ModelName:        cmp.Or(os.Getenv("TUMIX_MODEL"), base.ModelName),
+		JudgeModel:       cmp.Or(os.Getenv("TUMIX_JUDGE_MODEL"), base.JudgeModel),
+		TraceHTTP:        parseEnv("TUMIX_HTTP_TRACE", base.TraceHTTP),
+		UserID:           cmp.Or(os.Getenv("TUMIX_USER"), base.UserID),
+		SessionID:        cmp.Or(os.Getenv("TUMIX_SESSION"), base.SessionID),
+		SessionDir:       cmp.Or(os.Getenv("TUMIX_SESSION_DIR"), base.SessionDir),
+		SessionSync:      cmp.Or(os.Getenv("TUMIX_SESSION_SYNC"), base.SessionSync),
+		SessionMaxAge:    parseEnv("TUMIX_SESSION_MAX_AGE", base.SessionMaxAge),
+		SessionMaxUser:   parseEnv("TUMIX_SESSION_MAX_PER_USER", base.SessionMaxUser),
+		SessionMaxBytes:  parseEnv("TUMIX_SESSION_MAX_BYTES", base.SessionMaxBytes),
+		SessionGCDryRun:  parseEnv("TUMIX_SESSION_GC_DRY_RUN", base.SessionGCDryRun),
+		SessionGCEvery:   parseEnv("TUMIX_SESSION_GC_INTERVAL", base.SessionGCEvery),
+		MaxRounds:        parseEnv("TUMIX_MAX_ROUNDS", base.MaxRounds),
+		MinRounds:        parseEnv("TUMIX_MIN_ROUNDS", base.MinRounds),
+		Mode:             cmp.Or(os.Getenv("TUMIX_MODE"), base.Mode),
+		Verify:           parseEnv("TUMIX_VERIFY", base.Verify),
+		Triage:           cmp.Or(os.Getenv("TUMIX_TRIAGE"), base.Triage),
+		TriageCandidates: parseEnv("TUMIX_TRIAGE_CANDIDATES", base.TriageCandidates),
+		RoundTimeout:     parseEnv("TUMIX_ROUND_TIMEOUT", base.RoundTimeout),
+		RunTimeout:       parseEnv("TUMIX_RUN_TIMEOUT", base.RunTimeout),
+		Temperature:      parseEnv("TUMIX_TEMPERATURE", base.Temperature),
+		TopP:             parseEnv("TUMIX_TOP_P", base.TopP),
+		TopK:             parseEnv("TUMIX_TOP_K", base.TopK),
+		MaxTokens:        parseEnv("TUMIX_MAX_TOKENS", base.MaxTokens),
+		Seed:             parseEnv("TUMIX_SEED", base.Seed),
+		Stream:           parseEnv("TUMIX_STREAM", base.Stream),
+		Progress:         parseEnv("TUMIX_PROGRESS", base.Progress),
+		Explain:          parseEnv("TUMIX_EXPLAIN", base.Explain),
+		CallWarn:         parseEnv("TUMIX_CALL_WARN", base.CallWarn),
+		Concurrency:      parseEnv("TUMIX_CONCURRENCY", base.Concurrency),
+		BatchMaxRetries:  parseEnv("TUMIX_BATCH_MAX_RETRIES", base.BatchMaxRetries),
+		BatchContinue:    parseEnv("TUMIX_BATCH_CONTINUE_ON_ERROR", base.BatchContinue),
+		BatchAdaptive:    parseEnv("TUMIX_BATCH_ADAPTIVE", base.BatchAdaptive),
+		BatchIsolate:     parseEnv("TUMIX_BATCH_ISOLATE", base.BatchIsolate),
+		MaxPromptChars:   parseEnv("TUMIX_MAX_PROMPT_CHARS", base.MaxPromptChars),
+		MaxPromptTokens:  parseEnv("TUMIX_MAX_PROMPT_TOKENS", base.MaxPromptTokens),
+		CompressPrompt:   parseEnv("TUMIX_COMPRESS_PROMPT", base.CompressPrompt),
+		CompressTokens:   parseEnv("TUMIX_COMPRESS_THRESHOLD_TOKENS", base.CompressTokens),
+		SummarizeAnswers: cmp.Or(os.Getenv("TUMIX_SUMMARIZE_ANSWERS"), base.SummarizeAnswers),
+		SummaryTokens:    parseEnv("TUMIX_SUMMARY_ANSWER_TOKENS", base.SummaryTokens),
+		ReformatAnswers:  parseEnv("TUMIX_REFORMAT_ANSWERS", base.ReformatAnswers),
+		AnswerProtocol:   cmp.Or(os.Getenv("TUMIX_ANSWER_PROTOCOL"), base.AnswerProtocol),
+		StateMerge:       cmp.Or(os.Getenv("TUMIX_STATE_MERGE"), base.StateMerge),
+		MaxCostUSD:       parseEnv("TUMIX_MAX_COST_USD", base.MaxCostUSD),
+		AutoAgents:       parseEnv("TUMIX_AUTO_AGENTS", base.AutoAgents),
+		SamplesPerAgent:  parseEnv("TUMIX_SAMPLES_PER_AGENT", base.SamplesPerAgent),
+		DedupRequests:    parseEnv("TUMIX_DEDUP_REQUESTS", base.DedupRequests),
+		Hierarchical:     parseEnv("TUMIX_HIERARCHICAL", base.Hierarchical),
+		MaxSubTasks:      parseEnv("TUMIX_MAX_SUBTASKS", base.MaxSubTasks),
+		SubTaskRounds:    parseEnv("TUMIX_SUBTASK_ROUNDS", base.SubTaskRounds),
+		Workflow:         cmp.Or(os.Getenv("TUMIX_WORKFLOW"), base.Workflow),
+		Failover:         cmp.Or(os.Getenv("TUMIX_FAILOVER"), base.Failover),
+		MCPConfig:        cmp.Or(os.Getenv("TUMIX_MCP_CONFIG"), base.MCPConfig),
+		WebFetch:         parseEnv("TUMIX_WEBFETCH", base.WebFetch),
+		Python:           parseEnv("TUMIX_PYTHON", base.Python),
+		Observability:    cmp.Or(os.Getenv("TUMIX_OBSERVABILITY"), base.Observability),
+		ObservabilityURL: cmp.Or(os.Getenv("TUMIX_OBSERVABILITY_ENDPOINT"), base.ObservabilityURL),
+		AuditDir:         cmp.Or(os.Getenv("TUMIX_AUDIT_DIR"), base.AuditDir),
+		AuditRedactKeys:  cmp.Or(os.Getenv("TUMIX_AUDIT_REDACT_KEYS"), base.AuditRedactKeys),
+		AuditPatterns:    cmp.Or(os.Getenv("TUMIX_AUDIT_REDACT_PATTERNS"), base.AuditPatterns),
+		A2AAgents:        cmp.Or(os.Getenv("TUMIX_A2A_AGENTS"), base.A2AAgents),
+		XAIRPS:           parseEnv("TUMIX_XAI_RPS", base.XAIRPS),
+		XAIBurst:         parseEnv("TUMIX_XAI_BURST", base.XAIBurst),
+		ModelCatalog:     parseEnv("TUMIX_MODEL_CATALOG", base.ModelCatalog),
+		Logprobs:         parseEnv("TUMIX_LOGPROBS", base.Logprobs),
+		Preflight:        parseEnv("TUMIX_PREFLIGHT", base.Preflight),
+		RunLabels:        cmp.Or(os.Getenv("TUMIX_RUN_LABELS"), base.RunLabels),
+		SystemPrompt:     cmp.Or(os.Getenv("TUMIX_SYSTEM_PROMPT"), base.SystemPrompt),
+		SystemPromptFile: cmp.Or(os.Getenv("TUMIX_SYSTEM_PROMPT_FILE"), base.SystemPromptFile),
+		BudgetTokens:     parseEnv("TUMIX_BUDGET_TOKENS", base.BudgetTokens),
+		MaxAttachBytes:   parseEnv("TUMIX_MAX_ATTACH_BYTES", base.MaxAttachBytes),
+		QuotaRequests:    parseEnv("TUMIX_QUOTA_REQUESTS_PER_DAY", base.QuotaRequests),
+		QuotaTokens:      parseEnv("TUMIX_QUOTA_TOKENS_PER_DAY", base.QuotaTokens),
+		QuotaConcurrent:  parseEnv("TUMIX_QUOTA_MAX_CONCURRENT", base.QuotaConcurrent),
+		MetricsAddr:      cmp.Or(os.Getenv("TUMIX_METRICS_ADDR"), base.MetricsAddr),
+		OTLPEndpoint:     base.OTLPEndpoint,
+		BatchFile:        base.BatchFile,
+		OutputJSON:       base.OutputJSON,
+		LogJSON:          base.LogJSON,
+	}
+
+	flag.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "YAML config file providing the defaults of the other flags; environment variables and flags override it (TUMIX_CONFIG)")
 	flag.StringVar(&cfg.LLMBackend, "backend", cfg.LLMBackend, "LLM backend to use (gemini, openai, anthropic, xai)")
 	flag.StringVar(&cfg.ModelName, "model", cfg.ModelName, "Gemini model to use (default TUMIX_MODEL or gemini-2.5-flash)")
+	flag.StringVar(&cfg.JudgeModel, "judge_model", cfg.JudgeModel, "Model for the judge on the same backend (empty uses -model; TUMIX_JUDGE_MODEL)")
 	flag.StringVar(&cfg.APIKey, "api_key", cfg.APIKey, "Gemini API key (GOOGLE_API_KEY)")
 	flag.BoolVar(&cfg.TraceHTTP, "http_trace", cfg.TraceHTTP, "Enable HTTP client OpenTelemetry spans")
 	flag.StringVar(&cfg.UserID, "user", cfg.UserID, "User ID for the session")
 	flag.StringVar(&cfg.SessionID, "session", cfg.SessionID, "Session ID (auto-generated if empty)")
 	flag.StringVar(&cfg.SessionDir, "session_dir", cfg.SessionDir, "Directory to persist sessions (optional, uses in-memory if empty)")
+	flag.StringVar(&cfg.SessionSync, "session_sync", cfg.SessionSync, "When the -session_dir journal and snapshots are flushed with fsync: always (every mutation; default), compaction (snapshots only), or never (TUMIX_SESSION_SYNC)")
+	flag.DurationVar(&cfg.SessionMaxAge, "session_max_age", cfg.SessionMaxAge, "Delete the stored sessions not updated for longer (0 keeps them; TUMIX_SESSION_MAX_AGE)")
+	flag.IntVar(&cfg.SessionMaxUser, "session_max_per_user", cfg.SessionMaxUser, "Keep at most this many stored sessions per user, deleting the least recently updated (0 for no limit; TUMIX_SESSION_MAX_PER_USER)")
+	flag.Int64Var(&cfg.SessionMaxBytes, "session_max_bytes", cfg.SessionMaxBytes, "Keep the stored sessions within this many bytes, deleting the least recently updated (0 for no limit; TUMIX_SESSION_MAX_BYTES)")
+	flag.BoolVar(&cfg.SessionGCDryRun, "session_gc_dry_run", cfg.SessionGCDryRun, "Log the sessions the -session_max_* limits would delete without deleting them (TUMIX_SESSION_GC_DRY_RUN)")
+	flag.DurationVar(&cfg.SessionGCEvery, "session_gc_interval", cfg.SessionGCEvery, "Interval between the enforcements of the -session_max_* limits, which also run at startup (default 1h; TUMIX_SESSION_GC_INTERVAL)")
 	flag.UintVar(&cfg.MaxRounds, "max_rounds", cfg.MaxRounds, "Maximum TUMIX iterations (default 3, overridable via TUMIX_MAX_ROUNDS)")
-	flag.UintVar(&cfg.MinRounds, "min_rounds", cfg.MinRounds, "Minimum TUMIX iterations before judge can stop (default 2, TUMIX_MIN_ROUNDS)")
+	flag.UintVar(&cfg.MinRounds, "min_rounds", cfg.MinRounds, "Minimum TUMIX rounds before the judge or a consensus can stop the run; at most max_rounds (TUMIX_MIN_ROUNDS)")
+	flag.StringVar(&cfg.Mode, "mode", cfg.Mode, "Orchestration of the rounds: tumix (judge after every round) or debate (candidates critique each other's answers, judge aggregates after max_rounds; TUMIX_MODE)")
+	flag.BoolVar(&cfg.Verify, "verify", cfg.Verify, "Have a verifier agent check the answer before finalizing and run another round when it fails (mode tumix only; TUMIX_VERIFY)")
+	flag.StringVar(&cfg.Triage, "triage", cfg.Triage, "Rate the question difficulty before the first round and run easy ones with triage_candidates candidates only: heuristic (prompt length and shape), model (one call to the model, heuristic on failure), or empty to always run the full mixture (TUMIX_TRIAGE)")
+	flag.IntVar(&cfg.TriageCandidates, "triage_candidates", cfg.TriageCandidates, "Number of candidates, the first ones, run for questions the triage rates easy (TUMIX_TRIAGE_CANDIDATES)")
+	flag.DurationVar(&cfg.RoundTimeout, "round_timeout", cfg.RoundTimeout, "Deadline of each round; late candidates are cut short and the round ends with the answers so far, skipping the judge (0 for none; TUMIX_ROUND_TIMEOUT)")
+	flag.DurationVar(&cfg.RunTimeout, "run_timeout", cfg.RunTimeout, "Wall-clock deadline of the rounds; when it fires the answer is the majority vote over the answers so far (0 for none; TUMIX_RUN_TIMEOUT)")
 	flag.Float64Var(&cfg.Temperature, "temperature", cfg.Temperature, "Sampling temperature (set <0 to leave model default; env TUMIX_TEMPERATURE)")
 	flag.Float64Var(&cfg.TopP, "top_p", cfg.TopP, "Top-p nucleus sampling (set <0 to leave model default; env TUMIX_TOP_P)")
 	flag.IntVar(&cfg.TopK, "top_k", cfg.TopK, "Top-k sampling (0 to leave default; env TUMIX_TOP_K)")
 	flag.IntVar(&cfg.MaxTokens, "max_tokens", cfg.MaxTokens, "Max output tokens (0 to leave default; env TUMIX_MAX_TOKENS)")
 	flag.Int64Var(&cfg.Seed, "seed", cfg.Seed, "Deterministic seed (0 to leave unset; env TUMIX_SEED)")
-	flag.BoolVar(&cfg.OutputJSON, "json", false, "Emit final answer as JSON to stdout")
+	flag.BoolVar(&cfg.OutputJSON, "json", cfg.OutputJSON, "Emit final answer as JSON to stdout")
+	flag.BoolVar(&cfg.Stream, "stream", cfg.Stream, "Stream to stdout, as they arrive, the tokens of the agents deciding the final answer: the Judge of every round, the synthesizer of a hierarchical run, or a root agent answering with a model call (ignored with -json; TUMIX_STREAM)")
+	flag.BoolVar(&cfg.Progress, "progress", cfg.Progress, "Print a progress bar with the elapsed time, ETA, and cost to stderr after every round (ignored with -json; TUMIX_PROGRESS)")
+	flag.BoolVar(&cfg.Explain, "explain", cfg.Explain, "Print why the final answer was chosen: the last round's vote, the judge's candidate scores, and the judge's analysis (ignored with -json; TUMIX_EXPLAIN)")
 	flag.BoolVar(&cfg.DryRun, "dry_run", false, "Print resolved config and exit without calling model")
-	flag.BoolVar(&cfg.LogJSON, "log_json", false, "Use JSON logging format")
+	flag.BoolVar(&cfg.LogJSON, "log_json", cfg.LogJSON, "Use JSON logging format")
 	flag.StringVar(&cfg.OTLPEndpoint, "otlp_endpoint", cfg.OTLPEndpoint, "OTLP endpoint for tracing (empty to disable)")
+	flag.StringVar(&cfg.Observability, "observability", cfg.Observability, "Export every run to an LLM-observability platform in this format: openinference (Arize Phoenix, LangSmith), or empty to disable (TUMIX_OBSERVABILITY)")
+	flag.StringVar(&cfg.ObservabilityURL, "observability_endpoint", cfg.ObservabilityURL, "OTLP/HTTP JSON traces URL of -observability, e.g. http://localhost:6006/v1/traces, or a file the traces are appended to; set TUMIX_OBSERVABILITY_HEADERS=key=value,... for API keys (TUMIX_OBSERVABILITY_ENDPOINT)")
 	flag.IntVar(&cfg.CallWarn, "call_warn", cfg.CallWarn, "Warn if estimated LLM calls exceed this number")
-	flag.StringVar(&cfg.BatchFile, "batch_file", cfg.BatchFile, "Optional file with one prompt per line for batch processing")
+	flag.StringVar(&cfg.BatchFile, "batch_file", cfg.BatchFile, "Optional file with one prompt per line for batch processing, optionally followed by a tab and the expected answer")
+	flag.IntVar(&cfg.BatchMaxRetries, "batch_max_retries", cfg.BatchMaxRetries, "Retries per failed prompt when using -batch_file (TUMIX_BATCH_MAX_RETRIES)")
+	flag.BoolVar(&cfg.BatchContinue, "batch_continue_on_error", cfg.BatchContinue, "Keep running the remaining batch prompts after one fails (TUMIX_BATCH_CONTINUE_ON_ERROR)")
+	flag.BoolVar(&cfg.BatchAdaptive, "batch_adaptive", cfg.BatchAdaptive, "Adapt batch parallelism (AIMD) between 1 and -concurrency from rate-limit errors and latency (TUMIX_BATCH_ADAPTIVE)")
+	flag.BoolVar(&cfg.BatchIsolate, "batch_isolate", cfg.BatchIsolate, "Build the agents anew for every batch worker so concurrent prompts share no agent instances (TUMIX_BATCH_ISOLATE)")
 	flag.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "Max concurrent prompts when using -batch_file")
 	flag.IntVar(&cfg.MaxPromptChars, "max_prompt_chars", cfg.MaxPromptChars, "Fail if user prompt exceeds this many characters")
 	flag.IntVar(&cfg.MaxPromptTokens, "max_prompt_tokens", cfg.MaxPromptTokens, "Fail if estimated prompt tokens exceed this value (heuristic)")
+	flag.BoolVar(&cfg.CompressPrompt, "compress_prompt", cfg.CompressPrompt, "Summarize the background of prompts longer than -compress_threshold_tokens before the first round, keeping the question verbatim (TUMIX_COMPRESS_PROMPT)")
+	flag.IntVar(&cfg.CompressTokens, "compress_threshold_tokens", cfg.CompressTokens, "Estimated prompt tokens above which -compress_prompt applies (TUMIX_COMPRESS_THRESHOLD_TOKENS)")
+	flag.StringVar(&cfg.SummarizeAnswers, "summarize_answers", cfg.SummarizeAnswers, "Shrink the previous answers candidates read each round: truncate (each to -summary_answer_tokens), cluster (also list equal answers once), model (also summarize them with one call), or empty to share them verbatim (TUMIX_SUMMARIZE_ANSWERS)")
+	flag.IntVar(&cfg.SummaryTokens, "summary_answer_tokens", cfg.SummaryTokens, "Estimated tokens each previous answer is truncated to by -summarize_answers, keeping its final answer (0 disables truncation; TUMIX_SUMMARY_ANSWER_TOKENS)")
+	flag.BoolVar(&cfg.ReformatAnswers, "reformat_answers", cfg.ReformatAnswers, "Ask a candidate whose answer has no recognizable final answer for it once more, reminding it of the answer format (TUMIX_REFORMAT_ANSWERS)")
+	flag.StringVar(&cfg.AnswerProtocol, "answer_protocol", cfg.AnswerProtocol, "How every agent marks its final answer: delimited (<<<answer>>>) or json ({\"final_answer\": \"answer\"}) (TUMIX_ANSWER_PROTOCOL)")
+	flag.StringVar(&cfg.StateMerge, "state_merge", cfg.StateMerge, "Which session state writes of the candidates, each isolated in its own namespace during a round, are merged back: unique (keys no other candidate wrote differently; default), last (the last candidate wins), or none (TUMIX_STATE_MERGE)")
 	flag.Float64Var(&cfg.MaxCostUSD, "max_cost_usd", cfg.MaxCostUSD, "Hard cap on estimated LLM cost per run (default $0.01, TUMIX_MAX_COST_USD)")
 	flag.IntVar(&cfg.AutoAgents, "auto_agents", cfg.AutoAgents, "Number of auto-designed agents to add (0 disables; TUMIX_AUTO_AGENTS)")
+	flag.UintVar(&cfg.SamplesPerAgent, "samples_per_agent", cfg.SamplesPerAgent, "Completions sampled per candidate agent per round for self-consistency voting (default 1; TUMIX_SAMPLES_PER_AGENT)")
+	flag.BoolVar(&cfg.DedupRequests, "dedup_requests", cfg.DedupRequests, "Serve identical candidate model requests within a round with one call; only useful with deterministic sampling (TUMIX_DEDUP_REQUESTS)")
+	flag.BoolVar(&cfg.Hierarchical, "hierarchical", cfg.Hierarchical, "Split complex questions into sub-questions answered by parallel TUMIX runs, then synthesize the answers (TUMIX_HIERARCHICAL)")
+	flag.IntVar(&cfg.MaxSubTasks, "max_subtasks", cfg.MaxSubTasks, "Max sub-questions per question with -hierarchical (TUMIX_MAX_SUBTASKS)")
+	flag.UintVar(&cfg.SubTaskRounds, "subtask_rounds", cfg.SubTaskRounds, "Max TUMIX rounds per sub-question with -hierarchical (TUMIX_SUBTASK_ROUNDS)")
+	flag.StringVar(&cfg.Workflow, "workflow", cfg.Workflow, "YAML workflow spec (llmagent, judge, parallel, sequential, and loop nodes) run instead of the TUMIX rounds, e.g. debate.yaml (TUMIX_WORKFLOW)")
+	flag.StringVar(&cfg.Failover, "failover", cfg.Failover, "Comma-separated backend:model list tried in order when -backend hits quota/availability errors (e.g. xai:grok-4,openai:gpt-5; TUMIX_FAILOVER)")
+	flag.StringVar(&cfg.MCPConfig, "mcp_config", cfg.MCPConfig, "Optional MCP servers config file (mcpServers JSON) whose tools are given to an extra candidate agent (TUMIX_MCP_CONFIG)")
+	flag.BoolVar(&cfg.WebFetch, "webfetch", cfg.WebFetch, "Give an extra candidate agent a robots.txt-aware web page fetch tool (TUMIX_WEBFETCH)")
+	flag.BoolVar(&cfg.Python, "python", cfg.Python, "Give an extra candidate agent a persistent python3 kernel tool with time and memory limits (TUMIX_PYTHON)")
+	flag.StringVar(&cfg.AuditDir, "audit_dir", cfg.AuditDir, "Write a hash-chained JSONL audit trail per run to this directory; set TUMIX_AUDIT_KEY to HMAC-sign records (TUMIX_AUDIT_DIR)")
+	flag.StringVar(&cfg.AuditRedactKeys, "audit_redact_keys", cfg.AuditRedactKeys, "Comma-separated state/argument keys whose values are redacted from the audit trail (TUMIX_AUDIT_REDACT_KEYS)")
+	flag.StringVar(&cfg.AuditPatterns, "audit_redact_patterns", cfg.AuditPatterns, "File of regexps (one per line) redacted from the audit trail, replacing the built-in PII patterns (TUMIX_AUDIT_REDACT_PATTERNS)")
+	flag.StringVar(&cfg.A2AAgents, "a2a_agents", cfg.A2AAgents, "Comma-separated remote A2A agents (url or url#skill) added as extra candidate agents (TUMIX_A2A_AGENTS)")
+	flag.Float64Var(&cfg.XAIRPS, "xai_rps", cfg.XAIRPS, "Client-side xAI request rate limit per model and endpoint in requests/sec (0 disables; TUMIX_XAI_RPS)")
+	flag.IntVar(&cfg.XAIBurst, "xai_burst", cfg.XAIBurst, "Burst size of the xAI rate limit (0 uses ceil(xai_rps); TUMIX_XAI_BURST)")
+	flag.BoolVar(&cfg.ModelCatalog, "model_catalog", cfg.ModelCatalog, "Fetch the provider model catalogs (cached for a day) for context windows and prices (TUMIX_MODEL_CATALOG)")
+	flag.BoolVar(&cfg.Logprobs, "logprobs", cfg.Logprobs, "Request token log probabilities from the candidates and weight the fallback vote by answer confidence (TUMIX_LOGPROBS)")
+	flag.BoolVar(&cfg.Preflight, "preflight", cfg.Preflight, "Before any work, check the API key, connection, a 1-token completion, and the tokenizer of every configured backend and report their latency; fail fast on errors, and exit after the checks when no prompt is given (TUMIX_PREFLIGHT)")
+	flag.StringVar(&cfg.SystemPrompt, "system_prompt", cfg.SystemPrompt, "Instructions prepended to every candidate's global instruction; {agent_name} and {model_name} are substituted (TUMIX_SYSTEM_PROMPT)")
+	flag.StringVar(&cfg.SystemPromptFile, "system_prompt_file", cfg.SystemPromptFile, "File read as -system_prompt (TUMIX_SYSTEM_PROMPT_FILE)")
+	flag.StringVar(&cfg.RunLabels, "run_labels", cfg.RunLabels, "Comma-separated key=value experiment labels sent with the user and session IDs on every model call as headers, gRPC metadata, and OTel baggage (TUMIX_RUN_LABELS)")
+	flag.Func("attach", "Image, PDF, or text file sent with the prompt to every candidate; repeatable", func(path string) error {
+		cfg.Attachments = append(cfg.Attachments, path)
+		return nil
+	})
+	flag.IntVar(&cfg.MaxAttachBytes, "max_attach_bytes", cfg.MaxAttachBytes, "Fail if the -attach files exceed this many bytes in total (0 disables; TUMIX_MAX_ATTACH_BYTES)")
+	flag.Int64Var(&cfg.QuotaRequests, "quota_requests_per_day", cfg.QuotaRequests, "Reject runs of -user beyond this many per UTC day, counted in -session_dir across processes (0 disables; TUMIX_QUOTA_REQUESTS_PER_DAY)")
+	flag.Int64Var(&cfg.QuotaTokens, "quota_tokens_per_day", cfg.QuotaTokens, "Reject runs of -user once its runs used this many tokens in the UTC day (0 disables; TUMIX_QUOTA_TOKENS_PER_DAY)")
+	flag.IntVar(&cfg.QuotaConcurrent, "quota_max_concurrent", cfg.QuotaConcurrent, "Reject runs of -user beyond this many at once, e.g. batch workers (0 disables; TUMIX_QUOTA_MAX_CONCURRENT)")
 	flag.IntVar(&cfg.BudgetTokens, "budget_tokens", cfg.BudgetTokens, "Optional per-round input token budget override (0 uses estimate)")
 	flag.IntVar(&cfg.BenchLocal, "bench_local", cfg.BenchLocal, "Run local synthetic benchmark for N iterations and exit")
-	flag.StringVar(&cfg.MetricsAddr, "metrics_addr", cmp.Or(os.Getenv("TUMIX_METRICS_ADDR"), cfg.MetricsAddr), "If set, serve /debug/vars and /healthz on this address (e.g. :9090)")
+	flag.StringVar(&cfg.MetricsAddr, "metrics_addr", cfg.MetricsAddr, "If set, serve /debug/vars, /metrics, /healthz, /readyz, and /version on this address (e.g. :9090)")
 	flag.Parse()
 
 	cfg.Prompt = strings.TrimSpace(strings.Join(flag.Args(), " "))
-	if cfg.Prompt == "" {
+	if cfg.Prompt == "" && !validateOnly && !cfg.Preflight {
 		return cfg, errors.New("prompt is required; pass text after flags")
 	}
 	if cfg.MaxPromptChars > 0 && len(cfg.Prompt) > cfg.MaxPromptChars {
 		return cfg, fmt.Errorf("prompt length %d exceeds max_prompt_chars %d", len(cfg.Prompt), cfg.MaxPromptChars)
 	}
+	if cfg.MaxAttachBytes < 0 {
+		return cfg, errors.New("max_attach_bytes cannot be negative")
+	}
+	attachments, err := loadAttachments(cfg.Attachments, cfg.MaxAttachBytes)
+	if err != nil {
+		return cfg, fmt.Errorf("attach: %w", err)
+	}
+	cfg.attachments = attachments
 	if cfg.MaxPromptTokens > 0 {
-		est := estimateTokensFromChars(len(cfg.Prompt))
+		est := estimateTokensFromChars(len(cfg.Prompt)) + attachmentTokens(cfg.attachments)
 		if est > cfg.MaxPromptTokens {
 			return cfg, fmt.Errorf("prompt token estimate %d exceeds max_prompt_tokens %d", est, cfg.MaxPromptTokens)
 		}
@@ -299,26 +687,37 @@ func parseConfig() (config, error) {
 		return cfg, fmt.Errorf("invalid backend %q; must be one of: gemini, openai, anthropic, xai", cfg.LLMBackend)
 	}
 
-	if cfg.APIKey == "" {
-		// Try to fetch from backend specific env vars if generic GOOGLE_API_KEY is not set
-		switch cfg.LLMBackend {
-		case "gemini":
-			cfg.APIKey = os.Getenv("GOOGLE_API_KEY")
-		case "openai":
-			cfg.APIKey = os.Getenv("OPENAI_API_KEY")
-		case "anthropic":
-			cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
-		case "xai":
-			cfg.APIKey = os.Getenv("XAI_API_KEY")
+	failoverTargets, err := parseFailover(cfg.Failover)
+	if err != nil {
+		return cfg, err
+	}
+	if err := checkAttachments(cfg.LLMBackend, cfg.attachments); err != nil {
+		return cfg, fmt.Errorf("attach: %w", err)
+	}
+	for _, target := range failoverTargets {
+		if err := checkAttachments(target.backend, cfg.attachments); err != nil {
+			return cfg, fmt.Errorf("attach: failover %w", err)
 		}
 	}
 
 	if cfg.APIKey == "" {
+		// Try to fetch from backend specific env vars if generic GOOGLE_API_KEY is not set
+		cfg.APIKey = backendAPIKey(cfg.LLMBackend)
+	}
+
+	if cfg.APIKey == "" && !validateOnly {
 		return cfg, errors.New("API key must be set (via -api_key or appropriate environment variable)")
 	}
 	if cfg.SessionID == "" {
 		cfg.SessionID = fmt.Sprintf("session-%d", time.Now().UnixNano())
 	}
+	// Both name files of the session and quota stores.
+	if err := storage.ValidateName(cfg.UserID); err != nil {
+		return cfg, fmt.Errorf("invalid user: %w", err)
+	}
+	if err := storage.ValidateName(cfg.SessionID); err != nil {
+		return cfg, fmt.Errorf("invalid session: %w", err)
+	}
 	if cfg.Temperature >= 0 && (cfg.Temperature < 0 || cfg.Temperature > 2) {
 		return cfg, errors.New("temperature must be between 0 and 2")
 	}
@@ -337,22 +736,154 @@ func parseConfig() (config, error) {
 	if cfg.MinRounds > cfg.MaxRounds {
 		return cfg, fmt.Errorf("min_rounds (%d) cannot exceed max_rounds (%d)", cfg.MinRounds, cfg.MaxRounds)
 	}
+	if err := checkAgents(&cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.RoundTimeout < 0 || cfg.RunTimeout < 0 {
+		return cfg, errors.New("round_timeout and run_timeout cannot be negative")
+	}
 	if cfg.MaxCostUSD < 0 {
 		return cfg, errors.New("max_cost_usd cannot be negative")
 	}
-	if cfg.AutoAgents < 0 {
-		return cfg, errors.New("auto_agents cannot be negative")
-	}
 	if cfg.BudgetTokens < 0 {
 		return cfg, errors.New("budget_tokens cannot be negative")
 	}
+	if cfg.QuotaRequests < 0 || cfg.QuotaTokens < 0 || cfg.QuotaConcurrent < 0 {
+		return cfg, errors.New("quota limits cannot be negative")
+	}
+	if cfg.XAIRPS < 0 || cfg.XAIBurst < 0 {
+		return cfg, errors.New("xai_rps and xai_burst cannot be negative")
+	}
+	if cfg.XAIRPS > 0 {
+		cfg.xaiLimiter = xai.NewRateLimiter(cfg.XAIRPS, cfg.XAIBurst)
+	}
+	if cfg.MaxSubTasks < 0 {
+		return cfg, errors.New("max_subtasks cannot be negative")
+	}
+	if cfg.CompressTokens < 0 {
+		return cfg, errors.New("compress_threshold_tokens cannot be negative")
+	}
+	switch cfg.SummarizeAnswers {
+	case "", summarizeTruncate, summarizeCluster, summarizeModel:
+		// ok
+	default:
+		return cfg, fmt.Errorf("invalid summarize_answers %q; must be one of: truncate, cluster, model", cfg.SummarizeAnswers)
+	}
+	if cfg.SummaryTokens < 0 {
+		return cfg, errors.New("summary_answer_tokens cannot be negative")
+	}
+	protocol, ok := tumixagent.AnswerProtocolByName(cfg.AnswerProtocol)
+	if !ok {
+		return cfg, fmt.Errorf("invalid answer_protocol %q; must be one of: delimited, json", cfg.AnswerProtocol)
+	}
+	cfg.answerProtocol = protocol
+	switch tumixagent.StateMergePolicy(cfg.StateMerge) {
+	case "", tumixagent.StateMergeUnique, tumixagent.StateMergeLast, tumixagent.StateMergeNone:
+		// ok
+	default:
+		return cfg, fmt.Errorf("invalid state_merge %q; must be one of: unique, last, none", cfg.StateMerge)
+	}
+	switch sessionfs.SyncPolicy(cfg.SessionSync) {
+	case "", sessionfs.SyncAlways, sessionfs.SyncCompaction, sessionfs.SyncNever:
+		// ok
+	default:
+		return cfg, fmt.Errorf("invalid session_sync %q; must be one of: always, compaction, never", cfg.SessionSync)
+	}
+	switch cfg.Observability {
+	case "", "none":
+	case "openinference":
+		if cfg.ObservabilityURL == "" {
+			return cfg, errors.New("observability_endpoint is required with -observability=openinference")
+		}
+	default:
+		return cfg, fmt.Errorf("invalid observability %q; must be one of: openinference, none", cfg.Observability)
+	}
+	if cfg.SessionMaxAge < 0 || cfg.SessionMaxUser < 0 || cfg.SessionMaxBytes < 0 || cfg.SessionGCEvery < 0 {
+		return cfg, errors.New("session_max_age, session_max_per_user, session_max_bytes, and session_gc_interval cannot be negative")
+	}
+	if cfg.BatchMaxRetries < 0 {
+		return cfg, errors.New("batch_max_retries cannot be negative")
+	}
 	if cfg.Concurrency < 1 {
 		cfg.Concurrency = 1
 	}
+	labels, err := runmeta.ParseLabels(cfg.RunLabels)
+	if err != nil {
+		return cfg, fmt.Errorf("run_labels: %w", err)
+	}
+	cfg.runLabels = labels
+	if cfg.SystemPromptFile != "" {
+		if cfg.SystemPrompt != "" {
+			return cfg, errors.New("system_prompt and system_prompt_file are mutually exclusive")
+		}
+		data, err := os.ReadFile(cfg.SystemPromptFile)
+		if err != nil {
+			return cfg, fmt.Errorf("read system_prompt_file: %w", err)
+		}
+		cfg.SystemPrompt = string(data)
+	}
+	if cfg.Workflow != "" {
+		if cfg.Hierarchical {
+			return cfg, errors.New("workflow and hierarchical are mutually exclusive")
+		}
+		if cfg.Verify {
+			return cfg, errors.New("workflow and verify are mutually exclusive")
+		}
+		if cfg.RoundTimeout > 0 || cfg.RunTimeout > 0 {
+			return cfg, errors.New("workflow does not support round_timeout and run_timeout")
+		}
+		spec, err := workflow.Load(cfg.Workflow)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.workflow = spec
+	}
 
 	return cfg, nil
 }
 
+// Values of -triage.
+const (
+	triageHeuristic = "heuristic"
+	triageModel     = "model"
+)
+
+// Values of -summarize_answers.
+const (
+	summarizeTruncate = "truncate"
+	summarizeCluster  = "cluster"
+	summarizeModel    = "model"
+)
+
+// checkAgents validates the agent mixture settings of cfg, which a config reload may change.
+func checkAgents(cfg *config) error {
+	switch tumixagent.Mode(cfg.Mode) {
+	case tumixagent.ModeTumix, tumixagent.ModeDebate:
+		// ok
+	default:
+		return fmt.Errorf("invalid mode %q; must be one of: tumix, debate", cfg.Mode)
+	}
+	if cfg.Verify && tumixagent.Mode(cfg.Mode) != tumixagent.ModeTumix {
+		return errors.New("verify requires mode tumix")
+	}
+	if cfg.AutoAgents < 0 {
+		return errors.New("auto_agents cannot be negative")
+	}
+	switch cfg.Triage {
+	case "", triageHeuristic, triageModel:
+		// ok
+	default:
+		return fmt.Errorf("invalid triage %q; must be one of: heuristic, model", cfg.Triage)
+	}
+	if cfg.TriageCandidates < 1 {
+		return errors.New("triage_candidates must be at least 1")
+	}
+	if cfg.SamplesPerAgent == 0 {
+		cfg.SamplesPerAgent = 1
+	}
+	return nil
+}
+
 func newHTTPClient(traceEnabled bool) *http.Client {
 	return &http.Client{
 		Transport: httptelemetry.NewTransportWithTrace(nil, traceEnabled),
@@ -362,9 +893,26 @@ func newHTTPClient(traceEnabled bool) *http.Client {
 type countTokensFunc func(ctx context.Context, model string, contents []*genai.Content, config *genai.CountTokensConfig) (*genai.CountTokensResponse, error)
 
 func enforcePromptTokens(ctx context.Context, cfg *config, httpClient *http.Client) error {
-	if cfg.MaxPromptTokens <= 0 {
+	if limit, _ := promptTokenLimit(cfg); limit <= 0 {
 		return nil
 	}
+
+	// Backends with a provider-neutral gollm.Model count tokens with their own tokenizer.
+	switch cfg.LLMBackend {
+	case gollm.ProviderGemini, gollm.ProviderOpenAI, gollm.ProviderXAI:
+		m, err := gollm.NewModel(ctx, cfg.LLMBackend, cfg.APIKey, cfg.ModelName, gollm.WithHTTPClient(httpClient))
+		if err != nil {
+			return fmt.Errorf("init token client: %w", err)
+		}
+		return enforcePromptTokensWithCounter(ctx, cfg, func(ctx context.Context, _ string, contents []*genai.Content, _ *genai.CountTokensConfig) (*genai.CountTokensResponse, error) {
+			tokens, err := m.CountTokens(ctx, contents)
+			if err != nil {
+				return nil, err
+			}
+			return &genai.CountTokensResponse{TotalTokens: int32(min(tokens, math.MaxInt32))}, nil //nolint:gosec // clamped above
+		})
+	}
+
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey:     cfg.APIKey,
 		HTTPClient: httpClient,
@@ -380,11 +928,22 @@ func enforcePromptTokens(ctx context.Context, cfg *config, httpClient *http.Clie
 	})
 }
 
+// promptTokenLimit returns the prompt token limit of the run and the setting it comes from: the smaller of
+// -max_prompt_tokens and the model context window known from the model catalog.
+func promptTokenLimit(cfg *config) (limit int, source string) {
+	limit, source = cfg.MaxPromptTokens, "max_prompt_tokens"
+	if w := cfg.contextWindow; w > 0 && (limit <= 0 || w < limit) {
+		limit, source = w, "the model context window"
+	}
+	return limit, source
+}
+
 func enforcePromptTokensWithCounter(ctx context.Context, cfg *config, counter countTokensFunc) error {
-	if cfg.MaxPromptTokens <= 0 {
+	limit, source := promptTokenLimit(cfg)
+	if limit <= 0 {
 		return nil
 	}
-	contents := []*genai.Content{genai.NewContentFromText(cfg.Prompt, genai.RoleUser)}
+	contents := []*genai.Content{userContent(cfg)}
 	resp, err := counter(ctx, cfg.ModelName, contents, nil)
 	if err != nil {
 		return fmt.Errorf("count tokens: %w", err)
@@ -392,49 +951,219 @@ func enforcePromptTokensWithCounter(ctx context.Context, cfg *config, counter co
 	if resp == nil {
 		return errors.New("count tokens: empty response")
 	}
-	if tokens := int(resp.TotalTokens); tokens > cfg.MaxPromptTokens {
-		return fmt.Errorf("prompt tokens %d exceed max_prompt_tokens %d", tokens, cfg.MaxPromptTokens)
+	if tokens := int(resp.TotalTokens); tokens > limit {
+		return fmt.Errorf("prompt tokens %d exceed %s %d", tokens, source, limit)
 	}
 	return nil
 }
 
-func buildModel(ctx context.Context, cfg *config, httpClient *http.Client) (model.LLM, error) {
-	switch cfg.LLMBackend {
+// modelCatalogMaxAge is how long a cached provider model list is used before it is fetched again.
+const modelCatalogMaxAge = 24 * time.Hour
+
+// loadModelCatalog returns the model catalog of the backends of the run, failover targets included, refreshing the
+// stale providers and caching the result under the user cache directory.
+//
+// Refresh failures are returned along with the catalog, which then keeps the previously cached entries.
+func loadModelCatalog(ctx context.Context, cfg *config, httpClient *http.Client) (*catalog.Catalog, error) {
+	path := ""
+	if dir, err := os.UserCacheDir(); err == nil {
+		path = filepath.Join(dir, "tumix", "models.json")
+	}
+	cat := &catalog.Catalog{}
+	if path != "" {
+		cached, err := catalog.Load(path)
+		if err != nil {
+			log.Warn(ctx, "model catalog cache ignored", "error", err)
+		} else {
+			cat = cached
+		}
+	}
+
+	targets, err := parseFailover(cfg.Failover)
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]string{cfg.LLMBackend: cfg.APIKey}
+	for _, target := range targets {
+		if _, ok := keys[target.backend]; !ok {
+			keys[target.backend] = backendAPIKey(target.backend)
+		}
+	}
+
+	var sources []catalog.Source
+	for backend, apiKey := range keys {
+		switch backend {
+		case "gemini":
+			client, err := genai.NewClient(ctx, &genai.ClientConfig{
+				APIKey:     apiKey,
+				HTTPClient: httpClient,
+				HTTPOptions: genai.HTTPOptions{Headers: http.Header{
+					"User-Agent": []string{version.UserAgent("genai")},
+				}},
+			})
+			if err != nil {
+				return cat, fmt.Errorf("model catalog: %w", err)
+			}
+			sources = append(sources, catalog.NewGeminiSource(client))
+		case "openai":
+			client := openai.NewClient(option.WithAPIKey(apiKey), option.WithHTTPClient(httpClient))
+			sources = append(sources, catalog.NewOpenAISource(&client))
+		case "xai":
+			var opts []xai.ClientOption
+			if cfg.xaiLimiter != nil {
+				opts = append(opts, xai.WithRateLimiter(cfg.xaiLimiter))
+			}
+			client, err := xai.NewClient(apiKey, opts...)
+			if err != nil {
+				return cat, fmt.Errorf("model catalog: %w", err)
+			}
+			defer client.Close()
+			sources = append(sources, catalog.NewXAISource(client.Models))
+		}
+	}
+
+	changed, err := cat.Refresh(ctx, modelCatalogMaxAge, sources...)
+	if changed && path != "" {
+		if err := cat.Save(path); err != nil {
+			log.Warn(ctx, "model catalog cache not saved", "error", err)
+		}
+	}
+	return cat, err
+}
+
+// failoverTarget is a secondary backend used by the failover wrapper.
+type failoverTarget struct {
+	backend   string
+	modelName string
+}
+
+// parseFailover parses a comma-separated backend:model list.
+func parseFailover(spec string) ([]failoverTarget, error) {
+	var targets []failoverTarget
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		backend, modelName, ok := strings.Cut(entry, ":")
+		if !ok || modelName == "" {
+			return nil, fmt.Errorf("invalid failover entry %q; want backend:model", entry)
+		}
+		switch backend {
+		case "gemini", "openai", "anthropic", "xai":
+			// ok
+		default:
+			return nil, fmt.Errorf("invalid failover backend %q; must be one of: gemini, openai, anthropic, xai", backend)
+		}
+		targets = append(targets, failoverTarget{backend: backend, modelName: modelName})
+	}
+	return targets, nil
+}
+
+// backendAPIKey returns the API key from the backend specific environment variable.
+func backendAPIKey(backend string) string {
+	if env := backendAPIKeyEnv(backend); env != "" {
+		return os.Getenv(env)
+	}
+	return ""
+}
+
+// backendAPIKeyEnv returns the environment variable holding the API key of the model backend.
+func backendAPIKeyEnv(backend string) string {
+	switch backend {
+	case "gemini":
+		return "GOOGLE_API_KEY"
+	case "openai":
+		return "OPENAI_API_KEY"
+	case "anthropic":
+		return "ANTHROPIC_API_KEY"
+	case "xai":
+		return "XAI_API_KEY"
+	default:
+		return ""
+	}
+}
+
+// judgeModelName returns the judge model name, defaulting to the candidate model.
+func judgeModelName(cfg *config) string {
+	return cmp.Or(cfg.JudgeModel, cfg.ModelName)
+}
+
+func buildModel(ctx context.Context, cfg *config, modelName string, httpClient *http.Client) (model.LLM, error) {
+	llm, err := newBackendModel(ctx, cfg, cfg.LLMBackend, modelName, cfg.APIKey, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := parseFailover(cfg.Failover)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return llm, nil
+	}
+
+	backends := []model.LLM{llm}
+	for _, target := range targets {
+		fallback, err := newBackendModel(ctx, cfg, target.backend, target.modelName, backendAPIKey(target.backend), httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failover backend %s: %w", target.backend, err)
+		}
+		backends = append(backends, fallback)
+	}
+
+	llm, err = failover.New(backends)
+	if err != nil {
+		return nil, fmt.Errorf("create failover model: %w", err)
+	}
+	return llm, nil
+}
+
+func newBackendModel(ctx context.Context, cfg *config, backend, modelName, apiKey string, httpClient *http.Client) (model.LLM, error) {
+	switch backend {
 	case "gemini":
 		clientConfig := &genai.ClientConfig{
-			APIKey:     cfg.APIKey,
+			APIKey:     apiKey,
 			HTTPClient: httpClient,
 		}
 
-		llm, err := gemini.NewModel(ctx, cfg.ModelName, clientConfig)
+		llm, err := gemini.NewModel(ctx, modelName, clientConfig)
 		if err != nil {
-			return nil, fmt.Errorf("create model %s: %w", cfg.ModelName, err)
+			return nil, fmt.Errorf("create model %s: %w", modelName, err)
 		}
-		return llm, nil
+		return gollm.WithCapabilities(llm, gollm.ProviderCapabilities(gollm.ProviderGemini, modelName)), nil
 
 	case "openai":
-		llm, err := gollm.NewOpenAILLM(ctx, cfg.APIKey, cfg.ModelName, nil)
+		llm, err := gollm.NewOpenAILLM(ctx, apiKey, modelName, nil)
 		if err != nil {
-			return nil, fmt.Errorf("create model %s: %w", cfg.ModelName, err)
+			return nil, fmt.Errorf("create model %s: %w", modelName, err)
 		}
 		return llm, nil
 
 	case "anthropic":
-		llm, err := gollm.NewAnthropicLLM(ctx, cfg.APIKey, cfg.ModelName, nil)
+		llm, err := gollm.NewAnthropicLLM(ctx, apiKey, modelName, nil)
 		if err != nil {
-			return nil, fmt.Errorf("create model %s: %w", cfg.ModelName, err)
+			return nil, fmt.Errorf("create model %s: %w", modelName, err)
 		}
 		return llm, nil
 
 	case "xai":
-		llm, err := gollm.NewXAILLM(ctx, cfg.APIKey, cfg.ModelName, nil)
+		var opts []xai.ClientOption
+		if cfg.xaiLimiter != nil {
+			opts = append(opts, xai.WithRateLimiter(cfg.xaiLimiter))
+		}
+		opts = append(opts, xai.WithDialOptions(
+			grpc.WithChainUnaryInterceptor(runmeta.UnaryClientInterceptor),
+			grpc.WithChainStreamInterceptor(runmeta.StreamClientInterceptor),
+		))
+		llm, err := gollm.NewXAILLM(ctx, apiKey, modelName, nil, opts...)
 		if err != nil {
-			return nil, fmt.Errorf("create model %s: %w", cfg.ModelName, err)
+			return nil, fmt.Errorf("create model %s: %w", modelName, err)
 		}
 		return llm, nil
 
 	default:
-		return nil, fmt.Errorf("unsupported backend: %s", cfg.LLMBackend)
+		return nil, fmt.Errorf("unsupported backend: %s", backend)
 	}
 }
 
@@ -460,21 +1189,67 @@ func buildGenConfig(cfg *config) *genai.GenerateContentConfig {
 	if cfg.MaxTokens > 0 {
 		c.MaxOutputTokens = int32(cfg.MaxTokens) //nolint:gosec // TODO(zchee): fix nolint
 	}
-	if cfg.Seed > 0 {
+	if cfg.Seed != 0 {
 		val := int32(cfg.Seed) //nolint:gosec // TODO(zchee): fix nolint
 		c.Seed = &val
 	}
 	return c
 }
 
-func buildTumixLoader(llm model.LLM, genCfg *genai.GenerateContentConfig, minRounds, maxRounds uint, autoAgents int) (adkagent.Loader, int, error) {
-	builders := []func(model.LLM, *genai.GenerateContentConfig) (adkagent.Agent, error){
+func openAuditLog(cfg *config) (*audit.Logger, error) {
+	patterns := audit.DefaultPatterns
+	if cfg.AuditPatterns != "" {
+		var err error
+		patterns, err = audit.LoadPatterns(cfg.AuditPatterns)
+		if err != nil {
+			return nil, fmt.Errorf("audit: %w", err)
+		}
+	}
+	redactor, err := audit.NewRedactor(patterns, strings.Split(cfg.AuditRedactKeys, ","))
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+
+	opts := []audit.Option{audit.WithRedactor(redactor)}
+	if key := os.Getenv("TUMIX_AUDIT_KEY"); key != "" {
+		opts = append(opts, audit.WithKey([]byte(key)))
+	}
+	l, err := audit.Open(cfg.AuditDir, cfg.SessionID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	return l, nil
+}
+
+func buildMCPToolset(ctx context.Context, path string) (*mcp.Toolset, error) {
+	mcpCfg, err := mcp.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := mcp.NewToolset(ctx, mcpCfg)
+	if err != nil {
+		return nil, fmt.Errorf("mcp toolset: %w", err)
+	}
+	return ts, nil
+}
+
+// candidateBuilders returns the constructors of the built-in candidate agents suited to a backend with caps.
+//
+// Agents the backend cannot support are dropped or reconfigured up front rather than failing at request time: the
+// search and code agents use the native tools when the backend runs them, and the vision agent only joins questions
+// that carry images or PDFs when the model accepts image input.
+func candidateBuilders(cfg *config, caps gollm.Capabilities) []func(model.LLM, *genai.GenerateContentConfig, *tumixagent.AnswerProtocol) (adkagent.Agent, error) {
+	search, code, codePlus := tumixagent.NewSearchAgent, tumixagent.NewCodeAgent, tumixagent.NewCodePlusAgent
+	if caps.NativeTools {
+		search, code, codePlus = tumixagent.NewNativeSearchAgent, tumixagent.NewNativeCodeAgent, tumixagent.NewNativeCodePlusAgent
+	}
+	builders := []func(model.LLM, *genai.GenerateContentConfig, *tumixagent.AnswerProtocol) (adkagent.Agent, error){
 		tumixagent.NewBaseAgent,
 		tumixagent.NewCoTAgent,
 		tumixagent.NewCoTCodeAgent,
-		tumixagent.NewSearchAgent,
-		tumixagent.NewCodeAgent,
-		tumixagent.NewCodePlusAgent,
+		search,
+		code,
+		codePlus,
 		tumixagent.NewDualToolGSAgent,
 		tumixagent.NewDualToolLLMAgent,
 		tumixagent.NewDualToolComAgent,
@@ -485,105 +1260,360 @@ func buildTumixLoader(llm model.LLM, genCfg *genai.GenerateContentConfig, minRou
 		// tumixagent.NewGuidedPlusLLMAgent,
 		// tumixagent.NewGuidedPlusComAgent,
 	}
+	if caps.Vision && tumixagent.HasVisualContent(userContent(cfg)) {
+		builders = append(builders, tumixagent.NewVisionAgent)
+	}
+	return builders
+}
+
+// modelCapabilities returns the capabilities reported by llm, falling back to those of the configured backend model.
+func modelCapabilities(llm model.LLM, cfg *config) gollm.Capabilities {
+	if caps, ok := gollm.CapabilitiesOf(llm); ok {
+		return caps
+	}
+	return gollm.ProviderCapabilities(cfg.LLMBackend, cfg.ModelName)
+}
+
+func buildTumixLoader(llm, judgeLLM model.LLM, genCfg *genai.GenerateContentConfig, cfg *config, toolsets ...tool.Toolset) (adkagent.Loader, int, error) {
+	if cfg.workflow != nil {
+		loader, err := cfg.workflow.Build(workflow.Config{
+			Model:                 llm,
+			JudgeModel:            judgeLLM,
+			GenerateContentConfig: genCfg,
+			AnswerProtocol:        cfg.answerProtocol,
+		})
+		return loader, len(cfg.workflow.Nodes), err
+	}
+
+	caps := modelCapabilities(llm, cfg)
+	builders := candidateBuilders(cfg, caps)
 
-	candidates := make([]adkagent.Agent, 0, len(builders)+autoAgents)
+	// Only the candidates follow the system prompt; the Judge and the planner keep genCfg.
+	candidateGenCfg := tumixagent.WithSystemPrompt(genCfg, cfg.SystemPrompt)
+	if cfg.Logprobs {
+		candidateGenCfg = tumixagent.WithResponseLogprobs(candidateGenCfg)
+	}
+	// Every agent built below asks for the final answer in the protocol, and the run parses it.
+	candidates := make([]adkagent.Agent, 0, len(builders)+cfg.AutoAgents)
 	for i, builder := range builders {
-		a, err := builder(llm, genCfg)
+		a, err := builder(llm, candidateGenCfg, cfg.answerProtocol)
 		if err != nil {
 			return nil, 0, fmt.Errorf("build candidate %d: %w", i+1, err)
 		}
 		candidates = append(candidates, a)
 	}
 
-	if autoAgents > 0 {
-		autoAgents, err := tumixagent.NewAutoAgents(llm, genCfg, autoAgents)
+	// The toolset agent only works through function calling.
+	if len(toolsets) > 0 && caps.Tools {
+		a, err := tumixagent.NewToolsetAgent(llm, candidateGenCfg, cfg.answerProtocol, toolsets...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("build toolset agent: %w", err)
+		}
+		candidates = append(candidates, a)
+	}
+
+	remoteAgents, err := buildA2AAgents(cfg.A2AAgents, cfg.answerProtocol)
+	if err != nil {
+		return nil, 0, err
+	}
+	candidates = append(candidates, remoteAgents...)
+
+	if cfg.AutoAgents > 0 {
+		autoAgents, err := tumixagent.NewAutoAgents(llm, candidateGenCfg, cfg.answerProtocol, cfg.AutoAgents)
 		if err != nil {
 			return nil, 0, fmt.Errorf("build auto agents: %w", err)
 		}
 		candidates = append(candidates, autoAgents...)
 	}
 
-	judge, err := tumixagent.NewJudgeAgent(llm, genCfg)
-	if err != nil {
-		return nil, 0, fmt.Errorf("build judge agent: %w", err)
+	if cfg.Hierarchical {
+		loader, err := tumixagent.NewHierarchicalTumixAgent(tumixagent.HierarchicalConfig{
+			Sub: tumixagent.TumixConfig{
+				Candidates:                 candidates,
+				JudgeModel:                 judgeLLM,
+				JudgeGenerateContentConfig: genCfg,
+				MaxRounds:                  cfg.SubTaskRounds,
+				MinRounds:                  1,
+				SamplesPerAgent:            cfg.SamplesPerAgent,
+				AnswerProtocol:             cfg.answerProtocol,
+				Mode:                       tumixagent.Mode(cfg.Mode),
+				RoundTimeout:               cfg.RoundTimeout,
+				RunTimeout:                 cfg.RunTimeout,
+				Seed:                       cfg.Seed,
+			},
+			Model:                 judgeLLM,
+			GenerateContentConfig: genCfg,
+			MaxSubTasks:           cfg.MaxSubTasks,
+		})
+		return loader, len(candidates), err
+	}
+
+	var compression *tumixagent.PromptCompression
+	if cfg.CompressPrompt {
+		compression = &tumixagent.PromptCompression{
+			Model:                 llm,
+			GenerateContentConfig: genCfg,
+			ThresholdTokens:       cfg.CompressTokens,
+		}
+	}
+
+	var summary *tumixagent.AnswerSummary
+	switch cfg.SummarizeAnswers {
+	case summarizeTruncate:
+		summary = &tumixagent.AnswerSummary{MaxAnswerTokens: cfg.SummaryTokens}
+	case summarizeCluster:
+		summary = &tumixagent.AnswerSummary{Cluster: true, MaxAnswerTokens: cfg.SummaryTokens}
+	case summarizeModel:
+		summary = &tumixagent.AnswerSummary{Cluster: true, MaxAnswerTokens: cfg.SummaryTokens, Model: llm, GenerateContentConfig: genCfg}
+	}
+
+	var triage *tumixagent.Triage
+	switch cfg.Triage {
+	case triageHeuristic:
+		triage = &tumixagent.Triage{EasyCandidates: cfg.TriageCandidates}
+	case triageModel:
+		triage = &tumixagent.Triage{Model: llm, GenerateContentConfig: genCfg, EasyCandidates: cfg.TriageCandidates}
+	}
+
+	var verifier adkagent.Agent
+	if cfg.Verify {
+		verifier, err = tumixagent.NewVerifierAgent(judgeLLM, genCfg, modelCapabilities(judgeLLM, cfg).NativeTools)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
 
 	loader, err := tumixagent.NewTumixAgentWithConfig(tumixagent.TumixConfig{
-		Candidates: candidates,
-		Judge:      judge,
-		MaxRounds:  maxRounds,
-		MinRounds:  minRounds,
+		Candidates:                 candidates,
+		JudgeModel:                 judgeLLM,
+		JudgeGenerateContentConfig: genCfg,
+		MaxRounds:                  cfg.MaxRounds,
+		MinRounds:                  cfg.MinRounds,
+		SamplesPerAgent:            cfg.SamplesPerAgent,
+		PromptCompression:          compression,
+		AnswerSummary:              summary,
+		AnswerProtocol:             cfg.answerProtocol,
+		ReformatAnswers:            cfg.ReformatAnswers,
+		StateMerge:                 tumixagent.StateMergePolicy(cfg.StateMerge),
+		Mode:                       tumixagent.Mode(cfg.Mode),
+		Verifier:                   verifier,
+		Triage:                     triage,
+		RoundTimeout:               cfg.RoundTimeout,
+		RunTimeout:                 cfg.RunTimeout,
+		Seed:                       cfg.Seed,
 	})
 	return loader, len(candidates), err
 }
 
+// buildA2AAgents builds a remote candidate agent for every "url" or "url#skill" entry of spec, asking for the final
+// answer in protocol p.
+func buildA2AAgents(spec string, p *tumixagent.AnswerProtocol) ([]adkagent.Agent, error) {
+	var agents []adkagent.Agent
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		url, skill, _ := strings.Cut(entry, "#")
+		a, err := tumixagent.NewA2ARemoteAgent(url, skill, p)
+		if err != nil {
+			return nil, fmt.Errorf("build A2A agent %q: %w", entry, err)
+		}
+		agents = append(agents, a)
+	}
+	return agents, nil
+}
+
 func runOnce(ctx context.Context, cfg *config, loader adkagent.Loader) error {
-	sessionService := session.InMemoryService()
-	if cfg.SessionDir != "" {
-		svc, err := sessionfs.Service(cfg.SessionDir)
+	collector := newUsageCollector(cfg)
+	if cfg.quota != nil {
+		release, err := cfg.quota.Acquire(ctx, cfg.UserID)
 		if err != nil {
-			return fmt.Errorf("init session store: %w", err)
+			return err
+		}
+		defer func() {
+			if rerr := release(context.WithoutCancel(ctx), collector.Totals().Tokens()); rerr != nil {
+				log.Warn(ctx, "record quota usage failed", "error", rerr)
+			}
+		}()
+	}
+
+	sessionService := cfg.sessions
+	if sessionService == nil {
+		var err error
+		if sessionService, err = openSessionStore(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
+	var auditLog *audit.Logger
+	if cfg.AuditDir != "" {
+		var err error
+		auditLog, err = openAuditLog(cfg)
+		if err != nil {
+			return err
+		}
+		defer auditLog.Close()
+		if err := auditLog.Log(audit.KindRunStart, "", "", map[string]any{
+			"prompt":      cfg.Prompt,
+			"attachments": cfg.Attachments,
+			"backend":     cfg.LLMBackend,
+			"model":       cfg.ModelName,
+			"judge_model": judgeModelName(cfg),
+			"max_rounds":  cfg.MaxRounds,
+		}); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+	}
+
+	var observed *openinference.Recorder
+	if cfg.observer != nil {
+		observed = openinference.NewRecorder(openinference.Run{
+			Prompt:     cfg.Prompt,
+			UserID:     cfg.UserID,
+			SessionID:  cfg.SessionID,
+			Model:      cfg.ModelName,
+			JudgeModel: judgeModelName(cfg),
+			Evaluators: []string{tumixagent.JudgeAgentName},
+		})
+	}
+
+	runCfg := adkagent.RunConfig{}
+	stream := &partialPrinter{w: os.Stdout, authors: finalAnswerAuthors(loader.RootAgent().Name())}
+	if cfg.Stream && !cfg.OutputJSON {
+		runCfg.StreamingMode = adkagent.StreamingModeSSE
+	}
+	var progress tumixrun.ProgressReporter
+	if cfg.Progress && !cfg.OutputJSON {
+		progress = &progressBar{w: os.Stderr, width: 20}
+	}
+	res, err := tumixrun.Run(ctx, tumixrun.Request{
+		Agent:          loader.RootAgent(),
+		AppName:        cfg.AppName,
+		UserID:         cfg.UserID,
+		SessionID:      cfg.SessionID,
+		Labels:         cfg.runLabels,
+		Prompt:         cfg.Prompt,
+		Parts:          cfg.attachments,
+		SessionService: sessionService,
+		RunConfig:      runCfg,
+		InterruptGrace: interruptGrace,
+		Progress:       progress,
+		Usage:          collector,
+		OnEvent: func(event *session.Event) error {
+			if event != nil && event.Partial {
+				if runCfg.StreamingMode == adkagent.StreamingModeSSE {
+					stream.print(event)
+				}
+				return nil
+			}
+			stream.flush()
+			if !cfg.OutputJSON {
+				logEvent(ctx, event)
+			}
+			if auditLog != nil {
+				if err := auditLog.LogEvent(event); err != nil {
+					return fmt.Errorf("audit: %w", err)
+				}
+			}
+			if observed != nil {
+				observed.Record(event)
+			}
+			recordPanics(ctx, event)
+			return nil
+		},
+	})
+	totals := collector.Totals()
+	if observed != nil {
+		var output string
+		if res != nil {
+			output = res.Text
 		}
-		sessionService = svc
-	} else if dbPath := os.Getenv("TUMIX_SESSION_SQLITE"); dbPath != "" {
-		svc, err := sessiondb.Service(ctx, dbPath)
-		if err != nil {
-			return fmt.Errorf("init sqlite store: %w", err)
+		exportRun(ctx, cfg, observed.Finish(output, err))
+	}
+	if err != nil {
+		stream.flush()
+		if auditLog != nil {
+			_ = auditLog.Log(audit.KindRunEnd, "", "", map[string]any{"error": err.Error()})
 		}
-		sessionService = svc
+		return err
 	}
-	if _, err := sessionService.Create(ctx, &session.CreateRequest{
-		AppName:   cfg.AppName,
-		UserID:    cfg.UserID,
-		SessionID: cfg.SessionID,
-	}); err != nil {
-		return fmt.Errorf("create session: %w", err)
+	for _, reason := range res.Timeouts {
+		log.Warn(ctx, "round cut short by deadline", "reason", reason)
+	}
+	logAnswerChanges(ctx, res.Rounds)
+	for _, c := range res.FormatCompliance {
+		log.Info(ctx, "answer format", "agent", c.Agent, "answers", c.Answers, "conforming", c.Conforming, "repaired", c.Repaired,
+			"missing", c.Missing, "reprompted", c.Reprompted, "fixed", c.Fixed, "rate", c.Rate())
+	}
+	if res.Triage != nil {
+		log.Info(ctx, "triage", "difficulty", res.Triage.Difficulty, "reason", res.Triage.Reason, "candidates", res.Triage.Candidates,
+			"total_candidates", res.Triage.TotalCandidates, "saved_calls", res.Triage.SavedCalls, "saved_cost_usd", triageSavings(res.Triage, totals))
+	}
+	estimateAndWarn(ctx, cfg, totals)
+
+	if auditLog != nil {
+		if err := auditLog.Log(audit.KindRunEnd, res.Author, "", map[string]any{
+			"text":              res.Text,
+			"citations":         res.Citations,
+			"provisional":       res.Provisional,
+			"rounds":            res.Rounds,
+			"format_compliance": res.FormatCompliance,
+			"judge_rationale":   res.JudgeRationale,
+			"input_tokens":      totals.InputTokens,
+			"output_tokens":     totals.OutputTokens,
+			"cost_usd":          totals.CostUSD,
+		}); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
 	}
 
-	r, err := runner.New(runner.Config{
-		AppName:        cfg.AppName,
-		Agent:          loader.RootAgent(),
-		SessionService: sessionService,
-	})
-	if err != nil {
-		return fmt.Errorf("runner init: %w", err)
+	if cfg.onResult != nil {
+		cfg.onResult(res)
 	}
 
-	content := genai.NewContentFromText(cfg.Prompt, genai.RoleUser)
-	var finalAuthor, finalText string
-	var totalIn, totalOut int64
-	for event, err := range r.Run(ctx, cfg.UserID, cfg.SessionID, content, adkagent.RunConfig{}) {
-		if err != nil {
-			return fmt.Errorf("agent run: %w", err)
-		}
-		if !cfg.OutputJSON {
-			logEvent(ctx, event)
-		}
-		if text := firstText(event); text != "" {
-			finalText = text
-			finalAuthor = event.Author
+	if res.Provisional && !cfg.OutputJSON {
+		fmt.Fprintf(os.Stderr, "PROVISIONAL ANSWER (interrupted before the rounds finished): %s\n", res.Text)
+	}
+	if cfg.Explain && !cfg.OutputJSON {
+		if err := writeExplanation(os.Stdout, res); err != nil {
+			return fmt.Errorf("write explanation: %w", err)
 		}
-		inTok, outTok := recordUsage(ctx, event)
-		totalIn += inTok
-		totalOut += outTok
 	}
-	estimateAndWarn(ctx, cfg, int(totalIn), int(totalOut))
 
 	if cfg.OutputJSON {
 		out := map[string]any{
-			"session_id":    cfg.SessionID,
-			"author":        finalAuthor,
-			"text":          finalText,
-			"input_tokens":  totalIn,
-			"output_tokens": totalOut,
+			"session_id":          res.SessionID,
+			"author":              res.Author,
+			"text":                res.Text,
+			"answer":              res.Answer,
+			"confidence":          res.Confidence,
+			"rounds":              res.Rounds,
+			"triage":              res.Triage,
+			"format_compliance":   res.FormatCompliance,
+			"judge_rationale":     res.JudgeRationale,
+			"citations":           res.Citations,
+			"candidate_scores":    res.CandidateScores,
+			"timeouts":            res.Timeouts,
+			"provisional":         res.Provisional,
+			"served_by":           res.ServedBy,
+			"requests":            totals.Requests,
+			"input_tokens":        totals.InputTokens,
+			"output_tokens":       totals.OutputTokens,
+			"judge_input_tokens":  totals.JudgeInputTokens,
+			"judge_output_tokens": totals.JudgeOutputTokens,
+			"cost_usd":            totals.CostUSD,
+			"judge_cost_usd":      totals.JudgeCostUSD,
 			"config": map[string]any{
-				"model":       cfg.ModelName,
-				"max_rounds":  cfg.MaxRounds,
-				"temperature": cfg.Temperature,
-				"top_p":       cfg.TopP,
-				"top_k":       cfg.TopK,
-				"max_tokens":  cfg.MaxTokens,
-				"seed":        cfg.Seed,
+				"model":             cfg.ModelName,
+				"judge_model":       judgeModelName(cfg),
+				"max_rounds":        cfg.MaxRounds,
+				"samples_per_agent": cfg.SamplesPerAgent,
+				"temperature":       cfg.Temperature,
+				"top_p":             cfg.TopP,
+				"top_k":             cfg.TopK,
+				"max_tokens":        cfg.MaxTokens,
+				"seed":              cfg.Seed,
 			},
 		}
 		enc := jsontext.NewEncoder(os.Stdout)
@@ -595,13 +1625,77 @@ func runOnce(ctx context.Context, cfg *config, loader adkagent.Loader) error {
 	return nil
 }
 
-func runBatch(ctx context.Context, cfg *config, loader adkagent.Loader) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+// exitInterrupted is the exit status of a run interrupted by a signal, after its provisional answer was reported.
+const exitInterrupted = 130
+
+// interruptGrace is how long an interrupted run may take to finalize its provisional answer.
+const interruptGrace = 5 * time.Second
+
+// exitPartialFailure is the exit status of a batch in which some, but not all, prompts failed.
+const exitPartialFailure = 3
+
+// exitQuotaExceeded is the exit status of a run rejected by a -quota_* limit.
+const exitQuotaExceeded = 4
+
+// batchRetryBackoff is the base delay before retrying a failed batch prompt; it grows linearly per attempt.
+var batchRetryBackoff = time.Second
+
+type batchStatus string
+
+const (
+	batchOK      batchStatus = "ok"
+	batchFailed  batchStatus = "failed"
+	batchSkipped batchStatus = "skipped"
+)
+
+// batchResult is the outcome of one batch prompt.
+type batchResult struct {
+	Index    int         `json:"index"`
+	Prompt   string      `json:"prompt"`
+	Status   batchStatus `json:"status"`
+	Attempts int         `json:"attempts"`
+	Error    string      `json:"error,omitempty"`
+	// Want is the expected answer given after a tab on the prompt's line, if any.
+	Want string `json:"want,omitempty"`
+	// Answer and Confidence are the final answer of a successful run and the confidence it reported.
+	Answer     string  `json:"answer,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+	// Score is the [experiment.ExactMatch] grade of Answer against Want, or nil when the prompt has no Want or failed.
+	Score *float64 `json:"score,omitempty"`
+}
+
+// batchReport summarizes a batch run.
+type batchReport struct {
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Results   []batchResult `json:"results"`
+	// Calibration compares the confidence of the graded prompts with their scores, or is nil when none was graded
+	// with a confidence.
+	Calibration *experiment.Calibration `json:"calibration,omitempty"`
+}
+
+// exitCode returns 0 when every prompt succeeded, 1 when none did, and [exitPartialFailure] otherwise.
+func (r *batchReport) exitCode() int {
+	switch {
+	case r.Succeeded == r.Total:
+		return 0
+	case r.Succeeded == 0:
+		return 1
+	default:
+		return exitPartialFailure
+	}
+}
 
-	f, err := os.Open(filepath.Clean(cfg.BatchFile))
+func runBatch(ctx context.Context, cfg *config, loader *reloader) (*batchReport, error) {
+	path, err := filepath.Abs(cfg.BatchFile)
+	if err != nil {
+		return nil, fmt.Errorf("open batch file: %w", err)
+	}
+	f, err := os.Open(storage.LongPath(path))
 	if err != nil {
-		return fmt.Errorf("open batch file: %w", err)
+		return nil, fmt.Errorf("open batch file: %w", err)
 	}
 	defer f.Close()
 
@@ -615,48 +1709,329 @@ func runBatch(ctx context.Context, cfg *config, loader adkagent.Loader) error {
 		prompts = append(prompts, line)
 	}
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("read batch file: %w", err)
-	}
-
-	promptCh := make(chan string)
-	errCh := make(chan error, cfg.Concurrency)
-	var wg sync.WaitGroup
-	for i := range cfg.Concurrency {
-		wg.Add(1)
-		go func(worker int) {
-			defer wg.Done()
-			for p := range promptCh {
-				if ctx.Err() != nil {
-					return
-				}
-				local := *cfg
-				local.Prompt = p
-				if local.SessionID == "" {
-					local.SessionID = fmt.Sprintf("session-%d-%d", time.Now().UnixNano(), worker)
-				}
-				if err := runOnce(ctx, &local, loader); err != nil {
-					errCh <- fmt.Errorf("prompt %q: %w", p, err)
-					cancel()
-					return
+		return nil, fmt.Errorf("read batch file: %w", err)
+	}
+
+	run := func(ctx context.Context, local *config) error {
+		return runOnce(ctx, local, loader)
+	}
+	if cfg.BatchIsolate {
+		workers := &workerLoaders{reloading: loader, loaders: make([]workerLoader, max(cfg.Concurrency, 1))}
+		run = func(ctx context.Context, local *config) error {
+			l, err := workers.get(local.batchWorker)
+			if err != nil {
+				return err
+			}
+			return runOnce(ctx, local, l)
+		}
+	}
+	report := runBatchPrompts(ctx, cfg, prompts, run)
+	if err := writeBatchReport(ctx, cfg, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// workerLoaders builds the agents of every batch worker from the current configuration of a reloader, so concurrent
+// prompts share no agent instances. A worker rebuilds its agents after a reload.
+type workerLoaders struct {
+	reloading *reloader
+	// loaders is indexed by worker; each worker only touches its own entry.
+	loaders []workerLoader
+}
+
+type workerLoader struct {
+	state  *reloadState
+	loader adkagent.Loader
+}
+
+// get returns the loader of worker, built from the current configuration.
+func (w *workerLoaders) get(worker int) (adkagent.Loader, error) {
+	cur := w.reloading.current.Load()
+	l := &w.loaders[worker]
+	if l.state != cur {
+		cfg := cur.cfg
+		loader, err := w.reloading.build(&cfg)
+		if err != nil {
+			return nil, fmt.Errorf("build agents of batch worker %d: %w", worker, err)
+		}
+		l.state, l.loader = cur, loader
+	}
+	return l.loader, nil
+}
+
+// runBatchPrompts runs prompts on cfg.Concurrency workers, retrying each failed prompt up to cfg.BatchMaxRetries
+// times. Unless cfg.BatchContinue is set, the first prompt that still fails cancels the rest, which are
+// reported as skipped. With cfg.BatchAdaptive, an [aimdWindow] bounds how many workers run at once.
+// A prompt whose run panics fails with the panic as its error.
+func runBatchPrompts(ctx context.Context, cfg *config, prompts []string, run func(context.Context, *config) error) *batchReport {
+	// The workers and the feeder all return by the time Wait does, whichever of them stopped the batch.
+	g, ctx := errgroup.WithContext(ctx)
+
+	if cfg.BatchAdaptive && cfg.Concurrency > 1 {
+		window := newAIMDWindow(max(cfg.Concurrency/2, 1), cfg.Concurrency)
+		next := run
+		run = func(ctx context.Context, local *config) error {
+			if err := window.acquire(ctx); err != nil {
+				return err
+			}
+			start := time.Now()
+			err := next(ctx, local)
+			window.release(ctx, err, time.Since(start))
+			return err
+		}
+	}
+
+	results := make([]batchResult, len(prompts))
+	for i, p := range prompts {
+		prompt, want, _ := strings.Cut(p, "\t")
+		results[i] = batchResult{Index: i, Prompt: strings.TrimSpace(prompt), Want: strings.TrimSpace(want), Status: batchSkipped}
+	}
+
+	indexCh := make(chan int)
+	g.Go(func() error {
+		defer close(indexCh)
+		for i := range prompts {
+			select {
+			case indexCh <- i:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	for worker := range max(cfg.Concurrency, 1) {
+		g.Go(func() error {
+			for i := range indexCh {
+				res := &results[i]
+				err := runBatchPrompt(ctx, cfg, worker, res, run)
+				switch {
+				case err == nil:
+					res.Status = batchOK
+				case ctx.Err() != nil:
+					// Interrupted by an aborted batch or a signal rather than failed on its own.
+					res.Status = batchSkipped
+					res.Error = err.Error()
+				default:
+					res.Status = batchFailed
+					res.Error = err.Error()
+					if !cfg.BatchContinue {
+						// Cancels the group; the other workers drain the prompts left as skipped.
+						return err
+					}
 				}
 			}
-		}(i)
+			return nil
+		})
+	}
+	// The error is the failure that aborted the batch, already recorded in its result.
+	_ = g.Wait()
+
+	report := &batchReport{Total: len(results), Results: results}
+	var graded []experiment.ArmRun
+	for i := range results {
+		res := &results[i]
+		switch res.Status {
+		case batchOK:
+			report.Succeeded++
+		case batchFailed:
+			report.Failed++
+		case batchSkipped:
+			report.Skipped++
+		}
+		if res.Status != batchOK || res.Want == "" {
+			continue
+		}
+		// ExactMatch never fails.
+		score, _ := experiment.ExactMatch.Grade(ctx, experiment.Case{Prompt: res.Prompt, Want: res.Want}, res.Answer)
+		res.Score = &score
+		graded = append(graded, experiment.ArmRun{Outcome: experiment.Outcome{Answer: res.Answer, Confidence: res.Confidence}, Score: score})
 	}
+	report.Calibration = experiment.Calibrate(graded)
+	return report
+}
 
-	go func() {
-		for _, p := range prompts {
-			promptCh <- p
+// runBatchPrompt runs one prompt with retries and records the attempts in res.
+func runBatchPrompt(ctx context.Context, cfg *config, worker int, res *batchResult, run func(context.Context, *config) error) error {
+	var err error
+	for attempt := range max(cfg.BatchMaxRetries, 0) + 1 {
+		if attempt > 0 {
+			timer := time.NewTimer(time.Duration(attempt) * batchRetryBackoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			return errors.Join(err, ctx.Err())
+		}
+
+		res.Attempts++
+		local := *cfg
+		local.Prompt = res.Prompt
+		local.batchWorker = worker
+		local.onResult = func(r *tumixrun.Result) {
+			res.Answer, res.Confidence = cmp.Or(r.Answer, r.Text), r.Confidence
+		}
+		// Concurrent prompts would interleave streamed tokens on stdout.
+		local.Stream = local.Stream && cfg.Concurrency <= 1
+		local.Progress = local.Progress && cfg.Concurrency <= 1
+		// Every attempt of every prompt runs in a fresh session, so no prompt sees the events or state of another.
+		if cfg.SessionID != "" {
+			local.SessionID = fmt.Sprintf("%s-%d-%d", cfg.SessionID, res.Index, res.Attempts)
+		} else {
+			local.SessionID = fmt.Sprintf("session-%d-%d", time.Now().UnixNano(), worker)
+		}
+		if err = runRecovered(ctx, &local, run); err == nil {
+			return nil
+		}
+		log.Warn(ctx, "batch prompt failed", "index", res.Index, "attempt", res.Attempts, "error", err)
+		// Retrying right away cannot get under a quota.
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			return err
+		}
+	}
+	return err
+}
+
+// runRecovered calls run, returning a panic of it as an error, so that a panicking prompt fails like any other
+// instead of taking down the batch.
+func runRecovered(ctx context.Context, local *config, run func(context.Context, *config) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			log.Error(ctx, "batch prompt panicked", err, "stack", string(debug.Stack()))
 		}
-		close(promptCh)
 	}()
+	return run(ctx, local)
+}
 
-	wg.Wait()
-	select {
-	case err := <-errCh:
-		return err
-	default:
+// writeBatchReport prints the per-prompt status as JSON with -json, or logs it otherwise.
+func writeBatchReport(ctx context.Context, cfg *config, report *batchReport) error {
+	if cfg.OutputJSON {
+		enc := jsontext.NewEncoder(os.Stdout)
+		if err := json.MarshalEncode(enc, map[string]any{"batch": report}); err != nil {
+			return fmt.Errorf("encode batch report: %w", err)
+		}
 		return nil
 	}
+
+	for _, res := range report.Results {
+		if res.Status != batchOK {
+			log.Warn(ctx, "batch prompt "+string(res.Status), "index", res.Index, "prompt", res.Prompt, "attempts", res.Attempts, "error", res.Error)
+		}
+	}
+	log.Info(ctx, "batch finished", "total", report.Total, "succeeded", report.Succeeded, "failed", report.Failed, "skipped", report.Skipped)
+	if c := report.Calibration; c != nil {
+		log.Info(ctx, "batch calibration", "runs", c.Runs, "brier", c.Brier, "ece", c.ECE)
+	}
+	return nil
+}
+
+// aimdWindow is an additive-increase/multiplicative-decrease concurrency limit, as in TCP congestion control.
+//
+// Every call that succeeds grows the window by about one slot per window's worth of calls. A call that failed with a
+// quota or availability error, or succeeded after more than aimdLatencyFactor times the typical latency, halves it.
+// Other failures, such as an invalid request, say nothing about the load of the backend and leave the window as is.
+//
+// The typical latency is a moving average of the latencies of the successful calls, so it follows prompts whose
+// latencies differ or drift instead of holding on to the fastest call ever seen.
+type aimdWindow struct {
+	mu        sync.Mutex
+	limit     float64
+	maxLimit  float64
+	inflight  int
+	baseline  time.Duration
+	changed   chan struct{}
+	lastLimit int
+}
+
+const (
+	aimdDecrease      = 0.5
+	aimdLatencyFactor = 4
+	// aimdBaselineWeight is the weight of the latest successful call in the moving average of the latencies.
+	aimdBaselineWeight = 0.2
+)
+
+func newAIMDWindow(initial, maxLimit int) *aimdWindow {
+	w := &aimdWindow{
+		limit:     float64(initial),
+		maxLimit:  float64(maxLimit),
+		changed:   make(chan struct{}),
+		lastLimit: initial,
+	}
+	recordBatchWindow(context.Background(), initial)
+	return w
+}
+
+func recordBatchWindow(ctx context.Context, limit int) {
+	expBatchWindow.Set(int64(limit))
+	if batchWindowGauge != nil {
+		batchWindowGauge.Record(ctx, int64(limit))
+	}
+}
+
+// size returns the current number of slots.
+func (w *aimdWindow) size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return int(w.limit)
+}
+
+// acquire blocks until a slot is free or ctx is done.
+func (w *aimdWindow) acquire(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		if w.inflight < int(w.limit) {
+			w.inflight++
+			w.mu.Unlock()
+			return nil
+		}
+		changed := w.changed
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release frees a slot and resizes the window from the outcome of the call, which failed with err, if not nil, after
+// latency.
+func (w *aimdWindow) release(ctx context.Context, err error, latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.inflight--
+	switch {
+	case failover.ShouldFailover(err):
+		w.limit = max(1, w.limit*aimdDecrease)
+	case err != nil:
+	default:
+		slow := w.baseline > 0 && latency > aimdLatencyFactor*w.baseline
+		if w.baseline == 0 {
+			w.baseline = latency
+		} else {
+			w.baseline += time.Duration(aimdBaselineWeight * float64(latency-w.baseline))
+		}
+		if slow {
+			w.limit = max(1, w.limit*aimdDecrease)
+		} else {
+			w.limit = min(w.maxLimit, w.limit+1/w.limit)
+		}
+	}
+
+	if limit := int(w.limit); limit != w.lastLimit {
+		w.lastLimit = limit
+		recordBatchWindow(ctx, limit)
+	}
+	close(w.changed)
+	w.changed = make(chan struct{})
 }
 
 func logEvent(ctx context.Context, event *session.Event) {
@@ -682,22 +2057,86 @@ func logEvent(ctx context.Context, event *session.Event) {
 	log.Info(ctx, "agent response", "author", event.Author, "text", strings.Join(texts, " "))
 }
 
-func firstText(event *session.Event) string {
-	if event == nil || event.Content == nil {
-		return ""
+// finalAnswerAuthors returns the agents whose model calls decide the final answer of the root agent named root: the
+// root itself, the Judge, whose turn ending a TUMIX or debate run selects the answer, and the synthesizer of a
+// hierarchical run.
+func finalAnswerAuthors(root string) []string {
+	return []string{root, tumixagent.JudgeAgentName, tumixagent.SynthesisAgentName}
+}
+
+// partialPrinter writes streamed text deltas of partial events as they arrive.
+type partialPrinter struct {
+	w io.Writer
+	// authors are the agents whose partial events are printed, those deciding the final answer (see
+	// [finalAnswerAuthors]). The partials of the candidates are not printed.
+	authors []string
+	open    bool
+}
+
+// print writes the non-thought text parts of a partial event of one of p.authors. Every streamed turn starts on a line
+// of its own, prefixed with its author.
+func (p *partialPrinter) print(event *session.Event) {
+	if event == nil || event.Content == nil || !slices.Contains(p.authors, event.Author) {
+		return
 	}
 	for _, part := range event.Content.Parts {
-		if part == nil {
+		if part == nil || part.Thought || part.Text == "" {
 			continue
 		}
-		if part.Text != "" {
-			return part.Text
+		if !p.open {
+			fmt.Fprintf(p.w, "%s: ", event.Author)
 		}
+		fmt.Fprint(p.w, part.Text)
+		p.open = true
 	}
-	return ""
+}
+
+// flush terminates the streamed line, if any.
+func (p *partialPrinter) flush() {
+	if !p.open {
+		return
+	}
+	fmt.Fprintln(p.w)
+	p.open = false
+}
+
+// logAnswerChanges logs, for every round after the first, which candidates changed their answer from the previous
+// round, with the text diff of every changed answer.
+func logAnswerChanges(ctx context.Context, rounds []tumixagent.RoundStats) {
+	for _, r := range rounds {
+		if len(r.Changes) == 0 {
+			continue
+		}
+		var changed []string
+		for _, c := range r.Changes {
+			if !c.Changed {
+				continue
+			}
+			changed = append(changed, c.Agent)
+			log.Log(ctx, slog.LevelDebug, "answer changed", "round", r.Round, "agent", c.Agent, "sample", c.Sample, "diff", c.Diff)
+		}
+		log.Info(ctx, "round answers", "round", r.Round, "changed", len(changed), "unchanged", len(r.Changes)-len(changed), "changed_agents", changed)
+	}
+}
+
+// progressBar prints the progress of a run as a bar of the rounds done, followed by the elapsed time, the estimated
+// time remaining, and the cost so far and estimated for the remaining rounds.
+type progressBar struct {
+	w     io.Writer
+	width int
+}
+
+// ReportProgress implements [tumixrun.ProgressReporter].
+func (b *progressBar) ReportProgress(p tumixrun.Progress) {
+	filled := min(b.width*int(p.Round)/int(max(p.MaxRounds, 1)), b.width) //nolint:gosec // rounds are small
+	fmt.Fprintf(b.w, "[%s%s] round %d/%d, elapsed %s, eta %s, cost $%.4f (+$%.4f est.)\n",
+		strings.Repeat("#", filled), strings.Repeat("-", b.width-filled),
+		p.Round, p.MaxRounds, p.Elapsed.Round(time.Second), p.Remaining.Round(time.Second), p.Cost, p.EstimatedRemainingCost)
 }
 
 func initTracing(ctx context.Context, cfg *config) (func(), error) {
+	// Propagate the run metadata baggage alongside the trace context, with or without an exporter.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 	if cfg.OTLPEndpoint == "" {
 		return func() {}, nil
 	}
@@ -717,7 +2156,8 @@ func initTracing(ctx context.Context, cfg *config) (func(), error) {
 	tp := trace.NewTracerProvider(trace.WithBatcher(exp), trace.WithResource(res))
 	otel.SetTracerProvider(tp)
 	return func() {
-		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		// Flush the spans even when the run was interrupted.
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 		defer cancel()
 		if err := tp.Shutdown(shutdownCtx); err != nil {
 			log.Warn(ctx, "shutdown tracer provider", "error", err)
@@ -744,16 +2184,38 @@ func initMetrics() error {
 	if err != nil {
 		return fmt.Errorf("init cost_usd counter: %w", err)
 	}
+	batchWindowGauge, err = meter.Int64Gauge("tumix.batch.concurrency")
+	if err != nil {
+		return fmt.Errorf("init batch.concurrency gauge: %w", err)
+	}
+	quotaRejected, err = meter.Int64Counter("tumix.quota.rejections")
+	if err != nil {
+		return fmt.Errorf("init quota.rejections counter: %w", err)
+	}
+	reloadCounter, err = meter.Int64Counter("tumix.config.reloads")
+	if err != nil {
+		return fmt.Errorf("init config.reloads counter: %w", err)
+	}
+	sessionsEvicted, err = meter.Int64Counter("tumix.sessions.evicted")
+	if err != nil {
+		return fmt.Errorf("init sessions.evicted counter: %w", err)
+	}
+	panicCounter, err = meter.Int64Counter("tumix.panics")
+	if err != nil {
+		return fmt.Errorf("init panics counter: %w", err)
+	}
 	return nil
 }
 
-func serveMetrics(ctx context.Context, addr string) {
+func serveMetrics(ctx context.Context, addr string, ready *readiness) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		if _, err := w.Write([]byte("ok")); err != nil {
 			log.Warn(ctx, "healthz write failed", "error", err)
 		}
 	})
+	mux.Handle("/readyz", ready)
+	mux.HandleFunc("/version", serveVersion)
 	mux.Handle("/debug/vars", expvar.Handler())
 	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
 		writePromMetrics(w)
@@ -782,6 +2244,20 @@ func writePromMetrics(w http.ResponseWriter) {
 	fmt.Fprintf(w, "tumix_output_tokens %d\n", expOutputTokens.Value())
 	fmt.Fprintf(w, "# TYPE tumix_cost_usd counter\n")
 	fmt.Fprintf(w, "tumix_cost_usd %f\n", expCostUSD.Value())
+	fmt.Fprintf(w, "# TYPE tumix_batch_concurrency gauge\n")
+	fmt.Fprintf(w, "tumix_batch_concurrency %d\n", expBatchWindow.Value())
+	fmt.Fprintf(w, "# TYPE tumix_dedup_saved_calls counter\n")
+	fmt.Fprintf(w, "tumix_dedup_saved_calls %d\n", expDedupSaved.Value())
+	fmt.Fprintf(w, "# TYPE tumix_quota_rejections counter\n")
+	fmt.Fprintf(w, "tumix_quota_rejections %d\n", expQuotaRejected.Value())
+	fmt.Fprintf(w, "# TYPE tumix_sessions_evicted counter\n")
+	fmt.Fprintf(w, "tumix_sessions_evicted %d\n", expSessionsEvicted.Value())
+	fmt.Fprintf(w, "# TYPE tumix_panics counter\n")
+	fmt.Fprintf(w, "tumix_panics %d\n", expPanics.Value())
+	fmt.Fprintf(w, "# TYPE tumix_config_reloads counter\n")
+	fmt.Fprintf(w, "tumix_config_reloads %d\n", expConfigReloads.Value())
+	fmt.Fprintf(w, "# TYPE tumix_config_reload_errors counter\n")
+	fmt.Fprintf(w, "tumix_config_reload_errors %d\n", expConfigReloadErr.Value())
 }
 
 func estimateTokensFromChars(n int) int {
@@ -794,46 +2270,66 @@ func estimateTokensFromChars(n int) int {
 
 func benchLocal(cfg *config) {
 	start := time.Now()
-	var wg sync.WaitGroup
 	prompts := cfg.BenchLocal
 	workers := max(cfg.Concurrency, 1)
-	ch := make(chan int)
-	for range workers {
-		wg.Go(func() {
-			for range ch {
-				_ = strings.Repeat("x", 1024)
-			}
+	var g errgroup.Group
+	g.SetLimit(workers)
+	for range prompts {
+		g.Go(func() error {
+			_ = strings.Repeat("x", 1024)
+			return nil
 		})
 	}
-	for i := range prompts {
-		ch <- i
-	}
-	close(ch)
-	wg.Wait()
+	_ = g.Wait() // the iterations cannot fail
 	dur := time.Since(start)
 	fmt.Fprintf(os.Stdout, "bench_local iters=%d workers=%d duration=%s per_iter=%s\n", prompts, workers, dur, dur/time.Duration(prompts))
 }
 
-func estimateAndWarn(ctx context.Context, cfg *config, totalIn, totalOut int) {
-	// Upper-bound call count: (candidates + judge) per round.
-	agents := 12 + 1                     // 12 candidates + judge
-	calls := int(cfg.MaxRounds) * agents //nolint:gosec // TODO(zchee): fix nolint
+// estimateAndWarn warns when the upper bound of the LLM calls of a run is high and logs the usage of the run.
+func estimateAndWarn(ctx context.Context, cfg *config, totals usage.Totals) {
+	// Upper-bound call count: (candidates * samples + judge) per round.
+	samples := int(max(cfg.SamplesPerAgent, 1)) //nolint:gosec // TODO(zchee): fix nolint
+	agents := 12*samples + 1                    // 12 candidates per sample + judge
+	calls := int(cfg.MaxRounds) * agents        //nolint:gosec // TODO(zchee): fix nolint
 	if cfg.CallWarn > 0 && calls > cfg.CallWarn {
 		log.Warn(ctx, "estimated LLM calls high", "calls", calls, "threshold", cfg.CallWarn)
 	}
-	if cost := estimateCost(cfg.ModelName, totalIn, totalOut); cost > 0 {
-		costCounter.Add(ctx, cost)
-		expCostUSD.Add(cost)
-		log.Info(ctx, "usage", "input_tokens", totalIn, "output_tokens", totalOut, "cost_usd", cost)
+	if totals.CostUSD > 0 {
+		log.Info(ctx, "usage", "requests", totals.Requests, "input_tokens", totals.InputTokens, "output_tokens", totals.OutputTokens,
+			"cost_usd", totals.CostUSD, "judge_input_tokens", totals.JudgeInputTokens, "judge_output_tokens", totals.JudgeOutputTokens,
+			"judge_cost_usd", totals.JudgeCostUSD)
 	}
 }
 
-func estimateCost(modelName string, inputTokens, outputTokens int) float64 {
-	p, ok := prices[modelName]
-	if !ok {
-		p = prices["gemini-2.5-flash"]
+// triageSavings estimates the cost the triage saved by running decision.Candidates candidates instead of all of them,
+// extrapolating the candidate cost of totals to the candidates skipped.
+func triageSavings(decision *tumixagent.TriageDecision, totals usage.Totals) float64 {
+	if decision.Candidates <= 0 || decision.Candidates >= decision.TotalCandidates {
+		return 0
+	}
+	candidateCost := totals.CostUSD - totals.JudgeCostUSD
+	return candidateCost * float64(decision.TotalCandidates-decision.Candidates) / float64(decision.Candidates)
+}
+
+// newUsageCollector returns the collector of the usage of a run, pricing every call with [callCost] and recording it
+// in the metrics.
+func newUsageCollector(cfg *config) *usage.Collector {
+	c := usage.NewCollector(tumixagent.JudgeAgentName, func(call usage.Call) float64 { return callCost(cfg, call) })
+	c.Subscribe(recordUsage)
+	return c
+}
+
+// callCost returns the cost of call, pricing the Judge calls at the judge model.
+func callCost(cfg *config, call usage.Call) float64 {
+	model := cfg.ModelName
+	if call.Judge {
+		model = judgeModelName(cfg)
 	}
-	return (float64(inputTokens)/1000.0)*p.inUSDPerKT + (float64(outputTokens)/1000.0)*p.outUSDPerKT
+	return estimateCost(model, int(call.InputTokens), int(call.OutputTokens))
+}
+
+func estimateCost(modelName string, inputTokens, outputTokens int) float64 {
+	return prices.Cost(modelName, pricing.Usage{InputTokens: inputTokens, OutputTokens: outputTokens})
 }
 
 func capRoundsByBudget(cfg *config, candidates int) uint {
@@ -849,7 +2345,7 @@ func capRoundsByBudget(cfg *config, candidates int) uint {
 		if cfg.MaxPromptTokens > 0 {
 			inputTokens = cfg.MaxPromptTokens
 		} else {
-			inputTokens = estimateTokensFromChars(len(cfg.Prompt))
+			inputTokens = estimateTokensFromChars(len(cfg.Prompt)) + attachmentTokens(cfg.attachments)
 		}
 	}
 	if inputTokens < 1 {
@@ -868,65 +2364,248 @@ func capRoundsByBudget(cfg *config, candidates int) uint {
 	return capRounds
 }
 
-func loadPricing(ctx context.Context) {
-	path := os.Getenv("TUMIX_PRICING_FILE")
-	if path == "" {
-		return
+// openSessionStore opens the -session_dir store, or else the TUMIX_SESSION_SQLITE one, or else an in-memory one.
+func openSessionStore(ctx context.Context, cfg *config) (session.Service, error) {
+	if cfg.SessionDir != "" {
+		svc, err := sessionfs.ServiceWithOptions(cfg.SessionDir, sessionfs.Options{Sync: sessionfs.SyncPolicy(cfg.SessionSync)})
+		if err != nil {
+			return nil, fmt.Errorf("init session store: %w", err)
+		}
+		return svc, nil
+	}
+	if dbPath := os.Getenv("TUMIX_SESSION_SQLITE"); dbPath != "" {
+		svc, err := sessiondb.Service(ctx, dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("init sqlite store: %w", err)
+		}
+		return svc, nil
 	}
-	b, err := os.ReadFile(path)
+	return session.InMemoryService(), nil
+}
+
+// initSessionStore sets cfg.sessions to the persistent session store, if any, and enforces the -session_max_* limits
+// on it with a janitor running until ctx is done.
+func initSessionStore(ctx context.Context, cfg *config) error {
+	if cfg.SessionDir == "" && os.Getenv("TUMIX_SESSION_SQLITE") == "" {
+		return nil
+	}
+	svc, err := openSessionStore(ctx, cfg)
 	if err != nil {
-		log.Warn(ctx, "pricing file read failed", "error", err)
-		return
+		return err
+	}
+	cfg.sessions = svc
+
+	policy := retention.Policy{
+		MaxAge:             cfg.SessionMaxAge,
+		MaxSessionsPerUser: cfg.SessionMaxUser,
+		MaxTotalBytes:      cfg.SessionMaxBytes,
+	}
+	store, ok := svc.(retention.Store)
+	if !policy.Enabled() || !ok {
+		return nil
+	}
+	janitor := retention.New(policy, store,
+		retention.WithInterval(cfg.SessionGCEvery),
+		retention.WithDryRun(cfg.SessionGCDryRun),
+		retention.WithOnEvict(func(ctx context.Context, e retention.Eviction, dryRun bool) {
+			if !dryRun {
+				expSessionsEvicted.Add(1)
+			}
+			if sessionsEvicted != nil {
+				sessionsEvicted.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", string(e.Reason)), attribute.Bool("dry_run", dryRun)))
+			}
+			log.Info(ctx, "session evicted", "app", e.AppName, "user", e.UserID, "session", e.SessionID, "reason", e.Reason, "bytes", e.Bytes, "dry_run", dryRun)
+		}),
+		retention.WithOnError(func(ctx context.Context, err error) {
+			log.Warn(ctx, "session retention failed", "error", err)
+		}),
+	)
+	go janitor.Run(ctx)
+	return nil
+}
+
+// initObserver sets cfg.observer from -observability, with the headers of TUMIX_OBSERVABILITY_HEADERS.
+func initObserver(cfg *config) error {
+	if cfg.Observability != "openinference" {
+		return nil
+	}
+	headers, err := openinference.ParseHeaders(os.Getenv("TUMIX_OBSERVABILITY_HEADERS"))
+	if err != nil {
+		return err
+	}
+	cfg.observer, err = openinference.NewExporter(cfg.ObservabilityURL,
+		openinference.WithHTTPClient(newHTTPClient(cfg.TraceHTTP)),
+		openinference.WithHeaders(headers),
+		openinference.WithProject(cfg.AppName),
+	)
+	return err
+}
+
+// exportRun sends the spans of a run to the observability platform. A failed export only warns, so that it never fails
+// the run.
+func exportRun(ctx context.Context, cfg *config, spans []openinference.Span) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := cfg.observer.Export(ctx, spans); err != nil {
+		log.Warn(ctx, "export run to observability platform failed", "error", err)
+	}
+}
+
+// initQuota sets cfg.quota from the -quota_* limits. The usage is kept next to the sessions of -session_dir, and in
+// memory for this process only without it.
+func initQuota(cfg *config) error {
+	limits := quota.Limits{
+		RequestsPerDay: cfg.QuotaRequests,
+		TokensPerDay:   cfg.QuotaTokens,
+		MaxConcurrent:  cfg.QuotaConcurrent,
+	}
+	if !limits.Enabled() {
+		return nil
+	}
+	var store quota.Store = quota.NewMemoryStore()
+	if cfg.SessionDir != "" {
+		fileStore, err := quota.NewFileStore(cfg.SessionDir)
+		if err != nil {
+			return err
+		}
+		store = fileStore
 	}
-	tmp := map[string]struct {
-		In  float64 `json:"in_per_kt"`
-		Out float64 `json:"out_per_kt"`
-	}{}
-	if err := json.Unmarshal(b, &tmp); err != nil {
-		log.Warn(ctx, "pricing file parse failed", "error", err)
+	cfg.quota = quota.New(limits, store, quota.WithOnReject(func(ctx context.Context, e *quota.ExceededError) {
+		expQuotaRejected.Add(1)
+		if quotaRejected != nil {
+			quotaRejected.Add(ctx, 1, metric.WithAttributes(attribute.String("limit", string(e.Limit))))
+		}
+		log.Warn(ctx, "quota exceeded", "user", e.User, "limit", e.Limit, "used", e.Used, "max", e.Max, "retry_after", e.RetryAfter)
+	}))
+	return nil
+}
+
+// writeQuotaError prints a quota rejection with -json, shaped like the body of an HTTP 429 response.
+func writeQuotaError(cfg *config, e *quota.ExceededError) {
+	if !cfg.OutputJSON {
 		return
 	}
-	for k, v := range tmp {
-		prices[k] = struct{ inUSDPerKT, outUSDPerKT float64 }{inUSDPerKT: v.In, outUSDPerKT: v.Out}
+	enc := jsontext.NewEncoder(os.Stdout)
+	_ = json.MarshalEncode(enc, map[string]any{"error": map[string]any{
+		"status":              e.StatusCode(),
+		"message":             e.Error(),
+		"user":                e.User,
+		"limit":               e.Limit,
+		"max":                 e.Max,
+		"used":                e.Used,
+		"retry_after_seconds": int64(e.RetryAfter.Seconds()),
+	}})
+}
+
+func loadPricing(ctx context.Context) {
+	if err := reloadPricing(); err != nil {
+		log.Warn(ctx, "pricing file load failed", "error", err)
 	}
 }
 
-func recordUsage(ctx context.Context, event *session.Event) (in, out int64) {
-	if event == nil || event.UsageMetadata == nil {
-		return 0, 0
+// reloadPricing merges the TUMIX_PRICING_FILE prices, if set, into the pricing catalog.
+func reloadPricing() error {
+	path := os.Getenv("TUMIX_PRICING_FILE")
+	if path == "" {
+		return nil
 	}
-	in = int64(event.UsageMetadata.PromptTokenCount)
-	out = int64(event.UsageMetadata.CandidatesTokenCount)
+	return prices.LoadFile(path)
+}
+
+// recordUsage adds call to the OpenTelemetry metrics and the expvars.
+func recordUsage(ctx context.Context, call usage.Call) {
 	requestCounter.Add(ctx, 1)
-	inputTokCounter.Add(ctx, in)
-	outputTokCounter.Add(ctx, out)
+	inputTokCounter.Add(ctx, call.InputTokens)
+	outputTokCounter.Add(ctx, call.OutputTokens)
 	expRequests.Add(1)
-	expInputTokens.Add(in)
-	expOutputTokens.Add(out)
-	return in, out
+	expInputTokens.Add(call.InputTokens)
+	expOutputTokens.Add(call.OutputTokens)
+	if call.CostUSD > 0 {
+		costCounter.Add(ctx, call.CostUSD)
+		expCostUSD.Add(call.CostUSD)
+	}
+}
+
+// recordPanics counts and logs the panics of the candidates, the Judge, and their tools that event reports.
+func recordPanics(ctx context.Context, event *session.Event) {
+	for _, p := range tumixagent.PanicsFromEvent(event) {
+		expPanics.Add(1)
+		if panicCounter != nil {
+			panicCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("agent", p.Agent), attribute.String("tool", p.Tool)))
+		}
+		log.Error(ctx, "agent panicked", p, "agent", p.Agent, "tool", p.Tool, "stack", string(p.Stack))
+	}
 }
 
 func printConfig(cfg *config) error {
 	out := map[string]any{
+		"config_file":       cfg.ConfigFile,
+		"backend":           cfg.LLMBackend,
 		"model":             cfg.ModelName,
+		"judge_model":       judgeModelName(cfg),
 		"max_rounds":        cfg.MaxRounds,
 		"min_rounds":        cfg.MinRounds,
+		"mode":              cfg.Mode,
+		"verify":            cfg.Verify,
+		"triage":            cfg.Triage,
+		"triage_candidates": cfg.TriageCandidates,
+		"round_timeout":     cfg.RoundTimeout.String(),
+		"run_timeout":       cfg.RunTimeout.String(),
 		"temperature":       cfg.Temperature,
 		"top_p":             cfg.TopP,
 		"top_k":             cfg.TopK,
 		"max_tokens":        cfg.MaxTokens,
 		"seed":              cfg.Seed,
+		"stream":            cfg.Stream,
+		"progress":          cfg.Progress,
+		"explain":           cfg.Explain,
 		"session_dir":       cfg.SessionDir,
+		"session_sync":      cfg.SessionSync,
+		"session_retention": map[string]any{"max_age": cfg.SessionMaxAge.String(), "max_per_user": cfg.SessionMaxUser, "max_bytes": cfg.SessionMaxBytes, "dry_run": cfg.SessionGCDryRun, "interval": cfg.SessionGCEvery.String()},
 		"http_trace":        cfg.TraceHTTP,
 		"log_json":          cfg.LogJSON,
 		"otlp_endpoint":     cfg.OTLPEndpoint,
+		"observability":     map[string]any{"format": cfg.Observability, "endpoint": cfg.ObservabilityURL},
 		"batch_file":        cfg.BatchFile,
+		"batch_max_retries": cfg.BatchMaxRetries,
+		"batch_continue":    cfg.BatchContinue,
+		"batch_adaptive":    cfg.BatchAdaptive,
+		"batch_isolate":     cfg.BatchIsolate,
 		"concurrency":       cfg.Concurrency,
 		"max_cost_usd":      cfg.MaxCostUSD,
 		"auto_agents":       cfg.AutoAgents,
+		"samples_per_agent": cfg.SamplesPerAgent,
+		"dedup_requests":    cfg.DedupRequests,
+		"hierarchical":      cfg.Hierarchical,
+		"max_subtasks":      cfg.MaxSubTasks,
+		"subtask_rounds":    cfg.SubTaskRounds,
+		"workflow":          cfg.Workflow,
+		"failover":          cfg.Failover,
+		"mcp_config":        cfg.MCPConfig,
+		"webfetch":          cfg.WebFetch,
+		"python":            cfg.Python,
+		"audit_dir":         cfg.AuditDir,
+		"a2a_agents":        cfg.A2AAgents,
+		"xai_rps":           cfg.XAIRPS,
+		"xai_burst":         cfg.XAIBurst,
+		"model_catalog":     cfg.ModelCatalog,
+		"logprobs":          cfg.Logprobs,
+		"preflight":         cfg.Preflight,
 		"budget_tokens":     cfg.BudgetTokens,
 		"metrics_addr":      cfg.MetricsAddr,
 		"max_prompt_tokens": cfg.MaxPromptTokens,
+		"compress_prompt":   cfg.CompressPrompt,
+		"compress_tokens":   cfg.CompressTokens,
+		"summarize_answers": cfg.SummarizeAnswers,
+		"summary_tokens":    cfg.SummaryTokens,
+		"reformat_answers":  cfg.ReformatAnswers,
+		"answer_protocol":   cfg.AnswerProtocol,
+		"state_merge":       cfg.StateMerge,
+		"run_labels":        cfg.RunLabels,
+		"system_prompt":     cfg.SystemPrompt,
+		"attach":            cfg.Attachments,
+		"max_attach_bytes":  cfg.MaxAttachBytes,
+		"quota":             map[string]any{"requests_per_day": cfg.QuotaRequests, "tokens_per_day": cfg.QuotaTokens, "max_concurrent": cfg.QuotaConcurrent},
 	}
 	data, err := json.Marshal(out)
 	if err != nil {
@@ -943,9 +2622,30 @@ func parseEnv[T any](key string, fallback T) T {
 	if raw == "" {
 		return fallback
 	}
+	v, ok := parseEnvValue[T](raw)
+	if !ok {
+		invalidEnv.Store(key, raw)
+		return fallback
+	}
+	return v
+}
+
+// invalidEnv holds the environment variables parseEnv ignored because their values do not parse, by name, reported by
+// "tumix doctor".
+var invalidEnv sync.Map
+
+func parseEnvValue[T any](raw string) (T, bool) {
+	var zero T
 
 	typ := reflect.TypeFor[T]()
 	kind := typ.Kind()
+	if typ == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return zero, false
+		}
+		return any(d).(T), true
+	}
 
 	var (
 		v   any
@@ -963,15 +2663,15 @@ func parseEnv[T any](key string, fallback T) T {
 	case reflect.Float32, reflect.Float64:
 		v, err = strconv.ParseFloat(raw, typ.Bits())
 	default:
-		return fallback
+		return zero, false
 	}
 	if err != nil {
-		return fallback
+		return zero, false
 	}
 
 	rv := reflect.ValueOf(v)
 	if !rv.Type().ConvertibleTo(typ) {
-		return fallback
+		return zero, false
 	}
-	return rv.Convert(typ).Interface().(T)
+	return rv.Convert(typ).Interface().(T), true
 }
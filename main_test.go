@@ -19,14 +19,28 @@ package main
 import (
 	"context"
 	json "encoding/json/v2"
+	"errors"
 	"flag"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	adkagent "google.golang.org/adk/agent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/session"
 	"google.golang.org/genai"
+
+	tumixagent "github.com/zchee/tumix/agent"
+	"github.com/zchee/tumix/experiment"
+	"github.com/zchee/tumix/gollm"
+	tumixrun "github.com/zchee/tumix/run"
 )
 
 func assertParseEnv[T comparable](t *testing.T, key, raw string, fallback, want T) {
@@ -133,7 +147,7 @@ func TestEstimateTokensFromChars(t *testing.T) {
 
 func TestLoadPricingInvalidPath(t *testing.T) {
 	loadPricing(t.Context()) // should not panic when env unset
-	if _, ok := prices["gemini-2.5-flash"]; !ok {
+	if _, ok := prices.Lookup("gemini-2.5-flash"); !ok {
 		t.Fatalf("default pricing missing")
 	}
 }
@@ -189,6 +203,7 @@ func TestBuildGenConfig(t *testing.T) {
 		cfg      config
 		wantNil  bool
 		wantTopK float32
+		wantSeed int32
 	}{
 		"nil_when_all_defaults": {
 			cfg: config{
@@ -210,6 +225,15 @@ func TestBuildGenConfig(t *testing.T) {
 			},
 			wantNil:  false,
 			wantTopK: 12,
+			wantSeed: 42,
+		},
+		"negative_seed": {
+			cfg: config{
+				Temperature: -1,
+				TopP:        -1,
+				Seed:        -7,
+			},
+			wantSeed: -7,
 		},
 	}
 	for name, tt := range tests {
@@ -224,9 +248,12 @@ func TestBuildGenConfig(t *testing.T) {
 				if got == nil {
 					t.Fatalf("expected config, got nil")
 				}
-				if got.TopK == nil || *got.TopK != tt.wantTopK {
+				if tt.wantTopK != 0 && (got.TopK == nil || *got.TopK != tt.wantTopK) {
 					t.Fatalf("TopK = %v, want %v", got.TopK, tt.wantTopK)
 				}
+				if got.Seed == nil || *got.Seed != tt.wantSeed {
+					t.Fatalf("Seed = %v, want %v", got.Seed, tt.wantSeed)
+				}
 			}
 		})
 	}
@@ -265,6 +292,48 @@ func TestParseConfigSuccessAndEnvFallback(t *testing.T) {
 		}
 	})
 
+	t.Run("min_rounds", func(t *testing.T) {
+		tests := map[string]struct {
+			args []string
+			env  string
+			want uint
+		}{
+			"default":  {args: []string{"cmd", "-api_key=k", "hi"}, want: 2},
+			"flag":     {args: []string{"cmd", "-api_key=k", "-min_rounds=1", "hi"}, want: 1},
+			"env":      {args: []string{"cmd", "-api_key=k", "hi"}, env: "3", want: 3},
+			"flag_env": {args: []string{"cmd", "-api_key=k", "-min_rounds=1", "hi"}, env: "3", want: 1},
+			"zero":     {args: []string{"cmd", "-api_key=k", "-min_rounds=0", "hi"}, want: 1},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				restore := resetFlags(tt.args)
+				defer restore()
+
+				t.Setenv("TUMIX_MIN_ROUNDS", tt.env)
+				cfg, err := parseConfig()
+				if err != nil {
+					t.Fatalf("parseConfig error = %v", err)
+				}
+				if cfg.MinRounds != tt.want {
+					t.Fatalf("MinRounds = %d, want %d", cfg.MinRounds, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("preflight_without_prompt", func(t *testing.T) {
+		restore := resetFlags([]string{"cmd", "-api_key=k", "-preflight"})
+		defer restore()
+
+		cfg, err := parseConfig()
+		if err != nil {
+			t.Fatalf("parseConfig error = %v", err)
+		}
+		if !cfg.Preflight || cfg.Prompt != "" {
+			t.Fatalf("Preflight = %t, Prompt = %q, want true and empty", cfg.Preflight, cfg.Prompt)
+		}
+	})
+
 	t.Run("env_api_key_used_when_flag_missing", func(t *testing.T) {
 		restore := resetFlags([]string{"cmd", "-backend=gemini", "hi"})
 		defer restore()
@@ -293,6 +362,11 @@ func TestParseConfigErrors(t *testing.T) {
 		}
 	}
 
+	pdf := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.7"), 0o600); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+
 	tests := map[string]struct {
 		args []string
 		env  map[string]string
@@ -303,6 +377,88 @@ func TestParseConfigErrors(t *testing.T) {
 		"invalid_backend": {
 			args: []string{"cmd", "-api_key=k", "-backend=bad", "hello"},
 		},
+		"invalid_mode": {
+			args: []string{"cmd", "-api_key=k", "-mode=swarm", "hello"},
+		},
+		"verify_debate_conflict": {
+			args: []string{"cmd", "-api_key=k", "-mode=debate", "-verify", "hello"},
+		},
+		"invalid_triage": {
+			args: []string{"cmd", "-api_key=k", "-triage=magic", "hello"},
+		},
+		"zero_triage_candidates": {
+			args: []string{"cmd", "-api_key=k", "-triage=heuristic", "-triage_candidates=0", "hello"},
+		},
+		"invalid_summarize_answers": {
+			args: []string{"cmd", "-api_key=k", "-summarize_answers=zip", "hello"},
+		},
+		"invalid_state_merge": {
+			args: []string{"cmd", "-api_key=k", "-state_merge=first", "hello"},
+		},
+		"negative_session_max_age": {
+			args: []string{"cmd", "-api_key=k", "-session_max_age=-1h", "hello"},
+		},
+		"invalid_session_sync": {
+			args: []string{"cmd", "-api_key=k", "-session_sync=sometimes", "hello"},
+		},
+		"session_path_traversal": {
+			args: []string{"cmd", "-api_key=k", "-session=../../etc/passwd", "hello"},
+		},
+		"invalid_user": {
+			args: []string{"cmd", "-api_key=k", "-user=CON", "hello"},
+		},
+		"invalid_observability": {
+			args: []string{"cmd", "-api_key=k", "-observability=zipkin", "hello"},
+		},
+		"observability_without_endpoint": {
+			args: []string{"cmd", "-api_key=k", "-observability=openinference", "hello"},
+		},
+		"invalid_answer_protocol": {
+			args: []string{"cmd", "-api_key=k", "-answer_protocol=xml", "hello"},
+		},
+		"negative_summary_answer_tokens": {
+			args: []string{"cmd", "-api_key=k", "-summarize_answers=truncate", "-summary_answer_tokens=-1", "hello"},
+		},
+		"min_rounds_above_max_rounds": {
+			args: []string{"cmd", "-api_key=k", "-max_rounds=2", "-min_rounds=3", "hello"},
+		},
+		"env_min_rounds_above_max_rounds": {
+			args: []string{"cmd", "-api_key=k", "-max_rounds=2", "hello"},
+			env:  map[string]string{"TUMIX_MIN_ROUNDS": "3"},
+		},
+		"negative_round_timeout": {
+			args: []string{"cmd", "-api_key=k", "-round_timeout=-1s", "hello"},
+		},
+		"negative_quota": {
+			args: []string{"cmd", "-api_key=k", "-quota_tokens_per_day=-1", "hello"},
+		},
+		"invalid_failover": {
+			args: []string{"cmd", "-api_key=k", "-failover=xai", "hello"},
+		},
+		"system_prompt_conflict": {
+			args: []string{"cmd", "-api_key=k", "-system_prompt=x", "-system_prompt_file=x.txt", "hello"},
+		},
+		"missing_system_prompt_file": {
+			args: []string{"cmd", "-api_key=k", "-system_prompt_file=" + filepath.Join("testdata", "missing.txt"), "hello"},
+		},
+		"invalid_run_labels": {
+			args: []string{"cmd", "-api_key=k", "-run_labels=experiment", "hello"},
+		},
+		"missing_attachment": {
+			args: []string{"cmd", "-api_key=k", "-attach=" + filepath.Join("testdata", "missing.png"), "hello"},
+		},
+		"attachment_too_large": {
+			args: []string{"cmd", "-api_key=k", "-attach=main.go", "-max_attach_bytes=10", "hello"},
+		},
+		"missing_workflow": {
+			args: []string{"cmd", "-api_key=k", "-workflow=" + filepath.Join("testdata", "missing.yaml"), "hello"},
+		},
+		"workflow_hierarchical_conflict": {
+			args: []string{"cmd", "-api_key=k", "-hierarchical", "-workflow=" + filepath.Join("workflow", "examples", "debate.yaml"), "hello"},
+		},
+		"attachment_unsupported_by_backend": {
+			args: []string{"cmd", "-api_key=k", "-backend=xai", "-attach=" + pdf, "hello"},
+		},
 	}
 
 	for name, tt := range tests {
@@ -340,11 +496,11 @@ func TestLoadPricingOverride(t *testing.T) {
 	t.Setenv("TUMIX_PRICING_FILE", file)
 	loadPricing(t.Context())
 
-	p, ok := prices["custom-model"]
+	p, ok := prices.Lookup("custom-model")
 	if !ok {
 		t.Fatalf("custom pricing not loaded")
 	}
-	if p.inUSDPerKT != 0.123 || p.outUSDPerKT != 0.321 {
+	if p.InputPerKT != 0.123 || p.OutputPerKT != 0.321 {
 		t.Fatalf("pricing = %+v", p)
 	}
 }
@@ -373,18 +529,574 @@ func TestRecordUsageUpdatesCounters(t *testing.T) {
 		t.Fatalf("initMetrics: %v", err)
 	}
 
-	event := &session.Event{}
-	event.LLMResponse = model.LLMResponse{
-		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
-			PromptTokenCount:     10,
-			CandidatesTokenCount: 5,
+	cfg := &config{ModelName: "gemini-2.5-flash", JudgeModel: "gemini-2.5-pro"}
+	collector := newUsageCollector(cfg)
+	for _, author := range []string{"CoT", tumixagent.JudgeAgentName} {
+		event := &session.Event{Author: author}
+		event.LLMResponse = model.LLMResponse{
+			UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+				PromptTokenCount:     10,
+				CandidatesTokenCount: 5,
+			},
+		}
+		collector.AddEvent(t.Context(), event)
+	}
+	collector.AddEvent(t.Context(), &session.Event{Author: "CoT"})
+
+	totals := collector.Totals()
+	if expRequests.Value() != 2 || expInputTokens.Value() != 20 || expOutputTokens.Value() != 10 {
+		t.Fatalf("expvars not updated: req=%d in=%d out=%d", expRequests.Value(), expInputTokens.Value(), expOutputTokens.Value())
+	}
+	if totals.Requests != 2 || totals.JudgeInputTokens != 10 || totals.JudgeOutputTokens != 5 {
+		t.Fatalf("totals = %+v, want 2 requests and the judge tokens of one", totals)
+	}
+	wantJudge := estimateCost("gemini-2.5-pro", 10, 5)
+	if want := estimateCost("gemini-2.5-flash", 10, 5) + wantJudge; totals.CostUSD != want || totals.JudgeCostUSD != wantJudge {
+		t.Fatalf("totals cost = %v (judge %v), want %v (judge %v)", totals.CostUSD, totals.JudgeCostUSD, want, wantJudge)
+	}
+	if got := expCostUSD.Value(); got != totals.CostUSD {
+		t.Fatalf("tumix_cost_usd = %v, want the collected cost %v", got, totals.CostUSD)
+	}
+}
+
+func TestPartialPrinter(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	p := &partialPrinter{w: &buf, authors: finalAnswerAuthors("tumix")}
+	p.flush()
+
+	for _, tt := range []struct {
+		author string
+		parts  []*genai.Part
+	}{
+		{"base", []*genai.Part{{Text: "<<<41>>>"}}},
+		{tumixagent.JudgeAgentName, []*genai.Part{{Text: "thinking", Thought: true}, {Text: "The answer"}}},
+		{tumixagent.JudgeAgentName, []*genai.Part{nil, {Text: " is 42."}}},
+		{"", nil},
+		{"tumix", []*genai.Part{{Text: "Final answer: 42"}}},
+	} {
+		if tt.parts == nil {
+			// The complete event ending the turn.
+			p.flush()
+			continue
+		}
+		event := &session.Event{Author: tt.author}
+		event.Content = &genai.Content{Role: genai.RoleModel, Parts: tt.parts}
+		p.print(event)
+	}
+	p.print(&session.Event{Author: "tumix"})
+	p.flush()
+	p.flush()
+
+	if got, want := buf.String(), "LLM-as-Judge: The answer is 42.\ntumix: Final answer: 42\n"; got != want {
+		t.Fatalf("partialPrinter output = %q, want %q", got, want)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	b := &progressBar{w: &buf, width: 10}
+	b.ReportProgress(tumixrun.Progress{
+		Round:                  1,
+		MaxRounds:              3,
+		Elapsed:                2400 * time.Millisecond,
+		Remaining:              4800 * time.Millisecond,
+		Cost:                   0.0125,
+		EstimatedRemainingCost: 0.025,
+	})
+	b.ReportProgress(tumixrun.Progress{Round: 3, MaxRounds: 3, Elapsed: 7 * time.Second})
+
+	want := "[###-------] round 1/3, elapsed 2s, eta 5s, cost $0.0125 (+$0.0250 est.)\n" +
+		"[##########] round 3/3, elapsed 7s, eta 0s, cost $0.0000 (+$0.0000 est.)\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("progressBar output = %q, want %q", got, want)
+	}
+}
+
+func TestRunBatchPrompts(t *testing.T) {
+	orig := batchRetryBackoff
+	batchRetryBackoff = 0
+	t.Cleanup(func() { batchRetryBackoff = orig })
+
+	errBoom := errors.New("boom")
+	tests := map[string]struct {
+		cfg      config
+		failures map[string]int // prompt -> attempts that fail before it succeeds; -1 always fails
+		panics   map[string]bool
+		want     map[string]batchStatus
+		attempts map[string]int
+		exit     int
+	}{
+		"all succeed": {
+			cfg:  config{Concurrency: 2},
+			want: map[string]batchStatus{"a": batchOK, "b": batchOK, "c": batchOK},
+			exit: 0,
+		},
+		"retry recovers": {
+			cfg:      config{Concurrency: 1, BatchMaxRetries: 2},
+			failures: map[string]int{"b": 2},
+			want:     map[string]batchStatus{"a": batchOK, "b": batchOK, "c": batchOK},
+			attempts: map[string]int{"b": 3},
+			exit:     0,
+		},
+		"continue on error": {
+			cfg:      config{Concurrency: 1, BatchMaxRetries: 1, BatchContinue: true},
+			failures: map[string]int{"b": -1},
+			want:     map[string]batchStatus{"a": batchOK, "b": batchFailed, "c": batchOK},
+			attempts: map[string]int{"b": 2},
+			exit:     exitPartialFailure,
 		},
+		"abort skips the rest": {
+			cfg:      config{Concurrency: 1},
+			failures: map[string]int{"a": -1},
+			want:     map[string]batchStatus{"a": batchFailed, "b": batchSkipped, "c": batchSkipped},
+			exit:     1,
+		},
+		"panic fails the prompt": {
+			cfg:    config{Concurrency: 2, BatchContinue: true},
+			panics: map[string]bool{"b": true},
+			want:   map[string]batchStatus{"a": batchOK, "b": batchFailed, "c": batchOK},
+			exit:   exitPartialFailure,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := map[string]int{}
+			run := func(_ context.Context, local *config) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls[local.Prompt]++
+				if tt.panics[local.Prompt] {
+					panic("boom")
+				}
+				if n, ok := tt.failures[local.Prompt]; ok && (n < 0 || calls[local.Prompt] <= n) {
+					return errBoom
+				}
+				return nil
+			}
+
+			report := runBatchPrompts(t.Context(), &tt.cfg, []string{"a", "b", "c"}, run)
+			for _, res := range report.Results {
+				if res.Status != tt.want[res.Prompt] {
+					t.Fatalf("prompt %q status = %s (%s), want %s", res.Prompt, res.Status, res.Error, tt.want[res.Prompt])
+				}
+				if want, ok := tt.attempts[res.Prompt]; ok && res.Attempts != want {
+					t.Fatalf("prompt %q attempts = %d, want %d", res.Prompt, res.Attempts, want)
+				}
+			}
+			if got := report.exitCode(); got != tt.exit {
+				t.Fatalf("exitCode() = %d, want %d (report %+v)", got, tt.exit, report)
+			}
+		})
 	}
-	in, out := recordUsage(t.Context(), event)
-	if in != 10 || out != 5 {
-		t.Fatalf("recordUsage returned %d,%d want 10,5", in, out)
+}
+
+func TestRunBatchPromptsCancelsInFlight(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	run := func(ctx context.Context, local *config) error {
+		if local.Prompt == "a" {
+			<-started
+			return errors.New("boom")
+		}
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
 	}
-	if expRequests.Value() != 1 || expInputTokens.Value() != 10 || expOutputTokens.Value() != 5 {
-		t.Fatalf("expvars not updated: req=%d in=%d out=%d", expRequests.Value(), expInputTokens.Value(), expOutputTokens.Value())
+
+	report := runBatchPrompts(t.Context(), &config{Concurrency: 2}, []string{"a", "b"}, run)
+	want := []batchStatus{batchFailed, batchSkipped}
+	for i, res := range report.Results {
+		if res.Status != want[i] {
+			t.Fatalf("prompt %q status = %s (%s), want %s", res.Prompt, res.Status, res.Error, want[i])
+		}
+	}
+}
+
+func TestRunBatchPromptsFreshSessions(t *testing.T) {
+	orig := batchRetryBackoff
+	batchRetryBackoff = 0
+	t.Cleanup(func() { batchRetryBackoff = orig })
+
+	var (
+		mu       sync.Mutex
+		sessions = map[string][]string{}
+	)
+	cfg := config{Concurrency: 2, BatchMaxRetries: 1, SessionID: "s"}
+	run := func(_ context.Context, local *config) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sessions[local.Prompt] = append(sessions[local.Prompt], local.SessionID)
+		if local.Prompt == "b" && len(sessions["b"]) == 1 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	report := runBatchPrompts(t.Context(), &cfg, []string{"a", "b", "c"}, run)
+	if report.Succeeded != 3 {
+		t.Fatalf("report = %+v, want 3 succeeded", report)
+	}
+	want := map[string][]string{"a": {"s-0-1"}, "b": {"s-1-1", "s-1-2"}, "c": {"s-2-1"}}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("session IDs = %v, want %v", sessions, want)
+	}
+}
+
+func TestRunBatchPromptsCalibration(t *testing.T) {
+	t.Parallel()
+
+	answers := map[string]tumixrun.Result{
+		"q1": {Answer: "<<<42>>>", Confidence: 0.9},
+		"q2": {Answer: "8", Confidence: 0.6},
+		"q3": {Text: "free-form", Confidence: 0.8},
+	}
+	run := func(_ context.Context, local *config) error {
+		res, ok := answers[local.Prompt]
+		if !ok {
+			return errors.New("boom")
+		}
+		local.onResult(&res)
+		return nil
+	}
+
+	cfg := config{Concurrency: 2, BatchContinue: true}
+	report := runBatchPrompts(t.Context(), &cfg, []string{"q1\t42", "q2\t7", "q3", "q4\t1"}, run)
+
+	one, zero := 1.0, 0.0
+	wantResults := []batchResult{
+		{Index: 0, Prompt: "q1", Want: "42", Status: batchOK, Attempts: 1, Answer: "<<<42>>>", Confidence: 0.9, Score: &one},
+		{Index: 1, Prompt: "q2", Want: "7", Status: batchOK, Attempts: 1, Answer: "8", Confidence: 0.6, Score: &zero},
+		{Index: 2, Prompt: "q3", Status: batchOK, Attempts: 1, Answer: "free-form", Confidence: 0.8},
+		{Index: 3, Prompt: "q4", Want: "1", Status: batchFailed, Attempts: 1, Error: "boom"},
+	}
+	if diff := cmp.Diff(wantResults, report.Results); diff != "" {
+		t.Fatalf("results mismatch (-want +got):\n%s", diff)
+	}
+
+	// Brier: (0.1² + 0.6²) / 2 = 0.185; each run is alone in its bin, so the ECE is the mean gap.
+	want := &experiment.Calibration{
+		Runs:  2,
+		Brier: 0.185,
+		ECE:   0.35,
+		Bins: []experiment.CalibrationBin{
+			{Low: 0.6, High: 0.7, Runs: 1, MeanConfidence: 0.6, Accuracy: 0},
+			{Low: 0.9, High: 1, Runs: 1, MeanConfidence: 0.9, Accuracy: 1},
+		},
+	}
+	if diff := cmp.Diff(want, report.Calibration, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Fatalf("calibration mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWorkerLoaders(t *testing.T) {
+	t.Parallel()
+
+	var built int
+	r := newReloader(&config{Mode: "tumix"}, adkagent.NewSingleLoader(nil), nil, func(*config) (adkagent.Loader, error) {
+		built++
+		return adkagent.NewSingleLoader(nil), nil
+	})
+	workers := &workerLoaders{reloading: r, loaders: make([]workerLoader, 2)}
+
+	first, err := workers.get(0)
+	if err != nil {
+		t.Fatalf("get(0) error = %v", err)
+	}
+	if again, _ := workers.get(0); again != first {
+		t.Fatal("get(0) rebuilt the loader without a reload")
+	}
+	second, _ := workers.get(1)
+	if second == first || built != 2 {
+		t.Fatalf("workers share a loader or built %d loaders, want 2 distinct", built)
+	}
+
+	// A reload swaps the current configuration; every worker rebuilds on its next prompt.
+	r.current.Store(&reloadState{cfg: config{Mode: "debate"}})
+	if reloaded, _ := workers.get(0); reloaded == first || built != 3 {
+		t.Fatalf("get(0) after a reload kept the old loader or built %d loaders, want 3", built)
+	}
+}
+
+func TestAIMDWindow(t *testing.T) {
+	type outcome struct {
+		err     error
+		latency time.Duration
+	}
+	overloaded := genai.APIError{Code: http.StatusTooManyRequests}
+	invalid := genai.APIError{Code: http.StatusBadRequest}
+	tests := map[string]struct {
+		initial, max int
+		outcomes     []outcome
+		want         int
+	}{
+		"additive increase": {
+			initial:  2,
+			max:      8,
+			outcomes: []outcome{{latency: time.Second}, {latency: time.Second}, {latency: time.Second}, {latency: time.Second}},
+			want:     3,
+		},
+		"capped at max": {
+			initial:  2,
+			max:      2,
+			outcomes: []outcome{{latency: time.Second}, {latency: time.Second}, {latency: time.Second}},
+			want:     2,
+		},
+		"overload halves": {
+			initial:  8,
+			max:      8,
+			outcomes: []outcome{{err: overloaded, latency: time.Second}},
+			want:     4,
+		},
+		"never below one": {
+			initial:  2,
+			max:      8,
+			outcomes: []outcome{{err: overloaded}, {err: overloaded}, {err: overloaded}},
+			want:     1,
+		},
+		"slow call halves": {
+			initial:  6,
+			max:      8,
+			outcomes: []outcome{{latency: time.Second}, {latency: 10 * time.Second}},
+			want:     3,
+		},
+		"other failures leave the window": {
+			initial:  4,
+			max:      8,
+			outcomes: []outcome{{err: invalid, latency: time.Millisecond}, {err: errors.New("bad prompt"), latency: time.Millisecond}},
+			want:     4,
+		},
+		"fast failure sets no baseline": {
+			initial:  4,
+			max:      8,
+			outcomes: []outcome{{err: invalid, latency: time.Millisecond}, {latency: time.Second}, {latency: time.Second}},
+			want:     4,
+		},
+		"baseline follows slower prompts": {
+			initial: 4,
+			max:     4,
+			outcomes: []outcome{
+				{latency: 100 * time.Millisecond},
+				{latency: 350 * time.Millisecond}, {latency: 350 * time.Millisecond},
+				{latency: 700 * time.Millisecond}, {latency: 700 * time.Millisecond}, {latency: 700 * time.Millisecond},
+				{latency: 700 * time.Millisecond}, {latency: 700 * time.Millisecond}, {latency: 700 * time.Millisecond},
+				{latency: 700 * time.Millisecond}, {latency: 700 * time.Millisecond}, {latency: 700 * time.Millisecond},
+			},
+			want: 4,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := newAIMDWindow(tt.initial, tt.max)
+			for _, o := range tt.outcomes {
+				if err := w.acquire(t.Context()); err != nil {
+					t.Fatalf("acquire() error = %v", err)
+				}
+				w.release(t.Context(), o.err, o.latency)
+			}
+			if got := w.size(); got != tt.want {
+				t.Fatalf("size() = %d, want %d", got, tt.want)
+			}
+			if got := expBatchWindow.Value(); got != int64(tt.want) {
+				t.Fatalf("tumix_batch_concurrency = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAIMDWindowAcquireBlocks(t *testing.T) {
+	w := newAIMDWindow(1, 4)
+	if err := w.acquire(t.Context()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire() on a full window error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.acquire(t.Context()) }()
+	w.release(t.Context(), nil, time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+}
+
+func TestCandidateBuilders(t *testing.T) {
+	t.Parallel()
+
+	image := []*genai.Part{{InlineData: &genai.Blob{MIMEType: "image/png", Data: []byte("png")}}}
+	funcPtr := func(fn any) uintptr { return reflect.ValueOf(fn).Pointer() }
+
+	tests := map[string]struct {
+		caps        gollm.Capabilities
+		attachments []*genai.Part
+		wantNative  bool
+		wantVision  bool
+	}{
+		"gemini with image": {
+			caps:        gollm.ProviderCapabilities(gollm.ProviderGemini, "gemini-2.5-flash"),
+			attachments: image,
+			wantNative:  true,
+			wantVision:  true,
+		},
+		"gemini without image": {
+			caps:       gollm.ProviderCapabilities(gollm.ProviderGemini, "gemini-2.5-flash"),
+			wantNative: true,
+		},
+		"anthropic with image": {
+			caps:        gollm.ProviderCapabilities(gollm.ProviderAnthropic, "claude-sonnet-4-5"),
+			attachments: image,
+			wantVision:  true,
+		},
+		"text-only model with image": {
+			caps:        gollm.ProviderCapabilities(gollm.ProviderXAI, "grok-3"),
+			attachments: image,
+			wantNative:  true,
+		},
+		"unknown capabilities": {
+			attachments: image,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &config{Prompt: "What is the peak value?", attachments: tt.attachments}
+			builders := candidateBuilders(cfg, tt.caps)
+
+			want := 12
+			if tt.wantVision {
+				want++
+			}
+			if len(builders) != want {
+				t.Fatalf("candidateBuilders() returned %d builders, want %d", len(builders), want)
+			}
+			if got := funcPtr(builders[3]) == funcPtr(tumixagent.NewNativeSearchAgent); got != tt.wantNative {
+				t.Fatalf("native search agent = %t, want %t", got, tt.wantNative)
+			}
+			if got := funcPtr(builders[4]) == funcPtr(tumixagent.NewNativeCodeAgent); got != tt.wantNative {
+				t.Fatalf("native code agent = %t, want %t", got, tt.wantNative)
+			}
+			if got := funcPtr(builders[len(builders)-1]) == funcPtr(tumixagent.NewVisionAgent); got != tt.wantVision {
+				t.Fatalf("vision agent = %t, want %t", got, tt.wantVision)
+			}
+		})
+	}
+}
+
+func TestModelCapabilities(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config{LLMBackend: "openai", ModelName: "gpt-5"}
+	caps := gollm.Capabilities{Tools: true}
+	if got := modelCapabilities(gollm.WithCapabilities(nil, caps), cfg); got != caps {
+		t.Fatalf("modelCapabilities(reporter) = %+v, want %+v", got, caps)
+	}
+	if got, want := modelCapabilities(nil, cfg), gollm.ProviderCapabilities("openai", "gpt-5"); got != want {
+		t.Fatalf("modelCapabilities(nil) = %+v, want %+v", got, want)
+	}
+}
+
+func TestPromptTokenLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		maxPromptTokens int
+		contextWindow   int
+		wantLimit       int
+		wantSource      string
+	}{
+		"none":                      {wantSource: "max_prompt_tokens"},
+		"max_prompt_tokens only":    {maxPromptTokens: 100, wantLimit: 100, wantSource: "max_prompt_tokens"},
+		"context window only":       {contextWindow: 1000, wantLimit: 1000, wantSource: "the model context window"},
+		"smaller max_prompt_tokens": {maxPromptTokens: 100, contextWindow: 1000, wantLimit: 100, wantSource: "max_prompt_tokens"},
+		"smaller context window":    {maxPromptTokens: 5000, contextWindow: 1000, wantLimit: 1000, wantSource: "the model context window"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &config{MaxPromptTokens: tt.maxPromptTokens, contextWindow: tt.contextWindow}
+			limit, source := promptTokenLimit(cfg)
+			if limit != tt.wantLimit || source != tt.wantSource {
+				t.Fatalf("promptTokenLimit() = %d, %q, want %d, %q", limit, source, tt.wantLimit, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestEnforcePromptTokensWithCounterContextWindow(t *testing.T) {
+	cfg := config{Prompt: "hello world", ModelName: "m", contextWindow: 8}
+	counter := func(ctx context.Context, model string, contents []*genai.Content, config *genai.CountTokensConfig) (*genai.CountTokensResponse, error) {
+		return &genai.CountTokensResponse{TotalTokens: 10}, nil
+	}
+	err := enforcePromptTokensWithCounter(t.Context(), &cfg, counter)
+	if err == nil || !strings.Contains(err.Error(), "context window") {
+		t.Fatalf("enforcePromptTokensWithCounter() error = %v, want context window error", err)
+	}
+}
+
+func TestWriteExplanation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		res  *tumixrun.Result
+		want string
+	}{
+		"judged": {
+			res: &tumixrun.Result{
+				Answer:     "42",
+				Confidence: 0.9,
+				Rounds: []tumixagent.RoundStats{
+					{Round: 1, Unique: 3, TopAnswer: "41", VoteMargin: 0.4, Coverage: 1},
+					{Round: 2, Unique: 2, TopAnswer: "42", VoteMargin: 0.75, Coverage: 1},
+				},
+				CandidateScores: []tumixagent.CandidateScore{
+					{Agent: "CoT", Correctness: 0.5, Reasoning: 0.5},
+					{Agent: "Code", Correctness: 0.9, Reasoning: 0.8},
+				},
+				JudgeRationale: &tumixagent.JudgeRationale{Round: 2, Text: "Code ran the product.\nCoT slipped a digit. <<<YES>>>"},
+			},
+			want: "Why this answer: 42 (confidence 0.90)\n" +
+				"Round 2 vote: \"42\" got 75% of the answers (2 distinct, 100% of the candidates answered).\n" +
+				"Judge's scores of the candidates:\n" +
+				"  - Code: correctness 0.90, reasoning 0.80\n" +
+				"  - CoT: correctness 0.50, reasoning 0.50\n" +
+				"Judge's analysis (round 2):\n" +
+				"  Code ran the product.\n" +
+				"  CoT slipped a digit. <<<YES>>>\n",
+		},
+		"provisional without judge": {
+			res: &tumixrun.Result{Text: "Provisional answer (interrupted): 7", Provisional: true},
+			want: "Why this answer: Provisional answer (interrupted): 7\n" +
+				"The run was interrupted; the answer is provisional, salvaged from the rounds run so far.\n" +
+				"The Judge wrote no analysis; the answer comes from the candidates' vote.\n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf strings.Builder
+			if err := writeExplanation(&buf, tt.res); err != nil {
+				t.Fatalf("writeExplanation() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("writeExplanation() = %q, want %q", got, tt.want)
+			}
+		})
 	}
 }
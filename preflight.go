@@ -0,0 +1,255 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/openai/openai-go/v3"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/zchee/tumix/gollm"
+	"github.com/zchee/tumix/log"
+)
+
+// preflightTimeout bounds the checks of each backend of -preflight.
+const preflightTimeout = 30 * time.Second
+
+// preflightPrompt is the sanity completion of -preflight, capped at a single output token.
+const preflightPrompt = "Reply with OK."
+
+// preflightTarget is a backend and model the run calls, checked by -preflight.
+type preflightTarget struct {
+	role    string // model, judge, or failover
+	backend string
+	model   string
+	apiKey  string
+}
+
+func (t preflightTarget) String() string {
+	return t.role + " " + t.backend + ":" + t.model
+}
+
+// preflightReport is the outcome of the checks of one target, with the latency of each passed step.
+type preflightReport struct {
+	target     preflightTarget
+	connect    time.Duration
+	completion time.Duration
+	tokenizer  time.Duration
+	// tokenized reports whether the backend has a tokenizer to load.
+	tokenized bool
+	err       error
+}
+
+// preflighter runs the checks of -preflight. The functions are replaced in tests.
+type preflighter struct {
+	dial     func(ctx context.Context, addr string) error
+	newModel func(ctx context.Context, t preflightTarget) (model.LLM, error)
+	// tokenizer counts the tokens of contents with the tokenizer of t, reporting false when the backend has none.
+	tokenizer func(ctx context.Context, t preflightTarget, contents []*genai.Content) (bool, error)
+}
+
+// newPreflighter returns the preflighter of the backends of cfg, sharing httpClient with the run.
+func newPreflighter(cfg *config, httpClient *http.Client) *preflighter {
+	return &preflighter{
+		dial: func(ctx context.Context, addr string) error {
+			return dialCheck(addr)(ctx)
+		},
+		newModel: func(ctx context.Context, t preflightTarget) (model.LLM, error) {
+			return newBackendModel(ctx, cfg, t.backend, t.model, t.apiKey, httpClient)
+		},
+		tokenizer: func(ctx context.Context, t preflightTarget, contents []*genai.Content) (bool, error) {
+			switch t.backend {
+			case gollm.ProviderGemini, gollm.ProviderOpenAI, gollm.ProviderXAI:
+			default:
+				return false, nil
+			}
+			m, err := gollm.NewModel(ctx, t.backend, t.apiKey, t.model, gollm.WithHTTPClient(httpClient))
+			if err != nil {
+				return true, err
+			}
+			_, err = m.CountTokens(ctx, contents)
+			return true, err
+		},
+	}
+}
+
+// runPreflight checks every backend of cfg concurrently, logs the latency of each, and returns the failures.
+func runPreflight(ctx context.Context, cfg *config, httpClient *http.Client) error {
+	targets, err := preflightTargets(cfg)
+	if err != nil {
+		return err
+	}
+	reports := newPreflighter(cfg, httpClient).run(ctx, targets)
+
+	var errs []error
+	for _, r := range reports {
+		attrs := []any{"role", r.target.role, "backend", r.target.backend, "model", r.target.model}
+		if r.err != nil {
+			log.Error(ctx, "preflight check failed", r.err, attrs...)
+			errs = append(errs, r.err)
+			continue
+		}
+		attrs = append(attrs, "connect", r.connect, "completion", r.completion)
+		if r.tokenized {
+			attrs = append(attrs, "tokenizer", r.tokenizer)
+		}
+		log.Info(ctx, "preflight check passed", attrs...)
+	}
+	return errors.Join(errs...)
+}
+
+// preflightTargets returns the distinct backends and models of cfg: the candidate model, the judge model, and the
+// failover targets.
+func preflightTargets(cfg *config) ([]preflightTarget, error) {
+	targets := []preflightTarget{{role: "model", backend: cfg.LLMBackend, model: cfg.ModelName, apiKey: cfg.APIKey}}
+	if name := judgeModelName(cfg); name != cfg.ModelName {
+		targets = append(targets, preflightTarget{role: "judge", backend: cfg.LLMBackend, model: name, apiKey: cfg.APIKey})
+	}
+	failoverTargets, err := parseFailover(cfg.Failover)
+	if err != nil {
+		return nil, err
+	}
+	for _, ft := range failoverTargets {
+		if slices.ContainsFunc(targets, func(t preflightTarget) bool { return t.backend == ft.backend && t.model == ft.modelName }) {
+			continue
+		}
+		targets = append(targets, preflightTarget{role: "failover", backend: ft.backend, model: ft.modelName, apiKey: backendAPIKey(ft.backend)})
+	}
+	return targets, nil
+}
+
+// run checks targets concurrently and returns their reports in the order of targets.
+func (p *preflighter) run(ctx context.Context, targets []preflightTarget) []preflightReport {
+	reports := make([]preflightReport, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+			defer cancel()
+			reports[i] = p.check(ctx, t)
+		})
+	}
+	wg.Wait()
+	return reports
+}
+
+// check verifies the API key of t, opens a connection to its API host, runs a 1-token completion, and loads its
+// tokenizer, stopping at the first failure.
+func (p *preflighter) check(ctx context.Context, t preflightTarget) preflightReport {
+	r := preflightReport{target: t}
+	if t.apiKey == "" {
+		env := backendAPIKeyEnv(t.backend)
+		if t.role != "failover" {
+			env += " or -api_key"
+		}
+		r.err = fmt.Errorf("%s: no API key; set %s", t, env)
+		return r
+	}
+
+	if host := backendHost(t.backend); host != "" {
+		start := time.Now()
+		if err := p.dial(ctx, host); err != nil {
+			r.err = fmt.Errorf("%s: connect: %w; check the network, proxy, and firewall settings", t, err)
+			return r
+		}
+		r.connect = time.Since(start)
+	}
+
+	llm, err := p.newModel(ctx, t)
+	if err != nil {
+		r.err = fmt.Errorf("%s: %w", t, err)
+		return r
+	}
+	contents := []*genai.Content{genai.NewContentFromText(preflightPrompt, genai.RoleUser)}
+	req := &model.LLMRequest{
+		Model:    t.model,
+		Contents: contents,
+		Config:   &genai.GenerateContentConfig{MaxOutputTokens: 1},
+	}
+	start := time.Now()
+	for _, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			r.err = fmt.Errorf("%s: sanity completion: %w%s", t, err, preflightHint(err))
+			return r
+		}
+	}
+	r.completion = time.Since(start)
+
+	start = time.Now()
+	tokenized, err := p.tokenizer(ctx, t, contents)
+	if err != nil {
+		r.err = fmt.Errorf("%s: tokenizer: %w%s", t, err, preflightHint(err))
+		return r
+	}
+	r.tokenized, r.tokenizer = tokenized, time.Since(start)
+	return r
+}
+
+// preflightHint returns the remedy of the common API errors of the backends, prefixed by "; ", or "".
+func preflightHint(err error) string {
+	switch apiStatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "; check that the API key is valid and may use the model"
+	case http.StatusNotFound:
+		return "; check the model name (-model, -judge_model, or -failover)"
+	case http.StatusTooManyRequests:
+		return "; the API key is rate limited or out of quota"
+	default:
+		return ""
+	}
+}
+
+// apiStatusCode returns the HTTP status code of an API error of the backends, or 0. gRPC status codes (xAI) are
+// mapped to their HTTP equivalents.
+func apiStatusCode(err error) int {
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unauthenticated:
+			return http.StatusUnauthorized
+		case codes.PermissionDenied:
+			return http.StatusForbidden
+		case codes.NotFound:
+			return http.StatusNotFound
+		case codes.ResourceExhausted:
+			return http.StatusTooManyRequests
+		}
+	}
+	var gerr genai.APIError
+	if errors.As(err, &gerr) {
+		return gerr.Code
+	}
+	var oerr *openai.Error
+	if errors.As(err, &oerr) {
+		return oerr.StatusCode
+	}
+	var aerr *anthropic.Error
+	if errors.As(err, &aerr) {
+		return aerr.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestPreflightTargets(t *testing.T) {
+	t.Setenv("XAI_API_KEY", "xk")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	tests := map[string]struct {
+		cfg  config
+		want []preflightTarget
+	}{
+		"model only": {
+			cfg:  config{LLMBackend: "gemini", ModelName: "gemini-2.5-flash", APIKey: "k"},
+			want: []preflightTarget{{role: "model", backend: "gemini", model: "gemini-2.5-flash", apiKey: "k"}},
+		},
+		"judge model": {
+			cfg: config{LLMBackend: "gemini", ModelName: "gemini-2.5-flash", JudgeModel: "gemini-2.5-pro", APIKey: "k"},
+			want: []preflightTarget{
+				{role: "model", backend: "gemini", model: "gemini-2.5-flash", apiKey: "k"},
+				{role: "judge", backend: "gemini", model: "gemini-2.5-pro", apiKey: "k"},
+			},
+		},
+		"failover": {
+			cfg: config{LLMBackend: "gemini", ModelName: "gemini-2.5-flash", APIKey: "k", Failover: "xai:grok-4,gemini:gemini-2.5-flash,openai:gpt-5"},
+			want: []preflightTarget{
+				{role: "model", backend: "gemini", model: "gemini-2.5-flash", apiKey: "k"},
+				{role: "failover", backend: "xai", model: "grok-4", apiKey: "xk"},
+				{role: "failover", backend: "openai", model: "gpt-5"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := preflightTargets(&tt.cfg)
+			if err != nil {
+				t.Fatalf("preflightTargets() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(preflightTarget{})); diff != "" {
+				t.Fatalf("preflightTargets() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// pingLLM answers every request with err, or "OK" without one, and records the requests.
+type pingLLM struct {
+	err  error
+	reqs []*model.LLMRequest
+}
+
+func (p *pingLLM) Name() string { return "ping" }
+
+func (p *pingLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		p.reqs = append(p.reqs, req)
+		if p.err != nil {
+			yield(nil, p.err)
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("OK", genai.RoleModel)}, nil)
+	}
+}
+
+func TestPreflighterCheck(t *testing.T) {
+	t.Parallel()
+
+	target := preflightTarget{role: "model", backend: "gemini", model: "gemini-2.5-flash", apiKey: "k"}
+	tests := map[string]struct {
+		target        preflightTarget
+		dialErr       error
+		llmErr        error
+		noTokenizer   bool
+		tokenizerErr  error
+		wantErr       string
+		wantTokenized bool
+	}{
+		"ok": {
+			target:        target,
+			wantTokenized: true,
+		},
+		"no tokenizer": {
+			target:      preflightTarget{role: "model", backend: "anthropic", model: "claude-sonnet-4-5", apiKey: "k"},
+			noTokenizer: true,
+		},
+		"missing api key": {
+			target:  preflightTarget{role: "model", backend: "gemini", model: "gemini-2.5-flash"},
+			wantErr: "model gemini:gemini-2.5-flash: no API key; set GOOGLE_API_KEY or -api_key",
+		},
+		"missing failover api key": {
+			target:  preflightTarget{role: "failover", backend: "xai", model: "grok-4"},
+			wantErr: "failover xai:grok-4: no API key; set XAI_API_KEY",
+		},
+		"unreachable": {
+			target:  target,
+			dialErr: errors.New("dial tcp: timeout"),
+			wantErr: "model gemini:gemini-2.5-flash: connect: dial tcp: timeout; check the network, proxy, and firewall settings",
+		},
+		"invalid api key": {
+			target:  target,
+			llmErr:  genai.APIError{Code: http.StatusUnauthorized, Message: "API key not valid"},
+			wantErr: "model gemini:gemini-2.5-flash: sanity completion: Error 401, Message: API key not valid, Status: , Details: []; check that the API key is valid and may use the model",
+		},
+		"unknown model": {
+			target:  target,
+			llmErr:  genai.APIError{Code: http.StatusNotFound, Message: "model not found"},
+			wantErr: "model gemini:gemini-2.5-flash: sanity completion: Error 404, Message: model not found, Status: , Details: []; check the model name (-model, -judge_model, or -failover)",
+		},
+		"tokenizer": {
+			target:       target,
+			tokenizerErr: errors.New("load encoding"),
+			wantErr:      "model gemini:gemini-2.5-flash: tokenizer: load encoding",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			llm := &pingLLM{err: tt.llmErr}
+			p := &preflighter{
+				dial: func(context.Context, string) error { return tt.dialErr },
+				newModel: func(context.Context, preflightTarget) (model.LLM, error) {
+					return llm, nil
+				},
+				tokenizer: func(context.Context, preflightTarget, []*genai.Content) (bool, error) {
+					return !tt.noTokenizer, tt.tokenizerErr
+				},
+			}
+
+			r := p.check(t.Context(), tt.target)
+			if tt.wantErr != "" {
+				if r.err == nil || r.err.Error() != tt.wantErr {
+					t.Fatalf("check() error = %v, want %q", r.err, tt.wantErr)
+				}
+				return
+			}
+			if r.err != nil {
+				t.Fatalf("check() error = %v", r.err)
+			}
+			if r.tokenized != tt.wantTokenized {
+				t.Fatalf("check() tokenized = %t, want %t", r.tokenized, tt.wantTokenized)
+			}
+			if len(llm.reqs) != 1 || llm.reqs[0].Config.MaxOutputTokens != 1 {
+				t.Fatalf("check() sent %d requests, want one capped at 1 output token", len(llm.reqs))
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pricing estimates the USD cost of LLM calls from per-model token rates.
+//
+// The built-in catalog holds the public list prices of the Gemini, Grok, OpenAI, and Claude models TUMIX runs
+// against. Prices change; override or extend them with [Catalog.LoadFile].
+package pricing
+
+import (
+	json "encoding/json/v2"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultFallback is the model whose price is used for models missing from a catalog.
+const DefaultFallback = "gemini-2.5-flash"
+
+// Price holds the USD rates of a model per 1K tokens.
+type Price struct {
+	InputPerKT  float64 `json:"in_per_kt"`
+	OutputPerKT float64 `json:"out_per_kt"`
+	// CachedPerKT is the rate of cached input tokens; zero bills them as regular input.
+	CachedPerKT float64 `json:"cached_in_per_kt,omitempty"`
+}
+
+// Usage is the token usage of one or more calls.
+type Usage struct {
+	InputTokens int
+	// CachedInputTokens is the part of InputTokens served from a prompt cache.
+	CachedInputTokens int
+	OutputTokens      int
+}
+
+// Cost returns the USD cost of u at p.
+func (p Price) Cost(u Usage) float64 {
+	cached := min(max(u.CachedInputTokens, 0), u.InputTokens)
+	cachedRate := p.CachedPerKT
+	if cachedRate == 0 {
+		cachedRate = p.InputPerKT
+	}
+	return float64(u.InputTokens-cached)/1000*p.InputPerKT +
+		float64(cached)/1000*cachedRate +
+		float64(u.OutputTokens)/1000*p.OutputPerKT
+}
+
+// perM converts per-1M-token list prices into a Price.
+func perM(in, out, cached float64) Price {
+	return Price{InputPerKT: in / 1000, OutputPerKT: out / 1000, CachedPerKT: cached / 1000}
+}
+
+// builtin is the list price catalog, in USD per 1M tokens for readability.
+var builtin = map[string]Price{
+	// Gemini
+	"gemini-2.5-pro":        perM(1.25, 10, 0.125),
+	"gemini-2.5-flash":      perM(0.30, 2.50, 0.03),
+	"gemini-2.5-flash-lite": perM(0.10, 0.40, 0.01),
+	"gemini-2.0-flash":      perM(0.10, 0.40, 0.025),
+	"gemini-2.0-flash-lite": perM(0.075, 0.30, 0),
+	"gemini-1.5-pro":        perM(1.00, 4.00, 0),
+	"gemini-1.5-flash":      perM(0.20, 0.80, 0),
+	"gemini-1.5-flash-8b":   perM(0.20, 0.80, 0),
+
+	// Grok
+	"grok-4":           perM(3.00, 15.00, 0.75),
+	"grok-4-fast":      perM(0.20, 0.50, 0.05),
+	"grok-3":           perM(3.00, 15.00, 0.75),
+	"grok-3-mini":      perM(0.30, 0.50, 0.075),
+	"grok-code-fast-1": perM(0.20, 1.50, 0.02),
+
+	// OpenAI
+	"gpt-5":        perM(1.25, 10.00, 0.125),
+	"gpt-5-mini":   perM(0.25, 2.00, 0.025),
+	"gpt-5-nano":   perM(0.05, 0.40, 0.005),
+	"gpt-4.1":      perM(2.00, 8.00, 0.50),
+	"gpt-4.1-mini": perM(0.40, 1.60, 0.10),
+	"gpt-4o":       perM(2.50, 10.00, 1.25),
+	"gpt-4o-mini":  perM(0.15, 0.60, 0.075),
+	"o4-mini":      perM(1.10, 4.40, 0.275),
+
+	// Claude
+	"claude-opus-4":    perM(15.00, 75.00, 1.50),
+	"claude-sonnet-4":  perM(3.00, 15.00, 0.30),
+	"claude-3-5-haiku": perM(0.80, 4.00, 0.08),
+}
+
+// Catalog maps model names to prices. It is safe for concurrent use.
+type Catalog struct {
+	mu       sync.RWMutex
+	prices   map[string]Price
+	fallback string
+}
+
+// Default returns a catalog of the built-in list prices falling back to [DefaultFallback].
+func Default() *Catalog {
+	return New(builtin, DefaultFallback)
+}
+
+// New returns a catalog of prices that prices unknown models as fallback.
+func New(prices map[string]Price, fallback string) *Catalog {
+	return &Catalog{prices: maps.Clone(prices), fallback: fallback}
+}
+
+// Set adds or replaces the price of model.
+func (c *Catalog) Set(model string, p Price) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.prices == nil {
+		c.prices = make(map[string]Price)
+	}
+	c.prices[model] = p
+}
+
+// Lookup returns the price of model.
+//
+// A "models/" prefix is ignored, and a versioned name such as "gemini-2.5-flash-002" or "grok-4-0709" matches the
+// longest catalog entry it extends.
+func (c *Catalog) Lookup(model string) (Price, bool) {
+	model = strings.TrimPrefix(model, "models/")
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if p, ok := c.prices[model]; ok {
+		return p, true
+	}
+	var best string
+	for name := range c.prices {
+		if len(name) > len(best) && strings.HasPrefix(model, name+"-") {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return c.prices[best], true
+}
+
+// Price returns the price of model, or the fallback price when model is unknown.
+func (c *Catalog) Price(model string) Price {
+	if p, ok := c.Lookup(model); ok {
+		return p
+	}
+	p, _ := c.Lookup(c.fallback)
+	return p
+}
+
+// Cost returns the estimated USD cost of u on model.
+func (c *Catalog) Cost(model string, u Usage) float64 {
+	return c.Price(model).Cost(u)
+}
+
+// LoadFile merges the prices of a JSON file into the catalog.
+//
+// The file maps model names to rates per 1K tokens:
+//
+//	{"grok-4": {"in_per_kt": 0.003, "out_per_kt": 0.015, "cached_in_per_kt": 0.00075}}
+func (c *Catalog) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read pricing file: %w", err)
+	}
+	var prices map[string]Price
+	if err := json.Unmarshal(b, &prices); err != nil {
+		return fmt.Errorf("parse pricing file %s: %w", path, err)
+	}
+	for model, p := range prices {
+		if p.InputPerKT < 0 || p.OutputPerKT < 0 || p.CachedPerKT < 0 {
+			return fmt.Errorf("pricing file %s: negative rate for %q", path, model)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.prices == nil {
+		c.prices = make(map[string]Price, len(prices))
+	}
+	maps.Copy(c.prices, prices)
+	return nil
+}
+
+// FormatUSD formats a dollar amount for display, e.g. "$12.34" or "$0.0042".
+//
+// Amounts under a dollar keep four decimals since per-call costs are often fractions of a cent.
+func FormatUSD(v float64) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	if v >= 1 {
+		return sign + "$" + groupThousands(strconv.FormatFloat(math.Round(v*100)/100, 'f', 2, 64))
+	}
+	return sign + "$" + strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+// groupThousands inserts commas into the integer part of a formatted decimal.
+func groupThousands(s string) string {
+	intPart, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String()
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pricing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCatalogLookup(t *testing.T) {
+	t.Parallel()
+
+	c := Default()
+	tests := map[string]struct {
+		model string
+		want  Price
+		ok    bool
+	}{
+		"exact":                 {model: "grok-4", want: builtin["grok-4"], ok: true},
+		"versioned":             {model: "grok-4-0709", want: builtin["grok-4"], ok: true},
+		"longest prefix":        {model: "grok-4-fast-reasoning", want: builtin["grok-4-fast"], ok: true},
+		"models prefix":         {model: "models/gemini-2.5-flash-002", want: builtin["gemini-2.5-flash"], ok: true},
+		"distinct lite variant": {model: "gemini-2.5-flash-lite", want: builtin["gemini-2.5-flash-lite"], ok: true},
+		"unknown":               {model: "llama-3", ok: false},
+		"no partial word match": {model: "gpt-5x", ok: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := c.Lookup(tt.model)
+			if ok != tt.ok {
+				t.Fatalf("Lookup(%q) ok = %t, want %t", tt.model, ok, tt.ok)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("Lookup(%q) mismatch (-want +got):\n%s", tt.model, diff)
+			}
+		})
+	}
+
+	if diff := cmp.Diff(builtin[DefaultFallback], c.Price("llama-3")); diff != "" {
+		t.Fatalf("Price() fallback mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	t.Parallel()
+
+	p := Price{InputPerKT: 0.003, OutputPerKT: 0.015, CachedPerKT: 0.00075}
+	tests := map[string]struct {
+		price Price
+		usage Usage
+		want  float64
+	}{
+		"input and output": {price: p, usage: Usage{InputTokens: 2000, OutputTokens: 1000}, want: 0.021},
+		"cached input":     {price: p, usage: Usage{InputTokens: 2000, CachedInputTokens: 1000, OutputTokens: 1000}, want: 0.01875},
+		"cached clamped":   {price: p, usage: Usage{InputTokens: 1000, CachedInputTokens: 5000}, want: 0.00075},
+		"no cached rate":   {price: Price{InputPerKT: 0.001}, usage: Usage{InputTokens: 1000, CachedInputTokens: 500}, want: 0.001},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.price.Cost(tt.usage); math.Abs(got-tt.want) > 1e-12 {
+				t.Fatalf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogLoadFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "pricing.json")
+	if err := os.WriteFile(good, []byte(`{"grok-4": {"in_per_kt": 0.001, "out_per_kt": 0.002}, "custom": {"in_per_kt": 1, "out_per_kt": 2}}`), 0o600); err != nil {
+		t.Fatalf("write pricing: %v", err)
+	}
+	bad := filepath.Join(dir, "negative.json")
+	if err := os.WriteFile(bad, []byte(`{"custom": {"in_per_kt": -1}}`), 0o600); err != nil {
+		t.Fatalf("write pricing: %v", err)
+	}
+
+	c := Default()
+	if err := c.LoadFile(good); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if diff := cmp.Diff(Price{InputPerKT: 0.001, OutputPerKT: 0.002}, c.Price("grok-4-0709")); diff != "" {
+		t.Fatalf("override mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := c.Lookup("custom"); !ok {
+		t.Fatal("custom model not loaded")
+	}
+	if _, ok := Default().Lookup("custom"); ok {
+		t.Fatal("LoadFile() mutated the built-in catalog")
+	}
+
+	if err := c.LoadFile(bad); err == nil {
+		t.Fatal("LoadFile() with negative rate error = nil, want error")
+	}
+	if err := c.LoadFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("LoadFile() with missing file error = nil, want error")
+	}
+}
+
+func TestFormatUSD(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		v    float64
+		want string
+	}{
+		"zero":      {v: 0, want: "$0.0000"},
+		"sub cent":  {v: 0.00421, want: "$0.0042"},
+		"dollars":   {v: 12.345, want: "$12.35"},
+		"thousands": {v: 1234567.891, want: "$1,234,567.89"},
+		"negative":  {v: -3.5, want: "-$3.50"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := FormatUSD(tt.v); got != tt.want {
+				t.Fatalf("FormatUSD(%v) = %q, want %q", tt.v, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package quota enforces per-user limits on TUMIX runs: requests and tokens per day, and concurrent runs.
+//
+// Daily usage is kept in a [Store] keyed on the user ID, either in memory or in a file shared by every process using
+// the same directory. Days are UTC days. A rejected run fails with an [ExceededError], the equivalent of an HTTP 429
+// response.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limit names a quota limit.
+type Limit string
+
+// Limits enforced by a [Limiter].
+const (
+	LimitRequests   Limit = "requests_per_day"
+	LimitTokens     Limit = "tokens_per_day"
+	LimitConcurrent Limit = "max_concurrent"
+)
+
+// ExceededError reports a run rejected because the user reached a limit.
+type ExceededError struct {
+	User  string
+	Limit Limit
+	// Max is the limit and Used the usage of the user when the run was rejected.
+	Max  int64
+	Used int64
+	// RetryAfter is how long until the limit resets: the end of the UTC day for the daily limits, zero for
+	// [LimitConcurrent].
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *ExceededError) Error() string {
+	msg := fmt.Sprintf("quota: user %q exceeded %s (%d/%d)", e.User, e.Limit, e.Used, e.Max)
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf("; retry after %s", e.RetryAfter.Round(time.Second))
+	}
+	return msg
+}
+
+// StatusCode returns [http.StatusTooManyRequests].
+func (e *ExceededError) StatusCode() int { return http.StatusTooManyRequests }
+
+// Limits are the per-user limits. Zero disables a limit.
+type Limits struct {
+	RequestsPerDay int64
+	// TokensPerDay is checked before a run; the run that crosses it completes, and later runs of the day are
+	// rejected.
+	TokensPerDay  int64
+	MaxConcurrent int
+}
+
+// Enabled reports whether any limit is set.
+func (l Limits) Enabled() bool {
+	return l.RequestsPerDay > 0 || l.TokensPerDay > 0 || l.MaxConcurrent > 0
+}
+
+// Usage is the usage of a user on Day.
+type Usage struct {
+	// Day is the UTC day in time.DateOnly format.
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// Store persists the daily usage of every user.
+type Store interface {
+	// Update applies fn to the usage of user and stores the result unless fn fails, atomically with respect to other
+	// updates.
+	Update(ctx context.Context, user string, fn func(*Usage) error) error
+}
+
+// Option configures a [Limiter].
+type Option func(*Limiter)
+
+// WithClock sets the clock of the limiter, time.Now by default.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) { l.now = now }
+}
+
+// WithOnReject sets a function called with every rejection, e.g. to count them in metrics.
+func WithOnReject(fn func(context.Context, *ExceededError)) Option {
+	return func(l *Limiter) { l.onReject = fn }
+}
+
+// Limiter admits runs of users within their [Limits].
+type Limiter struct {
+	limits   Limits
+	store    Store
+	now      func() time.Time
+	onReject func(context.Context, *ExceededError)
+
+	mu     sync.Mutex
+	active map[string]int
+}
+
+// New returns a limiter enforcing limits with the usage kept in store.
+func New(limits Limits, store Store, opts ...Option) *Limiter {
+	l := &Limiter{
+		limits: limits,
+		store:  store,
+		now:    time.Now,
+		active: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Acquire admits a run of user and counts it toward the requests of the day.
+//
+// The returned release must be called once the run ends with the tokens it used. Concurrent runs are only counted
+// within this process.
+func (l *Limiter) Acquire(ctx context.Context, user string) (release func(ctx context.Context, tokens int64) error, err error) {
+	if err := l.enter(user); err != nil {
+		return nil, l.reject(ctx, err)
+	}
+
+	now := l.now().UTC()
+	err = l.store.Update(ctx, user, func(u *Usage) error {
+		resetDay(u, now)
+		switch {
+		case l.limits.RequestsPerDay > 0 && u.Requests >= l.limits.RequestsPerDay:
+			return l.exceeded(user, LimitRequests, l.limits.RequestsPerDay, u.Requests, now)
+		case l.limits.TokensPerDay > 0 && u.Tokens >= l.limits.TokensPerDay:
+			return l.exceeded(user, LimitTokens, l.limits.TokensPerDay, u.Tokens, now)
+		}
+		u.Requests++
+		return nil
+	})
+	if err != nil {
+		l.leave(user)
+		return nil, l.reject(ctx, err)
+	}
+
+	var once sync.Once
+	return func(ctx context.Context, tokens int64) error {
+		var err error
+		once.Do(func() {
+			l.leave(user)
+			if tokens <= 0 {
+				return
+			}
+			now := l.now().UTC()
+			err = l.store.Update(ctx, user, func(u *Usage) error {
+				resetDay(u, now)
+				u.Tokens += tokens
+				return nil
+			})
+		})
+		return err
+	}, nil
+}
+
+// enter counts a run of user toward its concurrent runs.
+func (l *Limiter) enter(user string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n := l.active[user]; l.limits.MaxConcurrent > 0 && n >= l.limits.MaxConcurrent {
+		return &ExceededError{User: user, Limit: LimitConcurrent, Max: int64(l.limits.MaxConcurrent), Used: int64(n)}
+	}
+	l.active[user]++
+	return nil
+}
+
+func (l *Limiter) leave(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[user]--; l.active[user] <= 0 {
+		delete(l.active, user)
+	}
+}
+
+func (l *Limiter) exceeded(user string, limit Limit, maxUsage, used int64, now time.Time) *ExceededError {
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return &ExceededError{User: user, Limit: limit, Max: maxUsage, Used: used, RetryAfter: midnight.Sub(now)}
+}
+
+// reject reports err to the onReject function when it is an [ExceededError].
+func (l *Limiter) reject(ctx context.Context, err error) error {
+	var e *ExceededError
+	if errors.As(err, &e) && l.onReject != nil {
+		l.onReject(ctx, e)
+	}
+	return err
+}
+
+// resetDay starts a new day of usage when u is of an earlier day than now.
+func resetDay(u *Usage, now time.Time) {
+	if day := now.Format(time.DateOnly); u.Day != day {
+		*u = Usage{Day: day}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package quota
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLimiterAcquire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		limits  Limits
+		usage   Usage
+		active  int
+		wantErr *ExceededError
+	}{
+		"within limits": {
+			limits: Limits{RequestsPerDay: 2, TokensPerDay: 100, MaxConcurrent: 2},
+			usage:  Usage{Day: "2025-06-01", Requests: 1, Tokens: 99},
+			active: 1,
+		},
+		"requests per day": {
+			limits:  Limits{RequestsPerDay: 2},
+			usage:   Usage{Day: "2025-06-01", Requests: 2},
+			wantErr: &ExceededError{User: "alice", Limit: LimitRequests, Max: 2, Used: 2, RetryAfter: 6 * time.Hour},
+		},
+		"tokens per day": {
+			limits:  Limits{TokensPerDay: 100},
+			usage:   Usage{Day: "2025-06-01", Tokens: 120},
+			wantErr: &ExceededError{User: "alice", Limit: LimitTokens, Max: 100, Used: 120, RetryAfter: 6 * time.Hour},
+		},
+		"usage of an earlier day is reset": {
+			limits: Limits{RequestsPerDay: 2, TokensPerDay: 100},
+			usage:  Usage{Day: "2025-05-31", Requests: 5, Tokens: 500},
+		},
+		"concurrent runs": {
+			limits:  Limits{MaxConcurrent: 1},
+			active:  1,
+			wantErr: &ExceededError{User: "alice", Limit: LimitConcurrent, Max: 1, Used: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := NewMemoryStore()
+			store.usage["alice"] = tt.usage
+			var rejected []*ExceededError
+			l := New(tt.limits, store,
+				WithClock(func() time.Time { return now }),
+				WithOnReject(func(_ context.Context, e *ExceededError) { rejected = append(rejected, e) }))
+			for range tt.active {
+				if _, err := l.Acquire(t.Context(), "alice"); err != nil {
+					t.Fatalf("Acquire() of an active run error = %v", err)
+				}
+			}
+			store.usage["alice"] = tt.usage
+
+			_, err := l.Acquire(t.Context(), "alice")
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Acquire() error = %v", err)
+				}
+				return
+			}
+			var got *ExceededError
+			if !errors.As(err, &got) {
+				t.Fatalf("Acquire() error = %v, want an ExceededError", err)
+			}
+			if diff := cmp.Diff(tt.wantErr, got); diff != "" {
+				t.Fatalf("ExceededError mismatch (-want +got):\n%s", diff)
+			}
+			if got.StatusCode() != http.StatusTooManyRequests {
+				t.Fatalf("StatusCode() = %d, want %d", got.StatusCode(), http.StatusTooManyRequests)
+			}
+			if len(rejected) != 1 {
+				t.Fatalf("onReject calls = %d, want 1", len(rejected))
+			}
+		})
+	}
+}
+
+func TestLimiterRelease(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	now := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	l := New(Limits{TokensPerDay: 100, MaxConcurrent: 1}, store, WithClock(func() time.Time { return now }))
+
+	release, err := l.Acquire(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if err := release(t.Context(), 150); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	// A second release must neither free another slot nor count the tokens twice.
+	if err := release(t.Context(), 150); err != nil {
+		t.Fatalf("second release() error = %v", err)
+	}
+
+	// The file store shares the usage with a limiter of another process.
+	other := New(Limits{TokensPerDay: 100}, store, WithClock(func() time.Time { return now }))
+	_, err = other.Acquire(t.Context(), "alice")
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != LimitTokens || exceeded.Used != 150 {
+		t.Fatalf("Acquire() after release error = %v, want tokens_per_day exceeded with 150 used", err)
+	}
+	if _, err := other.Acquire(t.Context(), "bob"); err != nil {
+		t.Fatalf("Acquire() of another user error = %v", err)
+	}
+	var got Usage
+	if err := store.Update(t.Context(), "alice", func(u *Usage) error { got = *u; return nil }); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if diff := cmp.Diff(Usage{Day: "2025-06-01", Requests: 1, Tokens: 150}, got); diff != "" {
+		t.Fatalf("stored usage mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package quota
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/zchee/tumix/internal/storage"
+)
+
+// MemoryStore is a [Store] kept in memory, lost when the process exits.
+type MemoryStore struct {
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty [MemoryStore].
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usage: make(map[string]Usage)}
+}
+
+// Update implements [Store].
+func (s *MemoryStore) Update(_ context.Context, user string, fn func(*Usage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.usage[user]
+	if err := fn(&u); err != nil {
+		return err
+	}
+	s.usage[user] = u
+	return nil
+}
+
+// FileStore is a [Store] kept in the quota.json file of a directory, next to the sessions of -session_dir.
+//
+// Every update holds an exclusive lock on quota.lock, so processes sharing the directory share the usage.
+type FileStore struct {
+	dir *storage.Dir
+	mu  sync.Mutex
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore returns a [FileStore] in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	d, err := storage.OpenDir(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("quota: %w", err)
+	}
+	return &FileStore{dir: d}, nil
+}
+
+// Update implements [Store].
+func (s *FileStore) Update(_ context.Context, user string, fn func(*Usage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, err := s.dir.Lock("quota.lock")
+	if err != nil {
+		return fmt.Errorf("quota: %w", err)
+	}
+	defer lock.Unlock() //nolint:errcheck // released on close anyway
+
+	usage, err := s.load()
+	if err != nil {
+		return err
+	}
+	u := usage[user]
+	if err := fn(&u); err != nil {
+		return err
+	}
+	usage[user] = u
+	return s.save(usage)
+}
+
+func (s *FileStore) load() (map[string]Usage, error) {
+	usage := make(map[string]Usage)
+	path, err := s.dir.Join("quota.json")
+	if err != nil {
+		return nil, fmt.Errorf("quota: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return usage, nil
+		}
+		return nil, fmt.Errorf("quota: read: %w", err)
+	}
+	if len(data) == 0 {
+		return usage, nil
+	}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("quota: unmarshal usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (s *FileStore) save(usage map[string]Usage) error {
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return fmt.Errorf("quota: marshal: %w", err)
+	}
+	if err := s.dir.WriteFile("quota.json", data, 0o600, false); err != nil {
+		return fmt.Errorf("quota: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	adkagent "google.golang.org/adk/agent"
+
+	"github.com/zchee/tumix/log"
+)
+
+var (
+	reloadCounter      metric.Int64Counter
+	expConfigReloads   = expvar.NewInt("tumix_config_reloads")
+	expConfigReloadErr = expvar.NewInt("tumix_config_reload_errors")
+)
+
+// reloadKey is a config file key of the agent mixture that a reload picks up, unless its flag or environment variable
+// overrides the file.
+type reloadKey struct {
+	flag string
+	env  string
+	copy func(dst, src *config)
+}
+
+var reloadKeys = []reloadKey{
+	{"mode", "TUMIX_MODE", func(dst, src *config) { dst.Mode = src.Mode }},
+	{"verify", "TUMIX_VERIFY", func(dst, src *config) { dst.Verify = src.Verify }},
+	{"triage", "TUMIX_TRIAGE", func(dst, src *config) { dst.Triage = src.Triage }},
+	{"triage_candidates", "TUMIX_TRIAGE_CANDIDATES", func(dst, src *config) { dst.TriageCandidates = src.TriageCandidates }},
+	{"auto_agents", "TUMIX_AUTO_AGENTS", func(dst, src *config) { dst.AutoAgents = src.AutoAgents }},
+	{"samples_per_agent", "TUMIX_SAMPLES_PER_AGENT", func(dst, src *config) { dst.SamplesPerAgent = src.SamplesPerAgent }},
+	{"a2a_agents", "TUMIX_A2A_AGENTS", func(dst, src *config) { dst.A2AAgents = src.A2AAgents }},
+	{"system_prompt", "TUMIX_SYSTEM_PROMPT", func(dst, src *config) { dst.SystemPrompt = src.SystemPrompt }},
+	{"system_prompt_file", "TUMIX_SYSTEM_PROMPT_FILE", func(dst, src *config) { dst.SystemPromptFile = src.SystemPromptFile }},
+}
+
+// overriddenKeys returns the flags of reloadKeys set on the command line or through their environment variable.
+func overriddenKeys() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	overridden := make(map[string]bool)
+	for _, k := range reloadKeys {
+		if _, ok := os.LookupEnv(k.env); ok || set[k.flag] {
+			overridden[k.flag] = true
+		}
+	}
+	return overridden
+}
+
+// reloadState is the configuration and the agent loader runs start with.
+type reloadState struct {
+	cfg config
+	// systemPrompt is the -system_prompt of cfg, before the -system_prompt_file contents replaced it.
+	systemPrompt string
+	loader       adkagent.Loader
+}
+
+// reloader is an [adkagent.Loader] whose agents, system prompt, and prices are reloaded from the -config file, the
+// -system_prompt_file, and TUMIX_PRICING_FILE on SIGHUP. A reload is validated and built in full before it atomically
+// replaces the current loader, so runs in flight finish with the agents they started with and a failed reload keeps
+// the previous configuration.
+type reloader struct {
+	// build builds the loader of cfg.
+	build func(cfg *config) (adkagent.Loader, error)
+	// overridden holds the flags of the reloadKeys whose file value is overridden.
+	overridden map[string]bool
+
+	mu      sync.Mutex // serializes reloads
+	current atomic.Pointer[reloadState]
+}
+
+var _ adkagent.Loader = (*reloader)(nil)
+
+func newReloader(cfg *config, loader adkagent.Loader, overridden map[string]bool, build func(*config) (adkagent.Loader, error)) *reloader {
+	st := &reloadState{cfg: *cfg, loader: loader}
+	if cfg.SystemPromptFile == "" {
+		st.systemPrompt = cfg.SystemPrompt
+	}
+	r := &reloader{build: build, overridden: overridden}
+	r.current.Store(st)
+	return r
+}
+
+// RootAgent implements [adkagent.Loader].
+func (r *reloader) RootAgent() adkagent.Agent { return r.current.Load().loader.RootAgent() }
+
+// ListAgents implements [adkagent.Loader].
+func (r *reloader) ListAgents() []string { return r.current.Load().loader.ListAgents() }
+
+// LoadAgent implements [adkagent.Loader].
+func (r *reloader) LoadAgent(name string) (adkagent.Agent, error) {
+	return r.current.Load().loader.LoadAgent(name)
+}
+
+// watch reloads on every SIGHUP until ctx is done.
+func (r *reloader) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := r.reload(ctx); err != nil {
+				log.Warn(ctx, "config reload failed; keeping the previous config", "error", err)
+			}
+		}
+	}
+}
+
+// reload re-reads the configuration and swaps it in when it is valid, recording the outcome in the reload metrics.
+func (r *reloader) reload(ctx context.Context) (err error) {
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+			expConfigReloadErr.Add(1)
+		} else {
+			expConfigReloads.Add(1)
+		}
+		if reloadCounter != nil {
+			reloadCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+		}
+	}()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.next(r.current.Load())
+	if err != nil {
+		return err
+	}
+	next.loader, err = r.build(&next.cfg)
+	if err != nil {
+		return fmt.Errorf("build agents: %w", err)
+	}
+	// The pricing file is validated in full before it is merged, so a bad file leaves the prices untouched.
+	if err := reloadPricing(); err != nil {
+		return err
+	}
+	r.current.Store(next)
+
+	log.Info(ctx, "config reloaded", "config_file", next.cfg.ConfigFile, "mode", next.cfg.Mode, "verify", next.cfg.Verify,
+		"auto_agents", next.cfg.AutoAgents, "samples_per_agent", next.cfg.SamplesPerAgent, "a2a_agents", next.cfg.A2AAgents,
+		"system_prompt_file", next.cfg.SystemPromptFile)
+	return nil
+}
+
+// next returns cur with the reloadKeys re-read from the -config file and the -system_prompt_file re-read, without a
+// loader.
+func (r *reloader) next(cur *reloadState) (*reloadState, error) {
+	next := &reloadState{cfg: cur.cfg}
+	next.cfg.SystemPrompt = cur.systemPrompt
+	if cur.cfg.ConfigFile != "" {
+		fc, err := loadConfigFile(cur.cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		// Keys removed from the file fall back to their defaults.
+		file := defaultConfig()
+		fc.apply(&file)
+		for _, k := range reloadKeys {
+			if !r.overridden[k.flag] {
+				k.copy(&next.cfg, &file)
+			}
+		}
+	}
+	next.systemPrompt = next.cfg.SystemPrompt
+
+	if next.cfg.SystemPromptFile != "" {
+		if next.cfg.SystemPrompt != "" {
+			return nil, errors.New("system_prompt and system_prompt_file are mutually exclusive")
+		}
+		data, err := os.ReadFile(next.cfg.SystemPromptFile)
+		if err != nil {
+			return nil, fmt.Errorf("read system_prompt_file: %w", err)
+		}
+		next.cfg.SystemPrompt = string(data)
+	}
+	if err := checkAgents(&next.cfg); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
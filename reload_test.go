@@ -0,0 +1,135 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	adkagent "google.golang.org/adk/agent"
+)
+
+func TestReloaderReload(t *testing.T) {
+	t.Parallel()
+
+	type mixture struct {
+		Mode            string
+		Verify          bool
+		AutoAgents      int
+		SamplesPerAgent uint
+		SystemPrompt    string
+	}
+
+	tests := map[string]struct {
+		start      config
+		overridden map[string]bool
+		file       string
+		prompt     string
+		want       mixture
+		wantErr    string
+	}{
+		"file changes the mixture": {
+			start: config{Mode: "tumix", SamplesPerAgent: 1},
+			file:  "agents:\n  mode: debate\n  auto_agents: 2\n  samples_per_agent: 3\n",
+			want:  mixture{Mode: "debate", AutoAgents: 2, SamplesPerAgent: 3},
+		},
+		"removed keys fall back to the defaults": {
+			start: config{Mode: "debate", AutoAgents: 4, SamplesPerAgent: 2},
+			file:  "model:\n  name: gpt-5\n",
+			want:  mixture{Mode: "tumix", SamplesPerAgent: 1},
+		},
+		"flags override the file": {
+			start:      config{Mode: "debate", SamplesPerAgent: 1},
+			overridden: map[string]bool{"mode": true},
+			file:       "agents:\n  mode: tumix\n  verify: true\n",
+			wantErr:    "verify requires mode tumix",
+		},
+		"system prompt file is re-read": {
+			start:  config{Mode: "tumix", SamplesPerAgent: 1, SystemPrompt: "old", SystemPromptFile: "prompt.txt"},
+			file:   "agents:\n  system_prompt_file: prompt.txt\n",
+			prompt: "Answer in French.",
+			want:   mixture{Mode: "tumix", SamplesPerAgent: 1, SystemPrompt: "Answer in French."},
+		},
+		"system prompt and its file conflict": {
+			start:   config{Mode: "tumix", SamplesPerAgent: 1},
+			file:    "agents:\n  system_prompt: be brief\n  system_prompt_file: prompt.txt\n",
+			prompt:  "Answer in French.",
+			wantErr: "system_prompt and system_prompt_file are mutually exclusive",
+		},
+		"invalid file": {
+			start:   config{Mode: "tumix", SamplesPerAgent: 1},
+			file:    "agents:\n  modes: debate\n",
+			wantErr: "parse config file",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			start := tt.start
+			start.ConfigFile = filepath.Join(dir, "tumix.yaml")
+			if start.SystemPromptFile != "" {
+				start.SystemPromptFile = filepath.Join(dir, start.SystemPromptFile)
+			}
+			file := tt.file
+			if tt.prompt != "" {
+				path := filepath.Join(dir, "prompt.txt")
+				if err := os.WriteFile(path, []byte(tt.prompt), 0o600); err != nil {
+					t.Fatalf("write prompt file: %v", err)
+				}
+				file = strings.ReplaceAll(file, "prompt.txt", path)
+			}
+			if err := os.WriteFile(start.ConfigFile, []byte(file), 0o600); err != nil {
+				t.Fatalf("write config file: %v", err)
+			}
+
+			initial := adkagent.NewSingleLoader(nil)
+			var built []config
+			r := newReloader(&start, initial, tt.overridden, func(cfg *config) (adkagent.Loader, error) {
+				built = append(built, *cfg)
+				return adkagent.NewSingleLoader(nil), nil
+			})
+
+			err := r.reload(t.Context())
+			got := r.current.Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("reload() error = %v, want containing %q", err, tt.wantErr)
+				}
+				if got.loader != initial || len(built) != 0 {
+					t.Fatalf("failed reload swapped the loader")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reload() error = %v", err)
+			}
+			if len(built) != 1 || got.loader == initial {
+				t.Fatalf("reload() built %d loaders, swapped = %t; want 1 and true", len(built), got.loader != initial)
+			}
+			c := got.cfg
+			if diff := cmp.Diff(tt.want, mixture{c.Mode, c.Verify, c.AutoAgents, c.SamplesPerAgent, c.SystemPrompt}); diff != "" {
+				t.Fatalf("reloaded config mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
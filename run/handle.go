@@ -0,0 +1,176 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package run
+
+import (
+	"cmp"
+	"context"
+	"iter"
+	"sync"
+
+	"google.golang.org/adk/session"
+)
+
+// Handle is a run started by [Start], which can be followed, inspected, canceled, and waited for while it is in
+// flight.
+type Handle struct {
+	sessionID string
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu       sync.Mutex
+	events   []*session.Event
+	changed  chan struct{} // closed and replaced on every event
+	progress Progress
+	res      *Result
+	err      error
+}
+
+// Start starts req in the background and returns its handle. The run ends when it completes, or is interrupted when
+// ctx is canceled or on [Handle.Cancel].
+func Start(ctx context.Context, req Request) *Handle {
+	req.SessionID = cmp.Or(req.SessionID, newSessionID())
+	ctx, cancel := context.WithCancel(ctx)
+	h := &Handle{
+		sessionID: req.SessionID,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		changed:   make(chan struct{}),
+	}
+
+	onEvent := req.OnEvent
+	req.OnEvent = func(event *session.Event) error {
+		if onEvent != nil {
+			if err := onEvent(event); err != nil {
+				return err
+			}
+		}
+		h.append(event)
+		return nil
+	}
+	reporter := req.Progress
+	req.Progress = ProgressFunc(func(p Progress) {
+		h.mu.Lock()
+		h.progress = p
+		h.mu.Unlock()
+		if reporter != nil {
+			reporter.ReportProgress(p)
+		}
+	})
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		res, err := Run(ctx, req)
+		h.mu.Lock()
+		h.res, h.err = res, err
+		h.mu.Unlock()
+	}()
+	return h
+}
+
+// SessionID returns the ID of the session of the run.
+func (h *Handle) SessionID() string { return h.sessionID }
+
+// Cancel interrupts the run. The TUMIX agent cuts the current round short and finalizes a provisional answer from
+// the rounds run so far within [Request.InterruptGrace]; [Handle.Wait] returns it.
+func (h *Handle) Cancel() { h.cancel() }
+
+// Done returns a channel closed once the run has ended.
+func (h *Handle) Done() <-chan struct{} { return h.done }
+
+// Wait waits for the run to end and returns its result. Like [Run], it returns the result collected so far with the
+// error of a failed run.
+func (h *Handle) Wait() (*Result, error) {
+	<-h.done
+	return h.res, h.err
+}
+
+// Partial returns the result of the events so far, the partial state of a run in flight.
+func (h *Handle) Partial() *Result {
+	h.mu.Lock()
+	events := h.events
+	h.mu.Unlock()
+
+	res := &Result{SessionID: h.sessionID}
+	for _, event := range events {
+		if !event.Partial {
+			res.add(event)
+		}
+	}
+	return res
+}
+
+// Progress returns the progress of the run at its last round boundary, the zero Progress before the first round
+// ends.
+func (h *Handle) Progress() Progress {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.progress
+}
+
+// Events yields every event of the run from the first one on, partial ones included, waiting for new ones until the
+// run ends; a failed run ends with its error. Events can be called any number of times, and stopping the iteration
+// does not cancel the run.
+func (h *Handle) Events() iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		for next := 0; ; {
+			h.mu.Lock()
+			events, changed := h.events[next:], h.changed
+			h.mu.Unlock()
+
+			for _, event := range events {
+				if !yield(event, nil) {
+					return
+				}
+			}
+			next += len(events)
+			if len(events) > 0 {
+				continue
+			}
+
+			select {
+			case <-changed:
+			case <-h.done:
+				// Every event was appended before the run ended.
+				h.mu.Lock()
+				events = h.events[next:]
+				h.mu.Unlock()
+				for _, event := range events {
+					if !yield(event, nil) {
+						return
+					}
+				}
+				if h.err != nil {
+					yield(nil, h.err)
+				}
+				return
+			}
+		}
+	}
+}
+
+func (h *Handle) append(event *session.Event) {
+	if event == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = append(h.events, event)
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package run
+
+import (
+	"errors"
+	"iter"
+	"testing"
+
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+
+	tumixagent "github.com/zchee/tumix/agent"
+)
+
+// blocking returns a candidate answering nothing until its run is canceled.
+func blocking(t *testing.T, name string) adkagent.Agent {
+	t.Helper()
+
+	return mustAgent(t, adkagent.Config{
+		Name:        name,
+		Description: "blocking candidate",
+		Run: func(ctx adkagent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				<-ctx.Done()
+				yield(nil, ctx.Err())
+			}
+		},
+	})
+}
+
+func TestHandleCancel(t *testing.T) {
+	t.Parallel()
+
+	loader, err := tumixagent.NewTumixAgentWithConfig(tumixagent.TumixConfig{
+		Candidates: []adkagent.Agent{answering(t, "A", "<<<42>>>", 10, 5), blocking(t, "slow")},
+		Judge:      silentJudge(t),
+		MaxRounds:  3,
+		MinRounds:  1,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	h := Start(t.Context(), Request{Agent: loader.RootAgent(), Prompt: "What is 6*7?"})
+	if h.SessionID() == "" {
+		t.Fatal("SessionID() is empty")
+	}
+
+	// Cancel once the fast candidate has answered the first round.
+	for event, err := range h.Events() {
+		if err != nil {
+			t.Fatalf("Events() error = %v", err)
+		}
+		if event.Author == "A" {
+			if got := h.Partial(); len(got.Events) == 0 || got.Answer != "" {
+				t.Fatalf("Partial() = %d events answering %q, want the events so far without an answer", len(got.Events), got.Answer)
+			}
+			h.Cancel()
+			break
+		}
+	}
+
+	res, err := h.Wait()
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if res.Answer != "42" || !res.Provisional {
+		t.Fatalf("Wait() answer = %q, provisional = %t; want a provisional 42", res.Answer, res.Provisional)
+	}
+	if res.SessionID != h.SessionID() {
+		t.Fatalf("Wait() session = %q, want %q", res.SessionID, h.SessionID())
+	}
+
+	// A late consumer replays the whole run.
+	var n int
+	for _, err := range h.Events() {
+		if err != nil {
+			t.Fatalf("Events() after the run error = %v", err)
+		}
+		n++
+	}
+	if n != len(res.Events) {
+		t.Fatalf("Events() after the run yielded %d events, want %d", n, len(res.Events))
+	}
+	if got := h.Partial(); got.Answer != res.Answer || len(got.Events) != len(res.Events) {
+		t.Fatalf("Partial() after the run = %d events answering %q, want %d answering %q", len(got.Events), got.Answer, len(res.Events), res.Answer)
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+	h := Start(t.Context(), Request{
+		Prompt:  "q",
+		Agent:   answering(t, "A", "42", 1, 1),
+		OnEvent: func(*session.Event) error { return errBoom },
+	})
+
+	var got error
+	for event, err := range h.Events() {
+		if event != nil {
+			t.Fatalf("Events() yielded an event rejected by OnEvent")
+		}
+		got = err
+	}
+	if !errors.Is(got, errBoom) {
+		t.Fatalf("Events() error = %v, want %v", got, errBoom)
+	}
+	if _, err := h.Wait(); !errors.Is(err, errBoom) {
+		t.Fatalf("Wait() error = %v, want %v", err, errBoom)
+	}
+}
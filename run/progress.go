@@ -0,0 +1,71 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package run
+
+import (
+	"time"
+
+	"github.com/zchee/tumix/usage"
+)
+
+// Progress is the progress of a run at a round boundary.
+type Progress struct {
+	// Round is the number of rounds done, out of at most MaxRounds; the Judge may end the run earlier.
+	Round     uint `json:"round"`
+	MaxRounds uint `json:"max_rounds"`
+
+	Elapsed time.Duration `json:"elapsed"`
+	// Remaining is the estimated time the remaining rounds take, extrapolated from the rounds done.
+	Remaining time.Duration `json:"remaining"`
+
+	// Usage is the usage of the model calls so far.
+	Usage usage.Totals `json:"usage"`
+	// Cost is the cost of Usage priced by [Request.Usage], and EstimatedRemainingCost the cost of the remaining rounds
+	// extrapolated from it; both are zero when the collector prices nothing.
+	Cost                   float64 `json:"cost_usd"`
+	EstimatedRemainingCost float64 `json:"estimated_remaining_cost_usd"`
+}
+
+// ProgressReporter is told the progress of a run at every round boundary.
+type ProgressReporter interface {
+	ReportProgress(Progress)
+}
+
+// ProgressFunc adapts a function to a [ProgressReporter].
+type ProgressFunc func(Progress)
+
+// ReportProgress calls f(p).
+func (f ProgressFunc) ReportProgress(p Progress) { f(p) }
+
+// newProgress returns the progress after round of maxRounds rounds, extrapolating the remaining rounds linearly from
+// the ones done.
+func newProgress(round, maxRounds uint, elapsed time.Duration, totals usage.Totals) Progress {
+	cost := totals.CostUSD
+	p := Progress{
+		Round:     round,
+		MaxRounds: maxRounds,
+		Elapsed:   elapsed,
+		Usage:     totals,
+		Cost:      cost,
+	}
+	if round > 0 && maxRounds > round {
+		ratio := float64(maxRounds-round) / float64(round)
+		p.Remaining = time.Duration(float64(elapsed) * ratio)
+		p.EstimatedRemainingCost = cost * ratio
+	}
+	return p
+}
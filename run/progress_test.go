@@ -0,0 +1,112 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package run
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	adkagent "google.golang.org/adk/agent"
+
+	tumixagent "github.com/zchee/tumix/agent"
+	"github.com/zchee/tumix/usage"
+)
+
+func TestNewProgress(t *testing.T) {
+	t.Parallel()
+
+	totals := usage.Totals{Requests: 2, InputTokens: 100, OutputTokens: 10, CostUSD: 0.5}
+	tests := map[string]struct {
+		round, maxRounds uint
+		want             Progress
+	}{
+		"first of three": {
+			round: 1, maxRounds: 3,
+			want: Progress{Round: 1, MaxRounds: 3, Elapsed: time.Second, Remaining: 2 * time.Second, Usage: totals, Cost: 0.5, EstimatedRemainingCost: 1},
+		},
+		"two of four": {
+			round: 2, maxRounds: 4,
+			want: Progress{Round: 2, MaxRounds: 4, Elapsed: time.Second, Remaining: time.Second, Usage: totals, Cost: 0.5, EstimatedRemainingCost: 0.5},
+		},
+		"last": {
+			round: 3, maxRounds: 3,
+			want: Progress{Round: 3, MaxRounds: 3, Elapsed: time.Second, Usage: totals, Cost: 0.5},
+		},
+		"no round": {
+			maxRounds: 3,
+			want:      Progress{MaxRounds: 3, Elapsed: time.Second, Usage: totals, Cost: 0.5},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := newProgress(tt.round, tt.maxRounds, time.Second, totals)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("newProgress() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	t.Parallel()
+
+	loader, err := tumixagent.NewTumixAgentWithConfig(tumixagent.TumixConfig{
+		Candidates: []adkagent.Agent{
+			answering(t, "A", "<<<42>>>", 10, 5),
+			answering(t, "B", "<<<41>>>", 10, 5),
+		},
+		Judge:     silentJudge(t),
+		MaxRounds: 3,
+		MinRounds: 3,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	var got []Progress
+	h := Start(t.Context(), Request{
+		Agent:    loader.RootAgent(),
+		Prompt:   "What is 6*7?",
+		Progress: ProgressFunc(func(p Progress) { got = append(got, p) }),
+		Usage:    usage.NewCollector(tumixagent.JudgeAgentName, func(c usage.Call) float64 { return float64(c.InputTokens+c.OutputTokens) / 1000 }),
+	})
+	if _, err := h.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("reported %d progresses, want 2", len(got))
+	}
+	want := []Progress{
+		{Round: 1, MaxRounds: 3, Usage: usage.Totals{Requests: 2, InputTokens: 20, OutputTokens: 10, CostUSD: 0.03}, Cost: 0.03, EstimatedRemainingCost: 0.06},
+		{Round: 2, MaxRounds: 3, Usage: usage.Totals{Requests: 4, InputTokens: 40, OutputTokens: 20, CostUSD: 0.06}, Cost: 0.06, EstimatedRemainingCost: 0.03},
+	}
+	ignoreTimes := cmp.FilterPath(func(p cmp.Path) bool {
+		name := p.Last().String()
+		return name == ".Elapsed" || name == ".Remaining"
+	}, cmp.Ignore())
+	if diff := cmp.Diff(want, got, ignoreTimes); diff != "" {
+		t.Fatalf("progress mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(got[1], h.Progress()); diff != "" {
+		t.Fatalf("Handle.Progress() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package run is the library entry point of TUMIX: it runs a TUMIX agent on a prompt and returns the structured
+// result, so Go programs can embed TUMIX without executing the tumix command.
+//
+//	loader, err := tumixagent.NewTumixAgentWithConfig(tumixagent.TumixConfig{...})
+//	...
+//	res, err := run.Run(ctx, run.Request{Agent: loader.RootAgent(), UserID: "alice", Prompt: "What is 6*7?"})
+//	...
+//	fmt.Println(res.Answer, res.Confidence)
+package run
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	tumixagent "github.com/zchee/tumix/agent"
+	"github.com/zchee/tumix/gollm/failover"
+	"github.com/zchee/tumix/log"
+	"github.com/zchee/tumix/telemetry/runmeta"
+	"github.com/zchee/tumix/usage"
+)
+
+// DefaultInterruptGrace is the [Request.InterruptGrace] used when it is zero.
+const DefaultInterruptGrace = 5 * time.Second
+
+// Request is a TUMIX run.
+type Request struct {
+	// Agent is the root agent, e.g. the RootAgent of [tumixagent.NewTumixAgentWithConfig].
+	Agent adkagent.Agent
+
+	// AppName defaults to "tumix", UserID to "user", and SessionID to a time-based ID.
+	AppName   string
+	UserID    string
+	SessionID string
+	// Labels are experiment labels sent with the user and session IDs on every model call; see [runmeta].
+	Labels map[string]string
+
+	// Prompt is the user question, followed by Parts, e.g. attachments, in the user turn.
+	Prompt string
+	Parts  []*genai.Part
+
+	// SessionService stores the session, created by Run; it defaults to an in-memory service.
+	SessionService session.Service
+	// RunConfig configures the runner, e.g. streaming.
+	RunConfig adkagent.RunConfig
+
+	// InterruptGrace is how long a run whose ctx is canceled may take to finalize the provisional answer of the rounds
+	// run so far before it is canceled as well. Zero uses DefaultInterruptGrace.
+	InterruptGrace time.Duration
+
+	// OnEvent, if set, is called with every event as it arrives, partial ones included; an error ends the run.
+	OnEvent func(*session.Event) error
+	// Progress, if set, is told the progress of the run at every round boundary of a TUMIX agent, on the goroutine of
+	// the run.
+	Progress ProgressReporter
+	// Usage, if set, collects the usage of every model call of the run, priced by its pricer and passed on to its
+	// subscribers as the events arrive. Run uses an unpriced collector when it is nil.
+	Usage *usage.Collector
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	SessionID string `json:"session_id"`
+
+	// Author and Text are the author and text of the last event with text, the final answer event of a TUMIX run.
+	Author string `json:"author"`
+	Text   string `json:"text"`
+	// Answer and Confidence are the final answer recorded by the TUMIX agent; Answer is empty when it recorded none.
+	Answer     string  `json:"answer,omitzero"`
+	Confidence float64 `json:"confidence,omitzero"`
+	// Provisional reports that the run was interrupted and Answer salvaged from the rounds run so far.
+	Provisional bool `json:"provisional"`
+
+	Rounds          []tumixagent.RoundStats     `json:"rounds,omitzero"`
+	Citations       []tumixagent.Citation       `json:"citations,omitzero"`
+	CandidateScores []tumixagent.CandidateScore `json:"candidate_scores,omitzero"`
+	// Timeouts lists why rounds were cut short by a deadline or an interruption.
+	Timeouts []string `json:"timeouts,omitzero"`
+	// Triage is the decision of the triage stage, nil without one.
+	Triage *tumixagent.TriageDecision `json:"triage,omitzero"`
+	// FormatCompliance counts how every candidate agent followed the answer format.
+	FormatCompliance []tumixagent.FormatCompliance `json:"format_compliance,omitzero"`
+	// JudgeRationale is the Judge's analysis of the last round it ran, nil when it ran none.
+	JudgeRationale *tumixagent.JudgeRationale `json:"judge_rationale,omitzero"`
+	// ServedBy maps the authors whose model calls failed over to the backend that served them.
+	ServedBy map[string]string `json:"served_by,omitzero"`
+
+	// Usage is the usage of the model calls of the run, the totals of Request.Usage.
+	Usage usage.Totals `json:"usage"`
+	// Events are the complete events of the run in order, without the partial ones.
+	Events []*session.Event `json:"-"`
+}
+
+// Run runs req.Agent on req.Prompt and returns the result.
+//
+// Canceling ctx interrupts the run: the TUMIX agent cuts the current round short and finalizes a provisional answer
+// within req.InterruptGrace. When the agent fails, Run returns the result collected so far with the error.
+func Run(ctx context.Context, req Request) (*Result, error) {
+	if req.Agent == nil {
+		return nil, fmt.Errorf("run: agent is required")
+	}
+	appName := cmp.Or(req.AppName, "tumix")
+	userID := cmp.Or(req.UserID, "user")
+	sessionID := cmp.Or(req.SessionID, newSessionID())
+	svc := req.SessionService
+	if svc == nil {
+		svc = session.InMemoryService()
+	}
+	if _, err := svc.Create(ctx, &session.CreateRequest{
+		AppName:   appName,
+		UserID:    userID,
+		SessionID: sessionID,
+	}); err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+
+	r, err := runner.New(runner.Config{
+		AppName:        appName,
+		Agent:          req.Agent,
+		SessionService: svc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("runner init: %w", err)
+	}
+
+	content := genai.NewContentFromText(req.Prompt, genai.RoleUser)
+	content.Parts = append(content.Parts, req.Parts...)
+	ctx = runmeta.NewContext(ctx, runmeta.RunMetadata{UserID: userID, SessionID: sessionID, Labels: req.Labels})
+	// An interrupt cuts the TUMIX rounds short and the orchestrator finalizes a provisional answer from what it has; the
+	// run, and storing its events, get the grace period to do so before they are canceled as well.
+	runCtx, cancelRun := context.WithCancel(tumixagent.WithInterrupt(context.WithoutCancel(ctx), ctx))
+	defer cancelRun()
+	grace := cmp.Or(req.InterruptGrace, DefaultInterruptGrace)
+	stopGrace := context.AfterFunc(ctx, func() { time.AfterFunc(grace, cancelRun) })
+	defer stopGrace()
+
+	res := &Result{SessionID: sessionID}
+	collector := req.Usage
+	if collector == nil {
+		collector = usage.NewCollector(tumixagent.JudgeAgentName, nil)
+	}
+	if req.Progress != nil {
+		start := time.Now()
+		runCtx = tumixagent.WithRoundFunc(runCtx, func(round, maxRounds uint) {
+			req.Progress.ReportProgress(newProgress(round, maxRounds, time.Since(start), collector.Totals()))
+		})
+	}
+	for event, err := range r.Run(runCtx, userID, sessionID, content, req.RunConfig) {
+		if err != nil && ctx.Err() != nil && runCtx.Err() == nil {
+			log.Warn(ctx, "agent error after interrupt", "error", err)
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("agent run: %w", err)
+		}
+		if req.OnEvent != nil {
+			if err := req.OnEvent(event); err != nil {
+				return res, err
+			}
+		}
+		if event == nil || event.Partial {
+			continue
+		}
+		collector.AddEvent(ctx, event)
+		res.add(event)
+		res.Usage = collector.Totals()
+	}
+	return res, nil
+}
+
+// add records the complete event in res.
+func (res *Result) add(event *session.Event) {
+	res.Events = append(res.Events, event)
+	if text := firstText(event); text != "" {
+		res.Text = text
+		res.Author = event.Author
+	}
+	if answer, conf, ok := tumixagent.FinalAnswerFromEvent(event); ok {
+		res.Answer, res.Confidence = answer, conf
+	}
+	if tumixagent.IsProvisional(event) {
+		res.Provisional = true
+	}
+	if stats := tumixagent.RoundStatsFromEvent(event); len(stats) > 0 {
+		res.Rounds = stats
+	}
+	if triage := tumixagent.TriageFromEvent(event); triage != nil {
+		res.Triage = triage
+	}
+	if compliance := tumixagent.FormatComplianceFromEvent(event); len(compliance) > 0 {
+		res.FormatCompliance = compliance
+	}
+	if rationale := tumixagent.JudgeRationaleFromEvent(event); rationale != nil {
+		res.JudgeRationale = rationale
+	}
+	if cites := tumixagent.CitationsFromEvent(event); len(cites) > 0 {
+		res.Citations = cites
+	}
+	if scores := tumixagent.CandidateScoresFromEvent(event); len(scores) > 0 {
+		res.CandidateScores = scores
+	}
+	if timeouts := tumixagent.TimeoutsFromEvent(event); len(timeouts) > 0 {
+		res.Timeouts = timeouts
+	}
+	if backend := failover.BackendFromResponse(&event.LLMResponse); backend != "" {
+		if res.ServedBy == nil {
+			res.ServedBy = make(map[string]string)
+		}
+		res.ServedBy[event.Author] = backend
+	}
+}
+
+// newSessionID returns a time-based session ID.
+func newSessionID() string {
+	return fmt.Sprintf("session-%d", time.Now().UnixNano())
+}
+
+// firstText returns the first text part of the event content.
+func firstText(event *session.Event) string {
+	if event.Content == nil {
+		return ""
+	}
+	for _, part := range event.Content.Parts {
+		if part != nil && part.Text != "" {
+			return part.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package run
+
+import (
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	tumixagent "github.com/zchee/tumix/agent"
+	"github.com/zchee/tumix/usage"
+)
+
+func mustAgent(t *testing.T, cfg adkagent.Config) adkagent.Agent {
+	t.Helper()
+
+	a, err := adkagent.New(cfg)
+	if err != nil {
+		t.Fatalf("agent.New(%s): %v", cfg.Name, err)
+	}
+	return a
+}
+
+// answering returns a candidate answering text with the given token usage.
+func answering(t *testing.T, name, text string, in, out int32) adkagent.Agent {
+	t.Helper()
+
+	return mustAgent(t, adkagent.Config{
+		Name:        name,
+		Description: "static candidate",
+		Run: func(ctx adkagent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{
+					Content:       genai.NewContentFromText(text, genai.RoleModel),
+					UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: in, CandidatesTokenCount: out},
+				}
+				yield(ev, nil)
+			}
+		},
+	})
+}
+
+// silentJudge never stops the rounds, leaving the answer to the vote.
+func silentJudge(t *testing.T) adkagent.Agent {
+	t.Helper()
+
+	return mustAgent(t, adkagent.Config{
+		Name:        tumixagent.JudgeAgentName,
+		Description: "silent judge",
+		Run: func(ctx adkagent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.LLMResponse = model.LLMResponse{
+					Content:       genai.NewContentFromText("continue", genai.RoleModel),
+					UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 1},
+				}
+				yield(ev, nil)
+			}
+		},
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	loader, err := tumixagent.NewTumixAgentWithConfig(tumixagent.TumixConfig{
+		Candidates: []adkagent.Agent{
+			answering(t, "A", "<<<42>>>", 10, 5),
+			answering(t, "B", "<<<42>>>", 10, 5),
+			answering(t, "C", "<<<41>>>", 10, 5),
+		},
+		Judge:     silentJudge(t),
+		MaxRounds: 2,
+		MinRounds: 2,
+	})
+	if err != nil {
+		t.Fatalf("loader: %v", err)
+	}
+
+	var partial, complete int
+	res, err := Run(t.Context(), Request{
+		Agent:     loader.RootAgent(),
+		UserID:    "alice",
+		SessionID: "s1",
+		Prompt:    "What is 6*7?",
+		OnEvent: func(event *session.Event) error {
+			if event.Partial {
+				partial++
+			} else {
+				complete++
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if res.Answer != "42" || res.Author != "tumix" || !strings.Contains(res.Text, "42") {
+		t.Fatalf("Run() answer = %q by %s (%q), want 42 by tumix", res.Answer, res.Author, res.Text)
+	}
+	if res.Confidence <= 0 || res.Provisional {
+		t.Fatalf("Run() confidence = %v, provisional = %t; want a positive confidence, not provisional", res.Confidence, res.Provisional)
+	}
+	conflicts := []tumixagent.Conflict{{Kind: tumixagent.ConflictNumericSpread, Detail: "41 (C) vs 42 (A, B)"}}
+	wantRounds := []tumixagent.RoundStats{
+		{Round: 1, Unique: 2, TopAnswer: "42", VoteMargin: 2.0 / 3, Coverage: 1, Conflicts: conflicts},
+		{
+			Round: 2, Unique: 2, TopAnswer: "42", VoteMargin: 2.0 / 3, Coverage: 1,
+			Changes:   []tumixagent.AnswerChange{{Agent: "A"}, {Agent: "B"}, {Agent: "C"}},
+			Conflicts: conflicts,
+		},
+	}
+	ignoreEntropy := cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".Entropy" }, cmp.Ignore())
+	if diff := cmp.Diff(wantRounds, res.Rounds, ignoreEntropy); diff != "" {
+		t.Fatalf("Run() rounds mismatch (-want +got):\n%s", diff)
+	}
+	if want := (&tumixagent.JudgeRationale{Round: 2, Text: "continue"}); !cmp.Equal(want, res.JudgeRationale) {
+		t.Fatalf("Run() judge rationale = %+v, want %+v", res.JudgeRationale, want)
+	}
+	// The Judge is first consulted after MinRounds.
+	wantUsage := usage.Totals{Requests: 2*3 + 1, InputTokens: 2*3*10 + 100, OutputTokens: 2*3*5 + 1, JudgeInputTokens: 100, JudgeOutputTokens: 1}
+	if diff := cmp.Diff(wantUsage, res.Usage); diff != "" {
+		t.Fatalf("Run() usage mismatch (-want +got):\n%s", diff)
+	}
+	if partial != 0 || complete != len(res.Events) || complete == 0 {
+		t.Fatalf("OnEvent saw %d partial and %d complete events, want 0 and %d", partial, complete, len(res.Events))
+	}
+	if res.SessionID != "s1" {
+		t.Fatalf("Run() session = %q, want s1", res.SessionID)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+	tests := map[string]struct {
+		req     func(t *testing.T) Request
+		wantErr string
+		wantRes bool
+	}{
+		"no agent": {
+			req:     func(*testing.T) Request { return Request{Prompt: "q"} },
+			wantErr: "run: agent is required",
+		},
+		"agent error": {
+			req: func(t *testing.T) Request {
+				return Request{
+					Prompt: "q",
+					Agent: mustAgent(t, adkagent.Config{
+						Name:        "failing",
+						Description: "failing agent",
+						Run: func(adkagent.InvocationContext) iter.Seq2[*session.Event, error] {
+							return func(yield func(*session.Event, error) bool) { yield(nil, errBoom) }
+						},
+					}),
+				}
+			},
+			wantErr: "agent run: boom",
+			wantRes: true,
+		},
+		"event callback error": {
+			req: func(t *testing.T) Request {
+				return Request{
+					Prompt:  "q",
+					Agent:   answering(t, "A", "42", 1, 1),
+					OnEvent: func(*session.Event) error { return errBoom },
+				}
+			},
+			wantErr: "boom",
+			wantRes: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := Run(t.Context(), tt.req(t))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := res != nil; got != tt.wantRes {
+				t.Fatalf("Run() result returned = %t, want %t", got, tt.wantRes)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package retention garbage-collects the sessions of a persistent session store by a retention [Policy]: a maximum
+// age, a maximum number of sessions per user, and a maximum total size.
+//
+// A [Janitor] enforces the policy in the background, deleting the least recently updated sessions first, or in dry-run
+// mode only reports the sessions it would delete.
+package retention
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+// Reason names the limit of a [Policy] that evicted a session.
+type Reason string
+
+// Reasons of an [Eviction].
+const (
+	ReasonMaxAge             Reason = "max_age"
+	ReasonMaxSessionsPerUser Reason = "max_sessions_per_user"
+	ReasonMaxTotalBytes      Reason = "max_total_bytes"
+)
+
+// Policy is the retention policy of a session store. Zero disables a limit.
+type Policy struct {
+	// MaxAge evicts the sessions not updated for longer.
+	MaxAge time.Duration
+	// MaxSessionsPerUser evicts the least recently updated sessions of a user of an app beyond this many.
+	MaxSessionsPerUser int
+	// MaxTotalBytes evicts the least recently updated sessions until the sessions left take at most this many bytes.
+	MaxTotalBytes int64
+}
+
+// Enabled reports whether any limit is set.
+func (p Policy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxSessionsPerUser > 0 || p.MaxTotalBytes > 0
+}
+
+// Usage describes a stored session.
+type Usage struct {
+	AppName   string
+	UserID    string
+	SessionID string
+	UpdatedAt time.Time
+	// Bytes is the size of the session in the store.
+	Bytes int64
+}
+
+// Eviction is a session evicted by a [Policy].
+type Eviction struct {
+	Usage
+	Reason Reason
+}
+
+// Store is a session store the retention policy is enforced on.
+type Store interface {
+	// Usage returns every stored session.
+	Usage(ctx context.Context) ([]Usage, error)
+	// Delete deletes a session.
+	Delete(ctx context.Context, req *session.DeleteRequest) error
+}
+
+// Select returns the sessions of usage that p evicts at now, the least recently updated first within each limit:
+// first those older than MaxAge, then those beyond MaxSessionsPerUser, then those beyond MaxTotalBytes.
+func (p Policy) Select(usage []Usage, now time.Time) []Eviction {
+	sessions := slices.Clone(usage)
+	// Newest first, so the sessions kept are a prefix.
+	slices.SortStableFunc(sessions, func(a, b Usage) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+
+	var evicted []Eviction
+	evict := func(u Usage, reason Reason) {
+		evicted = append(evicted, Eviction{Usage: u, Reason: reason})
+	}
+
+	kept := sessions[:0:0]
+	for _, u := range sessions {
+		if p.MaxAge > 0 && now.Sub(u.UpdatedAt) > p.MaxAge {
+			evict(u, ReasonMaxAge)
+			continue
+		}
+		kept = append(kept, u)
+	}
+
+	if p.MaxSessionsPerUser > 0 {
+		type user struct{ app, id string }
+		counts := make(map[user]int)
+		next := kept[:0:0]
+		for _, u := range kept {
+			key := user{u.AppName, u.UserID}
+			if counts[key] >= p.MaxSessionsPerUser {
+				evict(u, ReasonMaxSessionsPerUser)
+				continue
+			}
+			counts[key]++
+			next = append(next, u)
+		}
+		kept = next
+	}
+
+	if p.MaxTotalBytes > 0 {
+		var total int64
+		for i, u := range kept {
+			total += u.Bytes
+			if total > p.MaxTotalBytes {
+				for _, old := range kept[i:] {
+					evict(old, ReasonMaxTotalBytes)
+				}
+				break
+			}
+		}
+	}
+
+	// Within each limit, the least recently updated first.
+	slices.SortStableFunc(evicted, func(a, b Eviction) int {
+		if a.Reason != b.Reason {
+			return cmp.Compare(reasonRank(a.Reason), reasonRank(b.Reason))
+		}
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	return evicted
+}
+
+func reasonRank(r Reason) int {
+	return slices.Index([]Reason{ReasonMaxAge, ReasonMaxSessionsPerUser, ReasonMaxTotalBytes}, r)
+}
+
+// DefaultInterval is the default interval between the sweeps of a [Janitor].
+const DefaultInterval = time.Hour
+
+// Option configures a [Janitor].
+type Option func(*Janitor)
+
+// WithClock sets the clock of the janitor, time.Now by default.
+func WithClock(now func() time.Time) Option {
+	return func(j *Janitor) { j.now = now }
+}
+
+// WithInterval sets the interval between the sweeps of [Janitor.Run], [DefaultInterval] by default.
+func WithInterval(d time.Duration) Option {
+	return func(j *Janitor) {
+		if d > 0 {
+			j.interval = d
+		}
+	}
+}
+
+// WithDryRun makes the janitor report the sessions it would evict without deleting them.
+func WithDryRun(dryRun bool) Option {
+	return func(j *Janitor) { j.dryRun = dryRun }
+}
+
+// WithOnEvict sets a function called with every session evicted, or that would be in dry-run mode, e.g. to count
+// them in metrics.
+func WithOnEvict(fn func(ctx context.Context, e Eviction, dryRun bool)) Option {
+	return func(j *Janitor) { j.onEvict = fn }
+}
+
+// WithOnError sets a function called with the error of a failed sweep of [Janitor.Run].
+func WithOnError(fn func(context.Context, error)) Option {
+	return func(j *Janitor) { j.onError = fn }
+}
+
+// Janitor enforces a retention [Policy] on a [Store].
+type Janitor struct {
+	policy   Policy
+	store    Store
+	now      func() time.Time
+	interval time.Duration
+	dryRun   bool
+	onEvict  func(context.Context, Eviction, bool)
+	onError  func(context.Context, error)
+}
+
+// New returns a janitor enforcing policy on store.
+func New(policy Policy, store Store, opts ...Option) *Janitor {
+	j := &Janitor{
+		policy:   policy,
+		store:    store,
+		now:      time.Now,
+		interval: DefaultInterval,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Sweep evicts the sessions the policy selects, and returns them. In dry-run mode, it deletes nothing. A failed
+// deletion does not stop the sweep; the sessions that could not be deleted are left out of the result and their
+// errors joined.
+func (j *Janitor) Sweep(ctx context.Context) ([]Eviction, error) {
+	usage, err := j.store.Usage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retention: usage: %w", err)
+	}
+
+	var (
+		evicted []Eviction
+		errs    []error
+	)
+	for _, e := range j.policy.Select(usage, j.now()) {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if !j.dryRun {
+			err := j.store.Delete(ctx, &session.DeleteRequest{AppName: e.AppName, UserID: e.UserID, SessionID: e.SessionID})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("retention: delete session %s: %w", e.SessionID, err))
+				continue
+			}
+		}
+		evicted = append(evicted, e)
+		if j.onEvict != nil {
+			j.onEvict(ctx, e, j.dryRun)
+		}
+	}
+
+	return evicted, errors.Join(errs...)
+}
+
+// Run sweeps the store right away, then at every interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Sweep(ctx); err != nil && ctx.Err() == nil && j.onError != nil {
+			j.onError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
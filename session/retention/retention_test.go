@@ -0,0 +1,236 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package retention
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// usage returns the sessions s1..s5 of app: s1 and s2 of alice, s3, s4, and s5 of bob, each 100 bytes, updated s1
+// ten days ago, then two days apart, s5 a day ago.
+func usage() []Usage {
+	users := []string{"alice", "alice", "bob", "bob", "bob"}
+	out := make([]Usage, len(users))
+	for i, user := range users {
+		out[i] = Usage{
+			AppName:   "app",
+			UserID:    user,
+			SessionID: "s" + string(rune('1'+i)),
+			UpdatedAt: now.Add(-time.Duration(10-i*2) * 24 * time.Hour),
+			Bytes:     100,
+		}
+		if i == len(users)-1 {
+			out[i].UpdatedAt = now.Add(-24 * time.Hour)
+		}
+	}
+	return out
+}
+
+type evicted struct {
+	id     string
+	reason Reason
+}
+
+func ids(evictions []Eviction) []evicted {
+	var out []evicted
+	for _, e := range evictions {
+		out = append(out, evicted{e.SessionID, e.Reason})
+	}
+	return out
+}
+
+func TestPolicySelect(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy Policy
+		want   []evicted
+	}{
+		"disabled": {},
+		"max age": {
+			policy: Policy{MaxAge: 7 * 24 * time.Hour},
+			want:   []evicted{{"s1", ReasonMaxAge}, {"s2", ReasonMaxAge}},
+		},
+		"max sessions per user": {
+			policy: Policy{MaxSessionsPerUser: 1},
+			want:   []evicted{{"s1", ReasonMaxSessionsPerUser}, {"s3", ReasonMaxSessionsPerUser}, {"s4", ReasonMaxSessionsPerUser}},
+		},
+		"max total bytes": {
+			policy: Policy{MaxTotalBytes: 250},
+			want:   []evicted{{"s1", ReasonMaxTotalBytes}, {"s2", ReasonMaxTotalBytes}, {"s3", ReasonMaxTotalBytes}},
+		},
+		"combined": {
+			policy: Policy{MaxAge: 9 * 24 * time.Hour, MaxSessionsPerUser: 2, MaxTotalBytes: 200},
+			want:   []evicted{{"s1", ReasonMaxAge}, {"s3", ReasonMaxSessionsPerUser}, {"s2", ReasonMaxTotalBytes}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ids(tt.policy.Select(usage(), now))
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(evicted{})); diff != "" {
+				t.Fatalf("Select() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type fakeStore struct {
+	mu      sync.Mutex
+	usage   []Usage
+	deleted []string
+	failing string
+}
+
+func (s *fakeStore) Usage(context.Context) ([]Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Usage
+	for _, u := range s.usage {
+		if !slices.Contains(s.deleted, u.SessionID) {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) Delete(_ context.Context, req *session.DeleteRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.SessionID == s.failing {
+		return errors.New("locked")
+	}
+	s.deleted = append(s.deleted, req.SessionID)
+	return nil
+}
+
+func TestJanitorSweep(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dryRun      bool
+		failing     string
+		wantEvicted []string
+		wantDeleted []string
+		wantErr     bool
+	}{
+		"delete": {
+			wantEvicted: []string{"s1", "s2"},
+			wantDeleted: []string{"s1", "s2"},
+		},
+		"dry run": {
+			dryRun:      true,
+			wantEvicted: []string{"s1", "s2"},
+		},
+		"failed delete": {
+			failing:     "s1",
+			wantEvicted: []string{"s2"},
+			wantDeleted: []string{"s2"},
+			wantErr:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{usage: usage(), failing: tt.failing}
+			var (
+				reported []string
+				dryRuns  []bool
+			)
+			j := New(Policy{MaxAge: 7 * 24 * time.Hour}, store,
+				WithClock(func() time.Time { return now }),
+				WithDryRun(tt.dryRun),
+				WithOnEvict(func(_ context.Context, e Eviction, dryRun bool) {
+					reported = append(reported, e.SessionID)
+					dryRuns = append(dryRuns, dryRun)
+				}),
+			)
+
+			got, err := j.Sweep(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sweep() error = %v, want error %t", err, tt.wantErr)
+			}
+			var gotIDs []string
+			for _, e := range got {
+				gotIDs = append(gotIDs, e.SessionID)
+			}
+			if diff := cmp.Diff(tt.wantEvicted, gotIDs); diff != "" {
+				t.Fatalf("Sweep() evicted mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantEvicted, reported); diff != "" {
+				t.Fatalf("OnEvict mismatch (-want +got):\n%s", diff)
+			}
+			for _, dryRun := range dryRuns {
+				if dryRun != tt.dryRun {
+					t.Fatalf("OnEvict dry run = %t, want %t", dryRun, tt.dryRun)
+				}
+			}
+			if diff := cmp.Diff(tt.wantDeleted, store.deleted); diff != "" {
+				t.Fatalf("deleted mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJanitorRun(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeStore{usage: usage()}
+	swept := make(chan struct{})
+	var evictions int
+	j := New(Policy{MaxSessionsPerUser: 1}, store,
+		WithInterval(time.Millisecond),
+		WithOnEvict(func(context.Context, Eviction, bool) {
+			// The first sweep evicts s1, s3, and s4.
+			if evictions++; evictions == 3 {
+				close(swept)
+			}
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		j.Run(ctx)
+		close(done)
+	}()
+	<-swept
+	cancel()
+	<-done
+
+	remaining, err := store.Usage(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("Run() left %+v, want one session per user", remaining)
+	}
+}
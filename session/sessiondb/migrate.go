@@ -0,0 +1,197 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sessiondb
+
+import (
+	"context"
+	"database/sql"
+	json "encoding/json/v2"
+	"fmt"
+)
+
+// migration upgrades the schema from the version before it to version.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations are the schema versions in order. The version of a database is kept in its user_version pragma; a
+// database created before the migrations existed has version 0 and the sessions table of version 1.
+var migrations = []migration{
+	{version: 1, name: "create sessions", up: execMigration(
+		`CREATE TABLE IF NOT EXISTS sessions (
+			key TEXT PRIMARY KEY,
+			blob BLOB NOT NULL
+		)`,
+	)},
+	{version: 2, name: "index sessions", up: indexSessions},
+	{version: 3, name: "index events", up: indexEvents},
+}
+
+// LatestSchemaVersion returns the schema version [Open] migrates databases to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the schema version of the database.
+func (s *Store) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	if err := s.db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("sessiondb: schema version: %w", err)
+	}
+	return version, nil
+}
+
+func execMigration(stmts ...string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrate applies the migrations newer than the version of db, each in its own transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	for _, m := range migrations {
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("sessiondb: migrate to version %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	// The version is read in the transaction, so concurrent processes opening the database migrate it once.
+	var version int
+	if err := tx.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= m.version {
+		return nil
+	}
+	if err := m.up(ctx, tx); err != nil {
+		return err
+	}
+	// Pragmas take no parameters.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, m.version)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// indexSessions moves the identity and the times of every session out of its blob into indexed columns.
+func indexSessions(ctx context.Context, tx *sql.Tx) error {
+	if err := execMigration(
+		`ALTER TABLE sessions ADD COLUMN app_name TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN user_id TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN session_id TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN event_count INTEGER NOT NULL DEFAULT 0`,
+	)(ctx, tx); err != nil {
+		return err
+	}
+
+	err := eachSession(ctx, tx, func(key string, ps *persistSession) error {
+		_, err := tx.ExecContext(ctx, `UPDATE sessions
+			SET app_name = ?, user_id = ?, session_id = ?, created_at = ?, updated_at = ?, event_count = ?
+			WHERE key = ?`,
+			ps.AppName, ps.UserID, ps.SessionID, ps.createdAt().UnixNano(), ps.UpdatedAt.UnixNano(), len(ps.Events), key)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return execMigration(
+		`CREATE INDEX sessions_app_user_created ON sessions(app_name, user_id, created_at)`,
+		`CREATE INDEX sessions_created ON sessions(created_at)`,
+	)(ctx, tx)
+}
+
+// indexEvents creates the events table searched by [Store.SearchEvents], filled with the events of every session.
+func indexEvents(ctx context.Context, tx *sql.Tx) error {
+	if err := execMigration(
+		`CREATE TABLE events (
+			key TEXT NOT NULL,
+			idx INTEGER NOT NULL,
+			author TEXT NOT NULL,
+			timestamp INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			PRIMARY KEY (key, idx)
+		)`,
+		`CREATE INDEX events_timestamp ON events(timestamp)`,
+	)(ctx, tx); err != nil {
+		return err
+	}
+
+	return eachSession(ctx, tx, func(key string, ps *persistSession) error {
+		for i, ev := range ps.Events {
+			if err := putEvent(ctx, tx, key, i, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// eachSession calls fn with every stored session. The sessions are read first, so fn may write to the table.
+func eachSession(ctx context.Context, tx *sql.Tx, fn func(key string, ps *persistSession) error) error {
+	rows, err := tx.QueryContext(ctx, `SELECT key, blob FROM sessions`)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		key  string
+		blob []byte
+	}
+	var all []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.key, &r.blob); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range all {
+		ps := &persistSession{}
+		if err := json.Unmarshal(r.blob, ps); err != nil {
+			return fmt.Errorf("unmarshal session %s: %w", r.key, err)
+		}
+		if err := fn(r.key, ps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
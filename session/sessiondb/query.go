@@ -0,0 +1,203 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sessiondb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zchee/tumix/session/retention"
+)
+
+// SessionFilter selects stored sessions. The zero value selects all of them.
+type SessionFilter struct {
+	// AppName, UserID, and SessionID, if set, select the sessions with that value.
+	AppName   string
+	UserID    string
+	SessionID string
+	// Since and Until, if set, select the sessions created at or after Since and before Until.
+	Since time.Time
+	Until time.Time
+	// Limit, if positive, is the maximum number of results.
+	Limit int
+}
+
+// where returns the conditions of f on the sessions table aliased as s, and their arguments.
+func (f SessionFilter) where() (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	for _, c := range []struct {
+		col string
+		val string
+	}{{"s.app_name", f.AppName}, {"s.user_id", f.UserID}, {"s.session_id", f.SessionID}} {
+		if c.val != "" {
+			conds = append(conds, c.col+" = ?")
+			args = append(args, c.val)
+		}
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "s.created_at >= ?")
+		args = append(args, f.Since.UnixNano())
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, "s.created_at < ?")
+		args = append(args, f.Until.UnixNano())
+	}
+	if len(conds) == 0 {
+		return "1 = 1", nil
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (f SessionFilter) limit() string {
+	if f.Limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d", f.Limit)
+}
+
+// SessionSummary describes a stored session without its state and events.
+type SessionSummary struct {
+	AppName   string    `json:"app_name"`
+	UserID    string    `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Events    int       `json:"events"`
+}
+
+// ListSessions returns the sessions selected by filter, the most recently created first.
+func (s *Store) ListSessions(ctx context.Context, filter SessionFilter) ([]SessionSummary, error) {
+	where, args := filter.where()
+	rows, err := s.db.QueryContext(ctx, `SELECT s.app_name, s.user_id, s.session_id, s.created_at, s.updated_at, s.event_count
+		FROM sessions s WHERE `+where+` ORDER BY s.created_at DESC, s.key`+filter.limit(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("sessiondb: list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SessionSummary
+	for rows.Next() {
+		var (
+			sum              SessionSummary
+			created, updated int64
+		)
+		if err := rows.Scan(&sum.AppName, &sum.UserID, &sum.SessionID, &created, &updated, &sum.Events); err != nil {
+			return nil, fmt.Errorf("sessiondb: scan: %w", err)
+		}
+		sum.CreatedAt, sum.UpdatedAt = unixTime(created), unixTime(updated)
+		out = append(out, sum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sessiondb: iterate rows: %w", err)
+	}
+
+	return out, nil
+}
+
+// EventMatch is a stored event whose text matches a [Store.SearchEvents] query.
+type EventMatch struct {
+	AppName   string `json:"app_name"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	// Index is the position of the event in the events of its session.
+	Index     int       `json:"index"`
+	Author    string    `json:"author"`
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+// SearchEvents returns the events of the sessions selected by filter whose text contains query, ignoring the case of
+// ASCII letters, the most recent first. filter.Limit limits the number of events.
+func (s *Store) SearchEvents(ctx context.Context, query string, filter SessionFilter) ([]EventMatch, error) {
+	if query == "" {
+		return nil, errors.New("sessiondb: search query required")
+	}
+	where, args := filter.where()
+	args = append([]any{"%" + escapeLike(query) + "%"}, args...)
+	rows, err := s.db.QueryContext(ctx, `SELECT s.app_name, s.user_id, s.session_id, e.idx, e.author, e.timestamp, e.text
+		FROM events e JOIN sessions s ON s.key = e.key
+		WHERE e.text LIKE ? ESCAPE '\' AND `+where+` ORDER BY e.timestamp DESC, e.key, e.idx`+filter.limit(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("sessiondb: search events: %w", err)
+	}
+	defer rows.Close()
+
+	var out []EventMatch
+	for rows.Next() {
+		var (
+			m  EventMatch
+			ts int64
+		)
+		if err := rows.Scan(&m.AppName, &m.UserID, &m.SessionID, &m.Index, &m.Author, &ts, &m.Text); err != nil {
+			return nil, fmt.Errorf("sessiondb: scan: %w", err)
+		}
+		m.Timestamp = unixTime(ts)
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sessiondb: iterate rows: %w", err)
+	}
+
+	return out, nil
+}
+
+// Usage implements [retention.Store]. The size of a session is the size of its stored blob.
+func (s *Store) Usage(ctx context.Context) ([]retention.Usage, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT app_name, user_id, session_id, updated_at, length(blob) FROM sessions`)
+	if err != nil {
+		return nil, fmt.Errorf("sessiondb: usage: %w", err)
+	}
+	defer rows.Close()
+
+	var out []retention.Usage
+	for rows.Next() {
+		var (
+			u       retention.Usage
+			updated int64
+		)
+		if err := rows.Scan(&u.AppName, &u.UserID, &u.SessionID, &updated, &u.Bytes); err != nil {
+			return nil, fmt.Errorf("sessiondb: scan: %w", err)
+		}
+		u.UpdatedAt = unixTime(updated)
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sessiondb: iterate rows: %w", err)
+	}
+
+	return out, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func unixTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
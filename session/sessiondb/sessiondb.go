@@ -14,6 +14,8 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
+// Package sessiondb provides a sqlite-backed implementation of adk's session.Service, with a query API over the
+// stored sessions and events.
 package sessiondb
 
 import (
@@ -30,12 +32,24 @@ import (
 	"time"
 
 	"google.golang.org/adk/session"
+	"google.golang.org/genai"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/zchee/tumix/session/retention"
 )
 
 // Service returns a sqlite-backed [session.Service] stored at file path.
 func Service(ctx context.Context, path string) (session.Service, error) {
+	st, err := Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Open opens the sqlite session store at file path, creating or migrating its schema to the current version.
+func Open(ctx context.Context, path string) (*Store, error) {
 	if path == "" {
 		return nil, errors.New("sessiondb: path required")
 	}
@@ -49,23 +63,29 @@ func Service(ctx context.Context, path string) (session.Service, error) {
 		return nil, fmt.Errorf("sessiondb: open sqlite: %w", err)
 	}
 
-	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS sessions (
-		key TEXT PRIMARY KEY,
-		blob BLOB NOT NULL
-	)`); err != nil {
-		return nil, fmt.Errorf("sessiondb: create table: %w", err)
+	if err := migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
 	}
 
-	return &store{db: db}, nil
+	return &Store{db: db}, nil
 }
 
-// store implements [session.Service] using sqlite.
-type store struct {
+// Store implements [session.Service] using sqlite.
+type Store struct {
 	db *sql.DB
 	mu sync.Mutex
 }
 
-var _ session.Service = (*store)(nil)
+var (
+	_ session.Service = (*Store)(nil)
+	_ retention.Store = (*Store)(nil)
+)
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
 
 type persistSession struct {
 	AppName   string           `json:"app_name"`
@@ -73,15 +93,28 @@ type persistSession struct {
 	SessionID string           `json:"session_id"`
 	State     map[string]any   `json:"state"`
 	Events    []*session.Event `json:"events"`
+	CreatedAt time.Time        `json:"created_at,omitzero"`
 	UpdatedAt time.Time        `json:"updated_at"`
 }
 
-func (s *store) key(app, user, sessionID string) string {
+// createdAt returns the creation time of ps. Sessions stored before it was recorded fall back to their first event.
+func (ps *persistSession) createdAt() time.Time {
+	switch {
+	case !ps.CreatedAt.IsZero():
+		return ps.CreatedAt
+	case len(ps.Events) > 0 && !ps.Events[0].Timestamp.IsZero():
+		return ps.Events[0].Timestamp
+	default:
+		return ps.UpdatedAt
+	}
+}
+
+func (s *Store) key(app, user, sessionID string) string {
 	return fmt.Sprintf("%s/%s/%s", app, user, sessionID)
 }
 
 // Create implements [session.Service].
-func (s *store) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+func (s *Store) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
 	if req.AppName == "" || req.UserID == "" {
 		return nil, errors.New("sessiondb: app_name and user_id required")
 	}
@@ -94,18 +127,20 @@ func (s *store) Create(ctx context.Context, req *session.CreateRequest) (*sessio
 	}
 
 	k := s.key(req.AppName, req.UserID, req.SessionID)
+	now := time.Now()
 	ps := &persistSession{
 		AppName:   req.AppName,
 		UserID:    req.UserID,
 		SessionID: req.SessionID,
 		State:     map[string]any{},
-		UpdatedAt: time.Now(),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 	if req.State != nil {
 		ps.State = req.State
 	}
 
-	if err := s.put(ctx, k, ps, true); err != nil {
+	if err := s.put(ctx, s.db, k, ps, true); err != nil {
 		return nil, err
 	}
 
@@ -117,7 +152,7 @@ func (s *store) Create(ctx context.Context, req *session.CreateRequest) (*sessio
 }
 
 // Get implements [session.Service].
-func (s *store) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+func (s *Store) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
 	ps, err := s.load(ctx, req.AppName, req.UserID, req.SessionID)
 	if err != nil {
 		return nil, err
@@ -131,12 +166,17 @@ func (s *store) Get(ctx context.Context, req *session.GetRequest) (*session.GetR
 }
 
 // List implements [session.Service].
-func (s *store) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+func (s *Store) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
 	if req.AppName == "" {
 		return nil, errors.New("sessiondb: app_name required")
 	}
 
-	rows, err := s.db.QueryContext(ctx, `SELECT blob FROM sessions WHERE key LIKE ?`, req.AppName+"/%")
+	query, args := `SELECT blob FROM sessions WHERE app_name = ?`, []any{req.AppName}
+	if req.UserID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, req.UserID)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("sessiondb: list query: %w", err)
 	}
@@ -152,9 +192,6 @@ func (s *store) List(ctx context.Context, req *session.ListRequest) (*session.Li
 		if err := json.Unmarshal(b, ps); err != nil {
 			return nil, fmt.Errorf("sessiondb: unmarshal session: %w", err)
 		}
-		if req.UserID != "" && ps.UserID != req.UserID {
-			continue
-		}
 		sessions = append(sessions, &dbSession{
 			s: ps,
 		})
@@ -170,19 +207,32 @@ func (s *store) List(ctx context.Context, req *session.ListRequest) (*session.Li
 }
 
 // Delete implements [session.Service].
-func (s *store) Delete(ctx context.Context, req *session.DeleteRequest) error {
+func (s *Store) Delete(ctx context.Context, req *session.DeleteRequest) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE key = ?`, s.key(req.AppName, req.UserID, req.SessionID)); err != nil {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sessiondb: begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	key := s.key(req.AppName, req.UserID, req.SessionID)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE key = ?`, key); err != nil {
 		return fmt.Errorf("sessiondb: delete: %w", err)
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM events WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("sessiondb: delete events: %w", err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sessiondb: commit: %w", err)
+	}
 	return nil
 }
 
 // AppendEvent implements [session.Service].
-func (s *store) AppendEvent(ctx context.Context, sess session.Session, ev *session.Event) error {
+func (s *Store) AppendEvent(ctx context.Context, sess session.Session, ev *session.Event) error {
 	if ev == nil || sess == nil {
 		return errors.New("sessiondb: event and session required")
 	}
@@ -209,10 +259,32 @@ func (s *store) AppendEvent(ctx context.Context, sess session.Session, ev *sessi
 	ps.Events = append(ps.Events, ev)
 	ps.UpdatedAt = ev.Timestamp
 
-	return s.put(ctx, s.key(ps.AppName, ps.UserID, ps.SessionID), ps, false)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sessiondb: begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	key := s.key(ps.AppName, ps.UserID, ps.SessionID)
+	if err := s.put(ctx, tx, key, ps, false); err != nil {
+		return err
+	}
+	if err := putEvent(ctx, tx, key, len(ps.Events)-1, ev); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sessiondb: commit: %w", err)
+	}
+	return nil
+}
+
+// execer is a [*sql.DB] or a [*sql.Tx].
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
-func (s *store) put(ctx context.Context, key string, ps *persistSession, failIfExists bool) error {
+func (s *Store) put(ctx context.Context, db execer, key string, ps *persistSession, failIfExists bool) error {
 	b, err := json.Marshal(ps)
 	if err != nil {
 		return fmt.Errorf("sessiondb: marshal: %w", err)
@@ -223,14 +295,16 @@ func (s *store) put(ctx context.Context, key string, ps *persistSession, failIfE
 		op = `INSERT`
 	}
 
-	if _, err := s.db.ExecContext(ctx, op+` INTO sessions(key, blob) VALUES(?,?)`, key, b); err != nil {
+	if _, err := db.ExecContext(ctx, op+` INTO sessions(key, blob, app_name, user_id, session_id, created_at, updated_at, event_count)
+		VALUES(?,?,?,?,?,?,?,?)`,
+		key, b, ps.AppName, ps.UserID, ps.SessionID, ps.createdAt().UnixNano(), ps.UpdatedAt.UnixNano(), len(ps.Events)); err != nil {
 		return fmt.Errorf("sessiondb: upsert: %w", err)
 	}
 
 	return nil
 }
 
-func (s *store) load(ctx context.Context, app, user, sid string) (*persistSession, error) {
+func (s *Store) load(ctx context.Context, app, user, sid string) (*persistSession, error) {
 	if app == "" || user == "" || sid == "" {
 		return nil, errors.New("sessiondb: app/user/session required")
 	}
@@ -250,6 +324,29 @@ func (s *store) load(ctx context.Context, app, user, sid string) (*persistSessio
 	return ps, nil
 }
 
+// putEvent indexes the event at index i of the session stored under key for [Store.SearchEvents].
+func putEvent(ctx context.Context, db execer, key string, i int, ev *session.Event) error {
+	if _, err := db.ExecContext(ctx, `INSERT OR REPLACE INTO events(key, idx, author, timestamp, text) VALUES(?,?,?,?,?)`,
+		key, i, ev.Author, ev.Timestamp.UnixNano(), eventText(ev.Content)); err != nil {
+		return fmt.Errorf("sessiondb: insert event: %w", err)
+	}
+	return nil
+}
+
+// eventText returns the text parts of content, one per line.
+func eventText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range content.Parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
 // dbSession implements [session.Session] using in-memory struct.
 type dbSession struct {
 	s *persistSession
@@ -17,6 +17,7 @@
 package sessiondb
 
 import (
+	"database/sql"
 	"errors"
 	"maps"
 	"path/filepath"
@@ -24,7 +25,9 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/adk/session"
+	"google.golang.org/genai"
 )
 
 func TestSQLiteSessionLifecycleAndFilters(t *testing.T) {
@@ -202,7 +205,7 @@ func TestSQLiteSessionErrorPaths(t *testing.T) {
 	}
 
 	// load should error if fields missing
-	store := svc.(*store)
+	store := svc.(*Store)
 	if _, err := store.load(ctx, "", "u", "dup"); err == nil {
 		t.Fatalf("load missing app should error")
 	}
@@ -242,3 +245,174 @@ func TestSessionfsStateReuseInSessiondb(t *testing.T) {
 		t.Fatalf("state iteration diff (-want +got): %s", diff)
 	}
 }
+
+func appendText(t *testing.T, svc session.Service, app, user, sid, author, text string, ts time.Time) {
+	t.Helper()
+
+	got, err := svc.Get(t.Context(), &session.GetRequest{AppName: app, UserID: user, SessionID: sid})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	ev := session.NewEvent("inv")
+	ev.Author = author
+	ev.Timestamp = ts
+	ev.Content = genai.NewContentFromText(text, genai.RoleModel)
+	if err := svc.AppendEvent(t.Context(), got.Session, ev); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+}
+
+func TestSQLiteMigrateUnversioned(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+
+	// A database written before the migrations: the sessions table of version 1 and user_version 0.
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE sessions (key TEXT PRIMARY KEY, blob BLOB NOT NULL)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	blob := `{"app_name":"app","user_id":"u","session_id":"old","state":{},"updated_at":"2025-03-01T12:05:00Z",
+		"events":[{"Author":"tumix","Timestamp":"2025-03-01T12:00:00Z","Content":{"parts":[{"text":"the answer is <<<42>>>"}],"role":"model"}}]}`
+	if _, err := db.ExecContext(ctx, `INSERT INTO sessions(key, blob) VALUES(?, ?)`, "app/u/old", blob); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	store, err := Open(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if got, err := store.SchemaVersion(ctx); err != nil || got != LatestSchemaVersion() {
+		t.Fatalf("SchemaVersion() = %d, %v, want %d", got, err, LatestSchemaVersion())
+	}
+
+	sessions, err := store.ListSessions(ctx, SessionFilter{AppName: "app"})
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	want := []SessionSummary{{
+		AppName: "app", UserID: "u", SessionID: "old",
+		CreatedAt: created, UpdatedAt: created.Add(5 * time.Minute), Events: 1,
+	}}
+	if diff := cmp.Diff(want, sessions, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Fatalf("ListSessions() mismatch (-want +got):\n%s", diff)
+	}
+	matches, err := store.SearchEvents(ctx, "ANSWER", SessionFilter{})
+	if err != nil || len(matches) != 1 || matches[0].SessionID != "old" || matches[0].Author != "tumix" {
+		t.Fatalf("SearchEvents() = %+v, %v, want the migrated event", matches, err)
+	}
+
+	// Reopening a migrated database is a no-op.
+	again, err := Open(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	_ = again.Close()
+}
+
+func TestSQLiteQuery(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	store, err := Open(ctx, filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	for _, id := range []struct{ app, user, sid string }{{"app", "u1", "s1"}, {"app", "u1", "s2"}, {"app", "u2", "s3"}, {"other", "u1", "s4"}} {
+		if _, err := store.Create(ctx, &session.CreateRequest{AppName: id.app, UserID: id.user, SessionID: id.sid}); err != nil {
+			t.Fatalf("Create %s: %v", id.sid, err)
+		}
+		time.Sleep(time.Millisecond) // distinct creation times
+	}
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	appendText(t, store, "app", "u1", "s1", "user", "What is 6*7?", base)
+	appendText(t, store, "app", "u1", "s1", "tumix", "<<<42>>>", base.Add(time.Second))
+	appendText(t, store, "app", "u2", "s3", "tumix", "100% sure: <<<42_000>>>", base.Add(2*time.Second))
+	appendText(t, store, "other", "u1", "s4", "tumix", "<<<42>>>", base.Add(3*time.Second))
+
+	ids := func(sessions []SessionSummary) []string {
+		var out []string
+		for _, s := range sessions {
+			out = append(out, s.SessionID)
+		}
+		return out
+	}
+	tests := map[string]struct {
+		filter SessionFilter
+		want   []string
+	}{
+		"all":     {want: []string{"s4", "s3", "s2", "s1"}},
+		"app":     {filter: SessionFilter{AppName: "app"}, want: []string{"s3", "s2", "s1"}},
+		"user":    {filter: SessionFilter{AppName: "app", UserID: "u1"}, want: []string{"s2", "s1"}},
+		"session": {filter: SessionFilter{SessionID: "s3"}, want: []string{"s3"}},
+		"limit":   {filter: SessionFilter{Limit: 2}, want: []string{"s4", "s3"}},
+		"until":   {filter: SessionFilter{Until: time.Now().Add(-time.Hour)}},
+		"since":   {filter: SessionFilter{Since: time.Now().Add(-time.Hour)}, want: []string{"s4", "s3", "s2", "s1"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := store.ListSessions(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListSessions: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, ids(got)); diff != "" {
+				t.Fatalf("ListSessions(%+v) mismatch (-want +got):\n%s", tt.filter, diff)
+			}
+		})
+	}
+
+	sessions, err := store.ListSessions(ctx, SessionFilter{SessionID: "s1"})
+	if err != nil || len(sessions) != 1 || sessions[0].Events != 2 || !sessions[0].UpdatedAt.Equal(base.Add(time.Second)) {
+		t.Fatalf("ListSessions(s1) = %+v, %v, want 2 events updated at %v", sessions, err, base.Add(time.Second))
+	}
+
+	matches, err := store.SearchEvents(ctx, "<<<42", SessionFilter{AppName: "app"})
+	if err != nil {
+		t.Fatalf("SearchEvents: %v", err)
+	}
+	want := []EventMatch{
+		{AppName: "app", UserID: "u2", SessionID: "s3", Index: 0, Author: "tumix", Timestamp: base.Add(2 * time.Second), Text: "100% sure: <<<42_000>>>"},
+		{AppName: "app", UserID: "u1", SessionID: "s1", Index: 1, Author: "tumix", Timestamp: base.Add(time.Second), Text: "<<<42>>>"},
+	}
+	if diff := cmp.Diff(want, matches, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Fatalf("SearchEvents() mismatch (-want +got):\n%s", diff)
+	}
+	// LIKE wildcards in the query match literally.
+	for query, wantN := range map[string]int{"100%": 1, "42_": 1, "%": 1, "4_": 0} {
+		got, err := store.SearchEvents(ctx, query, SessionFilter{})
+		if err != nil || len(got) != wantN {
+			t.Fatalf("SearchEvents(%q) = %+v, %v, want %d matches", query, got, err, wantN)
+		}
+	}
+
+	usage, err := store.Usage(ctx)
+	if err != nil || len(usage) != 4 {
+		t.Fatalf("Usage() = %+v, %v, want 4 sessions", usage, err)
+	}
+	for _, u := range usage {
+		if u.Bytes <= 0 || u.UpdatedAt.IsZero() {
+			t.Fatalf("Usage() = %+v, want the size and update time of every session", usage)
+		}
+	}
+
+	if err := store.Delete(ctx, &session.DeleteRequest{AppName: "app", UserID: "u2", SessionID: "s3"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, err := store.SearchEvents(ctx, "100%", SessionFilter{}); err != nil || len(got) != 0 {
+		t.Fatalf("SearchEvents() after delete = %+v, %v, want none", got, err)
+	}
+	if _, err := store.SearchEvents(ctx, "", SessionFilter{}); err == nil {
+		t.Fatal("SearchEvents() with an empty query succeeded, want error")
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sessionfs
+
+import (
+	"bytes"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"runtime"
+	"strconv"
+
+	"google.golang.org/adk/session"
+)
+
+const (
+	snapshotFile = "sessions.json"
+	journalFile  = "sessions.journal"
+	lockFile     = "sessions.lock"
+
+	// snapshotVersion is the version of the snapshot format. Snapshots without a version are the bare session map
+	// written before the journal existed.
+	snapshotVersion = 1
+)
+
+// snapshot is the on-disk representation of all sessions, up to the journal record numbered Seq.
+type snapshot struct {
+	Version  int                        `json:"version"`
+	Seq      uint64                     `json:"seq"`
+	Sessions map[string]*persistSession `json:"sessions"`
+}
+
+// Journal operations.
+const (
+	opCreate = "create"
+	opAppend = "append"
+	opDelete = "delete"
+)
+
+// journalRecord is one mutation in the journal. Each is written on its own line, prefixed by the CRC-32 of its JSON
+// encoding, so that a record cut short by a crash is detected.
+type journalRecord struct {
+	Seq     uint64          `json:"seq"`
+	Op      string          `json:"op"`
+	Key     string          `json:"key"`
+	Session *persistSession `json:"session,omitzero"`
+	Event   *session.Event  `json:"event,omitzero"`
+}
+
+// load recovers the sessions from the snapshot and the journal, and compacts the journal if it has any record.
+func (f *fileService) load() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lock, err := f.dir.Lock(lockFile)
+	if err != nil {
+		return fmt.Errorf("sessionfs: %w", err)
+	}
+	defer lock.Unlock() //nolint:errcheck // released with the file anyway
+
+	// A temporary snapshot is left by a crash during a compaction, which the journal still covers.
+	if err := os.Remove(f.snapshotPath + ".tmp"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("sessionfs: remove stale snapshot: %w", err)
+	}
+
+	journal, err := os.OpenFile(f.journalPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("sessionfs: open journal: %w", err)
+	}
+	f.journal = journal
+	if err := f.reloadLocked(); err != nil {
+		return err
+	}
+	if f.records == 0 {
+		return nil
+	}
+
+	return f.compactLocked()
+}
+
+// lockLocked locks the directory against other processes, then catches up with the mutations they made since the
+// last operation. The returned function releases the lock.
+func (f *fileService) lockLocked() (unlock func(), err error) {
+	lock, err := f.dir.Lock(lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("sessionfs: %w", err)
+	}
+	if err := f.refreshLocked(); err != nil {
+		_ = lock.Unlock()
+		return nil, err
+	}
+	return func() { _ = lock.Unlock() }, nil
+}
+
+// refreshLocked catches up with the mutations other processes made: the records they appended to the journal are
+// replayed, and everything is reloaded if one of them compacted the journal into a new snapshot.
+func (f *fileService) refreshLocked() error {
+	info, err := os.Stat(f.snapshotPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("sessionfs: stat snapshot: %w", err)
+	}
+	if snapshotChanged(f.snapshotInfo, info) {
+		return f.reloadLocked()
+	}
+
+	st, err := f.journal.Stat()
+	if err != nil {
+		return fmt.Errorf("sessionfs: stat journal: %w", err)
+	}
+	switch size := st.Size(); {
+	case size == f.journalSize:
+		return nil
+	case size < f.journalSize:
+		return f.reloadLocked()
+	default:
+		data := make([]byte, size-f.journalSize)
+		if _, err := f.journal.ReadAt(data, f.journalSize); err != nil {
+			return fmt.Errorf("sessionfs: read journal: %w", err)
+		}
+		n, records, err := f.replay(data)
+		if err != nil {
+			return err
+		}
+		f.records += records
+		return f.truncateTail(f.journalSize+int64(n), size)
+	}
+}
+
+// reloadLocked reads the sessions from the snapshot and the journal again. The sessions already in memory are updated
+// in place, so that the sessions handed out stay current.
+func (f *fileService) reloadLocked() error {
+	data, err := os.ReadFile(f.snapshotPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("sessionfs: read: %w", err)
+	}
+	info, err := os.Stat(f.snapshotPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("sessionfs: stat snapshot: %w", err)
+	}
+	sessions := make(map[string]*persistSession)
+	var seq uint64
+	if len(data) > 0 {
+		if sessions, seq, err = decodeSnapshot(data); err != nil {
+			return err
+		}
+	}
+	for key := range f.sessions {
+		if _, ok := sessions[key]; !ok {
+			delete(f.sessions, key)
+		}
+	}
+	for key, ps := range sessions {
+		if cur, ok := f.sessions[key]; ok {
+			*cur = *ps
+			continue
+		}
+		f.sessions[key] = ps
+	}
+	f.seq = seq
+	f.snapshotInfo = info
+
+	data, err = os.ReadFile(f.journalPath)
+	if err != nil {
+		return fmt.Errorf("sessionfs: read journal: %w", err)
+	}
+	n, records, err := f.replay(data)
+	if err != nil {
+		return err
+	}
+	f.records = records
+	return f.truncateTail(int64(n), int64(len(data)))
+}
+
+// truncateTail sets the journal size to valid, dropping the record cut short by a crash that ends the journal of
+// the given size, if any.
+func (f *fileService) truncateTail(valid, size int64) error {
+	f.journalSize = valid
+	if valid == size {
+		return nil
+	}
+	if err := f.journal.Truncate(valid); err != nil {
+		return fmt.Errorf("sessionfs: truncate journal: %w", err)
+	}
+	return nil
+}
+
+// snapshotChanged reports whether the snapshot described by cur replaced the one described by old; either is nil if
+// there was no snapshot.
+func snapshotChanged(old, cur os.FileInfo) bool {
+	if old == nil || cur == nil {
+		return (old == nil) != (cur == nil)
+	}
+	return !os.SameFile(old, cur) || old.Size() != cur.Size() || !old.ModTime().Equal(cur.ModTime())
+}
+
+// decodeSnapshot decodes the sessions of a snapshot, in the current or the unversioned format, and the sequence number
+// of the last journal record it covers.
+func decodeSnapshot(data []byte) (map[string]*persistSession, uint64, error) {
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, 0, fmt.Errorf("sessionfs: unmarshal sessions: %w", err)
+	}
+	if probe.Version == 0 {
+		sessions := make(map[string]*persistSession)
+		if err := json.Unmarshal(data, &sessions); err != nil {
+			return nil, 0, fmt.Errorf("sessionfs: unmarshal sessions: %w", err)
+		}
+		return sessions, 0, nil
+	}
+	if probe.Version != snapshotVersion {
+		return nil, 0, fmt.Errorf("sessionfs: unsupported snapshot version %d", probe.Version)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, 0, fmt.Errorf("sessionfs: unmarshal sessions: %w", err)
+	}
+	if snap.Sessions == nil {
+		snap.Sessions = make(map[string]*persistSession)
+	}
+
+	return snap.Sessions, snap.Seq, nil
+}
+
+// replay applies the journal records in data that the snapshot does not cover yet, and returns the length of the
+// valid records in data and their number. It stops at the first invalid record if no valid record follows it, as the
+// record was cut short by a crash; otherwise the journal is corrupt.
+func (f *fileService) replay(data []byte) (n, records int, err error) {
+	for n < len(data) {
+		end := bytes.IndexByte(data[n:], '\n')
+		if end < 0 {
+			return n, records, nil
+		}
+		rec, ok := decodeRecord(data[n : n+end])
+		if !ok {
+			if hasValidRecord(data[n+end+1:]) {
+				return n, records, fmt.Errorf("sessionfs: corrupt journal record at offset %d", n)
+			}
+			return n, records, nil
+		}
+		n += end + 1
+		records++
+
+		if rec.Seq <= f.seq {
+			continue // already in the snapshot
+		}
+		if err := f.apply(rec); err != nil {
+			return n, records, err
+		}
+		f.seq = rec.Seq
+	}
+
+	return n, records, nil
+}
+
+// encodeRecord returns the journal line of rec.
+func encodeRecord(rec *journalRecord) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("sessionfs: marshal: %w", err)
+	}
+	line := strconv.AppendUint(make([]byte, 0, len(data)+10), uint64(crc32.ChecksumIEEE(data)), 16)
+	line = append(line, ' ')
+	line = append(line, data...)
+	return append(line, '\n'), nil
+}
+
+// decodeRecord decodes a journal line, without its newline. It reports false if the line is not a valid record.
+func decodeRecord(line []byte) (*journalRecord, bool) {
+	sum, data, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return nil, false
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil || crc32.ChecksumIEEE(data) != uint32(want) {
+		return nil, false
+	}
+	var rec journalRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, false
+	}
+	return &rec, true
+}
+
+func hasValidRecord(data []byte) bool {
+	for line := range bytes.Lines(data) {
+		if _, ok := decodeRecord(bytes.TrimSuffix(line, []byte("\n"))); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply applies the mutation of rec to the sessions in memory.
+func (f *fileService) apply(rec *journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		if rec.Session == nil {
+			return fmt.Errorf("sessionfs: journal record %d creates no session", rec.Seq)
+		}
+		f.sessions[rec.Key] = rec.Session
+	case opAppend:
+		ps, ok := f.sessions[rec.Key]
+		if !ok || rec.Event == nil {
+			return fmt.Errorf("sessionfs: journal record %d appends to missing session %s", rec.Seq, rec.Key)
+		}
+		applyState(ps, rec.Event)
+		ps.Events = append(ps.Events, rec.Event)
+		ps.UpdatedAt = rec.Event.Timestamp
+	case opDelete:
+		delete(f.sessions, rec.Key)
+	default:
+		return fmt.Errorf("sessionfs: journal record %d has unknown op %q", rec.Seq, rec.Op)
+	}
+	return nil
+}
+
+// commitLocked journals rec, then applies it. The journal is compacted when it has enough records; a failed
+// compaction does not fail the mutation, already journaled, and is retried on the next one.
+func (f *fileService) commitLocked(rec *journalRecord) error {
+	rec.Seq = f.seq + 1
+	line, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+
+	if err := f.writeJournal(line); err != nil {
+		// Drop what was written of the record, so the next one does not follow a corrupt record.
+		_ = f.journal.Truncate(f.journalSize)
+		return err
+	}
+	f.journalSize += int64(len(line))
+	f.records++
+	f.seq = rec.Seq
+
+	if err := f.apply(rec); err != nil {
+		return err
+	}
+	if f.records >= f.opts.CompactEvery {
+		_ = f.compactLocked()
+	}
+
+	return nil
+}
+
+func (f *fileService) writeJournal(line []byte) error {
+	if err := f.inject("journal"); err != nil {
+		return err
+	}
+	if _, err := f.journal.Write(line); err != nil {
+		return fmt.Errorf("sessionfs: write journal: %w", err)
+	}
+	if f.opts.Sync == SyncAlways {
+		if err := f.journal.Sync(); err != nil {
+			return fmt.Errorf("sessionfs: sync journal: %w", err)
+		}
+	}
+	return nil
+}
+
+// compactLocked writes a new snapshot of the sessions, then empties the journal. A crash in between leaves records
+// in the journal that the snapshot covers, which are skipped by their sequence number when the journal is replayed.
+func (f *fileService) compactLocked() error {
+	data, err := json.Marshal(snapshot{Version: snapshotVersion, Seq: f.seq, Sessions: f.sessions})
+	if err != nil {
+		return fmt.Errorf("sessionfs: marshal: %w", err)
+	}
+	if err := f.writeSnapshot(data); err != nil {
+		return err
+	}
+
+	if err := f.inject("truncate"); err != nil {
+		return err
+	}
+	if err := f.journal.Truncate(0); err != nil {
+		return fmt.Errorf("sessionfs: truncate journal: %w", err)
+	}
+	if f.opts.Sync != SyncNever {
+		if err := f.journal.Sync(); err != nil {
+			return fmt.Errorf("sessionfs: sync journal: %w", err)
+		}
+	}
+	f.journalSize = 0
+	f.records = 0
+
+	return nil
+}
+
+// writeSnapshot replaces the snapshot with data atomically: it is written to a temporary file, flushed, and renamed
+// over the snapshot.
+func (f *fileService) writeSnapshot(data []byte) error {
+	tmp := f.snapshotPath + ".tmp"
+	sync := f.opts.Sync != SyncNever
+
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("sessionfs: write tmp: %w", err)
+	}
+	_, err = file.Write(data)
+	if err == nil && sync {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("sessionfs: write tmp: %w", err)
+	}
+
+	if err := f.inject("rename"); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.snapshotPath); err != nil {
+		return fmt.Errorf("sessionfs: rename: %w", err)
+	}
+	// Without it, the next operation reloads the snapshot as if another process had written it.
+	f.snapshotInfo, _ = os.Stat(f.snapshotPath)
+	if sync {
+		return syncDir(f.dir.Path())
+	}
+
+	return nil
+}
+
+// syncDir flushes the directory entries of dir, making a rename in it durable. Windows cannot flush a directory, and
+// does nothing there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("sessionfs: sync dir: %w", err)
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("sessionfs: sync dir: %w", err)
+	}
+	return nil
+}
+
+func (f *fileService) inject(step string) error {
+	if f.fault == nil {
+		return nil
+	}
+	return f.fault(step)
+}
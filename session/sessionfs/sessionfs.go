@@ -15,9 +15,17 @@
 // SPDX-License-Identifier: Apache-2.0
 
 // Package sessionfs provides a lightweight file-backed implementation of
-// adk's session.Service. It keeps an in-memory map and snapshots it to disk
-// after each mutation. Concurrency is guarded with an in-process mutex only;
-// cross-process synchronization is not provided.
+// adk's session.Service. It keeps an in-memory map, persisted as a snapshot
+// and an append-only journal of the mutations since: every mutation is
+// journaled before it is applied, and the journal is compacted into a new
+// snapshot, written to a temporary file and renamed over the previous one,
+// every [Options.CompactEvery] records. A crash at any point loses at most
+// the record being written, which is discarded when the store is reopened.
+// Concurrency is guarded with an in-process mutex; every operation also locks
+// the directory against other processes and first catches up with the
+// mutations they journaled, so several processes may share a directory.
+// The app name, user ID, and session ID of a session must be valid file
+// names, as checked by [storage.ValidateName].
 package sessionfs
 
 import (
@@ -32,31 +40,79 @@ import (
 	"sync"
 	"time"
 
-	"golang.org/x/sys/unix"
 	"google.golang.org/adk/session"
+
+	"github.com/zchee/tumix/internal/storage"
+	"github.com/zchee/tumix/session/retention"
+)
+
+// SyncPolicy decides when the store flushes its files to stable storage with fsync.
+type SyncPolicy string
+
+const (
+	// SyncAlways flushes every journal record before the mutation returns, so an acknowledged mutation survives a
+	// power loss. It is the default.
+	SyncAlways SyncPolicy = "always"
+	// SyncCompaction flushes the snapshots only. A process crash loses nothing, but a power loss may lose the
+	// mutations journaled since the last compaction.
+	SyncCompaction SyncPolicy = "compaction"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
 )
 
-// Service returns a file-backed session service rooted at dir.
+// DefaultCompactEvery is the number of journal records after which the journal is compacted by default.
+const DefaultCompactEvery = 256
+
+// Options configures a file-backed session service.
+type Options struct {
+	// Sync decides when the files are flushed with fsync; empty uses [SyncAlways].
+	Sync SyncPolicy
+	// CompactEvery is the number of journal records after which the journal is compacted into a new snapshot;
+	// zero uses [DefaultCompactEvery].
+	CompactEvery int
+}
+
+// Service returns a file-backed session service rooted at dir, with the default [Options].
 func Service(dir string) (session.Service, error) {
+	return ServiceWithOptions(dir, Options{})
+}
+
+// ServiceWithOptions returns a file-backed session service rooted at dir.
+//
+// The sessions are recovered from the snapshot and the journal left in dir, if any. A record cut short by a crash at
+// the end of the journal is discarded; a corrupt record followed by valid ones is an error.
+func ServiceWithOptions(dir string, opts Options) (session.Service, error) {
 	if dir == "" {
 		return nil, errors.New("sessionfs: dir is required")
 	}
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return nil, fmt.Errorf("sessionfs: mkdir %s: %w", dir, err)
+	switch opts.Sync {
+	case "":
+		opts.Sync = SyncAlways
+	case SyncAlways, SyncCompaction, SyncNever:
+		// ok
+	default:
+		return nil, fmt.Errorf("sessionfs: unknown sync policy %q", opts.Sync)
 	}
-
-	lockFile, err := os.OpenFile(filepath.Join(dir, "sessions.lock"), os.O_CREATE|os.O_RDWR, 0o600)
-	if err != nil {
-		return nil, fmt.Errorf("sessionfs: lock file: %w", err)
+	if opts.CompactEvery < 0 {
+		return nil, errors.New("sessionfs: compact every cannot be negative")
 	}
-	if err := unix.Flock(int(lockFile.Fd()), unix.LOCK_EX); err != nil {
-		return nil, fmt.Errorf("sessionfs: flock: %w", err)
+	if opts.CompactEvery == 0 {
+		opts.CompactEvery = DefaultCompactEvery
+	}
+	root, err := storage.OpenDir(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("sessionfs: %w", err)
 	}
 
 	fs := &fileService{
-		root:     dir,
+		dir:      root,
+		opts:     opts,
 		sessions: make(map[string]*persistSession),
-		lockFile: lockFile,
+	}
+	for path, name := range map[*string]string{&fs.snapshotPath: snapshotFile, &fs.journalPath: journalFile} {
+		if *path, err = root.Join(name); err != nil {
+			return nil, fmt.Errorf("sessionfs: %w", err)
+		}
 	}
 	if err := fs.load(); err != nil {
 		return nil, err
@@ -78,57 +134,44 @@ type persistSession struct {
 // fileService implements [session.Service].
 type fileService struct {
 	mu       sync.RWMutex
-	root     string
+	dir      *storage.Dir
+	opts     Options
 	sessions map[string]*persistSession
-	lockFile *os.File
-}
 
-var _ session.Service = (*fileService)(nil)
-
-func (f *fileService) key(app, user, sessionID string) string {
-	return filepath.Join(app, user, sessionID)
+	snapshotPath string
+	journalPath  string
+	// snapshotInfo describes the snapshot file last read or written, to detect its replacement by another process.
+	snapshotInfo os.FileInfo
+
+	// journal is the open journal file, journalSize its length, and records the number of records in it.
+	journal     *os.File
+	journalSize int64
+	records     int
+	// seq is the sequence number of the last journaled mutation.
+	seq uint64
+
+	// fault, if set, is called before each step of a write that a crash could interrupt, and an error aborts the
+	// write there. Tests use it to inject crashes.
+	fault func(step string) error
 }
 
-func (f *fileService) load() error {
+var (
+	_ session.Service = (*fileService)(nil)
+	_ retention.Store = (*fileService)(nil)
+)
+
+// Close closes the journal. The service must not be used afterwards.
+func (f *fileService) Close() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-
-	dataPath := filepath.Join(f.root, "sessions.json")
-	data, err := os.ReadFile(dataPath)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
-		}
-		return fmt.Errorf("sessionfs: read: %w", err)
-	}
-	if len(data) == 0 {
-		return nil
+	if err := f.journal.Close(); err != nil {
+		return fmt.Errorf("sessionfs: close journal: %w", err)
 	}
-
-	if err := json.Unmarshal(data, &f.sessions); err != nil {
-		return fmt.Errorf("sessionfs: unmarshal sessions: %w", err)
-	}
-
 	return nil
 }
 
-func (f *fileService) saveLocked() error {
-	dataPath := filepath.Join(f.root, "sessions.json")
-	tmp := dataPath + ".tmp"
-	data, err := json.Marshal(f.sessions)
-	if err != nil {
-		return fmt.Errorf("sessionfs: marshal: %w", err)
-	}
-
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return fmt.Errorf("sessionfs: write tmp: %w", err)
-	}
-
-	if err := os.Rename(tmp, dataPath); err != nil {
-		return fmt.Errorf("sessionfs: rename: %w", err)
-	}
-
-	return nil
+func (f *fileService) key(app, user, sessionID string) string {
+	return filepath.Join(app, user, sessionID)
 }
 
 // Create implements [session.Service].
@@ -141,9 +184,19 @@ func (f *fileService) Create(_ context.Context, req *session.CreateRequest) (*se
 	if id == "" {
 		id = fmt.Sprintf("%d", time.Now().UnixNano())
 	}
+	for _, name := range []string{req.AppName, req.UserID, id} {
+		if err := storage.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("sessionfs: %w", err)
+		}
+	}
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	unlock, err := f.lockLocked()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	key := f.key(req.AppName, req.UserID, id)
 	if _, exists := f.sessions[key]; exists {
@@ -161,8 +214,7 @@ func (f *fileService) Create(_ context.Context, req *session.CreateRequest) (*se
 		State:     st,
 		UpdatedAt: time.Now(),
 	}
-	f.sessions[key] = ps
-	if err := f.saveLocked(); err != nil {
+	if err := f.commitLocked(&journalRecord{Op: opCreate, Key: key, Session: ps}); err != nil {
 		return nil, err
 	}
 
@@ -180,9 +232,15 @@ func (f *fileService) Get(_ context.Context, req *session.GetRequest) (*session.
 		return nil, errors.New("sessionfs: app_name, user_id, session_id required")
 	}
 
-	f.mu.RLock()
+	f.mu.Lock()
+	unlock, err := f.lockLocked()
+	if err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
 	ps, ok := f.sessions[f.key(req.AppName, req.UserID, req.SessionID)]
-	f.mu.RUnlock()
+	unlock()
+	f.mu.Unlock()
 
 	if !ok {
 		return nil, fmt.Errorf("sessionfs: session %s not found", req.SessionID)
@@ -202,8 +260,13 @@ func (f *fileService) List(_ context.Context, req *session.ListRequest) (*sessio
 		return nil, errors.New("sessionfs: app_name required")
 	}
 
-	f.mu.RLock()
-	defer f.mu.RUnlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lockLocked()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	out := make([]session.Session, 0)
 	prefix := req.AppName + "/"
@@ -230,10 +293,18 @@ func (f *fileService) Delete(_ context.Context, req *session.DeleteRequest) erro
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	unlock, err := f.lockLocked()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	delete(f.sessions, f.key(req.AppName, req.UserID, req.SessionID))
+	key := f.key(req.AppName, req.UserID, req.SessionID)
+	if _, ok := f.sessions[key]; !ok {
+		return nil
+	}
 
-	return f.saveLocked()
+	return f.commitLocked(&journalRecord{Op: opDelete, Key: key})
 }
 
 // AppendEvent implements [session.Service].
@@ -252,19 +323,46 @@ func (f *fileService) AppendEvent(_ context.Context, sess session.Session, ev *s
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	unlock, err := f.lockLocked()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	key := f.key(fsess.AppName(), fsess.UserID(), fsess.ID())
-	ps, ok := f.sessions[key]
-	if !ok {
+	if _, ok := f.sessions[key]; !ok {
 		return fmt.Errorf("sessionfs: session %s missing", fsess.ID())
 	}
 
-	trimmed := trimTemp(ev)
-	applyState(ps, trimmed)
-	ps.Events = append(ps.Events, trimmed)
-	ps.UpdatedAt = trimmed.Timestamp
+	return f.commitLocked(&journalRecord{Op: opAppend, Key: key, Event: trimTemp(ev)})
+}
+
+// Usage implements [retention.Store]. The size of a session is the size of its JSON encoding.
+func (f *fileService) Usage(_ context.Context) ([]retention.Usage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lockLocked()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	out := make([]retention.Usage, 0, len(f.sessions))
+	for _, ps := range f.sessions {
+		data, err := json.Marshal(ps)
+		if err != nil {
+			return nil, fmt.Errorf("sessionfs: marshal: %w", err)
+		}
+		out = append(out, retention.Usage{
+			AppName:   ps.AppName,
+			UserID:    ps.UserID,
+			SessionID: ps.SessionID,
+			UpdatedAt: ps.UpdatedAt,
+			Bytes:     int64(len(data)),
+		})
+	}
 
-	return f.saveLocked()
+	return out, nil
 }
 
 // fileSession implements [session.Session].
@@ -17,7 +17,10 @@
 package sessionfs
 
 import (
+	"bytes"
+	json "encoding/json/v2"
 	"errors"
+	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
@@ -27,6 +30,8 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/adk/session"
+
+	"github.com/zchee/tumix/internal/storage"
 )
 
 func TestFileServiceLifecycleAndPersistence(t *testing.T) {
@@ -37,11 +42,7 @@ func TestFileServiceLifecycleAndPersistence(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Service() error = %v", err)
 	}
-	t.Cleanup(func() {
-		if fs, ok := svc.(*fileService); ok && fs.lockFile != nil {
-			_ = fs.lockFile.Close()
-		}
-	})
+	t.Cleanup(func() { closeService(t, svc) })
 
 	ctx := t.Context()
 	app, user, sid := "app", "user", "sid"
@@ -73,9 +74,6 @@ func TestFileServiceLifecycleAndPersistence(t *testing.T) {
 	}
 
 	// Re-open service to verify on-disk persistence and temp-key trimming.
-	if fs, ok := svc.(*fileService); ok && fs.lockFile != nil {
-		_ = fs.lockFile.Close()
-	}
 	svc2, err := Service(dir)
 	if err != nil {
 		t.Fatalf("Service reload error = %v", err)
@@ -116,11 +114,7 @@ func TestFileServiceListDeleteAndErrors(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Service() error = %v", err)
 	}
-	t.Cleanup(func() {
-		if fs, ok := svc.(*fileService); ok && fs.lockFile != nil {
-			_ = fs.lockFile.Close()
-		}
-	})
+	t.Cleanup(func() { closeService(t, svc) })
 
 	ctx := t.Context()
 	app := "app"
@@ -147,6 +141,11 @@ func TestFileServiceListDeleteAndErrors(t *testing.T) {
 		t.Fatalf("List user filter got %+v", listU1.Sessions)
 	}
 
+	usage, err := svc.(*fileService).Usage(ctx)
+	if err != nil || len(usage) != 2 || usage[0].Bytes <= 0 {
+		t.Fatalf("Usage() = %+v, %v, want 2 sessions with their size", usage, err)
+	}
+
 	if err := svc.Delete(ctx, &session.DeleteRequest{AppName: app, UserID: "u1", SessionID: "s1"}); err != nil {
 		t.Fatalf("Delete error = %v", err)
 	}
@@ -174,11 +173,7 @@ func TestFileServiceErrorCases(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Service ok dir error = %v", err)
 	}
-	t.Cleanup(func() {
-		if fs, ok := svc.(*fileService); ok && fs.lockFile != nil {
-			_ = fs.lockFile.Close()
-		}
-	})
+	t.Cleanup(func() { closeService(t, svc) })
 	ctx := t.Context()
 	created, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user"})
 	if err != nil {
@@ -234,3 +229,382 @@ func (fakeSession) UserID() string            { return "user" }
 func (fakeSession) LastUpdateTime() time.Time { return time.Now() }
 func (fakeSession) Events() session.Events    { return nil }
 func (fakeSession) State() session.State      { return nil }
+
+// closeService releases the files of svc, like the exit of the process would.
+func closeService(t *testing.T, svc session.Service) {
+	t.Helper()
+
+	if err := svc.(*fileService).Close(); err != nil {
+		t.Fatalf("close service: %v", err)
+	}
+}
+
+func appendText(t *testing.T, svc session.Service, sess session.Session, text string) error {
+	t.Helper()
+
+	ev := session.NewEvent("inv")
+	ev.Author = "author"
+	ev.Actions.StateDelta = map[string]any{"last": text}
+	return svc.AppendEvent(t.Context(), sess, ev)
+}
+
+// eventTexts returns the "last" state delta of each event of the session, and its "last" state.
+func eventTexts(t *testing.T, svc session.Service) ([]string, any) {
+	t.Helper()
+
+	got, err := svc.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	var texts []string
+	for ev := range got.Session.Events().All() {
+		texts = append(texts, ev.Actions.StateDelta["last"].(string))
+	}
+	last, _ := got.Session.State().Get("last")
+	return texts, last
+}
+
+func TestFileServiceCrashRecovery(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts Options
+		// fault is the step of the write of "b" a crash interrupts, if any.
+		fault string
+		// crash alters the files left by the write of "b", as a crash would.
+		crash   func(t *testing.T, dir string)
+		wantErr bool
+		want    []string
+	}{
+		"clean": {
+			want: []string{"a", "b"},
+		},
+		"torn journal record": {
+			crash: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, journalFile)
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Truncate(path, info.Size()-5); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"a"},
+		},
+		"garbage after the last record": {
+			crash: func(t *testing.T, dir string) {
+				f, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.WriteString("1234 {\"seq\":\n\x00\x00\x00"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"a", "b"},
+		},
+		"stale snapshot tmp": {
+			crash: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, snapshotFile+".tmp"), []byte(`{"version":1,"seq"`), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"a", "b"},
+		},
+		"failed journal write": {
+			fault:   "journal",
+			wantErr: true,
+			want:    []string{"a"},
+		},
+		"crash before snapshot rename": {
+			opts:  Options{CompactEvery: 3},
+			fault: "rename",
+			want:  []string{"a", "b"},
+		},
+		"crash before journal truncate": {
+			opts:  Options{CompactEvery: 3},
+			fault: "truncate",
+			want:  []string{"a", "b"},
+		},
+		"no sync": {
+			opts: Options{Sync: SyncNever, CompactEvery: 1},
+			want: []string{"a", "b"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			svc, err := ServiceWithOptions(dir, tt.opts)
+			if err != nil {
+				t.Fatalf("ServiceWithOptions() error = %v", err)
+			}
+			created, err := svc.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := appendText(t, svc, created.Session, "a"); err != nil {
+				t.Fatalf("AppendEvent(a) error = %v", err)
+			}
+
+			errCrash := errors.New("crash")
+			svc.(*fileService).fault = func(step string) error {
+				if step == tt.fault {
+					return errCrash
+				}
+				return nil
+			}
+			if err := appendText(t, svc, created.Session, "b"); (err != nil) != tt.wantErr {
+				t.Fatalf("AppendEvent(b) error = %v, want error %t", err, tt.wantErr)
+			}
+			closeService(t, svc)
+			if tt.crash != nil {
+				tt.crash(t, dir)
+			}
+
+			svc, err = ServiceWithOptions(dir, tt.opts)
+			if err != nil {
+				t.Fatalf("reopen error = %v", err)
+			}
+			got, last := eventTexts(t, svc)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("recovered events mismatch (-want +got):\n%s", diff)
+			}
+			if want := tt.want[len(tt.want)-1]; last != want {
+				t.Fatalf("recovered state last = %v, want %s", last, want)
+			}
+			if _, err := os.Stat(filepath.Join(dir, snapshotFile+".tmp")); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("stale snapshot tmp left after recovery: %v", err)
+			}
+
+			// The recovered store keeps journaling after what it recovered.
+			sess, err := svc.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if err := appendText(t, svc, sess.Session, "c"); err != nil {
+				t.Fatalf("AppendEvent(c) error = %v", err)
+			}
+			closeService(t, svc)
+			svc, err = ServiceWithOptions(dir, tt.opts)
+			if err != nil {
+				t.Fatalf("second reopen error = %v", err)
+			}
+			t.Cleanup(func() { closeService(t, svc) })
+			got, _ = eventTexts(t, svc)
+			if diff := cmp.Diff(append(tt.want, "c"), got); diff != "" {
+				t.Fatalf("events after recovery mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFileServiceJournalCompaction(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	svc, err := ServiceWithOptions(dir, Options{CompactEvery: 4})
+	if err != nil {
+		t.Fatalf("ServiceWithOptions() error = %v", err)
+	}
+	created, err := svc.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		if err := appendText(t, svc, created.Session, text); err != nil {
+			t.Fatalf("AppendEvent(%s) error = %v", text, err)
+		}
+	}
+
+	// The create and the first three appends were compacted; the last two appends are in the journal.
+	if fs := svc.(*fileService); fs.records != 2 || fs.seq != 6 {
+		t.Fatalf("journal has %d records up to %d, want 2 up to 6", fs.records, fs.seq)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+	if snap.Version != snapshotVersion || snap.Seq != 4 || len(snap.Sessions["app/user/sid"].Events) != 3 {
+		t.Fatalf("snapshot = version %d, seq %d, %+v, want version 1, seq 4, 3 events", snap.Version, snap.Seq, snap.Sessions)
+	}
+
+	// Reopening replays the journal and compacts it.
+	closeService(t, svc)
+	svc, err = ServiceWithOptions(dir, Options{CompactEvery: 4})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	t.Cleanup(func() { closeService(t, svc) })
+	if info, err := os.Stat(filepath.Join(dir, journalFile)); err != nil || info.Size() != 0 {
+		t.Fatalf("journal after reopen = %v, %v, want empty", info, err)
+	}
+	got, last := eventTexts(t, svc)
+	if diff := cmp.Diff([]string{"a", "b", "c", "d", "e"}, got); diff != "" || last != "e" {
+		t.Fatalf("events mismatch (-want +got):\n%s, last = %v", diff, last)
+	}
+}
+
+func TestFileServiceCorruptJournal(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	svc, err := Service(dir)
+	if err != nil {
+		t.Fatalf("Service() error = %v", err)
+	}
+	created, err := svc.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := appendText(t, svc, created.Session, "a"); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+	closeService(t, svc)
+
+	// A corrupt first record, followed by a valid one, was not cut short by a crash.
+	path := filepath.Join(dir, journalFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read journal: %v", err)
+	}
+	data[bytes.IndexByte(data, '{')+1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+	if _, err := Service(dir); err == nil || !strings.Contains(err.Error(), "corrupt journal record") {
+		t.Fatalf("Service() with a corrupt journal error = %v, want corrupt journal record", err)
+	}
+}
+
+func TestFileServiceUnversionedSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	legacy := `{"app/user/sid":{"app_name":"app","user_id":"user","session_id":"sid","state":{"last":"a"},"events":[],"updated_at":"2025-01-01T00:00:00Z"}}`
+	if err := os.WriteFile(filepath.Join(dir, snapshotFile), []byte(legacy), 0o600); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+
+	svc, err := Service(dir)
+	if err != nil {
+		t.Fatalf("Service() error = %v", err)
+	}
+	t.Cleanup(func() { closeService(t, svc) })
+	if _, last := eventTexts(t, svc); last != "a" {
+		t.Fatalf("state last = %v, want a", last)
+	}
+}
+
+func TestFileServiceSharedDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	opts := Options{Sync: SyncNever, CompactEvery: 3}
+	a, err := ServiceWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("ServiceWithOptions() error = %v", err)
+	}
+	t.Cleanup(func() { closeService(t, a) })
+	b, err := ServiceWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("ServiceWithOptions() second error = %v", err)
+	}
+	t.Cleanup(func() { closeService(t, b) })
+
+	created, err := a.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	got, err := b.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: "sid"})
+	if err != nil {
+		t.Fatalf("Get() from the other service error = %v", err)
+	}
+
+	// Both services append concurrently, compacting the journal along the way.
+	const perService = 10
+	errc := make(chan error, 2*perService)
+	for i := range perService {
+		go func() { errc <- appendText(t, a, created.Session, fmt.Sprintf("a%d", i)) }()
+		go func() { errc <- appendText(t, b, got.Session, fmt.Sprintf("b%d", i)) }()
+	}
+	for range 2 * perService {
+		if err := <-errc; err != nil {
+			t.Fatalf("AppendEvent() error = %v", err)
+		}
+	}
+
+	textsA, _ := eventTexts(t, a)
+	textsB, _ := eventTexts(t, b)
+	if len(textsA) != 2*perService {
+		t.Fatalf("events = %d, want %d", len(textsA), 2*perService)
+	}
+	if diff := cmp.Diff(textsA, textsB); diff != "" {
+		t.Fatalf("services disagree on the events (-a +b):\n%s", diff)
+	}
+	if n := created.Session.Events().Len(); n != 2*perService {
+		t.Fatalf("events of the created session = %d, want %d", n, 2*perService)
+	}
+
+	if err := b.Delete(t.Context(), &session.DeleteRequest{AppName: "app", UserID: "user", SessionID: "sid"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := a.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: "sid"}); err == nil {
+		t.Fatal("Get() of a session deleted by the other service succeeded")
+	}
+}
+
+func TestFileServiceInvalidNames(t *testing.T) {
+	t.Parallel()
+
+	svc, err := Service(t.TempDir())
+	if err != nil {
+		t.Fatalf("Service() error = %v", err)
+	}
+	t.Cleanup(func() { closeService(t, svc) })
+
+	tests := map[string]*session.CreateRequest{
+		"traversal session":  {AppName: "app", UserID: "user", SessionID: "../../etc"},
+		"separator session":  {AppName: "app", UserID: "user", SessionID: `a\b`},
+		"dot dot user":       {AppName: "app", UserID: "..", SessionID: "sid"},
+		"reserved app":       {AppName: "NUL", UserID: "user", SessionID: "sid"},
+		"control in session": {AppName: "app", UserID: "user", SessionID: "a\x00b"},
+	}
+
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := svc.Create(t.Context(), req); !errors.Is(err, storage.ErrInvalidName) {
+				t.Fatalf("Create(%+v) error = %v, want %v", req, err, storage.ErrInvalidName)
+			}
+		})
+	}
+}
+
+func TestServiceWithOptionsErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]Options{
+		"unknown sync":           {Sync: "sometimes"},
+		"negative compact every": {CompactEvery: -1},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ServiceWithOptions(t.TempDir(), opts); err == nil {
+				t.Fatalf("ServiceWithOptions(%+v) succeeded, want error", opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/adk/session"
+
+	"github.com/zchee/tumix/session/sessiondb"
+)
+
+// sessionsUsage is printed for "tumix sessions" without a known subcommand.
+const sessionsUsage = `usage: tumix sessions ls [-db path] [-app name] [-user id] [-since 24h|time] [-limit n] [-search text] [-json]
+       tumix sessions show [-db path] [-app name] [-user id] [-json] session_id`
+
+// runSessions runs "tumix sessions ls|show", which explore the past runs stored in the TUMIX_SESSION_SQLITE database.
+func runSessions(args []string) int {
+	return sessionsCommand(context.Background(), args, os.Stdout, os.Stderr)
+}
+
+func sessionsCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "ls" && args[0] != "show") {
+		fmt.Fprintln(stderr, sessionsUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("sessions "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dbPath := fs.String("db", os.Getenv("TUMIX_SESSION_SQLITE"), "SQLite session database (TUMIX_SESSION_SQLITE)")
+	app := fs.String("app", "", "Only the sessions of this app")
+	user := fs.String("user", "", "Only the sessions of this user")
+	asJSON := fs.Bool("json", false, "Print JSON")
+	var (
+		since  *string
+		limit  *int
+		search *string
+	)
+	if args[0] == "ls" {
+		since = fs.String("since", "", "Only the sessions created within this duration, e.g. 24h, or since this RFC 3339 time")
+		limit = fs.Int("limit", 50, "Maximum number of sessions or events to list (0 lists all)")
+		search = fs.String("search", "", "List the events containing this text, ignoring case, instead of the sessions")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *dbPath == "" {
+		fmt.Fprintln(stderr, "sessions: -db or TUMIX_SESSION_SQLITE is required")
+		return 2
+	}
+	filter := sessiondb.SessionFilter{AppName: *app, UserID: *user}
+
+	var out any
+	switch args[0] {
+	case "ls":
+		if fs.NArg() != 0 {
+			fmt.Fprintln(stderr, sessionsUsage)
+			return 2
+		}
+		if *since != "" {
+			t, err := parseSince(*since, time.Now())
+			if err != nil {
+				fmt.Fprintf(stderr, "sessions: %v\n", err)
+				return 2
+			}
+			filter.Since = t
+		}
+		filter.Limit = *limit
+	case "show":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(stderr, sessionsUsage)
+			return 2
+		}
+		filter.SessionID = fs.Arg(0)
+	}
+
+	store, err := sessiondb.Open(ctx, *dbPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "sessions: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch {
+	case args[0] == "show":
+		detail, err := showSession(ctx, store, filter)
+		if err != nil {
+			fmt.Fprintf(stderr, "sessions: %v\n", err)
+			return 1
+		}
+		out = detail
+		if !*asJSON {
+			printSessionDetail(stdout, detail)
+			return 0
+		}
+	case *search != "":
+		matches, err := store.SearchEvents(ctx, *search, filter)
+		if err != nil {
+			fmt.Fprintf(stderr, "sessions: %v\n", err)
+			return 1
+		}
+		out = matches
+		if !*asJSON {
+			printEventMatches(stdout, matches)
+			return 0
+		}
+	default:
+		sessions, err := store.ListSessions(ctx, filter)
+		if err != nil {
+			fmt.Fprintf(stderr, "sessions: %v\n", err)
+			return 1
+		}
+		out = sessions
+		if !*asJSON {
+			printSessionSummaries(stdout, sessions)
+			return 0
+		}
+	}
+
+	if err := json.MarshalEncode(jsontext.NewEncoder(stdout, jsontext.WithIndent("  ")), out); err != nil {
+		fmt.Fprintf(stderr, "sessions: encode: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseSince parses the -since flag, a duration before now or an RFC 3339 time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -since %q; want a duration such as 24h or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// sessionDetail is a stored session as printed by "tumix sessions show".
+type sessionDetail struct {
+	sessiondb.SessionSummary
+	State  map[string]any `json:"state"`
+	Events []eventDetail  `json:"events"`
+}
+
+type eventDetail struct {
+	Author    string    `json:"author"`
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+// showSession loads the session selected by filter, which must be exactly one.
+func showSession(ctx context.Context, store *sessiondb.Store, filter sessiondb.SessionFilter) (*sessionDetail, error) {
+	sessions, err := store.ListSessions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("session %s not found", filter.SessionID)
+	}
+	if len(sessions) > 1 {
+		return nil, fmt.Errorf("session %s exists for %d apps or users; select one with -app and -user", filter.SessionID, len(sessions))
+	}
+
+	sum := sessions[0]
+	resp, err := store.Get(ctx, &session.GetRequest{AppName: sum.AppName, UserID: sum.UserID, SessionID: sum.SessionID})
+	if err != nil {
+		return nil, err
+	}
+	detail := &sessionDetail{
+		SessionSummary: sum,
+		State:          maps.Collect(resp.Session.State().All()),
+		Events:         make([]eventDetail, 0, resp.Session.Events().Len()),
+	}
+	for ev := range resp.Session.Events().All() {
+		detail.Events = append(detail.Events, eventDetail{Author: ev.Author, Timestamp: ev.Timestamp, Text: eventText(ev)})
+	}
+	return detail, nil
+}
+
+// eventText returns the text parts of the content of ev.
+func eventText(ev *session.Event) string {
+	if ev.Content == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range ev.Content.Parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func printSessionSummaries(w io.Writer, sessions []sessiondb.SessionSummary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CREATED\tUPDATED\tAPP\tUSER\tSESSION\tEVENTS")
+	for _, s := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", formatTime(s.CreatedAt), formatTime(s.UpdatedAt), s.AppName, s.UserID, s.SessionID, s.Events)
+	}
+	_ = tw.Flush()
+}
+
+func printEventMatches(w io.Writer, matches []sessiondb.EventMatch) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tAPP\tUSER\tSESSION\tEVENT\tAUTHOR\tTEXT")
+	for _, m := range matches {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", formatTime(m.Timestamp), m.AppName, m.UserID, m.SessionID, m.Index, m.Author, oneLine(m.Text, 80))
+	}
+	_ = tw.Flush()
+}
+
+func printSessionDetail(w io.Writer, d *sessionDetail) {
+	fmt.Fprintf(w, "session %s (app %s, user %s)\ncreated %s, updated %s, %d events\n",
+		d.SessionID, d.AppName, d.UserID, formatTime(d.CreatedAt), formatTime(d.UpdatedAt), len(d.Events))
+	for i, ev := range d.Events {
+		fmt.Fprintf(w, "\n[%d] %s %s\n%s\n", i, formatTime(ev.Timestamp), ev.Author, ev.Text)
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// oneLine returns the first line of s, cut to n runes.
+func oneLine(s string, n int) string {
+	s, _, cut := strings.Cut(strings.TrimSpace(s), "\n")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	if cut {
+		return s + " …"
+	}
+	return s
+}
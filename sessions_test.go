@@ -0,0 +1,157 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	json "encoding/json/v2"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/session/sessiondb"
+)
+
+func TestSessionsCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+	store, err := sessiondb.Open(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, id := range []struct{ user, sid string }{{"alice", "s1"}, {"bob", "s2"}, {"bob", "s1"}} {
+		created, err := store.Create(ctx, &session.CreateRequest{AppName: "tumix", UserID: id.user, SessionID: id.sid})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if id.sid != "s2" {
+			continue
+		}
+		for _, text := range []string{"What is the capital of France?", "The capital is <<<Paris>>>"} {
+			ev := session.NewEvent("inv")
+			ev.Author = "tumix"
+			ev.Timestamp = time.Now()
+			ev.Content = genai.NewContentFromText(text, genai.RoleModel)
+			if err := store.AppendEvent(ctx, created.Session, ev); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	tests := map[string]struct {
+		args     []string
+		wantCode int
+		want     []string
+		wantNot  []string
+	}{
+		"ls": {
+			args: []string{"ls"},
+			want: []string{"SESSION", "alice", "bob", "s1", "s2"},
+		},
+		"ls user": {
+			args:    []string{"ls", "-user", "alice"},
+			want:    []string{"alice"},
+			wantNot: []string{"bob"},
+		},
+		"ls search": {
+			args:    []string{"ls", "-search", "paris"},
+			want:    []string{"s2", "The capital is <<<Paris>>>"},
+			wantNot: []string{"France"},
+		},
+		"ls since": {
+			args:    []string{"ls", "-since", "2000-01-01T00:00:00Z", "-app", "other"},
+			wantNot: []string{"s1"},
+		},
+		"show": {
+			args: []string{"show", "s2"},
+			want: []string{"session s2 (app tumix, user bob)", "2 events", "[0]", "France", "[1]", "<<<Paris>>>"},
+		},
+		"show ambiguous": {
+			args:     []string{"show", "s1"},
+			wantCode: 1,
+		},
+		"show user": {
+			args: []string{"show", "-user", "bob", "s1"},
+			want: []string{"user bob", "0 events"},
+		},
+		"show unknown": {
+			args:     []string{"show", "s9"},
+			wantCode: 1,
+		},
+		"no subcommand": {
+			wantCode: 2,
+		},
+		"show without id": {
+			args:     []string{"show"},
+			wantCode: 2,
+		},
+		"invalid since": {
+			args:     []string{"ls", "-since", "yesterday"},
+			wantCode: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			args := tt.args
+			if len(args) > 0 {
+				args = append([]string{args[0], "-db", dbPath}, args[1:]...)
+			}
+			if code := sessionsCommand(t.Context(), args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("sessionsCommand(%q) = %d, want %d; stderr: %s", args, code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout.String(), want) {
+					t.Fatalf("output = %q, want it to contain %q", stdout.String(), want)
+				}
+			}
+			for _, notWant := range tt.wantNot {
+				if strings.Contains(stdout.String(), notWant) {
+					t.Fatalf("output = %q, want it not to contain %q", stdout.String(), notWant)
+				}
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		var stdout, stderr bytes.Buffer
+		if code := sessionsCommand(t.Context(), []string{"show", "-db", dbPath, "-json", "-user", "bob", "s2"}, &stdout, &stderr); code != 0 {
+			t.Fatalf("sessionsCommand() = %d; stderr: %s", code, stderr.String())
+		}
+		var got sessionDetail
+		if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", stdout.String(), err)
+		}
+		if got.SessionID != "s2" || got.UserID != "bob" || len(got.Events) != 2 || got.Events[1].Text != "The capital is <<<Paris>>>" {
+			t.Fatalf("show -json = %+v", got)
+		}
+	})
+}
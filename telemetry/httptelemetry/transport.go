@@ -22,6 +22,8 @@ import (
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
+
+	"github.com/zchee/tumix/telemetry/runmeta"
 )
 
 // Transport implements [http.RoundTripper] with optional OpenTelemetry tracing.
@@ -61,7 +63,14 @@ func NewTransportWithTrace(base http.RoundTripper, traceEnabled bool) *Transport
 }
 
 // RoundTrip implements [http.RoundTripper].
+//
+// The [runmeta.RunMetadata] of the request context is sent as headers.
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := runmeta.FromContext(req.Context()); ok {
+		// A RoundTripper must not modify the caller's request.
+		req = req.Clone(req.Context())
+		runmeta.SetHeaders(req.Context(), req.Header)
+	}
 	resp, err := t.rt.RoundTrip(req)
 	if err != nil {
 		return nil, fmt.Errorf("RoundTrip failed: %w", err)
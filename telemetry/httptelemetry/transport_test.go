@@ -27,6 +27,7 @@ import (
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/zchee/tumix/telemetry/httptelemetry"
+	"github.com/zchee/tumix/telemetry/runmeta"
 )
 
 type stubRoundTripper struct {
@@ -161,3 +162,32 @@ func TestTransportTracingToggle(t *testing.T) {
 		})
 	}
 }
+
+func TestTransportRoundTripRunMetadata(t *testing.T) {
+	t.Parallel()
+
+	rr := httptest.NewRecorder()
+	rr.WriteHeader(http.StatusNoContent)
+
+	stub := &stubRoundTripper{resp: rr.Result()}
+	tr := httptelemetry.NewTransportWithTrace(stub, false)
+
+	ctx := runmeta.NewContext(t.Context(), runmeta.RunMetadata{UserID: "u1", SessionID: "s1"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/foo", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := stub.last.Header.Get(runmeta.HeaderSessionID); got != "s1" {
+		t.Fatalf("sent %s = %q, want %q", runmeta.HeaderSessionID, got, "s1")
+	}
+	if got := req.Header.Get(runmeta.HeaderSessionID); got != "" {
+		t.Fatalf("caller request was modified: %s = %q", runmeta.HeaderSessionID, got)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package openinference
+
+import (
+	"bytes"
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ExportOption configures an [Exporter].
+type ExportOption func(*Exporter)
+
+// WithHTTPClient sets the HTTP client of the exporter, http.DefaultClient by default.
+func WithHTTPClient(c *http.Client) ExportOption {
+	return func(e *Exporter) { e.client = c }
+}
+
+// WithHeaders sets extra HTTP headers of the export requests, e.g. the API key of the platform.
+func WithHeaders(headers map[string]string) ExportOption {
+	return func(e *Exporter) { e.headers = headers }
+}
+
+// WithServiceName sets the service.name resource attribute, "tumix" by default.
+func WithServiceName(name string) ExportOption {
+	return func(e *Exporter) { e.service = name }
+}
+
+// WithProject sets the openinference.project.name resource attribute, the project of the traces in Phoenix.
+func WithProject(name string) ExportOption {
+	return func(e *Exporter) { e.project = name }
+}
+
+// Exporter sends spans to an OpenInference collector.
+type Exporter struct {
+	endpoint string
+	// path is the file spans are appended to, if the endpoint is not an HTTP URL.
+	path    string
+	client  *http.Client
+	headers map[string]string
+	service string
+	project string
+
+	mu sync.Mutex // guards writes to path
+}
+
+// NewExporter returns an exporter to endpoint: an HTTP URL accepting OTLP/HTTP JSON traces, such as
+// http://localhost:6006/v1/traces of Phoenix or https://api.smith.langchain.com/otel/v1/traces of LangSmith, or a file
+// path or file:// URL every export is appended to as a JSON line.
+func NewExporter(endpoint string, opts ...ExportOption) (*Exporter, error) {
+	if endpoint == "" {
+		return nil, errors.New("openinference: endpoint required")
+	}
+	e := &Exporter{endpoint: endpoint, client: http.DefaultClient, service: "tumix"}
+	u, err := url.Parse(endpoint)
+	switch {
+	case err == nil && (u.Scheme == "http" || u.Scheme == "https"):
+		if u.Host == "" {
+			return nil, fmt.Errorf("openinference: endpoint %q has no host", endpoint)
+		}
+	case err == nil && u.Scheme == "file":
+		e.path = u.Path
+	default:
+		e.path = endpoint
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// exportRequest is an OTLP ExportTraceServiceRequest in the JSON encoding.
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+// Export sends spans in one request.
+func (e *Exporter) Export(ctx context.Context, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	attrs := []Attribute{{Key: "service.name", Value: Value{StringValue: e.service}}}
+	if e.project != "" {
+		attrs = append(attrs, Attribute{Key: AttrProjectName, Value: Value{StringValue: e.project}})
+	}
+	body, err := json.Marshal(exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: attrs},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "github.com/zchee/tumix"}, Spans: spans}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("openinference: marshal spans: %w", err)
+	}
+
+	if e.path != "" {
+		return e.appendFile(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("openinference: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, k := range slices.Sorted(maps.Keys(e.headers)) {
+		req.Header.Set(k, e.headers[k])
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("openinference: export: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("openinference: export: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (e *Exporter) appendFile(body []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	f, err := os.OpenFile(e.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("openinference: %w", err)
+	}
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("openinference: write %s: %w", e.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("openinference: close %s: %w", e.path, err)
+	}
+	return nil
+}
+
+// ParseHeaders parses comma-separated key=value HTTP headers, the format of OTEL_EXPORTER_OTLP_HEADERS, e.g.
+// "x-api-key=secret,Langsmith-Project=tumix". Values are URL-unescaped.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("openinference: invalid header %q; want key=value", pair)
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("openinference: invalid header %q: %w", pair, err)
+		}
+		headers[k] = v
+	}
+	return headers, nil
+}
@@ -0,0 +1,404 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package openinference exports TUMIX runs as OpenInference traces, so LLM-observability platforms such as Arize
+// Phoenix and LangSmith can ingest them.
+//
+// A [Recorder] maps the session events of a run to spans carrying the OpenInference semantic attributes: a CHAIN span
+// for the run, an LLM span for every model response, a TOOL span for every tool call, and an EVALUATOR span for every
+// judge decision. An [Exporter] sends the spans in the OTLP/HTTP JSON encoding.
+package openinference
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	json "encoding/json/v2"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	"github.com/zchee/tumix/usage"
+)
+
+// SpanKind is the value of the [AttrSpanKind] attribute.
+type SpanKind string
+
+// Span kinds used by the [Recorder].
+const (
+	KindChain     SpanKind = "CHAIN"
+	KindLLM       SpanKind = "LLM"
+	KindTool      SpanKind = "TOOL"
+	KindEvaluator SpanKind = "EVALUATOR"
+)
+
+// OpenInference semantic attribute names.
+const (
+	AttrSpanKind        = "openinference.span.kind"
+	AttrInputValue      = "input.value"
+	AttrInputMimeType   = "input.mime_type"
+	AttrOutputValue     = "output.value"
+	AttrOutputMimeType  = "output.mime_type"
+	AttrModelName       = "llm.model_name"
+	AttrTokenPrompt     = "llm.token_count.prompt"
+	AttrTokenCompletion = "llm.token_count.completion"
+	AttrTokenTotal      = "llm.token_count.total"
+	AttrOutputMessages  = "llm.output_messages"
+	AttrToolName        = "tool.name"
+	AttrToolParameters  = "tool.parameters"
+	AttrToolCallID      = "tool_call.id"
+	AttrSessionID       = "session.id"
+	AttrUserID          = "user.id"
+	AttrMetadata        = "metadata"
+	AttrProjectName     = "openinference.project.name"
+)
+
+const (
+	mimeTextPlain       = "text/plain"
+	mimeApplicationJSON = "application/json"
+
+	// otlpSpanKindInternal is SPAN_KIND_INTERNAL; the OpenInference kind is an attribute.
+	otlpSpanKindInternal = 1
+)
+
+// Status codes of a [Status].
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// Span is a span in the OTLP JSON encoding.
+type Span struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitzero"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano int64       `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   int64       `json:"endTimeUnixNano,string"`
+	Attributes        []Attribute `json:"attributes,omitzero"`
+	Status            Status      `json:"status"`
+}
+
+// Attr returns the value of the attribute key of s: a string, an int64, or nil if s has none.
+func (s *Span) Attr(key string) any {
+	for _, a := range s.Attributes {
+		if a.Key != key {
+			continue
+		}
+		if a.Value.IntValue != nil {
+			return *a.Value.IntValue
+		}
+		return a.Value.StringValue
+	}
+	return nil
+}
+
+// Attribute is a key-value attribute in the OTLP JSON encoding.
+type Attribute struct {
+	Key   string `json:"key"`
+	Value Value  `json:"value"`
+}
+
+// Value is an attribute value in the OTLP JSON encoding, either a string or an integer.
+type Value struct {
+	StringValue string `json:"stringValue,omitzero"`
+	IntValue    *int64 `json:"intValue,omitzero,string"`
+}
+
+// Status is the status of a span in the OTLP JSON encoding.
+type Status struct {
+	Code    int    `json:"code,omitzero"`
+	Message string `json:"message,omitzero"`
+}
+
+// Run describes the run a [Recorder] records.
+type Run struct {
+	// Name is the name of the root span, "tumix" if empty.
+	Name      string
+	Prompt    string
+	UserID    string
+	SessionID string
+	// Model and JudgeModel are the model names of the LLM and EVALUATOR spans.
+	Model      string
+	JudgeModel string
+	// Evaluators are the authors whose responses are recorded as EVALUATOR spans, e.g. the judge agent.
+	Evaluators []string
+}
+
+// RecorderOption configures a [Recorder].
+type RecorderOption func(*Recorder)
+
+// WithClock sets the clock of the recorder, time.Now by default. It times the run, and the events without a timestamp.
+func WithClock(now func() time.Time) RecorderOption {
+	return func(r *Recorder) { r.now = now }
+}
+
+// Recorder maps the session events of a run to OpenInference spans. It is safe for concurrent use.
+type Recorder struct {
+	run     Run
+	now     func() time.Time
+	traceID string
+	rootID  string
+	start   time.Time
+
+	mu sync.Mutex
+	// last is the time of the last event of every author, the start of its next span.
+	last  map[string]time.Time
+	spans []*Span
+	// tools are the TOOL spans awaiting their response, by call ID.
+	tools map[string]*Span
+}
+
+// NewRecorder starts recording run.
+func NewRecorder(run Run, opts ...RecorderOption) *Recorder {
+	r := &Recorder{
+		run:     run,
+		now:     time.Now,
+		traceID: newID(16),
+		rootID:  newID(8),
+		last:    make(map[string]time.Time),
+		tools:   make(map[string]*Span),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.start = r.now()
+	return r
+}
+
+// Record maps a final event of the run to spans. Partial events and the events of the user are ignored.
+//
+// An event with token usage or tool calls is a model response, recorded as an LLM span, or an EVALUATOR span if its
+// author is one of the evaluators; any other event with text is recorded as a CHAIN span. A tool call opens a TOOL
+// span under the span of its event, closed by the event carrying its response.
+func (r *Recorder) Record(event *session.Event) {
+	if event == nil || event.Partial || event.Author == "user" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at := event.Timestamp
+	if at.IsZero() {
+		at = r.now()
+	}
+
+	var (
+		texts     []string
+		calls     []*genai.FunctionCall
+		responses []*genai.FunctionResponse
+	)
+	if event.Content != nil {
+		for _, part := range event.Content.Parts {
+			switch {
+			case part == nil:
+			case part.FunctionCall != nil:
+				calls = append(calls, part.FunctionCall)
+			case part.FunctionResponse != nil:
+				responses = append(responses, part.FunctionResponse)
+			case part.Text != "" && !part.Thought:
+				texts = append(texts, part.Text)
+			}
+		}
+	}
+
+	for _, resp := range responses {
+		span, ok := r.tools[toolKey(resp.ID, resp.Name)]
+		if !ok {
+			continue
+		}
+		delete(r.tools, toolKey(resp.ID, resp.Name))
+		span.EndTimeUnixNano = at.UnixNano()
+		span.setString(AttrOutputValue, marshalString(resp.Response))
+		span.setString(AttrOutputMimeType, mimeApplicationJSON)
+		if _, failed := resp.Response["error"]; failed {
+			span.Status = Status{Code: StatusError, Message: fmt.Sprint(resp.Response["error"])}
+		}
+	}
+
+	kind := KindChain
+	switch {
+	case slices.Contains(r.run.Evaluators, event.Author):
+		kind = KindEvaluator
+	case event.UsageMetadata != nil || len(calls) > 0:
+		kind = KindLLM
+	}
+	if kind == KindChain && len(texts) == 0 {
+		return
+	}
+
+	start, ok := r.last[event.Author]
+	if !ok {
+		start = r.start
+	}
+	r.last[event.Author] = at
+	span := r.newSpan(event.Author, kind, start, at)
+	text := strings.Join(texts, "\n")
+	if text != "" {
+		span.setString(AttrOutputValue, text)
+		span.setString(AttrOutputMimeType, mimeTextPlain)
+	}
+	if kind != KindChain {
+		model := r.run.Model
+		if kind == KindEvaluator {
+			model = r.run.JudgeModel
+		}
+		if model != "" {
+			span.setString(AttrModelName, model)
+		}
+		span.setString(AttrOutputMessages+".0.message.role", "assistant")
+		if text != "" {
+			span.setString(AttrOutputMessages+".0.message.content", text)
+		}
+		for i, call := range calls {
+			prefix := AttrOutputMessages + ".0.message.tool_calls." + strconv.Itoa(i) + ".tool_call."
+			span.setString(prefix+"function.name", call.Name)
+			span.setString(prefix+"function.arguments", marshalString(call.Args))
+			if call.ID != "" {
+				span.setString(prefix+"id", call.ID)
+			}
+		}
+	}
+	if call, ok := usage.FromEvent(event); ok {
+		span.setInt(AttrTokenPrompt, call.InputTokens)
+		span.setInt(AttrTokenCompletion, call.OutputTokens)
+		span.setInt(AttrTokenTotal, call.TotalTokens)
+	}
+	span.setString(AttrMetadata, marshalString(map[string]string{
+		"author":        event.Author,
+		"branch":        event.Branch,
+		"invocation_id": event.InvocationID,
+		"event_id":      event.ID,
+	}))
+
+	for _, call := range calls {
+		tool := r.newSpan(call.Name, KindTool, at, at)
+		tool.ParentSpanID = span.SpanID
+		tool.setString(AttrToolName, call.Name)
+		args := marshalString(call.Args)
+		tool.setString(AttrToolParameters, args)
+		tool.setString(AttrInputValue, args)
+		tool.setString(AttrInputMimeType, mimeApplicationJSON)
+		if call.ID != "" {
+			tool.setString(AttrToolCallID, call.ID)
+		}
+		r.tools[toolKey(call.ID, call.Name)] = tool
+	}
+}
+
+// Finish ends the run with its final output, or the error it failed with, and returns the spans of the run, the root
+// span first. The TOOL spans whose response never came end with the run.
+func (r *Recorder) Finish(output string, err error) []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	end := r.now()
+	root := &Span{
+		TraceID:           r.traceID,
+		SpanID:            r.rootID,
+		Name:              cmp.Or(r.run.Name, "tumix"),
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: r.start.UnixNano(),
+		EndTimeUnixNano:   end.UnixNano(),
+		Status:            Status{Code: StatusOK},
+	}
+	root.setString(AttrSpanKind, string(KindChain))
+	root.setString(AttrInputValue, r.run.Prompt)
+	root.setString(AttrInputMimeType, mimeTextPlain)
+	if output != "" {
+		root.setString(AttrOutputValue, output)
+		root.setString(AttrOutputMimeType, mimeTextPlain)
+	}
+	if r.run.SessionID != "" {
+		root.setString(AttrSessionID, r.run.SessionID)
+	}
+	if r.run.UserID != "" {
+		root.setString(AttrUserID, r.run.UserID)
+	}
+	if err != nil {
+		root.Status = Status{Code: StatusError, Message: err.Error()}
+	}
+
+	for _, tool := range r.tools {
+		tool.EndTimeUnixNano = max(tool.EndTimeUnixNano, end.UnixNano())
+	}
+	clear(r.tools)
+
+	spans := make([]Span, 0, len(r.spans)+1)
+	spans = append(spans, *root)
+	for _, s := range r.spans {
+		spans = append(spans, *s)
+	}
+	return spans
+}
+
+// newSpan adds a span of kind under the root span.
+func (r *Recorder) newSpan(name string, kind SpanKind, start, end time.Time) *Span {
+	s := &Span{
+		TraceID:           r.traceID,
+		SpanID:            newID(8),
+		ParentSpanID:      r.rootID,
+		Name:              name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: start.UnixNano(),
+		EndTimeUnixNano:   end.UnixNano(),
+		Status:            Status{Code: StatusOK},
+	}
+	s.setString(AttrSpanKind, string(kind))
+	r.spans = append(r.spans, s)
+	return s
+}
+
+func (s *Span) setString(key, value string) {
+	s.Attributes = append(s.Attributes, Attribute{Key: key, Value: Value{StringValue: value}})
+}
+
+func (s *Span) setInt(key string, value int64) {
+	s.Attributes = append(s.Attributes, Attribute{Key: key, Value: Value{IntValue: &value}})
+}
+
+// toolKey identifies a tool call; calls without an ID are matched to their response by name.
+func toolKey(id, name string) string {
+	if id != "" {
+		return id
+	}
+	return "name:" + name
+}
+
+// marshalString returns the JSON encoding of v, or its Go syntax if it cannot be encoded.
+func marshalString(v any) string {
+	data, err := json.Marshal(v, json.Deterministic(true))
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(data)
+}
+
+// newID returns a random hex ID of n bytes, the form of OTLP trace (16 bytes) and span (8 bytes) IDs.
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
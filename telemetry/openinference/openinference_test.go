@@ -0,0 +1,301 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package openinference
+
+import (
+	"bytes"
+	json "encoding/json/v2"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+var start = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newEvent(author string, at time.Duration, parts ...*genai.Part) *session.Event {
+	ev := session.NewEvent("inv")
+	ev.Author = author
+	ev.Timestamp = start.Add(at)
+	ev.Content = &genai.Content{Role: genai.RoleModel, Parts: parts}
+	return ev
+}
+
+// span is the summary of a [Span] compared by the tests.
+type span struct {
+	Name   string
+	Kind   any
+	Parent string
+	Start  time.Duration
+	End    time.Duration
+	Status int
+	Attrs  map[string]any
+}
+
+func summarize(spans []Span, keys ...string) []span {
+	names := make(map[string]string)
+	for _, s := range spans {
+		names[s.SpanID] = s.Name
+	}
+	out := make([]span, len(spans))
+	for i, s := range spans {
+		out[i] = span{
+			Name:   s.Name,
+			Kind:   s.Attr(AttrSpanKind),
+			Parent: names[s.ParentSpanID],
+			Start:  time.Unix(0, s.StartTimeUnixNano).Sub(start),
+			End:    time.Unix(0, s.EndTimeUnixNano).Sub(start),
+			Status: s.Status.Code,
+			Attrs:  make(map[string]any),
+		}
+		for _, k := range keys {
+			if v := s.Attr(k); v != nil {
+				out[i].Attrs[k] = v
+			}
+		}
+	}
+	return out
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	clock := start
+	r := NewRecorder(Run{
+		Prompt:     "What is 6*7?",
+		UserID:     "alice",
+		SessionID:  "s1",
+		Model:      "grok-4",
+		JudgeModel: "grok-4-judge",
+		Evaluators: []string{"judge"},
+	}, WithClock(func() time.Time { return clock }))
+
+	call := newEvent("CoT", time.Second, &genai.Part{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "calc", Args: map[string]any{"expr": "6*7"}}})
+	call.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 2, TotalTokenCount: 12}
+	answer := newEvent("CoT", 3*time.Second, genai.NewPartFromText("<<<42>>>"))
+	answer.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 20, CandidatesTokenCount: 3, TotalTokenCount: 23}
+	judge := newEvent("judge", 4*time.Second, genai.NewPartFromText("<<<YES>>>"))
+	judge.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 30, CandidatesTokenCount: 1, TotalTokenCount: 31}
+	partial := newEvent("CoT", 3*time.Second, genai.NewPartFromText("<<<4"))
+	partial.Partial = true
+
+	for _, ev := range []*session.Event{
+		newEvent("user", 0, genai.NewPartFromText("What is 6*7?")),
+		call,
+		newEvent("CoT", 2*time.Second, &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: "c1", Name: "calc", Response: map[string]any{"result": 42}}}),
+		partial,
+		answer,
+		judge,
+		newEvent("tumix", 5*time.Second, genai.NewPartFromText("42")),
+		newEvent("tumix", 5*time.Second),
+	} {
+		r.Record(ev)
+	}
+	clock = start.Add(6 * time.Second)
+	got := summarize(r.Finish("42", nil), AttrModelName, AttrTokenTotal, AttrOutputValue, AttrToolName, AttrToolParameters, AttrInputValue, AttrSessionID)
+
+	want := []span{
+		{Name: "tumix", Kind: "CHAIN", End: 6 * time.Second, Status: StatusOK, Attrs: map[string]any{
+			AttrInputValue: "What is 6*7?", AttrOutputValue: "42", AttrSessionID: "s1",
+		}},
+		{Name: "CoT", Kind: "LLM", Parent: "tumix", End: time.Second, Status: StatusOK, Attrs: map[string]any{
+			AttrModelName: "grok-4", AttrTokenTotal: int64(12),
+		}},
+		{Name: "calc", Kind: "TOOL", Parent: "CoT", Start: time.Second, End: 2 * time.Second, Status: StatusOK, Attrs: map[string]any{
+			AttrToolName: "calc", AttrToolParameters: `{"expr":"6*7"}`, AttrInputValue: `{"expr":"6*7"}`, AttrOutputValue: `{"result":42}`,
+		}},
+		{Name: "CoT", Kind: "LLM", Parent: "tumix", Start: time.Second, End: 3 * time.Second, Status: StatusOK, Attrs: map[string]any{
+			AttrModelName: "grok-4", AttrTokenTotal: int64(23), AttrOutputValue: "<<<42>>>",
+		}},
+		{Name: "judge", Kind: "EVALUATOR", Parent: "tumix", End: 4 * time.Second, Status: StatusOK, Attrs: map[string]any{
+			AttrModelName: "grok-4-judge", AttrTokenTotal: int64(31), AttrOutputValue: "<<<YES>>>",
+		}},
+		{Name: "tumix", Kind: "CHAIN", Parent: "tumix", End: 5 * time.Second, Status: StatusOK, Attrs: map[string]any{
+			AttrOutputValue: "42",
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("spans mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecorderFailedRun(t *testing.T) {
+	t.Parallel()
+
+	clock := start
+	r := NewRecorder(Run{Name: "batch", Prompt: "q"}, WithClock(func() time.Time { return clock }))
+	r.Record(newEvent("agent", time.Second, &genai.Part{FunctionCall: &genai.FunctionCall{Name: "search", Args: map[string]any{"q": "x"}}}))
+	clock = start.Add(2 * time.Second)
+	got := summarize(r.Finish("", errors.New("deadline exceeded")))
+
+	want := []span{
+		{Name: "batch", Kind: "CHAIN", End: 2 * time.Second, Status: StatusError, Attrs: map[string]any{}},
+		{Name: "agent", Kind: "LLM", Parent: "batch", End: time.Second, Status: StatusOK, Attrs: map[string]any{}},
+		// The tool never answered, so its span ends with the run.
+		{Name: "search", Kind: "TOOL", Parent: "agent", Start: time.Second, End: 2 * time.Second, Status: StatusOK, Attrs: map[string]any{}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("spans mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testSpans() []Span {
+	r := NewRecorder(Run{Prompt: "q"})
+	ev := newEvent("agent", time.Second, genai.NewPartFromText("a"))
+	ev.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 7}
+	r.Record(ev)
+	return r.Finish("a", nil)
+}
+
+func TestExporterHTTP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		body   []byte
+		header http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		if r.URL.Path != "/v1/traces" {
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	e, err := NewExporter(srv.URL+"/v1/traces", WithHTTPClient(srv.Client()), WithHeaders(map[string]string{"X-Api-Key": "secret"}), WithProject("tumix-dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := testSpans()
+	if err := e.Export(t.Context(), spans); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if got := header.Get("X-Api-Key"); got != "secret" {
+		t.Fatalf("X-Api-Key = %q, want secret", got)
+	}
+	if got := header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", got)
+	}
+
+	var req exportRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("unmarshal %s: %v", body, err)
+	}
+	if diff := cmp.Diff(spans, req.ResourceSpans[0].ScopeSpans[0].Spans); diff != "" {
+		t.Fatalf("exported spans mismatch (-want +got):\n%s", diff)
+	}
+	wantResource := []Attribute{
+		{Key: "service.name", Value: Value{StringValue: "tumix"}},
+		{Key: AttrProjectName, Value: Value{StringValue: "tumix-dev"}},
+	}
+	if diff := cmp.Diff(wantResource, req.ResourceSpans[0].Resource.Attributes); diff != "" {
+		t.Fatalf("resource mismatch (-want +got):\n%s", diff)
+	}
+	// OTLP JSON encodes 64-bit integers as strings.
+	if !bytes.Contains(body, []byte(`"intValue":"7"`)) || !bytes.Contains(body, []byte(`"startTimeUnixNano":"`)) {
+		t.Fatalf("body = %s, want 64-bit integers as strings", body)
+	}
+
+	bad, err := NewExporter(srv.URL+"/wrong", WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bad.Export(t.Context(), spans); err == nil {
+		t.Fatal("Export() to a failing endpoint succeeded")
+	}
+}
+
+func TestExporterFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	for _, endpoint := range []string{path, "file://" + path} {
+		e, err := NewExporter(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Export(t.Context(), testSpans()); err != nil {
+			t.Fatalf("Export(%s) error = %v", endpoint, err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		var req exportRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			t.Fatalf("unmarshal %s: %v", line, err)
+		}
+		if n := len(req.ResourceSpans[0].ScopeSpans[0].Spans); n != 2 {
+			t.Fatalf("got %d spans, want 2", n)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		"empty": {
+			want: map[string]string{},
+		},
+		"pairs": {
+			in:   "x-api-key=secret, Langsmith-Project=my%20project",
+			want: map[string]string{"x-api-key": "secret", "Langsmith-Project": "my project"},
+		},
+		"missing value": {
+			in:      "x-api-key",
+			wantErr: true,
+		},
+		"missing key": {
+			in:      "=secret",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseHeaders(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeaders(%q) error = %v, want error %t", tt.in, err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); !tt.wantErr && diff != "" {
+				t.Fatalf("ParseHeaders(%q) mismatch (-want +got):\n%s", tt.in, diff)
+			}
+		})
+	}
+}
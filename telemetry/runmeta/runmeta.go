@@ -0,0 +1,187 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package runmeta carries the user, session, and experiment labels of a TUMIX run through the context into
+// outgoing model calls, as HTTP headers, gRPC metadata, and OpenTelemetry baggage, so provider-side logs and traces
+// can be correlated with TUMIX sessions.
+package runmeta
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Header names of the propagated metadata. gRPC metadata uses the lowercased names.
+const (
+	HeaderUserID    = "X-Tumix-User-Id"
+	HeaderSessionID = "X-Tumix-Session-Id"
+	HeaderLabels    = "X-Tumix-Labels"
+)
+
+// Baggage member keys of the propagated metadata. Labels are keyed by [BaggageLabelPrefix] and the label name.
+const (
+	BaggageUserID      = "tumix.user_id"
+	BaggageSessionID   = "tumix.session_id"
+	BaggageLabelPrefix = "tumix.label."
+)
+
+// RunMetadata identifies the run a model call belongs to.
+type RunMetadata struct {
+	UserID    string
+	SessionID string
+	// Labels are free-form experiment labels, e.g. {"experiment": "dedup", "arm": "b"}.
+	Labels map[string]string
+}
+
+// IsZero reports whether md carries no metadata.
+func (md RunMetadata) IsZero() bool {
+	return md.UserID == "" && md.SessionID == "" && len(md.Labels) == 0
+}
+
+// encodeLabels encodes the labels as a sorted, URL-escaped "k=v;k=v" list.
+func (md RunMetadata) encodeLabels() string {
+	var sb strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(md.Labels)) {
+		if sb.Len() > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString(url.QueryEscape(k))
+		sb.WriteByte('=')
+		sb.WriteString(url.QueryEscape(md.Labels[k]))
+	}
+	return sb.String()
+}
+
+// pairs returns the header name and value pairs of md, skipping empty values.
+func (md RunMetadata) pairs() [][2]string {
+	var out [][2]string
+	if md.UserID != "" {
+		out = append(out, [2]string{HeaderUserID, md.UserID})
+	}
+	if md.SessionID != "" {
+		out = append(out, [2]string{HeaderSessionID, md.SessionID})
+	}
+	if labels := md.encodeLabels(); labels != "" {
+		out = append(out, [2]string{HeaderLabels, labels})
+	}
+	return out
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying md, with md also added to the context's OpenTelemetry baggage.
+//
+// Metadata that is not a valid baggage member, such as a label name with spaces, is only sent as headers.
+func NewContext(ctx context.Context, md RunMetadata) context.Context {
+	if md.IsZero() {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, contextKey{}, md)
+
+	bag := baggage.FromContext(ctx)
+	set := func(key, value string) {
+		if value == "" {
+			return
+		}
+		m, err := baggage.NewMemberRaw(key, value)
+		if err != nil {
+			return
+		}
+		if b, err := bag.SetMember(m); err == nil {
+			bag = b
+		}
+	}
+	set(BaggageUserID, md.UserID)
+	set(BaggageSessionID, md.SessionID)
+	for _, k := range slices.Sorted(maps.Keys(md.Labels)) {
+		set(BaggageLabelPrefix+k, md.Labels[k])
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// FromContext returns the run metadata carried by ctx.
+func FromContext(ctx context.Context) (RunMetadata, bool) {
+	md, ok := ctx.Value(contextKey{}).(RunMetadata)
+	return md, ok
+}
+
+// SetHeaders sets the run metadata of ctx on h.
+func SetHeaders(ctx context.Context, h http.Header) {
+	md, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+	for _, p := range md.pairs() {
+		h.Set(p[0], p[1])
+	}
+}
+
+// outgoingContext returns ctx with the run metadata it carries appended to the outgoing gRPC metadata.
+func outgoingContext(ctx context.Context) context.Context {
+	md, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	kv := make([]string, 0, 6)
+	for _, p := range md.pairs() {
+		kv = append(kv, strings.ToLower(p[0]), p[1])
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// UnaryClientInterceptor attaches the run metadata of the call context as outgoing gRPC metadata.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor attaches the run metadata of the stream context as outgoing gRPC metadata.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+}
+
+// ParseLabels parses a comma-separated "key=value" list of experiment labels.
+func ParseLabels(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(entry, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q: want key=value", entry)
+		}
+		labels[k] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
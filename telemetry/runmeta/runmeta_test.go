@@ -0,0 +1,154 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package runmeta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/baggage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func testMetadata() RunMetadata {
+	return RunMetadata{
+		UserID:    "alice",
+		SessionID: "s-1",
+		Labels:    map[string]string{"experiment": "dedup", "arm": "b c"},
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext(t.Context(), testMetadata())
+
+	got, ok := FromContext(ctx)
+	if !ok {
+		t.Fatal("FromContext() ok = false, want true")
+	}
+	if diff := cmp.Diff(testMetadata(), got); diff != "" {
+		t.Fatalf("FromContext() mismatch (-want +got):\n%s", diff)
+	}
+
+	bag := baggage.FromContext(ctx)
+	wantBaggage := map[string]string{
+		BaggageUserID:                     "alice",
+		BaggageSessionID:                  "s-1",
+		BaggageLabelPrefix + "experiment": "dedup",
+		BaggageLabelPrefix + "arm":        "b c",
+	}
+	for k, want := range wantBaggage {
+		if got := bag.Member(k).Value(); got != want {
+			t.Fatalf("baggage %s = %q, want %q", k, got, want)
+		}
+	}
+
+	if zero := NewContext(t.Context(), RunMetadata{}); zero != t.Context() {
+		t.Fatal("NewContext() with zero metadata returned a new context")
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	t.Parallel()
+
+	h := http.Header{}
+	SetHeaders(NewContext(t.Context(), testMetadata()), h)
+	want := http.Header{
+		HeaderUserID:    {"alice"},
+		HeaderSessionID: {"s-1"},
+		HeaderLabels:    {"arm=b+c;experiment=dedup"},
+	}
+	if diff := cmp.Diff(want, h); diff != "" {
+		t.Fatalf("SetHeaders() mismatch (-want +got):\n%s", diff)
+	}
+
+	empty := http.Header{}
+	SetHeaders(t.Context(), empty)
+	if len(empty) != 0 {
+		t.Fatalf("SetHeaders() without metadata set %v", empty)
+	}
+}
+
+func TestClientInterceptors(t *testing.T) {
+	t.Parallel()
+
+	want := metadata.MD{
+		"x-tumix-user-id":    {"alice"},
+		"x-tumix-session-id": {"s-1"},
+		"x-tumix-labels":     {"arm=b+c;experiment=dedup"},
+	}
+	ctx := NewContext(t.Context(), testMetadata())
+
+	var unary metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		unary, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := UnaryClientInterceptor(ctx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("UnaryClientInterceptor() error = %v", err)
+	}
+	if diff := cmp.Diff(want, unary); diff != "" {
+		t.Fatalf("unary metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	var stream metadata.MD
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, _ = metadata.FromOutgoingContext(ctx)
+		return nil, nil
+	}
+	if _, err := StreamClientInterceptor(ctx, &grpc.StreamDesc{}, nil, "/svc/Stream", streamer); err != nil {
+		t.Fatalf("StreamClientInterceptor() error = %v", err)
+	}
+	if diff := cmp.Diff(want, stream); diff != "" {
+		t.Fatalf("stream metadata mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		"empty":         {in: "  "},
+		"pairs":         {in: "experiment=dedup, arm = b", want: map[string]string{"experiment": "dedup", "arm": "b"}},
+		"empty value":   {in: "flag=", want: map[string]string{"flag": ""}},
+		"trailing":      {in: "a=1,", want: map[string]string{"a": "1"}},
+		"missing equal": {in: "a", wantErr: true},
+		"missing key":   {in: "=1", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseLabels(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLabels(%q) error = %v, wantErr %t", tt.in, err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("ParseLabels(%q) mismatch (-want +got):\n%s", tt.in, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,520 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zchee/tumix/internal/version"
+)
+
+// protocolVersion is the MCP revision spoken by the client.
+const protocolVersion = "2025-06-18"
+
+// maxMessageSize bounds a single JSON-RPC message read from a server.
+const maxMessageSize = 16 << 20
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitzero"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitzero"`
+}
+
+type rpcMessage struct {
+	JSONRPC string         `json:"jsonrpc"`
+	ID      *int64         `json:"id,omitzero"`
+	Method  string         `json:"method,omitzero"`
+	Result  jsontext.Value `json:"result,omitzero"`
+	Error   *RPCError      `json:"error,omitzero"`
+}
+
+// RPCError is a JSON-RPC error returned by an MCP server.
+type RPCError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    jsontext.Value `json:"data,omitzero"`
+}
+
+// Error implements error.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp rpc error %d: %s", e.Code, e.Message)
+}
+
+// Tool is a tool advertised by an MCP server.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitzero"`
+	InputSchema jsontext.Value `json:"inputSchema,omitzero"`
+}
+
+// Content is a single content block of a tool result.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitzero"`
+	MIMEType string `json:"mimeType,omitzero"`
+	Data     string `json:"data,omitzero"`
+}
+
+// CallToolResult is the result of a tools/call request.
+type CallToolResult struct {
+	Content           []Content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitzero"`
+	IsError           bool      `json:"isError,omitzero"`
+}
+
+// Text concatenates the text content blocks of the result.
+func (r *CallToolResult) Text() string {
+	var sb strings.Builder
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			sb.WriteString(c.Text)
+		}
+	}
+	return sb.String()
+}
+
+type transport interface {
+	call(ctx context.Context, req *rpcRequest) (*rpcMessage, error)
+	notify(ctx context.Context, req *rpcRequest) error
+	close() error
+}
+
+// Client is a connection to a single MCP server.
+type Client struct {
+	name   string
+	t      transport
+	nextID atomic.Int64
+}
+
+// Connect starts or dials the MCP server described by cfg and performs the initialize handshake.
+func Connect(ctx context.Context, name string, cfg ServerConfig) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("mcp server %q: %w", name, err)
+	}
+
+	var (
+		t   transport
+		err error
+	)
+	if cfg.Command != "" {
+		t, err = newStdioTransport(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+	} else {
+		t = newHTTPTransport(cfg, http.DefaultClient)
+	}
+
+	c := &Client{name: name, t: t}
+	if err := c.initialize(ctx); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("mcp server %q: %w", name, err)
+	}
+
+	return c, nil
+}
+
+// Name returns the configured server name.
+func (c *Client) Name() string { return c.name }
+
+// Close terminates the connection.
+func (c *Client) Close() error { return c.t.close() }
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo": map[string]any{
+			"name":    "tumix",
+			"version": version.Version,
+		},
+	}
+	if err := c.call(ctx, "initialize", params, nil); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := c.t.notify(ctx, &rpcRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return fmt.Errorf("initialized notification: %w", err)
+	}
+	return nil
+}
+
+// ListTools returns every tool advertised by the server, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var (
+		tools  []Tool
+		cursor string
+	)
+	for {
+		var params map[string]any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor,omitzero"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("list tools: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes the named tool with args.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	var res CallToolResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &res); err != nil {
+		return nil, fmt.Errorf("call tool %q: %w", name, err)
+	}
+	return &res, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	id := c.nextID.Add(1)
+	msg, err := c.t.call(ctx, &rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if out == nil || len(msg.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Result, out); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// stdioTransport speaks newline-delimited JSON-RPC with a child process.
+type stdioTransport struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// writeMu serializes the messages written to stdin. It is not mu, which the read loop needs to dispatch the
+	// responses, so a blocked write cannot stop the responses of a server that writes before it reads.
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan *rpcMessage
+	done    chan struct{}
+	err     error
+}
+
+func newStdioTransport(cfg ServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...) //nolint:gosec // command comes from the user supplied config
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cfg.Command, err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *rpcMessage),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+
+	return t, nil
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		switch {
+		case msg.ID != nil && msg.Method == "":
+			t.mu.Lock()
+			ch, ok := t.pending[*msg.ID]
+			delete(t.pending, *msg.ID)
+			t.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		case msg.ID != nil:
+			// Server-initiated requests: answer ping, reject the rest.
+			reply := map[string]any{"jsonrpc": "2.0", "id": *msg.ID, "result": map[string]any{}}
+			if msg.Method != "ping" {
+				reply = map[string]any{"jsonrpc": "2.0", "id": *msg.ID, "error": RPCError{Code: -32601, Message: "method not found"}}
+			}
+			// Reply without blocking the loop, which must keep reading for the server to read the reply.
+			go func() { _ = t.write(reply) }()
+		}
+	}
+
+	t.mu.Lock()
+	t.err = cmp.Or(scanner.Err(), io.EOF)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) call(ctx context.Context, req *rpcRequest) (*rpcMessage, error) {
+	ch := make(chan *rpcMessage, 1)
+	t.mu.Lock()
+	t.pending[*req.ID] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.forget(*req.ID)
+		return nil, err
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-ctx.Done():
+		t.forget(*req.ID)
+		return nil, ctx.Err()
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, fmt.Errorf("server exited: %w", t.err)
+	}
+}
+
+func (t *stdioTransport) forget(id int64) {
+	t.mu.Lock()
+	delete(t.pending, id)
+	t.mu.Unlock()
+}
+
+func (t *stdioTransport) notify(_ context.Context, req *rpcRequest) error {
+	return t.write(req)
+}
+
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+	if t.cmd.Process != nil {
+		_ = t.cmd.Process.Kill()
+	}
+	_ = t.cmd.Wait()
+	return nil
+}
+
+// httpTransport speaks the MCP streamable HTTP transport.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newHTTPTransport(cfg ServerConfig, client *http.Client) *httpTransport {
+	return &httpTransport{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client:  client,
+	}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Mcp-Protocol-Version", protocolVersion)
+	req.Header.Set("User-Agent", version.UserAgent("mcp"))
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *httpTransport) post(ctx context.Context, msg *rpcRequest) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("encode message: %w", err)
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post %s: %w", msg.Method, err)
+	}
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return nil, fmt.Errorf("post %s: unexpected status %s: %s", msg.Method, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, req *rpcRequest) (*rpcMessage, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return readSSEResponse(resp.Body, *req.ID)
+	}
+
+	var msg rpcMessage
+	if err := json.UnmarshalRead(io.LimitReader(resp.Body, maxMessageSize), &msg); err != nil {
+		return nil, fmt.Errorf("decode %s response: %w", req.Method, err)
+	}
+	return &msg, nil
+}
+
+// readSSEResponse scans an event stream for the response matching id.
+func readSSEResponse(r io.Reader, id int64) (*rpcMessage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+
+	var data strings.Builder
+	flush := func() (*rpcMessage, bool) {
+		defer data.Reset()
+		if data.Len() == 0 {
+			return nil, false
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal([]byte(data.String()), &msg); err != nil {
+			return nil, false
+		}
+		if msg.ID == nil || *msg.ID != id || msg.Method != "" {
+			return nil, false
+		}
+		return &msg, true
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if msg, ok := flush(); ok {
+				return msg, nil
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(rest, " "))
+		}
+	}
+	if msg, ok := flush(); ok {
+		return msg, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read event stream: %w", err)
+	}
+	return nil, errors.New("event stream ended without a response")
+}
+
+func (t *httpTransport) notify(ctx context.Context, req *rpcRequest) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sid := t.sessionID
+	t.mu.Unlock()
+	if sid == "" {
+		return nil
+	}
+
+	// Best effort session termination; servers may answer 405.
+	req, err := t.newRequest(context.Background(), http.MethodDelete, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mcp connects to Model Context Protocol (MCP) servers and exposes their tools to ADK agents.
+package mcp
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config declares the MCP servers to connect to.
+//
+// The file format follows the widely used "mcpServers" layout:
+//
+//	{
+//	  "mcpServers": {
+//	    "fs": {"command": "mcp-server-filesystem", "args": ["/tmp"]},
+//	    "db": {"url": "http://localhost:8080/mcp", "headers": {"Authorization": "Bearer ..."}}
+//	  }
+//	}
+type Config struct {
+	Servers map[string]ServerConfig `json:"mcpServers"`
+}
+
+// ServerConfig describes how to reach a single MCP server.
+//
+// Exactly one of Command (stdio transport) or URL (streamable HTTP transport) must be set.
+type ServerConfig struct {
+	// Command is the executable launched for the stdio transport.
+	Command string `json:"command,omitzero"`
+	// Args are the arguments passed to Command.
+	Args []string `json:"args,omitzero"`
+	// Env holds extra environment variables for Command.
+	Env map[string]string `json:"env,omitzero"`
+	// URL is the endpoint of a streamable HTTP server.
+	URL string `json:"url,omitzero"`
+	// Headers are sent with every HTTP request.
+	Headers map[string]string `json:"headers,omitzero"`
+}
+
+// Validate reports whether the server config selects exactly one transport.
+func (c ServerConfig) Validate() error {
+	switch {
+	case c.Command == "" && c.URL == "":
+		return errors.New("either command or url is required")
+	case c.Command != "" && c.URL != "":
+		return errors.New("command and url are mutually exclusive")
+	default:
+		return nil
+	}
+}
+
+// LoadConfig reads and validates an MCP config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("read mcp config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse mcp config: %w", err)
+	}
+	for name, server := range cfg.Servers {
+		if err := server.Validate(); err != nil {
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+	}
+
+	return &cfg, nil
+}
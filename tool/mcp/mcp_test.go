@@ -0,0 +1,271 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	json "encoding/json/v2"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+
+	"github.com/zchee/tumix/agent/agenttest"
+)
+
+// newTestServer returns a streamable HTTP MCP server exposing an "echo" tool.
+//
+// When sse is true, responses are sent as an event stream.
+func newTestServer(t *testing.T, sse bool) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     *int64         `json:"id"`
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}
+		if err := json.UnmarshalRead(r.Body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		var result any
+		switch req.Method {
+		case "initialize":
+			w.Header().Set("Mcp-Session-Id", "sess-1")
+			result = map[string]any{"protocolVersion": protocolVersion, "capabilities": map[string]any{}}
+		case "tools/list":
+			if r.Header.Get("Mcp-Session-Id") != "sess-1" {
+				http.Error(w, "missing session", http.StatusBadRequest)
+				return
+			}
+			result = map[string]any{"tools": []any{map[string]any{
+				"name":        "echo",
+				"description": "Echo the text.",
+				"inputSchema": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"text": map[string]any{"type": "string"}},
+				},
+			}}}
+		case "tools/call":
+			args, _ := req.Params["arguments"].(map[string]any)
+			result = map[string]any{"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(args["text"])}}}
+		default:
+			result = nil
+		}
+
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+}
+
+func TestToolsetHTTP(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sse bool
+	}{
+		"success: json responses": {sse: false},
+		"success: sse responses":  {sse: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newTestServer(t, tt.sse)
+			defer srv.Close()
+
+			ts, err := NewToolset(t.Context(), &Config{Servers: map[string]ServerConfig{"demo": {URL: srv.URL}}})
+			if err != nil {
+				t.Fatalf("NewToolset() error = %v", err)
+			}
+			defer ts.Close()
+
+			tools, err := ts.Tools(nil)
+			if err != nil {
+				t.Fatalf("Tools() error = %v", err)
+			}
+			if len(tools) != 1 || tools[0].Name() != "demo_echo" {
+				t.Fatalf("tools = %v, want [demo_echo]", tools)
+			}
+
+			type runnableTool interface {
+				Run(ctx tool.Context, args any) (map[string]any, error)
+			}
+			rt, ok := tools[0].(runnableTool)
+			if !ok {
+				t.Fatalf("tool %T is not runnable", tools[0])
+			}
+			ctx := agenttest.NewToolContext(t.Context(), agenttest.NewInMemoryState(nil), &session.EventActions{}, "call-1", nil)
+			got, err := rt.Run(ctx, map[string]any{"text": "hello"})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if diff := cmp.Diff(map[string]any{"output": "hello"}, got); diff != "" {
+				t.Fatalf("Run() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestStdioTransportServerWritesFirst has the server write a response and another message before it reads the rest of
+// the request being written, so the transport must dispatch responses while its write is blocked.
+func TestStdioTransportServerWritesFirst(t *testing.T) {
+	t.Parallel()
+
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	tr := &stdioTransport{
+		stdin:   stdinW,
+		pending: make(map[int64]chan *rpcMessage),
+		done:    make(chan struct{}),
+	}
+	go tr.readLoop(stdoutR)
+	t.Cleanup(func() {
+		stdinR.Close()
+		stdoutW.Close()
+	})
+
+	go func() {
+		// Read the first byte only: the rest of the request stays blocked in the write.
+		if _, err := stdinR.Read(make([]byte, 1)); err != nil {
+			return
+		}
+		fmt.Fprintln(stdoutW, `{"jsonrpc":"2.0","id":1,"result":{"ok":true}}`)
+		fmt.Fprintln(stdoutW, `{"jsonrpc":"2.0","method":"notifications/progress"}`)
+		_, _ = io.Copy(io.Discard, stdinR)
+	}()
+
+	id := int64(1)
+	type result struct {
+		msg *rpcMessage
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		msg, err := tr.call(t.Context(), &rpcRequest{JSONRPC: "2.0", ID: &id, Method: "tools/list"})
+		done <- result{msg: msg, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("call() error = %v", res.err)
+		}
+		if got := string(res.msg.Result); got != `{"ok":true}` {
+			t.Fatalf("call() result = %s, want {\"ok\":true}", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call() deadlocked with a server writing before it reads")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data    string
+		want    *Config
+		wantErr bool
+	}{
+		"success: stdio and http servers": {
+			data: `{"mcpServers":{"fs":{"command":"mcp-fs","args":["/tmp"]},"db":{"url":"http://localhost/mcp"}}}`,
+			want: &Config{Servers: map[string]ServerConfig{
+				"fs": {Command: "mcp-fs", Args: []string{"/tmp"}},
+				"db": {URL: "http://localhost/mcp"},
+			}},
+		},
+		"error: missing transport": {
+			data:    `{"mcpServers":{"bad":{}}}`,
+			wantErr: true,
+		},
+		"error: both transports": {
+			data:    `{"mcpServers":{"bad":{"command":"x","url":"http://x"}}}`,
+			wantErr: true,
+		},
+		"error: invalid json": {
+			data:    `{`,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "mcp.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+			got, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("LoadConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToolName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		server, name string
+		want         string
+	}{
+		"plain":     {server: "fs", name: "read_file", want: "fs_read_file"},
+		"sanitized": {server: "my server", name: "a.b/c", want: "my_server_a_b_c"},
+		"truncated": {server: "s", name: string(make([]byte, 80)), want: "s_" + strings.Repeat("_", 62)},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ToolName(tt.server, tt.name); got != tt.want {
+				t.Fatalf("ToolName(%q, %q) = %q, want %q", tt.server, tt.name, got, tt.want)
+			}
+		})
+	}
+}
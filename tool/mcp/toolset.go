@@ -0,0 +1,150 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// maxToolNameLen is the longest function name accepted by every supported backend.
+const maxToolNameLen = 64
+
+// Toolset exposes the tools of one or more MCP servers as ADK function tools.
+//
+// Tool names are prefixed with the server name ("<server>_<tool>") so tools from different servers never collide.
+type Toolset struct {
+	clients []*Client
+	tools   []tool.Tool
+}
+
+var _ tool.Toolset = (*Toolset)(nil)
+
+// NewToolset connects to every server declared in cfg and wraps their tools.
+//
+// The returned Toolset owns the connections; call [Toolset.Close] when done.
+func NewToolset(ctx context.Context, cfg *Config) (*Toolset, error) {
+	if cfg == nil || len(cfg.Servers) == 0 {
+		return nil, errors.New("mcp: no servers configured")
+	}
+
+	ts := &Toolset{}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Servers)) {
+		client, err := Connect(ctx, name, cfg.Servers[name])
+		if err != nil {
+			_ = ts.Close()
+			return nil, err
+		}
+		ts.clients = append(ts.clients, client)
+
+		tools, err := client.ListTools(ctx)
+		if err != nil {
+			_ = ts.Close()
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+		for _, t := range tools {
+			wrapped, err := newFunctionTool(client, t)
+			if err != nil {
+				_ = ts.Close()
+				return nil, fmt.Errorf("mcp server %q: %w", name, err)
+			}
+			ts.tools = append(ts.tools, wrapped)
+		}
+	}
+
+	return ts, nil
+}
+
+// Name implements [tool.Toolset].
+func (ts *Toolset) Name() string { return "mcp" }
+
+// Tools implements [tool.Toolset].
+func (ts *Toolset) Tools(agent.ReadonlyContext) ([]tool.Tool, error) {
+	return ts.tools, nil
+}
+
+// Close closes every server connection.
+func (ts *Toolset) Close() error {
+	var errs []error
+	for _, c := range ts.clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close mcp server %q: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newFunctionTool wraps an MCP tool into an ADK function tool calling back into client.
+func newFunctionTool(client *Client, t Tool) (tool.Tool, error) {
+	schema := &jsonschema.Schema{Type: "object"}
+	if len(t.InputSchema) > 0 {
+		if err := json.Unmarshal(t.InputSchema, schema); err != nil {
+			return nil, fmt.Errorf("decode input schema of tool %q: %w", t.Name, err)
+		}
+	}
+
+	cfg := functiontool.Config{
+		Name:        ToolName(client.Name(), t.Name),
+		Description: t.Description,
+		InputSchema: schema,
+	}
+	ft, err := functiontool.New(cfg, func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		res, err := client.CallTool(ctx, t.Name, args)
+		if err != nil {
+			return nil, err
+		}
+		if res.IsError {
+			return nil, fmt.Errorf("mcp tool %q failed: %s", t.Name, res.Text())
+		}
+		if res.StructuredContent != nil {
+			return map[string]any{"output": res.StructuredContent}, nil
+		}
+		return map[string]any{"output": res.Text()}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build tool %q: %w", t.Name, err)
+	}
+	return ft, nil
+}
+
+// ToolName returns the function name under which a server tool is exposed to models.
+//
+// Characters outside [A-Za-z0-9_-] are replaced with '_' and the result is truncated to 64 bytes.
+func ToolName(server, name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, server+"_"+name)
+	if len(sanitized) > maxToolNameLen {
+		sanitized = sanitized[:maxToolNameLen]
+	}
+	return sanitized
+}
@@ -0,0 +1,305 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package python runs Python code cells in persistent interpreter processes ("kernels") for code agents.
+//
+// Each kernel keeps its global namespace across cells, like a Jupyter kernel, and reports stdout, stderr,
+// the value of a trailing expression, and any traceback separately.
+package python
+
+import (
+	"bufio"
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the wall-clock limit of a single cell.
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxMemory is the address space limit of a kernel process in bytes.
+	DefaultMaxMemory int64 = 2 << 30
+	// DefaultMaxOutput caps the stdout and stderr bytes returned per cell.
+	DefaultMaxOutput = 16 << 10
+
+	// interruptGrace is how long an interrupted cell may take to unwind before the kernel is killed.
+	interruptGrace = 2 * time.Second
+)
+
+// ErrKernelDead is returned when the kernel process exited and its state was lost.
+var ErrKernelDead = errors.New("python kernel is not running")
+
+// driver is the kernel main loop run by the interpreter.
+//
+// Requests are read from fd 3 and responses written to fd 4 so user code cannot corrupt the protocol through
+// stdin or stdout. SIGINT interrupts only a running cell.
+const driver = `
+import ast, contextlib, io, json, os, signal, sys, traceback
+
+try:
+    import resource
+    limit = int(os.environ.get("TUMIX_PYTHON_MAX_MEMORY", "0"))
+    if limit > 0:
+        resource.setrlimit(resource.RLIMIT_AS, (limit, limit))
+except Exception:
+    pass
+
+requests = os.fdopen(3, "r")
+responses = os.fdopen(4, "w")
+namespace = {"__name__": "__main__"}
+signal.signal(signal.SIGINT, signal.SIG_IGN)
+
+for line in requests:
+    code = json.loads(line)["code"]
+    out, err = io.StringIO(), io.StringIO()
+    result, error = None, None
+    signal.signal(signal.SIGINT, signal.default_int_handler)
+    try:
+        with contextlib.redirect_stdout(out), contextlib.redirect_stderr(err):
+            tree = ast.parse(code, "<cell>", "exec")
+            last = None
+            if tree.body and isinstance(tree.body[-1], ast.Expr):
+                last = ast.Expression(tree.body.pop().value)
+            exec(compile(tree, "<cell>", "exec"), namespace)
+            if last is not None:
+                value = eval(compile(last, "<cell>", "eval"), namespace)
+                if value is not None:
+                    result = repr(value)
+    except BaseException:
+        etype, value, tb = sys.exc_info()
+        error = "".join(traceback.format_exception(etype, value, tb.tb_next))
+    finally:
+        signal.signal(signal.SIGINT, signal.SIG_IGN)
+    responses.write(json.dumps({"stdout": out.getvalue(), "stderr": err.getvalue(), "result": result, "error": error}) + "\n")
+    responses.flush()
+`
+
+// Result is the outcome of executing one code cell.
+type Result struct {
+	// Stdout is what the cell printed to standard output.
+	Stdout string `json:"stdout,omitzero"`
+	// Stderr is what the cell printed to standard error.
+	Stderr string `json:"stderr,omitzero"`
+	// Value is the repr of the cell's trailing expression, if it is not None.
+	Value string `json:"result,omitzero"`
+	// Error is the formatted traceback of an uncaught exception.
+	Error string `json:"error,omitzero"`
+	// TimedOut reports whether the cell hit the wall-clock limit and was interrupted.
+	TimedOut bool `json:"timed_out,omitzero"`
+}
+
+type response struct {
+	Stdout string  `json:"stdout"`
+	Stderr string  `json:"stderr"`
+	Result *string `json:"result"`
+	Error  *string `json:"error"`
+}
+
+// Kernel is a persistent Python interpreter process.
+//
+// Cells run one at a time; a Kernel is safe for concurrent use.
+type Kernel struct {
+	opts options
+
+	cmd       *exec.Cmd
+	requests  *os.File
+	responses chan response
+	exited    chan struct{}
+	killed    chan struct{}
+	killOnce  sync.Once
+
+	mu sync.Mutex
+}
+
+// StartKernel launches a new kernel process.
+func StartKernel(opts ...Option) (*Kernel, error) {
+	return startKernel(newOptions(opts))
+}
+
+func startKernel(o options) (*Kernel, error) {
+	reqR, reqW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("create request pipe: %w", err)
+	}
+	respR, respW, err := os.Pipe()
+	if err != nil {
+		reqR.Close()
+		reqW.Close()
+		return nil, fmt.Errorf("create response pipe: %w", err)
+	}
+
+	cmd := exec.Command(o.interpreter, "-u", "-c", driver)
+	cmd.Dir = o.dir
+	cmd.Env = append(os.Environ(), "TUMIX_PYTHON_MAX_MEMORY="+strconv.FormatInt(o.maxMemory, 10))
+	cmd.ExtraFiles = []*os.File{reqR, respW}
+	if err := cmd.Start(); err != nil {
+		reqR.Close()
+		reqW.Close()
+		respR.Close()
+		respW.Close()
+		return nil, fmt.Errorf("start python kernel: %w", err)
+	}
+	reqR.Close()
+	respW.Close()
+
+	k := &Kernel{
+		opts:      o,
+		cmd:       cmd,
+		requests:  reqW,
+		responses: make(chan response),
+		exited:    make(chan struct{}),
+		killed:    make(chan struct{}),
+	}
+	go k.readResponses(respR)
+
+	return k, nil
+}
+
+// readResponses forwards decoded responses until the kernel closes its end of the pipe.
+func (k *Kernel) readResponses(r *os.File) {
+	defer close(k.exited)
+	defer r.Close()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	for sc.Scan() {
+		var resp response
+		if err := json.Unmarshal(sc.Bytes(), &resp); err != nil {
+			msg := "decode kernel response: " + err.Error()
+			resp = response{Error: &msg}
+		}
+		select {
+		case k.responses <- resp:
+		case <-k.killed:
+		}
+	}
+	_ = k.cmd.Wait()
+}
+
+// Execute runs code in the kernel and returns its outputs.
+//
+// When the cell exceeds the wall-clock limit it is interrupted with SIGINT, which keeps the namespace intact; if
+// it does not stop within a grace period, the kernel is killed and [ErrKernelDead] is returned.
+func (k *Kernel) Execute(ctx context.Context, code string) (*Result, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !k.Alive() {
+		return nil, ErrKernelDead
+	}
+
+	line, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return nil, fmt.Errorf("encode cell: %w", err)
+	}
+	if _, err := k.requests.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("send cell: %w", errors.Join(ErrKernelDead, err))
+	}
+
+	timer := time.NewTimer(k.opts.timeout)
+	defer timer.Stop()
+
+	var timedOut bool
+	select {
+	case resp := <-k.responses:
+		return k.result(resp, false), nil
+	case <-k.exited:
+		return nil, ErrKernelDead
+	case <-timer.C:
+		timedOut = true
+	case <-ctx.Done():
+	}
+
+	// Interrupt the running cell and give it a moment to unwind.
+	_ = k.cmd.Process.Signal(os.Interrupt)
+	select {
+	case resp := <-k.responses:
+		if timedOut {
+			return k.result(resp, true), nil
+		}
+		return nil, ctx.Err()
+	case <-k.exited:
+	case <-time.After(interruptGrace):
+		_ = k.kill()
+	}
+	if timedOut {
+		return nil, fmt.Errorf("cell exceeded %s: %w", k.opts.timeout, ErrKernelDead)
+	}
+	return nil, ctx.Err()
+}
+
+func (k *Kernel) result(resp response, timedOut bool) *Result {
+	r := &Result{
+		Stdout:   truncate(resp.Stdout, k.opts.maxOutput),
+		Stderr:   truncate(resp.Stderr, k.opts.maxOutput),
+		TimedOut: timedOut,
+	}
+	if resp.Result != nil {
+		r.Value = truncate(*resp.Result, k.opts.maxOutput)
+	}
+	if resp.Error != nil {
+		r.Error = truncate(*resp.Error, k.opts.maxOutput)
+	}
+	if timedOut {
+		r.Error = fmt.Sprintf("cell interrupted after exceeding the %s time limit\n%s", k.opts.timeout, r.Error)
+	}
+	return r
+}
+
+// Alive reports whether the kernel process is still running.
+func (k *Kernel) Alive() bool {
+	select {
+	case <-k.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close stops the kernel process.
+func (k *Kernel) Close() error {
+	if err := k.requests.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("close python kernel: %w", err)
+	}
+	select {
+	case <-k.exited:
+	case <-time.After(interruptGrace):
+		return k.kill()
+	}
+	return nil
+}
+
+func (k *Kernel) kill() error {
+	k.killOnce.Do(func() { close(k.killed) })
+	if err := k.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("kill python kernel: %w", err)
+	}
+	<-k.exited
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n[truncated]"
+}
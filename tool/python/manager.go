@@ -0,0 +1,211 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package python
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKernels bounds the number of live kernels held by a [Manager].
+const DefaultMaxKernels = 16
+
+type options struct {
+	interpreter string
+	dir         string
+	timeout     time.Duration
+	maxMemory   int64
+	maxOutput   int
+	maxKernels  int
+}
+
+// Option configures kernels started by [StartKernel] or a [Manager].
+type Option func(*options)
+
+// WithInterpreter sets the Python executable (default "python3").
+func WithInterpreter(path string) Option {
+	return func(o *options) {
+		if path != "" {
+			o.interpreter = path
+		}
+	}
+}
+
+// WithDir sets the working directory of kernel processes.
+func WithDir(dir string) Option {
+	return func(o *options) { o.dir = dir }
+}
+
+// WithTimeout sets the wall-clock limit of a single cell.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithMaxMemory sets the address space limit of a kernel in bytes. Zero or less disables the limit.
+//
+// The limit is enforced with RLIMIT_AS where the platform supports it.
+func WithMaxMemory(n int64) Option {
+	return func(o *options) { o.maxMemory = n }
+}
+
+// WithMaxOutput caps the stdout, stderr, result, and error bytes returned per cell.
+func WithMaxOutput(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxOutput = n
+		}
+	}
+}
+
+// WithMaxKernels bounds the number of live kernels a [Manager] keeps; the least recently used one is closed first.
+func WithMaxKernels(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxKernels = n
+		}
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		interpreter: "python3",
+		timeout:     DefaultTimeout,
+		maxMemory:   DefaultMaxMemory,
+		maxOutput:   DefaultMaxOutput,
+		maxKernels:  DefaultMaxKernels,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type managedKernel struct {
+	kernel   *Kernel
+	lastUsed time.Time
+}
+
+// Manager lazily starts one kernel per key and reuses it across cells.
+//
+// Keys typically identify an agent within an invocation so each code agent keeps its own state for the whole
+// run. A Manager is safe for concurrent use.
+type Manager struct {
+	opts options
+
+	mu      sync.Mutex
+	kernels map[string]*managedKernel
+	closed  bool
+}
+
+// NewManager returns a Manager starting kernels configured by opts.
+func NewManager(opts ...Option) *Manager {
+	return &Manager{
+		opts:    newOptions(opts),
+		kernels: make(map[string]*managedKernel),
+	}
+}
+
+// Execute runs code in the kernel of key, starting it first if needed.
+//
+// If the kernel died during the cell, it is dropped and the next call for key starts a fresh one.
+func (m *Manager) Execute(ctx context.Context, key, code string) (*Result, error) {
+	k, err := m.kernel(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := k.Execute(ctx, code)
+	if errors.Is(err, ErrKernelDead) {
+		m.drop(key, k)
+		return nil, fmt.Errorf("%w; its state was lost and the next cell starts a fresh kernel", err)
+	}
+	return res, err
+}
+
+func (m *Manager) kernel(key string) (*Kernel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, errors.New("python kernel manager is closed")
+	}
+
+	if mk, ok := m.kernels[key]; ok && mk.kernel.Alive() {
+		mk.lastUsed = time.Now()
+		return mk.kernel, nil
+	}
+
+	if len(m.kernels) >= m.opts.maxKernels {
+		m.evictLocked()
+	}
+	k, err := startKernel(m.opts)
+	if err != nil {
+		return nil, err
+	}
+	m.kernels[key] = &managedKernel{kernel: k, lastUsed: time.Now()}
+	return k, nil
+}
+
+// evictLocked closes the least recently used kernel. m.mu must be held.
+func (m *Manager) evictLocked() {
+	var (
+		oldestKey string
+		oldest    *managedKernel
+	)
+	for key, mk := range m.kernels {
+		if oldest == nil || mk.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = key, mk
+		}
+	}
+	if oldest != nil {
+		delete(m.kernels, oldestKey)
+		go oldest.kernel.Close()
+	}
+}
+
+func (m *Manager) drop(key string, k *Kernel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if mk, ok := m.kernels[key]; ok && mk.kernel == k {
+		delete(m.kernels, key)
+	}
+}
+
+// Close stops every kernel.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	kernels := m.kernels
+	m.kernels = make(map[string]*managedKernel)
+	m.closed = true
+	m.mu.Unlock()
+
+	var errs []error
+	for _, mk := range kernels {
+		if err := mk.kernel.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package python
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+
+	"github.com/zchee/tumix/agent/agenttest"
+)
+
+func requirePython(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found in PATH")
+	}
+}
+
+func TestKernelExecute(t *testing.T) {
+	t.Parallel()
+	requirePython(t)
+
+	k, err := StartKernel(WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("StartKernel() error = %v", err)
+	}
+	t.Cleanup(func() { _ = k.Close() })
+
+	// Cells run in order against the same kernel, so state carries over between steps.
+	steps := []struct {
+		name string
+		code string
+		want *Result
+	}{
+		{name: "assign and print", code: "x = 40\nprint('x set')", want: &Result{Stdout: "x set\n"}},
+		{name: "state persists", code: "x + 2", want: &Result{Value: "42"}},
+		{name: "stderr and value", code: "import sys\nsys.stderr.write('warn\\n')\n[x]", want: &Result{Stderr: "warn\n", Value: "[40]"}},
+		{name: "none value omitted", code: "None", want: &Result{}},
+		{name: "stdin is not the protocol", code: "import sys\nsys.stdin.read()", want: &Result{Value: "''"}},
+	}
+	for _, step := range steps {
+		got, err := k.Execute(t.Context(), step.code)
+		if err != nil {
+			t.Fatalf("%s: Execute() error = %v", step.name, err)
+		}
+		if diff := cmp.Diff(step.want, got); diff != "" {
+			t.Fatalf("%s: Execute() mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+
+	got, err := k.Execute(t.Context(), "1 / 0")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(got.Error, "ZeroDivisionError") || strings.Contains(got.Error, "namespace") {
+		t.Fatalf("Execute() error output = %q, want a user-only ZeroDivisionError traceback", got.Error)
+	}
+
+	got, err = k.Execute(t.Context(), "import time\nwhile True: time.sleep(0.01)")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !got.TimedOut || !strings.Contains(got.Error, "KeyboardInterrupt") {
+		t.Fatalf("Execute() = %+v, want interrupted cell", got)
+	}
+
+	got, err = k.Execute(t.Context(), "x")
+	if err != nil {
+		t.Fatalf("Execute() after interrupt error = %v", err)
+	}
+	if got.Value != "40" {
+		t.Fatalf("Execute() after interrupt value = %q, want state to survive the interrupt", got.Value)
+	}
+}
+
+func TestManagerExecute(t *testing.T) {
+	t.Parallel()
+	requirePython(t)
+
+	m := NewManager(WithMaxKernels(1))
+	t.Cleanup(func() { _ = m.Close() })
+
+	if _, err := m.Execute(t.Context(), "a", "v = 'a'"); err != nil {
+		t.Fatalf("Execute(a) error = %v", err)
+	}
+	got, err := m.Execute(t.Context(), "b", "'v' in globals()")
+	if err != nil {
+		t.Fatalf("Execute(b) error = %v", err)
+	}
+	if got.Value != "False" {
+		t.Fatalf("kernel b sees kernel a state: value = %q", got.Value)
+	}
+
+	// Kernel "a" was evicted by "b" under the one-kernel limit and starts fresh.
+	got, err = m.Execute(t.Context(), "a", "'v' in globals()")
+	if err != nil {
+		t.Fatalf("Execute(a) error = %v", err)
+	}
+	if got.Value != "False" {
+		t.Fatalf("evicted kernel kept state: value = %q", got.Value)
+	}
+
+	if _, err := m.Execute(t.Context(), "a", "import os\nos._exit(1)"); err == nil {
+		t.Fatal("Execute() of exiting cell error = nil, want ErrKernelDead")
+	}
+	got, err = m.Execute(t.Context(), "a", "1 + 1")
+	if err != nil {
+		t.Fatalf("Execute() after kernel death error = %v", err)
+	}
+	if got.Value != "2" {
+		t.Fatalf("Execute() after kernel death value = %q, want 2", got.Value)
+	}
+}
+
+func TestToolset(t *testing.T) {
+	t.Parallel()
+	requirePython(t)
+
+	ts, err := NewToolset(NewManager())
+	if err != nil {
+		t.Fatalf("NewToolset() error = %v", err)
+	}
+	t.Cleanup(func() { _ = ts.Close() })
+
+	tools, err := ts.Tools(nil)
+	if err != nil {
+		t.Fatalf("Tools() error = %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name() != ToolName {
+		t.Fatalf("tools = %v, want [%s]", tools, ToolName)
+	}
+
+	type runnableTool interface {
+		Run(ctx tool.Context, args any) (map[string]any, error)
+	}
+	rt, ok := tools[0].(runnableTool)
+	if !ok {
+		t.Fatalf("tool %T is not runnable", tools[0])
+	}
+
+	ctx := agenttest.NewToolContext(t.Context(), agenttest.NewInMemoryState(nil), &session.EventActions{}, "call-1", nil)
+	if _, err := rt.Run(ctx, map[string]any{"code": "n = 6"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	got, err := rt.Run(ctx, map[string]any{"code": "print(n * 7)\nn"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if diff := cmp.Diff(map[string]any{"stdout": "42\n", "result": "6"}, got); diff != "" {
+		t.Fatalf("Run() mismatch (-want +got):\n%s", diff)
+	}
+}
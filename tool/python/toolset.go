@@ -0,0 +1,88 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package python
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ToolName is the function name under which the Python tool is exposed to models.
+const ToolName = "python"
+
+type executeArgs struct {
+	Code string `json:"code"`
+}
+
+// Toolset exposes a [Manager] as the "python" ADK function tool.
+//
+// Every agent gets its own kernel per invocation, so variables defined in one call are visible in the next
+// call of the same agent across rounds, but never leak between agents or prompts.
+type Toolset struct {
+	manager *Manager
+	tools   []tool.Tool
+}
+
+var _ tool.Toolset = (*Toolset)(nil)
+
+// NewToolset returns a Toolset running cells on m.
+func NewToolset(m *Manager) (*Toolset, error) {
+	if m == nil {
+		return nil, errors.New("python: kernel manager is required")
+	}
+
+	cfg := functiontool.Config{
+		Name: ToolName,
+		Description: "Execute Python 3 code in a persistent interpreter. Variables, imports, and functions persist " +
+			"across calls. Returns stdout, stderr, the repr of the last expression as result, and the traceback as error. " +
+			"Use print for intermediate values.",
+	}
+	t, err := functiontool.New(cfg, func(ctx tool.Context, args executeArgs) (*Result, error) {
+		if strings.TrimSpace(args.Code) == "" {
+			return nil, errors.New("code is required")
+		}
+		return m.Execute(ctx, KernelKey(ctx), args.Code)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build %s tool: %w", ToolName, err)
+	}
+
+	return &Toolset{manager: m, tools: []tool.Tool{t}}, nil
+}
+
+// Name implements [tool.Toolset].
+func (ts *Toolset) Name() string { return "python" }
+
+// Tools implements [tool.Toolset].
+func (ts *Toolset) Tools(agent.ReadonlyContext) ([]tool.Tool, error) {
+	return ts.tools, nil
+}
+
+// Close stops every kernel started by the toolset.
+func (ts *Toolset) Close() error {
+	return ts.manager.Close()
+}
+
+// KernelKey returns the kernel key of the agent calling a tool: one kernel per session, invocation, and agent.
+func KernelKey(ctx agent.ReadonlyContext) string {
+	return ctx.SessionID() + "/" + ctx.InvocationID() + "/" + ctx.AgentName()
+}
@@ -0,0 +1,228 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// boilerplateRe matches class or id values of page chrome that carries no article content.
+var boilerplateRe = regexp.MustCompile(`(?i)(^|[\s_-])(nav|navbar|menu|footer|sidebar|cookie|banner|advert|ads|comments?|share|social|breadcrumbs?|popup|modal|subscribe|newsletter|related)($|[\s_-])`)
+
+// skippedElements never contain readable content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+}
+
+// blockElements start a new line in the extracted text.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Tr: true, atom.Pre: true, atom.Blockquote: true, atom.Br: true,
+	atom.Figcaption: true, atom.Hr: true,
+}
+
+// linkDenseElements are dropped when most of their text is link text, as is typical of menus and link farms.
+var linkDenseElements = map[atom.Atom]bool{
+	atom.Ul: true, atom.Ol: true, atom.Div: true, atom.Table: true, atom.Section: true,
+}
+
+// maxLinkDensity is the share of link text above which a link-dense element is treated as boilerplate.
+const maxLinkDensity = 0.6
+
+// extractReadable parses an HTML document and returns its title and main readable text.
+//
+// The content root is the first <article>, <main>, or role="main" element, falling back to <body>. Page chrome
+// (navigation, headers, footers, scripts, forms, link lists) is removed and whitespace is normalized.
+func extractReadable(r io.Reader) (title, text string, err error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", fmt.Errorf("parse html: %w", err)
+	}
+
+	if n := findFirst(doc, func(n *html.Node) bool { return n.DataAtom == atom.Title }); n != nil {
+		title = collapseSpaces(textContent(n))
+	}
+
+	root := findFirst(doc, func(n *html.Node) bool {
+		return n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main"
+	})
+	if root == nil {
+		root = findFirst(doc, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+	}
+	if root == nil {
+		root = doc
+	}
+
+	var b strings.Builder
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		writeReadable(&b, c, false)
+	}
+
+	return title, normalizeLines(b.String()), nil
+}
+
+// writeReadable appends the readable text of n to b.
+//
+// Line breaks in text are kept only inside <pre>; elsewhere they are ordinary whitespace.
+func writeReadable(b *strings.Builder, n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			b.WriteString(n.Data)
+		} else {
+			b.WriteString(strings.ReplaceAll(n.Data, "\n", " "))
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if isBoilerplate(n) {
+		return
+	}
+
+	block := blockElements[n.DataAtom]
+	if block {
+		b.WriteByte('\n')
+	}
+	if n.DataAtom == atom.Li {
+		b.WriteString("- ")
+	}
+	pre = pre || n.DataAtom == atom.Pre
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeReadable(b, c, pre)
+	}
+	if block {
+		b.WriteByte('\n')
+	}
+}
+
+// isBoilerplate reports whether n should be dropped from the readable text.
+func isBoilerplate(n *html.Node) bool {
+	if skippedElements[n.DataAtom] {
+		return true
+	}
+	if _, hidden := attrOK(n, "hidden"); hidden || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	if boilerplateRe.MatchString(attr(n, "class")) || boilerplateRe.MatchString(attr(n, "id")) {
+		return true
+	}
+	if linkDenseElements[n.DataAtom] {
+		total, links := textLengths(n, false)
+		if total > 0 && float64(links)/float64(total) > maxLinkDensity {
+			return true
+		}
+	}
+	return false
+}
+
+// textLengths returns the length of all non-space text below n and the part of it inside links.
+func textLengths(n *html.Node, inLink bool) (total, links int) {
+	if n.Type == html.TextNode {
+		l := len(strings.Join(strings.Fields(n.Data), ""))
+		if inLink {
+			return l, l
+		}
+		return l, 0
+	}
+	inLink = inLink || n.DataAtom == atom.A
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t, l := textLengths(c, inLink)
+		total += t
+		links += l
+	}
+	return total, links
+}
+
+// findFirst returns the first node below n, in document order, satisfying match.
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent returns the concatenated text of every text node below n.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := attrOK(n, key)
+	return v
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// collapseSpaces replaces runs of whitespace with a single space.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizeLines collapses whitespace within each line and drops empty lines.
+func normalizeLines(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		if line = collapseSpaces(line); line != "" && line != "-" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
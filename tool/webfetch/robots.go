@@ -0,0 +1,146 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// robotsRule is a single Allow or Disallow line of a robots.txt group.
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsRules holds the rules of the robots.txt groups that apply to one user agent.
+//
+// A nil *robotsRules allows everything.
+type robotsRules struct {
+	rules []robotsRule
+}
+
+var (
+	allowAll    = &robotsRules{}
+	disallowAll = &robotsRules{rules: []robotsRule{{allow: false, pattern: "/", re: regexp.MustCompile(`^/`)}}}
+)
+
+// parseRobots parses a robots.txt body following RFC 9309 and keeps the rules that apply to product.
+//
+// Groups naming product (case-insensitively) take precedence over the "*" group, even when they have no rules.
+func parseRobots(data []byte, product string) *robotsRules {
+	product = strings.ToLower(product)
+
+	var (
+		specific, wildcard []robotsRule
+		foundSpecific      bool
+		matchSpecific      bool
+		matchWildcard      bool
+		inAgents           bool
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				matchSpecific, matchWildcard = false, false
+				inAgents = true
+			}
+			agent := strings.ToLower(value)
+			switch {
+			case agent == "*":
+				matchWildcard = true
+			case agent != "" && strings.HasPrefix(product, agent):
+				matchSpecific, foundSpecific = true, true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value, re: compileRobotsPattern(value)}
+			if matchSpecific {
+				specific = append(specific, rule)
+			}
+			if matchWildcard {
+				wildcard = append(wildcard, rule)
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	if foundSpecific {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// compileRobotsPattern converts a robots.txt path pattern into an anchored regexp.
+//
+// '*' matches any sequence of characters and a trailing '$' anchors the end of the path.
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	var b strings.Builder
+	b.WriteByte('^')
+	for i, part := range strings.Split(pattern, "*") {
+		if i > 0 {
+			b.WriteString(".*")
+		}
+		b.WriteString(regexp.QuoteMeta(part))
+	}
+	if anchored {
+		b.WriteByte('$')
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// allowed reports whether path (including any query string) may be fetched.
+//
+// The longest matching pattern wins; on a tie Allow wins.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil || path == "/robots.txt" {
+		return true
+	}
+
+	allow, best := true, -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		switch n := len(rule.pattern); {
+		case n > best:
+			allow, best = rule.allow, n
+		case n == best && rule.allow:
+			allow = true
+		}
+	}
+	return allow
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ToolName is the function name under which the fetch tool is exposed to models.
+const ToolName = "web_fetch"
+
+// defaultMaxChars bounds the page text returned to the model when the call does not ask for a limit.
+const defaultMaxChars = 8000
+
+type fetchArgs struct {
+	URL      string `json:"url"`
+	MaxChars int    `json:"max_chars,omitzero"`
+}
+
+type fetchResult struct {
+	Output string `json:"output"`
+}
+
+// Toolset exposes a [Fetcher] as the "web_fetch" ADK function tool.
+type Toolset struct {
+	tools []tool.Tool
+}
+
+var _ tool.Toolset = (*Toolset)(nil)
+
+// NewToolset returns a Toolset fetching pages with f.
+func NewToolset(f *Fetcher) (*Toolset, error) {
+	if f == nil {
+		return nil, errors.New("webfetch: fetcher is required")
+	}
+
+	cfg := functiontool.Config{
+		Name: ToolName,
+		Description: "Fetch a web page and return its readable text between <information> and </information>. " +
+			"Use it to read pages found by search instead of relying on snippets. " +
+			"Optionally set max_chars to bound the returned text (default 8000).",
+	}
+	t, err := functiontool.New(cfg, func(ctx tool.Context, args fetchArgs) (fetchResult, error) {
+		if strings.TrimSpace(args.URL) == "" {
+			return fetchResult{}, errors.New("url is required")
+		}
+		page, err := f.Fetch(ctx, strings.TrimSpace(args.URL))
+		if err != nil {
+			return fetchResult{}, err
+		}
+		return fetchResult{Output: FormatInformation(page, args.MaxChars)}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build %s tool: %w", ToolName, err)
+	}
+
+	return &Toolset{tools: []tool.Tool{t}}, nil
+}
+
+// Name implements [tool.Toolset].
+func (ts *Toolset) Name() string { return "webfetch" }
+
+// Tools implements [tool.Toolset].
+func (ts *Toolset) Tools(agent.ReadonlyContext) ([]tool.Tool, error) {
+	return ts.tools, nil
+}
+
+// FormatInformation renders page inside an <information> block, the format search agents are prompted to read.
+//
+// The text is cut at maxChars runes; a non-positive maxChars selects the default of 8000.
+func FormatInformation(page *Page, maxChars int) string {
+	if maxChars <= 0 {
+		maxChars = defaultMaxChars
+	}
+
+	text, truncated := page.Text, page.Truncated
+	if r := []rune(text); len(r) > maxChars {
+		text, truncated = string(r[:maxChars]), true
+	}
+
+	var b strings.Builder
+	b.WriteString("<information>\n")
+	fmt.Fprintf(&b, "URL: %s\n", page.URL)
+	if page.Title != "" {
+		fmt.Fprintf(&b, "Title: %s\n", page.Title)
+	}
+	b.WriteString("\n")
+	b.WriteString(text)
+	if truncated {
+		b.WriteString("\n[truncated]")
+	}
+	b.WriteString("\n</information>")
+	return b.String()
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webfetch fetches web pages politely and extracts their readable text for search agents.
+package webfetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUserAgent identifies the fetcher to servers and selects its robots.txt group.
+	DefaultUserAgent = "tumix-webfetch/1.0 (+https://github.com/zchee/tumix)"
+	// DefaultTimeout bounds a single fetch, including the robots.txt lookup.
+	DefaultTimeout = 15 * time.Second
+	// DefaultMaxBytes caps the number of body bytes read from a page.
+	DefaultMaxBytes int64 = 2 << 20
+
+	// maxRobotsBytes caps the robots.txt body; RFC 9309 requires parsing at least 500 KiB.
+	maxRobotsBytes = 512 << 10
+	// maxRedirects matches the net/http default redirect limit.
+	maxRedirects = 10
+)
+
+// ErrDisallowed is returned when robots.txt forbids fetching a URL.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// Page is the readable content of a fetched URL.
+type Page struct {
+	// URL is the final URL after redirects.
+	URL string
+	// Title is the document title, if any.
+	Title string
+	// Text is the extracted readable text.
+	Text string
+	// Truncated reports whether the body exceeded the size limit and was cut.
+	Truncated bool
+}
+
+// Fetcher fetches pages over HTTP, honoring robots.txt.
+//
+// robots.txt files are cached per origin for the lifetime of the Fetcher. A Fetcher is safe for concurrent use.
+type Fetcher struct {
+	client    *http.Client
+	userAgent string
+	timeout   time.Duration
+	maxBytes  int64
+
+	mu     sync.Mutex
+	robots map[string]*robotsRules
+}
+
+// Option configures a [Fetcher].
+type Option func(*Fetcher)
+
+// WithHTTPClient sets the HTTP client used for requests.
+//
+// Its transport should wrap one from [NewTransport]; any other transport lets fetched URLs reach internal addresses.
+func WithHTTPClient(c *http.Client) Option {
+	return func(f *Fetcher) {
+		if c != nil {
+			f.client = c
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent header. Its product token also selects the robots.txt group.
+func WithUserAgent(ua string) Option {
+	return func(f *Fetcher) {
+		if ua != "" {
+			f.userAgent = ua
+		}
+	}
+}
+
+// WithTimeout sets the per-fetch timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(f *Fetcher) {
+		if d > 0 {
+			f.timeout = d
+		}
+	}
+}
+
+// WithMaxBytes sets the maximum number of body bytes read from a page.
+func WithMaxBytes(n int64) Option {
+	return func(f *Fetcher) {
+		if n > 0 {
+			f.maxBytes = n
+		}
+	}
+}
+
+// New returns a Fetcher configured by opts.
+func New(opts ...Option) *Fetcher {
+	f := &Fetcher{
+		client:    &http.Client{Transport: NewTransport()},
+		userAgent: DefaultUserAgent,
+		timeout:   DefaultTimeout,
+		maxBytes:  DefaultMaxBytes,
+		robots:    make(map[string]*robotsRules),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Fetch retrieves rawURL and extracts its readable text.
+//
+// Only http and https URLs are accepted. Every hop of a redirect chain is checked against robots.txt, and the default
+// transport refuses to connect to internal addresses (see [NewTransport]).
+// HTML is reduced to its main content; other text types are returned as is.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("url %q has no host", rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	if err := f.checkRobots(ctx, u); err != nil {
+		return nil, err
+	}
+
+	client := *f.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return f.checkRobots(req.Context(), req.URL)
+	}
+
+	resp, err := f.get(ctx, &client, u.String(), "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.1")
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: unexpected status %s", u, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	page := &Page{URL: resp.Request.URL.String()}
+	if int64(len(body)) > f.maxBytes {
+		body = body[:f.maxBytes]
+		page.Truncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.Title, page.Text, err = extractReadable(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", u, err)
+		}
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		page.Text = strings.TrimSpace(string(body))
+	default:
+		return nil, fmt.Errorf("fetch %s: unsupported content type %q", u, mediaType)
+	}
+
+	return page, nil
+}
+
+// checkRobots returns [ErrDisallowed] if the robots.txt of u's origin forbids fetching u.
+func (f *Fetcher) checkRobots(ctx context.Context, u *url.URL) error {
+	rules, err := f.robotsFor(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !rules.allowed(path) {
+		return fmt.Errorf("fetch %s: %w", u, ErrDisallowed)
+	}
+	return nil
+}
+
+// robotsFor returns the cached robots.txt rules of u's origin, fetching them on first use.
+//
+// Following RFC 9309, a missing robots.txt (4xx) allows everything and an unreachable one (5xx) disallows everything.
+func (f *Fetcher) robotsFor(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	origin := u.Scheme + "://" + u.Host
+
+	f.mu.Lock()
+	rules, ok := f.robots[origin]
+	f.mu.Unlock()
+	if ok {
+		return rules, nil
+	}
+
+	resp, err := f.get(ctx, f.client, origin+"/robots.txt", "text/plain")
+	if err != nil {
+		return nil, fmt.Errorf("fetch robots.txt of %s: %w", origin, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+		if err != nil {
+			return nil, fmt.Errorf("read robots.txt of %s: %w", origin, err)
+		}
+		rules = parseRobots(data, productToken(f.userAgent))
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		rules = allowAll
+	default:
+		rules = disallowAll
+	}
+
+	f.mu.Lock()
+	f.robots[origin] = rules
+	f.mu.Unlock()
+
+	return rules, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, client *http.Client, rawURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", accept)
+
+	return client.Do(req)
+}
+
+// productToken returns the product name of a User-Agent string, e.g. "tumix-webfetch" for "tumix-webfetch/1.0 (...)".
+func productToken(ua string) string {
+	token, _, _ := strings.Cut(ua, "/")
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
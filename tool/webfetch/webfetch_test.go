@@ -0,0 +1,346 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+
+	"github.com/zchee/tumix/agent/agenttest"
+)
+
+const articleHTML = `<!doctype html>
+<html><head><title> Go 1.26 Released </title><script>var x = 1;</script></head>
+<body>
+<header><a href="/">Home</a></header>
+<nav><ul><li><a href="/a">A</a></li><li><a href="/b">B</a></li></ul></nav>
+<div class="cookie-banner">We use cookies.</div>
+<article>
+  <h1>Go 1.26 is released</h1>
+  <p>The Go team is   happy to announce
+  Go 1.26.</p>
+  <ul><li>Faster GC</li><li>New <code>iter</code> helpers</li></ul>
+  <div class="share-links"><a href="/x">Share on X</a></div>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, articleHTML)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "  plain body\n")
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, strings.Repeat("a", 64))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "\x89PNG")
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/private/page", http.StatusFound)
+	})
+	mux.HandleFunc("/private/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "secret")
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestFetcherFetch(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	t.Cleanup(srv.Close)
+
+	tests := map[string]struct {
+		path           string
+		maxBytes       int64
+		want           *Page
+		wantDisallowed bool
+		wantErr        bool
+	}{
+		"success: readable html": {
+			path: "/article",
+			want: &Page{
+				URL:   srv.URL + "/article",
+				Title: "Go 1.26 Released",
+				Text:  "Go 1.26 is released\nThe Go team is happy to announce Go 1.26.\n- Faster GC\n- New iter helpers",
+			},
+		},
+		"success: plain text": {
+			path: "/plain",
+			want: &Page{URL: srv.URL + "/plain", Text: "plain body"},
+		},
+		"success: truncated body": {
+			path:     "/big",
+			maxBytes: 32,
+			want:     &Page{URL: srv.URL + "/big", Text: strings.Repeat("a", 32), Truncated: true},
+		},
+		"error: disallowed by robots": {
+			path:           "/private/page",
+			wantDisallowed: true,
+			wantErr:        true,
+		},
+		"error: redirect into disallowed path": {
+			path:           "/moved",
+			wantDisallowed: true,
+			wantErr:        true,
+		},
+		"error: unsupported content type": {
+			path:    "/image",
+			wantErr: true,
+		},
+		"error: not found": {
+			path:    "/missing",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := New(WithHTTPClient(srv.Client()), WithMaxBytes(tt.maxBytes))
+			got, err := f.Fetch(t.Context(), srv.URL+tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got, want := errors.Is(err, ErrDisallowed), tt.wantDisallowed; got != want {
+				t.Fatalf("errors.Is(err, ErrDisallowed) = %v, want %v (err = %v)", got, want, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("Fetch() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetcherFetchInvalidURL(t *testing.T) {
+	t.Parallel()
+
+	f := New()
+	for _, rawURL := range []string{"ftp://example.com/file", "http://", "::"} {
+		if _, err := f.Fetch(t.Context(), rawURL); err == nil {
+			t.Fatalf("Fetch(%q) error = nil, want error", rawURL)
+		}
+	}
+}
+
+func TestFetcherBlocksInternalAddresses(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		fmt.Fprint(w, "internal")
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New()
+	for _, rawURL := range []string{srv.URL + "/plain", "http://localhost:" + port + "/plain"} {
+		if _, err := f.Fetch(t.Context(), rawURL); !errors.Is(err, ErrBlockedAddress) {
+			t.Fatalf("Fetch(%q) error = %v, want %v", rawURL, err, ErrBlockedAddress)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("server got %d requests, want 0", n)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		addr string
+		want bool
+	}{
+		"public IPv4":           {addr: "93.184.216.34", want: true},
+		"public IPv6":           {addr: "2606:4700::6810:84e5", want: true},
+		"loopback":              {addr: "127.0.0.1", want: false},
+		"IPv6 loopback":         {addr: "::1", want: false},
+		"private":               {addr: "10.1.2.3", want: false},
+		"IPv6 unique local":     {addr: "fd00::1", want: false},
+		"link-local metadata":   {addr: "169.254.169.254", want: false},
+		"IPv6 link-local":       {addr: "fe80::1", want: false},
+		"unspecified":           {addr: "0.0.0.0", want: false},
+		"this network":          {addr: "0.1.2.3", want: false},
+		"carrier-grade NAT":     {addr: "100.100.100.200", want: false},
+		"IPv4-mapped loopback":  {addr: "::ffff:127.0.0.1", want: false},
+		"NAT64 private address": {addr: "64:ff9b::a00:1", want: false},
+		"broadcast":             {addr: "255.255.255.255", want: false},
+		"multicast":             {addr: "224.0.0.1", want: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Fatalf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	t.Parallel()
+
+	const robots = `# comment
+User-agent: tumix-webfetch
+Disallow: /tmp/
+Allow: /tmp/public
+Disallow: /*.pdf$
+
+User-agent: *
+Disallow: /
+`
+
+	tests := map[string]struct {
+		product string
+		path    string
+		want    bool
+	}{
+		"specific group: allowed by default": {product: "tumix-webfetch", path: "/docs", want: true},
+		"specific group: disallowed prefix":  {product: "tumix-webfetch", path: "/tmp/x", want: false},
+		"specific group: longer allow wins":  {product: "tumix-webfetch", path: "/tmp/public/a", want: true},
+		"specific group: anchored wildcard":  {product: "tumix-webfetch", path: "/a/b.pdf", want: false},
+		"specific group: anchor not matched": {product: "tumix-webfetch", path: "/a/b.pdf?x=1", want: true},
+		"specific group: robots.txt":         {product: "tumix-webfetch", path: "/robots.txt", want: true},
+		"wildcard group: disallow all":       {product: "otherbot", path: "/docs", want: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseRobots([]byte(robots), tt.product).allowed(tt.path); got != tt.want {
+				t.Fatalf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolset(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	ts, err := NewToolset(New(WithHTTPClient(srv.Client())))
+	if err != nil {
+		t.Fatalf("NewToolset() error = %v", err)
+	}
+	tools, err := ts.Tools(nil)
+	if err != nil {
+		t.Fatalf("Tools() error = %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name() != ToolName {
+		t.Fatalf("tools = %v, want [%s]", tools, ToolName)
+	}
+
+	type runnableTool interface {
+		Run(ctx tool.Context, args any) (map[string]any, error)
+	}
+	rt, ok := tools[0].(runnableTool)
+	if !ok {
+		t.Fatalf("tool %T is not runnable", tools[0])
+	}
+	ctx := agenttest.NewToolContext(t.Context(), agenttest.NewInMemoryState(nil), &session.EventActions{}, "call-1", nil)
+	got, err := rt.Run(ctx, map[string]any{"url": srv.URL + "/plain"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := map[string]any{"output": "<information>\nURL: " + srv.URL + "/plain\n\nplain body\n</information>"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Run() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFormatInformation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		page     *Page
+		maxChars int
+		want     string
+	}{
+		"success: with title": {
+			page: &Page{URL: "https://example.com", Title: "Example", Text: "body"},
+			want: "<information>\nURL: https://example.com\nTitle: Example\n\nbody\n</information>",
+		},
+		"success: cut at max chars": {
+			page:     &Page{URL: "https://example.com", Text: "héllo world"},
+			maxChars: 5,
+			want:     "<information>\nURL: https://example.com\n\nhéllo\n[truncated]\n</information>",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, FormatInformation(tt.page, tt.maxChars)); diff != "" {
+				t.Fatalf("FormatInformation() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRobotsEmptyGroup(t *testing.T) {
+	t.Parallel()
+
+	// RFC 9309: a group matching the product applies even when it has no rules, so the "*" group does not.
+	const robots = `User-agent: tumix-webfetch
+Disallow:
+
+User-agent: *
+Disallow: /
+`
+	if !parseRobots([]byte(robots), "tumix-webfetch").allowed("/docs") {
+		t.Fatal("allowed(/docs) = false, want true")
+	}
+	if parseRobots([]byte(robots), "otherbot").allowed("/docs") {
+		t.Fatal("allowed(/docs) for otherbot = true, want false")
+	}
+}
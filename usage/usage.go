@@ -0,0 +1,147 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package usage collects the token usage and cost of the model calls of a run in one place.
+//
+// Every model call is reported once to a [Collector], which prices it, adds it to its [Totals], and passes it on to
+// its subscribers. The quota, the cost estimates, the metrics, and the reported results all derive their numbers
+// from the same calls, so they cannot drift apart.
+package usage
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/adk/session"
+)
+
+// Call is the usage of one model call.
+type Call struct {
+	// Author is the agent that made the call, and Judge reports that it is the Judge of the Collector.
+	Author string
+	Judge  bool
+
+	InputTokens  int64
+	OutputTokens int64
+	// TotalTokens is the total the model reported, which can count thinking and tool use tokens beyond InputTokens
+	// and OutputTokens.
+	TotalTokens int64
+
+	// CostUSD is the cost of the call priced by the Collector.
+	CostUSD float64
+}
+
+// FromEvent returns the call whose usage event reports, or false if it reports none.
+func FromEvent(event *session.Event) (Call, bool) {
+	if event == nil || event.UsageMetadata == nil {
+		return Call{}, false
+	}
+	u := event.UsageMetadata
+	return Call{
+		Author:       event.Author,
+		InputTokens:  int64(u.PromptTokenCount),
+		OutputTokens: int64(u.CandidatesTokenCount),
+		TotalTokens:  int64(u.TotalTokenCount),
+	}, true
+}
+
+// Totals is the usage of the calls reported to a [Collector]. The Judge fields are the part of the totals spent by
+// the Judge.
+type Totals struct {
+	Requests          int64   `json:"requests"`
+	InputTokens       int64   `json:"input_tokens"`
+	OutputTokens      int64   `json:"output_tokens"`
+	JudgeInputTokens  int64   `json:"judge_input_tokens"`
+	JudgeOutputTokens int64   `json:"judge_output_tokens"`
+	CostUSD           float64 `json:"cost_usd"`
+	JudgeCostUSD      float64 `json:"judge_cost_usd"`
+}
+
+// Tokens returns the input plus output tokens of t.
+func (t Totals) Tokens() int64 {
+	return t.InputTokens + t.OutputTokens
+}
+
+// add adds c to t.
+func (t *Totals) add(c Call) {
+	t.Requests++
+	t.InputTokens += c.InputTokens
+	t.OutputTokens += c.OutputTokens
+	t.CostUSD += c.CostUSD
+	if c.Judge {
+		t.JudgeInputTokens += c.InputTokens
+		t.JudgeOutputTokens += c.OutputTokens
+		t.JudgeCostUSD += c.CostUSD
+	}
+}
+
+// Pricer returns the USD cost of a call.
+type Pricer func(Call) float64
+
+// Collector accumulates the usage of the model calls of a run. It is safe for concurrent use.
+type Collector struct {
+	judge string
+	price Pricer
+
+	mu     sync.Mutex
+	totals Totals
+	subs   []func(context.Context, Call)
+}
+
+// NewCollector returns a collector attributing the calls of the agent named judge to the Judge and pricing every
+// call with price. A nil price leaves the costs zero.
+func NewCollector(judge string, price Pricer) *Collector {
+	return &Collector{judge: judge, price: price}
+}
+
+// Subscribe registers fn to be called with every call reported after it, priced, in the order they are reported.
+func (c *Collector) Subscribe(fn func(context.Context, Call)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs = append(c.subs, fn)
+}
+
+// Add prices call, adds it to the totals, passes it to the subscribers, and returns it priced.
+func (c *Collector) Add(ctx context.Context, call Call) Call {
+	call.Judge = call.Judge || (c.judge != "" && call.Author == c.judge)
+	if c.price != nil {
+		call.CostUSD = c.price(call)
+	}
+	c.mu.Lock()
+	c.totals.add(call)
+	subs := c.subs
+	c.mu.Unlock()
+	for _, fn := range subs {
+		fn(ctx, call)
+	}
+	return call
+}
+
+// AddEvent adds the call whose usage event reports, if any; see [FromEvent].
+func (c *Collector) AddEvent(ctx context.Context, event *session.Event) (Call, bool) {
+	call, ok := FromEvent(event)
+	if !ok {
+		return Call{}, false
+	}
+	return c.Add(ctx, call), true
+}
+
+// Totals returns the usage of the calls added so far.
+func (c *Collector) Totals() Totals {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.totals
+}
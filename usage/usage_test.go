@@ -0,0 +1,157 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package usage
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func usageEvent(author string, in, out, total int32) *session.Event {
+	event := &session.Event{Author: author}
+	event.LLMResponse = model.LLMResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     in,
+			CandidatesTokenCount: out,
+			TotalTokenCount:      total,
+		},
+	}
+	return event
+}
+
+func TestFromEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		event  *session.Event
+		want   Call
+		wantOK bool
+	}{
+		"usage": {
+			event:  usageEvent("CoT", 10, 5, 20),
+			want:   Call{Author: "CoT", InputTokens: 10, OutputTokens: 5, TotalTokens: 20},
+			wantOK: true,
+		},
+		"no usage": {event: &session.Event{Author: "CoT"}},
+		"nil":      {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := FromEvent(tt.event)
+			if ok != tt.wantOK {
+				t.Fatalf("FromEvent() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("FromEvent() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	// Judge tokens cost ten times as much.
+	price := func(c Call) float64 {
+		cost := float64(c.InputTokens+c.OutputTokens) / 1000
+		if c.Judge {
+			cost *= 10
+		}
+		return cost
+	}
+	c := NewCollector("Judge", price)
+	var seen []Call
+	c.Subscribe(func(_ context.Context, call Call) { seen = append(seen, call) })
+
+	c.AddEvent(t.Context(), usageEvent("CoT", 100, 50, 150))
+	c.AddEvent(t.Context(), &session.Event{Author: "CoT"})
+	c.AddEvent(t.Context(), usageEvent("Judge", 200, 10, 210))
+	c.Add(t.Context(), Call{Author: "Triage", Judge: true, InputTokens: 10})
+
+	wantSeen := []Call{
+		{Author: "CoT", InputTokens: 100, OutputTokens: 50, TotalTokens: 150, CostUSD: 0.15},
+		{Author: "Judge", Judge: true, InputTokens: 200, OutputTokens: 10, TotalTokens: 210, CostUSD: 2.1},
+		{Author: "Triage", Judge: true, InputTokens: 10, CostUSD: 0.1},
+	}
+	if diff := cmp.Diff(wantSeen, seen); diff != "" {
+		t.Fatalf("subscriber calls mismatch (-want +got):\n%s", diff)
+	}
+
+	// The totals are the sums of the calls the subscribers saw.
+	var want Totals
+	for _, call := range seen {
+		want.add(call)
+	}
+	if diff := cmp.Diff(want, c.Totals()); diff != "" {
+		t.Fatalf("Totals() mismatch (-want +got):\n%s", diff)
+	}
+	if got := c.Totals(); got.Requests != 3 || got.Tokens() != 370 || got.JudgeInputTokens != 210 || got.JudgeOutputTokens != 10 {
+		t.Fatalf("Totals() = %+v, want 3 requests of 370 tokens, 220 of them the Judge's", got)
+	}
+}
+
+func TestCollectorUnpriced(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollector("", nil)
+	if call, ok := c.AddEvent(t.Context(), usageEvent("Judge", 10, 5, 15)); !ok || call.Judge || call.CostUSD != 0 {
+		t.Fatalf("AddEvent() = %+v, %t, want an unpriced candidate call", call, ok)
+	}
+	if want := (Totals{Requests: 1, InputTokens: 10, OutputTokens: 5}); c.Totals() != want {
+		t.Fatalf("Totals() = %+v, want %+v", c.Totals(), want)
+	}
+}
+
+func TestCollectorConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollector("Judge", func(Call) float64 { return 0.5 })
+	var (
+		mu    sync.Mutex
+		total float64
+	)
+	c.Subscribe(func(_ context.Context, call Call) {
+		mu.Lock()
+		defer mu.Unlock()
+		total += call.CostUSD
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				c.AddEvent(t.Context(), usageEvent("CoT", 1, 1, 2))
+				_ = c.Totals()
+			}
+		})
+	}
+	wg.Wait()
+
+	got := c.Totals()
+	if got.Requests != 800 || got.Tokens() != 1600 || got.CostUSD != 400 || total != got.CostUSD {
+		t.Fatalf("Totals() = %+v with %v seen by the subscriber, want 800 requests costing 400", got, total)
+	}
+}
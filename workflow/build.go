@@ -0,0 +1,227 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/agent/workflowagents/loopagent"
+	"google.golang.org/adk/agent/workflowagents/parallelagent"
+	"google.golang.org/adk/agent/workflowagents/sequentialagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/exitlooptool"
+	"google.golang.org/genai"
+
+	tumixagent "github.com/zchee/tumix/agent"
+)
+
+// defaultName names the root agent of a spec without a name.
+const defaultName = "workflow"
+
+// Config holds the models the nodes of a workflow run on.
+type Config struct {
+	// Model runs the llmagent nodes.
+	Model model.LLM
+	// JudgeModel runs the judge nodes; nil uses Model.
+	JudgeModel model.LLM
+	// GenerateContentConfig is the generation config of every node.
+	GenerateContentConfig *genai.GenerateContentConfig
+	// AnswerProtocol is the final answer format judge nodes without an instruction ask for; nil means
+	// [tumixagent.DelimitedAnswers].
+	AnswerProtocol *tumixagent.AnswerProtocol
+}
+
+// Build builds the agent tree of s. The root agent stores the question of the run under [StateKeyQuestion] and runs
+// the root node.
+func (s *Spec) Build(cfg Config) (agent.Loader, error) {
+	if cfg.Model == nil {
+		return nil, errors.New("workflow model is required")
+	}
+	if cfg.JudgeModel == nil {
+		cfg.JudgeModel = cfg.Model
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	b := &builder{cfg: cfg, nodes: make(map[string]*Node, len(s.Nodes))}
+	for i := range s.Nodes {
+		b.nodes[s.Nodes[i].Name] = &s.Nodes[i]
+	}
+	root, err := b.build(s.RootName(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.Name
+	if name == "" {
+		name = defaultName
+	}
+	a, err := agent.New(agent.Config{
+		Name:        name,
+		Description: s.Description,
+		SubAgents:   []agent.Agent{root},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return run(ctx, root)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build workflow %s: %w", name, err)
+	}
+
+	return agent.NewSingleLoader(a), nil
+}
+
+func run(ctx agent.InvocationContext, root agent.Agent) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		if err := ctx.Session().State().Set(StateKeyQuestion, contentText(ctx.UserContent())); err != nil {
+			yield(nil, fmt.Errorf("set state %s: %w", StateKeyQuestion, err))
+			return
+		}
+		for event, err := range root.Run(ctx) {
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+type builder struct {
+	cfg   Config
+	nodes map[string]*Node
+}
+
+// build builds the agent of the node name; inLoop reports whether a loop node encloses it.
+func (b *builder) build(name string, inLoop bool) (agent.Agent, error) {
+	n := b.nodes[name]
+	if n.isLeaf() {
+		return b.buildLeaf(n, inLoop)
+	}
+
+	children := make([]agent.Agent, 0, len(n.Children))
+	for _, child := range n.Children {
+		a, err := b.build(child, inLoop || n.Type == NodeLoop)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, a)
+	}
+	agentCfg := agent.Config{
+		Name:        n.Name,
+		Description: n.Description,
+		SubAgents:   children,
+	}
+
+	var (
+		a   agent.Agent
+		err error
+	)
+	switch n.Type {
+	case NodeParallel:
+		a, err = parallelagent.New(parallelagent.Config{AgentConfig: agentCfg})
+	case NodeSequential:
+		a, err = sequentialagent.New(sequentialagent.Config{AgentConfig: agentCfg})
+	case NodeLoop:
+		a, err = loopagent.New(loopagent.Config{AgentConfig: agentCfg, MaxIterations: n.MaxIterations})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("build %s node %s: %w", n.Type, n.Name, err)
+	}
+	return a, nil
+}
+
+func (b *builder) buildLeaf(n *Node, inLoop bool) (agent.Agent, error) {
+	cfg := llmagent.Config{
+		Name:                  n.Name,
+		Description:           n.Description,
+		Model:                 b.cfg.Model,
+		GenerateContentConfig: cloneGenConfig(b.cfg.GenerateContentConfig),
+		Instruction:           n.Instruction,
+		OutputKey:             n.Output,
+	}
+	if n.Type == NodeJudge {
+		cfg.Model = b.cfg.JudgeModel
+		if cfg.Instruction == "" {
+			cfg.Instruction = judgeInstruction(n.Inputs, inLoop, b.cfg.AnswerProtocol)
+		}
+		if inLoop {
+			exitLoop, err := exitlooptool.New()
+			if err != nil {
+				return nil, fmt.Errorf("build judge node %s: %w", n.Name, err)
+			}
+			cfg.Tools = []tool.Tool{exitLoop}
+		}
+	}
+
+	a, err := llmagent.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build %s node %s: %w", n.Type, n.Name, err)
+	}
+	return a, nil
+}
+
+// judgeInstruction is the instruction of a judge node without one, listing the state of every input and asking for
+// the answer in protocol p.
+func judgeInstruction(inputs []string, inLoop bool, p *tumixagent.AnswerProtocol) string {
+	var sb strings.Builder
+	sb.WriteString(`Task: Weigh the contributions below and decide the answer to the question. Build on the strongest
+reasoning, point out where the contributions disagree, and do not start over.
+
+Question:
+{question}
+`)
+	for _, in := range inputs {
+		if in == StateKeyQuestion {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s:\n{%s}\n", in, in)
+	}
+	if inLoop {
+		sb.WriteString("\nWhen the contributions agree and the answer is settled, call exit_loop. Otherwise say what the next iteration should address.\n")
+	}
+	sb.WriteString("\nEnd with the answer " + p.Enclosure() + ".")
+	return sb.String()
+}
+
+func cloneGenConfig(cfg *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	if cfg == nil {
+		return nil
+	}
+	copied := *cfg
+	return &copied
+}
+
+// contentText returns the text parts of c joined by newlines.
+func contentText(c *genai.Content) string {
+	if c == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range c.Parts {
+		if part != nil && part.Text != "" && !part.Thought {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package workflow
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// scriptedLLM answers every request with reply, or "ok", and records the system instructions it was sent.
+type scriptedLLM struct {
+	reply func(instruction string) *genai.Content
+
+	mu           sync.Mutex
+	instructions []string
+}
+
+func (m *scriptedLLM) Name() string { return "scripted" }
+
+func (m *scriptedLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	var instruction string
+	if req.Config != nil && req.Config.SystemInstruction != nil {
+		instruction = contentText(req.Config.SystemInstruction)
+	}
+	m.mu.Lock()
+	m.instructions = append(m.instructions, instruction)
+	m.mu.Unlock()
+
+	content := genai.NewContentFromText("ok", genai.RoleModel)
+	if m.reply != nil {
+		content = m.reply(instruction)
+	}
+	return func(yield func(*model.LLMResponse, error) bool) {
+		yield(&model.LLMResponse{Content: content}, nil)
+	}
+}
+
+func TestBuildRun(t *testing.T) {
+	t.Parallel()
+
+	spec, err := Parse([]byte(`
+name: refine
+nodes:
+  - {name: main, type: sequential, children: [draft, loop]}
+  - {name: draft, type: llmagent, output: answer, instruction: "Draft Q={question}"}
+  - {name: loop, type: loop, max_iterations: 3, children: [review, rewrite]}
+  - {name: review, type: judge, inputs: [answer], output: critique}
+  - {name: rewrite, type: llmagent, inputs: [answer, critique], output: answer, instruction: "Rewrite {answer} per {critique}"}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	llm := &scriptedLLM{reply: func(string) *genai.Content {
+		return genai.NewContentFromText("draft answer", genai.RoleModel)
+	}}
+	judge := &scriptedLLM{reply: func(string) *genai.Content {
+		return &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{genai.NewPartFromFunctionCall("exit_loop", map[string]any{})}}
+	}}
+	loader, err := spec.Build(Config{Model: llm, JudgeModel: judge})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	ctx := t.Context()
+	svc := session.InMemoryService()
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "app", Agent: loader.RootAgent(), SessionService: svc})
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	for _, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("2+2?", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+
+	// The judge exits the loop on its first turn, so the rewrite never runs.
+	if diff := cmp.Diff([]string{"Draft Q=2+2?"}, llm.instructions); diff != "" {
+		t.Fatalf("model instructions mismatch (-want +got):\n%s", diff)
+	}
+	if len(judge.instructions) != 1 || !strings.Contains(judge.instructions[0], "answer:\ndraft answer") {
+		t.Fatalf("judge instructions = %q, want one with the draft answer", judge.instructions)
+	}
+
+	res, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "u", SessionID: "s"})
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	answer, err := res.Session.State().Get("answer")
+	if err != nil || answer != "draft answer" {
+		t.Fatalf("state answer = %v (err %v), want draft answer", answer, err)
+	}
+}
+
+func TestBuildRequiresModel(t *testing.T) {
+	t.Parallel()
+
+	spec := &Spec{Nodes: []Node{{Name: "a", Type: NodeLLMAgent, Instruction: "x"}}}
+	if _, err := spec.Build(Config{}); err == nil {
+		t.Fatal("Build() without a model succeeded")
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package workflow builds ADK agent trees from declarative YAML specs, for orchestrations other than TUMIX such as
+// single-agent self-refinement, debate, or round-robin critique.
+//
+// A spec lists named nodes. Leaf nodes are "llmagent" and "judge" agents; "parallel", "sequential", and "loop"
+// nodes run their children. Nodes exchange data through session state: a leaf writes its reply to its output key,
+// and the nodes declaring that key as an input read it through {key} placeholders in their instructions. The
+// question of the run is always available as {question}.
+//
+//	name: debate
+//	root: main
+//	nodes:
+//	  - {name: pro, type: llmagent, output: pro_case, instruction: "Argue for the best answer to {question}."}
+//	  - {name: con, type: llmagent, output: con_case, instruction: "Argue against the obvious answer to {question}."}
+//	  - {name: sides, type: parallel, children: [pro, con]}
+//	  - {name: verdict, type: judge, inputs: [pro_case, con_case]}
+//	  - {name: main, type: sequential, children: [sides, verdict]}
+package workflow
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// NodeType is the kind of a workflow node.
+type NodeType string
+
+const (
+	// NodeLLMAgent is a model call with an instruction, running on the default model.
+	NodeLLMAgent NodeType = "llmagent"
+	// NodeJudge is a model call on the judge model that weighs its inputs and gives the answer. Inside a loop it may
+	// call exit_loop to end the loop.
+	NodeJudge NodeType = "judge"
+	// NodeParallel runs its children concurrently.
+	NodeParallel NodeType = "parallel"
+	// NodeSequential runs its children in order.
+	NodeSequential NodeType = "sequential"
+	// NodeLoop runs its children in order up to MaxIterations times, or until a judge exits the loop.
+	NodeLoop NodeType = "loop"
+)
+
+// StateKeyQuestion is the state key holding the text of the user question, readable by every node.
+const StateKeyQuestion = "question"
+
+// Spec is a declarative workflow.
+type Spec struct {
+	// Name names the root agent of the workflow.
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Root is the name of the node the workflow runs. It may be omitted when exactly one node has no parent.
+	Root  string `yaml:"root"`
+	Nodes []Node `yaml:"nodes"`
+}
+
+// Node is one agent of a workflow.
+type Node struct {
+	Name        string   `yaml:"name"`
+	Type        NodeType `yaml:"type"`
+	Description string   `yaml:"description"`
+
+	// Instruction is the system instruction of an llmagent or judge node. A judge without one compares its inputs.
+	Instruction string `yaml:"instruction"`
+	// Inputs are the state keys the node reads; every input must be the output of some node.
+	Inputs []string `yaml:"inputs"`
+	// Output is the state key the reply of an llmagent or judge node is stored under.
+	Output string `yaml:"output"`
+
+	// Children are the names of the nodes a parallel, sequential, or loop node runs.
+	Children []string `yaml:"children"`
+	// MaxIterations bounds a loop node; zero loops until a judge exits the loop.
+	MaxIterations uint `yaml:"max_iterations"`
+}
+
+// isLeaf reports whether n is a model call rather than a container of other nodes.
+func (n *Node) isLeaf() bool {
+	return n.Type == NodeLLMAgent || n.Type == NodeJudge
+}
+
+// Load reads and validates the workflow spec at path.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow: %w", err)
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("workflow %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Parse decodes a YAML (or JSON) workflow spec, rejecting unknown keys, and validates it.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.UnmarshalWithOptions(data, &spec, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("parse workflow: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// placeholderRE matches the {key} and {key?} state placeholders of an instruction. Prefixed keys such as {app:key}
+// and artifacts are left to ADK.
+var placeholderRE = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(\??)\}`)
+
+// Validate checks that s describes a single tree of known node types whose state edges are all connected: every
+// input is written by some node, and a {key} placeholder is only used where the key is already written when the
+// node first runs (use {key?} for keys written later, such as by a previous loop iteration).
+func (s *Spec) Validate() error {
+	if len(s.Nodes) == 0 {
+		return errors.New("workflow has no nodes")
+	}
+
+	nodes := make(map[string]*Node, len(s.Nodes))
+	outputs := make(map[string]bool)
+	for i := range s.Nodes {
+		n := &s.Nodes[i]
+		if err := n.validate(); err != nil {
+			return err
+		}
+		if _, dup := nodes[n.Name]; dup {
+			return fmt.Errorf("duplicate node %q", n.Name)
+		}
+		nodes[n.Name] = n
+		if n.Output != "" {
+			outputs[n.Output] = true
+		}
+	}
+
+	if _, ok := nodes[s.Name]; ok || (s.Name == "" && nodes[defaultName] != nil) {
+		return fmt.Errorf("node %q has the name of the workflow", cmp.Or(s.Name, defaultName))
+	}
+
+	parents := make(map[string]string, len(s.Nodes))
+	for _, n := range s.Nodes {
+		for _, child := range n.Children {
+			if _, ok := nodes[child]; !ok {
+				return fmt.Errorf("node %q: unknown child %q", n.Name, child)
+			}
+			if parent, ok := parents[child]; ok {
+				return fmt.Errorf("node %q is a child of both %q and %q", child, parent, n.Name)
+			}
+			parents[child] = n.Name
+		}
+		for _, in := range n.Inputs {
+			if in != StateKeyQuestion && !outputs[in] {
+				return fmt.Errorf("node %q: input %q is not the output of any node", n.Name, in)
+			}
+		}
+	}
+
+	root, err := s.root(parents)
+	if err != nil {
+		return err
+	}
+
+	// A single tree needs every node to be reachable from the root; with one parent per node that also rules out
+	// cycles.
+	reached := make(map[string]bool, len(s.Nodes))
+	var visit func(name string)
+	visit = func(name string) {
+		reached[name] = true
+		for _, child := range nodes[name].Children {
+			visit(child)
+		}
+	}
+	visit(root)
+	for _, n := range s.Nodes {
+		if !reached[n.Name] {
+			return fmt.Errorf("node %q is not reachable from root %q", n.Name, root)
+		}
+	}
+
+	_, err = checkReads(nodes, root, map[string]bool{StateKeyQuestion: true})
+	return err
+}
+
+// RootName returns the name of the node the workflow runs.
+func (s *Spec) RootName() string {
+	parents := make(map[string]string)
+	for _, n := range s.Nodes {
+		for _, child := range n.Children {
+			parents[child] = n.Name
+		}
+	}
+	root, _ := s.root(parents)
+	return root
+}
+
+func (s *Spec) root(parents map[string]string) (string, error) {
+	if s.Root != "" {
+		if !slices.ContainsFunc(s.Nodes, func(n Node) bool { return n.Name == s.Root }) {
+			return "", fmt.Errorf("unknown root %q", s.Root)
+		}
+		if parent, ok := parents[s.Root]; ok {
+			return "", fmt.Errorf("root %q is a child of %q", s.Root, parent)
+		}
+		return s.Root, nil
+	}
+
+	var tops []string
+	for _, n := range s.Nodes {
+		if _, ok := parents[n.Name]; !ok {
+			tops = append(tops, n.Name)
+		}
+	}
+	switch len(tops) {
+	case 0:
+		return "", errors.New("every node has a parent; the workflow has a cycle")
+	case 1:
+		return tops[0], nil
+	default:
+		return "", fmt.Errorf("root is required when several nodes have no parent: %s", strings.Join(tops, ", "))
+	}
+}
+
+func (n *Node) validate() error {
+	if n.Name == "" {
+		return errors.New("node name is required")
+	}
+	// ADK reserves "user" as the author of user events.
+	if n.Name == "user" {
+		return errors.New(`node name "user" is reserved`)
+	}
+
+	switch n.Type {
+	case NodeLLMAgent, NodeJudge:
+		if n.Type == NodeLLMAgent && strings.TrimSpace(n.Instruction) == "" {
+			return fmt.Errorf("node %q: llmagent requires an instruction", n.Name)
+		}
+		if n.Type == NodeJudge && n.Instruction == "" && len(n.Inputs) == 0 {
+			return fmt.Errorf("node %q: judge requires inputs or an instruction", n.Name)
+		}
+		if len(n.Children) > 0 {
+			return fmt.Errorf("node %q: %s cannot have children", n.Name, n.Type)
+		}
+		if n.MaxIterations > 0 {
+			return fmt.Errorf("node %q: max_iterations only applies to loop nodes", n.Name)
+		}
+		for _, m := range placeholderRE.FindAllStringSubmatch(n.Instruction, -1) {
+			if key := m[1]; key != StateKeyQuestion && !slices.Contains(n.Inputs, key) {
+				return fmt.Errorf("node %q: instruction reads {%s} but %q is not an input", n.Name, key, key)
+			}
+		}
+
+	case NodeParallel, NodeSequential, NodeLoop:
+		if len(n.Children) == 0 {
+			return fmt.Errorf("node %q: %s requires children", n.Name, n.Type)
+		}
+		if n.Instruction != "" || len(n.Inputs) > 0 || n.Output != "" {
+			return fmt.Errorf("node %q: %s cannot have an instruction, inputs, or output", n.Name, n.Type)
+		}
+		if n.Type != NodeLoop && n.MaxIterations > 0 {
+			return fmt.Errorf("node %q: max_iterations only applies to loop nodes", n.Name)
+		}
+
+	case "":
+		return fmt.Errorf("node %q: type is required", n.Name)
+	default:
+		return fmt.Errorf("node %q: unknown type %q", n.Name, n.Type)
+	}
+	return nil
+}
+
+// checkReads walks the tree below name in execution order with the state keys available when it first runs, and
+// returns the keys written once it has run.
+func checkReads(nodes map[string]*Node, name string, avail map[string]bool) (map[string]bool, error) {
+	n := nodes[name]
+	switch n.Type {
+	case NodeParallel:
+		// Siblings run concurrently, so none can rely on the others' outputs.
+		out := avail
+		for _, child := range n.Children {
+			written, err := checkReads(nodes, child, avail)
+			if err != nil {
+				return nil, err
+			}
+			out = union(out, written)
+		}
+		return out, nil
+
+	case NodeSequential, NodeLoop:
+		// The first iteration of a loop is a sequence; later iterations also see the outputs of earlier ones,
+		// which instructions reach through optional placeholders.
+		for _, child := range n.Children {
+			written, err := checkReads(nodes, child, avail)
+			if err != nil {
+				return nil, err
+			}
+			avail = written
+		}
+		return avail, nil
+	}
+
+	reads := n.Inputs
+	if n.Instruction != "" {
+		reads = nil
+		for _, m := range placeholderRE.FindAllStringSubmatch(n.Instruction, -1) {
+			if m[2] == "" {
+				reads = append(reads, m[1])
+			}
+		}
+	}
+	for _, key := range reads {
+		if !avail[key] {
+			return nil, fmt.Errorf("node %q reads %q before any node writes it; use {%s?} if it is written later", n.Name, key, key)
+		}
+	}
+	if n.Output == "" {
+		return avail, nil
+	}
+	return union(avail, map[string]bool{n.Output: true}), nil
+}
+
+func union(a, b map[string]bool) map[string]bool {
+	out := maps.Clone(a)
+	maps.Copy(out, b)
+	return out
+}
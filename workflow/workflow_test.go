@@ -0,0 +1,180 @@
+// Copyright 2025 The tumix Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package workflow
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		spec    string
+		wantErr string
+	}{
+		"valid": {
+			spec: `
+nodes:
+  - {name: main, type: sequential, children: [draft, review]}
+  - {name: draft, type: llmagent, output: answer, instruction: "Answer {question}."}
+  - {name: review, type: judge, inputs: [answer]}
+`,
+		},
+		"optional read of a later output": {
+			spec: `
+root: main
+nodes:
+  - {name: main, type: loop, max_iterations: 2, children: [draft, review]}
+  - {name: draft, type: llmagent, inputs: [critique], output: answer, instruction: "Answer {question}. {critique?}"}
+  - {name: review, type: judge, inputs: [answer], output: critique}
+`,
+		},
+		"unknown key": {
+			spec:    "nodes:\n  - {name: a, type: llmagent, instruction: x, prompt: y}\n",
+			wantErr: "parse workflow",
+		},
+		"no nodes": {
+			spec:    "name: empty\n",
+			wantErr: "workflow has no nodes",
+		},
+		"unknown type": {
+			spec:    "nodes:\n  - {name: a, type: swarm}\n",
+			wantErr: `node "a": unknown type "swarm"`,
+		},
+		"duplicate node": {
+			spec:    "nodes:\n  - {name: a, type: llmagent, instruction: x}\n  - {name: a, type: llmagent, instruction: y}\n",
+			wantErr: `duplicate node "a"`,
+		},
+		"llmagent without instruction": {
+			spec:    "nodes:\n  - {name: a, type: llmagent}\n",
+			wantErr: "llmagent requires an instruction",
+		},
+		"leaf with children": {
+			spec:    "nodes:\n  - {name: a, type: judge, inputs: [question], children: [b]}\n  - {name: b, type: llmagent, instruction: x}\n",
+			wantErr: "judge cannot have children",
+		},
+		"container with output": {
+			spec:    "nodes:\n  - {name: a, type: parallel, output: x, children: [b]}\n  - {name: b, type: llmagent, instruction: x}\n",
+			wantErr: "parallel cannot have an instruction, inputs, or output",
+		},
+		"unknown child": {
+			spec:    "nodes:\n  - {name: a, type: sequential, children: [b]}\n",
+			wantErr: `node "a": unknown child "b"`,
+		},
+		"two parents": {
+			spec: `
+nodes:
+  - {name: a, type: sequential, children: [c]}
+  - {name: b, type: sequential, children: [c]}
+  - {name: c, type: llmagent, instruction: x}
+`,
+			wantErr: `node "c" is a child of both "a" and "b"`,
+		},
+		"ambiguous root": {
+			spec:    "nodes:\n  - {name: a, type: llmagent, instruction: x}\n  - {name: b, type: llmagent, instruction: y}\n",
+			wantErr: "root is required when several nodes have no parent: a, b",
+		},
+		"unreachable node": {
+			spec:    "root: a\nnodes:\n  - {name: a, type: llmagent, instruction: x}\n  - {name: b, type: llmagent, instruction: y}\n",
+			wantErr: `node "b" is not reachable from root "a"`,
+		},
+		"cycle": {
+			spec:    "nodes:\n  - {name: a, type: sequential, children: [b]}\n  - {name: b, type: loop, children: [a]}\n",
+			wantErr: "every node has a parent",
+		},
+		"undeclared placeholder": {
+			spec:    "nodes:\n  - {name: a, type: llmagent, instruction: \"Use {notes}.\"}\n",
+			wantErr: `instruction reads {notes} but "notes" is not an input`,
+		},
+		"dangling input": {
+			spec:    "nodes:\n  - {name: a, type: judge, inputs: [notes]}\n",
+			wantErr: `input "notes" is not the output of any node`,
+		},
+		"parallel sibling read": {
+			spec: `
+nodes:
+  - {name: main, type: parallel, children: [a, b]}
+  - {name: a, type: llmagent, output: x, instruction: "Answer {question}."}
+  - {name: b, type: llmagent, inputs: [x], instruction: "Check {x}."}
+`,
+			wantErr: `node "b" reads "x" before any node writes it`,
+		},
+		"name clash with workflow": {
+			spec:    "name: a\nnodes:\n  - {name: a, type: llmagent, instruction: x}\n",
+			wantErr: `node "a" has the name of the workflow`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse([]byte(tt.spec))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadExamples(t *testing.T) {
+	t.Parallel()
+
+	paths, err := filepath.Glob(filepath.Join("examples", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no example workflows")
+	}
+	for _, path := range paths {
+		spec, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(%s): %v", path, err)
+		}
+		if _, err := spec.Build(Config{Model: &scriptedLLM{}}); err != nil {
+			t.Fatalf("Build(%s): %v", path, err)
+		}
+	}
+}
+
+func TestJudgeInstruction(t *testing.T) {
+	t.Parallel()
+
+	got := judgeInstruction([]string{"question", "pro", "con"}, true, nil)
+	var keys []string
+	for _, m := range placeholderRE.FindAllStringSubmatch(got, -1) {
+		keys = append(keys, m[1])
+	}
+	if diff := cmp.Diff([]string{"question", "pro", "con"}, keys); diff != "" {
+		t.Fatalf("judgeInstruction() placeholders mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(got, "exit_loop") {
+		t.Fatalf("judgeInstruction() in a loop does not mention exit_loop:\n%s", got)
+	}
+}